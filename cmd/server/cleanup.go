@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/app"
+	"github.com/spf13/cobra"
+)
+
+func newCleanupCommand() *cobra.Command {
+	var visitDays, trackDays int
+	cmd := &cobra.Command{
+		Use:   "cleanup",
+		Short: "Delete profile visits and track history past their retention period",
+		Long: "Delete profile visits, short link clicks, track history, and play events past their retention period. " +
+			"Defaults come from VISIT_RETENTION_DAYS and TRACK_RETENTION_DAYS.",
+		Args: cobra.NoArgs,
+		RunE: withApp(func(cmd *cobra.Command, a *app.App) error {
+			if !cmd.Flags().Changed("visits-older-than") {
+				visitDays = a.Config.Retention.VisitDays
+			}
+			if !cmd.Flags().Changed("tracks-older-than") {
+				trackDays = a.Config.Retention.TrackDays
+			}
+			if visitDays < 0 || trackDays < 0 {
+				return fmt.Errorf("retention days must not be negative")
+			}
+			if err := a.Connect(false); err != nil {
+				return err
+			}
+			return a.Cleanup(cmd.Context(), visitDays, trackDays)
+		}),
+	}
+	cmd.Flags().IntVar(&visitDays, "visits-older-than", 0, "delete profile visits and short link clicks older than this many days (0 keeps them)")
+	cmd.Flags().IntVar(&trackDays, "tracks-older-than", 0, "delete track history, play events, and listening sessions older than this many days (0 keeps them)")
+	return cmd
+}
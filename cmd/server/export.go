@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/app"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+	"github.com/spf13/cobra"
+)
+
+// userExport is everything stored about a user, minus Spotify credentials
+type userExport struct {
+	ExportedAt time.Time       `json:"exported_at"`
+	User       *models.User    `json:"user"`
+	Profile    *models.Profile `json:"profile"`
+	Tracks     []models.Track  `json:"tracks"`
+}
+
+func newExportCommand() *cobra.Command {
+	var profileURL, tenantSlug, output string
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write a user's account, profile, and track history as JSON",
+		Args:  cobra.NoArgs,
+		RunE: withApp(func(cmd *cobra.Command, a *app.App) error {
+			if err := a.Connect(false); err != nil {
+				return err
+			}
+			ctx := cmd.Context()
+			if tenantSlug != "" {
+				tenant, err := a.TenantService.GetBySlug(ctx, tenantSlug)
+				if err != nil {
+					return err
+				}
+				ctx = services.WithTenant(ctx, tenant)
+			}
+
+			user, err := a.UserService.GetUserByProfileURL(ctx, profileURL)
+			if err != nil {
+				return err
+			}
+			profile, err := a.ProfileService.GetProfile(ctx, user.ID)
+			if err != nil {
+				return err
+			}
+
+			export := userExport{ExportedAt: time.Now().UTC(), User: user, Profile: profile, Tracks: []models.Track{}}
+			query := services.TrackHistoryQuery{Limit: services.MaxHistoryLimit}
+			for {
+				page, err := a.ProfileService.GetTrackHistory(ctx, user.ID, query)
+				if err != nil {
+					return err
+				}
+				export.Tracks = append(export.Tracks, page.Tracks...)
+				if page.NextCursor == "" {
+					break
+				}
+				query.Cursor = page.NextCursor
+			}
+
+			// Logs may also go to stdout, so default to a file
+			if output == "" {
+				output = user.ProfileURL + ".json"
+			}
+			if output == "-" {
+				return writeExport(cmd.OutOrStdout(), export)
+			}
+			f, err := os.Create(output)
+			if err != nil {
+				return err
+			}
+			if err := writeExport(f, export); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+			a.Logger.Info().Str("profileURL", user.ProfileURL).Int("tracks", len(export.Tracks)).Str("output", output).Msg("Exported user data")
+			return nil
+		}),
+	}
+	cmd.Flags().StringVar(&profileURL, "profile", "", "profile URL of the user to export")
+	cmd.Flags().StringVar(&tenantSlug, "tenant", "", "slug of the tenant the profile belongs to (default: no tenant)")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "file to write, or - for stdout (default <profile>.json)")
+	cmd.MarkFlagRequired("profile")
+	return cmd
+}
+
+func writeExport(w io.Writer, export userExport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(export)
+}
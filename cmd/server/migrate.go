@@ -0,0 +1,24 @@
+package main
+
+import (
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/app"
+	"github.com/spf13/cobra"
+)
+
+func newMigrateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply database migrations and exit",
+		Args:  cobra.NoArgs,
+		RunE: withApp(func(cmd *cobra.Command, a *app.App) error {
+			if err := a.ConnectDatabase(); err != nil {
+				return err
+			}
+			if err := a.Migrate(); err != nil {
+				return err
+			}
+			a.Logger.Info().Msg("Migrations applied")
+			return nil
+		}),
+	}
+}
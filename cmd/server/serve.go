@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/app"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/grpcserver"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/handlers"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/idempotency"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/introspect"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/lifecycle"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/ratelimit"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cobra"
+)
+
+func newServeCommand() *cobra.Command {
+	var skipMigrations bool
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Run the HTTP, admin, and gRPC servers with the background workers",
+		Args:  cobra.NoArgs,
+		RunE: withApp(func(cmd *cobra.Command, a *app.App) error {
+			return serve(a, !skipMigrations)
+		}),
+	}
+	cmd.Flags().BoolVar(&skipMigrations, "skip-migrations", false, "don't run database migrations at startup")
+	return cmd
+}
+
+// serve runs the full API process until SIGINT or SIGTERM
+func serve(a *app.App, migrate bool) error {
+	cfg := a.Config
+	logger := a.Logger
+
+	// Set Gin mode
+	if cfg.Environment == "production" {
+		gin.SetMode(gin.ReleaseMode)
+	} else {
+		logger.Info().Msg("Running in development mode")
+	}
+
+	if err := a.Connect(migrate); err != nil {
+		return err
+	}
+
+	limiter := ratelimit.New(cfg.RateLimit, a.Redis)
+	a.Live.OnReload(func(next *config.Config) {
+		limiter.Update(next.RateLimit)
+	})
+	idempotencyStore := idempotency.New(cfg.Idempotency, a.Redis)
+
+	// Every long-running subsystem is started and stopped by one group
+	group := a.NewGroup()
+	a.AddWorkers(group)
+	if cfg.Jobs.InServer {
+		a.AddJobs(group)
+	}
+
+	// Components reported by /debug/stats on the admin listener
+	a.RegisterStats()
+	introspect.Register("websockets", func() interface{} {
+		return map[string]int{"open": handlers.OpenWebSocketConnections()}
+	})
+
+	// Initialize router
+	router := gin.New()
+	router.Use(handlers.RecoveryMiddleware(a.Reporter))
+	router.Use(utils.RequestIDMiddleware())
+	router.Use(utils.LoggerMiddleware(logger))
+	router.Use(handlers.AuditMiddleware(a.Audit))
+	router.Use(handlers.ErrorMiddleware())
+	router.Use(handlers.BodyLimitMiddleware(cfg.BodyLimit))
+	router.Use(handlers.CORSMiddleware(a.Live))
+	router.Use(handlers.CacheControlMiddleware(cfg.HTTPCache))
+	router.Use(handlers.TenantMiddleware(a.TenantService, logger))
+	router.Use(handlers.APIKeyMiddleware(a.APIKeys, a.UserService, logger))
+
+	// Register routes
+	logger.Info().Msg("Registering routes")
+	handlers.RegisterAuthHandlers(router, a.UserService, a.Providers, a.AppleMusic, logger)
+	handlers.RegisterProfileHandlers(router, a.ProfileService, a.UserService, cfg.Privacy, limiter, idempotencyStore, logger)
+	handlers.RegisterTrackHandlers(router, a.SpotifyService, a.Providers, a.ProfileService, a.UserService, a.Lyrics, cfg.Privacy, limiter, idempotencyStore, logger)
+	handlers.RegisterStatsHandlers(router, a.ProfileService, a.UserService, limiter, logger)
+	handlers.RegisterAnalyticsHandlers(router, a.UserService, limiter, logger)
+	handlers.RegisterMediaWebhookHandlers(router, a.MediaWebhooks, a.ProfileService, a.UserService, limiter, logger)
+	handlers.RegisterWebhookHandlers(router, a.Webhooks, a.UserService, limiter, idempotencyStore, logger)
+	handlers.RegisterScrobbleHandlers(router, a.Scrobbles, a.UserService, limiter, logger)
+	handlers.RegisterShortLinkHandlers(router, a.ShortLinks, a.UserService, limiter, idempotencyStore, logger)
+	handlers.RegisterSessionHandlers(router, a.UserService, limiter, logger)
+	handlers.RegisterAccountHandlers(router, a.UserService, cfg.Retention, limiter, logger)
+	handlers.RegisterAPIKeyHandlers(router, a.APIKeys, a.UserService, limiter, idempotencyStore, logger)
+	if a.AlbumArt != nil {
+		handlers.RegisterAlbumArtHandlers(router, a.AlbumArt, limiter, logger)
+	}
+	handlers.RegisterBadgeHandlers(router, a.Badges, a.ProfileService, a.UserService, limiter, a.Live, logger)
+	handlers.RegisterOGImageHandlers(router, a.OGImages, a.ProfileService, a.UserService, limiter, a.Live, logger)
+	handlers.RegisterPublicHandlers(router, a.ProfileService, a.SpotifyService, a.UserService, a.Lyrics, limiter, a.Live, logger)
+	handlers.RegisterDocsHandlers(router)
+	handlers.RegisterHealthHandlers(router, a.DB, a.Redis, a.Canary)
+	handlers.RegisterVersionHandlers(router)
+	router.NoRoute(handlers.NotFoundHandler())
+
+	// Serve static files
+	router.Static("/static", "./web/static")
+	router.LoadHTMLGlob("./web/templates/*")
+
+	// Setup server
+	server := &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler:      router,
+		ReadTimeout:  time.Duration(cfg.Server.ReadTimeoutSeconds) * time.Second,
+		WriteTimeout: time.Duration(cfg.Server.WriteTimeoutSeconds) * time.Second,
+		IdleTimeout:  time.Duration(cfg.Server.IdleTimeoutSeconds) * time.Second,
+	}
+	adminServer := a.AdminServer()
+
+	group.Add(lifecycle.HTTPServer("admin_server", adminServer, a.ShutdownTimeout()))
+	group.Add(lifecycle.HTTPServer("http_server", server, a.ShutdownTimeout()))
+	logger.Info().Msgf("Starting server on port %d", cfg.Server.Port)
+	logger.Info().Msgf("Starting admin server on %s", adminServer.Addr)
+
+	// Start the gRPC server on its own port
+	if cfg.GRPC.Enabled {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPC.Port))
+		if err != nil {
+			return fmt.Errorf("failed to listen for gRPC: %w", err)
+		}
+		grpcServer := grpcserver.New(a.ProfileService, a.UserService, a.SpotifyService, logger)
+		group.Add(lifecycle.Component{
+			Name: "grpc_server",
+			Run: func(ctx context.Context) error {
+				if err := grpcServer.Serve(lis); err != nil {
+					return err
+				}
+				<-ctx.Done()
+				return nil
+			},
+			// Watch streams never end on their own, so anything still open
+			// when the deadline passes is cut off and clients reconnect
+			// elsewhere
+			Stop: func(ctx context.Context) error {
+				stopped := make(chan struct{})
+				go func() {
+					grpcServer.GracefulStop()
+					close(stopped)
+				}()
+				select {
+				case <-stopped:
+				case <-ctx.Done():
+					grpcServer.Stop()
+				}
+				return nil
+			},
+			StopTimeout: a.ShutdownTimeout(),
+		})
+		logger.Info().Msgf("Starting gRPC server on port %d", cfg.GRPC.Port)
+	}
+
+	// Run until an interrupt, then stop servers before the workers they use
+	if err := a.Run(group); err != nil {
+		return fmt.Errorf("server stopped after a component failed: %w", err)
+	}
+	logger.Info().Msg("Server exiting")
+	return nil
+}
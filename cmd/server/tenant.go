@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"text/tabwriter"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/app"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/audit"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+	"github.com/spf13/cobra"
+)
+
+// tenantSecretEnv supplies the client secret when --client-secret is omitted,
+// keeping it out of shell history
+const tenantSecretEnv = "TENANT_SPOTIFY_CLIENT_SECRET"
+
+func newTenantCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tenant",
+		Short: "Manage white-label tenants with their own domain and Spotify app",
+		Args:  cobra.NoArgs,
+	}
+	cmd.AddCommand(
+		newTenantCreateCommand(),
+		newTenantListCommand(),
+		newTenantSetActiveCommand("enable", "Serve a disabled tenant's domain again", true),
+		newTenantSetActiveCommand("disable", "Stop serving a tenant's domain without deleting its users", false),
+	)
+	return cmd
+}
+
+// withTenants runs a tenant command with only the database connected
+func withTenants(run func(cmd *cobra.Command, a *app.App, tenants *services.TenantService) error) func(cmd *cobra.Command, args []string) error {
+	return withApp(func(cmd *cobra.Command, a *app.App) error {
+		if err := a.ConnectDatabase(); err != nil {
+			return err
+		}
+		return run(cmd, a, services.NewTenantService(a.DB, a.Logger))
+	})
+}
+
+func newTenantCreateCommand() *cobra.Command {
+	var tenant models.Tenant
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a tenant served on its own domain",
+		Long: "Create a tenant served on its own domain. Requests to the domain sign users in " +
+			"with the tenant's Spotify app and only see the tenant's profiles. The client secret " +
+			"is read from " + tenantSecretEnv + " when --client-secret is not given.",
+		Args: cobra.NoArgs,
+		RunE: withTenants(func(cmd *cobra.Command, a *app.App, tenants *services.TenantService) error {
+			if tenant.SpotifyClientSecret == "" {
+				tenant.SpotifyClientSecret = os.Getenv(tenantSecretEnv)
+			}
+			if tenant.SpotifyClientSecret == "" {
+				return fmt.Errorf("--client-secret or %s is required", tenantSecretEnv)
+			}
+			if u, err := url.Parse(tenant.SpotifyRedirectURI); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("--redirect-uri must be an absolute http or https URL")
+			}
+
+			if err := tenants.CreateTenant(cmd.Context(), &tenant); err != nil {
+				return err
+			}
+			a.Audit.Log(cmd.Context(), audit.Event{
+				Type:   audit.EventAdminAction,
+				Reason: "create_tenant",
+				Actor:  "cli",
+				Fields: map[string]interface{}{"tenant": tenant.Slug, "domain": tenant.Domain},
+			})
+			fmt.Fprintf(cmd.OutOrStdout(), "Created tenant %s (%s) on %s\n", tenant.Slug, tenant.ID, tenant.Domain)
+			return nil
+		}),
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&tenant.Slug, "slug", "", "short unique name for the tenant (required)")
+	flags.StringVar(&tenant.Name, "name", "", "brand name shown to users (required)")
+	flags.StringVar(&tenant.Domain, "domain", "", "host the tenant is served on, e.g. music.example.com (required)")
+	flags.StringVar(&tenant.SpotifyClientID, "client-id", "", "the tenant's Spotify app client ID (required)")
+	flags.StringVar(&tenant.SpotifyClientSecret, "client-secret", "", "the tenant's Spotify app client secret")
+	flags.StringVar(&tenant.SpotifyRedirectURI, "redirect-uri", "", "callback registered with the tenant's Spotify app, e.g. https://music.example.com/auth/spotify/callback (required)")
+	flags.StringVar(&tenant.DefaultTheme, "theme", "default", "theme for new profiles")
+	flags.StringVar(&tenant.DefaultBackgroundColor, "background-color", "#121212", "background color for new profiles")
+	flags.StringVar(&tenant.DefaultTextColor, "text-color", "#FFFFFF", "text color for new profiles")
+	flags.StringVar(&tenant.DefaultAnimationStyle, "animation-style", "fade", "animation style for new profiles")
+	for _, name := range []string{"slug", "name", "domain", "client-id", "redirect-uri"} {
+		_ = cmd.MarkFlagRequired(name)
+	}
+	return cmd
+}
+
+func newTenantListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List tenants",
+		Args:  cobra.NoArgs,
+		RunE: withTenants(func(cmd *cobra.Command, _ *app.App, tenants *services.TenantService) error {
+			list, err := tenants.ListTenants(cmd.Context())
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "SLUG\tDOMAIN\tNAME\tSPOTIFY CLIENT ID\tACTIVE")
+			for _, t := range list {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\n", t.Slug, t.Domain, t.Name, t.SpotifyClientID, t.IsActive)
+			}
+			return w.Flush()
+		}),
+	}
+}
+
+func newTenantSetActiveCommand(use, short string, active bool) *cobra.Command {
+	return &cobra.Command{
+		Use:   use + " <slug>",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withTenants(func(cmd *cobra.Command, a *app.App, tenants *services.TenantService) error {
+				if err := tenants.SetTenantActive(cmd.Context(), args[0], active); err != nil {
+					return err
+				}
+				a.Audit.Log(cmd.Context(), audit.Event{
+					Type:   audit.EventAdminAction,
+					Reason: use + "_tenant",
+					Actor:  "cli",
+					Fields: map[string]interface{}{"tenant": args[0]},
+				})
+				fmt.Fprintf(cmd.OutOrStdout(), "Tenant %s %sd\n", args[0], use)
+				return nil
+			})(cmd, args)
+		},
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/app"
+	"github.com/spf13/cobra"
+)
+
+func newWorkerCommand() *cobra.Command {
+	var migrate bool
+	cmd := &cobra.Command{
+		Use:   "worker",
+		Short: "Run only the background workers and jobs, without the public HTTP and gRPC servers",
+		Long: "Run only the background workers and jobs. The admin listener still serves " +
+			"/metrics and /debug endpoints for the worker process. Same as the cmd/worker binary.",
+		Args: cobra.NoArgs,
+		RunE: withApp(func(cmd *cobra.Command, a *app.App) error {
+			return a.RunWorker(migrate)
+		}),
+	}
+	cmd.Flags().BoolVar(&migrate, "migrate", false, "run database migrations at startup")
+	return cmd
+}
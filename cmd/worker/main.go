@@ -0,0 +1,25 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/app"
+)
+
+// The worker binary runs background workers and jobs without the public HTTP
+// and gRPC servers, so it can be scaled and deployed apart from API pods
+func main() {
+	migrate := flag.Bool("migrate", false, "run database migrations at startup")
+	flag.Parse()
+
+	a, err := app.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = a.RunWorker(*migrate)
+	a.Close()
+	if err != nil {
+		a.Logger.Fatal().Err(err).Msg("Worker failed")
+	}
+}
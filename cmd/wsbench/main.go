@@ -0,0 +1,332 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/app"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/realtime"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+	"github.com/gorilla/websocket"
+)
+
+// trackPrefix marks the synthetic tracks published by a run, so messages
+// from the real poller or an earlier run are not counted
+const trackPrefix = "wsbench-"
+
+// options are the command-line settings for one run
+type options struct {
+	baseURL     string
+	profile     string
+	tenant      string
+	conns       int
+	dialWorkers int
+	publishes   int
+	interval    time.Duration
+	drain       time.Duration
+}
+
+// bench holds the connections and publish times for one run
+type bench struct {
+	opts  options
+	runID string
+
+	mu   sync.Mutex
+	sent map[string]time.Time
+
+	latencies [][]time.Duration
+	resyncs   atomic.Int64
+	unmatched atomic.Int64
+}
+
+// wsbench opens many WebSocket connections to a profile, publishes synthetic
+// track changes through Redis the same way real changes are published, and
+// reports how long each change took to reach the clients. It is an internal
+// tool for validating fan-out changes before release; point it at a staging
+// or local deployment, never production.
+func main() {
+	var opts options
+	flag.StringVar(&opts.baseURL, "url", "http://localhost:8080", "base URL of the server under test")
+	flag.StringVar(&opts.profile, "profile", "", "profile URL to subscribe to (required)")
+	flag.StringVar(&opts.tenant, "tenant", "", "slug of the tenant the profile belongs to, if any")
+	flag.IntVar(&opts.conns, "conns", 1000, "number of WebSocket connections to open")
+	flag.IntVar(&opts.dialWorkers, "dial-concurrency", 50, "connections to open at the same time")
+	flag.IntVar(&opts.publishes, "publishes", 50, "number of track changes to publish")
+	flag.DurationVar(&opts.interval, "interval", time.Second, "time between track changes")
+	flag.DurationVar(&opts.drain, "drain", 5*time.Second, "how long to wait for deliveries after the last publish")
+	flag.Parse()
+
+	if opts.profile == "" {
+		fmt.Fprintln(os.Stderr, "wsbench: -profile is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	a, err := app.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = run(a, opts)
+	a.Close()
+	if err != nil {
+		a.Logger.Fatal().Err(err).Msg("Benchmark failed")
+	}
+}
+
+// run connects, publishes, and prints the report
+func run(a *app.App, opts options) error {
+	if err := a.Connect(false); err != nil {
+		return err
+	}
+	if !a.SpotifyService.RealtimeAvailable() {
+		return fmt.Errorf("redis is unavailable, track changes cannot be published")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if opts.tenant != "" {
+		tenant, err := a.TenantService.GetBySlug(ctx, opts.tenant)
+		if err != nil {
+			return err
+		}
+		ctx = services.WithTenant(ctx, tenant)
+	}
+
+	user, err := a.UserService.GetUserByProfileURL(ctx, opts.profile)
+	if err != nil {
+		return fmt.Errorf("failed to find profile %q: %w", opts.profile, err)
+	}
+
+	// The socket only checks that a visit cookie is present, so one visit is
+	// shared by every connection instead of recording thousands
+	visitID, err := recordVisit(opts)
+	if err != nil {
+		return err
+	}
+
+	b := &bench{
+		opts:      opts,
+		runID:     strconv.FormatInt(time.Now().UnixNano(), 36),
+		sent:      make(map[string]time.Time, opts.publishes),
+		latencies: make([][]time.Duration, opts.conns),
+	}
+
+	a.Logger.Info().Int("conns", opts.conns).Str("profile", opts.profile).Msg("Opening connections")
+	start := time.Now()
+	conns, failures := b.dial(visitID)
+	if len(conns) == 0 {
+		return fmt.Errorf("no connections could be opened (%d failed)", failures)
+	}
+	a.Logger.Info().Int("open", len(conns)).Int("failed", failures).Dur("took", time.Since(start)).Msg("Connections open")
+
+	var readers sync.WaitGroup
+	for i, conn := range conns {
+		readers.Add(1)
+		go func(i int, conn *websocket.Conn) {
+			defer readers.Done()
+			b.read(i, conn)
+		}(i, conn)
+	}
+
+	a.Logger.Info().Int("publishes", opts.publishes).Dur("interval", opts.interval).Msg("Publishing track changes")
+	ticker := time.NewTicker(opts.interval)
+	defer ticker.Stop()
+	for seq := 0; seq < opts.publishes; seq++ {
+		if seq > 0 {
+			<-ticker.C
+		}
+		track := b.track(seq)
+		b.mu.Lock()
+		b.sent[track.TrackID] = time.Now()
+		b.mu.Unlock()
+		if err := a.SpotifyService.NotifyTrackChange(ctx, user.ID, track); err != nil {
+			return fmt.Errorf("failed to publish track change: %w", err)
+		}
+	}
+
+	time.Sleep(opts.drain)
+	for _, conn := range conns {
+		conn.Close()
+	}
+	readers.Wait()
+
+	b.report(os.Stdout, len(conns), failures)
+	return nil
+}
+
+// recordVisit loads the profile once to get a visit_id cookie
+func recordVisit(opts options) (string, error) {
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar, Timeout: 10 * time.Second}
+
+	profileURL := strings.TrimRight(opts.baseURL, "/") + "/profile/" + url.PathEscape(opts.profile)
+	req, err := http.NewRequest(http.MethodGet, profileURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to load profile: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to load profile: %s", resp.Status)
+	}
+
+	for _, cookie := range jar.Cookies(resp.Request.URL) {
+		if cookie.Name == "visit_id" {
+			return cookie.Value, nil
+		}
+	}
+	return "", fmt.Errorf("profile response did not set a visit_id cookie")
+}
+
+// dial opens the connections with a bounded number in flight, returning the
+// ones that succeeded and how many failed
+func (b *bench) dial(visitID string) ([]*websocket.Conn, int) {
+	wsURL := strings.TrimRight(b.opts.baseURL, "/") + "/ws/tracks/" + url.PathEscape(b.opts.profile)
+	wsURL = "ws" + strings.TrimPrefix(wsURL, "http")
+
+	header := http.Header{}
+	header.Set("Cookie", (&http.Cookie{Name: "visit_id", Value: visitID}).String())
+	dialer := &websocket.Dialer{HandshakeTimeout: 10 * time.Second}
+
+	var (
+		mu       sync.Mutex
+		conns    []*websocket.Conn
+		failures int
+		logged   bool
+		wg       sync.WaitGroup
+	)
+	slots := make(chan struct{}, b.opts.dialWorkers)
+	for i := 0; i < b.opts.conns; i++ {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			conn, resp, err := dialer.Dial(wsURL, header)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failures++
+				if !logged {
+					logged = true
+					status := ""
+					if resp != nil {
+						status = " (" + resp.Status + ")"
+					}
+					fmt.Fprintf(os.Stderr, "wsbench: connection failed: %v%s\n", err, status)
+				}
+				return
+			}
+			conns = append(conns, conn)
+		}()
+	}
+	wg.Wait()
+	return conns, failures
+}
+
+// read records the delivery latency of every synthetic track a connection
+// receives until it is closed
+func (b *bench) read(i int, conn *websocket.Conn) {
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		received := time.Now()
+
+		var message struct {
+			Type    realtime.EventType `json:"type"`
+			TrackID string             `json:"track_id"`
+		}
+		if json.Unmarshal(data, &message) != nil {
+			continue
+		}
+		if message.Type == realtime.EventResync {
+			b.resyncs.Add(1)
+			continue
+		}
+		if !strings.HasPrefix(message.TrackID, trackPrefix+b.runID) {
+			continue
+		}
+
+		b.mu.Lock()
+		sentAt, ok := b.sent[message.TrackID]
+		b.mu.Unlock()
+		if !ok {
+			b.unmatched.Add(1)
+			continue
+		}
+		b.latencies[i] = append(b.latencies[i], received.Sub(sentAt))
+	}
+}
+
+// track builds the synthetic track change for one publish
+func (b *bench) track(seq int) *models.SpotifyCurrentlyPlaying {
+	return &models.SpotifyCurrentlyPlaying{
+		IsPlaying:  true,
+		TrackID:    fmt.Sprintf("%s%s-%d", trackPrefix, b.runID, seq),
+		TrackName:  fmt.Sprintf("Benchmark Track %d", seq+1),
+		ArtistName: "wsbench",
+		AlbumName:  "Load Test",
+		DurationMs: 180000,
+		ChangedAt:  time.Now().UnixMilli(),
+	}
+}
+
+// report prints delivery counts and latency percentiles
+func (b *bench) report(w io.Writer, open, failed int) {
+	var all []time.Duration
+	for _, l := range b.latencies {
+		all = append(all, l...)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+
+	expected := open * b.opts.publishes
+	fmt.Fprintf(w, "connections: %d open, %d failed\n", open, failed)
+	fmt.Fprintf(w, "deliveries:  %d of %d (%.2f%%), %d resyncs, %d unmatched\n",
+		len(all), expected, percent(len(all), expected), b.resyncs.Load(), b.unmatched.Load())
+	if len(all) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "latency:     p50 %s  p90 %s  p99 %s  p99.9 %s  max %s\n",
+		percentile(all, 50), percentile(all, 90), percentile(all, 99), percentile(all, 99.9), all[len(all)-1])
+}
+
+// percentile returns the nearest-rank percentile of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(p/100*float64(len(sorted)) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1].Round(10 * time.Microsecond)
+}
+
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total) * 100
+}
@@ -0,0 +1,217 @@
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+	"github.com/rs/zerolog"
+)
+
+// Alert statuses
+const (
+	StatusFiring   = "firing"
+	StatusResolved = "resolved"
+)
+
+// Built-in rules
+const (
+	RuleSpotifyErrorRate     = "spotify_error_rate"
+	RuleTokenRefreshFailures = "spotify_token_refresh_failures"
+)
+
+// Alert is sent when a rule starts firing, periodically while it keeps
+// firing, and once more when it resolves
+type Alert struct {
+	Rule      string    `json:"rule"`
+	Status    string    `json:"status"`
+	Summary   string    `json:"summary"`
+	Value     float64   `json:"value"`
+	Threshold float64   `json:"threshold"`
+	Since     time.Time `json:"since"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+// evaluation is the result of checking a rule once
+type evaluation struct {
+	firing  bool
+	value   float64
+	summary string
+}
+
+// rule is a threshold check run on every monitor tick
+type rule struct {
+	name      string
+	threshold float64
+	evaluate  func() evaluation
+
+	// firing state, owned by the monitor loop
+	since    time.Time
+	notified time.Time
+}
+
+// Monitor periodically evaluates alert rules over the metrics counters and
+// notifies external receivers when a threshold is crossed
+type Monitor struct {
+	notifiers []Notifier
+	interval  time.Duration
+	repeat    time.Duration
+	backlog   float64
+	logger    zerolog.Logger
+
+	mu    sync.Mutex
+	rules []*rule
+}
+
+// New creates a monitor with the Spotify rules, or returns nil when no
+// receiver is configured. A nil Monitor is safe to use and does nothing.
+func New(cfg config.AlertingConfig, logger zerolog.Logger) *Monitor {
+	var notifiers []Notifier
+	client := &http.Client{Timeout: notifyTimeout}
+	if cfg.WebhookURL != "" {
+		notifiers = append(notifiers, WebhookNotifier{URL: cfg.WebhookURL, Client: client})
+	}
+	if cfg.SlackWebhookURL != "" {
+		notifiers = append(notifiers, SlackNotifier{URL: cfg.SlackWebhookURL, Client: client})
+	}
+	if len(notifiers) == 0 {
+		return nil
+	}
+
+	m := &Monitor{
+		notifiers: notifiers,
+		interval:  time.Duration(cfg.IntervalSeconds) * time.Second,
+		repeat:    time.Duration(cfg.RepeatMinutes) * time.Minute,
+		backlog:   float64(cfg.QueueBacklog),
+		logger:    logger.With().Str("service", "alerting").Logger(),
+	}
+	if cfg.SpotifyErrorRatePercent > 0 {
+		m.add(RuleSpotifyErrorRate, float64(cfg.SpotifyErrorRatePercent), spotifyErrorRate(cfg.SpotifyErrorRatePercent, cfg.SpotifyMinRequests))
+	}
+	if cfg.TokenRefreshFailures > 0 {
+		m.add(RuleTokenRefreshFailures, float64(cfg.TokenRefreshFailures), tokenRefreshFailures(cfg.TokenRefreshFailures))
+	}
+	return m
+}
+
+// WatchBacklog adds a rule that fires while a queue's depth is at or above
+// the configured backlog threshold
+func (m *Monitor) WatchBacklog(queue string, depth func() int) {
+	if m == nil || m.backlog <= 0 {
+		return
+	}
+	m.add(queue+"_backlog", m.backlog, func() evaluation {
+		value := float64(depth())
+		return evaluation{
+			firing:  value >= m.backlog,
+			value:   value,
+			summary: fmt.Sprintf("%s has %.0f queued items (threshold %.0f)", queue, value, m.backlog),
+		}
+	})
+}
+
+func (m *Monitor) add(name string, threshold float64, evaluate func() evaluation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rules = append(m.rules, &rule{name: name, threshold: threshold, evaluate: evaluate})
+}
+
+// Run evaluates the rules every interval until ctx is cancelled
+func (m *Monitor) Run(ctx context.Context) {
+	if m == nil {
+		return
+	}
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.evaluate(ctx, time.Now())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// evaluate runs every rule once and sends notifications for state changes
+func (m *Monitor) evaluate(ctx context.Context, now time.Time) {
+	m.mu.Lock()
+	rules := append([]*rule(nil), m.rules...)
+	m.mu.Unlock()
+
+	for _, r := range rules {
+		result := r.evaluate()
+		alert := Alert{Rule: r.name, Summary: result.summary, Value: result.value, Threshold: r.threshold, SentAt: now}
+
+		switch {
+		case result.firing && r.since.IsZero():
+			r.since, r.notified = now, now
+			alert.Status, alert.Since = StatusFiring, now
+			m.logger.Warn().Str("rule", r.name).Float64("value", result.value).Msg(result.summary)
+		case result.firing && m.repeat > 0 && now.Sub(r.notified) >= m.repeat:
+			r.notified = now
+			alert.Status, alert.Since = StatusFiring, r.since
+		case !result.firing && !r.since.IsZero():
+			alert.Status, alert.Since = StatusResolved, r.since
+			alert.Summary = fmt.Sprintf("%s is back under its threshold", r.name)
+			r.since, r.notified = time.Time{}, time.Time{}
+			m.logger.Info().Str("rule", r.name).Msg("Alert resolved")
+		default:
+			continue
+		}
+		m.notify(ctx, alert)
+	}
+}
+
+// notify sends an alert to every receiver, logging failures
+func (m *Monitor) notify(ctx context.Context, alert Alert) {
+	for _, n := range m.notifiers {
+		if err := n.Notify(ctx, alert); err != nil {
+			m.logger.Error().Err(err).Str("rule", alert.Rule).Msg("Failed to send alert")
+		}
+	}
+}
+
+// spotifyErrorRate fires when the share of failed Spotify calls since the
+// last tick reaches percent, once there were at least minRequests calls
+func spotifyErrorRate(percent, minRequests int) func() evaluation {
+	lastSuccess, lastFailed := services.SpotifyRequestCounts("")
+	return func() evaluation {
+		success, failed := services.SpotifyRequestCounts("")
+		deltaSuccess, deltaFailed := success-lastSuccess, failed-lastFailed
+		lastSuccess, lastFailed = success, failed
+
+		total := deltaSuccess + deltaFailed
+		if total == 0 {
+			return evaluation{}
+		}
+		rate := deltaFailed / total * 100
+		return evaluation{
+			firing:  total >= float64(minRequests) && rate >= float64(percent),
+			value:   rate,
+			summary: fmt.Sprintf("%.0f%% of Spotify API calls failed (%.0f of %.0f, threshold %d%%)", rate, deltaFailed, total, percent),
+		}
+	}
+}
+
+// tokenRefreshFailures fires when at least threshold token refreshes failed
+// since the last tick
+func tokenRefreshFailures(threshold int) func() evaluation {
+	_, last := services.SpotifyRequestCounts(services.SpotifyOpTokenRefresh)
+	return func() evaluation {
+		_, failed := services.SpotifyRequestCounts(services.SpotifyOpTokenRefresh)
+		delta := failed - last
+		last = failed
+		return evaluation{
+			firing:  delta >= float64(threshold),
+			value:   delta,
+			summary: fmt.Sprintf("%.0f Spotify token refreshes failed in the last interval (threshold %d)", delta, threshold),
+		}
+	}
+}
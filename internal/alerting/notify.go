@@ -0,0 +1,76 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// notifyTimeout bounds each delivery so a slow receiver can't stall the monitor
+const notifyTimeout = 10 * time.Second
+
+// Notifier delivers alerts to an external destination
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// WebhookNotifier posts each alert as JSON to a URL
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// Notify implements Notifier
+func (n WebhookNotifier) Notify(ctx context.Context, alert Alert) error {
+	return postJSON(ctx, n.Client, n.URL, alert)
+}
+
+// SlackNotifier posts each alert as a message to a Slack incoming webhook
+type SlackNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// Notify implements Notifier
+func (n SlackNotifier) Notify(ctx context.Context, alert Alert) error {
+	icon := ":rotating_light:"
+	if alert.Status == StatusResolved {
+		icon = ":white_check_mark:"
+	}
+	text := fmt.Sprintf("%s *[%s] %s*\n%s", icon, alert.Status, alert.Rule, alert.Summary)
+	return postJSON(ctx, n.Client, n.URL, map[string]string{"text": text})
+}
+
+// postJSON sends body to url and treats any non-2xx response as a failure
+func postJSON(ctx context.Context, client *http.Client, url string, body interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("alert receiver returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package app
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/handlers"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AdminServer builds the admin listener serving metrics and debugging
+// endpoints. Operational endpoints get their own listener so the public one
+// never exposes them.
+func (a *App) AdminServer() *http.Server {
+	adminRouter := gin.New()
+	adminRouter.Use(handlers.RecoveryMiddleware(a.Reporter))
+	adminRouter.Use(utils.RequestIDMiddleware())
+	adminRouter.Use(utils.LoggerMiddleware(a.Logger.With().Str("listener", "admin").Logger()))
+	adminRouter.Use(handlers.AuditMiddleware(a.Audit))
+	adminRouter.Use(handlers.ErrorMiddleware())
+	handlers.RegisterAdminHandlers(adminRouter, a.Config.Admin, a.Live, a.UsageReports)
+	if a.Config.Admin.Token == "" {
+		a.Logger.Warn().Msg("ADMIN_TOKEN not set, profiling endpoints are disabled")
+	}
+
+	return &http.Server{
+		Addr:              net.JoinHostPort(a.Config.Admin.Host, strconv.Itoa(a.Config.Admin.Port)),
+		Handler:           adminRouter,
+		ReadHeaderTimeout: 10 * time.Second,
+		// No write timeout: CPU profiles and traces stream for as long as requested
+	}
+}
@@ -0,0 +1,227 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/alerting"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/audit"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/canary"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/database"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/errreport"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/geoip"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/poller"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/utils"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/version"
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog"
+)
+
+// EnvFile is the optional environment file read at startup and on reload
+const EnvFile = ".env"
+
+// App holds the configuration and dependencies shared by every command, so
+// the server, worker, and maintenance commands are wired up the same way
+type App struct {
+	Config   *config.Config
+	Live     *config.Live
+	Logger   zerolog.Logger
+	Reporter errreport.Reporter
+	Audit    *audit.Logger
+	Build    version.Info
+
+	// Set by Connect
+	DB             *database.DB
+	Redis          *database.RedisClient
+	GeoIP          *geoip.Locator
+	TenantService  *services.TenantService
+	UserService    *services.UserService
+	SpotifyService *services.SpotifyService
+	AppleMusic     *services.AppleMusicService
+	Providers      *services.Providers
+	ProfileService *services.ProfileService
+	MediaWebhooks  *services.MediaWebhookService
+	Lyrics         *services.LyricsService
+	TrackLinks     *services.TrackLinkService
+	ShortLinks     *services.ShortLinkService
+	AlbumArt       *services.AlbumArtService
+	Badges         *services.BadgeService
+	OGImages       *services.OGImageService
+	Webhooks       *services.WebhookService
+	Scrobbles      *services.ScrobbleService
+	APIKeys        *services.APIKeyService
+	UsageReports   *services.UsageReportService
+	Canary         *canary.Canary
+	Alerts         *alerting.Monitor
+	Poller         *poller.Poller
+}
+
+// New loads and validates configuration and sets up logging, error
+// reporting, and audit logging. It does not touch the network.
+func New() (*App, error) {
+	// Load environment variables
+	if err := godotenv.Load(EnvFile); err != nil {
+		log.Println("Warning: .env file not found, using environment variables")
+	}
+
+	a := &App{Logger: utils.NewLogger(), Build: version.Get()}
+	a.Logger.Info().
+		Str("version", a.Build.Version).
+		Str("commit", a.Build.Commit).
+		Str("build_time", a.Build.BuildTime).
+		Str("go_version", a.Build.GoVersion).
+		Msg("Starting Music Sharing App")
+
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if err := utils.ConfigureLogLevels(cfg.Logging); err != nil {
+		return nil, fmt.Errorf("invalid log level configuration: %w", err)
+	}
+	a.Config = cfg
+
+	// Cache TTLs, rate limits, CORS, and feature flags can be reloaded at runtime
+	a.Live = config.NewLive(cfg, EnvFile)
+
+	// Report panics and error logs to the error tracker, if one is configured
+	if cfg.Errors.Release == "" && a.Build.Version != "dev" {
+		cfg.Errors.Release = a.Build.Version
+	}
+	a.Reporter, err = errreport.New(cfg.Errors)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize error reporting: %w", err)
+	}
+	a.Logger = a.Logger.Hook(errreport.LogHook{Reporter: a.Reporter})
+
+	// Security events go to their own stream, apart from access logs
+	a.Audit, err = audit.New(cfg.Audit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize audit logging: %w", err)
+	}
+
+	return a, nil
+}
+
+// Connect opens PostgreSQL and Redis, optionally runs migrations, and creates
+// the services. Redis being down is not an error; the app runs in degraded
+// mode until it returns.
+func (a *App) Connect(migrate bool) error {
+	cfg := a.Config
+
+	if err := a.ConnectDatabase(); err != nil {
+		return err
+	}
+	if migrate {
+		if err := a.Migrate(); err != nil {
+			return err
+		}
+	}
+
+	a.Logger.Info().Msg("Connecting to Redis")
+	var err error
+	a.Redis, err = database.NewRedisClient(cfg.Redis)
+	if err != nil {
+		a.Logger.Error().Err(err).Msg("Redis unavailable, starting in degraded mode")
+	}
+
+	a.GeoIP, err = geoip.Open(cfg.Visits.GeoIPDatabasePath)
+	if err != nil {
+		return err
+	}
+
+	a.TenantService = services.NewTenantService(a.DB, a.Logger)
+	a.UserService = services.NewUserService(a.DB, a.Redis, cfg.Sessions, cfg.Visits, a.GeoIP, a.Logger)
+	a.SpotifyService = services.NewSpotifyService(cfg.Spotify, cfg.Cache, a.Redis, a.TenantService, a.Logger)
+	providers := []services.MusicProvider{a.SpotifyService}
+	a.AppleMusic, err = services.NewAppleMusicService(cfg.AppleMusic, a.Logger)
+	if err != nil {
+		return err
+	}
+	if a.AppleMusic != nil {
+		providers = append(providers, a.AppleMusic)
+	}
+	if tidal := services.NewTidalService(cfg.Tidal, a.Logger); tidal != nil {
+		providers = append(providers, tidal)
+	}
+	if deezer := services.NewDeezerService(cfg.Deezer, a.Logger); deezer != nil {
+		providers = append(providers, deezer)
+	}
+	a.Providers = services.NewProviders(providers...)
+	a.ProfileService = services.NewProfileService(a.DB, a.Redis, a.SpotifyService, a.Providers, cfg.Cache, cfg.History, a.Logger)
+	a.MediaWebhooks = services.NewMediaWebhookService(a.DB, a.Logger)
+	a.Lyrics = services.NewLyricsService(cfg.Lyrics, a.Redis, a.Logger)
+	a.TrackLinks = services.NewTrackLinkService(cfg.Odesli, a.DB, a.Logger)
+	a.ShortLinks = services.NewShortLinkService(a.DB, a.Logger)
+	a.AlbumArt = services.NewAlbumArtService(cfg.Art, a.DB, a.Redis, a.Logger)
+	a.Badges = services.NewBadgeService(a.ProfileService, a.AlbumArt, a.Redis, a.Logger)
+	a.OGImages = services.NewOGImageService(a.ProfileService, a.AlbumArt, a.Redis, a.Logger)
+	a.Webhooks = services.NewWebhookService(cfg.Webhooks, a.DB, a.Logger)
+	a.SpotifyService.OnTrackChange(func(ctx context.Context, userID string, track *models.SpotifyCurrentlyPlaying) {
+		if err := a.Webhooks.EnqueueTrackChange(ctx, userID, track); err != nil {
+			a.Logger.Warn().Ctx(ctx).Err(err).Msg("Failed to queue webhook deliveries")
+		}
+	})
+	a.Scrobbles = services.NewScrobbleService(cfg.LastFM, cfg.ListenBrainz, cfg.Scrobbling, a.DB, a.Logger)
+	a.ProfileService.OnListen(func(ctx context.Context, listen *models.PlayEvent, album string) {
+		if err := a.Scrobbles.EnqueueListen(ctx, listen, album); err != nil {
+			a.Logger.Warn().Ctx(ctx).Err(err).Msg("Failed to queue scrobbles")
+		}
+	})
+	a.APIKeys = services.NewAPIKeyService(cfg.APIKeys, a.DB, a.Redis, a.Logger)
+	a.UsageReports = services.NewUsageReportService(a.DB, a.Logger)
+	a.Live.OnReload(func(next *config.Config) {
+		a.SpotifyService.UpdateCacheConfig(next.Cache)
+		a.ProfileService.UpdateCacheConfig(next.Cache)
+	})
+
+	a.Canary = canary.New(cfg.Canary, a.SpotifyService, a.Logger)
+	a.Alerts = alerting.New(cfg.Alerting, a.Logger)
+	a.Poller = poller.New(cfg.Poller, a.UserService, a.ProfileService, a.Logger)
+	a.Alerts.WatchBacklog("now_playing_poller", a.Poller.Backlog)
+	return nil
+}
+
+// ConnectDatabase opens PostgreSQL only, for commands that don't need Redis
+// or the services
+func (a *App) ConnectDatabase() error {
+	if a.DB != nil {
+		return nil
+	}
+	a.Logger.Info().Msg("Connecting to PostgreSQL")
+	db, err := database.NewPostgresConnection(a.Config.Database, a.Logger)
+	if err != nil {
+		return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+	a.DB = db
+	return nil
+}
+
+// Migrate applies database migrations. The database must be connected.
+func (a *App) Migrate() error {
+	a.Logger.Info().Msg("Running database migrations")
+	if err := database.RunMigrations(a.DB); err != nil {
+		return fmt.Errorf("failed to run database migrations: %w", err)
+	}
+	return nil
+}
+
+// Close releases connections and flushes pending error reports
+func (a *App) Close() {
+	if a.Redis != nil {
+		a.Redis.Close()
+	}
+	a.GeoIP.Close()
+	if a.DB != nil {
+		a.DB.Close()
+	}
+	a.Reporter.Flush(2 * time.Second)
+}
@@ -0,0 +1,118 @@
+package app
+
+import (
+	"context"
+	"time"
+)
+
+// Cleanup deletes profile visits and short link clicks older than visitDays
+// and track history, play events, and listening sessions older than
+// trackDays. Zero keeps that data. Visits are anonymized after
+// VISIT_ANONYMIZE_DAYS, expired sign-in sessions are always deleted, deleted
+// accounts after ACCOUNT_DELETION_GRACE_DAYS, finished
+// webhook deliveries after WEBHOOK_LOG_RETENTION_DAYS, and finished scrobbles
+// after SCROBBLE_RETENTION_DAYS.
+func (a *App) Cleanup(ctx context.Context, visitDays, trackDays int) error {
+	now := time.Now()
+
+	deleted, err := a.UserService.PurgeExpiredSessions(ctx)
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		a.Logger.Info().Int64("deleted", deleted).Msg("Purged expired sessions")
+	}
+
+	graceDays := a.Config.Retention.DeletedAccountDays
+	deleted, err = a.UserService.PurgeDeletedAccounts(ctx, now.AddDate(0, 0, -graceDays))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		a.Logger.Info().Int64("deleted", deleted).Int("older_than_days", graceDays).Msg("Purged deleted accounts")
+	}
+
+	if logDays := a.Config.Webhooks.LogRetentionDays; logDays > 0 {
+		deleted, err := a.Webhooks.PurgeDeliveries(ctx, now.AddDate(0, 0, -logDays))
+		if err != nil {
+			return err
+		}
+		if deleted > 0 {
+			a.Logger.Info().Int64("deleted", deleted).Int("older_than_days", logDays).Msg("Purged webhook deliveries")
+		}
+	}
+
+	if scrobbleDays := a.Config.Scrobbling.RetentionDays; scrobbleDays > 0 {
+		deleted, err := a.Scrobbles.PurgeScrobbles(ctx, now.AddDate(0, 0, -scrobbleDays))
+		if err != nil {
+			return err
+		}
+		if deleted > 0 {
+			a.Logger.Info().Int64("deleted", deleted).Int("older_than_days", scrobbleDays).Msg("Purged scrobbles")
+		}
+	}
+
+	if anonymizeDays := a.Config.Retention.VisitAnonymizeDays; anonymizeDays > 0 {
+		anonymized, err := a.UserService.AnonymizeProfileVisits(ctx, now.AddDate(0, 0, -anonymizeDays))
+		if err != nil {
+			return err
+		}
+		if anonymized > 0 {
+			a.Logger.Info().Int64("anonymized", anonymized).Int("older_than_days", anonymizeDays).Msg("Anonymized profile visits")
+		}
+	}
+
+	if visitDays > 0 {
+		deleted, err := a.UserService.PurgeProfileVisits(ctx, now.AddDate(0, 0, -visitDays))
+		if err != nil {
+			return err
+		}
+		a.Logger.Info().Int64("deleted", deleted).Int("older_than_days", visitDays).Msg("Purged profile visits")
+
+		deleted, err = a.ShortLinks.PurgeClicks(ctx, now.AddDate(0, 0, -visitDays))
+		if err != nil {
+			return err
+		}
+		a.Logger.Info().Int64("deleted", deleted).Int("older_than_days", visitDays).Msg("Purged short link clicks")
+	}
+	if trackDays > 0 {
+		deleted, err := a.ProfileService.PurgeTrackHistory(ctx, now.AddDate(0, 0, -trackDays))
+		if err != nil {
+			return err
+		}
+		a.Logger.Info().Int64("deleted", deleted).Int("older_than_days", trackDays).Msg("Purged track history")
+
+		deleted, err = a.ProfileService.PurgePlayEvents(ctx, now.AddDate(0, 0, -trackDays))
+		if err != nil {
+			return err
+		}
+		a.Logger.Info().Int64("deleted", deleted).Int("older_than_days", trackDays).Msg("Purged play events")
+
+		deleted, err = a.ProfileService.PurgeListeningSessions(ctx, now.AddDate(0, 0, -trackDays))
+		if err != nil {
+			return err
+		}
+		a.Logger.Info().Int64("deleted", deleted).Int("older_than_days", trackDays).Msg("Purged listening sessions")
+	}
+	return nil
+}
+
+// runCleanup applies the configured retention now and then every cleanup
+// interval until ctx is cancelled. Failures are logged and retried on the
+// next run.
+func (a *App) runCleanup(ctx context.Context) error {
+	ticker := time.NewTicker(time.Duration(a.Config.Jobs.CleanupIntervalMinutes) * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		retention := a.Config.Retention
+		if err := a.Cleanup(ctx, retention.VisitDays, retention.TrackDays); err != nil && ctx.Err() == nil {
+			a.Logger.Error().Err(err).Msg("Retention cleanup failed")
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,373 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/audit"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/introspect"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/lifecycle"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/utils"
+	"github.com/rs/zerolog"
+)
+
+// NewGroup creates the lifecycle group that starts and stops every
+// long-running subsystem
+func (a *App) NewGroup() *lifecycle.Group {
+	return lifecycle.New(a.ShutdownTimeout(), a.Reporter, a.Logger)
+}
+
+// ShutdownTimeout bounds how long each component gets to stop
+func (a *App) ShutdownTimeout() time.Duration {
+	return time.Duration(a.Config.Server.GracefulShutdownSeconds) * time.Second
+}
+
+// AddWorkers adds the background workers every process runs: Redis health
+// checks, cache invalidation, and runtime signal handling. Connect must have
+// been called.
+func (a *App) AddWorkers(group *lifecycle.Group) {
+	logger := a.Logger
+
+	// Watch Redis health so degraded mode recovers automatically
+	group.Add(lifecycle.Component{Name: "redis_health_check", Run: func(ctx context.Context) error {
+		a.Redis.StartHealthCheck(ctx, 5*time.Second, logger)
+		return nil
+	}})
+
+	// Keep in-process hot caches coherent across instances
+	group.Add(lifecycle.Component{Name: "cache_invalidation", Run: func(ctx context.Context) error {
+		services.WatchCacheInvalidations(ctx, a.Redis, a.SpotifyService, a.ProfileService, logger)
+		return nil
+	}})
+
+	group.Add(lifecycle.Component{Name: "runtime_signals", Run: a.handleSignals})
+}
+
+// AddJobs adds the background jobs that only need to run somewhere in the
+// deployment: the Spotify canary, alerting, now-playing polling, history
+// backfill, listening stats rollups, track link resolution, webhook delivery, scrobbling, retention
+// cleanup, API key usage rollups, and monthly usage reports. Connect must have been called.
+func (a *App) AddJobs(group *lifecycle.Group) {
+	// Exercise the Spotify token refresh and playback path with a test account
+	if a.Canary != nil {
+		group.Add(lifecycle.Component{Name: "spotify_canary", Run: func(ctx context.Context) error {
+			a.Canary.Run(ctx)
+			return nil
+		}})
+	}
+
+	// Notify on-call when Spotify or queue health crosses a threshold
+	if a.Alerts != nil {
+		group.Add(lifecycle.Component{Name: "alert_monitor", Run: func(ctx context.Context) error {
+			a.Alerts.Run(ctx)
+			return nil
+		}})
+	}
+
+	// Keep now-playing fresh for profiles that are being watched
+	if a.Poller != nil {
+		group.Add(lifecycle.Component{Name: "now_playing_poller", Run: func(ctx context.Context) error {
+			a.Poller.Run(ctx)
+			return nil
+		}})
+	}
+
+	// Merge recently played tracks into history for plays nobody watched
+	if a.Config.History.BackfillIntervalMinutes > 0 {
+		group.Add(lifecycle.Component{Name: "history_backfill", Run: a.runHistoryBackfill})
+	}
+
+	// Fetch audio features for tracks new to history
+	if a.Config.History.AudioFeaturesIntervalMinutes > 0 {
+		group.Add(lifecycle.Component{Name: "audio_features", Run: a.runAudioFeatures})
+	}
+
+	// Roll play events up into daily listening stats
+	if a.Config.History.StatsRollupIntervalMinutes > 0 {
+		group.Add(lifecycle.Component{Name: "listening_stats_rollup", Run: a.runStatsRollup})
+	}
+
+	// Look up other platforms' links for tracks new to history
+	if a.TrackLinks != nil {
+		group.Add(lifecycle.Component{Name: "track_links", Run: a.runTrackLinks})
+	}
+
+	// Send queued webhook deliveries and retry failed ones
+	if a.Config.Webhooks.DeliveryIntervalSeconds > 0 {
+		group.Add(lifecycle.Component{Name: "webhook_delivery", Run: a.runWebhookDeliveries})
+	}
+
+	// Submit queued scrobbles and retry ones the service couldn't take
+	if a.Config.Scrobbling.IntervalSeconds > 0 {
+		group.Add(lifecycle.Component{Name: "scrobble_submission", Run: a.runScrobbles})
+	}
+
+	// Delete visits and history past their retention period
+	group.Add(lifecycle.Component{Name: "retention_cleanup", Run: a.runCleanup})
+
+	// Copy API key usage counters from Redis into Postgres
+	group.Add(lifecycle.Component{Name: "api_key_usage_rollup", Run: a.runUsageRollup})
+
+	// Compile last month's anonymized usage report once the month ends
+	group.Add(lifecycle.Component{Name: "usage_report", Run: func(ctx context.Context) error {
+		a.UsageReports.Run(ctx)
+		return nil
+	}})
+}
+
+// runStatsRollup rolls up recent listening stats now and then every interval
+// until ctx is cancelled. Failures are logged and retried on the next run.
+func (a *App) runStatsRollup(ctx context.Context) error {
+	ticker := time.NewTicker(time.Duration(a.Config.History.StatsRollupIntervalMinutes) * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		lookback := time.Now().AddDate(0, 0, -a.Config.History.StatsRollupLookbackDays)
+		days, err := a.ProfileService.RollupListeningStats(ctx, lookback)
+		if err != nil && ctx.Err() == nil {
+			a.Logger.Error().Err(err).Msg("Listening stats rollup failed")
+		} else if days > 0 {
+			a.Logger.Debug().Int64("days", days).Msg("Rolled up listening stats")
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// runTrackLinks resolves links for new tracks every interval until ctx is
+// cancelled. Failures are logged and retried on the next run.
+func (a *App) runTrackLinks(ctx context.Context) error {
+	ticker := time.NewTicker(time.Duration(a.Config.Odesli.IntervalSeconds) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			resolved, err := a.TrackLinks.ResolveNew(ctx)
+			if err != nil && ctx.Err() == nil {
+				a.Logger.Error().Err(err).Msg("Track link resolution failed")
+				continue
+			}
+			if resolved > 0 {
+				a.Logger.Info().Int("tracks", resolved).Msg("Resolved track links")
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// runUsageRollup rolls up API key usage every rollup interval until ctx is
+// cancelled. Failures are logged and retried on the next run.
+func (a *App) runUsageRollup(ctx context.Context) error {
+	ticker := time.NewTicker(time.Duration(a.Config.APIKeys.UsageRollupMinutes) * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := a.APIKeys.RollupUsage(ctx); err != nil && ctx.Err() == nil {
+				a.Logger.Error().Err(err).Msg("API key usage rollup failed")
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// runHistoryBackfill backfills listening history every backfill interval
+// until ctx is cancelled. Failures are logged and retried on the next run.
+func (a *App) runHistoryBackfill(ctx context.Context) error {
+	ticker := time.NewTicker(time.Duration(a.Config.History.BackfillIntervalMinutes) * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			added, err := a.ProfileService.BackfillHistory(ctx, a.UserService)
+			if err != nil && ctx.Err() == nil {
+				a.Logger.Error().Err(err).Msg("History backfill failed")
+				continue
+			}
+			if added > 0 {
+				a.Logger.Info().Int("plays", added).Msg("Backfilled listening history")
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// runAudioFeatures enriches history with audio features every interval until
+// ctx is cancelled. Failures are logged and retried on the next run.
+func (a *App) runAudioFeatures(ctx context.Context) error {
+	ticker := time.NewTicker(time.Duration(a.Config.History.AudioFeaturesIntervalMinutes) * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			enriched, err := a.ProfileService.EnrichAudioFeatures(ctx, a.UserService)
+			if err != nil && ctx.Err() == nil {
+				a.Logger.Error().Err(err).Msg("Audio feature enrichment failed")
+				continue
+			}
+			if enriched > 0 {
+				a.Logger.Info().Int("tracks", enriched).Msg("Enriched tracks with audio features")
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// runWebhookDeliveries sends due webhook deliveries every delivery interval
+// until ctx is cancelled. Failures are logged and retried on the next run.
+func (a *App) runWebhookDeliveries(ctx context.Context) error {
+	ticker := time.NewTicker(time.Duration(a.Config.Webhooks.DeliveryIntervalSeconds) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			delivered, err := a.Webhooks.DeliverDue(ctx)
+			if err != nil && ctx.Err() == nil {
+				a.Logger.Error().Err(err).Msg("Webhook delivery failed")
+				continue
+			}
+			if delivered > 0 {
+				a.Logger.Debug().Int("deliveries", delivered).Msg("Delivered webhooks")
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// runScrobbles submits due scrobbles every scrobble interval until ctx is
+// cancelled. Failures are logged and retried on the next run.
+func (a *App) runScrobbles(ctx context.Context) error {
+	ticker := time.NewTicker(time.Duration(a.Config.Scrobbling.IntervalSeconds) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			submitted, err := a.Scrobbles.SubmitDue(ctx)
+			if err != nil && ctx.Err() == nil {
+				a.Logger.Error().Err(err).Msg("Scrobble submission failed")
+				continue
+			}
+			if submitted > 0 {
+				a.Logger.Debug().Int("scrobbles", submitted).Msg("Submitted scrobbles")
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// RunWorker runs the background workers and jobs, plus the admin listener for
+// metrics and debugging, until SIGINT or SIGTERM
+func (a *App) RunWorker(migrate bool) error {
+	if err := a.Connect(migrate); err != nil {
+		return err
+	}
+
+	group := a.NewGroup()
+	a.AddWorkers(group)
+	a.AddJobs(group)
+	a.RegisterStats()
+
+	adminServer := a.AdminServer()
+	group.Add(lifecycle.HTTPServer("admin_server", adminServer, a.ShutdownTimeout()))
+	a.Logger.Info().Msgf("Starting admin server on %s", adminServer.Addr)
+
+	if err := a.Run(group); err != nil {
+		return fmt.Errorf("worker stopped after a component failed: %w", err)
+	}
+	a.Logger.Info().Msg("Worker exiting")
+	return nil
+}
+
+// handleSignals applies runtime signals until ctx is cancelled. SIGHUP
+// reloads the non-critical configuration; SIGUSR1 turns on debug logging
+// everywhere and SIGUSR2 restores the configured levels.
+func (a *App) handleSignals(ctx context.Context) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case sig := <-signals:
+			var action string
+			switch sig {
+			case syscall.SIGHUP:
+				action = "reload_config"
+				if _, err := a.Live.Reload(); err != nil {
+					a.Logger.Error().Err(err).Msg("Failed to reload configuration")
+					continue
+				}
+				a.Logger.Warn().Msg("Configuration reloaded")
+			case syscall.SIGUSR1:
+				action = "set_log_level"
+				utils.SetAllLogLevels(zerolog.DebugLevel)
+				a.Logger.Warn().Interface("levels", utils.LogLevels()).Msg("Log levels changed")
+			default:
+				action = "reset_log_levels"
+				utils.ResetLogLevels()
+				a.Logger.Warn().Interface("levels", utils.LogLevels()).Msg("Log levels changed")
+			}
+			a.Audit.Log(ctx, audit.Event{
+				Type:   audit.EventAdminAction,
+				Reason: action,
+				Actor:  "signal",
+				Fields: map[string]interface{}{"signal": sig.String()},
+			})
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// RegisterStats adds the shared components to /debug/stats
+func (a *App) RegisterStats() {
+	introspect.Register("build", func() interface{} {
+		return a.Build
+	})
+	introspect.Register("caches", func() interface{} {
+		return map[string]interface{}{
+			"now_playing": a.SpotifyService.CacheStats(),
+			"profiles":    a.ProfileService.CacheStats(),
+		}
+	})
+	introspect.Register("redis", func() interface{} {
+		return map[string]bool{"available": a.Redis.Available()}
+	})
+	introspect.Register("postgres", func() interface{} {
+		return a.DB.Stats()
+	})
+	if a.Canary != nil {
+		introspect.Register("spotify_canary", func() interface{} {
+			result, _ := a.Canary.Status()
+			return result
+		})
+	}
+}
+
+// Run starts the group and blocks until SIGINT or SIGTERM, then shuts it down
+func (a *App) Run(group *lifecycle.Group) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	return group.Run(ctx)
+}
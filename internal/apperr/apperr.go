@@ -0,0 +1,133 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kind classifies an error so transports can map it to a status code
+type Kind string
+
+const (
+	KindInvalid       Kind = "invalid"
+	KindUnauthorized  Kind = "unauthorized"
+	KindForbidden     Kind = "forbidden"
+	KindNotFound      Kind = "not_found"
+	KindConflict      Kind = "conflict"
+	KindNotAcceptable Kind = "not_acceptable"
+	KindTooLarge      Kind = "too_large"
+	KindRateLimited   Kind = "rate_limited"
+	KindUnavailable   Kind = "unavailable"
+	KindInternal      Kind = "internal"
+)
+
+// Error is an application error carrying a machine-readable code and a
+// message that is safe to show to API clients
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Details interface{}
+	Err     error
+}
+
+// Error implements the error interface, including the wrapped cause
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// Unwrap returns the underlying cause
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WithDetails attaches structured details, such as field errors, to the error
+func (e *Error) WithDetails(details interface{}) *Error {
+	e.Details = details
+	return e
+}
+
+// Wrap attaches an underlying cause to the error
+func (e *Error) Wrap(err error) *Error {
+	e.Err = err
+	return e
+}
+
+// New creates an error of the given kind
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// Invalid reports a malformed or unacceptable request
+func Invalid(code, message string) *Error {
+	return New(KindInvalid, code, message)
+}
+
+// Unauthorized reports missing or invalid authentication
+func Unauthorized(code, message string) *Error {
+	return New(KindUnauthorized, code, message)
+}
+
+// Forbidden reports an authenticated caller that may not perform the action
+func Forbidden(code, message string) *Error {
+	return New(KindForbidden, code, message)
+}
+
+// NotFound reports a missing resource
+func NotFound(code, message string) *Error {
+	return New(KindNotFound, code, message)
+}
+
+// Conflict reports a request that conflicts with the current state
+func Conflict(code, message string) *Error {
+	return New(KindConflict, code, message)
+}
+
+// TooLarge reports a request body over the allowed size
+func TooLarge(code, message string) *Error {
+	return New(KindTooLarge, code, message)
+}
+
+// RateLimited reports a caller that exceeded a rate limit
+func RateLimited(code, message string) *Error {
+	return New(KindRateLimited, code, message)
+}
+
+// Unavailable reports a dependency that is temporarily unavailable
+func Unavailable(code, message string) *Error {
+	return New(KindUnavailable, code, message)
+}
+
+// Internal reports an unexpected failure, wrapping its cause
+func Internal(code, message string, err error) *Error {
+	return New(KindInternal, code, message).Wrap(err)
+}
+
+// As returns the *Error in err's chain, if any
+func As(err error) (*Error, bool) {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr, true
+	}
+	return nil, false
+}
+
+// From returns the *Error in err's chain if there is one, otherwise wraps err
+// as an internal error with the given code and message
+func From(err error, code, message string) *Error {
+	if appErr, ok := As(err); ok {
+		return appErr
+	}
+	return Internal(code, message, err)
+}
+
+// KindOf returns the kind of err, or KindInternal for untyped errors
+func KindOf(err error) Kind {
+	if appErr, ok := As(err); ok {
+		return appErr.Kind
+	}
+	return KindInternal
+}
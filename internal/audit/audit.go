@@ -0,0 +1,91 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/utils"
+	"github.com/rs/zerolog"
+)
+
+// Stream is the value of the log_stream field on every audit entry, so log
+// shippers can route security events separately from access logs
+const Stream = "security"
+
+// Event types
+const (
+	EventAuthFailure  = "auth_failure"
+	EventCSRFRejected = "csrf_rejected"
+	EventRateLimited  = "rate_limited"
+	EventAdminAction  = "admin_action"
+)
+
+// Event is one security-relevant occurrence
+type Event struct {
+	Type   string
+	Reason string
+	// Actor identifies the caller: a user ID, "admin", or empty when anonymous
+	Actor     string
+	IP        string
+	Method    string
+	Path      string
+	UserAgent string
+	Fields    map[string]interface{}
+}
+
+// Logger writes security events to a dedicated stream. A nil Logger discards
+// everything.
+type Logger struct {
+	logger zerolog.Logger
+}
+
+// New creates an audit logger writing JSON lines to cfg.Output: "stdout",
+// "stderr", or a file path opened for appending
+func New(cfg config.AuditConfig) (*Logger, error) {
+	var out io.Writer
+	switch cfg.Output {
+	case "", "stdout":
+		out = os.Stdout
+	case "stderr":
+		out = os.Stderr
+	default:
+		file, err := os.OpenFile(cfg.Output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+		out = file
+	}
+
+	// Audit events bypass module levels and sampling: none may be dropped
+	logger := zerolog.New(out).With().
+		Timestamp().
+		Str("log_stream", Stream).
+		Logger()
+	return &Logger{logger: logger}, nil
+}
+
+// Log records an event, tagged with the request ID from ctx
+func (l *Logger) Log(ctx context.Context, event Event) {
+	if l == nil {
+		return
+	}
+
+	entry := l.logger.Log().Str("event", event.Type)
+	for _, field := range [][2]string{
+		{"reason", event.Reason},
+		{"actor", event.Actor},
+		{"ip", event.IP},
+		{"method", event.Method},
+		{"path", event.Path},
+		{"user_agent", event.UserAgent},
+		{"request_id", utils.RequestIDFromContext(ctx)},
+	} {
+		if field[1] != "" {
+			entry = entry.Str(field[0], field[1])
+		}
+	}
+	entry.Fields(event.Fields).Send()
+}
@@ -0,0 +1,126 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// Cache is a small in-process TTL cache used in front of Redis for hot keys
+type Cache[V any] struct {
+	mu         sync.Mutex
+	items      map[string]entry[V]
+	ttl        time.Duration
+	maxEntries int
+	hits       uint64
+	misses     uint64
+}
+
+// Stats summarizes cache effectiveness since creation
+type Stats struct {
+	Entries int     `json:"entries"`
+	Hits    uint64  `json:"hits"`
+	Misses  uint64  `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+}
+
+type entry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// New creates a cache whose entries live for ttl; a non-positive ttl disables caching
+func New[V any](ttl time.Duration, maxEntries int) *Cache[V] {
+	return &Cache[V]{
+		items:      make(map[string]entry[V]),
+		ttl:        ttl,
+		maxEntries: maxEntries,
+	}
+}
+
+// Get returns the cached value for key if it has not expired
+func (c *Cache[V]) Get(key string) (V, bool) {
+	var zero V
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ttl <= 0 {
+		return zero, false
+	}
+	e, ok := c.items[key]
+	if !ok {
+		c.misses++
+		return zero, false
+	}
+	if time.Now().After(e.expiresAt) {
+		delete(c.items, key)
+		c.misses++
+		return zero, false
+	}
+	c.hits++
+	return e.value, true
+}
+
+// Set stores a value for key, evicting entries if the cache is full
+func (c *Cache[V]) Set(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ttl <= 0 {
+		return
+	}
+	if _, exists := c.items[key]; !exists && c.maxEntries > 0 && len(c.items) >= c.maxEntries {
+		c.evict()
+	}
+	c.items[key] = entry[V]{value: value, expiresAt: time.Now().Add(c.ttl)}
+}
+
+// SetLimits changes the TTL and size limit. Existing entries keep their
+// expiry; a non-positive ttl disables caching and drops them.
+func (c *Cache[V]) SetLimits(ttl time.Duration, maxEntries int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ttl = ttl
+	c.maxEntries = maxEntries
+	if ttl <= 0 {
+		clear(c.items)
+	}
+}
+
+// Stats returns hit and miss counts and the current number of entries
+func (c *Cache[V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := Stats{Entries: len(c.items), Hits: c.hits, Misses: c.misses}
+	if total := c.hits + c.misses; total > 0 {
+		stats.HitRate = float64(c.hits) / float64(total)
+	}
+	return stats
+}
+
+// Delete removes key from the cache
+func (c *Cache[V]) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.items, key)
+}
+
+// evict drops expired entries, and if none were expired drops an arbitrary one.
+// Callers must hold the lock.
+func (c *Cache[V]) evict() {
+	now := time.Now()
+	for key, e := range c.items {
+		if now.After(e.expiresAt) {
+			delete(c.items, key)
+		}
+	}
+	if len(c.items) < c.maxEntries {
+		return
+	}
+	for key := range c.items {
+		delete(c.items, key)
+		return
+	}
+}
@@ -0,0 +1,169 @@
+package canary
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/metrics"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/utils"
+	"github.com/rs/zerolog"
+)
+
+// Stages of a canary run, in order
+const (
+	StageTokenRefresh     = "token_refresh"
+	StageCurrentlyPlaying = "currently_playing"
+)
+
+var (
+	canaryRuns = metrics.NewCounterVec(
+		"spotify_canary_runs_total",
+		"Spotify canary runs by result (success, failure) and the stage that failed.",
+		"result", "stage",
+	)
+	canaryDuration = metrics.NewHistogramVec(
+		"spotify_canary_duration_seconds",
+		"Duration of each Spotify canary stage.",
+		[]float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		"stage",
+	)
+	canaryUp = metrics.NewGaugeVec(
+		"spotify_canary_up",
+		"1 if the last Spotify canary run succeeded, 0 otherwise.",
+	)
+	canaryLastSuccess = metrics.NewGaugeVec(
+		"spotify_canary_last_success_timestamp_seconds",
+		"Unix time of the last successful Spotify canary run.",
+	)
+)
+
+// Result is the outcome of one canary run
+type Result struct {
+	OK         bool      `json:"ok"`
+	CheckedAt  time.Time `json:"checked_at"`
+	DurationMs int64     `json:"duration_ms"`
+	// FailedStage and Error are set when the run failed
+	FailedStage string `json:"failed_stage,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// Canary periodically exercises the token refresh and currently-playing path
+// against a dedicated Spotify test account, so upstream breakage shows up in
+// health checks and metrics before users notice
+type Canary struct {
+	spotifyService *services.SpotifyService
+	interval       time.Duration
+	timeout        time.Duration
+	logger         zerolog.Logger
+
+	// refreshToken is replaced when Spotify rotates it
+	mu           sync.Mutex
+	refreshToken string
+
+	last atomic.Pointer[Result]
+}
+
+// New creates a canary, or returns nil when no test account is configured. A
+// nil Canary is safe to use and reports no status.
+func New(cfg config.CanaryConfig, spotifyService *services.SpotifyService, logger zerolog.Logger) *Canary {
+	if cfg.RefreshToken == "" {
+		return nil
+	}
+	return &Canary{
+		spotifyService: spotifyService,
+		interval:       time.Duration(cfg.IntervalSeconds) * time.Second,
+		timeout:        time.Duration(cfg.TimeoutSeconds) * time.Second,
+		logger:         utils.ModuleLogger(logger.With().Str("service", "spotify-canary").Logger(), utils.LogModuleSpotify),
+		refreshToken:   cfg.RefreshToken,
+	}
+}
+
+// Run checks immediately and then every interval until ctx is cancelled
+func (c *Canary) Run(ctx context.Context) {
+	if c == nil {
+		return
+	}
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.Check(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Check performs one canary run and records its result
+func (c *Canary) Check(ctx context.Context) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	stage, err := c.run(ctx)
+	duration := time.Since(start)
+	result := Result{OK: err == nil, CheckedAt: start, DurationMs: duration.Milliseconds()}
+
+	if err != nil {
+		result.FailedStage = stage
+		result.Error = err.Error()
+		canaryRuns.Inc("failure", stage)
+		canaryUp.Set(0)
+		c.logger.Error().Err(err).Str("stage", stage).Msg("Spotify canary failed")
+	} else {
+		canaryRuns.Inc("success", "")
+		canaryUp.Set(1)
+		canaryLastSuccess.Set(float64(start.Unix()))
+		c.logger.Debug().Dur("duration", duration).Msg("Spotify canary succeeded")
+	}
+
+	c.last.Store(&result)
+	return result
+}
+
+// run walks the stages, returning the one that failed
+func (c *Canary) run(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	refreshToken := c.refreshToken
+	c.mu.Unlock()
+
+	start := time.Now()
+	token, err := c.spotifyService.RefreshAccessToken(ctx, refreshToken)
+	canaryDuration.Observe(time.Since(start).Seconds(), StageTokenRefresh)
+	if err != nil {
+		return StageTokenRefresh, err
+	}
+	if token.RefreshToken != "" {
+		c.mu.Lock()
+		c.refreshToken = token.RefreshToken
+		c.mu.Unlock()
+	}
+
+	start = time.Now()
+	_, err = c.spotifyService.GetCurrentlyPlayingTrack(ctx, token.AccessToken)
+	canaryDuration.Observe(time.Since(start).Seconds(), StageCurrentlyPlaying)
+	if err != nil {
+		return StageCurrentlyPlaying, err
+	}
+	return "", nil
+}
+
+// Status returns the last result, and false if the canary is disabled or
+// hasn't run yet
+func (c *Canary) Status() (Result, bool) {
+	if c == nil {
+		return Result{}, false
+	}
+	last := c.last.Load()
+	if last == nil {
+		return Result{}, false
+	}
+	return *last, true
+}
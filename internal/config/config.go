@@ -1,18 +1,52 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Environment string
-	Server      ServerConfig
-	Database    DatabaseConfig
-	Redis       RedisConfig
-	Spotify     SpotifyConfig
+	Environment  string
+	Server       ServerConfig
+	GRPC         GRPCConfig
+	Admin        AdminConfig
+	Database     DatabaseConfig
+	Redis        RedisConfig
+	Spotify      SpotifyConfig
+	AppleMusic   AppleMusicConfig
+	Tidal        TidalConfig
+	Deezer       DeezerConfig
+	Lyrics       LyricsConfig
+	Odesli       OdesliConfig
+	Art          ArtConfig
+	Cache        CacheConfig
+	CORS         CORSConfig
+	HTTPCache    CacheControlConfig
+	Embed        EmbedConfig
+	BodyLimit    BodyLimitConfig
+	RateLimit    RateLimitConfig
+	Idempotency  IdempotencyConfig
+	Errors       ErrorReportingConfig
+	Logging      LoggingConfig
+	Audit        AuditConfig
+	Canary       CanaryConfig
+	Alerting     AlertingConfig
+	Jobs         JobsConfig
+	Retention    RetentionConfig
+	History      HistoryConfig
+	APIKeys      APIKeyConfig
+	Privacy      PrivacyConfig
+	Visits       VisitConfig
+	Poller       PollerConfig
+	Sessions     SessionConfig
+	Webhooks     WebhookConfig
+	LastFM       LastFMConfig
+	ListenBrainz ListenBrainzConfig
+	Scrobbling   ScrobbleConfig
 }
 
 // ServerConfig holds HTTP server configuration
@@ -24,6 +58,48 @@ type ServerConfig struct {
 	GracefulShutdownSeconds int
 }
 
+// GRPCConfig holds gRPC server configuration
+type GRPCConfig struct {
+	Enabled bool
+	Port    int
+}
+
+// AdminConfig holds the admin listener configuration. It serves metrics and
+// debugging endpoints and should stay bound to a private interface. Token
+// guards the profiling endpoints; they are disabled while it is empty.
+// MetricsToken, when set, is required to scrape metrics, for when the
+// listener is reachable beyond the host.
+type AdminConfig struct {
+	Host         string
+	Port         int
+	Token        string
+	MetricsToken string
+}
+
+// ErrorReportingConfig holds the Sentry-compatible error reporter settings.
+// Reporting is disabled while DSN is empty.
+type ErrorReportingConfig struct {
+	DSN         string
+	Environment string
+	Release     string
+}
+
+// LoggingConfig holds the startup log levels. Level is the default for every
+// module (empty keeps debug in development and info elsewhere); ModuleLevels
+// holds module=level overrides. DebugSampleEvery keeps one in every N debug
+// events.
+type LoggingConfig struct {
+	Level            string
+	ModuleLevels     []string
+	DebugSampleEvery int
+}
+
+// AuditConfig holds the security audit log settings. Output is "stdout",
+// "stderr", or a file path.
+type AuditConfig struct {
+	Output string
+}
+
 // DatabaseConfig holds database configuration
 type DatabaseConfig struct {
 	Host     string
@@ -32,6 +108,10 @@ type DatabaseConfig struct {
 	Password string
 	DBName   string
 	SSLMode  string
+
+	QueryTimeoutSeconds     int
+	StatementTimeoutSeconds int
+	SlowQueryMillis         int
 }
 
 // RedisConfig holds Redis configuration
@@ -40,6 +120,9 @@ type RedisConfig struct {
 	Port     int
 	Password string
 	DB       int
+
+	// KeyPrefix namespaces every key and channel, e.g. "wamlt:prod:"
+	KeyPrefix string
 }
 
 // SpotifyConfig holds Spotify API configuration
@@ -48,11 +131,287 @@ type SpotifyConfig struct {
 	ClientSecret string
 	RedirectURI  string
 	Scopes       []string
+
+	// Fake replaces Spotify with an in-process fake that cycles through
+	// generated tracks, each playing for FakeTrackSeconds. Development only.
+	Fake             bool
+	FakeTrackSeconds int
+}
+
+// AppleMusicConfig holds the Sign in with Apple and MusicKit settings. Apple
+// Music sign-in is offered when TeamID is set. One MusicKit private key with
+// Sign in with Apple enabled signs both, given inline or as a file path.
+type AppleMusicConfig struct {
+	TeamID         string
+	KeyID          string
+	PrivateKey     string
+	PrivateKeyFile string
+	ServicesID     string
+	RedirectURI    string
+}
+
+// TidalConfig holds TIDAL API configuration. TIDAL sign-in is offered when
+// ClientID is set.
+type TidalConfig struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURI  string
+	Scopes       []string
+}
+
+// DeezerConfig holds Deezer API configuration. Deezer sign-in is offered when
+// AppID is set. Perms are the Deezer permissions requested at sign-in.
+type DeezerConfig struct {
+	AppID       string
+	Secret      string
+	RedirectURI string
+	Perms       []string
 }
 
-// Load loads configuration from environment variables
+// LyricsConfig holds the lyrics lookup settings. APIURL is an LRCLIB-compatible
+// server; results, including misses, are cached per track for CacheHours.
+type LyricsConfig struct {
+	Enabled    bool
+	APIURL     string
+	CacheHours int
+}
+
+// OdesliConfig holds the cross-platform link lookup settings. Up to
+// BatchSize tracks new to history are resolved every IntervalSeconds; APIKey
+// is optional and raises Odesli's rate limit of 10 requests a minute.
+type OdesliConfig struct {
+	Enabled         bool
+	APIURL          string
+	APIKey          string
+	IntervalSeconds int
+	BatchSize       int
+}
+
+// ArtConfig holds the album art proxy settings. Only art on AllowedHosts is
+// fetched; an entry starting with "*." matches any subdomain.
+type ArtConfig struct {
+	Enabled        bool
+	AllowedHosts   []string
+	MaxSourceBytes int64
+	CacheHours     int
+}
+
+// CanaryConfig holds the Spotify canary settings. RefreshToken belongs to a
+// dedicated test account; the canary is disabled while it is empty.
+type CanaryConfig struct {
+	RefreshToken    string
+	IntervalSeconds int
+	TimeoutSeconds  int
+}
+
+// AlertingConfig holds the operational alert monitor settings. Alerts go to
+// a generic JSON webhook, a Slack incoming webhook, or both; the monitor is
+// disabled while neither is set. A threshold of 0 turns its rule off.
+type AlertingConfig struct {
+	WebhookURL      string
+	SlackWebhookURL string
+	IntervalSeconds int
+	RepeatMinutes   int
+
+	SpotifyErrorRatePercent int
+	SpotifyMinRequests      int
+	TokenRefreshFailures    int
+	QueueBacklog            int
+}
+
+// JobsConfig controls the background jobs that only need to run somewhere in
+// the deployment, rather than in every API process. InServer runs them in
+// serve too, for single-process deployments; turn it off when a dedicated
+// worker runs them.
+type JobsConfig struct {
+	InServer               bool
+	CleanupIntervalMinutes int
+}
+
+// RetentionConfig holds how long data is kept before cleanup deletes it. Zero
+// keeps it forever, except for DeletedAccountDays, the grace period before a
+// deleted account is purged, where zero purges on the next cleanup. Visits
+// older than VisitAnonymizeDays lose their IP address, user agent, and
+// referrer path ahead of deletion; zero keeps them until then.
+type RetentionConfig struct {
+	VisitDays          int
+	VisitAnonymizeDays int
+	TrackDays          int
+	DeletedAccountDays int
+}
+
+// HistoryConfig decides which plays make it into listening history. A play
+// counts once it lasts MinListenSeconds or MinListenPercent of the track;
+// zero turns that rule off, and with both off every play counts. Recently
+// played tracks are merged in every BackfillIntervalMinutes, audio features
+// fetched for new tracks every AudioFeaturesIntervalMinutes, and daily stats
+// rolled up every StatsRollupIntervalMinutes; 0 turns a job off. Each rollup
+// recomputes the last StatsRollupLookbackDays days.
+type HistoryConfig struct {
+	MinListenSeconds             int
+	MinListenPercent             int
+	BackfillIntervalMinutes      int
+	AudioFeaturesIntervalMinutes int
+	StatsRollupIntervalMinutes   int
+	StatsRollupLookbackDays      int
+}
+
+// APIKeyConfig holds API key quotas and how often their usage counters are
+// rolled from Redis into Postgres. A DailyQuota of zero is unlimited.
+type APIKeyConfig struct {
+	DailyQuota         int
+	UsageRollupMinutes int
+}
+
+// PrivacyConfig controls how visitors who ask not to be tracked are handled.
+// With HonorDoNotTrack, visitors sending DNT: 1 or Sec-GPC: 1 get no visit
+// record or visit cookie and are only counted anonymously in presence.
+type PrivacyConfig struct {
+	HonorDoNotTrack bool
+}
+
+// VisitConfig controls how profile visits are recorded. Page loads by the
+// same browser, recognized by its visitor cookie, within DedupeMinutes of
+// its last visit ending are folded into that visit; 0 records every load.
+// Visits are located with the MaxMind database at GeoIPDatabasePath; empty
+// leaves them unlocated.
+type VisitConfig struct {
+	DedupeMinutes     int
+	GeoIPDatabasePath string
+}
+
+// PollerConfig holds the now-playing poller settings. The poller refreshes
+// profiles with active viewers every interval so viewers are served from the
+// cache; it is disabled while IntervalSeconds is 0.
+type PollerConfig struct {
+	IntervalSeconds int
+	Concurrency     int
+	TimeoutSeconds  int
+}
+
+// SessionConfig controls sign-in sessions. A session lasts LifetimeDays
+// from when it was last used.
+type SessionConfig struct {
+	LifetimeDays int
+}
+
+// WebhookConfig holds outgoing webhook delivery settings. Due deliveries are
+// sent every DeliveryIntervalSeconds, 0 turns delivery off, and failures are
+// retried with backoff until MaxAttempts. Webhooks can only call public
+// addresses unless AllowPrivateNetworks. Delivery logs are kept for
+// LogRetentionDays; zero keeps them.
+type WebhookConfig struct {
+	DeliveryIntervalSeconds int
+	TimeoutSeconds          int
+	MaxAttempts             int
+	AllowPrivateNetworks    bool
+	LogRetentionDays        int
+}
+
+// LastFMConfig holds Last.fm API configuration. Users can connect Last.fm
+// for scrobbling when APIKey is set.
+type LastFMConfig struct {
+	APIKey      string
+	Secret      string
+	RedirectURI string
+}
+
+// ListenBrainzConfig holds ListenBrainz settings. Users connect with their
+// own token, so no app credentials are needed. APIURL can point at a
+// self-hosted server.
+type ListenBrainzConfig struct {
+	Enabled bool
+	APIURL  string
+}
+
+// ScrobbleConfig holds scrobble submission settings. Queued scrobbles are
+// submitted every IntervalSeconds, 0 turns submission off, and failures are
+// retried with backoff until MaxAttempts. Submitted scrobbles are kept for
+// RetentionDays; zero keeps them.
+type ScrobbleConfig struct {
+	IntervalSeconds int
+	MaxAttempts     int
+	RetentionDays   int
+}
+
+// CacheConfig holds in-process hot cache and Redis now-playing cache
+// configuration
+type CacheConfig struct {
+	HotTTLMillis         int
+	HotMaxEntries        int
+	NowPlayingTTLSeconds int
+}
+
+// CORSConfig holds cross-origin settings for the public API and embeds
+type CORSConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	AllowCredentials bool
+	MaxAgeSeconds    int
+}
+
+// CacheControlConfig holds HTTP caching headers per route policy
+type CacheControlConfig struct {
+	Policies map[string]CacheControlPolicy
+}
+
+// CacheControlPolicy is the Cache-Control header sent to browsers and the
+// Surrogate-Control header consumed (and stripped) by CDNs
+type CacheControlPolicy struct {
+	CacheControl     string
+	SurrogateControl string
+}
+
+// EmbedConfig holds options for embedding public data in third-party pages
+type EmbedConfig struct {
+	// JSONPEnabled allows ?callback= wrapping for script-tag embeds
+	JSONPEnabled bool
+	// BadgeCacheSeconds is how long rendered SVG badges are cached in Redis
+	BadgeCacheSeconds int
+	// OGImageCacheSeconds is how long rendered link preview images are
+	// cached in Redis
+	OGImageCacheSeconds int
+}
+
+// BodyLimitConfig caps request body sizes in bytes
+type BodyLimitConfig struct {
+	JSONBytes   int64
+	UploadBytes int64
+}
+
+// RateLimitConfig holds token-bucket rate limits per route policy
+type RateLimitConfig struct {
+	Enabled  bool
+	Policies map[string]RateLimitPolicy
+}
+
+// IdempotencyConfig holds how long Idempotency-Key responses are kept for replay
+type IdempotencyConfig struct {
+	TTLHours int
+}
+
+// RateLimitPolicy allows PerMinute requests on average with bursts up to Burst
+type RateLimitPolicy struct {
+	PerMinute int
+	Burst     int
+}
+
+// malformedEnv collects variables that fail to parse while Load runs
+var malformedEnv struct {
+	sync.Mutex
+	problems []string
+}
+
+// Load loads configuration from environment variables. Numeric and boolean
+// variables that don't parse are an error rather than silently falling back
+// to their defaults; call Validate for range and consistency checks.
 func Load() (*Config, error) {
-	return &Config{
+	malformedEnv.Lock()
+	defer malformedEnv.Unlock()
+	malformedEnv.problems = nil
+
+	cfg := &Config{
 		Environment: getEnv("APP_ENV", "development"),
 		Server: ServerConfig{
 			Port:                    getEnvAsInt("SERVER_PORT", 8080),
@@ -61,6 +420,29 @@ func Load() (*Config, error) {
 			IdleTimeoutSeconds:      getEnvAsInt("SERVER_IDLE_TIMEOUT", 60),
 			GracefulShutdownSeconds: getEnvAsInt("SERVER_SHUTDOWN_TIMEOUT", 30),
 		},
+		GRPC: GRPCConfig{
+			Enabled: getEnvAsBool("GRPC_ENABLED", false),
+			Port:    getEnvAsInt("GRPC_PORT", 9090),
+		},
+		Admin: AdminConfig{
+			Host:         getEnv("ADMIN_HOST", "127.0.0.1"),
+			Port:         getEnvAsInt("ADMIN_PORT", 9091),
+			Token:        getEnv("ADMIN_TOKEN", ""),
+			MetricsToken: getEnv("ADMIN_METRICS_TOKEN", ""),
+		},
+		Errors: ErrorReportingConfig{
+			DSN:         getEnv("SENTRY_DSN", ""),
+			Environment: getEnv("SENTRY_ENVIRONMENT", getEnv("APP_ENV", "development")),
+			Release:     getEnv("SENTRY_RELEASE", ""),
+		},
+		Logging: LoggingConfig{
+			Level:            getEnv("LOG_LEVEL", ""),
+			ModuleLevels:     getEnvAsSlice("LOG_MODULE_LEVELS", ""),
+			DebugSampleEvery: getEnvAsInt("LOG_DEBUG_SAMPLE_EVERY", 1),
+		},
+		Audit: AuditConfig{
+			Output: getEnv("AUDIT_LOG_OUTPUT", "stdout"),
+		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
 			Port:     getEnvAsInt("DB_PORT", 5432),
@@ -68,20 +450,206 @@ func Load() (*Config, error) {
 			Password: getEnv("DB_PASSWORD", "postgres"),
 			DBName:   getEnv("DB_NAME", "music_sharing"),
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
+
+			QueryTimeoutSeconds:     getEnvAsInt("DB_QUERY_TIMEOUT", 5),
+			StatementTimeoutSeconds: getEnvAsInt("DB_STATEMENT_TIMEOUT", 10),
+			SlowQueryMillis:         getEnvAsInt("DB_SLOW_QUERY_MS", 200),
 		},
 		Redis: RedisConfig{
 			Host:     getEnv("REDIS_HOST", "localhost"),
 			Port:     getEnvAsInt("REDIS_PORT", 6379),
 			Password: getEnv("REDIS_PASSWORD", ""),
 			DB:       getEnvAsInt("REDIS_DB", 0),
+
+			KeyPrefix: getEnv("REDIS_KEY_PREFIX", ""),
 		},
 		Spotify: SpotifyConfig{
 			ClientID:     getEnv("SPOTIFY_CLIENT_ID", ""),
 			ClientSecret: getEnv("SPOTIFY_CLIENT_SECRET", ""),
 			RedirectURI:  getEnv("SPOTIFY_REDIRECT_URI", "http://localhost:8080/auth/spotify/callback"),
-			Scopes:       strings.Split(getEnv("SPOTIFY_SCOPES", "user-read-private user-read-email user-read-currently-playing"), " "),
+			Scopes:       strings.Split(getEnv("SPOTIFY_SCOPES", "user-read-private user-read-email user-read-currently-playing user-read-recently-played"), " "),
+
+			Fake:             getEnvAsBool("DEV_FAKE_SPOTIFY", false),
+			FakeTrackSeconds: getEnvAsInt("DEV_FAKE_SPOTIFY_TRACK_SECONDS", 30),
+		},
+		AppleMusic: AppleMusicConfig{
+			TeamID:         getEnv("APPLE_TEAM_ID", ""),
+			KeyID:          getEnv("APPLE_KEY_ID", ""),
+			PrivateKey:     strings.ReplaceAll(getEnv("APPLE_PRIVATE_KEY", ""), `\n`, "\n"),
+			PrivateKeyFile: getEnv("APPLE_PRIVATE_KEY_FILE", ""),
+			ServicesID:     getEnv("APPLE_SERVICES_ID", ""),
+			RedirectURI:    getEnv("APPLE_REDIRECT_URI", "http://localhost:8080/auth/apple/callback"),
+		},
+		Tidal: TidalConfig{
+			ClientID:     getEnv("TIDAL_CLIENT_ID", ""),
+			ClientSecret: getEnv("TIDAL_CLIENT_SECRET", ""),
+			RedirectURI:  getEnv("TIDAL_REDIRECT_URI", "http://localhost:8080/auth/tidal/callback"),
+			Scopes:       strings.Fields(getEnv("TIDAL_SCOPES", "user.read")),
+		},
+		Deezer: DeezerConfig{
+			AppID:       getEnv("DEEZER_APP_ID", ""),
+			Secret:      getEnv("DEEZER_SECRET", ""),
+			RedirectURI: getEnv("DEEZER_REDIRECT_URI", "http://localhost:8080/auth/deezer/callback"),
+			Perms:       strings.Split(getEnv("DEEZER_PERMS", "basic_access,email,offline_access,listening_history"), ","),
+		},
+		Canary: CanaryConfig{
+			RefreshToken:    getEnv("SPOTIFY_CANARY_REFRESH_TOKEN", ""),
+			IntervalSeconds: getEnvAsInt("SPOTIFY_CANARY_INTERVAL_SECONDS", 300),
+			TimeoutSeconds:  getEnvAsInt("SPOTIFY_CANARY_TIMEOUT_SECONDS", 15),
+		},
+		Alerting: AlertingConfig{
+			WebhookURL:      getEnv("ALERT_WEBHOOK_URL", ""),
+			SlackWebhookURL: getEnv("ALERT_SLACK_WEBHOOK_URL", ""),
+			IntervalSeconds: getEnvAsInt("ALERT_INTERVAL_SECONDS", 60),
+			RepeatMinutes:   getEnvAsInt("ALERT_REPEAT_MINUTES", 60),
+
+			SpotifyErrorRatePercent: getEnvAsInt("ALERT_SPOTIFY_ERROR_RATE_PERCENT", 20),
+			SpotifyMinRequests:      getEnvAsInt("ALERT_SPOTIFY_MIN_REQUESTS", 20),
+			TokenRefreshFailures:    getEnvAsInt("ALERT_TOKEN_REFRESH_FAILURES", 10),
+			QueueBacklog:            getEnvAsInt("ALERT_QUEUE_BACKLOG", 1000),
+		},
+		Jobs: JobsConfig{
+			InServer:               getEnvAsBool("BACKGROUND_JOBS_IN_SERVER", true),
+			CleanupIntervalMinutes: getEnvAsInt("CLEANUP_INTERVAL_MINUTES", 60),
+		},
+		Retention: RetentionConfig{
+			VisitDays:          getEnvAsInt("VISIT_RETENTION_DAYS", 90),
+			VisitAnonymizeDays: getEnvAsInt("VISIT_ANONYMIZE_DAYS", 30),
+			TrackDays:          getEnvAsInt("TRACK_RETENTION_DAYS", 0),
+			DeletedAccountDays: getEnvAsInt("ACCOUNT_DELETION_GRACE_DAYS", 30),
+		},
+		History: HistoryConfig{
+			MinListenSeconds: getEnvAsInt("HISTORY_MIN_LISTEN_SECONDS", 30),
+			MinListenPercent: getEnvAsInt("HISTORY_MIN_LISTEN_PERCENT", 50),
+
+			BackfillIntervalMinutes:      getEnvAsInt("HISTORY_BACKFILL_INTERVAL_MINUTES", 30),
+			AudioFeaturesIntervalMinutes: getEnvAsInt("HISTORY_AUDIO_FEATURES_INTERVAL_MINUTES", 10),
+			StatsRollupIntervalMinutes:   getEnvAsInt("HISTORY_STATS_ROLLUP_INTERVAL_MINUTES", 15),
+			StatsRollupLookbackDays:      getEnvAsInt("HISTORY_STATS_ROLLUP_LOOKBACK_DAYS", 3),
+		},
+		Privacy: PrivacyConfig{
+			HonorDoNotTrack: getEnvAsBool("PRIVACY_HONOR_DNT", true),
 		},
-	}, nil
+		Visits: VisitConfig{
+			DedupeMinutes:     getEnvAsInt("VISIT_DEDUPE_MINUTES", 30),
+			GeoIPDatabasePath: getEnv("GEOIP_DATABASE_PATH", ""),
+		},
+		Poller: PollerConfig{
+			IntervalSeconds: getEnvAsInt("POLLER_INTERVAL_SECONDS", 15),
+			Concurrency:     getEnvAsInt("POLLER_CONCURRENCY", 8),
+			TimeoutSeconds:  getEnvAsInt("POLLER_TIMEOUT_SECONDS", 10),
+		},
+		Sessions: SessionConfig{
+			LifetimeDays: getEnvAsInt("SESSION_LIFETIME_DAYS", 30),
+		},
+		Webhooks: WebhookConfig{
+			DeliveryIntervalSeconds: getEnvAsInt("WEBHOOK_DELIVERY_INTERVAL_SECONDS", 5),
+			TimeoutSeconds:          getEnvAsInt("WEBHOOK_TIMEOUT_SECONDS", 10),
+			MaxAttempts:             getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 6),
+			AllowPrivateNetworks:    getEnvAsBool("WEBHOOK_ALLOW_PRIVATE_NETWORKS", false),
+			LogRetentionDays:        getEnvAsInt("WEBHOOK_LOG_RETENTION_DAYS", 30),
+		},
+		LastFM: LastFMConfig{
+			APIKey:      getEnv("LASTFM_API_KEY", ""),
+			Secret:      getEnv("LASTFM_SECRET", ""),
+			RedirectURI: getEnv("LASTFM_REDIRECT_URI", "http://localhost:8080/auth/lastfm/callback"),
+		},
+		ListenBrainz: ListenBrainzConfig{
+			Enabled: getEnvAsBool("LISTENBRAINZ_ENABLED", true),
+			APIURL:  getEnv("LISTENBRAINZ_API_URL", "https://api.listenbrainz.org"),
+		},
+		Scrobbling: ScrobbleConfig{
+			IntervalSeconds: getEnvAsInt("SCROBBLE_INTERVAL_SECONDS", 30),
+			MaxAttempts:     getEnvAsInt("SCROBBLE_MAX_ATTEMPTS", 10),
+			RetentionDays:   getEnvAsInt("SCROBBLE_RETENTION_DAYS", 30),
+		},
+		APIKeys: APIKeyConfig{
+			DailyQuota:         getEnvAsInt("API_KEY_DAILY_QUOTA", 10000),
+			UsageRollupMinutes: getEnvAsInt("API_KEY_USAGE_ROLLUP_MINUTES", 5),
+		},
+		Cache: CacheConfig{
+			HotTTLMillis:         getEnvAsInt("HOT_CACHE_TTL_MS", 500),
+			HotMaxEntries:        getEnvAsInt("HOT_CACHE_MAX_ENTRIES", 10000),
+			NowPlayingTTLSeconds: getEnvAsInt("NOW_PLAYING_CACHE_TTL_SECONDS", 120),
+		},
+		CORS: CORSConfig{
+			AllowedOrigins:   getEnvAsSlice("CORS_ALLOWED_ORIGINS", "*"),
+			AllowedMethods:   getEnvAsSlice("CORS_ALLOWED_METHODS", "GET,HEAD,POST,OPTIONS"),
+			AllowedHeaders:   getEnvAsSlice("CORS_ALLOWED_HEADERS", "Content-Type,If-None-Match,If-Modified-Since,API-Version,X-Request-ID"),
+			AllowCredentials: getEnvAsBool("CORS_ALLOW_CREDENTIALS", false),
+			MaxAgeSeconds:    getEnvAsInt("CORS_MAX_AGE", 600),
+		},
+		HTTPCache: CacheControlConfig{
+			Policies: map[string]CacheControlPolicy{
+				"public": {
+					CacheControl:     getEnv("CACHE_CONTROL_PUBLIC", "public, max-age=5, stale-while-revalidate=10"),
+					SurrogateControl: getEnv("SURROGATE_CONTROL_PUBLIC", "max-age=5"),
+				},
+				"private": {
+					CacheControl: getEnv("CACHE_CONTROL_PRIVATE", "no-store"),
+				},
+				"docs": {
+					CacheControl: getEnv("CACHE_CONTROL_DOCS", "public, max-age=3600"),
+				},
+				"static": {
+					CacheControl:     getEnv("CACHE_CONTROL_STATIC", "public, max-age=86400"),
+					SurrogateControl: getEnv("SURROGATE_CONTROL_STATIC", "max-age=604800"),
+				},
+			},
+		},
+		Embed: EmbedConfig{
+			JSONPEnabled:        getEnvAsBool("JSONP_ENABLED", false),
+			BadgeCacheSeconds:   getEnvAsInt("BADGE_CACHE_SECONDS", 15),
+			OGImageCacheSeconds: getEnvAsInt("OG_IMAGE_CACHE_SECONDS", 60),
+		},
+		BodyLimit: BodyLimitConfig{
+			JSONBytes:   int64(getEnvAsInt("MAX_JSON_BODY_BYTES", 64<<10)),
+			UploadBytes: int64(getEnvAsInt("MAX_UPLOAD_BODY_BYTES", 10<<20)),
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: getEnvAsBool("RATE_LIMIT_ENABLED", true),
+			Policies: map[string]RateLimitPolicy{
+				"api": {
+					PerMinute: getEnvAsInt("RATE_LIMIT_API_PER_MINUTE", 120),
+					Burst:     getEnvAsInt("RATE_LIMIT_API_BURST", 30),
+				},
+				"public": {
+					PerMinute: getEnvAsInt("RATE_LIMIT_PUBLIC_PER_MINUTE", 300),
+					Burst:     getEnvAsInt("RATE_LIMIT_PUBLIC_BURST", 60),
+				},
+				"refresh": {
+					PerMinute: getEnvAsInt("RATE_LIMIT_REFRESH_PER_MINUTE", 6),
+					Burst:     getEnvAsInt("RATE_LIMIT_REFRESH_BURST", 3),
+				},
+			},
+		},
+		Idempotency: IdempotencyConfig{
+			TTLHours: getEnvAsInt("IDEMPOTENCY_TTL_HOURS", 24),
+		},
+		Lyrics: LyricsConfig{
+			Enabled:    getEnvAsBool("LYRICS_ENABLED", true),
+			APIURL:     getEnv("LYRICS_API_URL", "https://lrclib.net/api"),
+			CacheHours: getEnvAsInt("LYRICS_CACHE_HOURS", 168),
+		},
+		Odesli: OdesliConfig{
+			Enabled:         getEnvAsBool("ODESLI_ENABLED", true),
+			APIURL:          getEnv("ODESLI_API_URL", "https://api.song.link/v1-alpha.1"),
+			APIKey:          getEnv("ODESLI_API_KEY", ""),
+			IntervalSeconds: getEnvAsInt("ODESLI_INTERVAL_SECONDS", 60),
+			BatchSize:       getEnvAsInt("ODESLI_BATCH_SIZE", 8),
+		},
+		Art: ArtConfig{
+			Enabled:        getEnvAsBool("ART_PROXY_ENABLED", true),
+			AllowedHosts:   getEnvAsSlice("ART_PROXY_ALLOWED_HOSTS", "i.scdn.co,*.mzstatic.com,*.dzcdn.net,resources.tidal.com"),
+			MaxSourceBytes: int64(getEnvAsInt("ART_MAX_SOURCE_BYTES", 5<<20)),
+			CacheHours:     getEnvAsInt("ART_CACHE_HOURS", 720),
+		},
+	}
+
+	if len(malformedEnv.problems) > 0 {
+		return nil, &ValidationError{Problems: malformedEnv.problems}
+	}
+	return cfg, nil
 }
 
 // Helper functions for reading environment variables
@@ -97,5 +665,34 @@ func getEnvAsInt(key string, defaultValue int) int {
 	if value, err := strconv.Atoi(valueStr); err == nil {
 		return value
 	}
+	reportMalformed(key, valueStr, "an integer")
+	return defaultValue
+}
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	reportMalformed(key, valueStr, "a boolean")
 	return defaultValue
 }
+
+// reportMalformed records a set but unparseable variable. Callers must be
+// inside Load.
+func reportMalformed(key, value, want string) {
+	if value != "" {
+		malformedEnv.problems = append(malformedEnv.problems, fmt.Sprintf("%s must be %s, got %q", key, want, value))
+	}
+}
+
+// getEnvAsSlice splits a comma-separated variable, dropping empty entries
+func getEnvAsSlice(key, defaultValue string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, defaultValue), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
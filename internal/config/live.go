@@ -0,0 +1,114 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/joho/godotenv"
+)
+
+// Live holds the running configuration and swaps in new values on Reload.
+// Only the non-critical sections (Cache, CORS, RateLimit, Embed) change on
+// reload; everything else keeps its startup value until restart.
+type Live struct {
+	current atomic.Pointer[Config]
+
+	mu      sync.Mutex
+	envFile string
+	// fromFile tracks the variables that came from envFile rather than the
+	// process environment; only those follow edits to the file
+	fromFile    map[string]bool
+	subscribers []func(*Config)
+}
+
+// NewLive wraps the startup configuration. envFile is re-read on every
+// reload; variables set in the process environment take precedence over it.
+func NewLive(cfg *Config, envFile string) *Live {
+	l := &Live{envFile: envFile, fromFile: map[string]bool{}}
+	l.current.Store(cfg)
+
+	// godotenv.Load never overrides the process environment, so a variable
+	// whose value matches the file came from it
+	values, _ := godotenv.Read(envFile)
+	for key, value := range values {
+		if current, ok := os.LookupEnv(key); ok && current == value {
+			l.fromFile[key] = true
+		}
+	}
+	return l
+}
+
+// Get returns the current configuration. Callers should not hold on to it
+// across requests.
+func (l *Live) Get() *Config {
+	return l.current.Load()
+}
+
+// OnReload registers fn to be called with the new configuration after every
+// successful reload
+func (l *Live) OnReload(fn func(*Config)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.subscribers = append(l.subscribers, fn)
+}
+
+// Reload re-reads the environment file and applies the reloadable sections
+func (l *Live) Reload() (*Config, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.refreshEnvFile(); err != nil {
+		return nil, err
+	}
+	loaded, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	// Only the reloadable sections are swapped in, so only they can make a
+	// reload fail; the rest was validated at startup
+	v := &validator{}
+	loaded.validateReloadable(v)
+	if len(v.problems) > 0 {
+		return nil, &ValidationError{Problems: v.problems}
+	}
+
+	next := *l.current.Load()
+	next.Cache = loaded.Cache
+	next.CORS = loaded.CORS
+	next.RateLimit = loaded.RateLimit
+	next.Embed = loaded.Embed
+	l.current.Store(&next)
+
+	for _, fn := range l.subscribers {
+		fn(&next)
+	}
+	return &next, nil
+}
+
+// refreshEnvFile applies the current contents of the environment file to the
+// variables it owns. Callers must hold the lock.
+func (l *Live) refreshEnvFile() error {
+	values, err := godotenv.Read(l.envFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read %s: %w", l.envFile, err)
+	}
+
+	for key := range l.fromFile {
+		if _, ok := values[key]; !ok {
+			os.Unsetenv(key)
+			delete(l.fromFile, key)
+		}
+	}
+	for key, value := range values {
+		if _, set := os.LookupEnv(key); set && !l.fromFile[key] {
+			continue
+		}
+		os.Setenv(key, value)
+		l.fromFile[key] = true
+	}
+	return nil
+}
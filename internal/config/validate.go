@@ -0,0 +1,275 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ValidationError lists every configuration problem found, so one restart
+// fixes them all
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// validator accumulates problems found by Validate
+type validator struct {
+	problems []string
+}
+
+func (v *validator) addf(format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+func (v *validator) required(key, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.addf("%s is required", key)
+	}
+}
+
+func (v *validator) port(key string, value int) {
+	if value < 1 || value > 65535 {
+		v.addf("%s must be between 1 and 65535, got %d", key, value)
+	}
+}
+
+func (v *validator) positive(key string, value int) {
+	if value <= 0 {
+		v.addf("%s must be greater than 0, got %d", key, value)
+	}
+}
+
+func (v *validator) nonNegative(key string, value int) {
+	if value < 0 {
+		v.addf("%s must not be negative, got %d", key, value)
+	}
+}
+
+// url checks for an absolute http(s) URL; empty values are left to required
+func (v *validator) url(key, value string) {
+	if value == "" {
+		return
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.addf("%s must be an absolute http or https URL, got %q", key, value)
+	}
+}
+
+// Validate checks for missing secrets, malformed URLs, and out-of-range
+// values, returning a *ValidationError that lists all of them
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	v.port("SERVER_PORT", c.Server.Port)
+	v.positive("SERVER_READ_TIMEOUT", c.Server.ReadTimeoutSeconds)
+	v.positive("SERVER_WRITE_TIMEOUT", c.Server.WriteTimeoutSeconds)
+	v.positive("SERVER_IDLE_TIMEOUT", c.Server.IdleTimeoutSeconds)
+	v.positive("SERVER_SHUTDOWN_TIMEOUT", c.Server.GracefulShutdownSeconds)
+
+	v.port("ADMIN_PORT", c.Admin.Port)
+	if c.Admin.Port == c.Server.Port {
+		v.addf("ADMIN_PORT must differ from SERVER_PORT, both are %d", c.Server.Port)
+	}
+	if c.GRPC.Enabled {
+		v.port("GRPC_PORT", c.GRPC.Port)
+		if c.GRPC.Port == c.Server.Port || c.GRPC.Port == c.Admin.Port {
+			v.addf("GRPC_PORT must differ from SERVER_PORT and ADMIN_PORT, got %d", c.GRPC.Port)
+		}
+	}
+
+	v.required("DB_HOST", c.Database.Host)
+	v.port("DB_PORT", c.Database.Port)
+	v.required("DB_USER", c.Database.User)
+	v.required("DB_NAME", c.Database.DBName)
+	switch c.Database.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		v.addf("DB_SSLMODE must be one of disable, allow, prefer, require, verify-ca, verify-full, got %q", c.Database.SSLMode)
+	}
+	v.positive("DB_QUERY_TIMEOUT", c.Database.QueryTimeoutSeconds)
+	v.positive("DB_STATEMENT_TIMEOUT", c.Database.StatementTimeoutSeconds)
+	v.nonNegative("DB_SLOW_QUERY_MS", c.Database.SlowQueryMillis)
+
+	v.required("REDIS_HOST", c.Redis.Host)
+	v.port("REDIS_PORT", c.Redis.Port)
+	v.nonNegative("REDIS_DB", c.Redis.DB)
+
+	if c.Spotify.Fake {
+		if c.Environment == "production" {
+			v.addf("DEV_FAKE_SPOTIFY cannot be enabled when APP_ENV is production")
+		}
+		v.positive("DEV_FAKE_SPOTIFY_TRACK_SECONDS", c.Spotify.FakeTrackSeconds)
+	} else {
+		v.required("SPOTIFY_CLIENT_ID", c.Spotify.ClientID)
+		v.required("SPOTIFY_CLIENT_SECRET", c.Spotify.ClientSecret)
+	}
+	v.required("SPOTIFY_REDIRECT_URI", c.Spotify.RedirectURI)
+	v.url("SPOTIFY_REDIRECT_URI", c.Spotify.RedirectURI)
+
+	if c.AppleMusic.TeamID != "" {
+		v.required("APPLE_KEY_ID", c.AppleMusic.KeyID)
+		v.required("APPLE_SERVICES_ID", c.AppleMusic.ServicesID)
+		if c.AppleMusic.PrivateKey == "" && c.AppleMusic.PrivateKeyFile == "" {
+			v.addf("APPLE_PRIVATE_KEY or APPLE_PRIVATE_KEY_FILE is required when APPLE_TEAM_ID is set")
+		}
+		v.required("APPLE_REDIRECT_URI", c.AppleMusic.RedirectURI)
+		v.url("APPLE_REDIRECT_URI", c.AppleMusic.RedirectURI)
+	}
+
+	if c.Tidal.ClientID != "" {
+		v.required("TIDAL_CLIENT_SECRET", c.Tidal.ClientSecret)
+		v.required("TIDAL_REDIRECT_URI", c.Tidal.RedirectURI)
+		v.url("TIDAL_REDIRECT_URI", c.Tidal.RedirectURI)
+	}
+
+	if c.Deezer.AppID != "" {
+		v.required("DEEZER_SECRET", c.Deezer.Secret)
+		v.required("DEEZER_REDIRECT_URI", c.Deezer.RedirectURI)
+		v.url("DEEZER_REDIRECT_URI", c.Deezer.RedirectURI)
+	}
+
+	if c.LastFM.APIKey != "" {
+		v.required("LASTFM_SECRET", c.LastFM.Secret)
+		v.required("LASTFM_REDIRECT_URI", c.LastFM.RedirectURI)
+		v.url("LASTFM_REDIRECT_URI", c.LastFM.RedirectURI)
+	}
+
+	if c.ListenBrainz.Enabled {
+		v.required("LISTENBRAINZ_API_URL", c.ListenBrainz.APIURL)
+		v.url("LISTENBRAINZ_API_URL", c.ListenBrainz.APIURL)
+	}
+
+	if c.Canary.RefreshToken != "" {
+		v.positive("SPOTIFY_CANARY_INTERVAL_SECONDS", c.Canary.IntervalSeconds)
+		v.positive("SPOTIFY_CANARY_TIMEOUT_SECONDS", c.Canary.TimeoutSeconds)
+	}
+
+	v.url("ALERT_WEBHOOK_URL", c.Alerting.WebhookURL)
+	v.url("ALERT_SLACK_WEBHOOK_URL", c.Alerting.SlackWebhookURL)
+	if c.Alerting.WebhookURL != "" || c.Alerting.SlackWebhookURL != "" {
+		v.positive("ALERT_INTERVAL_SECONDS", c.Alerting.IntervalSeconds)
+		v.nonNegative("ALERT_REPEAT_MINUTES", c.Alerting.RepeatMinutes)
+		if p := c.Alerting.SpotifyErrorRatePercent; p < 0 || p > 100 {
+			v.addf("ALERT_SPOTIFY_ERROR_RATE_PERCENT must be between 0 and 100, got %d", p)
+		}
+		v.nonNegative("ALERT_SPOTIFY_MIN_REQUESTS", c.Alerting.SpotifyMinRequests)
+		v.nonNegative("ALERT_TOKEN_REFRESH_FAILURES", c.Alerting.TokenRefreshFailures)
+		v.nonNegative("ALERT_QUEUE_BACKLOG", c.Alerting.QueueBacklog)
+	}
+
+	v.positive("CLEANUP_INTERVAL_MINUTES", c.Jobs.CleanupIntervalMinutes)
+	v.nonNegative("VISIT_RETENTION_DAYS", c.Retention.VisitDays)
+	v.nonNegative("VISIT_ANONYMIZE_DAYS", c.Retention.VisitAnonymizeDays)
+	v.nonNegative("TRACK_RETENTION_DAYS", c.Retention.TrackDays)
+	v.nonNegative("ACCOUNT_DELETION_GRACE_DAYS", c.Retention.DeletedAccountDays)
+	v.nonNegative("HISTORY_MIN_LISTEN_SECONDS", c.History.MinListenSeconds)
+	if p := c.History.MinListenPercent; p < 0 || p > 100 {
+		v.addf("HISTORY_MIN_LISTEN_PERCENT must be between 0 and 100, got %d", p)
+	}
+	v.nonNegative("HISTORY_BACKFILL_INTERVAL_MINUTES", c.History.BackfillIntervalMinutes)
+	v.nonNegative("HISTORY_AUDIO_FEATURES_INTERVAL_MINUTES", c.History.AudioFeaturesIntervalMinutes)
+	v.nonNegative("HISTORY_STATS_ROLLUP_INTERVAL_MINUTES", c.History.StatsRollupIntervalMinutes)
+	v.positive("HISTORY_STATS_ROLLUP_LOOKBACK_DAYS", c.History.StatsRollupLookbackDays)
+	v.nonNegative("API_KEY_DAILY_QUOTA", c.APIKeys.DailyQuota)
+	v.positive("API_KEY_USAGE_ROLLUP_MINUTES", c.APIKeys.UsageRollupMinutes)
+
+	v.positive("SESSION_LIFETIME_DAYS", c.Sessions.LifetimeDays)
+	v.nonNegative("VISIT_DEDUPE_MINUTES", c.Visits.DedupeMinutes)
+	v.nonNegative("WEBHOOK_DELIVERY_INTERVAL_SECONDS", c.Webhooks.DeliveryIntervalSeconds)
+	if c.Webhooks.DeliveryIntervalSeconds > 0 {
+		v.positive("WEBHOOK_TIMEOUT_SECONDS", c.Webhooks.TimeoutSeconds)
+		v.positive("WEBHOOK_MAX_ATTEMPTS", c.Webhooks.MaxAttempts)
+	}
+	v.nonNegative("WEBHOOK_LOG_RETENTION_DAYS", c.Webhooks.LogRetentionDays)
+	v.nonNegative("SCROBBLE_INTERVAL_SECONDS", c.Scrobbling.IntervalSeconds)
+	if c.Scrobbling.IntervalSeconds > 0 {
+		v.positive("SCROBBLE_MAX_ATTEMPTS", c.Scrobbling.MaxAttempts)
+	}
+	v.nonNegative("SCROBBLE_RETENTION_DAYS", c.Scrobbling.RetentionDays)
+	v.nonNegative("POLLER_INTERVAL_SECONDS", c.Poller.IntervalSeconds)
+	if c.Poller.IntervalSeconds > 0 {
+		v.positive("POLLER_CONCURRENCY", c.Poller.Concurrency)
+		v.positive("POLLER_TIMEOUT_SECONDS", c.Poller.TimeoutSeconds)
+	}
+
+	if c.Errors.DSN != "" {
+		if u, err := url.Parse(c.Errors.DSN); err != nil || u.Scheme == "" || u.Host == "" || u.User == nil {
+			v.addf("SENTRY_DSN must look like https://<key>@<host>/<project>")
+		}
+	}
+	v.positive("LOG_DEBUG_SAMPLE_EVERY", c.Logging.DebugSampleEvery)
+	v.required("AUDIT_LOG_OUTPUT", c.Audit.Output)
+	if c.BodyLimit.JSONBytes <= 0 {
+		v.addf("MAX_JSON_BODY_BYTES must be greater than 0, got %d", c.BodyLimit.JSONBytes)
+	}
+	if c.BodyLimit.UploadBytes <= 0 {
+		v.addf("MAX_UPLOAD_BODY_BYTES must be greater than 0, got %d", c.BodyLimit.UploadBytes)
+	}
+	v.positive("IDEMPOTENCY_TTL_HOURS", c.Idempotency.TTLHours)
+
+	if c.Lyrics.Enabled {
+		v.required("LYRICS_API_URL", c.Lyrics.APIURL)
+		v.url("LYRICS_API_URL", c.Lyrics.APIURL)
+		v.positive("LYRICS_CACHE_HOURS", c.Lyrics.CacheHours)
+	}
+
+	if c.Odesli.Enabled {
+		v.required("ODESLI_API_URL", c.Odesli.APIURL)
+		v.url("ODESLI_API_URL", c.Odesli.APIURL)
+		v.positive("ODESLI_INTERVAL_SECONDS", c.Odesli.IntervalSeconds)
+		v.positive("ODESLI_BATCH_SIZE", c.Odesli.BatchSize)
+	}
+
+	if c.Art.Enabled {
+		if len(c.Art.AllowedHosts) == 0 {
+			v.addf("ART_PROXY_ALLOWED_HOSTS must list at least one host when ART_PROXY_ENABLED is true")
+		}
+		if c.Art.MaxSourceBytes <= 0 {
+			v.addf("ART_MAX_SOURCE_BYTES must be greater than 0, got %d", c.Art.MaxSourceBytes)
+		}
+		v.positive("ART_CACHE_HOURS", c.Art.CacheHours)
+	}
+
+	c.validateReloadable(v)
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+// validateReloadable checks the sections that Live.Reload can swap in
+func (c *Config) validateReloadable(v *validator) {
+	v.nonNegative("HOT_CACHE_TTL_MS", c.Cache.HotTTLMillis)
+	v.nonNegative("HOT_CACHE_MAX_ENTRIES", c.Cache.HotMaxEntries)
+	v.positive("NOW_PLAYING_CACHE_TTL_SECONDS", c.Cache.NowPlayingTTLSeconds)
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin != "*" {
+			v.url("CORS_ALLOWED_ORIGINS", origin)
+		}
+	}
+	v.nonNegative("CORS_MAX_AGE", c.CORS.MaxAgeSeconds)
+	v.nonNegative("BADGE_CACHE_SECONDS", c.Embed.BadgeCacheSeconds)
+	v.nonNegative("OG_IMAGE_CACHE_SECONDS", c.Embed.OGImageCacheSeconds)
+
+	if c.RateLimit.Enabled {
+		names := make([]string, 0, len(c.RateLimit.Policies))
+		for name := range c.RateLimit.Policies {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			policy := c.RateLimit.Policies[name]
+			prefix := "RATE_LIMIT_" + strings.ToUpper(name)
+			v.positive(prefix+"_PER_MINUTE", policy.PerMinute)
+			v.positive(prefix+"_BURST", policy.Burst)
+		}
+	}
+}
@@ -1,21 +1,39 @@
 package database
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"time"
 
 	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/utils"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq" // PostgreSQL driver
+	"github.com/rs/zerolog"
 )
 
+// DB wraps sqlx.DB, bounds every query with the configured query timeout, and
+// logs queries slower than the slow query threshold
+type DB struct {
+	*sqlx.DB
+	queryTimeout       time.Duration
+	slowQueryThreshold time.Duration
+	logger             zerolog.Logger
+}
+
 // NewPostgresConnection establishes a connection to the PostgreSQL database
-func NewPostgresConnection(cfg config.DatabaseConfig) (*sqlx.DB, error) {
+func NewPostgresConnection(cfg config.DatabaseConfig, logger zerolog.Logger) (*DB, error) {
 	dsn := fmt.Sprintf(
 		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode,
 	)
 
+	// Let the server abort runaway statements even if the client goes away
+	if cfg.StatementTimeoutSeconds > 0 {
+		dsn += fmt.Sprintf(" statement_timeout=%d", cfg.StatementTimeoutSeconds*1000)
+	}
+
 	db, err := sqlx.Connect("postgres", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
@@ -31,11 +49,72 @@ func NewPostgresConnection(cfg config.DatabaseConfig) (*sqlx.DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return db, nil
+	return &DB{
+		DB:                 db,
+		queryTimeout:       time.Duration(cfg.QueryTimeoutSeconds) * time.Second,
+		slowQueryThreshold: time.Duration(cfg.SlowQueryMillis) * time.Millisecond,
+		logger:             utils.ModuleLogger(logger.With().Str("service", "postgres").Logger(), utils.LogModuleDB),
+	}, nil
+}
+
+// WithTimeout derives a context that is cancelled when the parent is done or
+// the query timeout elapses, whichever comes first
+func (db *DB) WithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if db.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, db.queryTimeout)
+}
+
+// GetContext runs a single-row query bounded by the query timeout
+func (db *DB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return db.get(ctx, db.DB, dest, query, args...)
+}
+
+// SelectContext runs a multi-row query bounded by the query timeout
+func (db *DB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return db.selectRows(ctx, db.DB, dest, query, args...)
+}
+
+// ExecContext runs a statement bounded by the query timeout
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return db.exec(ctx, db.DB, query, args...)
+}
+
+// NamedExecContext runs a named statement bounded by the query timeout
+func (db *DB) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	return db.namedExec(ctx, db.DB, query, arg)
+}
+
+// QueryxContext runs a query bounded by the query timeout, which lasts until
+// the rows are closed
+func (db *DB) QueryxContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	return db.queryx(ctx, db.DB, query, args...)
+}
+
+// QueryRowxContext runs a single-row query bounded by the query timeout,
+// which lasts until the row is scanned
+func (db *DB) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *Row {
+	return db.queryRowx(ctx, db.DB, query, args...)
+}
+
+// BeginTxx starts a transaction whose statements are each bounded by the
+// query timeout. The transaction itself lasts as long as ctx.
+func (db *DB) BeginTxx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := db.DB.BeginTxx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, db: db}, nil
+}
+
+// Beginx starts a transaction without a context, like BeginTxx
+func (db *DB) Beginx() (*Tx, error) {
+	return db.BeginTxx(context.Background(), nil)
 }
 
 // RunMigrations applies database migrations to ensure the schema is up to date
-func RunMigrations(db *sqlx.DB) error {
+func RunMigrations(db *DB) error {
 	// For simplicity, we're defining our schema initialization here
 	// In a real app, you would use a migration tool like golang-migrate
 
@@ -60,6 +139,41 @@ func RunMigrations(db *sqlx.DB) error {
 		return fmt.Errorf("failed to create users table: %w", err)
 	}
 
+	// Create tenants table and scope users to it and to the music provider
+	// they signed in with. Users without a tenant belong to the default
+	// Spotify app, so provider accounts and emails are unique per tenant and
+	// provider rather than globally. Providers may not share an email.
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS tenants (
+			id UUID PRIMARY KEY,
+			slug VARCHAR(100) UNIQUE NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			domain VARCHAR(255) UNIQUE NOT NULL,
+			spotify_client_id VARCHAR(255) NOT NULL,
+			spotify_client_secret TEXT NOT NULL,
+			spotify_redirect_uri TEXT NOT NULL,
+			default_theme VARCHAR(50) NOT NULL DEFAULT 'default',
+			default_background_color VARCHAR(20) NOT NULL DEFAULT '#121212',
+			default_text_color VARCHAR(20) NOT NULL DEFAULT '#FFFFFF',
+			default_animation_style VARCHAR(50) NOT NULL DEFAULT 'fade',
+			is_active BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		);
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_id UUID REFERENCES tenants(id) ON DELETE RESTRICT;
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS provider VARCHAR(20) NOT NULL DEFAULT 'spotify';
+		ALTER TABLE users DROP CONSTRAINT IF EXISTS users_spotify_id_key;
+		ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+		CREATE UNIQUE INDEX IF NOT EXISTS users_tenant_provider_account_idx
+			ON users(COALESCE(tenant_id, '00000000-0000-0000-0000-000000000000'), provider, spotify_id);
+		CREATE UNIQUE INDEX IF NOT EXISTS users_tenant_provider_email_idx
+			ON users(COALESCE(tenant_id, '00000000-0000-0000-0000-000000000000'), provider, email)
+			WHERE email <> '';
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create tenants table: %w", err)
+	}
+
 	// Create profiles table
 	_, err = db.Exec(`
 		CREATE TABLE IF NOT EXISTS profiles (
@@ -118,12 +232,371 @@ func RunMigrations(db *sqlx.DB) error {
 		return fmt.Errorf("failed to create profile_visits table: %w", err)
 	}
 
-	// Create indexes
+	// Users' IANA time zone; empty means UTC
+	_, err = db.Exec(`ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT ''`)
+	if err != nil {
+		return fmt.Errorf("failed to add users.timezone: %w", err)
+	}
+
+	// Profiles opt in to showing lyrics
+	_, err = db.Exec(`ALTER TABLE profiles ADD COLUMN IF NOT EXISTS show_lyrics BOOLEAN NOT NULL DEFAULT FALSE`)
+	if err != nil {
+		return fmt.Errorf("failed to add profiles.show_lyrics: %w", err)
+	}
+
+	// Create media_webhooks table. Plex and Jellyfin post to a per-user
+	// secret URL; only a hash of its token is stored.
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS media_webhooks (
+			user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+			token_hash VARCHAR(64) UNIQUE NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			last_event_at TIMESTAMP WITH TIME ZONE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create media_webhooks table: %w", err)
+	}
+
+	// Create play_events table. Every play is recorded here, including skips
+	// too short to be kept in tracks.
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS play_events (
+			id UUID PRIMARY KEY,
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			spotify_track_id VARCHAR(255) NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			artist VARCHAR(255) NOT NULL,
+			duration_ms INTEGER NOT NULL,
+			listened_ms INTEGER NOT NULL,
+			counted BOOLEAN NOT NULL,
+			started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			ended_at TIMESTAMP WITH TIME ZONE NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create play_events table: %w", err)
+	}
+
+	// Create short_links and short_link_clicks tables
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS short_links (
+			code VARCHAR(16) PRIMARY KEY,
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			label VARCHAR(100) NOT NULL DEFAULT '',
+			click_count BIGINT NOT NULL DEFAULT 0,
+			last_clicked_at TIMESTAMP WITH TIME ZONE,
+			expires_at TIMESTAMP WITH TIME ZONE,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		);
+		CREATE TABLE IF NOT EXISTS short_link_clicks (
+			id BIGSERIAL PRIMARY KEY,
+			code VARCHAR(16) NOT NULL REFERENCES short_links(code) ON DELETE CASCADE,
+			referrer VARCHAR(255) NOT NULL,
+			clicked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create short link tables: %w", err)
+	}
+
+	// Create api_keys and api_key_usage tables
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS api_keys (
+			id UUID PRIMARY KEY,
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			name VARCHAR(100) NOT NULL DEFAULT '',
+			prefix VARCHAR(16) NOT NULL,
+			key_hash VARCHAR(64) NOT NULL UNIQUE,
+			daily_quota INTEGER NOT NULL DEFAULT 0,
+			last_used_at TIMESTAMP WITH TIME ZONE,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		);
+		CREATE TABLE IF NOT EXISTS api_key_usage (
+			key_id UUID NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+			day DATE NOT NULL,
+			endpoint VARCHAR(255) NOT NULL,
+			requests BIGINT NOT NULL DEFAULT 0,
+			PRIMARY KEY (key_id, day, endpoint)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create API key tables: %w", err)
+	}
+
+	// Create listening_sessions table. Counted plays less than the session gap
+	// apart are grouped into one session.
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS listening_sessions (
+			id UUID PRIMARY KEY,
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			ended_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			track_count INTEGER NOT NULL DEFAULT 0,
+			dominant_artist VARCHAR(255) NOT NULL DEFAULT ''
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create listening_sessions table: %w", err)
+	}
+
+	_, err = db.Exec(`ALTER TABLE play_events ADD COLUMN IF NOT EXISTS session_id UUID REFERENCES listening_sessions(id) ON DELETE SET NULL`)
+	if err != nil {
+		return fmt.Errorf("failed to add play_events.session_id: %w", err)
+	}
+
+	// Track the public profiles signed-in users visit, unless they opt out
+	_, err = db.Exec(`ALTER TABLE users ADD COLUMN IF NOT EXISTS track_recently_viewed BOOLEAN NOT NULL DEFAULT true`)
+	if err != nil {
+		return fmt.Errorf("failed to add users.track_recently_viewed: %w", err)
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS recently_viewed_profiles (
+			viewer_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			profile_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			view_count INTEGER NOT NULL DEFAULT 1,
+			last_viewed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (viewer_id, profile_user_id)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create recently_viewed_profiles table: %w", err)
+	}
+
+	// Create usage_reports and usage_report_referrers tables for the monthly
+	// anonymized usage report
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS usage_reports (
+			month VARCHAR(7) PRIMARY KEY,
+			active_users BIGINT NOT NULL,
+			new_users BIGINT NOT NULL,
+			total_users BIGINT NOT NULL,
+			tracks_logged BIGINT NOT NULL,
+			profile_visits BIGINT NOT NULL,
+			unique_visitors BIGINT NOT NULL,
+			generated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		);
+		CREATE TABLE IF NOT EXISTS usage_report_referrers (
+			month VARCHAR(7) NOT NULL REFERENCES usage_reports(month) ON DELETE CASCADE,
+			referrer VARCHAR(255) NOT NULL,
+			visits BIGINT NOT NULL,
+			PRIMARY KEY (month, referrer)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create usage report tables: %w", err)
+	}
+
+	// Create sessions table. The cookie holds a random token and only its
+	// hash is stored, so deleting the row signs that browser out.
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS sessions (
+			id UUID PRIMARY KEY,
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			token_hash VARCHAR(64) UNIQUE NOT NULL,
+			ip_address VARCHAR(45) NOT NULL DEFAULT '',
+			user_agent TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			last_active_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create sessions table: %w", err)
+	}
+
+	// Create listening_stats_daily table. Days are rolled up from play events
+	// in the user's time zone and outlive the events' retention.
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS listening_stats_daily (
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			day DATE NOT NULL,
+			plays INTEGER NOT NULL,
+			listened_ms BIGINT NOT NULL,
+			unique_artists INTEGER NOT NULL,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (user_id, day)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create listening_stats_daily table: %w", err)
+	}
+
+	// Create track_features table. Audio features belong to the track, not a
+	// user's play of it, so each track is fetched once. Tracks Spotify has
+	// no features for are kept with NULL features so they aren't retried.
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS track_features (
+			spotify_track_id VARCHAR(255) PRIMARY KEY,
+			tempo DOUBLE PRECISION,
+			energy DOUBLE PRECISION,
+			danceability DOUBLE PRECISION,
+			valence DOUBLE PRECISION,
+			fetched_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create track_features table: %w", err)
+	}
+
+	// Create track_links table. Like audio features, links belong to the
+	// track and are resolved once; tracks Odesli can't match are kept with
+	// empty links so they aren't retried.
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS track_links (
+			spotify_track_id VARCHAR(255) PRIMARY KEY,
+			page_url TEXT NOT NULL DEFAULT '',
+			apple_music_url TEXT NOT NULL DEFAULT '',
+			youtube_url TEXT NOT NULL DEFAULT '',
+			deezer_url TEXT NOT NULL DEFAULT '',
+			fetched_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create track_links table: %w", err)
+	}
+
+	// Create webhooks and webhook_deliveries tables. Each delivery records the
+	// playback it reports, so the same state isn't sent to a webhook twice in
+	// a row.
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS webhooks (
+			id UUID PRIMARY KEY,
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			url TEXT NOT NULL,
+			description VARCHAR(100) NOT NULL DEFAULT '',
+			is_active BOOLEAN NOT NULL DEFAULT true,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		);
+		CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id BIGSERIAL PRIMARY KEY,
+			webhook_id UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+			event VARCHAR(50) NOT NULL,
+			playback_key TEXT NOT NULL,
+			payload JSONB NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			response_status INTEGER,
+			last_error TEXT NOT NULL DEFAULT '',
+			next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			delivered_at TIMESTAMP WITH TIME ZONE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook tables: %w", err)
+	}
+
+	// Deliveries are signed with a per-webhook secret. Webhooks created before
+	// signing have none, and are sent unsigned until their secret is rotated.
+	_, err = db.Exec(`ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS secret VARCHAR(70) NOT NULL DEFAULT ''`)
+	if err != nil {
+		return fmt.Errorf("failed to add secret column to webhooks: %w", err)
+	}
+
+	// Create scrobble_accounts and scrobbles tables. Listens are queued per
+	// connected account and removed with it.
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS scrobble_accounts (
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			provider VARCHAR(20) NOT NULL,
+			username VARCHAR(255) NOT NULL DEFAULT '',
+			session_key TEXT NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (user_id, provider)
+		);
+		CREATE TABLE IF NOT EXISTS scrobbles (
+			id BIGSERIAL PRIMARY KEY,
+			user_id UUID NOT NULL,
+			provider VARCHAR(20) NOT NULL,
+			artist VARCHAR(255) NOT NULL,
+			track VARCHAR(255) NOT NULL,
+			album VARCHAR(255) NOT NULL DEFAULT '',
+			duration_ms INTEGER NOT NULL DEFAULT 0,
+			started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT NOT NULL DEFAULT '',
+			next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			submitted_at TIMESTAMP WITH TIME ZONE,
+			FOREIGN KEY (user_id, provider) REFERENCES scrobble_accounts(user_id, provider) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create scrobble tables: %w", err)
+	}
+
+	// Repeat visitors are recognized by the hash of a long-lived anonymous
+	// cookie
+	_, err = db.Exec(`ALTER TABLE profile_visits ADD COLUMN IF NOT EXISTS visitor_hash VARCHAR(64) NOT NULL DEFAULT ''`)
+	if err != nil {
+		return fmt.Errorf("failed to add profile_visits.visitor_hash: %w", err)
+	}
+
+	// Visits are located by country and region when GeoIP is configured
+	_, err = db.Exec(`
+		ALTER TABLE profile_visits ADD COLUMN IF NOT EXISTS country VARCHAR(2) NOT NULL DEFAULT '';
+		ALTER TABLE profile_visits ADD COLUMN IF NOT EXISTS region VARCHAR(100) NOT NULL DEFAULT '';
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to add profile_visits location: %w", err)
+	}
+
+	// Crawlers and link preview fetchers are recorded but left out of stats
+	_, err = db.Exec(`ALTER TABLE profile_visits ADD COLUMN IF NOT EXISTS is_bot BOOLEAN NOT NULL DEFAULT false`)
+	if err != nil {
+		return fmt.Errorf("failed to add profile_visits.is_bot: %w", err)
+	}
+
+	// Deleted accounts are hidden right away and purged after a grace period
+	_, err = db.Exec(`ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE`)
+	if err != nil {
+		return fmt.Errorf("failed to add users.deleted_at: %w", err)
+	}
+
+	// pg_trgm backs fuzzy history search for misspelled titles. It is a
+	// trusted extension, so the database owner can create it.
+	if _, err = db.Exec(`CREATE EXTENSION IF NOT EXISTS pg_trgm`); err != nil {
+		return fmt.Errorf("failed to create pg_trgm extension: %w", err)
+	}
+
+	// Create indexes. The history search indexes are on expressions rather
+	// than stored columns so SELECT * on tracks stays unchanged; queries must
+	// repeat the expressions exactly to use them.
 	_, err = db.Exec(`
+		CREATE INDEX IF NOT EXISTS users_profile_url_lower_idx ON users(LOWER(profile_url));
 		CREATE INDEX IF NOT EXISTS tracks_user_id_idx ON tracks(user_id);
 		CREATE INDEX IF NOT EXISTS tracks_played_at_idx ON tracks(played_at);
+		CREATE INDEX IF NOT EXISTS tracks_user_history_idx ON tracks(user_id, played_at DESC, id DESC);
+		CREATE INDEX IF NOT EXISTS tracks_search_idx ON tracks USING GIN (to_tsvector('simple', name || ' ' || artist || ' ' || album));
+		CREATE INDEX IF NOT EXISTS tracks_search_trgm_idx ON tracks USING GIN (LOWER(name || ' ' || artist || ' ' || album) gin_trgm_ops);
 		CREATE INDEX IF NOT EXISTS profile_visits_user_id_idx ON profile_visits(user_id);
 		CREATE INDEX IF NOT EXISTS profile_visits_started_at_idx ON profile_visits(started_at);
+		CREATE INDEX IF NOT EXISTS profile_visits_user_started_idx ON profile_visits(user_id, started_at);
+		CREATE INDEX IF NOT EXISTS profile_visits_visitor_idx ON profile_visits(user_id, visitor_hash, started_at DESC) WHERE visitor_hash <> '';
+		CREATE INDEX IF NOT EXISTS play_events_user_started_idx ON play_events(user_id, started_at DESC);
+		CREATE INDEX IF NOT EXISTS play_events_ended_at_idx ON play_events(ended_at);
+		CREATE INDEX IF NOT EXISTS short_links_user_id_idx ON short_links(user_id, created_at DESC);
+		CREATE INDEX IF NOT EXISTS short_link_clicks_code_idx ON short_link_clicks(code);
+		CREATE INDEX IF NOT EXISTS short_link_clicks_clicked_at_idx ON short_link_clicks(clicked_at);
+		CREATE INDEX IF NOT EXISTS api_keys_user_id_idx ON api_keys(user_id, created_at DESC);
+		CREATE INDEX IF NOT EXISTS listening_sessions_user_started_idx ON listening_sessions(user_id, started_at DESC, id DESC);
+		CREATE INDEX IF NOT EXISTS listening_sessions_ended_at_idx ON listening_sessions(ended_at);
+		CREATE INDEX IF NOT EXISTS play_events_session_id_idx ON play_events(session_id);
+		CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions(user_id, last_active_at DESC);
+		CREATE INDEX IF NOT EXISTS sessions_expires_at_idx ON sessions(expires_at);
+		CREATE INDEX IF NOT EXISTS recently_viewed_profiles_viewer_idx ON recently_viewed_profiles(viewer_id, last_viewed_at DESC);
+		CREATE INDEX IF NOT EXISTS webhooks_user_id_idx ON webhooks(user_id, created_at DESC);
+		CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_id_idx ON webhook_deliveries(webhook_id, id DESC);
+		CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+		CREATE INDEX IF NOT EXISTS webhook_deliveries_created_at_idx ON webhook_deliveries(created_at);
+		CREATE INDEX IF NOT EXISTS scrobbles_account_idx ON scrobbles(user_id, provider, id DESC);
+		CREATE INDEX IF NOT EXISTS scrobbles_due_idx ON scrobbles(next_attempt_at) WHERE status = 'pending';
+		CREATE INDEX IF NOT EXISTS scrobbles_created_at_idx ON scrobbles(created_at);
+		CREATE INDEX IF NOT EXISTS users_deleted_at_idx ON users(deleted_at) WHERE deleted_at IS NOT NULL;
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to create indexes: %w", err)
@@ -0,0 +1,196 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// The helpers below run queries for both DB and Tx. Each must be called
+// directly from the exported method so observeQuery can name the query
+// after that method's caller.
+
+func (db *DB) get(ctx context.Context, q sqlx.QueryerContext, dest interface{}, query string, args ...interface{}) error {
+	queryCtx, cancel := db.WithTimeout(ctx)
+	defer cancel()
+
+	start := time.Now()
+	err := sqlx.GetContext(queryCtx, q, dest, query, args...)
+	rows := int64(1)
+	if err != nil {
+		rows = 0
+	}
+	db.observeQuery(ctx, start, query, rows, err)
+	return err
+}
+
+func (db *DB) selectRows(ctx context.Context, q sqlx.QueryerContext, dest interface{}, query string, args ...interface{}) error {
+	queryCtx, cancel := db.WithTimeout(ctx)
+	defer cancel()
+
+	start := time.Now()
+	err := sqlx.SelectContext(queryCtx, q, dest, query, args...)
+	db.observeQuery(ctx, start, query, selectedRows(dest), err)
+	return err
+}
+
+func (db *DB) exec(ctx context.Context, e sqlx.ExecerContext, query string, args ...interface{}) (sql.Result, error) {
+	queryCtx, cancel := db.WithTimeout(ctx)
+	defer cancel()
+
+	start := time.Now()
+	result, err := e.ExecContext(queryCtx, query, args...)
+	db.observeQuery(ctx, start, query, affectedRows(result), err)
+	return result, err
+}
+
+func (db *DB) namedExec(ctx context.Context, e sqlx.ExtContext, query string, arg interface{}) (sql.Result, error) {
+	queryCtx, cancel := db.WithTimeout(ctx)
+	defer cancel()
+
+	start := time.Now()
+	result, err := sqlx.NamedExecContext(queryCtx, e, query, arg)
+	db.observeQuery(ctx, start, query, affectedRows(result), err)
+	return result, err
+}
+
+func (db *DB) queryx(ctx context.Context, q sqlx.QueryerContext, query string, args ...interface{}) (*Rows, error) {
+	queryCtx, cancel := db.WithTimeout(ctx)
+	done := db.deferredObserver(ctx, queryName(ctx, 2), cancel, query)
+
+	rows, err := q.QueryxContext(queryCtx, query, args...)
+	if err != nil {
+		done(0, err)
+		return nil, err
+	}
+	return &Rows{Rows: rows, done: done}, nil
+}
+
+func (db *DB) queryRowx(ctx context.Context, q sqlx.QueryerContext, query string, args ...interface{}) *Row {
+	queryCtx, cancel := db.WithTimeout(ctx)
+	done := db.deferredObserver(ctx, queryName(ctx, 2), cancel, query)
+	return &Row{Row: q.QueryRowxContext(queryCtx, query, args...), done: done}
+}
+
+// deferredObserver returns a func that ends a query whose results are read
+// after the DB method returns: it cancels the query's timeout and checks it
+// for slowness, once
+func (db *DB) deferredObserver(ctx context.Context, name string, cancel context.CancelFunc, statement string) func(rows int64, err error) {
+	start := time.Now()
+	var once sync.Once
+	return func(rows int64, err error) {
+		once.Do(func() {
+			cancel()
+			db.observeNamedQuery(ctx, name, start, statement, rows, err)
+		})
+	}
+}
+
+// Rows is sqlx.Rows for a query bounded by the query timeout. The timeout is
+// released once the rows are exhausted or closed.
+type Rows struct {
+	*sqlx.Rows
+	scanned int64
+	done    func(rows int64, err error)
+}
+
+// Next prepares the next row, ending the query after the last one
+func (r *Rows) Next() bool {
+	if r.Rows.Next() {
+		r.scanned++
+		return true
+	}
+	r.done(r.scanned, r.Rows.Err())
+	return false
+}
+
+// Close closes the rows and ends the query
+func (r *Rows) Close() error {
+	err := r.Rows.Close()
+	r.done(r.scanned, r.Rows.Err())
+	return err
+}
+
+// Row is sqlx.Row for a query bounded by the query timeout. The timeout is
+// released once the row is scanned.
+type Row struct {
+	*sqlx.Row
+	done func(rows int64, err error)
+}
+
+// Scan copies the row's columns into dest and ends the query
+func (r *Row) Scan(dest ...interface{}) error {
+	err := r.Row.Scan(dest...)
+	r.done(scannedRows(err), err)
+	return err
+}
+
+// StructScan scans the row into a struct and ends the query
+func (r *Row) StructScan(dest interface{}) error {
+	err := r.Row.StructScan(dest)
+	r.done(scannedRows(err), err)
+	return err
+}
+
+// MapScan scans the row into a map and ends the query
+func (r *Row) MapScan(dest map[string]interface{}) error {
+	err := r.Row.MapScan(dest)
+	r.done(scannedRows(err), err)
+	return err
+}
+
+// SliceScan scans the row into a slice and ends the query
+func (r *Row) SliceScan() ([]interface{}, error) {
+	values, err := r.Row.SliceScan()
+	r.done(scannedRows(err), err)
+	return values, err
+}
+
+func scannedRows(err error) int64 {
+	if err != nil {
+		return 0
+	}
+	return 1
+}
+
+// Tx wraps sqlx.Tx so statements in a transaction get the same query timeout
+// and slow query logging as DB
+type Tx struct {
+	*sqlx.Tx
+	db *DB
+}
+
+// GetContext runs a single-row query bounded by the query timeout
+func (tx *Tx) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return tx.db.get(ctx, tx.Tx, dest, query, args...)
+}
+
+// SelectContext runs a multi-row query bounded by the query timeout
+func (tx *Tx) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return tx.db.selectRows(ctx, tx.Tx, dest, query, args...)
+}
+
+// ExecContext runs a statement bounded by the query timeout
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return tx.db.exec(ctx, tx.Tx, query, args...)
+}
+
+// NamedExecContext runs a named statement bounded by the query timeout
+func (tx *Tx) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	return tx.db.namedExec(ctx, tx.Tx, query, arg)
+}
+
+// QueryxContext runs a query bounded by the query timeout, which lasts until
+// the rows are closed
+func (tx *Tx) QueryxContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	return tx.db.queryx(ctx, tx.Tx, query, args...)
+}
+
+// QueryRowxContext runs a single-row query bounded by the query timeout,
+// which lasts until the row is scanned
+func (tx *Tx) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *Row {
+	return tx.db.queryRowx(ctx, tx.Tx, query, args...)
+}
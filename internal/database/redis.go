@@ -2,19 +2,30 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/utils"
 	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog"
 )
 
+// ErrRedisUnavailable is returned by every command while Redis is unreachable
+var ErrRedisUnavailable = errors.New("redis unavailable")
+
 // RedisClient wraps the redis.Client with additional functionality
 type RedisClient struct {
-	client *redis.Client
+	client    *redis.Client
+	prefix    string
+	available atomic.Bool
 }
 
-// NewRedisClient creates a new Redis client
+// NewRedisClient creates a new Redis client. If Redis cannot be reached the
+// client is still returned, in degraded mode, along with the connection error;
+// StartHealthCheck brings it back once Redis recovers.
 func NewRedisClient(cfg config.RedisConfig) (*RedisClient, error) {
 	client := redis.NewClient(&redis.Options{
 		Addr:     fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
@@ -22,15 +33,58 @@ func NewRedisClient(cfg config.RedisConfig) (*RedisClient, error) {
 		DB:       cfg.DB,
 	})
 
+	rc := &RedisClient{client: client, prefix: cfg.KeyPrefix}
+	client.AddHook(availabilityHook{rc: rc})
+	client.AddHook(metricsHook{})
+
 	// Test connection
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	if err := rc.ping(context.Background()); err != nil {
+		return rc, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	rc.available.Store(true)
+
+	return rc, nil
+}
+
+// Available reports whether Redis is currently reachable
+func (rc *RedisClient) Available() bool {
+	return rc.available.Load()
+}
+
+// StartHealthCheck pings Redis every interval and flips the client between
+// normal and degraded mode until ctx is cancelled
+func (rc *RedisClient) StartHealthCheck(ctx context.Context, interval time.Duration, logger zerolog.Logger) {
+	logger = utils.ModuleLogger(logger, utils.LogModuleDB)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
-	if err := client.Ping(ctx).Err(); err != nil {
-		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	for {
+		select {
+		case <-ticker.C:
+			err := rc.ping(ctx)
+			wasAvailable := rc.available.Swap(err == nil)
+			switch {
+			case err != nil && wasAvailable:
+				logger.Error().Err(err).Msg("Redis unavailable, entering degraded mode")
+			case err == nil && !wasAvailable:
+				logger.Info().Msg("Redis reachable again, leaving degraded mode")
+			}
+		case <-ctx.Done():
+			return
+		}
 	}
+}
 
-	return &RedisClient{client: client}, nil
+func (rc *RedisClient) ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return rc.client.Ping(ctx).Err()
+}
+
+// key applies the configured environment prefix to a key or channel name
+func (rc *RedisClient) key(name string) string {
+	return rc.prefix + name
 }
 
 // Close closes the Redis client connection
@@ -40,75 +94,238 @@ func (rc *RedisClient) Close() error {
 
 // Set sets a key-value pair with an optional expiration
 func (rc *RedisClient) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
-	return rc.client.Set(ctx, key, value, expiration).Err()
+	return rc.client.Set(ctx, rc.key(key), value, expiration).Err()
+}
+
+// SetIfAbsent sets a key only if it doesn't exist yet, reporting whether it did
+func (rc *RedisClient) SetIfAbsent(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	return rc.client.SetNX(ctx, rc.key(key), value, expiration).Result()
 }
 
 // Get retrieves a value by key
 func (rc *RedisClient) Get(ctx context.Context, key string) (string, error) {
-	return rc.client.Get(ctx, key).Result()
+	return rc.client.Get(ctx, rc.key(key)).Result()
+}
+
+// GetMany retrieves several keys in one round trip. Missing keys are left out
+// of the result.
+func (rc *RedisClient) GetMany(ctx context.Context, keys ...string) (map[string]string, error) {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = rc.key(key)
+	}
+
+	values, err := rc.client.MGet(ctx, prefixed...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string, len(keys))
+	for i, value := range values {
+		if str, ok := value.(string); ok {
+			result[keys[i]] = str
+		}
+	}
+	return result, nil
 }
 
 // Delete deletes a key
 func (rc *RedisClient) Delete(ctx context.Context, key string) error {
-	return rc.client.Del(ctx, key).Err()
+	return rc.client.Del(ctx, rc.key(key)).Err()
 }
 
 // HashSet sets a field in a hash stored at key
 func (rc *RedisClient) HashSet(ctx context.Context, key, field string, value interface{}) error {
-	return rc.client.HSet(ctx, key, field, value).Err()
+	return rc.client.HSet(ctx, rc.key(key), field, value).Err()
 }
 
 // HashGet retrieves a field from a hash stored at key
 func (rc *RedisClient) HashGet(ctx context.Context, key, field string) (string, error) {
-	return rc.client.HGet(ctx, key, field).Result()
+	return rc.client.HGet(ctx, rc.key(key), field).Result()
 }
 
 // HashGetAll retrieves all fields and values of a hash stored at key
 func (rc *RedisClient) HashGetAll(ctx context.Context, key string) (map[string]string, error) {
-	return rc.client.HGetAll(ctx, key).Result()
+	return rc.client.HGetAll(ctx, rc.key(key)).Result()
 }
 
 // Publish publishes a message to a channel
 func (rc *RedisClient) Publish(ctx context.Context, channel string, message interface{}) error {
-	return rc.client.Publish(ctx, channel, message).Err()
+	return rc.client.Publish(ctx, rc.key(channel), message).Err()
 }
 
 // Subscribe subscribes to channels and returns a message channel
 func (rc *RedisClient) Subscribe(ctx context.Context, channels ...string) *redis.PubSub {
-	return rc.client.Subscribe(ctx, channels...)
+	prefixed := make([]string, len(channels))
+	for i, channel := range channels {
+		prefixed[i] = rc.key(channel)
+	}
+	return rc.client.Subscribe(ctx, prefixed...)
+}
+
+// PatternSubscribe subscribes to channels matching the given patterns
+func (rc *RedisClient) PatternSubscribe(ctx context.Context, patterns ...string) *redis.PubSub {
+	prefixed := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		prefixed[i] = rc.key(pattern)
+	}
+	return rc.client.PSubscribe(ctx, prefixed...)
 }
 
 // AddToSet adds members to a set
 func (rc *RedisClient) AddToSet(ctx context.Context, key string, members ...interface{}) error {
-	return rc.client.SAdd(ctx, key, members...).Err()
+	return rc.client.SAdd(ctx, rc.key(key), members...).Err()
 }
 
 // GetSetMembers returns all members of a set
 func (rc *RedisClient) GetSetMembers(ctx context.Context, key string) ([]string, error) {
-	return rc.client.SMembers(ctx, key).Result()
+	return rc.client.SMembers(ctx, rc.key(key)).Result()
 }
 
 // RemoveFromSet removes members from a set
 func (rc *RedisClient) RemoveFromSet(ctx context.Context, key string, members ...interface{}) error {
-	return rc.client.SRem(ctx, key, members...).Err()
+	return rc.client.SRem(ctx, rc.key(key), members...).Err()
 }
 
 // GetSetSize returns the number of members in a set
 func (rc *RedisClient) GetSetSize(ctx context.Context, key string) (int64, error) {
-	return rc.client.SCard(ctx, key).Result()
+	return rc.client.SCard(ctx, rc.key(key)).Result()
+}
+
+// AddToSortedSet adds a member to a sorted set, or updates its score if it is already present
+func (rc *RedisClient) AddToSortedSet(ctx context.Context, key string, score float64, member interface{}) error {
+	return rc.client.ZAdd(ctx, rc.key(key), &redis.Z{Score: score, Member: member}).Err()
+}
+
+// RemoveFromSortedSet removes members from a sorted set
+func (rc *RedisClient) RemoveFromSortedSet(ctx context.Context, key string, members ...interface{}) error {
+	return rc.client.ZRem(ctx, rc.key(key), members...).Err()
+}
+
+// CountSortedSetByScore returns the number of members of a sorted set with a score between min and max
+func (rc *RedisClient) CountSortedSetByScore(ctx context.Context, key, min, max string) (int64, error) {
+	return rc.client.ZCount(ctx, rc.key(key), min, max).Result()
+}
+
+// GetSortedSetByScore returns the members of a sorted set with a score between min and max
+func (rc *RedisClient) GetSortedSetByScore(ctx context.Context, key, min, max string) ([]string, error) {
+	return rc.client.ZRangeByScore(ctx, rc.key(key), &redis.ZRangeBy{Min: min, Max: max}).Result()
+}
+
+// AddToHyperLogLog adds members to a HyperLogLog
+func (rc *RedisClient) AddToHyperLogLog(ctx context.Context, key string, members ...interface{}) error {
+	return rc.client.PFAdd(ctx, rc.key(key), members...).Err()
+}
+
+// CountHyperLogLog returns the approximate number of distinct members across
+// one or more HyperLogLogs
+func (rc *RedisClient) CountHyperLogLog(ctx context.Context, keys ...string) (int64, error) {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = rc.key(key)
+	}
+	return rc.client.PFCount(ctx, prefixed...).Result()
+}
+
+// RemoveSortedSetByScore removes all members of a sorted set with a score between min and max
+func (rc *RedisClient) RemoveSortedSetByScore(ctx context.Context, key, min, max string) error {
+	return rc.client.ZRemRangeByScore(ctx, rc.key(key), min, max).Err()
+}
+
+// RunScript runs a Lua script (via EVALSHA, falling back to EVAL) with prefixed keys
+func (rc *RedisClient) RunScript(ctx context.Context, script *redis.Script, keys []string, args ...interface{}) (interface{}, error) {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = rc.key(key)
+	}
+	return script.Run(ctx, rc.client, prefixed, args...).Result()
 }
 
 // IncrementCounter increments a counter by 1 and returns the new value
 func (rc *RedisClient) IncrementCounter(ctx context.Context, key string) (int64, error) {
-	return rc.client.Incr(ctx, key).Result()
+	return rc.client.Incr(ctx, rc.key(key)).Result()
 }
 
 // DecrementCounter decrements a counter by 1 and returns the new value
 func (rc *RedisClient) DecrementCounter(ctx context.Context, key string) (int64, error) {
-	return rc.client.Decr(ctx, key).Result()
+	return rc.client.Decr(ctx, rc.key(key)).Result()
 }
 
 // SetExpiration sets an expiration on a key
 func (rc *RedisClient) SetExpiration(ctx context.Context, key string, expiration time.Duration) error {
-	return rc.client.Expire(ctx, key, expiration).Err()
+	return rc.client.Expire(ctx, rc.key(key), expiration).Err()
+}
+
+// Pipeline queues commands so they are sent to Redis in a single round trip
+type Pipeline struct {
+	pipe redis.Pipeliner
+	rc   *RedisClient
+}
+
+// Pipelined runs fn against a pipeline and executes the queued commands in one round trip
+func (rc *RedisClient) Pipelined(ctx context.Context, fn func(*Pipeline)) error {
+	_, err := rc.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		fn(&Pipeline{pipe: pipe, rc: rc})
+		return nil
+	})
+	return err
+}
+
+// TxPipelined is like Pipelined but wraps the queued commands in MULTI/EXEC
+func (rc *RedisClient) TxPipelined(ctx context.Context, fn func(*Pipeline)) error {
+	_, err := rc.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		fn(&Pipeline{pipe: pipe, rc: rc})
+		return nil
+	})
+	return err
+}
+
+// Set queues setting a key-value pair with an optional expiration
+func (p *Pipeline) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) {
+	p.pipe.Set(ctx, p.rc.key(key), value, expiration)
+}
+
+// Delete queues deleting a key
+func (p *Pipeline) Delete(ctx context.Context, key string) {
+	p.pipe.Del(ctx, p.rc.key(key))
+}
+
+// AddToSet queues adding members to a set
+func (p *Pipeline) AddToSet(ctx context.Context, key string, members ...interface{}) {
+	p.pipe.SAdd(ctx, p.rc.key(key), members...)
+}
+
+// RemoveFromSet queues removing members from a set
+func (p *Pipeline) RemoveFromSet(ctx context.Context, key string, members ...interface{}) {
+	p.pipe.SRem(ctx, p.rc.key(key), members...)
+}
+
+// SetExpiration queues setting an expiration on a key
+func (p *Pipeline) SetExpiration(ctx context.Context, key string, expiration time.Duration) {
+	p.pipe.Expire(ctx, p.rc.key(key), expiration)
+}
+
+// AddToSortedSet queues adding a member to a sorted set
+func (p *Pipeline) AddToSortedSet(ctx context.Context, key string, score float64, member interface{}) {
+	p.pipe.ZAdd(ctx, p.rc.key(key), &redis.Z{Score: score, Member: member})
+}
+
+// RemoveFromSortedSet queues removing members from a sorted set
+func (p *Pipeline) RemoveFromSortedSet(ctx context.Context, key string, members ...interface{}) {
+	p.pipe.ZRem(ctx, p.rc.key(key), members...)
+}
+
+// RemoveSortedSetByScore queues removing sorted set members with a score between min and max
+func (p *Pipeline) RemoveSortedSetByScore(ctx context.Context, key, min, max string) {
+	p.pipe.ZRemRangeByScore(ctx, p.rc.key(key), min, max)
+}
+
+// AddToHyperLogLog queues adding members to a HyperLogLog
+func (p *Pipeline) AddToHyperLogLog(ctx context.Context, key string, members ...interface{}) {
+	p.pipe.PFAdd(ctx, p.rc.key(key), members...)
+}
+
+// HashIncrement queues incrementing a hash field by value
+func (p *Pipeline) HashIncrement(ctx context.Context, key, field string, value int64) {
+	p.pipe.HIncrBy(ctx, p.rc.key(key), field, value)
 }
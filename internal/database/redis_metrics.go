@@ -0,0 +1,112 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/metrics"
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	redisOperationDuration = metrics.NewHistogramVec(
+		"redis_operation_duration_seconds",
+		"Latency of Redis commands by operation.",
+		nil, "operation",
+	)
+	redisOperationErrors = metrics.NewCounterVec(
+		"redis_operation_errors_total",
+		"Redis commands that returned an error, by operation.",
+		"operation",
+	)
+	redisPubSubDeliveryLag = metrics.NewHistogramVec(
+		"redis_pubsub_delivery_lag_seconds",
+		"Time between publishing a pub/sub message and a subscriber receiving it.",
+		[]float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 5},
+		"channel",
+	)
+)
+
+type startTimeKey struct{}
+
+// availabilityHook fails commands fast while the client is in degraded mode so
+// requests don't each wait out a dial timeout. Pings pass through so the
+// health check can detect recovery.
+type availabilityHook struct {
+	rc *RedisClient
+}
+
+func (h availabilityHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	if cmd.Name() != "ping" && !h.rc.Available() {
+		return ctx, ErrRedisUnavailable
+	}
+	return ctx, nil
+}
+
+func (availabilityHook) AfterProcess(context.Context, redis.Cmder) error {
+	return nil
+}
+
+func (h availabilityHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	if !h.rc.Available() {
+		return ctx, ErrRedisUnavailable
+	}
+	return ctx, nil
+}
+
+func (availabilityHook) AfterProcessPipeline(context.Context, []redis.Cmder) error {
+	return nil
+}
+
+// metricsHook records latency and errors for every command sent through the client
+type metricsHook struct{}
+
+func (metricsHook) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, startTimeKey{}, time.Now()), nil
+}
+
+func (metricsHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	observeCommand(ctx, cmd.Name(), cmd.Err())
+	return nil
+}
+
+func (metricsHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, startTimeKey{}, time.Now()), nil
+}
+
+func (metricsHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if cmdErr := cmd.Err(); cmdErr != nil && !errors.Is(cmdErr, redis.Nil) {
+			err = cmdErr
+			break
+		}
+	}
+	observeCommand(ctx, "pipeline", err)
+	return nil
+}
+
+func observeCommand(ctx context.Context, operation string, err error) {
+	if start, ok := ctx.Value(startTimeKey{}).(time.Time); ok {
+		redisOperationDuration.Observe(time.Since(start).Seconds(), operation)
+	}
+
+	// A missing key is a normal cache miss, not an error
+	if err != nil && !errors.Is(err, redis.Nil) {
+		redisOperationErrors.Inc(operation)
+	}
+}
+
+// ObservePubSubDeliveryLag records how long a message took to reach a subscriber.
+// The channel's trailing ID segment is dropped to keep label cardinality bounded.
+func ObservePubSubDeliveryLag(channel string, publishedAt time.Time) {
+	if publishedAt.IsZero() {
+		return
+	}
+	if idx := strings.LastIndex(channel, ":"); idx >= 0 {
+		channel = channel[:idx]
+	}
+	redisPubSubDeliveryLag.Observe(time.Since(publishedAt).Seconds(), channel)
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/metrics"
+)
+
+// maxLoggedStatementLength truncates SQL in slow query logs
+const maxLoggedStatementLength = 300
+
+var slowQueries = metrics.NewCounterVec(
+	"db_slow_queries_total",
+	"PostgreSQL queries that exceeded the slow query threshold, by query name.",
+	"query",
+)
+
+type queryNameKey struct{}
+
+// WithQueryName names the queries run with ctx in slow query logs and
+// metrics. Without it, queries are named after the calling function.
+func WithQueryName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, queryNameKey{}, name)
+}
+
+// observeQuery logs and counts a query that took longer than the slow query
+// threshold. It must be called directly from the helper running the query,
+// itself called from the DB or Tx method, so that method's caller can be used
+// as the query name.
+func (db *DB) observeQuery(ctx context.Context, start time.Time, statement string, rows int64, err error) {
+	if db.slowQueryThreshold <= 0 || time.Since(start) < db.slowQueryThreshold {
+		return
+	}
+	db.observeNamedQuery(ctx, queryName(ctx, 3), start, statement, rows, err)
+}
+
+// queryName returns the name given with WithQueryName, or else the short name
+// of the function skip frames above the caller
+func queryName(ctx context.Context, skip int) string {
+	if name, _ := ctx.Value(queryNameKey{}).(string); name != "" {
+		return name
+	}
+	return callerName(skip + 1)
+}
+
+// observeNamedQuery is observeQuery for a query named up front, for results
+// read after the method that ran the query has returned
+func (db *DB) observeNamedQuery(ctx context.Context, name string, start time.Time, statement string, rows int64, err error) {
+	elapsed := time.Since(start)
+	if db.slowQueryThreshold <= 0 || elapsed < db.slowQueryThreshold {
+		return
+	}
+	slowQueries.Inc(name)
+
+	event := db.logger.Warn().Ctx(ctx).
+		Str("query", name).
+		Dur("duration", elapsed).
+		Int64("rows", rows).
+		Str("statement", compactStatement(statement))
+	if err != nil {
+		event = event.Err(err)
+	}
+	event.Msg("Slow query")
+}
+
+// callerName returns the short name of the function skip frames above the
+// caller, e.g. "ProfileService.GetTrackHistory"
+func callerName(skip int) string {
+	pc, _, _, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return "unknown"
+	}
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return "unknown"
+	}
+
+	name := fn.Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if _, rest, ok := strings.Cut(name, "."); ok {
+		name = rest
+	}
+	return strings.NewReplacer("(*", "", ")", "").Replace(name)
+}
+
+// compactStatement collapses whitespace and truncates SQL for logging
+func compactStatement(statement string) string {
+	statement = strings.Join(strings.Fields(statement), " ")
+	if len(statement) > maxLoggedStatementLength {
+		statement = statement[:maxLoggedStatementLength] + "…"
+	}
+	return statement
+}
+
+// selectedRows counts the rows SelectContext scanned into dest
+func selectedRows(dest interface{}) int64 {
+	v := reflect.ValueOf(dest)
+	for v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice {
+		return 0
+	}
+	return int64(v.Len())
+}
+
+// affectedRows reads the row count from an Exec result, if there is one
+func affectedRows(result sql.Result) int64 {
+	if result == nil {
+		return 0
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0
+	}
+	return rows
+}
@@ -0,0 +1,145 @@
+package errreport
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/utils"
+	"github.com/getsentry/sentry-go"
+	"github.com/rs/zerolog"
+)
+
+// Reporter ships errors and panics to an external error tracker
+type Reporter interface {
+	// CaptureError reports an error along with optional tags
+	CaptureError(ctx context.Context, err error, tags map[string]string)
+	// CaptureMessage reports a log message at the given level
+	CaptureMessage(ctx context.Context, level zerolog.Level, message string)
+	// CapturePanic reports a recovered panic value
+	CapturePanic(ctx context.Context, recovered interface{}, tags map[string]string)
+	// Flush waits up to timeout for queued events to be delivered
+	Flush(timeout time.Duration) bool
+}
+
+// New returns a Sentry-compatible reporter for the configured DSN, or a no-op
+// reporter when none is set
+func New(cfg config.ErrorReportingConfig) (Reporter, error) {
+	if cfg.DSN == "" {
+		return Nop{}, nil
+	}
+
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Dsn:              cfg.DSN,
+		Environment:      cfg.Environment,
+		Release:          cfg.Release,
+		AttachStacktrace: true,
+		BeforeSend: func(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+			return scrub(event)
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create error reporter: %w", err)
+	}
+
+	return &sentryReporter{hub: sentry.NewHub(client, sentry.NewScope())}, nil
+}
+
+// Nop discards everything; it is used when error reporting is disabled
+type Nop struct{}
+
+func (Nop) CaptureError(context.Context, error, map[string]string)       {}
+func (Nop) CaptureMessage(context.Context, zerolog.Level, string)        {}
+func (Nop) CapturePanic(context.Context, interface{}, map[string]string) {}
+func (Nop) Flush(time.Duration) bool                                     { return true }
+
+type sentryReporter struct {
+	hub *sentry.Hub
+}
+
+// scoped returns a hub for one event, tagged with the request ID from ctx
+func (r *sentryReporter) scoped(ctx context.Context, tags map[string]string) *sentry.Hub {
+	hub := r.hub.Clone()
+	hub.ConfigureScope(func(scope *sentry.Scope) {
+		if id := utils.RequestIDFromContext(ctx); id != "" {
+			scope.SetTag("request_id", id)
+		}
+		scope.SetTags(tags)
+	})
+	return hub
+}
+
+func (r *sentryReporter) CaptureError(ctx context.Context, err error, tags map[string]string) {
+	r.scoped(ctx, tags).CaptureException(err)
+}
+
+func (r *sentryReporter) CaptureMessage(ctx context.Context, level zerolog.Level, message string) {
+	hub := r.scoped(ctx, nil)
+	hub.ConfigureScope(func(scope *sentry.Scope) {
+		scope.SetLevel(sentryLevel(level))
+	})
+	hub.CaptureMessage(message)
+}
+
+func (r *sentryReporter) CapturePanic(ctx context.Context, recovered interface{}, tags map[string]string) {
+	r.scoped(ctx, tags).RecoverWithContext(ctx, recovered)
+}
+
+func (r *sentryReporter) Flush(timeout time.Duration) bool {
+	return r.hub.Flush(timeout)
+}
+
+func sentryLevel(level zerolog.Level) sentry.Level {
+	switch level {
+	case zerolog.FatalLevel, zerolog.PanicLevel:
+		return sentry.LevelFatal
+	case zerolog.ErrorLevel:
+		return sentry.LevelError
+	case zerolog.WarnLevel:
+		return sentry.LevelWarning
+	case zerolog.InfoLevel:
+		return sentry.LevelInfo
+	default:
+		return sentry.LevelDebug
+	}
+}
+
+// uuidPattern matches user IDs (and any other UUIDs) embedded in free text
+var uuidPattern = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+
+// scrub strips user identifiers from an event before it leaves the process:
+// the user block, cookies and credentials, and UUIDs in messages and URLs.
+// The request_id tag is kept so events can be matched to logs.
+func scrub(event *sentry.Event) *sentry.Event {
+	event.User = sentry.User{}
+	delete(event.Tags, "user_id")
+	delete(event.Extra, "user_id")
+
+	event.Message = redact(event.Message)
+	for i := range event.Exception {
+		event.Exception[i].Value = redact(event.Exception[i].Value)
+	}
+	for _, breadcrumb := range event.Breadcrumbs {
+		breadcrumb.Message = redact(breadcrumb.Message)
+	}
+
+	if event.Request != nil {
+		event.Request.Cookies = ""
+		event.Request.URL = redact(event.Request.URL)
+		event.Request.QueryString = redact(event.Request.QueryString)
+		for name := range event.Request.Headers {
+			switch name {
+			case "Cookie", "Authorization", "X-Api-Key":
+				delete(event.Request.Headers, name)
+			}
+		}
+	}
+
+	return event
+}
+
+func redact(s string) string {
+	return uuidPattern.ReplaceAllString(s, "[id]")
+}
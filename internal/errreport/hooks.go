@@ -0,0 +1,28 @@
+package errreport
+
+import (
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// flushTimeout bounds how long a fatal log waits for its report to send
+const flushTimeout = 2 * time.Second
+
+// LogHook forwards error-level and above log messages to a Reporter
+type LogHook struct {
+	Reporter Reporter
+}
+
+// Run implements zerolog.Hook
+func (h LogHook) Run(e *zerolog.Event, level zerolog.Level, message string) {
+	if level < zerolog.ErrorLevel || level == zerolog.NoLevel || message == "" {
+		return
+	}
+
+	h.Reporter.CaptureMessage(e.GetCtx(), level, message)
+	if level >= zerolog.FatalLevel {
+		// Fatal exits the process before an async send would complete
+		h.Reporter.Flush(flushTimeout)
+	}
+}
@@ -0,0 +1,82 @@
+// Package geoip resolves visitor IP addresses to where they are, using a
+// MaxMind GeoIP2 or GeoLite2 database loaded at startup
+package geoip
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// Location is where an IP address is. Country is an ISO 3166-1 alpha-2 code
+// and Region the English name of the largest subdivision; either is empty
+// when unknown. Country databases never have a region.
+type Location struct {
+	Country string
+	Region  string
+}
+
+// Locator looks up IP addresses in a MaxMind database. A nil Locator finds
+// nothing, so callers don't have to check whether GeoIP is configured.
+type Locator struct {
+	reader  *geoip2.Reader
+	regions bool
+}
+
+// Open loads the MaxMind database at path, either a City or a Country
+// edition. It returns nil without an error when path is empty.
+func Open(path string) (*Locator, error) {
+	if path == "" {
+		return nil, nil
+	}
+	reader, err := geoip2.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoIP database: %w", err)
+	}
+	dbType := reader.Metadata().DatabaseType
+	if !strings.Contains(dbType, "City") && !strings.Contains(dbType, "Country") && !strings.Contains(dbType, "Enterprise") {
+		reader.Close()
+		return nil, fmt.Errorf("GeoIP database %s is a %s database, not City or Country", path, dbType)
+	}
+	return &Locator{
+		reader:  reader,
+		regions: !strings.Contains(dbType, "Country"),
+	}, nil
+}
+
+// Lookup returns where ip is. Addresses that don't parse or aren't in the
+// database, such as private ones, have an empty Location.
+func (l *Locator) Lookup(ip string) Location {
+	addr := net.ParseIP(ip)
+	if l == nil || addr == nil {
+		return Location{}
+	}
+
+	if !l.regions {
+		record, err := l.reader.Country(addr)
+		if err != nil {
+			return Location{}
+		}
+		return Location{Country: record.Country.IsoCode}
+	}
+
+	record, err := l.reader.City(addr)
+	if err != nil {
+		return Location{}
+	}
+	location := Location{Country: record.Country.IsoCode}
+	if len(record.Subdivisions) > 0 {
+		location.Region = record.Subdivisions[0].Names["en"]
+	}
+	return location
+}
+
+// Close releases the database
+func (l *Locator) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.reader.Close()
+}
@@ -0,0 +1,268 @@
+package grpcserver
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/realtime"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/utils"
+	nowplayingv1 "github.com/brandonhuynh1/whatamilisteningto-api/proto/nowplaying/v1"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// kindCodes maps error kinds to gRPC status codes
+var kindCodes = map[apperr.Kind]codes.Code{
+	apperr.KindInvalid:       codes.InvalidArgument,
+	apperr.KindUnauthorized:  codes.Unauthenticated,
+	apperr.KindForbidden:     codes.PermissionDenied,
+	apperr.KindNotFound:      codes.NotFound,
+	apperr.KindNotAcceptable: codes.InvalidArgument,
+	apperr.KindTooLarge:      codes.ResourceExhausted,
+	apperr.KindConflict:      codes.AlreadyExists,
+	apperr.KindRateLimited:   codes.ResourceExhausted,
+	apperr.KindUnavailable:   codes.Unavailable,
+	apperr.KindInternal:      codes.Internal,
+}
+
+// New builds a gRPC server exposing NowPlayingService plus the standard health
+// and reflection services
+func New(profileService *services.ProfileService, userService *services.UserService, spotifyService *services.SpotifyService, logger zerolog.Logger) *grpc.Server {
+	logger = logger.With().Str("handler", "grpc").Logger()
+
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unaryLogger(logger)),
+		grpc.ChainStreamInterceptor(streamLogger(logger)),
+	)
+
+	nowplayingv1.RegisterNowPlayingServiceServer(srv, &nowPlayingServer{
+		profileService: profileService,
+		userService:    userService,
+		spotifyService: spotifyService,
+		logger:         logger,
+	})
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	reflection.Register(srv)
+
+	return srv
+}
+
+type nowPlayingServer struct {
+	nowplayingv1.UnimplementedNowPlayingServiceServer
+
+	profileService *services.ProfileService
+	userService    *services.UserService
+	spotifyService *services.SpotifyService
+	logger         zerolog.Logger
+}
+
+// GetProfile returns a public profile with its current and recent tracks
+func (s *nowPlayingServer) GetProfile(ctx context.Context, req *nowplayingv1.GetProfileRequest) (*nowplayingv1.Profile, error) {
+	user, err := s.sharingUser(ctx, req.GetProfileUrl())
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.profileService.GetProfileResponse(ctx, user, s.userService)
+	if err != nil {
+		return nil, toStatus(apperr.From(err, "profile_failed", "Failed to get profile"))
+	}
+
+	profile := &nowplayingv1.Profile{
+		UserId:          resp.User.ID,
+		DisplayName:     resp.User.DisplayName,
+		ProfileUrl:      resp.User.ProfileURL,
+		Theme:           resp.Profile.Theme,
+		BackgroundColor: resp.Profile.BackgroundColor,
+		TextColor:       resp.Profile.TextColor,
+		CustomMessage:   resp.Profile.CustomMessage,
+		ShowStats:       resp.Profile.ShowStats,
+		ShowHistory:     resp.Profile.ShowHistory,
+		AnimationStyle:  resp.Profile.AnimationStyle,
+		ViewerCount:     int32(resp.ViewerCount),
+	}
+	if resp.CurrentTrack != nil {
+		profile.CurrentTrack = trackProto(resp.CurrentTrack)
+	}
+	for i := range resp.RecentTracks {
+		profile.RecentTracks = append(profile.RecentTracks, trackProto(&resp.RecentTracks[i]))
+	}
+
+	return profile, nil
+}
+
+// GetNowPlaying returns a public profile's current playback state
+func (s *nowPlayingServer) GetNowPlaying(ctx context.Context, req *nowplayingv1.GetNowPlayingRequest) (*nowplayingv1.NowPlaying, error) {
+	user, err := s.sharingUser(ctx, req.GetProfileUrl())
+	if err != nil {
+		return nil, err
+	}
+
+	return s.nowPlaying(ctx, user)
+}
+
+// WatchNowPlaying streams the current playback state followed by every change
+func (s *nowPlayingServer) WatchNowPlaying(req *nowplayingv1.WatchNowPlayingRequest, stream nowplayingv1.NowPlayingService_WatchNowPlayingServer) error {
+	ctx := stream.Context()
+
+	user, err := s.sharingUser(ctx, req.GetProfileUrl())
+	if err != nil {
+		return err
+	}
+
+	if !s.spotifyService.RealtimeAvailable() {
+		return status.Error(codes.Unavailable, "Real-time updates are temporarily unavailable")
+	}
+
+	// Subscribe before sending the initial state so no change is missed in between
+	sub := s.spotifyService.SubscribeToTrackUpdates(ctx, user.ID)
+
+	current, err := s.nowPlaying(ctx, user)
+	if err != nil {
+		return err
+	}
+	if err := stream.Send(current); err != nil {
+		return err
+	}
+
+	for event := range sub.Events() {
+		switch event.Type {
+		case realtime.EventResync:
+			// Updates may have been missed while disconnected
+			current, err := s.nowPlaying(ctx, user)
+			if err != nil {
+				return err
+			}
+			if err := stream.Send(current); err != nil {
+				return err
+			}
+		case realtime.EventMessage:
+			var track models.SpotifyCurrentlyPlaying
+			if err := json.Unmarshal([]byte(event.Payload), &track); err != nil {
+				s.logger.Warn().Ctx(ctx).Err(err).Msg("Dropping malformed track update")
+				continue
+			}
+			if err := stream.Send(nowPlayingProto(&track)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return status.FromContextError(ctx.Err()).Err()
+}
+
+// nowPlaying fetches playback state for user as a protobuf message
+func (s *nowPlayingServer) nowPlaying(ctx context.Context, user *models.User) (*nowplayingv1.NowPlaying, error) {
+	track, err := s.profileService.GetNowPlaying(ctx, user, s.userService)
+	if err != nil {
+		s.logger.Error().Ctx(ctx).Err(err).Str("profileURL", user.ProfileURL).Msg("Failed to get currently playing track")
+		return nil, toStatus(apperr.From(err, "now_playing_failed", "Failed to get currently playing track"))
+	}
+	return nowPlayingProto(track), nil
+}
+
+// sharingUser loads the profile owner, failing unless they exist and are
+// actively sharing
+func (s *nowPlayingServer) sharingUser(ctx context.Context, profileURL string) (*models.User, error) {
+	if profileURL == "" {
+		return nil, status.Error(codes.InvalidArgument, "profile_url is required")
+	}
+
+	user, err := s.userService.GetUserByProfileURL(ctx, profileURL)
+	if err != nil {
+		return nil, toStatus(apperr.From(err, "profile_lookup_failed", "Failed to load profile"))
+	}
+
+	if !user.IsActive || !user.IsSharingEnabled {
+		return nil, status.Error(codes.PermissionDenied, "Profile not available")
+	}
+
+	return user, nil
+}
+
+// toStatus converts a typed application error into a gRPC status
+func toStatus(err *apperr.Error) error {
+	code, ok := kindCodes[err.Kind]
+	if !ok {
+		code = codes.Internal
+	}
+	return status.Error(code, err.Message)
+}
+
+func nowPlayingProto(track *models.SpotifyCurrentlyPlaying) *nowplayingv1.NowPlaying {
+	return &nowplayingv1.NowPlaying{
+		IsPlaying:   track.IsPlaying,
+		TrackId:     track.TrackID,
+		TrackName:   track.TrackName,
+		ArtistName:  track.ArtistName,
+		AlbumName:   track.AlbumName,
+		AlbumArtUrl: track.AlbumArtURL,
+		TrackUrl:    track.TrackURL,
+		DurationMs:  int32(track.DurationMs),
+		ProgressMs:  int32(track.ProgressMs),
+		ChangedAt:   track.ChangedAt,
+	}
+}
+
+func trackProto(track *models.Track) *nowplayingv1.Track {
+	return &nowplayingv1.Track{
+		SpotifyTrackId: track.SpotifyTrackID,
+		Name:           track.Name,
+		Artist:         track.Artist,
+		Album:          track.Album,
+		AlbumArtUrl:    track.AlbumArtURL,
+		TrackUrl:       track.TrackURL,
+		DurationMs:     int32(track.DurationMs),
+		PlayedAt:       track.PlayedAt.UnixMilli(),
+	}
+}
+
+// unaryLogger tags each call with a request ID and logs its outcome
+func unaryLogger(logger zerolog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx = utils.WithRequestID(ctx, uuid.NewString())
+		resp, err := handler(ctx, req)
+		logCall(ctx, logger, info.FullMethod, err)
+		return resp, err
+	}
+}
+
+// streamLogger tags each stream with a request ID and logs when it ends
+func streamLogger(logger zerolog.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := utils.WithRequestID(ss.Context(), uuid.NewString())
+		err := handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+		logCall(ctx, logger, info.FullMethod, err)
+		return err
+	}
+}
+
+func logCall(ctx context.Context, logger zerolog.Logger, method string, err error) {
+	code := status.Code(err)
+	event := logger.Info()
+	switch code {
+	case codes.OK, codes.Canceled, codes.NotFound, codes.PermissionDenied, codes.InvalidArgument:
+	default:
+		event = logger.Error().Err(err)
+	}
+	event.Ctx(ctx).Str("method", method).Str("code", code.String()).Msg("")
+}
+
+// contextStream overrides the context of a server stream
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
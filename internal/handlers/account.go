@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/openapi"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/ratelimit"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+// RegisterAccountHandlers registers the route users delete their account with
+func RegisterAccountHandlers(r *gin.Engine, userService *services.UserService, retention config.RetentionConfig, limiter *ratelimit.Limiter, logger zerolog.Logger) {
+	handler := &accountHandler{
+		userService: userService,
+		graceDays:   retention.DeletedAccountDays,
+		logger:      logger.With().Str("handler", "account").Logger(),
+	}
+
+	registerAPIRoutes(r, "/account", []gin.HandlerFunc{authMiddleware(userService), sessionOnly("Deleting your account"), rateLimit(limiter, "api")}, func(account *gin.RouterGroup) {
+		handle(account, http.MethodDelete, "", openapi.Operation{
+			Summary:     "Delete your account",
+			Description: "Hides your profile, signs you out everywhere, disconnects your music provider, and removes your API keys, webhooks, scrobbling, and short links. Visits to your profile are deleted and your visits to others are anonymized. Your listening history is purged with the account after the grace period; signing in before purge_after restores it.",
+			Tag:         "account",
+			Auth:        true,
+			SessionOnly: true,
+			Responses: map[int]interface{}{
+				http.StatusOK:                  accountDeletedResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusForbidden:           errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.deleteAccount)
+	})
+}
+
+type accountHandler struct {
+	userService *services.UserService
+	graceDays   int
+	logger      zerolog.Logger
+}
+
+// deleteAccount deletes the caller's account and signs them out
+func (h *accountHandler) deleteAccount(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if err := h.userService.DeleteAccount(c.Request.Context(), userID); err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Str("userID", userID).Msg("Failed to delete account")
+		abortWithError(c, apperr.From(err, "account_delete_failed", "Failed to delete account"))
+		return
+	}
+	h.logger.Info().Ctx(c.Request.Context()).Str("userID", userID).Msg("Account deleted")
+
+	clearSessionCookie(c)
+	c.JSON(http.StatusOK, accountDeletedResponse{
+		Success:    true,
+		PurgeAfter: time.Now().AddDate(0, 0, h.graceDays).UTC(),
+	})
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/audit"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/introspect"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/metrics"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+// RegisterAdminHandlers registers operational routes. They belong on the admin
+// listener only, never on the public router. Metrics also require the metrics
+// token when one is configured. The pprof, log-level, and usage report routes
+// additionally require the admin token and are left unregistered when none is
+// configured.
+func RegisterAdminHandlers(r *gin.Engine, cfg config.AdminConfig, live *config.Live, reports *services.UsageReportService) {
+	if cfg.MetricsToken != "" {
+		r.GET("/metrics", adminAuth(cfg.MetricsToken), gin.WrapH(metrics.Handler()))
+	} else {
+		r.GET("/metrics", gin.WrapH(metrics.Handler()))
+	}
+
+	if cfg.Token == "" {
+		return
+	}
+
+	r.POST("/config/reload", adminAuth(cfg.Token), func(c *gin.Context) {
+		reloadConfig(c, live)
+	})
+
+	logLevels := r.Group("/log-levels", adminAuth(cfg.Token))
+	{
+		logLevels.GET("", getLogLevels)
+		logLevels.PUT("/:module", setLogLevel)
+		logLevels.DELETE("", resetLogLevels)
+	}
+
+	registerUsageReportRoutes(r, cfg.Token, reports)
+
+	r.GET("/debug/stats", adminAuth(cfg.Token), auditAdminAction("debug_stats"), func(c *gin.Context) {
+		c.JSON(http.StatusOK, introspect.Snapshot())
+	})
+
+	debug := r.Group("/debug/pprof", adminAuth(cfg.Token), auditAdminAction("pprof"))
+	{
+		debug.GET("/", gin.WrapF(pprof.Index))
+		debug.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+		debug.GET("/profile", gin.WrapF(pprof.Profile))
+		debug.GET("/symbol", gin.WrapF(pprof.Symbol))
+		debug.POST("/symbol", gin.WrapF(pprof.Symbol))
+		debug.GET("/trace", gin.WrapF(pprof.Trace))
+		// Named profiles: heap, goroutine, allocs, block, mutex, threadcreate
+		debug.GET("/:name", func(c *gin.Context) {
+			pprof.Handler(c.Param("name")).ServeHTTP(c.Writer, c.Request)
+		})
+	}
+}
+
+// adminAuth requires an "Authorization: Bearer <token>" header matching the
+// configured admin token
+func adminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			auditEvent(c, audit.EventAuthFailure, "invalid_admin_token", nil)
+			c.Header("WWW-Authenticate", `Bearer realm="admin"`)
+			abortWithError(c, apperr.Unauthorized("admin_auth_required", "Admin authentication required"))
+			return
+		}
+		c.Set(adminActorKey, true)
+		c.Next()
+	}
+}
+
+// auditAdminAction records every request that reaches the wrapped routes as
+// an admin action
+func auditAdminAction(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		auditEvent(c, audit.EventAdminAction, action, nil)
+		c.Next()
+	}
+}
+
+// getLogLevels lists every log module and its current level
+func getLogLevels(c *gin.Context) {
+	c.JSON(http.StatusOK, logLevelsResponse{Levels: utils.LogLevels()})
+}
+
+// setLogLevel changes one module's level until the next reset or restart
+func setLogLevel(c *gin.Context) {
+	var req setLogLevelRequest
+	if err := bindJSON(c, &req); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	level, err := zerolog.ParseLevel(req.Level)
+	if err != nil {
+		abortWithError(c, apperr.Invalid("invalid_level", "Invalid log level").Wrap(err))
+		return
+	}
+	if err := utils.SetLogLevel(c.Param("module"), level); err != nil {
+		abortWithError(c, apperr.NotFound("log_module_not_found", "Unknown log module").Wrap(err))
+		return
+	}
+	auditEvent(c, audit.EventAdminAction, "set_log_level", map[string]interface{}{
+		"module": c.Param("module"),
+		"level":  level.String(),
+	})
+
+	c.JSON(http.StatusOK, logLevelsResponse{Levels: utils.LogLevels()})
+}
+
+// reloadConfig re-reads the environment and applies the reloadable settings
+func reloadConfig(c *gin.Context, live *config.Live) {
+	if _, err := live.Reload(); err != nil {
+		abortWithError(c, apperr.Internal("config_reload_failed", "Failed to reload configuration", err))
+		return
+	}
+	auditEvent(c, audit.EventAdminAction, "reload_config", nil)
+	c.JSON(http.StatusOK, successResponse{Success: true})
+}
+
+// resetLogLevels restores every module to its configured level
+func resetLogLevels(c *gin.Context) {
+	utils.ResetLogLevels()
+	auditEvent(c, audit.EventAdminAction, "reset_log_levels", nil)
+	c.JSON(http.StatusOK, logLevelsResponse{Levels: utils.LogLevels()})
+}
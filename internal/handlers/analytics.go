@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/openapi"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/ratelimit"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+// RegisterAnalyticsHandlers registers the routes profile owners see who
+// visits their profile with
+func RegisterAnalyticsHandlers(r *gin.Engine, userService *services.UserService, limiter *ratelimit.Limiter, logger zerolog.Logger) {
+	handler := &analyticsHandler{
+		userService: userService,
+		logger:      logger.With().Str("handler", "analytics").Logger(),
+	}
+
+	registerAPIRoutes(r, "/analytics", []gin.HandlerFunc{authMiddleware(userService), rateLimit(limiter, "api")}, func(analytics *gin.RouterGroup) {
+		handle(analytics, http.MethodGet, "/visits", openapi.Operation{
+			Summary:     "Get your profile's visit analytics",
+			Description: "Totals visits to your profile over the range, with unique visitors (told apart by account when signed in, by IP address otherwise), the average visit duration, visits broken down by day, week (starting Monday), or month in your time zone, and the top referring sites. Visitors who opted out of tracking aren't counted, and neither are crawlers, link preview fetchers, and scripts unless include_bots is set.",
+			Tag:         "analytics",
+			Auth:        true,
+			Params: []openapi.Param{
+				rangeParam,
+				{Name: "period", In: "query", Description: "day (the default), week, or month"},
+				{Name: "include_bots", In: "query", Type: "boolean", Description: "Count visits by bots too (default false)"},
+			},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  visitAnalyticsResponse{},
+				http.StatusBadRequest:          errorResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.getVisitAnalytics)
+	})
+}
+
+type analyticsHandler struct {
+	userService *services.UserService
+	logger      zerolog.Logger
+}
+
+// getVisitAnalytics summarizes the visits to the caller's profile
+func (h *analyticsHandler) getVisitAnalytics(c *gin.Context) {
+	var req visitAnalyticsQuery
+	if err := bindQuery(c, &req); err != nil {
+		abortWithError(c, err)
+		return
+	}
+	if req.Range == "" {
+		req.Range = services.StatsRangeMonth
+	}
+	if req.Period == "" {
+		req.Period = services.PeriodDay
+	}
+	from := services.StatsRangeStart(req.Range, time.Now())
+
+	user, err := h.userService.GetUserByID(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		abortWithError(c, apperr.From(err, "user_fetch_failed", "Failed to get user"))
+		return
+	}
+
+	analytics, err := h.userService.GetVisitAnalytics(c.Request.Context(), user, from, req.Period, req.IncludeBots)
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to get visit analytics")
+		abortWithError(c, apperr.From(err, "analytics_fetch_failed", "Failed to get visit analytics"))
+		return
+	}
+
+	c.JSON(http.StatusOK, visitAnalyticsResponse{
+		Range:          req.Range,
+		From:           rangeStart(from),
+		Period:         req.Period,
+		VisitAnalytics: *analytics,
+	})
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/audit"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/idempotency"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/openapi"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/ratelimit"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+// apiKeyHeader carries API keys on requests
+const apiKeyHeader = "X-API-Key"
+
+// defaultUsageDays is how far back usage reports go without ?days=
+const defaultUsageDays = 30
+
+// APIKeyMiddleware authenticates requests that carry an X-API-Key header,
+// enforces the key's daily quota, and records the request in the key's usage.
+// authMiddleware then treats the key's owner as signed in. Requests without
+// the header pass through untouched.
+func APIKeyMiddleware(apiKeys *services.APIKeyService, userService *services.UserService, logger zerolog.Logger) gin.HandlerFunc {
+	logger = logger.With().Str("handler", "api_key").Logger()
+	return func(c *gin.Context) {
+		secret := c.GetHeader(apiKeyHeader)
+		if secret == "" {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		key, err := apiKeys.Authenticate(ctx, secret)
+		if err == nil {
+			// Keys from another tenant's users are not valid here
+			user, userErr := userService.GetUserByID(ctx, key.UserID)
+			if userErr != nil || !services.InTenant(ctx, user) {
+				err = apperr.Unauthorized("invalid_api_key", "Invalid API key")
+			}
+		}
+		if err != nil {
+			if apperr.KindOf(err) != apperr.KindUnauthorized {
+				logger.Error().Ctx(ctx).Err(err).Msg("Failed to authenticate API key")
+			}
+			auditEvent(c, audit.EventAuthFailure, "invalid_api_key", nil)
+			abortWithError(c, apperr.From(err, "api_key_failed", "Failed to authenticate API key"))
+			return
+		}
+
+		var exceeded *services.QuotaExceededError
+		if err := apiKeys.TakeQuota(ctx, key); errors.As(err, &exceeded) {
+			retryAfter := int(math.Ceil(exceeded.RetryAfter.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			auditEvent(c, audit.EventRateLimited, "api_key_quota", map[string]interface{}{
+				"api_key_id":          key.ID,
+				"retry_after_seconds": retryAfter,
+			})
+			abortWithError(c, apperr.RateLimited("api_key_quota_exceeded", "API key daily quota exceeded").
+				WithDetails(gin.H{"daily_quota": exceeded.Quota, "retry_after_seconds": retryAfter}))
+			return
+		}
+
+		c.Set("api_key_id", key.ID)
+		c.Set("api_key_user_id", key.UserID)
+		c.Next()
+
+		// Unmatched paths would each count as their own endpoint
+		if route := c.FullPath(); route != "" {
+			apiKeys.RecordUsage(ctx, key.ID, c.Request.Method+" "+route)
+		}
+	}
+}
+
+// sessionOnly rejects requests authenticated with an API key, so a leaked key
+// can't be used to mint or revoke credentials. action names what is refused.
+func sessionOnly(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("api_key_id") != "" {
+			abortWithError(c, apperr.Forbidden("session_required", action+" requires signing in"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RegisterAPIKeyHandlers registers the routes users manage their API keys
+// and view their usage with
+func RegisterAPIKeyHandlers(r *gin.Engine, apiKeys *services.APIKeyService, userService *services.UserService, limiter *ratelimit.Limiter, idempotencyStore *idempotency.Store, logger zerolog.Logger) {
+	handler := &apiKeyHandler{
+		apiKeys: apiKeys,
+		logger:  logger.With().Str("handler", "api_key").Logger(),
+	}
+
+	registerAPIRoutes(r, "/keys", []gin.HandlerFunc{authMiddleware(userService), sessionOnly("Managing API keys"), rateLimit(limiter, "api")}, func(keys *gin.RouterGroup) {
+		handle(keys, http.MethodPost, "", openapi.Operation{
+			Summary:     "Create an API key",
+			Description: "The key is only returned in this response; store it somewhere safe. Send it in the X-API-Key header to call the API as yourself.",
+			Tag:         "keys",
+			Auth:        true,
+			SessionOnly: true,
+			Params:      []openapi.Param{idempotencyKeyParam},
+			Request:     createAPIKeyRequest{},
+			Responses: map[int]interface{}{
+				http.StatusCreated:             apiKeyCreatedResponse{},
+				http.StatusBadRequest:          errorResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusForbidden:           errorResponse{},
+				http.StatusConflict:            errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, idempotent(idempotencyStore), handler.create)
+		handle(keys, http.MethodGet, "", openapi.Operation{
+			Summary:     "List your API keys",
+			Tag:         "keys",
+			Auth:        true,
+			SessionOnly: true,
+			Responses: map[int]interface{}{
+				http.StatusOK:                  apiKeysResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusForbidden:           errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.list)
+		handle(keys, http.MethodDelete, "/:id", openapi.Operation{
+			Summary:     "Revoke an API key",
+			Tag:         "keys",
+			Auth:        true,
+			SessionOnly: true,
+			Params: []openapi.Param{
+				{Name: "id", In: "path", Description: "API key ID"},
+			},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  successResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusForbidden:           errorResponse{},
+				http.StatusNotFound:            errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.revoke)
+		handle(keys, http.MethodGet, "/:id/usage", openapi.Operation{
+			Summary:     "Get an API key's usage",
+			Description: "Returns requests per UTC day, today's count against the daily quota, when the key was last used, and its busiest endpoints over the last days days.",
+			Tag:         "keys",
+			Auth:        true,
+			SessionOnly: true,
+			Params: []openapi.Param{
+				{Name: "id", In: "path", Description: "API key ID"},
+				{Name: "days", In: "query", Description: "Days of usage to report, 1-90 (default 30)"},
+			},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  apiKeyUsageResponse{},
+				http.StatusBadRequest:          errorResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusForbidden:           errorResponse{},
+				http.StatusNotFound:            errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.usage)
+	})
+}
+
+type apiKeyHandler struct {
+	apiKeys *services.APIKeyService
+	logger  zerolog.Logger
+}
+
+// create issues a new API key for the caller
+func (h *apiKeyHandler) create(c *gin.Context) {
+	var req createAPIKeyRequest
+	if err := bindJSON(c, &req); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	key, secret, err := h.apiKeys.CreateKey(c.Request.Context(), c.GetString("user_id"), req.Name)
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to create API key")
+		abortWithError(c, apperr.From(err, "api_key_create_failed", "Failed to create API key"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, apiKeyCreatedResponse{APIKey: *key, Key: secret})
+}
+
+// list returns the caller's API keys
+func (h *apiKeyHandler) list(c *gin.Context) {
+	keys, err := h.apiKeys.ListKeys(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to list API keys")
+		abortWithError(c, apperr.From(err, "api_key_list_failed", "Failed to list API keys"))
+		return
+	}
+
+	c.JSON(http.StatusOK, apiKeysResponse{Keys: keys})
+}
+
+// revoke deletes one of the caller's API keys
+func (h *apiKeyHandler) revoke(c *gin.Context) {
+	if err := h.apiKeys.RevokeKey(c.Request.Context(), c.GetString("user_id"), c.Param("id")); err != nil {
+		abortWithError(c, apperr.From(err, "api_key_revoke_failed", "Failed to revoke API key"))
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse{Success: true})
+}
+
+// usage reports one of the caller's API keys' usage
+func (h *apiKeyHandler) usage(c *gin.Context) {
+	var query apiKeyUsageQuery
+	if err := bindQuery(c, &query); err != nil {
+		abortWithError(c, err)
+		return
+	}
+	if query.Days == 0 {
+		query.Days = defaultUsageDays
+	}
+
+	key, err := h.apiKeys.GetKey(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		abortWithError(c, apperr.From(err, "api_key_usage_failed", "Failed to get API key usage"))
+		return
+	}
+
+	usage, err := h.apiKeys.GetUsage(c.Request.Context(), key, query.Days)
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to get API key usage")
+		abortWithError(c, apperr.From(err, "api_key_usage_failed", "Failed to get API key usage"))
+		return
+	}
+
+	c.JSON(http.StatusOK, apiKeyUsageResponse{APIKeyUsage: *usage})
+}
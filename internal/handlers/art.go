@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/openapi"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/ratelimit"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+// defaultAlbumArtSize is served when no size is requested
+const defaultAlbumArtSize = 300
+
+// RegisterAlbumArtHandlers registers the album art proxy
+func RegisterAlbumArtHandlers(r *gin.Engine, albumArt *services.AlbumArtService, limiter *ratelimit.Limiter, logger zerolog.Logger) {
+	handler := &albumArtHandler{
+		albumArt: albumArt,
+		logger:   logger.With().Str("handler", "album_art").Logger(),
+	}
+
+	sizes := make([]string, len(services.AlbumArtSizes))
+	for i, size := range services.AlbumArtSizes {
+		sizes[i] = strconv.Itoa(size)
+	}
+
+	art := r.Group("/art", rateLimit(limiter, "public"))
+	handle(art, http.MethodGet, "/:trackID", openapi.Operation{
+		Summary:     "Get album art for a track",
+		Description: "Serves the album art of a track from listening history as a square JPEG, resized and cached by this server. Supports ETag/If-None-Match.",
+		Tag:         "public",
+		Params: []openapi.Param{
+			{Name: "trackID", In: "path", Description: "Track ID, as in track_id of now playing"},
+			{Name: "size", In: "query", Type: "integer", Description: fmt.Sprintf("Width and height in pixels: %s (default %d)", strings.Join(sizes, ", "), defaultAlbumArtSize)},
+		},
+		Responses: map[int]interface{}{
+			http.StatusOK:                 nil,
+			http.StatusNotModified:        nil,
+			http.StatusBadRequest:         errorResponse{},
+			http.StatusNotFound:           errorResponse{},
+			http.StatusTooManyRequests:    errorResponse{},
+			http.StatusServiceUnavailable: errorResponse{},
+		},
+	}, handler.getAlbumArt)
+}
+
+type albumArtHandler struct {
+	albumArt *services.AlbumArtService
+	logger   zerolog.Logger
+}
+
+// getAlbumArt serves a track's resized album art
+func (h *albumArtHandler) getAlbumArt(c *gin.Context) {
+	size := defaultAlbumArtSize
+	if raw := c.Query("size"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || !slices.Contains(services.AlbumArtSizes, parsed) {
+			abortWithError(c, apperr.Invalid("invalid_size", fmt.Sprintf("size must be one of %v", services.AlbumArtSizes)))
+			return
+		}
+		size = parsed
+	}
+
+	data, err := h.albumArt.GetAlbumArt(c.Request.Context(), c.Param("trackID"), size)
+	if err != nil {
+		if apperr.KindOf(err) != apperr.KindNotFound {
+			h.logger.Warn().Ctx(c.Request.Context()).Err(err).Str("track_id", c.Param("trackID")).Msg("Failed to serve album art")
+		}
+		abortWithError(c, apperr.From(err, "art_failed", "Failed to get album art"))
+		return
+	}
+
+	hash := fnv.New64a()
+	hash.Write(data)
+	if notModified(c, fmt.Sprintf(`"%x"`, hash.Sum64())) {
+		return
+	}
+	c.Data(http.StatusOK, "image/jpeg", data)
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/audit"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// auditLoggerKey holds the security audit logger on the Gin context
+	auditLoggerKey = "audit_logger"
+
+	// adminActorKey marks requests authenticated with the admin token
+	adminActorKey = "admin_actor"
+)
+
+// AuditMiddleware makes the security audit logger available to handlers and
+// middleware further down the chain
+func AuditMiddleware(logger *audit.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(auditLoggerKey, logger)
+		c.Next()
+	}
+}
+
+// auditEvent records a security event along with the caller's request details
+func auditEvent(c *gin.Context, eventType, reason string, fields map[string]interface{}) {
+	logger, _ := c.Value(auditLoggerKey).(*audit.Logger)
+
+	actor := c.GetString("user_id")
+	if actor == "" && c.GetBool(adminActorKey) {
+		actor = "admin"
+	}
+
+	logger.Log(c.Request.Context(), audit.Event{
+		Type:      eventType,
+		Reason:    reason,
+		Actor:     actor,
+		IP:        c.ClientIP(),
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.Path,
+		UserAgent: c.Request.UserAgent(),
+		Fields:    fields,
+	})
+}
@@ -1,135 +1,345 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/audit"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/openapi"
 	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 )
 
-// RegisterAuthHandlers registers all auth-related routes
-func RegisterAuthHandlers(r *gin.Engine, userService *services.UserService, spotifyService *services.SpotifyService, logger zerolog.Logger) {
+// RegisterAuthHandlers registers all auth-related routes. Sign-in routes
+// are registered for each configured provider, and the Apple Music routes
+// only when appleMusic is configured.
+func RegisterAuthHandlers(r *gin.Engine, userService *services.UserService, providers *services.Providers, appleMusic *services.AppleMusicService, logger zerolog.Logger) {
 	handler := &authHandler{
-		userService:    userService,
-		spotifyService: spotifyService,
-		logger:         logger.With().Str("handler", "auth").Logger(),
+		userService: userService,
+		appleMusic:  appleMusic,
+		logger:      logger.With().Str("handler", "auth").Logger(),
 	}
 
 	auth := r.Group("/auth")
 	{
-		auth.GET("/spotify", handler.initiateSpotifyAuth)
-		auth.GET("/spotify/callback", handler.handleSpotifyCallback)
-		auth.GET("/logout", handler.logout)
-		auth.GET("/status", handler.checkAuthStatus)
+		for _, name := range []string{services.ProviderSpotify, services.ProviderTidal, services.ProviderDeezer} {
+			if provider, ok := providers.Get(name); ok {
+				registerOAuthRoutes(auth, handler, name, provider)
+			}
+		}
+		handle(auth, http.MethodGet, "/logout", openapi.Operation{
+			Summary:   "Sign out",
+			Tag:       "auth",
+			Responses: map[int]interface{}{http.StatusTemporaryRedirect: nil},
+		}, handler.logout)
+		handle(auth, http.MethodGet, "/status", openapi.Operation{
+			Summary:   "Check whether the caller is signed in",
+			Tag:       "auth",
+			Responses: map[int]interface{}{http.StatusOK: authStatusResponse{}},
+		}, handler.checkAuthStatus)
+
+		if appleMusic != nil {
+			registerAppleMusicRoutes(auth, handler, userService)
+		}
 	}
 }
 
+// timezoneCookie carries the browser's time zone through sign-in
+const timezoneCookie = "auth_timezone"
+
+// timezoneParam documents the time zone sign-in pages pass along
+var timezoneParam = openapi.Param{
+	Name:        "timezone",
+	In:          "query",
+	Description: "The browser's IANA time zone (Intl.DateTimeFormat().resolvedOptions().timeZone), saved if the account has none yet",
+}
+
+// registerOAuthRoutes registers /auth/<path> and its callback for a provider
+// that signs in with OAuth
+func registerOAuthRoutes(auth *gin.RouterGroup, handler *authHandler, path string, provider services.MusicProvider) {
+	label := providerLabel(provider)
+	stateCookie := path + "_auth_state"
+
+	handle(auth, http.MethodGet, "/"+path, openapi.Operation{
+		Summary:   "Start the " + label + " OAuth flow",
+		Tag:       "auth",
+		Params:    []openapi.Param{timezoneParam},
+		Responses: map[int]interface{}{http.StatusTemporaryRedirect: nil},
+	}, handler.initiateAuth(provider, stateCookie))
+	handle(auth, http.MethodGet, "/"+path+"/callback", openapi.Operation{
+		Summary: "Complete the " + label + " OAuth flow",
+		Tag:     "auth",
+		Params: []openapi.Param{
+			{Name: "code", In: "query", Required: true, Description: "Authorization code from " + label},
+			{Name: "state", In: "query", Required: true, Description: "State issued by /auth/" + path},
+		},
+		Responses: map[int]interface{}{
+			http.StatusTemporaryRedirect:   nil,
+			http.StatusBadRequest:          errorResponse{},
+			http.StatusInternalServerError: errorResponse{},
+		},
+	}, handler.handleCallback(provider, stateCookie))
+}
+
+// registerAppleMusicRoutes registers Sign in with Apple and the endpoints the
+// browser uses to connect Apple Music with MusicKit JS
+func registerAppleMusicRoutes(auth *gin.RouterGroup, handler *authHandler, userService *services.UserService) {
+	handle(auth, http.MethodGet, "/apple", openapi.Operation{
+		Summary:   "Start Sign in with Apple",
+		Tag:       "auth",
+		Params:    []openapi.Param{timezoneParam},
+		Responses: map[int]interface{}{http.StatusTemporaryRedirect: nil},
+	}, handler.initiateAuth(handler.appleMusic, "apple_auth_state"))
+	handle(auth, http.MethodGet, "/apple/callback", openapi.Operation{
+		Summary: "Complete Sign in with Apple",
+		Tag:     "auth",
+		Params: []openapi.Param{
+			{Name: "code", In: "query", Required: true, Description: "Authorization code from Apple"},
+			{Name: "state", In: "query", Required: true, Description: "State issued by /auth/apple"},
+		},
+		Responses: map[int]interface{}{
+			http.StatusTemporaryRedirect:   nil,
+			http.StatusBadRequest:          errorResponse{},
+			http.StatusInternalServerError: errorResponse{},
+		},
+	}, handler.handleCallback(handler.appleMusic, "apple_auth_state"))
+
+	apple := auth.Group("/apple", authMiddleware(userService))
+	handle(apple, http.MethodGet, "/developer-token", openapi.Operation{
+		Summary:     "Get a MusicKit developer token",
+		Description: "Configures MusicKit JS so the browser can ask the user to authorize Apple Music.",
+		Tag:         "auth",
+		Auth:        true,
+		Responses: map[int]interface{}{
+			http.StatusOK:                  appleDeveloperTokenResponse{},
+			http.StatusUnauthorized:        errorResponse{},
+			http.StatusInternalServerError: errorResponse{},
+		},
+	}, handler.getAppleDeveloperToken)
+	handle(apple, http.MethodPost, "/music-token", openapi.Operation{
+		Summary:     "Connect Apple Music",
+		Description: "Stores the Music User Token MusicKit JS returned after the user authorized Apple Music. Only for users who signed in with Apple.",
+		Tag:         "auth",
+		Auth:        true,
+		Request:     appleMusicTokenRequest{},
+		Responses: map[int]interface{}{
+			http.StatusOK:                  successResponse{},
+			http.StatusBadRequest:          errorResponse{},
+			http.StatusUnauthorized:        errorResponse{},
+			http.StatusForbidden:           errorResponse{},
+			http.StatusInternalServerError: errorResponse{},
+		},
+	}, handler.setAppleMusicToken)
+}
+
 type authHandler struct {
-	userService    *services.UserService
-	spotifyService *services.SpotifyService
-	logger         zerolog.Logger
+	userService *services.UserService
+	appleMusic  *services.AppleMusicService
+	logger      zerolog.Logger
 }
 
-// initiateSpotifyAuth redirects to Spotify's auth page
-func (h *authHandler) initiateSpotifyAuth(c *gin.Context) {
-	// Generate a random state for security
-	state := uuid.New().String()
+// initiateAuth redirects to a provider's sign-in page
+func (h *authHandler) initiateAuth(provider services.MusicProvider, stateCookie string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Generate a random state for security
+		state := uuid.New().String()
 
-	// Store state in cookie for validation later
-	c.SetCookie("spotify_auth_state", state, 60*15, "/", "", false, true)
+		// Store state in cookie for validation later
+		c.SetCookie(stateCookie, state, 60*15, "/", "", false, true)
+		if timezone := c.Query("timezone"); services.ValidTimezone(timezone) {
+			c.SetCookie(timezoneCookie, timezone, 60*15, "/", "", false, true)
+		}
 
-	// Redirect to Spotify login
-	authURL := h.spotifyService.GetAuthURL(state)
-	c.Redirect(http.StatusTemporaryRedirect, authURL)
+		// Redirect to the provider's login
+		authURL := provider.GetAuthURL(c.Request.Context(), state)
+		c.Redirect(http.StatusTemporaryRedirect, authURL)
+	}
 }
 
-// handleSpotifyCallback processes Spotify's auth callback
-func (h *authHandler) handleSpotifyCallback(c *gin.Context) {
-	// Get code and state from query params
-	code := c.Query("code")
-	state := c.Query("state")
-
-	// Get stored state from cookie
-	storedState, err := c.Cookie("spotify_auth_state")
-	if err != nil || state != storedState {
-		h.logger.Error().Err(err).Str("provided_state", state).Str("stored_state", storedState).Msg("State validation failed")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "State validation failed"})
-		return
+// handleCallback processes a provider's auth callback
+func (h *authHandler) handleCallback(provider services.MusicProvider, stateCookie string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get code and state from query params
+		code := c.Query("code")
+		state := c.Query("state")
+
+		// Get stored state from cookie
+		storedState, err := c.Cookie(stateCookie)
+		if err != nil || state != storedState {
+			// The values themselves stay out of the logs: a state that leaks
+			// while its cookie is still live could be replayed
+			reason := "mismatch"
+			switch {
+			case err != nil || storedState == "":
+				reason = "cookie_missing"
+			case state == "":
+				reason = "param_missing"
+			}
+			h.logger.Error().Ctx(c.Request.Context()).Str("reason", reason).Msg("State validation failed")
+			auditEvent(c, audit.EventCSRFRejected, "oauth_state_mismatch", nil)
+			abortWithError(c, apperr.Invalid("oauth_state_mismatch", "State validation failed"))
+			return
+		}
+
+		h.completeSignIn(c, provider, code, state)
 	}
+}
 
+// completeSignIn exchanges an authorization code with a provider, creates or
+// updates the user, and starts their session
+func (h *authHandler) completeSignIn(c *gin.Context, provider services.MusicProvider, code, state string) {
 	// Exchange code for tokens
-	tokenResponse, err := h.spotifyService.ExchangeCodeForToken(c.Request.Context(), code)
+	token, err := provider.ExchangeCodeForToken(c.Request.Context(), code, state)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("Failed to exchange code for token")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate with Spotify"})
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Str("provider", provider.Name()).Msg("Failed to exchange code for token")
+		auditEvent(c, audit.EventAuthFailure, "oauth_code_exchange_failed", nil)
+		abortWithError(c, apperr.From(err, provider.Name()+"_auth_failed", "Failed to authenticate with "+providerLabel(provider)))
 		return
 	}
 
-	// Get user info from Spotify
-	spotifyID, email, displayName, err := h.spotifyService.GetUserProfile(c.Request.Context(), tokenResponse.AccessToken)
+	// Get user info from the provider
+	account, err := provider.GetAccount(c.Request.Context(), token)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("Failed to get user profile from Spotify")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user profile"})
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Str("provider", provider.Name()).Msg("Failed to get user account")
+		abortWithError(c, apperr.From(err, provider.Name()+"_profile_failed", "Failed to get user profile"))
 		return
 	}
 
 	// Create or update user
-	user, err := h.userService.CreateOrUpdateUser(
-		c.Request.Context(),
-		spotifyID,
-		email,
-		displayName,
-		tokenResponse.AccessToken,
-		tokenResponse.RefreshToken,
-		tokenResponse.ExpiresIn,
-	)
-
+	user, err := h.userService.CreateOrUpdateUser(c.Request.Context(), provider.Name(), account, token)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("Failed to create/update user")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process user data"})
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to create/update user")
+		abortWithError(c, apperr.From(err, "user_upsert_failed", "Failed to process user data"))
 		return
 	}
 
+	// Adopt the browser's time zone on first sign-in
+	if timezone, err := c.Cookie(timezoneCookie); err == nil && user.Timezone == "" && services.ValidTimezone(timezone) {
+		if err := h.userService.DetectUserTimezone(c.Request.Context(), user.ID, timezone); err != nil {
+			h.logger.Warn().Ctx(c.Request.Context()).Err(err).Msg("Failed to save detected timezone")
+		}
+	}
+	c.SetCookie(timezoneCookie, "", -1, "/", "", false, true)
+
+	// Replace any session this browser already had
+	h.endSession(c)
+
 	// Create session for user
-	c.SetCookie("user_id", user.ID, 3600*24*30, "/", "", false, true)
+	sessionToken, _, err := h.userService.CreateSession(c.Request.Context(), user.ID, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to create session")
+		abortWithError(c, apperr.From(err, "session_create_failed", "Failed to sign in"))
+		return
+	}
+	setSessionCookie(c, sessionToken, h.userService.SessionLifetime())
 
 	// Redirect to user's profile
 	c.Redirect(http.StatusTemporaryRedirect, "/profile/"+user.ProfileURL)
 }
 
-// logout logs the user out
+// providerLabels are providers' names for messages and docs
+var providerLabels = map[string]string{
+	services.ProviderSpotify:    "Spotify",
+	services.ProviderAppleMusic: "Apple",
+	services.ProviderTidal:      "TIDAL",
+	services.ProviderDeezer:     "Deezer",
+}
+
+// providerLabel returns a provider's name for messages
+func providerLabel(provider services.MusicProvider) string {
+	if label, ok := providerLabels[provider.Name()]; ok {
+		return label
+	}
+	return provider.Name()
+}
+
+// getAppleDeveloperToken returns the MusicKit developer token
+func (h *authHandler) getAppleDeveloperToken(c *gin.Context) {
+	token, expiresAt, err := h.appleMusic.DeveloperToken()
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to sign developer token")
+		abortWithError(c, apperr.Internal("developer_token_failed", "Failed to create developer token", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, appleDeveloperTokenResponse{
+		DeveloperToken: token,
+		ExpiresAt:      expiresAt.UTC().Format(time.RFC3339),
+	})
+}
+
+// setAppleMusicToken stores the caller's Music User Token
+func (h *authHandler) setAppleMusicToken(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req appleMusicTokenRequest
+	if err := bindJSON(c, &req); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	user, err := h.userService.GetUserByID(c.Request.Context(), userID)
+	if err != nil {
+		abortWithError(c, apperr.From(err, "user_fetch_failed", "Failed to get user"))
+		return
+	}
+	if user.Provider != services.ProviderAppleMusic {
+		abortWithError(c, apperr.Forbidden("not_apple_music_user", "Only users who signed in with Apple can connect Apple Music"))
+		return
+	}
+
+	lifetime := int(services.AppleMusicTokenLifetime.Seconds())
+	if err := h.userService.UpdateUserToken(c.Request.Context(), userID, req.MusicUserToken, lifetime); err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to store Music User Token")
+		abortWithError(c, apperr.From(err, "token_update_failed", "Failed to connect Apple Music"))
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse{Success: true})
+}
+
+// logout ends the browser's session
 func (h *authHandler) logout(c *gin.Context) {
-	// Clear cookies
-	c.SetCookie("user_id", "", -1, "/", "", false, true)
+	h.endSession(c)
+	clearSessionCookie(c)
 
 	// Redirect to home page
 	c.Redirect(http.StatusTemporaryRedirect, "/")
 }
 
-// checkAuthStatus checks if the user is authenticated
-func (h *authHandler) checkAuthStatus(c *gin.Context) {
-	userID, err := c.Cookie("user_id")
-	if err != nil {
-		c.JSON(http.StatusOK, gin.H{"authenticated": false})
+// endSession ends the session the request's cookie belongs to, if any
+func (h *authHandler) endSession(c *gin.Context) {
+	token, err := c.Cookie(sessionCookie)
+	if err != nil || token == "" {
 		return
 	}
+	if err := h.userService.EndSession(c.Request.Context(), token); err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to end session")
+	}
+}
 
-	user, err := h.userService.GetUserByID(c.Request.Context(), userID)
+// checkAuthStatus checks if the user is authenticated
+func (h *authHandler) checkAuthStatus(c *gin.Context) {
+	user, _, err := currentUser(c, h.userService)
 	if err != nil {
-		c.SetCookie("user_id", "", -1, "/", "", false, true)
-		c.JSON(http.StatusOK, gin.H{"authenticated": false})
+		if !errors.Is(err, http.ErrNoCookie) {
+			clearSessionCookie(c)
+		}
+		c.JSON(http.StatusOK, authStatusResponse{Authenticated: false})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"authenticated": true,
-		"user": gin.H{
-			"id":          user.ID,
-			"displayName": user.DisplayName,
-			"profileUrl":  user.ProfileURL,
-			"isSharing":   user.IsSharingEnabled,
+	c.JSON(http.StatusOK, authStatusResponse{
+		Authenticated: true,
+		User: &authStatusUser{
+			ID:          user.ID,
+			DisplayName: user.DisplayName,
+			ProfileURL:  user.ProfileURL,
+			IsSharing:   user.IsSharingEnabled,
 		},
 	})
 }
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/openapi"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/ratelimit"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+// RegisterBadgeHandlers registers the embeddable now playing badge
+func RegisterBadgeHandlers(r *gin.Engine, badges *services.BadgeService, profileService *services.ProfileService, userService *services.UserService, limiter *ratelimit.Limiter, live *config.Live, logger zerolog.Logger) {
+	handler := &badgeHandler{
+		badges:         badges,
+		profileService: profileService,
+		userService:    userService,
+		config:         live,
+		logger:         logger.With().Str("handler", "badge").Logger(),
+	}
+
+	badge := r.Group("/badge", rateLimit(limiter, "public"))
+	handleWithHead(badge, "/:file", openapi.Operation{
+		Summary:     "Get a profile's now playing badge",
+		Description: "Renders the currently playing track, artist, and album art as an SVG card in the profile's colors, for embedding in READMEs and blogs. Badges are cached briefly. Supports ETag/If-None-Match and Last-Modified/If-Modified-Since, keyed to the last track change.",
+		Tag:         "public",
+		Params: []openapi.Param{
+			{Name: "file", In: "path", Description: "Profile slug followed by .svg"},
+		},
+		Responses: map[int]interface{}{
+			http.StatusOK:                  nil,
+			http.StatusNotModified:         nil,
+			http.StatusForbidden:           errorResponse{},
+			http.StatusNotFound:            errorResponse{},
+			http.StatusTooManyRequests:     errorResponse{},
+			http.StatusInternalServerError: errorResponse{},
+		},
+	}, handler.getBadge)
+}
+
+type badgeHandler struct {
+	badges         *services.BadgeService
+	profileService *services.ProfileService
+	userService    *services.UserService
+	config         *config.Live
+	logger         zerolog.Logger
+}
+
+// getBadge serves a profile's now playing badge
+func (h *badgeHandler) getBadge(c *gin.Context) {
+	user, ok := imageProfileUser(c, h.userService, "/badge", ".svg")
+	if !ok {
+		return
+	}
+
+	track, err := h.profileService.GetNowPlaying(c.Request.Context(), user, h.userService)
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Str("profileURL", user.ProfileURL).Msg("Failed to get currently playing track")
+		abortWithError(c, apperr.From(err, "now_playing_failed", "Failed to get currently playing track"))
+		return
+	}
+
+	ttl := time.Duration(h.config.Get().Embed.BadgeCacheSeconds) * time.Second
+	svg, err := h.badges.RenderBadge(c.Request.Context(), user, track, ttl)
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Str("profileURL", user.ProfileURL).Msg("Failed to render badge")
+		abortWithError(c, apperr.From(err, "badge_failed", "Failed to render badge"))
+		return
+	}
+
+	hash := fnv.New64a()
+	hash.Write(svg)
+	if notModifiedSince(c, fmt.Sprintf(`"%x"`, hash.Sum64()), trackLastModified(track)) {
+		return
+	}
+	c.Data(http.StatusOK, "image/svg+xml; charset=utf-8", svg)
+}
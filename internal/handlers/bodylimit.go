@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// uploadMediaTypes get the upload body limit; everything else is held to the
+// JSON limit
+var uploadMediaTypes = map[string]bool{
+	"multipart/form-data":      true,
+	"application/octet-stream": true,
+}
+
+// BodyLimitMiddleware caps request body sizes: uploads get the larger limit,
+// everything else the JSON limit. Bodies that declare an oversized
+// Content-Length are rejected up front; others fail when reading passes the limit.
+func BodyLimitMiddleware(cfg config.BodyLimitConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		limit := cfg.JSONBytes
+		if mediaType, _, err := mime.ParseMediaType(c.GetHeader("Content-Type")); err == nil && uploadMediaTypes[mediaType] {
+			limit = cfg.UploadBytes
+		}
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > limit {
+			abortWithError(c, bodyTooLarge(limit))
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
+
+// bodyReadError converts a failure reading the request body into an API
+// error, reporting bodies cut off by BodyLimitMiddleware as too large
+func bodyReadError(err error, fallback *apperr.Error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return bodyTooLarge(maxBytesErr.Limit)
+	}
+	return fallback.Wrap(err)
+}
+
+func bodyTooLarge(limit int64) *apperr.Error {
+	return apperr.TooLarge("body_too_large", "Request body is too large").
+		WithDetails(gin.H{"limit_bytes": limit})
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// cachePolicyRoute assigns a cache policy to every route under a path prefix
+type cachePolicyRoute struct {
+	prefix string
+	policy string
+}
+
+// cachePolicyRoutes map routes to cache policies; the first matching prefix wins
+var cachePolicyRoutes = []cachePolicyRoute{
+	{prefix: "/api/v1/public/", policy: "public"},
+	{prefix: "/api/public/profiles/", policy: "public"},
+	{prefix: "/api/openapi.json", policy: "docs"},
+	{prefix: "/api/docs", policy: "docs"},
+	{prefix: "/api/", policy: "private"},
+	{prefix: "/healthz", policy: "private"},
+	{prefix: "/version", policy: "private"},
+	{prefix: "/openapi.json", policy: "docs"},
+	{prefix: "/docs", policy: "docs"},
+	{prefix: "/static/", policy: "static"},
+	{prefix: "/art/", policy: "static"},
+	{prefix: "/badge/", policy: "public"},
+	{prefix: "/og/", policy: "public"},
+}
+
+// CacheControlMiddleware sets Cache-Control and Surrogate-Control on responses
+// according to the configured policy for their route. Only GET and HEAD
+// successes are cacheable; other methods and error responses get no-store.
+func CacheControlMiddleware(cfg config.CacheControlConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		policy, ok := cfg.Policies[cachePolicyFor(c.Request.URL.Path)]
+		if !ok {
+			c.Next()
+			return
+		}
+
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Header("Cache-Control", "no-store")
+			c.Next()
+			return
+		}
+
+		if policy.CacheControl != "" {
+			c.Header("Cache-Control", policy.CacheControl)
+		}
+		if policy.SurrogateControl != "" {
+			c.Header("Surrogate-Control", policy.SurrogateControl)
+		}
+
+		c.Next()
+
+		// Errors are rendered after this returns, so the headers can still change
+		if len(c.Errors) > 0 && !c.Writer.Written() {
+			c.Header("Cache-Control", "no-store")
+			c.Writer.Header().Del("Surrogate-Control")
+		}
+	}
+}
+
+func cachePolicyFor(path string) string {
+	for _, route := range cachePolicyRoutes {
+		if strings.HasPrefix(path, route.prefix) {
+			return route.policy
+		}
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// redirectToCanonicalProfile permanently redirects requests whose :profileURL
+// differs in casing from the stored slug, reporting whether it did
+func redirectToCanonicalProfile(c *gin.Context, user *models.User) bool {
+	if c.Param("profileURL") == user.ProfileURL {
+		return false
+	}
+
+	target := *c.Request.URL
+	target.Path = strings.Replace(c.FullPath(), ":profileURL", user.ProfileURL, 1)
+	target.RawPath = strings.Replace(c.FullPath(), ":profileURL", url.PathEscape(user.ProfileURL), 1)
+
+	c.Redirect(http.StatusMovedPermanently, target.RequestURI())
+	c.Abort()
+	return true
+}
+
+// imageProfileUser looks up the sharing profile an image route such as
+// /badge/:file is for, where :file is the profile slug followed by ext.
+// Like redirectToCanonicalProfile, requests for a slug in the wrong casing
+// are redirected. It aborts and reports false when there's nothing to serve.
+func imageProfileUser(c *gin.Context, userService *services.UserService, prefix, ext string) (*models.User, bool) {
+	profileURL, ok := strings.CutSuffix(c.Param("file"), ext)
+	if !ok || profileURL == "" {
+		abortWithError(c, apperr.NotFound("not_found", "Images are served at "+prefix+"/:profileURL"+ext))
+		return nil, false
+	}
+
+	user, err := userService.GetUserByProfileURL(c.Request.Context(), profileURL)
+	if err != nil {
+		abortWithError(c, apperr.From(err, "profile_lookup_failed", "Failed to load profile"))
+		return nil, false
+	}
+
+	if profileURL != user.ProfileURL {
+		target := *c.Request.URL
+		target.Path = prefix + "/" + user.ProfileURL + ext
+		target.RawPath = prefix + "/" + url.PathEscape(user.ProfileURL) + ext
+		c.Redirect(http.StatusMovedPermanently, target.RequestURI())
+		c.Abort()
+		return nil, false
+	}
+
+	if !user.IsActive || !user.IsSharingEnabled {
+		abortWithError(c, apperr.Forbidden("profile_unavailable", "Profile not available"))
+		return nil, false
+	}
+	return user, true
+}
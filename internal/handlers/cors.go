@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// corsPathPrefixes are the routes third-party sites may call from the browser:
+// the public JSON API and embeddable widgets
+var corsPathPrefixes = []string{
+	"/api/v1/public/",
+	"/api/public/profiles/",
+}
+
+// corsPolicy is a CORS configuration compiled for per-request checks
+type corsPolicy struct {
+	allowAll         bool
+	allowed          map[string]bool
+	allowCredentials bool
+	methods          string
+	headers          string
+	maxAge           string
+}
+
+func newCORSPolicy(cfg config.CORSConfig) *corsPolicy {
+	p := &corsPolicy{
+		allowed:          make(map[string]bool, len(cfg.AllowedOrigins)),
+		allowCredentials: cfg.AllowCredentials,
+		methods:          strings.Join(cfg.AllowedMethods, ", "),
+		headers:          strings.Join(cfg.AllowedHeaders, ", "),
+		maxAge:           strconv.Itoa(cfg.MaxAgeSeconds),
+	}
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			p.allowAll = true
+		}
+		p.allowed[origin] = true
+	}
+	return p
+}
+
+// CORSMiddleware adds CORS headers to public API and embed routes and answers
+// their preflight requests. It is installed on the engine rather than on route
+// groups so OPTIONS preflights are handled even though no OPTIONS routes exist.
+// The policy follows configuration reloads.
+func CORSMiddleware(live *config.Live) gin.HandlerFunc {
+	var current atomic.Pointer[corsPolicy]
+	current.Store(newCORSPolicy(live.Get().CORS))
+	live.OnReload(func(cfg *config.Config) {
+		current.Store(newCORSPolicy(cfg.CORS))
+	})
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" || !corsEnabled(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
+		policy := current.Load()
+		c.Header("Vary", "Origin")
+		if !policy.allowAll && !policy.allowed[origin] {
+			c.Next()
+			return
+		}
+
+		// Credentialed requests can't use the wildcard, so echo the origin
+		if policy.allowAll && !policy.allowCredentials {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+		}
+		if policy.allowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+		c.Header("Access-Control-Expose-Headers", "ETag, API-Version, X-Request-ID")
+
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			c.Header("Access-Control-Allow-Methods", policy.methods)
+			c.Header("Access-Control-Allow-Headers", policy.headers)
+			c.Header("Access-Control-Max-Age", policy.maxAge)
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func corsEnabled(path string) bool {
+	for _, prefix := range corsPathPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
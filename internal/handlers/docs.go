@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+// apiDocs collects route annotations for the generated OpenAPI spec
+var apiDocs = openapi.NewRegistry()
+
+// handle registers a route and documents it in the OpenAPI spec. Routes on the
+// deprecated unversioned /api prefix are registered but left out of the spec.
+func handle(g *gin.RouterGroup, method, path string, op openapi.Operation, handlers ...gin.HandlerFunc) {
+	g.Handle(method, path, handlers...)
+
+	base := g.BasePath()
+	if strings.HasPrefix(base, "/api/") && !strings.HasPrefix(base, "/api/v1") {
+		return
+	}
+	apiDocs.Add(method, strings.TrimSuffix(base, "/")+path, op)
+}
+
+// handleWithHead registers a GET route and a matching HEAD route so caches can
+// revalidate without downloading the body
+func handleWithHead(g *gin.RouterGroup, path string, op openapi.Operation, handlers ...gin.HandlerFunc) {
+	handle(g, http.MethodGet, path, op, handlers...)
+
+	head := op
+	head.Summary = op.Summary + " (headers only)"
+	head.Responses = make(map[int]interface{}, len(op.Responses))
+	for status := range op.Responses {
+		head.Responses[status] = nil
+	}
+	handle(g, http.MethodHead, path, head, handlers...)
+}
+
+// RegisterDocsHandlers serves the OpenAPI spec and a docs UI. It must be called
+// after every other handler is registered so the spec is complete.
+func RegisterDocsHandlers(r *gin.Engine) {
+	spec := apiDocs.Build(openapi.Info{
+		Title:       "WhatAmIListeningTo API",
+		Description: "Share what you're listening to on Spotify in real time.",
+		Version:     currentAPIVersion + ".0.0",
+	})
+
+	serveSpec := func(c *gin.Context) {
+		c.JSON(http.StatusOK, spec)
+	}
+	servePage := func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(docsPage))
+	}
+
+	// Also served under /api for integrators who look for docs beside the API
+	r.GET("/openapi.json", serveSpec)
+	r.GET("/docs", servePage)
+	r.GET("/api/openapi.json", serveSpec)
+	r.GET("/api/docs", servePage)
+}
+
+const docsPage = `<!DOCTYPE html>
+<html>
+<head>
+  <title>WhatAmIListeningTo API</title>
+  <meta charset="utf-8"/>
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+</head>
+<body>
+  <redoc spec-url="/openapi.json"></redoc>
+  <script src="https://cdn.redoc.ly/redoc/latest/bundles/redoc.standalone.js"></script>
+</body>
+</html>
+`
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/errreport"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// legacyErrorsKey marks requests on deprecated routes that still expect the
+// pre-v1 {"error": "..."} shape
+const legacyErrorsKey = "legacy_errors"
+
+// htmlErrorsKey marks page routes whose errors render as HTML for browsers
+const htmlErrorsKey = "html_errors"
+
+// errorResponse is the error envelope returned by every JSON endpoint
+type errorResponse struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// legacyErrorResponse is the error shape served on deprecated unversioned routes
+type legacyErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// kindStatus maps error kinds to HTTP status codes
+var kindStatus = map[apperr.Kind]int{
+	apperr.KindInvalid:       http.StatusBadRequest,
+	apperr.KindUnauthorized:  http.StatusUnauthorized,
+	apperr.KindForbidden:     http.StatusForbidden,
+	apperr.KindNotFound:      http.StatusNotFound,
+	apperr.KindNotAcceptable: http.StatusNotAcceptable,
+	apperr.KindTooLarge:      http.StatusRequestEntityTooLarge,
+	apperr.KindConflict:      http.StatusConflict,
+	apperr.KindRateLimited:   http.StatusTooManyRequests,
+	apperr.KindUnavailable:   http.StatusServiceUnavailable,
+	apperr.KindInternal:      http.StatusInternalServerError,
+}
+
+// abortWithError records err for ErrorMiddleware and stops the handler chain
+func abortWithError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// ErrorMiddleware renders errors recorded with abortWithError as JSON error
+// envelopes, mapping error kinds to HTTP statuses
+func ErrorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		appErr := apperr.From(c.Errors.Last().Err, "internal_error", "Internal server error")
+		status, ok := kindStatus[appErr.Kind]
+		if !ok {
+			status = http.StatusInternalServerError
+		}
+
+		// Pass a music provider's rate limit on, so clients retry after it
+		var rateLimited *services.ProviderRateLimitError
+		if errors.As(appErr, &rateLimited) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(rateLimited.RetryAfter.Seconds()))))
+		}
+
+		if c.GetBool(legacyErrorsKey) {
+			c.JSON(status, legacyErrorResponse{Error: appErr.Message})
+			return
+		}
+
+		if c.GetBool(htmlErrorsKey) && !acceptsJSON(c) {
+			template := "error.html"
+			if status == http.StatusNotFound {
+				template = "404.html"
+			}
+			c.HTML(status, template, gin.H{
+				"error":      appErr.Message,
+				"request_id": c.GetString("request_id"),
+			})
+			return
+		}
+
+		c.JSON(status, errorResponse{
+			Code:      appErr.Code,
+			Message:   appErr.Message,
+			Details:   appErr.Details,
+			RequestID: c.GetString("request_id"),
+		})
+	}
+}
+
+// RecoveryMiddleware recovers from handler panics like gin.Recovery, reports
+// them, and answers with the standard 500 error envelope. It runs outside
+// ErrorMiddleware, so it writes the response itself.
+func RecoveryMiddleware(reporter errreport.Reporter) gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
+		reporter.CapturePanic(c.Request.Context(), recovered, map[string]string{
+			"route":  c.FullPath(),
+			"method": c.Request.Method,
+		})
+		c.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse{
+			Code:      "internal_error",
+			Message:   "Internal server error",
+			RequestID: c.GetString("request_id"),
+		})
+	})
+}
+
+// htmlErrors marks a page route so its errors render as HTML error pages,
+// unless the client asks for JSON
+func htmlErrors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(htmlErrorsKey, true)
+		c.Next()
+	}
+}
+
+// acceptsJSON reports whether the client prefers JSON over HTML
+func acceptsJSON(c *gin.Context) bool {
+	accept := c.GetHeader("Accept")
+	if vendorMediaType.MatchString(accept) {
+		return true
+	}
+	return c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON
+}
+
+// NotFoundHandler answers requests that match no route: a JSON error under
+// /api/ or for clients that accept JSON, an HTML 404 page otherwise
+func NotFoundHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !strings.HasPrefix(c.Request.URL.Path, "/api/") {
+			c.Set(htmlErrorsKey, true)
+		}
+		abortWithError(c, apperr.NotFound("route_not_found", "Not found"))
+	}
+}
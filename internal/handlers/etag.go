@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// nowPlayingETag derives a weak ETag from the fields that change whenever the
+// playback state does, without encoding the payload to JSON
+func nowPlayingETag(track *models.SpotifyCurrentlyPlaying) string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%t|%s|%d|%d", track.IsPlaying, track.TrackID, track.ProgressMs, track.DurationMs)
+	return fmt.Sprintf(`W/"%x"`, h.Sum64())
+}
+
+// notModified sets the ETag header and, if the client's If-None-Match already
+// matches it, writes 304 Not Modified and reports true
+func notModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+
+	ifNoneMatch := c.GetHeader("If-None-Match")
+	if ifNoneMatch == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		// If-None-Match uses weak comparison, so W/ prefixes are ignored
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// trackLastModified returns when the track or play state last changed, or the
+// zero time if unknown
+func trackLastModified(track *models.SpotifyCurrentlyPlaying) time.Time {
+	if track.ChangedAt == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(track.ChangedAt)
+}
+
+// notModifiedSince is notModified plus Last-Modified/If-Modified-Since
+// handling. If-None-Match takes precedence when present, so If-Modified-Since
+// is only consulted for clients and proxies that don't send ETags.
+func notModifiedSince(c *gin.Context, etag string, modified time.Time) bool {
+	if !modified.IsZero() {
+		c.Header("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+
+	if c.GetHeader("If-None-Match") != "" || modified.IsZero() {
+		return notModified(c, etag)
+	}
+	c.Header("ETag", etag)
+
+	since, err := http.ParseTime(c.GetHeader("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+
+	// HTTP dates have second precision
+	if !modified.Truncate(time.Second).After(since) {
+		c.Status(http.StatusNotModified)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// historyCSVHeader names the columns of a CSV history export
+var historyCSVHeader = []string{
+	"played_at", "name", "artist", "album", "duration_ms",
+	"spotify_track_id", "track_url", "album_art_url",
+}
+
+const (
+	// exportFlushEvery is how many tracks are written between flushes to
+	// the client
+	exportFlushEvery = 500
+
+	// exportWriteWindow is how long the client gets to take each chunk of an
+	// export. The deadline moves forward with every flush, so long exports
+	// aren't cut off by the server's write timeout.
+	exportWriteWindow = time.Minute
+)
+
+// exportTrackHistory streams the user's whole history as JSON or CSV. The
+// status and headers are only sent with the first track, so a failure
+// before then still gets an error response; after that the download is cut
+// short and the failure logged.
+func (h *trackHandler) exportTrackHistory(c *gin.Context) {
+	var req historyExportQuery
+	if err := bindQuery(c, &req); err != nil {
+		abortWithError(c, err)
+		return
+	}
+	format := req.Format
+	if format == "" {
+		format = "json"
+	}
+
+	filename := "listening-history-" + time.Now().UTC().Format(time.DateOnly) + "." + format
+	deadline := http.NewResponseController(c.Writer)
+	started := false
+	start := func() {
+		started = true
+		_ = deadline.SetWriteDeadline(time.Now().Add(exportWriteWindow))
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+		c.Header("Cache-Control", "no-store")
+		if format == "csv" {
+			c.Header("Content-Type", "text/csv; charset=utf-8")
+		} else {
+			c.Header("Content-Type", "application/json; charset=utf-8")
+		}
+		c.Status(http.StatusOK)
+	}
+
+	var write func(*models.Track) error
+	var finish func()
+	written := 0
+	if format == "csv" {
+		w := csv.NewWriter(c.Writer)
+		write = func(track *models.Track) error {
+			if !started {
+				start()
+				if err := w.Write(historyCSVHeader); err != nil {
+					return err
+				}
+			}
+			return w.Write([]string{
+				track.PlayedAt.UTC().Format(time.RFC3339),
+				track.Name,
+				track.Artist,
+				track.Album,
+				strconv.Itoa(track.DurationMs),
+				track.SpotifyTrackID,
+				track.TrackURL,
+				track.AlbumArtURL,
+			})
+		}
+		finish = func() {
+			if !started {
+				start()
+				_ = w.Write(historyCSVHeader)
+			}
+			w.Flush()
+		}
+	} else {
+		encoder := json.NewEncoder(c.Writer)
+		write = func(track *models.Track) error {
+			separator := ","
+			if !started {
+				start()
+				separator = "["
+			}
+			if _, err := c.Writer.WriteString(separator); err != nil {
+				return err
+			}
+			return encoder.Encode(track)
+		}
+		finish = func() {
+			if !started {
+				start()
+				_, _ = c.Writer.WriteString("[")
+			}
+			_, _ = c.Writer.WriteString("]\n")
+		}
+	}
+
+	err := h.profileService.ExportHistory(c.Request.Context(), c.GetString("user_id"), func(track *models.Track) error {
+		if err := write(track); err != nil {
+			return err
+		}
+		written++
+		if written%exportFlushEvery == 0 {
+			c.Writer.Flush()
+			_ = deadline.SetWriteDeadline(time.Now().Add(exportWriteWindow))
+		}
+		return nil
+	})
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Int("tracks_written", written).Msg("Failed to export track history")
+		if !started {
+			abortWithError(c, apperr.From(err, "export_failed", "Failed to export track history"))
+		}
+		return
+	}
+	finish()
+}
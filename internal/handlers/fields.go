@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+// maxSelectedFields bounds how many paths one ?fields= value may name
+const maxSelectedFields = 50
+
+// fieldsParam documents sparse field selection on JSON endpoints
+var fieldsParam = openapi.Param{
+	Name:        "fields",
+	In:          "query",
+	Description: "Comma-separated JSON fields to return; use dots for nested fields, e.g. tracks.name",
+}
+
+// fieldTree is a parsed ?fields= selection. A nil subtree selects the whole value.
+type fieldTree map[string]fieldTree
+
+// parseFields parses a comma-separated list of dotted JSON field paths
+func parseFields(raw string) (fieldTree, error) {
+	paths := strings.Split(raw, ",")
+	if len(paths) > maxSelectedFields {
+		return nil, apperr.Invalid("invalid_fields", "Too many fields selected")
+	}
+
+	tree := fieldTree{}
+	for _, path := range paths {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+
+		node := tree
+		parts := strings.Split(path, ".")
+		for i, part := range parts {
+			if part == "" {
+				return nil, apperr.Invalid("invalid_fields", "Invalid field path").WithDetails(gin.H{"field": path})
+			}
+			if i == len(parts)-1 {
+				// Selecting a whole value overrides narrower selections under it
+				node[part] = nil
+				break
+			}
+
+			child, seen := node[part]
+			if seen && child == nil {
+				// Already selected in full
+				break
+			}
+			if child == nil {
+				child = fieldTree{}
+				node[part] = child
+			}
+			node = child
+		}
+	}
+	return tree, nil
+}
+
+// prune keeps only the selected fields of objects, applying the selection to
+// each element of arrays
+func (t fieldTree) prune(value interface{}) interface{} {
+	if t == nil {
+		return value
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		pruned := make(map[string]interface{}, len(t))
+		for name, subtree := range t {
+			if field, ok := v[name]; ok {
+				pruned[name] = subtree.prune(field)
+			}
+		}
+		return pruned
+	case []interface{}:
+		for i := range v {
+			v[i] = t.prune(v[i])
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// sparseJSON writes v as JSON, trimmed to the fields named in ?fields= when
+// the client asks for a subset. Unknown fields are ignored.
+func sparseJSON(c *gin.Context, status int, v interface{}) {
+	raw := c.Query("fields")
+	if raw == "" {
+		c.JSON(status, v)
+		return
+	}
+
+	tree, err := parseFields(raw)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		abortWithError(c, apperr.Internal("encode_failed", "Failed to encode response", err))
+		return
+	}
+
+	var generic interface{}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	if err := decoder.Decode(&generic); err != nil {
+		abortWithError(c, apperr.Internal("encode_failed", "Failed to encode response", err))
+		return
+	}
+
+	c.JSON(status, tree.prune(generic))
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Response formats for endpoints that serve more than JSON
+const (
+	formatJSON = "json"
+	formatText = "text"
+	formatXML  = "xml"
+	// formatJSONP wraps JSON in a ?callback= call; it is never negotiated
+	formatJSONP = "jsonp"
+)
+
+// jsonpCallback matches dotted JavaScript identifiers such as myWidget.update
+var jsonpCallback = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*(\.[A-Za-z_$][A-Za-z0-9_$]*)*$`)
+
+// maxJSONPCallbackLength bounds the callback name
+const maxJSONPCallbackLength = 64
+
+// formatMediaTypes maps ?format values to the media types matched against Accept
+var formatMediaTypes = map[string]string{
+	formatJSON: gin.MIMEJSON,
+	formatText: gin.MIMEPlain,
+	formatXML:  gin.MIMEXML,
+}
+
+// nowPlayingXML is the XML document for a now-playing response
+type nowPlayingXML struct {
+	XMLName xml.Name `xml:"now_playing"`
+	*models.SpotifyCurrentlyPlaying
+}
+
+// negotiateFormat picks the response format from the format query parameter,
+// falling back to the Accept header and then JSON
+func negotiateFormat(c *gin.Context) (string, error) {
+	c.Header("Vary", "Accept")
+
+	if format := c.Query("format"); format != "" {
+		if _, ok := formatMediaTypes[format]; !ok {
+			return "", apperr.Invalid("unsupported_format", "Unsupported format").
+				WithDetails(gin.H{"supported": []string{formatJSON, formatText, formatXML}})
+		}
+		return format, nil
+	}
+
+	accept := c.GetHeader("Accept")
+	if accept == "" || vendorMediaType.MatchString(accept) {
+		return formatJSON, nil
+	}
+
+	switch c.NegotiateFormat(gin.MIMEJSON, gin.MIMEPlain, gin.MIMEXML, gin.MIMEXML2) {
+	case gin.MIMEJSON:
+		return formatJSON, nil
+	case gin.MIMEPlain:
+		return formatText, nil
+	case gin.MIMEXML, gin.MIMEXML2:
+		return formatXML, nil
+	}
+	return "", apperr.New(apperr.KindNotAcceptable, "unsupported_format", "None of the acceptable media types can be served").
+		WithDetails(gin.H{"supported": []string{gin.MIMEJSON, gin.MIMEPlain, gin.MIMEXML}})
+}
+
+// formatETag distinguishes the ETag of each representation of a resource
+func formatETag(etag, format string) string {
+	if format == formatJSON {
+		return etag
+	}
+	return strings.TrimSuffix(etag, `"`) + "-" + format + `"`
+}
+
+// renderNowPlaying writes track in the negotiated format. The text format is a
+// single "Artist – Title" line, or an empty body when nothing is playing.
+func renderNowPlaying(c *gin.Context, format string, track *models.SpotifyCurrentlyPlaying) {
+	switch format {
+	case formatText:
+		body := ""
+		if track.IsPlaying {
+			body = track.ArtistName + " – " + track.TrackName + "\n"
+		}
+		c.String(http.StatusOK, body)
+	case formatXML:
+		c.XML(http.StatusOK, nowPlayingXML{SpotifyCurrentlyPlaying: track})
+	case formatJSONP:
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.JSONP(http.StatusOK, track)
+	default:
+		c.JSON(http.StatusOK, track)
+	}
+}
+
+// validJSONPCallback reports whether name is safe to use as a JSONP callback
+func validJSONPCallback(name string) bool {
+	return len(name) <= maxJSONPCallbackLength && jsonpCallback.MatchString(name)
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/canary"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/database"
+	"github.com/gin-gonic/gin"
+)
+
+// healthCheckTimeout bounds the database ping behind /healthz
+const healthCheckTimeout = 2 * time.Second
+
+// RegisterHealthHandlers registers /healthz for load balancers and uptime
+// checks. Only a database outage fails it; Redis degraded mode and a failing
+// Spotify canary report "degraded" with a 200.
+func RegisterHealthHandlers(r *gin.Engine, db *database.DB, redis *database.RedisClient, spotifyCanary *canary.Canary) {
+	handler := &healthHandler{db: db, redis: redis, canary: spotifyCanary}
+	r.GET("/healthz", handler.healthz)
+	r.HEAD("/healthz", handler.healthz)
+}
+
+type healthHandler struct {
+	db     *database.DB
+	redis  *database.RedisClient
+	canary *canary.Canary
+}
+
+// healthz reports the state of the app's dependencies
+func (h *healthHandler) healthz(c *gin.Context) {
+	resp := healthResponse{Status: "ok", Database: healthCheck{OK: true}, Redis: healthCheck{OK: true}}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), healthCheckTimeout)
+	defer cancel()
+	// Connection details stay out of the public response
+	if err := h.db.PingContext(ctx); err != nil {
+		resp.Database = healthCheck{OK: false, Error: "database unreachable"}
+	}
+	if !h.redis.Available() {
+		resp.Redis = healthCheck{OK: false, Error: database.ErrRedisUnavailable.Error()}
+	}
+	if result, ok := h.canary.Status(); ok {
+		resp.SpotifyCanary = &result
+	}
+
+	status := http.StatusOK
+	switch {
+	case !resp.Database.OK:
+		resp.Status = "unavailable"
+		status = http.StatusServiceUnavailable
+	case !resp.Redis.OK || (resp.SpotifyCanary != nil && !resp.SpotifyCanary.OK):
+		resp.Status = "degraded"
+	}
+
+	c.JSON(status, resp)
+}
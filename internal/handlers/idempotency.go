@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/idempotency"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+// maxIdempotencyKeyLength bounds client-supplied Idempotency-Key values
+const maxIdempotencyKeyLength = 255
+
+// idempotencyKeyParam documents the Idempotency-Key header on mutating routes
+var idempotencyKeyParam = openapi.Param{
+	Name:        "Idempotency-Key",
+	In:          "header",
+	Description: "Unique key for this change; retries with the same key replay the first response instead of repeating it",
+}
+
+// idempotent makes a mutating route safe to retry. Requests carrying an
+// Idempotency-Key are processed once per caller and key; retries get the
+// stored response. Failed requests (5xx or errors) release the key. Without
+// Redis, requests are processed normally.
+func idempotent(store *idempotency.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" || !store.Available() {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			abortWithError(c, apperr.Invalid("invalid_idempotency_key", "Idempotency-Key is too long"))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			abortWithError(c, bodyReadError(err, apperr.Invalid("invalid_body", "Invalid request body")))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		// Keys are scoped per caller so clients can't replay each other's responses
+		storeKey := sha256Hex(callerIdentity(c) + "|" + key)
+		requestHash := sha256Hex(c.Request.Method + " " + c.Request.URL.Path + "\n" + string(body))
+
+		replay, err := store.Begin(c.Request.Context(), storeKey, requestHash)
+		switch {
+		case errors.Is(err, idempotency.ErrInProgress):
+			abortWithError(c, apperr.Conflict("idempotency_in_progress", "A request with this Idempotency-Key is still being processed"))
+			return
+		case errors.Is(err, idempotency.ErrMismatch):
+			abortWithError(c, apperr.Invalid("idempotency_key_reused", "Idempotency-Key was already used for a different request"))
+			return
+		case err != nil:
+			c.Next()
+			return
+		case replay != nil:
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(replay.Status, replay.ContentType, replay.Body)
+			c.Abort()
+			return
+		}
+
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// Store even if the client went away; it is likely to retry
+		ctx := context.WithoutCancel(c.Request.Context())
+		if len(c.Errors) > 0 || !recorder.Written() || recorder.Status() >= http.StatusInternalServerError {
+			_ = store.Release(ctx, storeKey)
+			return
+		}
+		_ = store.Complete(ctx, storeKey, requestHash, idempotency.Response{
+			Status:      recorder.Status(),
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		})
+	}
+}
+
+// bodyRecorder keeps a copy of the response body as it is written
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/idempotency"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/openapi"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/ratelimit"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+// RegisterShortLinkHandlers registers the /s/:code redirect and the routes
+// owners use to manage their short links
+func RegisterShortLinkHandlers(r *gin.Engine, shortLinks *services.ShortLinkService, userService *services.UserService, limiter *ratelimit.Limiter, idempotencyStore *idempotency.Store, logger zerolog.Logger) {
+	handler := &shortLinkHandler{
+		shortLinks:  shortLinks,
+		userService: userService,
+		logger:      logger.With().Str("handler", "short_link").Logger(),
+	}
+
+	r.GET("/s/:code", htmlErrors(), rateLimit(limiter, "public"), handler.follow)
+
+	registerAPIRoutes(r, "/links", []gin.HandlerFunc{authMiddleware(userService), rateLimit(limiter, "api")}, func(links *gin.RouterGroup) {
+		handle(links, http.MethodPost, "", openapi.Operation{
+			Summary:     "Create a short link to your profile",
+			Description: "Returns a /s/:code link on the host the request was made to, so tenant domains get branded links. Links without expires_in_days never expire.",
+			Tag:         "links",
+			Auth:        true,
+			Params:      []openapi.Param{idempotencyKeyParam},
+			Request:     createShortLinkRequest{},
+			Responses: map[int]interface{}{
+				http.StatusCreated:             shortLinkResponse{},
+				http.StatusBadRequest:          errorResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusConflict:            errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, idempotent(idempotencyStore), handler.create)
+		handle(links, http.MethodGet, "", openapi.Operation{
+			Summary: "List your short links",
+			Tag:     "links",
+			Auth:    true,
+			Responses: map[int]interface{}{
+				http.StatusOK:                  shortLinksResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.list)
+		handle(links, http.MethodGet, "/:code", openapi.Operation{
+			Summary:     "Get a short link's click analytics",
+			Description: "Returns the click count, last click, and the sites clicks came from. Referrers are reduced to their host; clicks without one count as direct.",
+			Tag:         "links",
+			Auth:        true,
+			Params: []openapi.Param{
+				{Name: "code", In: "path", Description: "Short link code"},
+			},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  shortLinkStatsResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusNotFound:            errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.stats)
+		handle(links, http.MethodDelete, "/:code", openapi.Operation{
+			Summary: "Delete a short link",
+			Tag:     "links",
+			Auth:    true,
+			Params: []openapi.Param{
+				{Name: "code", In: "path", Description: "Short link code"},
+			},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  successResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusNotFound:            errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.delete)
+	})
+}
+
+type shortLinkHandler struct {
+	shortLinks  *services.ShortLinkService
+	userService *services.UserService
+	logger      zerolog.Logger
+}
+
+// follow counts a click and redirects to the linked profile. The profile is
+// looked up by user, so links keep working when its URL changes.
+func (h *shortLinkHandler) follow(c *gin.Context) {
+	userID, err := h.shortLinks.ResolveLink(c.Request.Context(), c.Param("code"), c.GetHeader("Referer"))
+	if err != nil {
+		abortWithError(c, apperr.From(err, "short_link_failed", "Failed to follow link"))
+		return
+	}
+
+	user, err := h.userService.GetUserByID(c.Request.Context(), userID)
+	if err != nil {
+		abortWithError(c, apperr.From(err, "user_fetch_failed", "Failed to get user"))
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/profile/"+user.ProfileURL)
+}
+
+// create makes a short link to the caller's profile
+func (h *shortLinkHandler) create(c *gin.Context) {
+	var req createShortLinkRequest
+	if err := bindJSON(c, &req); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresInDays > 0 {
+		expires := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		expiresAt = &expires
+	}
+
+	link, err := h.shortLinks.CreateLink(c.Request.Context(), c.GetString("user_id"), req.Label, expiresAt)
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to create short link")
+		abortWithError(c, apperr.From(err, "short_link_create_failed", "Failed to create short link"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, h.response(c, *link))
+}
+
+// list returns the caller's short links
+func (h *shortLinkHandler) list(c *gin.Context) {
+	links, err := h.shortLinks.ListLinks(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to list short links")
+		abortWithError(c, apperr.From(err, "short_link_list_failed", "Failed to list short links"))
+		return
+	}
+
+	resp := shortLinksResponse{Links: make([]shortLinkResponse, 0, len(links))}
+	for _, link := range links {
+		resp.Links = append(resp.Links, h.response(c, link))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// stats returns click analytics for one of the caller's short links
+func (h *shortLinkHandler) stats(c *gin.Context) {
+	stats, err := h.shortLinks.GetLinkStats(c.Request.Context(), c.GetString("user_id"), c.Param("code"))
+	if err != nil {
+		abortWithError(c, apperr.From(err, "short_link_stats_failed", "Failed to get short link"))
+		return
+	}
+
+	c.JSON(http.StatusOK, shortLinkStatsResponse{ShortLinkStats: *stats, URL: h.linkURL(c, stats.Code)})
+}
+
+// delete removes one of the caller's short links
+func (h *shortLinkHandler) delete(c *gin.Context) {
+	if err := h.shortLinks.DeleteLink(c.Request.Context(), c.GetString("user_id"), c.Param("code")); err != nil {
+		abortWithError(c, apperr.From(err, "short_link_delete_failed", "Failed to delete short link"))
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse{Success: true})
+}
+
+func (h *shortLinkHandler) response(c *gin.Context, link models.ShortLink) shortLinkResponse {
+	return shortLinkResponse{ShortLink: link, URL: h.linkURL(c, link.Code)}
+}
+
+// linkURL is the short link on the host the request was made to
+func (h *shortLinkHandler) linkURL(c *gin.Context, code string) string {
+	return requestBaseURL(c) + "/s/" + code
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+	"github.com/rs/zerolog"
+)
+
+// lyricLineEvent is the WebSocket message type for timed lyrics
+const lyricLineEvent = "lyrics_line"
+
+// lyricLineMessage is sent over the track WebSocket as playback reaches each
+// line of synced lyrics
+type lyricLineMessage struct {
+	Type    string `json:"type"`
+	TrackID string `json:"track_id"`
+	Index   int    `json:"index"`
+	TimeMs  int    `json:"time_ms"`
+	Text    string `json:"text"`
+}
+
+// lyricsFollower schedules the synced lyric lines of the track a WebSocket
+// viewer is watching, estimating the playback position from the progress
+// reported with each track update
+type lyricsFollower struct {
+	lyrics *services.LyricsService
+	tracks chan *models.SpotifyCurrentlyPlaying
+	lines  chan lyricLineMessage
+	logger zerolog.Logger
+}
+
+func newLyricsFollower(lyrics *services.LyricsService, logger zerolog.Logger) *lyricsFollower {
+	return &lyricsFollower{
+		lyrics: lyrics,
+		tracks: make(chan *models.SpotifyCurrentlyPlaying, 1),
+		lines:  make(chan lyricLineMessage, 4),
+		logger: logger,
+	}
+}
+
+// follow replaces the schedule with track's lyrics. Only the latest track
+// matters, so a pending one that hasn't been picked up yet is dropped.
+func (f *lyricsFollower) follow(track *models.SpotifyCurrentlyPlaying) {
+	select {
+	case <-f.tracks:
+	default:
+	}
+	f.tracks <- track
+}
+
+// run emits lines on f.lines as they start until ctx is cancelled
+func (f *lyricsFollower) run(ctx context.Context) {
+	var (
+		trackID string
+		lines   []models.LyricLine
+		start   time.Time
+		next    int
+		timer   = time.NewTimer(time.Hour)
+	)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case track := <-f.tracks:
+			timer.Stop()
+			lines = nil
+			if !track.IsPlaying {
+				continue
+			}
+			lyrics, err := f.lyrics.GetLyrics(ctx, track)
+			if err != nil {
+				f.logger.Debug().Ctx(ctx).Err(err).Str("track_id", track.TrackID).Msg("No synced lyrics to follow")
+				continue
+			}
+			if len(lyrics.Lines) == 0 {
+				continue
+			}
+
+			// Progress was measured when the update was published
+			measured := time.Now()
+			if track.PublishedAt > 0 {
+				measured = time.UnixMilli(track.PublishedAt)
+			}
+			trackID = track.TrackID
+			lines = lyrics.Lines
+			start = measured.Add(-time.Duration(track.ProgressMs) * time.Millisecond)
+
+			// Resume from the line being sung now, if any
+			position := time.Since(start)
+			next = 0
+			for next < len(lines) && time.Duration(lines[next].TimeMs)*time.Millisecond <= position {
+				next++
+			}
+			if next > 0 {
+				next--
+			}
+			timer.Reset(time.Until(start.Add(time.Duration(lines[next].TimeMs) * time.Millisecond)))
+		case <-timer.C:
+			if next >= len(lines) {
+				continue
+			}
+			line := lines[next]
+			select {
+			case f.lines <- lyricLineMessage{Type: lyricLineEvent, TrackID: trackID, Index: next, TimeMs: line.TimeMs, Text: line.Text}:
+			case <-ctx.Done():
+				return
+			}
+			next++
+			if next < len(lines) {
+				timer.Reset(time.Until(start.Add(time.Duration(lines[next].TimeMs) * time.Millisecond)))
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
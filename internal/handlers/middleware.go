@@ -1,8 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/audit"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
 	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
 	"github.com/gin-gonic/gin"
 )
@@ -10,23 +15,71 @@ import (
 // authMiddleware checks if the user is authenticated
 func authMiddleware(userService *services.UserService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userID, err := c.Cookie("user_id")
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
-			c.Abort()
+		// APIKeyMiddleware has already vouched for key holders
+		if userID := c.GetString("api_key_user_id"); userID != "" {
+			c.Set("user_id", userID)
+			c.Next()
 			return
 		}
 
-		user, err := userService.GetUserByID(c.Request.Context(), userID)
+		user, session, err := currentUser(c, userService)
+		if errors.Is(err, http.ErrNoCookie) {
+			auditEvent(c, audit.EventAuthFailure, "missing_session", nil)
+			abortWithError(c, apperr.Unauthorized("authentication_required", "Authentication required"))
+			return
+		}
 		if err != nil {
-			c.SetCookie("user_id", "", -1, "/", "", false, true)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authentication"})
-			c.Abort()
+			clearSessionCookie(c)
+			auditEvent(c, audit.EventAuthFailure, "invalid_session", nil)
+			abortWithError(c, apperr.Unauthorized("invalid_authentication", "Invalid authentication"))
 			return
 		}
 
 		// Store user ID in context for handlers to use
 		c.Set("user_id", user.ID)
+		c.Set("session_id", session.ID)
 		c.Next()
 	}
 }
+
+// sessionCookie holds a signed-in browser's session token
+const sessionCookie = "session"
+
+// currentUser returns the signed-in user and session for the request's
+// session cookie, or http.ErrNoCookie when there is none. Sessions from
+// another tenant's domain are not valid here.
+func currentUser(c *gin.Context, userService *services.UserService) (*models.User, *models.Session, error) {
+	token, err := c.Cookie(sessionCookie)
+	if err != nil || token == "" {
+		return nil, nil, http.ErrNoCookie
+	}
+
+	ctx := c.Request.Context()
+	session, err := userService.AuthenticateSession(ctx, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	user, err := userService.GetUserByID(ctx, session.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !services.InTenant(ctx, user) {
+		return nil, nil, apperr.Unauthorized("invalid_authentication", "Invalid authentication")
+	}
+	return user, session, nil
+}
+
+// setSessionCookie hands the browser its session token, dropping the bare
+// user_id cookie sessions replaced
+func setSessionCookie(c *gin.Context, token string, lifetime time.Duration) {
+	c.SetCookie("user_id", "", -1, "/", "", false, true)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(sessionCookie, token, int(lifetime.Seconds()), "/", "", false, true)
+}
+
+// clearSessionCookie removes the session cookie, along with the bare
+// user_id cookie sessions replaced
+func clearSessionCookie(c *gin.Context) {
+	c.SetCookie(sessionCookie, "", -1, "/", "", false, true)
+	c.SetCookie("user_id", "", -1, "/", "", false, true)
+}
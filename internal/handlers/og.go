@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/openapi"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/ratelimit"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+// RegisterOGImageHandlers registers the OpenGraph link preview images
+func RegisterOGImageHandlers(r *gin.Engine, ogImages *services.OGImageService, profileService *services.ProfileService, userService *services.UserService, limiter *ratelimit.Limiter, live *config.Live, logger zerolog.Logger) {
+	handler := &ogImageHandler{
+		ogImages:       ogImages,
+		profileService: profileService,
+		userService:    userService,
+		config:         live,
+		logger:         logger.With().Str("handler", "og_image").Logger(),
+	}
+
+	og := r.Group("/og", rateLimit(limiter, "public"))
+	handleWithHead(og, "/:file", openapi.Operation{
+		Summary:     "Get a profile's link preview image",
+		Description: "Renders a 1200x630 PNG of the profile's current track, or the last one played when the owner shows history, for og:image tags. Images are cached briefly. Supports ETag/If-None-Match and Last-Modified/If-Modified-Since, keyed to the last track change.",
+		Tag:         "public",
+		Params: []openapi.Param{
+			{Name: "file", In: "path", Description: "Profile slug followed by .png"},
+		},
+		Responses: map[int]interface{}{
+			http.StatusOK:                  nil,
+			http.StatusNotModified:         nil,
+			http.StatusForbidden:           errorResponse{},
+			http.StatusNotFound:            errorResponse{},
+			http.StatusTooManyRequests:     errorResponse{},
+			http.StatusInternalServerError: errorResponse{},
+		},
+	}, handler.getOGImage)
+}
+
+type ogImageHandler struct {
+	ogImages       *services.OGImageService
+	profileService *services.ProfileService
+	userService    *services.UserService
+	config         *config.Live
+	logger         zerolog.Logger
+}
+
+// ogImageURL is the absolute URL of a profile's link preview image
+func ogImageURL(c *gin.Context, profileURL string) string {
+	return requestBaseURL(c) + "/og/" + url.PathEscape(profileURL) + ".png"
+}
+
+// getOGImage serves a profile's link preview image
+func (h *ogImageHandler) getOGImage(c *gin.Context) {
+	user, ok := imageProfileUser(c, h.userService, "/og", ".png")
+	if !ok {
+		return
+	}
+
+	track, err := h.profileService.GetNowPlaying(c.Request.Context(), user, h.userService)
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Str("profileURL", user.ProfileURL).Msg("Failed to get currently playing track")
+		abortWithError(c, apperr.From(err, "now_playing_failed", "Failed to get currently playing track"))
+		return
+	}
+
+	ttl := time.Duration(h.config.Get().Embed.OGImageCacheSeconds) * time.Second
+	data, err := h.ogImages.RenderOGImage(c.Request.Context(), user, track, ttl)
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Str("profileURL", user.ProfileURL).Msg("Failed to render OpenGraph image")
+		abortWithError(c, apperr.From(err, "og_image_failed", "Failed to render link preview image"))
+		return
+	}
+
+	hash := fnv.New64a()
+	hash.Write(data)
+	if notModifiedSince(c, fmt.Sprintf(`"%x"`, hash.Sum64()), trackLastModified(track)) {
+		return
+	}
+	c.Data(http.StatusOK, "image/png", data)
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/idempotency"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/openapi"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/ratelimit"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+// RegisterWebhookHandlers registers the routes users manage the webhooks
+// their track changes are sent to with
+func RegisterWebhookHandlers(r *gin.Engine, webhooks *services.WebhookService, userService *services.UserService, limiter *ratelimit.Limiter, idempotencyStore *idempotency.Store, logger zerolog.Logger) {
+	handler := &webhookHandler{
+		webhooks: webhooks,
+		logger:   logger.With().Str("handler", "webhook").Logger(),
+	}
+
+	registerAPIRoutes(r, "/webhooks", []gin.HandlerFunc{authMiddleware(userService), sessionOnly("Managing webhooks"), rateLimit(limiter, "api")}, func(group *gin.RouterGroup) {
+		handle(group, http.MethodPost, "", openapi.Operation{
+			Summary:     "Register a webhook",
+			Description: "Whenever you start, pause, or change tracks, a track.changed event is POSTed to the URL as JSON. Deliveries are signed with the webhook's secret, which is only returned in this response, in the X-Signature header. Deliveries that fail or get a non-2xx response are retried with backoff. URLs must resolve to public addresses.",
+			Tag:         "webhooks",
+			Auth:        true,
+			SessionOnly: true,
+			Params:      []openapi.Param{idempotencyKeyParam},
+			Request:     createWebhookRequest{},
+			Responses: map[int]interface{}{
+				http.StatusCreated:             webhookSecretResponse{},
+				http.StatusBadRequest:          errorResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusForbidden:           errorResponse{},
+				http.StatusConflict:            errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, idempotent(idempotencyStore), handler.create)
+		handle(group, http.MethodGet, "", openapi.Operation{
+			Summary:     "List your webhooks",
+			Tag:         "webhooks",
+			Auth:        true,
+			SessionOnly: true,
+			Responses: map[int]interface{}{
+				http.StatusOK:                  webhooksResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusForbidden:           errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.list)
+		handle(group, http.MethodDelete, "/:id", openapi.Operation{
+			Summary:     "Delete a webhook",
+			Description: "Also deletes the webhook's delivery log.",
+			Tag:         "webhooks",
+			Auth:        true,
+			SessionOnly: true,
+			Params: []openapi.Param{
+				{Name: "id", In: "path", Description: "Webhook ID"},
+			},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  successResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusForbidden:           errorResponse{},
+				http.StatusNotFound:            errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.delete)
+		handle(group, http.MethodPost, "/:id/secret", openapi.Operation{
+			Summary:     "Rotate a webhook's secret",
+			Description: "Replaces the secret deliveries are signed with. The new secret is only returned in this response, and takes effect immediately, including for retries of earlier events. Requires a signed-in session, so a leaked API key can't take over a webhook's signing.",
+			Tag:         "webhooks",
+			Auth:        true,
+			SessionOnly: true,
+			Params: []openapi.Param{
+				{Name: "id", In: "path", Description: "Webhook ID"},
+				idempotencyKeyParam,
+			},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  webhookSecretResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusForbidden:           errorResponse{},
+				http.StatusNotFound:            errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, idempotent(idempotencyStore), handler.rotateSecret)
+		handle(group, http.MethodGet, "/:id/deliveries", openapi.Operation{
+			Summary:     "List a webhook's deliveries",
+			Description: "Returns the webhook's most recent deliveries, newest first, with their status, attempts, and the last response or error.",
+			Tag:         "webhooks",
+			Auth:        true,
+			SessionOnly: true,
+			Params: []openapi.Param{
+				{Name: "id", In: "path", Description: "Webhook ID"},
+			},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  webhookDeliveriesResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusForbidden:           errorResponse{},
+				http.StatusNotFound:            errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.deliveries)
+	})
+}
+
+type webhookHandler struct {
+	webhooks *services.WebhookService
+	logger   zerolog.Logger
+}
+
+// create registers a webhook for the caller
+func (h *webhookHandler) create(c *gin.Context) {
+	var req createWebhookRequest
+	if err := bindJSON(c, &req); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	webhook, err := h.webhooks.CreateWebhook(c.Request.Context(), c.GetString("user_id"), req.URL, req.Description)
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to create webhook")
+		abortWithError(c, apperr.From(err, "webhook_create_failed", "Failed to create webhook"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, webhookSecretResponse{Webhook: *webhook, Secret: webhook.Secret})
+}
+
+// list returns the caller's webhooks
+func (h *webhookHandler) list(c *gin.Context) {
+	webhooks, err := h.webhooks.ListWebhooks(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to list webhooks")
+		abortWithError(c, apperr.From(err, "webhook_list_failed", "Failed to list webhooks"))
+		return
+	}
+
+	c.JSON(http.StatusOK, webhooksResponse{Webhooks: webhooks})
+}
+
+// delete removes one of the caller's webhooks
+func (h *webhookHandler) delete(c *gin.Context) {
+	if err := h.webhooks.DeleteWebhook(c.Request.Context(), c.GetString("user_id"), c.Param("id")); err != nil {
+		abortWithError(c, apperr.From(err, "webhook_delete_failed", "Failed to delete webhook"))
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse{Success: true})
+}
+
+// rotateSecret replaces one of the caller's webhooks' signing secret
+func (h *webhookHandler) rotateSecret(c *gin.Context) {
+	webhook, err := h.webhooks.RotateSecret(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		abortWithError(c, apperr.From(err, "webhook_rotate_failed", "Failed to rotate webhook secret"))
+		return
+	}
+
+	c.JSON(http.StatusOK, webhookSecretResponse{Webhook: *webhook, Secret: webhook.Secret})
+}
+
+// deliveries returns one of the caller's webhooks' recent deliveries
+func (h *webhookHandler) deliveries(c *gin.Context) {
+	deliveries, err := h.webhooks.ListDeliveries(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		abortWithError(c, apperr.From(err, "webhook_deliveries_failed", "Failed to list webhook deliveries"))
+		return
+	}
+
+	c.JSON(http.StatusOK, webhookDeliveriesResponse{Deliveries: deliveries})
+}
@@ -0,0 +1,12 @@
+package handlers
+
+import (
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// untracked reports whether the visitor asked not to be tracked, with
+// DNT: 1 or Sec-GPC: 1, and the deployment honors it
+func untracked(c *gin.Context, privacy config.PrivacyConfig) bool {
+	return privacy.HonorDoNotTrack && (c.GetHeader("DNT") == "1" || c.GetHeader("Sec-GPC") == "1")
+}
@@ -1,38 +1,122 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/idempotency"
 	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/openapi"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/ratelimit"
 	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog"
 )
 
+// visitorCookie holds the anonymous token that recognizes a browser across
+// profile visits
+const visitorCookie = "visitor_id"
+
+// visitorCookieAge is how long the visitor cookie lasts after the browser's
+// last profile visit
+const visitorCookieAge = 365 * 24 * time.Hour
+
 // RegisterProfileHandlers registers all profile-related routes
-func RegisterProfileHandlers(r *gin.Engine, profileService *services.ProfileService, userService *services.UserService, logger zerolog.Logger) {
+func RegisterProfileHandlers(r *gin.Engine, profileService *services.ProfileService, userService *services.UserService, privacy config.PrivacyConfig, limiter *ratelimit.Limiter, idempotencyStore *idempotency.Store, logger zerolog.Logger) {
 	handler := &profileHandler{
 		profileService: profileService,
 		userService:    userService,
+		privacy:        privacy,
 		logger:         logger.With().Str("handler", "profile").Logger(),
 	}
 
 	// Public routes
-	r.GET("/profile/:profileURL", handler.getPublicProfile)
+	// Rendering a profile can poll the owner's provider, so pages share the
+	// public API's limit
+	r.GET("/profile/:profileURL", htmlErrors(), rateLimit(limiter, "public"), handler.getPublicProfile)
 
 	// Protected routes
-	profile := r.Group("/api/profile")
-	profile.Use(authMiddleware(userService))
-	{
-		profile.GET("", handler.getProfile)
-		profile.PUT("", handler.updateProfile)
-		profile.PUT("/settings", handler.updateSettings)
-	}
+	registerAPIRoutes(r, "/profile", []gin.HandlerFunc{authMiddleware(userService), rateLimit(limiter, "api")}, func(profile *gin.RouterGroup) {
+		handle(profile, http.MethodGet, "", openapi.Operation{
+			Summary: "Get the authenticated user's profile",
+			Tag:     "profile",
+			Auth:    true,
+			Params:  []openapi.Param{fieldsParam},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  models.Profile{},
+				http.StatusBadRequest:          errorResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.getProfile)
+		handle(profile, http.MethodPut, "", openapi.Operation{
+			Summary:     "Update the authenticated user's profile",
+			Description: "Text and background colors must have a WCAG contrast ratio of at least 4.5:1. Lower ratios are rejected with insufficient_contrast and suggested accessible palettes in details, unless force is set, in which case the profile is saved with a warning.",
+			Tag:         "profile",
+			Auth:        true,
+			Params:      []openapi.Param{idempotencyKeyParam},
+			Request:     updateProfileRequest{},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  updateProfileResponse{},
+				http.StatusBadRequest:          errorResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusConflict:            errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, idempotent(idempotencyStore), handler.updateProfile)
+		handle(profile, http.MethodPut, "/settings", openapi.Operation{
+			Summary: "Update sharing settings",
+			Tag:     "profile",
+			Auth:    true,
+			Params:  []openapi.Param{idempotencyKeyParam},
+			Request: updateSettingsRequest{},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  successResponse{},
+				http.StatusBadRequest:          errorResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusConflict:            errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, idempotent(idempotencyStore), handler.updateSettings)
+		handle(profile, http.MethodGet, "/palette", openapi.Operation{
+			Summary:     "Check profile colors for accessible contrast",
+			Description: "Returns the WCAG contrast ratio of a background and text color and, when it is below 4.5:1, the closest accessible palettes: one keeping the background and one keeping the text color. URL-encode # as %23.",
+			Tag:         "profile",
+			Auth:        true,
+			Params: []openapi.Param{
+				{Name: "background_color", In: "query", Description: "Background hex color, like #121212"},
+				{Name: "text_color", In: "query", Description: "Text hex color, like #ffffff"},
+			},
+			Responses: map[int]interface{}{
+				http.StatusOK:           paletteResponse{},
+				http.StatusBadRequest:   errorResponse{},
+				http.StatusUnauthorized: errorResponse{},
+			},
+		}, handler.getPalette)
+	})
+
+	registerAPIRoutes(r, "/me", []gin.HandlerFunc{authMiddleware(userService), rateLimit(limiter, "api")}, func(me *gin.RouterGroup) {
+		handle(me, http.MethodGet, "/recently-viewed", openapi.Operation{
+			Summary:     "List public profiles you visited recently",
+			Description: "Returns up to 20 profiles, most recently viewed first, with how often each was viewed. Profiles no longer shared are left out. Turn tracking off with track_recently_viewed in settings, which also clears the list.",
+			Tag:         "profile",
+			Auth:        true,
+			Responses: map[int]interface{}{
+				http.StatusOK:                  recentlyViewedResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.getRecentlyViewed)
+	})
 }
 
 type profileHandler struct {
 	profileService *services.ProfileService
 	userService    *services.UserService
+	privacy        config.PrivacyConfig
 	logger         zerolog.Logger
 }
 
@@ -43,30 +127,62 @@ func (h *profileHandler) getPublicProfile(c *gin.Context) {
 	// Get user by profile URL
 	user, err := h.userService.GetUserByProfileURL(c.Request.Context(), profileURL)
 	if err != nil {
-		h.logger.Error().Err(err).Str("profileURL", profileURL).Msg("Profile not found")
-		c.HTML(http.StatusNotFound, "404.html", gin.H{
-			"error": "Profile not found",
-		})
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Str("profileURL", profileURL).Msg("Profile not found")
+		abortWithError(c, apperr.From(err, "profile_lookup_failed", "Failed to load profile"))
+		return
+	}
+
+	if redirectToCanonicalProfile(c, user) {
 		return
 	}
 
 	// If user is not active or not sharing
 	if !user.IsActive || !user.IsSharingEnabled {
+		if acceptsJSON(c) {
+			abortWithError(c, apperr.NotFound("profile_unavailable", "Profile not available"))
+			return
+		}
 		c.HTML(http.StatusNotFound, "profile_unavailable.html", gin.H{
 			"username": user.DisplayName,
 		})
 		return
 	}
 
-	// Record the visit
+	// Visitors who asked not to be tracked are only counted anonymously,
+	// once their page connects for live updates
+	if untracked(c, h.privacy) {
+		c.SetCookie("visit_id", "", -1, "/", "", false, false)
+		c.SetCookie(visitorCookie, "", -1, "/", "", false, true)
+	} else {
+		h.recordVisit(c, user)
+	}
+
+	// Get profile data
+	profileResponse, err := h.profileService.GetProfileResponse(c.Request.Context(), user, h.userService)
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to get profile data")
+		abortWithError(c, apperr.From(err, "profile_load_failed", "Failed to load profile data"))
+		return
+	}
+
+	// Render profile page
+	c.HTML(http.StatusOK, "profile.html", gin.H{
+		"profile": profileResponse,
+		"ogImage": ogImageURL(c, user.ProfileURL),
+	})
+}
+
+// recordVisit records a visit to user's public profile, sets the visit_id
+// cookie the WebSocket authenticates with, and adds the profile to a signed-in
+// visitor's recently viewed list
+func (h *profileHandler) recordVisit(c *gin.Context, user *models.User) {
 	visitorIP := c.ClientIP()
 	userAgent := c.GetHeader("User-Agent")
 	referrer := c.GetHeader("Referer")
 
 	var visitorUserID *string
-	loggedInUserID, _ := c.Cookie("user_id")
-	if loggedInUserID != "" && loggedInUserID != user.ID {
-		visitorUserID = &loggedInUserID
+	if visitor, _, err := currentUser(c, h.userService); err == nil && visitor.ID != user.ID {
+		visitorUserID = &visitor.ID
 	}
 
 	visitID, err := h.userService.RecordProfileVisit(
@@ -75,30 +191,40 @@ func (h *profileHandler) getPublicProfile(c *gin.Context) {
 		visitorIP,
 		userAgent,
 		referrer,
+		h.visitorToken(c),
 		visitorUserID,
 	)
 
 	if err != nil {
-		h.logger.Error().Err(err).Msg("Failed to record profile visit")
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to record profile visit")
 	} else {
 		// Set the visit ID cookie for WebSocket authentication
 		c.SetCookie("visit_id", visitID, 0, "/", "", false, false)
 	}
 
-	// Get profile data
-	profileResponse, err := h.profileService.GetProfileResponse(c.Request.Context(), user, h.userService)
-	if err != nil {
-		h.logger.Error().Err(err).Msg("Failed to get profile data")
-		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
-			"error": "Failed to load profile data",
-		})
-		return
+	if visitorUserID != nil {
+		if err := h.userService.RecordRecentlyViewed(c.Request.Context(), *visitorUserID, user.ID); err != nil {
+			h.logger.Warn().Ctx(c.Request.Context()).Err(err).Msg("Failed to record recently viewed profile")
+		}
 	}
+}
 
-	// Render profile page
-	c.HTML(http.StatusOK, "profile.html", gin.H{
-		"profile": profileResponse,
-	})
+// visitorToken returns the browser's visitor cookie token, issuing a new one
+// when it has none, and keeps the cookie alive for another visitorCookieAge.
+// It returns "" when no token could be generated, so the visit is recorded
+// without deduplication.
+func (h *profileHandler) visitorToken(c *gin.Context) string {
+	token, err := c.Cookie(visitorCookie)
+	if err != nil || !services.ValidVisitorToken(token) {
+		token, err = services.NewVisitorToken()
+		if err != nil {
+			h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to issue visitor cookie")
+			return ""
+		}
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(visitorCookie, token, int(visitorCookieAge.Seconds()), "/", "", false, true)
+	return token
 }
 
 // getProfile returns the authenticated user's profile
@@ -107,53 +233,152 @@ func (h *profileHandler) getProfile(c *gin.Context) {
 
 	profile, err := h.profileService.GetProfile(c.Request.Context(), userID)
 	if err != nil {
-		h.logger.Error().Err(err).Str("userID", userID).Msg("Failed to get profile")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get profile"})
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Str("userID", userID).Msg("Failed to get profile")
+		abortWithError(c, apperr.From(err, "profile_fetch_failed", "Failed to get profile"))
 		return
 	}
 
-	c.JSON(http.StatusOK, profile)
+	sparseJSON(c, http.StatusOK, profile)
 }
 
 // updateProfile updates the authenticated user's profile
 func (h *profileHandler) updateProfile(c *gin.Context) {
 	userID := c.GetString("user_id")
 
-	var profileUpdates models.Profile
-	if err := c.ShouldBindJSON(&profileUpdates); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+	var req updateProfileRequest
+	if err := bindJSON(c, &req); err != nil {
+		abortWithError(c, err)
 		return
 	}
 
-	err := h.profileService.UpdateProfile(c.Request.Context(), userID, profileUpdates)
+	// Unreadable color pairs are rejected unless the owner insists
+	var warnings []string
+	ratio, err := services.ContrastRatio(req.BackgroundColor, req.TextColor)
 	if err != nil {
-		h.logger.Error().Err(err).Str("userID", userID).Msg("Failed to update profile")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+		abortWithError(c, apperr.Invalid("invalid_color", "Invalid color").Wrap(err))
 		return
 	}
+	if ratio < services.MinContrastRatio {
+		if !req.Force {
+			suggestions, _ := services.SuggestPalettes(req.BackgroundColor, req.TextColor)
+			abortWithError(c, apperr.Invalid("insufficient_contrast",
+				fmt.Sprintf("Text and background colors have a contrast ratio of %.2f:1; at least %.1f:1 is required. Set force to save anyway.", services.RoundContrastRatio(ratio), services.MinContrastRatio)).
+				WithDetails(contrastDetails{
+					ContrastRatio: services.RoundContrastRatio(ratio),
+					MinimumRatio:  services.MinContrastRatio,
+					Suggestions:   suggestions,
+				}))
+			return
+		}
+		warnings = append(warnings, fmt.Sprintf("Text and background colors have a contrast ratio of %.2f:1, below the %.1f:1 WCAG AA minimum, and may be hard to read", services.RoundContrastRatio(ratio), services.MinContrastRatio))
+	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true})
-}
+	showLyrics := req.ShowLyrics
+	if showLyrics == nil {
+		current, err := h.profileService.GetProfile(c.Request.Context(), userID)
+		if err != nil {
+			abortWithError(c, apperr.From(err, "profile_fetch_failed", "Failed to get profile"))
+			return
+		}
+		showLyrics = &current.ShowLyrics
+	}
 
-// updateSettings updates the user's sharing settings
-func (h *profileHandler) updateSettings(c *gin.Context) {
-	userID := c.GetString("user_id")
+	profileUpdates := models.Profile{
+		Theme:           req.Theme,
+		BackgroundColor: req.BackgroundColor,
+		TextColor:       req.TextColor,
+		CustomMessage:   req.CustomMessage,
+		ShowStats:       *req.ShowStats,
+		ShowHistory:     *req.ShowHistory,
+		ShowLyrics:      *showLyrics,
+		AnimationStyle:  req.AnimationStyle,
+	}
 
-	var settings struct {
-		IsSharingEnabled bool `json:"isSharingEnabled"`
+	err = h.profileService.UpdateProfile(c.Request.Context(), userID, profileUpdates)
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Str("userID", userID).Msg("Failed to update profile")
+		abortWithError(c, apperr.From(err, "profile_update_failed", "Failed to update profile"))
+		return
 	}
 
-	if err := c.ShouldBindJSON(&settings); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+	c.JSON(http.StatusOK, updateProfileResponse{Success: true, Warnings: warnings})
+}
+
+// getPalette reports the contrast of a color pair with accessible alternatives
+func (h *profileHandler) getPalette(c *gin.Context) {
+	var query paletteQuery
+	if err := bindQuery(c, &query); err != nil {
+		abortWithError(c, err)
 		return
 	}
 
-	err := h.userService.UpdateUserSettings(c.Request.Context(), userID, settings.IsSharingEnabled)
+	ratio, err := services.ContrastRatio(query.BackgroundColor, query.TextColor)
 	if err != nil {
-		h.logger.Error().Err(err).Str("userID", userID).Msg("Failed to update settings")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update settings"})
+		abortWithError(c, apperr.Invalid("invalid_color", "Invalid color").Wrap(err))
 		return
 	}
+	suggestions, err := services.SuggestPalettes(query.BackgroundColor, query.TextColor)
+	if err != nil {
+		abortWithError(c, apperr.Invalid("invalid_color", "Invalid color").Wrap(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, paletteResponse{
+		BackgroundColor: query.BackgroundColor,
+		TextColor:       query.TextColor,
+		ContrastRatio:   services.RoundContrastRatio(ratio),
+		MinimumRatio:    services.MinContrastRatio,
+		Accessible:      ratio >= services.MinContrastRatio,
+		Suggestions:     suggestions,
+	})
+}
+
+// getRecentlyViewed lists the public profiles the user visited recently
+func (h *profileHandler) getRecentlyViewed(c *gin.Context) {
+	profiles, err := h.userService.GetRecentlyViewed(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to get recently viewed profiles")
+		abortWithError(c, apperr.From(err, "recently_viewed_failed", "Failed to get recently viewed profiles"))
+		return
+	}
+
+	c.JSON(http.StatusOK, recentlyViewedResponse{Profiles: profiles})
+}
+
+// updateSettings updates the user's sharing settings
+func (h *profileHandler) updateSettings(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var settings updateSettingsRequest
+	if err := bindJSON(c, &settings); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	if settings.IsSharingEnabled != nil {
+		err := h.userService.UpdateUserSettings(c.Request.Context(), userID, *settings.IsSharingEnabled)
+		if err != nil {
+			h.logger.Error().Ctx(c.Request.Context()).Err(err).Str("userID", userID).Msg("Failed to update settings")
+			abortWithError(c, apperr.From(err, "settings_update_failed", "Failed to update settings"))
+			return
+		}
+	}
+	if settings.Timezone != nil {
+		err := h.userService.UpdateUserTimezone(c.Request.Context(), userID, *settings.Timezone)
+		if err != nil {
+			h.logger.Error().Ctx(c.Request.Context()).Err(err).Str("userID", userID).Msg("Failed to update timezone")
+			abortWithError(c, apperr.From(err, "settings_update_failed", "Failed to update settings"))
+			return
+		}
+	}
+	if settings.TrackRecentlyViewed != nil {
+		err := h.userService.SetTrackRecentlyViewed(c.Request.Context(), userID, *settings.TrackRecentlyViewed)
+		if err != nil {
+			h.logger.Error().Ctx(c.Request.Context()).Err(err).Str("userID", userID).Msg("Failed to update recently viewed setting")
+			abortWithError(c, apperr.From(err, "settings_update_failed", "Failed to update settings"))
+			return
+		}
+	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true})
+	c.JSON(http.StatusOK, successResponse{Success: true})
 }
@@ -0,0 +1,406 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/openapi"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/ratelimit"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+// RegisterPublicHandlers registers unauthenticated read-only JSON routes
+func RegisterPublicHandlers(r *gin.Engine, profileService *services.ProfileService, spotifyService *services.SpotifyService, userService *services.UserService, lyrics *services.LyricsService, limiter *ratelimit.Limiter, live *config.Live, logger zerolog.Logger) {
+	handler := &publicHandler{
+		profileService: profileService,
+		spotifyService: spotifyService,
+		userService:    userService,
+		lyrics:         lyrics,
+		config:         live,
+		logger:         logger.With().Str("handler", "public").Logger(),
+	}
+
+	public := r.Group("/api/v1/public", apiVersionMiddleware(), rateLimit(limiter, "public"))
+	{
+		handleWithHead(public, "/:profileURL/now-playing", openapi.Operation{
+			Summary:     "Get a profile's currently playing track",
+			Description: "Returns a weak ETag and a Last-Modified time of the last track change. Send either back in If-None-Match or If-Modified-Since to get 304 Not Modified. Also available as plain text (\"Artist – Title\", empty when nothing is playing) or XML via the format parameter or Accept.",
+			Tag:         "public",
+			Params: []openapi.Param{
+				{Name: "profileURL", In: "path", Description: "Profile slug"},
+				{Name: "format", In: "query", Description: "Response format: json (default), text, or xml"},
+				{Name: "callback", In: "query", Description: "JSONP callback name, when JSONP is enabled on this server"},
+			},
+			Responses: map[int]interface{}{
+				http.StatusOK:              models.SpotifyCurrentlyPlaying{},
+				http.StatusNotModified:     nil,
+				http.StatusBadRequest:      errorResponse{},
+				http.StatusForbidden:       errorResponse{},
+				http.StatusNotFound:        errorResponse{},
+				http.StatusNotAcceptable:   errorResponse{},
+				http.StatusTooManyRequests: errorResponse{},
+			},
+		}, handler.getNowPlaying)
+		handle(public, http.MethodGet, "/profiles/:profileURL", publicProfileOperation, handler.getProfile)
+		handle(public, http.MethodPost, "/now-playing/batch", openapi.Operation{
+			Summary:     "Get several profiles' currently playing tracks",
+			Description: fmt.Sprintf("Looks up to %d profiles at once from the cache, without calling Spotify. Results follow request order with duplicates removed.", maxBatchProfiles),
+			Tag:         "public",
+			Request:     batchNowPlayingRequest{},
+			Responses: map[int]interface{}{
+				http.StatusOK:              batchNowPlayingResponse{},
+				http.StatusBadRequest:      errorResponse{},
+				http.StatusTooManyRequests: errorResponse{},
+			},
+		}, handler.batchNowPlaying)
+		handle(public, http.MethodGet, "/:profileURL/speech", openapi.Operation{
+			Summary:     "Get a spoken summary of a profile's currently playing track",
+			Description: "Returns a short sentence for voice assistants to read out, such as \"Sam is listening to Song by Artist, 2 minutes in.\" The locale comes from the locale parameter, then Accept-Language; en, es, fr, and de are supported, with en as the fallback. Also available as plain text or XML via the format parameter or Accept.",
+			Tag:         "public",
+			Params: []openapi.Param{
+				{Name: "profileURL", In: "path", Description: "Profile slug"},
+				{Name: "locale", In: "query", Description: "Language tag such as de or de-DE"},
+				{Name: "format", In: "query", Description: "Response format: json (default), text, or xml"},
+			},
+			Responses: map[int]interface{}{
+				http.StatusOK:              speechResponse{},
+				http.StatusBadRequest:      errorResponse{},
+				http.StatusForbidden:       errorResponse{},
+				http.StatusNotFound:        errorResponse{},
+				http.StatusNotAcceptable:   errorResponse{},
+				http.StatusTooManyRequests: errorResponse{},
+			},
+		}, handler.getSpeech)
+		handle(public, http.MethodGet, "/:profileURL/wrapped/:year", openapi.Operation{
+			Summary:     "Get a profile's year in review",
+			Description: "The shareable version of GET /stats/wrapped/:year. Only available when the owner shows their history on their profile.",
+			Tag:         "public",
+			Params: []openapi.Param{
+				{Name: "profileURL", In: "path", Description: "Profile slug"},
+				wrappedYearParam,
+			},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  models.Wrapped{},
+				http.StatusBadRequest:          errorResponse{},
+				http.StatusForbidden:           errorResponse{},
+				http.StatusNotFound:            errorResponse{},
+				http.StatusTooManyRequests:     errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.getWrapped)
+		if lyrics != nil {
+			handle(public, http.MethodGet, "/:profileURL/lyrics", openapi.Operation{
+				Summary:     "Get lyrics for a profile's currently playing track",
+				Description: "Returns plain lyrics and, when available, lines timed in milliseconds from the start of the track. Only available when the owner has turned on show_lyrics.",
+				Tag:         "public",
+				Params: []openapi.Param{
+					{Name: "profileURL", In: "path", Description: "Profile slug"},
+				},
+				Responses: map[int]interface{}{
+					http.StatusOK:                 models.Lyrics{},
+					http.StatusForbidden:          errorResponse{},
+					http.StatusNotFound:           errorResponse{},
+					http.StatusTooManyRequests:    errorResponse{},
+					http.StatusServiceUnavailable: errorResponse{},
+				},
+			}, handler.getLyrics)
+		}
+	}
+
+	// The profile endpoint was first published without a version, so the
+	// unversioned path stays as a deprecated alias like other /api routes
+	legacy := r.Group("/api/public", deprecatedRouteMiddleware(), rateLimit(limiter, "public"))
+	handle(legacy, http.MethodGet, "/profiles/:profileURL", publicProfileOperation, handler.getProfile)
+}
+
+// publicProfileOperation documents GET /api/v1/public/profiles/:profileURL
+var publicProfileOperation = openapi.Operation{
+	Summary:     "Get a public profile",
+	Description: "Returns the same data the profile page renders: the owner's public details, profile settings, current track, recent tracks when the owner shows history, and the live viewer count when they show stats. Unlike the page, fetching it doesn't count as a visit.",
+	Tag:         "public",
+	Params: []openapi.Param{
+		{Name: "profileURL", In: "path", Description: "Profile slug"},
+	},
+	Responses: map[int]interface{}{
+		http.StatusOK:                  models.ProfileResponse{},
+		http.StatusForbidden:           errorResponse{},
+		http.StatusNotFound:            errorResponse{},
+		http.StatusNotAcceptable:       errorResponse{},
+		http.StatusTooManyRequests:     errorResponse{},
+		http.StatusInternalServerError: errorResponse{},
+	},
+}
+
+type publicHandler struct {
+	profileService *services.ProfileService
+	spotifyService *services.SpotifyService
+	userService    *services.UserService
+	lyrics         *services.LyricsService
+	config         *config.Live
+	logger         zerolog.Logger
+}
+
+// getProfile returns a public profile as JSON
+func (h *publicHandler) getProfile(c *gin.Context) {
+	user, ok := h.sharingUser(c)
+	if !ok {
+		return
+	}
+
+	profile, err := h.profileService.GetProfileResponse(c.Request.Context(), user, h.userService)
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Str("profileURL", user.ProfileURL).Msg("Failed to get profile data")
+		abortWithError(c, apperr.From(err, "profile_load_failed", "Failed to load profile data"))
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// getNowPlaying returns the currently playing track for a public profile
+func (h *publicHandler) getNowPlaying(c *gin.Context) {
+	format, err := h.nowPlayingFormat(c)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	user, ok := h.sharingUser(c)
+	if !ok {
+		return
+	}
+
+	track, err := h.profileService.GetNowPlaying(c.Request.Context(), user, h.userService)
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Str("profileURL", user.ProfileURL).Msg("Failed to get currently playing track")
+		abortWithError(c, apperr.From(err, "now_playing_failed", "Failed to get currently playing track"))
+		return
+	}
+
+	if notModifiedSince(c, formatETag(nowPlayingETag(track), format), trackLastModified(track)) {
+		return
+	}
+
+	renderNowPlaying(c, format, track)
+}
+
+// getWrapped returns a public profile's year in review
+func (h *publicHandler) getWrapped(c *gin.Context) {
+	year, err := wrappedYear(c)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	user, ok := h.sharingUser(c)
+	if !ok {
+		return
+	}
+
+	profile, err := h.profileService.GetProfile(c.Request.Context(), user.ID)
+	if err != nil {
+		abortWithError(c, apperr.From(err, "profile_fetch_failed", "Failed to get profile"))
+		return
+	}
+	if !profile.ShowHistory {
+		abortWithError(c, apperr.Forbidden("wrapped_unavailable", "This profile doesn't share its listening history"))
+		return
+	}
+
+	wrapped, err := h.profileService.GetWrapped(c.Request.Context(), user, year)
+	if err != nil {
+		if apperr.KindOf(err) != apperr.KindInvalid {
+			h.logger.Error().Ctx(c.Request.Context()).Err(err).Str("profileURL", user.ProfileURL).Int("year", year).Msg("Failed to get year in review")
+		}
+		abortWithError(c, apperr.From(err, "wrapped_failed", "Failed to get year in review"))
+		return
+	}
+
+	c.JSON(http.StatusOK, wrapped)
+}
+
+// getLyrics returns the lyrics for a public profile's currently playing track
+func (h *publicHandler) getLyrics(c *gin.Context) {
+	user, ok := h.sharingUser(c)
+	if !ok {
+		return
+	}
+
+	profile, err := h.profileService.GetProfile(c.Request.Context(), user.ID)
+	if err != nil {
+		abortWithError(c, apperr.From(err, "profile_fetch_failed", "Failed to get profile"))
+		return
+	}
+	if !profile.ShowLyrics {
+		abortWithError(c, apperr.Forbidden("lyrics_disabled", "Lyrics are not shown on this profile"))
+		return
+	}
+
+	track, err := h.profileService.GetNowPlaying(c.Request.Context(), user, h.userService)
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Str("profileURL", user.ProfileURL).Msg("Failed to get currently playing track")
+		abortWithError(c, apperr.From(err, "now_playing_failed", "Failed to get currently playing track"))
+		return
+	}
+	if track == nil || !track.IsPlaying {
+		abortWithError(c, apperr.NotFound("not_playing", "Nothing is playing"))
+		return
+	}
+
+	lyrics, err := h.lyrics.GetLyrics(c.Request.Context(), track)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, lyrics)
+}
+
+// getSpeech returns a sentence describing what a public profile is playing
+func (h *publicHandler) getSpeech(c *gin.Context) {
+	format, err := negotiateFormat(c)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	user, ok := h.sharingUser(c)
+	if !ok {
+		return
+	}
+
+	track, err := h.profileService.GetNowPlaying(c.Request.Context(), user, h.userService)
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Str("profileURL", user.ProfileURL).Msg("Failed to get currently playing track")
+		abortWithError(c, apperr.From(err, "now_playing_failed", "Failed to get currently playing track"))
+		return
+	}
+
+	c.Header("Vary", "Accept, Accept-Language")
+	locale := services.MatchSpeechLocale(c.Query("locale"), c.GetHeader("Accept-Language"))
+	listener := user.DisplayName
+	if listener == "" {
+		listener = user.ProfileURL
+	}
+	resp := speechResponse{
+		Text:      services.NowPlayingSpeech(listener, track, locale),
+		Locale:    locale,
+		IsPlaying: track != nil && track.IsPlaying,
+	}
+
+	c.Header("Content-Language", locale)
+	switch format {
+	case formatText:
+		c.String(http.StatusOK, resp.Text+"\n")
+	case formatXML:
+		c.XML(http.StatusOK, resp)
+	default:
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// nowPlayingFormat picks the now-playing representation; a JSONP callback,
+// when enabled, takes precedence over negotiation
+func (h *publicHandler) nowPlayingFormat(c *gin.Context) (string, error) {
+	callback := c.Query("callback")
+	if callback == "" {
+		return negotiateFormat(c)
+	}
+	if !h.config.Get().Embed.JSONPEnabled {
+		return "", apperr.Invalid("jsonp_disabled", "JSONP callbacks are not enabled")
+	}
+	if !validJSONPCallback(callback) {
+		return "", apperr.Invalid("invalid_callback", "Callback must be a JavaScript identifier")
+	}
+	return formatJSONP, nil
+}
+
+// batchNowPlaying returns cached now-playing state for several public profiles
+func (h *publicHandler) batchNowPlaying(c *gin.Context) {
+	var req batchNowPlayingRequest
+	if err := bindJSON(c, &req); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	profileURLs := make([]string, 0, len(req.ProfileURLs))
+	seen := make(map[string]bool, len(req.ProfileURLs))
+	for _, profileURL := range req.ProfileURLs {
+		if !seen[profileURL] {
+			seen[profileURL] = true
+			profileURLs = append(profileURLs, profileURL)
+		}
+	}
+
+	users, err := h.userService.GetUsersByProfileURLs(c.Request.Context(), profileURLs)
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to look up profiles")
+		abortWithError(c, apperr.From(err, "profile_lookup_failed", "Failed to load profiles"))
+		return
+	}
+
+	usersByURL := make(map[string]*models.User, len(users))
+	sharingIDs := make([]string, 0, len(users))
+	for i := range users {
+		user := &users[i]
+		usersByURL[services.NormalizeProfileURL(user.ProfileURL)] = user
+		if user.IsActive && user.IsSharingEnabled {
+			sharingIDs = append(sharingIDs, user.ID)
+		}
+	}
+
+	// A partial result is still useful, so cache errors only degrade to "not playing"
+	tracks, err := h.spotifyService.GetCachedCurrentlyPlayingBatch(c.Request.Context(), sharingIDs)
+	if err != nil {
+		h.logger.Warn().Ctx(c.Request.Context()).Err(err).Msg("Failed to read cached tracks")
+	}
+
+	results := make([]batchNowPlayingResult, 0, len(profileURLs))
+	for _, profileURL := range profileURLs {
+		result := batchNowPlayingResult{ProfileURL: profileURL}
+		user, ok := usersByURL[services.NormalizeProfileURL(profileURL)]
+		switch {
+		case !ok:
+			result.Status = "not_found"
+		case !user.IsActive || !user.IsSharingEnabled:
+			result.Status = "unavailable"
+		default:
+			result.Status = "ok"
+			result.NowPlaying = tracks[user.ID]
+			if result.NowPlaying == nil {
+				result.NowPlaying = &models.SpotifyCurrentlyPlaying{IsPlaying: false}
+			}
+		}
+		results = append(results, result)
+	}
+
+	c.JSON(http.StatusOK, batchNowPlayingResponse{Results: results})
+}
+
+// sharingUser loads the profile owner named in the URL, aborting unless they
+// exist and are actively sharing. Non-canonical slugs are redirected.
+func (h *publicHandler) sharingUser(c *gin.Context) (*models.User, bool) {
+	profileURL := c.Param("profileURL")
+
+	user, err := h.userService.GetUserByProfileURL(c.Request.Context(), profileURL)
+	if err != nil {
+		abortWithError(c, apperr.From(err, "profile_lookup_failed", "Failed to load profile"))
+		return nil, false
+	}
+
+	if redirectToCanonicalProfile(c, user) {
+		return nil, false
+	}
+
+	if !user.IsActive || !user.IsSharingEnabled {
+		abortWithError(c, apperr.Forbidden("profile_unavailable", "Profile not available"))
+		return nil, false
+	}
+
+	return user, true
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"strconv"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/audit"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimit enforces the named policy per caller. Callers are identified by API
+// key, then signed-in user, then client IP. Limiter failures fail open.
+func rateLimit(limiter *ratelimit.Limiter, policyName string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Looked up per request so reloaded policies apply immediately
+		policy, enforced := limiter.Policy(policyName)
+		if !enforced {
+			c.Next()
+			return
+		}
+
+		identity := callerIdentity(c)
+		result, err := limiter.Allow(c.Request.Context(), policy, identity)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+
+		if !result.Allowed {
+			retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			auditEvent(c, audit.EventRateLimited, policy.Name, map[string]interface{}{
+				"identity":            identity,
+				"retry_after_seconds": retryAfter,
+			})
+			abortWithError(c, apperr.RateLimited("rate_limited", "Too many requests").
+				WithDetails(gin.H{"policy": policy.Name, "retry_after_seconds": retryAfter}))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// callerIdentity picks the most specific identity available for the caller
+func callerIdentity(c *gin.Context) string {
+	if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+		sum := sha256.Sum256([]byte(apiKey))
+		return "key:" + hex.EncodeToString(sum[:8])
+	}
+	if userID := c.GetString("user_id"); userID != "" {
+		return "user:" + userID
+	}
+	return "ip:" + c.ClientIP()
+}
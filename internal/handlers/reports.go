@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// registerUsageReportRoutes registers the monthly usage report routes on the
+// admin listener, behind the admin token
+func registerUsageReportRoutes(r *gin.Engine, token string, reports *services.UsageReportService) {
+	handler := &usageReportHandler{reports: reports}
+
+	group := r.Group("/reports/usage", adminAuth(token), auditAdminAction("usage_report"))
+	{
+		group.GET("", handler.list)
+		group.GET("/:month", handler.get)
+		group.POST("/:month", handler.generate)
+	}
+}
+
+type usageReportHandler struct {
+	reports *services.UsageReportService
+}
+
+// list returns the months with a usage report
+func (h *usageReportHandler) list(c *gin.Context) {
+	months, err := h.reports.ListReports(c.Request.Context())
+	if err != nil {
+		abortWithError(c, apperr.From(err, "report_list_failed", "Failed to list usage reports"))
+		return
+	}
+
+	c.JSON(http.StatusOK, usageReportsResponse{Months: months})
+}
+
+// get downloads one month's usage report as JSON, or CSV with ?format=csv
+func (h *usageReportHandler) get(c *gin.Context) {
+	var query usageReportQuery
+	if err := bindQuery(c, &query); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	report, err := h.reports.GetReport(c.Request.Context(), c.Param("month"))
+	if err != nil {
+		abortWithError(c, apperr.From(err, "report_fetch_failed", "Failed to get usage report"))
+		return
+	}
+
+	filename := "usage-report-" + report.Month
+	if query.Format == "csv" {
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.csv"`)
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Status(http.StatusOK)
+		writeUsageReportCSV(c, report)
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`.json"`)
+	c.JSON(http.StatusOK, report)
+}
+
+// generate compiles, or recompiles, one month's usage report
+func (h *usageReportHandler) generate(c *gin.Context) {
+	report, err := h.reports.GenerateReport(c.Request.Context(), c.Param("month"))
+	if err != nil {
+		abortWithError(c, apperr.From(err, "report_generate_failed", "Failed to generate usage report"))
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// writeUsageReportCSV writes a report as section,name,value rows: one
+// "metric" row per count, then one "referrer" row per referring site
+func writeUsageReportCSV(c *gin.Context, report *models.UsageReport) {
+	w := csv.NewWriter(c.Writer)
+	metric := func(name string, value int64) {
+		_ = w.Write([]string{"metric", name, strconv.FormatInt(value, 10)})
+	}
+
+	_ = w.Write([]string{"section", "name", "value"})
+	_ = w.Write([]string{"metric", "month", report.Month})
+	metric("active_users", report.ActiveUsers)
+	metric("new_users", report.NewUsers)
+	metric("total_users", report.TotalUsers)
+	metric("tracks_logged", report.TracksLogged)
+	metric("profile_visits", report.ProfileVisits)
+	metric("unique_visitors", report.UniqueVisitors)
+	for _, referrer := range report.TopReferrers {
+		_ = w.Write([]string{"referrer", referrer.Referrer, strconv.FormatInt(referrer.Visits, 10)})
+	}
+	w.Flush()
+}
@@ -0,0 +1,448 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/canary"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+)
+
+// successResponse acknowledges a mutation
+type successResponse struct {
+	Success bool `json:"success"`
+}
+
+// authStatusResponse reports whether the caller is signed in
+type authStatusResponse struct {
+	Authenticated bool            `json:"authenticated"`
+	User          *authStatusUser `json:"user,omitempty"`
+}
+
+// authStatusUser is the signed-in user summary in authStatusResponse
+type authStatusUser struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+	ProfileURL  string `json:"profileUrl"`
+	IsSharing   bool   `json:"isSharing"`
+}
+
+// appleDeveloperTokenResponse carries the MusicKit developer token
+type appleDeveloperTokenResponse struct {
+	DeveloperToken string `json:"developer_token"`
+	ExpiresAt      string `json:"expires_at"`
+}
+
+// appleMusicTokenRequest connects Apple Music for a user who signed in with Apple
+type appleMusicTokenRequest struct {
+	MusicUserToken string `json:"music_user_token" binding:"required"`
+}
+
+// mediaWebhookResponse carries a newly created Plex/Jellyfin webhook URL
+type mediaWebhookResponse struct {
+	URL string `json:"url"`
+}
+
+// updateSettingsRequest changes the authenticated user's sharing, time zone,
+// and recently viewed tracking; any may be left out, but not all
+type updateSettingsRequest struct {
+	IsSharingEnabled    *bool   `json:"isSharingEnabled" binding:"required_without_all=Timezone TrackRecentlyViewed"`
+	Timezone            *string `json:"timezone" binding:"omitempty,timezone"`
+	TrackRecentlyViewed *bool   `json:"track_recently_viewed"`
+}
+
+// updateProfileRequest replaces the authenticated user's profile customization
+type updateProfileRequest struct {
+	Theme           string `json:"theme" binding:"required,theme"`
+	BackgroundColor string `json:"background_color" binding:"required,hexcolor"`
+	TextColor       string `json:"text_color" binding:"required,hexcolor"`
+	CustomMessage   string `json:"custom_message" binding:"max=280"`
+	ShowStats       *bool  `json:"show_stats" binding:"required"`
+	ShowHistory     *bool  `json:"show_history" binding:"required"`
+	ShowLyrics      *bool  `json:"show_lyrics"`
+	AnimationStyle  string `json:"animation_style" binding:"required,animation_style"`
+	// Force saves colors below the WCAG AA contrast ratio, with a warning
+	Force bool `json:"force"`
+}
+
+// updateProfileResponse reports a saved profile and any accessibility warnings
+type updateProfileResponse struct {
+	Success  bool     `json:"success"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// contrastDetails explains a rejected color pair and suggests accessible ones
+type contrastDetails struct {
+	ContrastRatio float64            `json:"contrast_ratio"`
+	MinimumRatio  float64            `json:"minimum_ratio"`
+	Suggestions   []services.Palette `json:"suggestions"`
+}
+
+// paletteQuery names the color pair to check
+type paletteQuery struct {
+	BackgroundColor string `form:"background_color" json:"background_color" binding:"required,hexcolor"`
+	TextColor       string `form:"text_color" json:"text_color" binding:"required,hexcolor"`
+}
+
+// paletteResponse reports a color pair's contrast and accessible alternatives
+type paletteResponse struct {
+	BackgroundColor string             `json:"background_color"`
+	TextColor       string             `json:"text_color"`
+	ContrastRatio   float64            `json:"contrast_ratio"`
+	MinimumRatio    float64            `json:"minimum_ratio"`
+	Accessible      bool               `json:"accessible"`
+	Suggestions     []services.Palette `json:"suggestions"`
+}
+
+// createShortLinkRequest creates a short link to the caller's profile
+type createShortLinkRequest struct {
+	Label         string `json:"label" binding:"max=100"`
+	ExpiresInDays int    `json:"expires_in_days" binding:"omitempty,min=1,max=3650"`
+}
+
+// shortLinkResponse is a short link with its full URL
+type shortLinkResponse struct {
+	models.ShortLink
+	URL string `json:"url"`
+}
+
+// shortLinksResponse lists the caller's short links
+type shortLinksResponse struct {
+	Links []shortLinkResponse `json:"links"`
+}
+
+// shortLinkStatsResponse is a short link's click analytics
+type shortLinkStatsResponse struct {
+	models.ShortLinkStats
+	URL string `json:"url"`
+}
+
+// createAPIKeyRequest names a new API key
+type createAPIKeyRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// apiKeyCreatedResponse is a new API key, including the key itself, which is
+// never shown again
+type apiKeyCreatedResponse struct {
+	models.APIKey
+	Key string `json:"key"`
+}
+
+// sessionsResponse lists where the caller is signed in
+type sessionsResponse struct {
+	Sessions []models.Session `json:"sessions"`
+}
+
+// accountDeletedResponse reports when a deleted account will be purged for
+// good
+type accountDeletedResponse struct {
+	Success    bool      `json:"success"`
+	PurgeAfter time.Time `json:"purge_after"`
+}
+
+// sessionsRevokedResponse reports how many sessions were signed out
+type sessionsRevokedResponse struct {
+	Revoked int `json:"revoked"`
+}
+
+// apiKeysResponse lists the caller's API keys
+type apiKeysResponse struct {
+	Keys []models.APIKey `json:"keys"`
+}
+
+// createWebhookRequest registers a URL to be sent the caller's track changes
+type createWebhookRequest struct {
+	URL         string `json:"url" binding:"required,url,max=2048"`
+	Description string `json:"description" binding:"max=100"`
+}
+
+// webhookSecretResponse is a webhook with the secret its deliveries are
+// signed with, which is only shown when it's created or rotated
+type webhookSecretResponse struct {
+	models.Webhook
+	Secret string `json:"secret"`
+}
+
+// webhooksResponse lists the caller's webhooks
+type webhooksResponse struct {
+	Webhooks []models.Webhook `json:"webhooks"`
+}
+
+// webhookDeliveriesResponse lists a webhook's recent deliveries
+type webhookDeliveriesResponse struct {
+	Deliveries []models.WebhookDelivery `json:"deliveries"`
+}
+
+// scrobbleAccountsResponse lists the caller's connected scrobbling accounts
+type scrobbleAccountsResponse struct {
+	Accounts []models.ScrobbleAccountStatus `json:"accounts"`
+}
+
+// connectListenBrainzRequest is the token from the caller's ListenBrainz
+// settings page
+type connectListenBrainzRequest struct {
+	Token string `json:"token" binding:"required,max=100"`
+}
+
+// scrobblesResponse lists a scrobbling account's recent scrobbles
+type scrobblesResponse struct {
+	Scrobbles []models.Scrobble `json:"scrobbles"`
+}
+
+// apiKeyUsageQuery selects how many days of usage to report
+type apiKeyUsageQuery struct {
+	Days int `form:"days" json:"days" binding:"omitempty,min=1,max=90"`
+}
+
+// apiKeyUsageResponse is an API key's usage report
+type apiKeyUsageResponse struct {
+	models.APIKeyUsage
+}
+
+// manualNowPlayingRequest sets a hand-entered now-playing entry lasting up
+// to six hours
+type manualNowPlayingRequest struct {
+	Title           string `json:"title" binding:"required,max=255"`
+	Artist          string `json:"artist" binding:"required,max=255"`
+	Album           string `json:"album" binding:"max=255"`
+	ArtworkURL      string `json:"artwork_url" binding:"omitempty,max=2048,http_url"`
+	SpotifyURL      string `json:"spotify_url" binding:"omitempty,max=2048,spotify_url"`
+	DurationSeconds int    `json:"duration_seconds" binding:"required,min=1,max=21600"`
+}
+
+// reportNowPlayingRequest is playback a client such as a browser extension
+// reports. IsPlaying defaults to true.
+type reportNowPlayingRequest struct {
+	Title      string `json:"title" binding:"required,max=255"`
+	Artist     string `json:"artist" binding:"required,max=255"`
+	Album      string `json:"album" binding:"max=255"`
+	ArtworkURL string `json:"artwork_url" binding:"omitempty,max=2048,http_url"`
+	TrackURL   string `json:"track_url" binding:"omitempty,max=2048,http_url"`
+	DurationMs int    `json:"duration_ms" binding:"min=0,max=21600000"`
+	ProgressMs int    `json:"progress_ms" binding:"min=0,max=21600000"`
+	IsPlaying  *bool  `json:"is_playing"`
+}
+
+// trackHistoryQuery holds the filters and paging options for track history
+type trackHistoryQuery struct {
+	From         string `form:"from" json:"from" binding:"omitempty,timestamp"`
+	To           string `form:"to" json:"to" binding:"omitempty,timestamp"`
+	Artist       string `form:"artist" json:"artist" binding:"max=255"`
+	Album        string `form:"album" json:"album" binding:"max=255"`
+	Cursor       string `form:"cursor" json:"cursor"`
+	Limit        int    `form:"limit" json:"limit" binding:"omitempty,min=1,max=100"`
+	IncludeTotal bool   `form:"include_total" json:"include_total"`
+}
+
+// trackHistoryResponse wraps a page of track history
+type trackHistoryResponse struct {
+	Tracks     []models.Track `json:"tracks"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+	Total      *int           `json:"total,omitempty"`
+}
+
+// randomTrackResponse is a track from the caller's history with its play stats
+type randomTrackResponse struct {
+	Track         models.Track `json:"track"`
+	PlayCount     int          `json:"play_count"`
+	FirstPlayedAt time.Time    `json:"first_played_at"`
+	LastPlayedAt  time.Time    `json:"last_played_at"`
+}
+
+// trackSearchQuery holds a history search
+type trackSearchQuery struct {
+	Q     string `form:"q" json:"q" binding:"required,max=200"`
+	Limit int    `form:"limit" json:"limit" binding:"omitempty,min=1,max=50"`
+}
+
+// trackSearchResult is a track matching a history search with its play stats
+type trackSearchResult struct {
+	Track         models.Track `json:"track"`
+	PlayCount     int          `json:"play_count"`
+	FirstPlayedAt time.Time    `json:"first_played_at"`
+	LastPlayedAt  time.Time    `json:"last_played_at"`
+}
+
+// trackSearchResponse wraps history search results, best match first
+type trackSearchResponse struct {
+	Results []trackSearchResult `json:"results"`
+}
+
+// listeningSessionsQuery holds the filters and paging options for listening
+// sessions
+type listeningSessionsQuery struct {
+	From   string `form:"from" json:"from" binding:"omitempty,timestamp"`
+	To     string `form:"to" json:"to" binding:"omitempty,timestamp"`
+	Cursor string `form:"cursor" json:"cursor"`
+	Limit  int    `form:"limit" json:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// listeningSessionsResponse wraps a page of listening sessions
+type listeningSessionsResponse struct {
+	Sessions   []models.ListeningSession `json:"sessions"`
+	NextCursor string                    `json:"next_cursor,omitempty"`
+}
+
+// statsQuery picks the time range and size of a top artists or tracks ranking
+type statsQuery struct {
+	Range string `form:"range" json:"range" binding:"omitempty,oneof=week month year all"`
+	Limit int    `form:"limit" json:"limit" binding:"omitempty,min=1,max=50"`
+}
+
+// dailyStatsResponse is the caller's listening for each day in the range.
+// From is when the range starts, and is omitted for all time.
+type dailyStatsResponse struct {
+	Range  string                       `json:"range"`
+	From   *time.Time                   `json:"from,omitempty"`
+	Days   []models.DailyListeningStats `json:"days"`
+	Totals listeningTotals              `json:"totals"`
+}
+
+// listeningTotals adds up daily stats over a range
+type listeningTotals struct {
+	Plays           int `json:"plays"`
+	MinutesListened int `json:"minutes_listened"`
+	ActiveDays      int `json:"active_days"`
+}
+
+// minutesQuery picks the time range and breakdown of minutes listened
+type minutesQuery struct {
+	Range  string `form:"range" json:"range" binding:"omitempty,oneof=week month year all"`
+	Period string `form:"period" json:"period" binding:"omitempty,oneof=day week month"`
+}
+
+// minutesResponse breaks down the caller's minutes listened over the range.
+// From is when the range starts, and is omitted for all time.
+type minutesResponse struct {
+	Range           string                   `json:"range"`
+	From            *time.Time               `json:"from,omitempty"`
+	Period          string                   `json:"period"`
+	MinutesListened int                      `json:"minutes_listened"`
+	Periods         []models.ListeningPeriod `json:"periods"`
+}
+
+// visitAnalyticsQuery picks the time range and breakdown of visit analytics
+type visitAnalyticsQuery struct {
+	Range       string `form:"range" json:"range" binding:"omitempty,oneof=week month year all"`
+	Period      string `form:"period" json:"period" binding:"omitempty,oneof=day week month"`
+	IncludeBots bool   `form:"include_bots" json:"include_bots"`
+}
+
+// visitAnalyticsResponse summarizes the visits to the caller's profile over
+// the range. From is when the range starts, and is omitted for all time.
+type visitAnalyticsResponse struct {
+	Range  string     `json:"range"`
+	From   *time.Time `json:"from,omitempty"`
+	Period string     `json:"period"`
+	models.VisitAnalytics
+}
+
+// heatmapResponse counts the caller's plays over the range by weekday and
+// hour. From is when the range starts, and is omitted for all time.
+type heatmapResponse struct {
+	Range string     `json:"range"`
+	From  *time.Time `json:"from,omitempty"`
+	models.ListeningHeatmap
+}
+
+// topArtistsResponse ranks the caller's most played artists. From is when the
+// range starts, and is omitted for all time.
+type topArtistsResponse struct {
+	Range   string             `json:"range"`
+	From    *time.Time         `json:"from,omitempty"`
+	Artists []models.TopArtist `json:"artists"`
+}
+
+// topTracksResponse ranks the caller's most played tracks. From is when the
+// range starts, and is omitted for all time.
+type topTracksResponse struct {
+	Range  string            `json:"range"`
+	From   *time.Time        `json:"from,omitempty"`
+	Tracks []models.TopTrack `json:"tracks"`
+}
+
+// moodStatsResponse breaks down how the caller's plays sound. From is when
+// the range starts, and is omitted for all time.
+type moodStatsResponse struct {
+	Range string     `json:"range"`
+	From  *time.Time `json:"from,omitempty"`
+	models.MoodStats
+}
+
+// recentlyViewedResponse lists the profiles the caller visited recently
+type recentlyViewedResponse struct {
+	Profiles []models.RecentlyViewedProfile `json:"profiles"`
+}
+
+// historyExportQuery picks the download format of a history export
+type historyExportQuery struct {
+	Format string `form:"format" json:"format" binding:"omitempty,oneof=json csv"`
+}
+
+// usageReportQuery picks the download format of a usage report
+type usageReportQuery struct {
+	Format string `form:"format" json:"format" binding:"omitempty,oneof=json csv"`
+}
+
+// usageReportsResponse lists the months with a usage report, newest first
+type usageReportsResponse struct {
+	Months []string `json:"months"`
+}
+
+// speechResponse is a spoken summary of what a profile is playing
+type speechResponse struct {
+	XMLName   xml.Name `json:"-" xml:"speech"`
+	Text      string   `json:"text" xml:"text"`
+	Locale    string   `json:"locale" xml:"locale"`
+	IsPlaying bool     `json:"is_playing" xml:"is_playing"`
+}
+
+// maxBatchProfiles caps how many profiles one batch now-playing request may ask
+// for; keep it in sync with the max rule on batchNowPlayingRequest
+const maxBatchProfiles = 50
+
+// batchNowPlayingRequest lists the profiles to look up in one batch
+type batchNowPlayingRequest struct {
+	ProfileURLs []string `json:"profile_urls" binding:"required,min=1,max=50,dive,required,max=255"`
+}
+
+// batchNowPlayingResult is the outcome for one requested profile. Status is
+// "ok", "not_found", or "unavailable" (the profile isn't sharing).
+type batchNowPlayingResult struct {
+	ProfileURL string                          `json:"profile_url"`
+	Status     string                          `json:"status"`
+	NowPlaying *models.SpotifyCurrentlyPlaying `json:"now_playing,omitempty"`
+}
+
+// batchNowPlayingResponse holds one result per distinct requested profile, in
+// request order
+type batchNowPlayingResponse struct {
+	Results []batchNowPlayingResult `json:"results"`
+}
+
+// logLevelsResponse reports the current level of every log module
+type logLevelsResponse struct {
+	Levels map[string]string `json:"levels"`
+}
+
+// setLogLevelRequest changes one log module's level
+type setLogLevelRequest struct {
+	Level string `json:"level" binding:"required,oneof=trace debug info warn error"`
+}
+
+// healthResponse reports dependency health. Status is "ok", "degraded", or
+// "unavailable".
+type healthResponse struct {
+	Status        string         `json:"status"`
+	Database      healthCheck    `json:"database"`
+	Redis         healthCheck    `json:"redis"`
+	SpotifyCanary *canary.Result `json:"spotify_canary,omitempty"`
+}
+
+// healthCheck is the state of one dependency
+type healthCheck struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/audit"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/openapi"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/ratelimit"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// lastfmStateCookie carries the state through Last.fm authorization
+const lastfmStateCookie = "lastfm_auth_state"
+
+// RegisterScrobbleHandlers registers the routes users connect scrobbling
+// accounts and manage them with. The routes to connect each service are only
+// registered when it's configured.
+func RegisterScrobbleHandlers(r *gin.Engine, scrobbles *services.ScrobbleService, userService *services.UserService, limiter *ratelimit.Limiter, logger zerolog.Logger) {
+	handler := &scrobbleHandler{
+		scrobbles:   scrobbles,
+		userService: userService,
+		logger:      logger.With().Str("handler", "scrobble").Logger(),
+	}
+
+	if scrobbles.LastFMEnabled() {
+		lastfm := r.Group("/auth/lastfm", authMiddleware(userService), sessionOnly("Connecting Last.fm"))
+		handle(lastfm, http.MethodGet, "", openapi.Operation{
+			Summary:     "Connect Last.fm",
+			Description: "Redirects to Last.fm to authorize scrobbling your listens.",
+			Tag:         "scrobbling",
+			Auth:        true,
+			SessionOnly: true,
+			Responses: map[int]interface{}{
+				http.StatusTemporaryRedirect: nil,
+				http.StatusUnauthorized:      errorResponse{},
+				http.StatusForbidden:         errorResponse{},
+			},
+		}, handler.connectLastFM)
+		handle(lastfm, http.MethodGet, "/callback", openapi.Operation{
+			Summary:     "Complete connecting Last.fm",
+			Tag:         "scrobbling",
+			Auth:        true,
+			SessionOnly: true,
+			Params: []openapi.Param{
+				{Name: "token", In: "query", Required: true, Description: "Token from Last.fm"},
+				{Name: "state", In: "query", Required: true, Description: "State issued by /auth/lastfm"},
+			},
+			Responses: map[int]interface{}{
+				http.StatusTemporaryRedirect:   nil,
+				http.StatusBadRequest:          errorResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusForbidden:           errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.lastFMCallback)
+	}
+
+	registerAPIRoutes(r, "/scrobbling", []gin.HandlerFunc{authMiddleware(userService), rateLimit(limiter, "api")}, func(group *gin.RouterGroup) {
+		if scrobbles.ListenBrainzEnabled() {
+			handle(group, http.MethodPut, "/listenbrainz", openapi.Operation{
+				Summary:     "Connect ListenBrainz",
+				Description: "Checks the token from your ListenBrainz settings page and starts submitting your listens with it, replacing any token saved before.",
+				Tag:         "scrobbling",
+				Auth:        true,
+				SessionOnly: true,
+				Request:     connectListenBrainzRequest{},
+				Responses: map[int]interface{}{
+					http.StatusOK:                  models.ScrobbleAccount{},
+					http.StatusBadRequest:          errorResponse{},
+					http.StatusUnauthorized:        errorResponse{},
+					http.StatusForbidden:           errorResponse{},
+					http.StatusInternalServerError: errorResponse{},
+				},
+			}, sessionOnly("Connecting ListenBrainz"), handler.connectListenBrainz)
+		}
+		handle(group, http.MethodGet, "", openapi.Operation{
+			Summary:     "List your scrobbling accounts",
+			Description: "Each account has counts of its pending, submitted, and failed scrobbles, when one was last submitted, and the latest error.",
+			Tag:         "scrobbling",
+			Auth:        true,
+			Responses: map[int]interface{}{
+				http.StatusOK:                  scrobbleAccountsResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.list)
+		handle(group, http.MethodGet, "/:provider/scrobbles", openapi.Operation{
+			Summary:     "List an account's scrobbles",
+			Description: "Returns the account's most recent scrobbles, newest first, with their status, attempts, and last error.",
+			Tag:         "scrobbling",
+			Auth:        true,
+			Params: []openapi.Param{
+				{Name: "provider", In: "path", Description: "Scrobbling service: lastfm or listenbrainz"},
+			},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  scrobblesResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusNotFound:            errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.listScrobbles)
+		handle(group, http.MethodDelete, "/:provider", openapi.Operation{
+			Summary:     "Disconnect a scrobbling account",
+			Description: "Stops scrobbling to the account and drops any listens still queued for it.",
+			Tag:         "scrobbling",
+			Auth:        true,
+			Params: []openapi.Param{
+				{Name: "provider", In: "path", Description: "Scrobbling service: lastfm or listenbrainz"},
+			},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  successResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusNotFound:            errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.disconnect)
+	})
+}
+
+type scrobbleHandler struct {
+	scrobbles   *services.ScrobbleService
+	userService *services.UserService
+	logger      zerolog.Logger
+}
+
+// connectLastFM redirects to Last.fm's authorization page
+func (h *scrobbleHandler) connectLastFM(c *gin.Context) {
+	state := uuid.New().String()
+	c.SetCookie(lastfmStateCookie, state, 60*15, "/", "", false, true)
+	c.Redirect(http.StatusTemporaryRedirect, h.scrobbles.LastFMAuthURL(state))
+}
+
+// lastFMCallback saves the caller's Last.fm session and sends them back to
+// their profile
+func (h *scrobbleHandler) lastFMCallback(c *gin.Context) {
+	state := c.Query("state")
+	storedState, err := c.Cookie(lastfmStateCookie)
+	if err != nil || state == "" || state != storedState {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Str("provided_state", state).Str("stored_state", storedState).Msg("State validation failed")
+		auditEvent(c, audit.EventCSRFRejected, "lastfm_state_mismatch", nil)
+		abortWithError(c, apperr.Invalid("oauth_state_mismatch", "State validation failed"))
+		return
+	}
+	c.SetCookie(lastfmStateCookie, "", -1, "/", "", false, true)
+
+	userID := c.GetString("user_id")
+	if _, err := h.scrobbles.ConnectLastFM(c.Request.Context(), userID, c.Query("token")); err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to connect Last.fm")
+		abortWithError(c, apperr.From(err, "lastfm_connect_failed", "Failed to connect Last.fm"))
+		return
+	}
+
+	user, err := h.userService.GetUserByID(c.Request.Context(), userID)
+	if err != nil {
+		abortWithError(c, apperr.From(err, "user_lookup_failed", "Failed to get user"))
+		return
+	}
+	c.Redirect(http.StatusTemporaryRedirect, "/profile/"+user.ProfileURL)
+}
+
+// connectListenBrainz saves the caller's ListenBrainz token
+func (h *scrobbleHandler) connectListenBrainz(c *gin.Context) {
+	var req connectListenBrainzRequest
+	if err := bindJSON(c, &req); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	account, err := h.scrobbles.ConnectListenBrainz(c.Request.Context(), c.GetString("user_id"), req.Token)
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to connect ListenBrainz")
+		abortWithError(c, apperr.From(err, "listenbrainz_connect_failed", "Failed to connect ListenBrainz"))
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+// list returns the caller's scrobbling accounts
+func (h *scrobbleHandler) list(c *gin.Context) {
+	accounts, err := h.scrobbles.ListAccounts(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to list scrobbling accounts")
+		abortWithError(c, apperr.From(err, "scrobbling_list_failed", "Failed to list scrobbling accounts"))
+		return
+	}
+
+	c.JSON(http.StatusOK, scrobbleAccountsResponse{Accounts: accounts})
+}
+
+// listScrobbles returns one of the caller's scrobbling accounts' recent
+// scrobbles
+func (h *scrobbleHandler) listScrobbles(c *gin.Context) {
+	scrobbles, err := h.scrobbles.ListScrobbles(c.Request.Context(), c.GetString("user_id"), c.Param("provider"))
+	if err != nil {
+		abortWithError(c, apperr.From(err, "scrobbles_list_failed", "Failed to list scrobbles"))
+		return
+	}
+
+	c.JSON(http.StatusOK, scrobblesResponse{Scrobbles: scrobbles})
+}
+
+// disconnect removes one of the caller's scrobbling accounts
+func (h *scrobbleHandler) disconnect(c *gin.Context) {
+	if err := h.scrobbles.Disconnect(c.Request.Context(), c.GetString("user_id"), c.Param("provider")); err != nil {
+		abortWithError(c, apperr.From(err, "scrobbling_disconnect_failed", "Failed to disconnect scrobbling account"))
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse{Success: true})
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/openapi"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/ratelimit"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+// RegisterSessionHandlers registers the routes users see and revoke the
+// browsers they're signed in on with
+func RegisterSessionHandlers(r *gin.Engine, userService *services.UserService, limiter *ratelimit.Limiter, logger zerolog.Logger) {
+	handler := &sessionHandler{
+		userService: userService,
+		logger:      logger.With().Str("handler", "session").Logger(),
+	}
+
+	registerAPIRoutes(r, "/sessions", []gin.HandlerFunc{authMiddleware(userService), sessionOnly("Managing sessions"), rateLimit(limiter, "api")}, func(sessions *gin.RouterGroup) {
+		handle(sessions, http.MethodGet, "", openapi.Operation{
+			Summary:     "List where you're signed in",
+			Description: "Lists your unexpired sessions, most recently active first, with the IP address and user agent that signed in. The session making the request has current set.",
+			Tag:         "sessions",
+			Auth:        true,
+			SessionOnly: true,
+			Responses: map[int]interface{}{
+				http.StatusOK:                  sessionsResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusForbidden:           errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.list)
+		handle(sessions, http.MethodDelete, "", openapi.Operation{
+			Summary:     "Sign out everywhere else",
+			Description: "Revokes every session except the one making the request.",
+			Tag:         "sessions",
+			Auth:        true,
+			SessionOnly: true,
+			Responses: map[int]interface{}{
+				http.StatusOK:                  sessionsRevokedResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusForbidden:           errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.revokeOthers)
+		handle(sessions, http.MethodDelete, "/:id", openapi.Operation{
+			Summary:     "Revoke a session",
+			Description: "Signs that browser out. Revoking the current session signs you out.",
+			Tag:         "sessions",
+			Auth:        true,
+			SessionOnly: true,
+			Params: []openapi.Param{
+				{Name: "id", In: "path", Description: "Session ID"},
+			},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  successResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusForbidden:           errorResponse{},
+				http.StatusNotFound:            errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.revoke)
+	})
+}
+
+type sessionHandler struct {
+	userService *services.UserService
+	logger      zerolog.Logger
+}
+
+// list returns the caller's sessions
+func (h *sessionHandler) list(c *gin.Context) {
+	sessions, err := h.userService.ListSessions(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to list sessions")
+		abortWithError(c, apperr.From(err, "session_list_failed", "Failed to list sessions"))
+		return
+	}
+
+	currentID := c.GetString("session_id")
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+
+	c.JSON(http.StatusOK, sessionsResponse{Sessions: sessions})
+}
+
+// revoke signs out one of the caller's sessions
+func (h *sessionHandler) revoke(c *gin.Context) {
+	sessionID := c.Param("id")
+	if err := h.userService.RevokeSession(c.Request.Context(), c.GetString("user_id"), sessionID); err != nil {
+		abortWithError(c, apperr.From(err, "session_revoke_failed", "Failed to revoke session"))
+		return
+	}
+
+	if sessionID == c.GetString("session_id") {
+		clearSessionCookie(c)
+	}
+	c.JSON(http.StatusOK, successResponse{Success: true})
+}
+
+// revokeOthers signs out every session but the caller's
+func (h *sessionHandler) revokeOthers(c *gin.Context) {
+	revoked, err := h.userService.RevokeOtherSessions(c.Request.Context(), c.GetString("user_id"), c.GetString("session_id"))
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to revoke sessions")
+		abortWithError(c, apperr.From(err, "session_revoke_failed", "Failed to revoke sessions"))
+		return
+	}
+
+	c.JSON(http.StatusOK, sessionsRevokedResponse{Revoked: revoked})
+}
@@ -0,0 +1,339 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/openapi"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/ratelimit"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+// rangeParam picks the time range every stats endpoint covers
+var rangeParam = openapi.Param{Name: "range", In: "query", Description: "week (last 7 days), month (last 30 days, the default), year, or all"}
+
+// statsParams are the query parameters shared by the top artist and track
+// endpoints
+var statsParams = []openapi.Param{
+	rangeParam,
+	{Name: "limit", In: "query", Type: "integer", Description: "How many to rank, 1-50 (default 10)"},
+}
+
+// wrappedYearParam is the calendar year of a year in review
+var wrappedYearParam = openapi.Param{Name: "year", In: "path", Type: "integer", Description: "Calendar year, from 2000 to the current year"}
+
+// RegisterStatsHandlers registers the listening stats routes
+func RegisterStatsHandlers(r *gin.Engine, profileService *services.ProfileService, userService *services.UserService, limiter *ratelimit.Limiter, logger zerolog.Logger) {
+	handler := &statsHandler{
+		profileService: profileService,
+		userService:    userService,
+		logger:         logger.With().Str("handler", "stats").Logger(),
+	}
+
+	registerAPIRoutes(r, "/stats", []gin.HandlerFunc{authMiddleware(userService), rateLimit(limiter, "api")}, func(stats *gin.RouterGroup) {
+		handle(stats, http.MethodGet, "/top-artists", openapi.Operation{
+			Summary:     "Get your most played artists",
+			Description: "Ranks artists by plays in your history over the range, breaking ties by the most recently played.",
+			Tag:         "stats",
+			Auth:        true,
+			Params:      statsParams,
+			Responses: map[int]interface{}{
+				http.StatusOK:                  topArtistsResponse{},
+				http.StatusBadRequest:          errorResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.getTopArtists)
+		handle(stats, http.MethodGet, "/top-tracks", openapi.Operation{
+			Summary:     "Get your most played tracks",
+			Description: "Ranks tracks by plays in your history over the range, breaking ties by the most recently played. Each track is described by its latest play.",
+			Tag:         "stats",
+			Auth:        true,
+			Params:      statsParams,
+			Responses: map[int]interface{}{
+				http.StatusOK:                  topTracksResponse{},
+				http.StatusBadRequest:          errorResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.getTopTracks)
+		handle(stats, http.MethodGet, "/daily", openapi.Operation{
+			Summary:     "Get your daily listening",
+			Description: "Returns plays, minutes listened, and unique artists for each day you listened in the range, in your time zone, with totals. Served from rollups refreshed in the background, so today's numbers may lag a few minutes.",
+			Tag:         "stats",
+			Auth:        true,
+			Params:      []openapi.Param{rangeParam},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  dailyStatsResponse{},
+				http.StatusBadRequest:          errorResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.getDailyStats)
+		handle(stats, http.MethodGet, "/minutes", openapi.Operation{
+			Summary:     "Get your minutes listened",
+			Description: "Totals how long you listened over the range, skips included, broken down by day, week (starting Monday), or month in your time zone. A play lasts from when it started until the next track or a stop, capped at the track's length. Served from the daily rollups.",
+			Tag:         "stats",
+			Auth:        true,
+			Params: []openapi.Param{
+				rangeParam,
+				{Name: "period", In: "query", Description: "day (the default), week, or month"},
+			},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  minutesResponse{},
+				http.StatusBadRequest:          errorResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.getMinutesListened)
+		handle(stats, http.MethodGet, "/heatmap", openapi.Operation{
+			Summary:     "Get your listening heatmap",
+			Description: "Counts your plays over the range by weekday and hour in your time zone, as a 7x24 matrix: plays[weekday][hour], with Monday first and hours from 0 to 23.",
+			Tag:         "stats",
+			Auth:        true,
+			Params:      []openapi.Param{rangeParam},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  heatmapResponse{},
+				http.StatusBadRequest:          errorResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.getHeatmap)
+		handle(stats, http.MethodGet, "/wrapped/:year", openapi.Operation{
+			Summary:     "Get your year in review",
+			Description: "Summarizes a calendar year in your time zone: plays, minutes listened, active days, your busiest day, top 5 artists and tracks, and a mood breakdown from audio features. Years still in progress are cached for an hour, finished ones for a day.",
+			Tag:         "stats",
+			Auth:        true,
+			Params:      []openapi.Param{wrappedYearParam},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  models.Wrapped{},
+				http.StatusBadRequest:          errorResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.getWrapped)
+		handle(stats, http.MethodGet, "/mood", openapi.Operation{
+			Summary:     "Get your mood and energy breakdown",
+			Description: "Breaks down your plays over the range by energy and by mood, from the tracks' Spotify audio features. Features are fetched in the background, so new plays may not be analyzed yet.",
+			Tag:         "stats",
+			Auth:        true,
+			Params:      []openapi.Param{rangeParam},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  moodStatsResponse{},
+				http.StatusBadRequest:          errorResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.getMoodStats)
+	})
+}
+
+type statsHandler struct {
+	profileService *services.ProfileService
+	userService    *services.UserService
+	logger         zerolog.Logger
+}
+
+// bindStatsQuery reads the range and limit, returning when the range starts
+// (the zero time for all time)
+func bindStatsQuery(c *gin.Context) (statsQuery, time.Time, error) {
+	var req statsQuery
+	if err := bindQuery(c, &req); err != nil {
+		return req, time.Time{}, err
+	}
+	if req.Range == "" {
+		req.Range = services.StatsRangeMonth
+	}
+	return req, services.StatsRangeStart(req.Range, time.Now()), nil
+}
+
+// rangeStart returns from for a stats response, or nil for all time
+func rangeStart(from time.Time) *time.Time {
+	if from.IsZero() {
+		return nil
+	}
+	return &from
+}
+
+// getTopArtists ranks the user's most played artists
+func (h *statsHandler) getTopArtists(c *gin.Context) {
+	req, from, err := bindStatsQuery(c)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	artists, err := h.profileService.GetTopArtists(c.Request.Context(), c.GetString("user_id"), from, time.Time{}, req.Limit)
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to get top artists")
+		abortWithError(c, apperr.From(err, "stats_fetch_failed", "Failed to get top artists"))
+		return
+	}
+
+	c.JSON(http.StatusOK, topArtistsResponse{Range: req.Range, From: rangeStart(from), Artists: artists})
+}
+
+// getTopTracks ranks the user's most played tracks
+func (h *statsHandler) getTopTracks(c *gin.Context) {
+	req, from, err := bindStatsQuery(c)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	tracks, err := h.profileService.GetTopTracks(c.Request.Context(), c.GetString("user_id"), from, time.Time{}, req.Limit)
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to get top tracks")
+		abortWithError(c, apperr.From(err, "stats_fetch_failed", "Failed to get top tracks"))
+		return
+	}
+
+	c.JSON(http.StatusOK, topTracksResponse{Range: req.Range, From: rangeStart(from), Tracks: tracks})
+}
+
+// getDailyStats reports the user's listening for each day in the range
+func (h *statsHandler) getDailyStats(c *gin.Context) {
+	req, from, err := bindStatsQuery(c)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	days, err := h.profileService.GetDailyStats(c.Request.Context(), c.GetString("user_id"), from)
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to get daily stats")
+		abortWithError(c, apperr.From(err, "stats_fetch_failed", "Failed to get daily stats"))
+		return
+	}
+
+	var totals listeningTotals
+	var listenedMs int64
+	for _, day := range days {
+		totals.Plays += day.Plays
+		listenedMs += day.ListenedMs
+		if day.Plays > 0 {
+			totals.ActiveDays++
+		}
+	}
+	totals.MinutesListened = int(listenedMs / int64(time.Minute/time.Millisecond))
+
+	c.JSON(http.StatusOK, dailyStatsResponse{Range: req.Range, From: rangeStart(from), Days: days, Totals: totals})
+}
+
+// getMinutesListened breaks down how long the user listened by period
+func (h *statsHandler) getMinutesListened(c *gin.Context) {
+	var req minutesQuery
+	if err := bindQuery(c, &req); err != nil {
+		abortWithError(c, err)
+		return
+	}
+	if req.Range == "" {
+		req.Range = services.StatsRangeMonth
+	}
+	if req.Period == "" {
+		req.Period = services.PeriodDay
+	}
+	from := services.StatsRangeStart(req.Range, time.Now())
+
+	periods, err := h.profileService.GetMinutesListened(c.Request.Context(), c.GetString("user_id"), from, req.Period)
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to get minutes listened")
+		abortWithError(c, apperr.From(err, "stats_fetch_failed", "Failed to get minutes listened"))
+		return
+	}
+
+	var listenedMs int64
+	for _, period := range periods {
+		listenedMs += period.ListenedMs
+	}
+
+	c.JSON(http.StatusOK, minutesResponse{
+		Range:           req.Range,
+		From:            rangeStart(from),
+		Period:          req.Period,
+		MinutesListened: int(listenedMs / int64(time.Minute/time.Millisecond)),
+		Periods:         periods,
+	})
+}
+
+// getHeatmap counts the user's plays by weekday and hour
+func (h *statsHandler) getHeatmap(c *gin.Context) {
+	req, from, err := bindStatsQuery(c)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	user, err := h.userService.GetUserByID(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		abortWithError(c, apperr.From(err, "user_lookup_failed", "Failed to load user"))
+		return
+	}
+
+	heatmap, err := h.profileService.GetHeatmap(c.Request.Context(), user, from)
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to get listening heatmap")
+		abortWithError(c, apperr.From(err, "stats_fetch_failed", "Failed to get listening heatmap"))
+		return
+	}
+
+	c.JSON(http.StatusOK, heatmapResponse{Range: req.Range, From: rangeStart(from), ListeningHeatmap: *heatmap})
+}
+
+// getWrapped summarizes the user's listening in a year
+func (h *statsHandler) getWrapped(c *gin.Context) {
+	year, err := wrappedYear(c)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	user, err := h.userService.GetUserByID(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		abortWithError(c, apperr.From(err, "user_lookup_failed", "Failed to load user"))
+		return
+	}
+
+	wrapped, err := h.profileService.GetWrapped(c.Request.Context(), user, year)
+	if err != nil {
+		if apperr.KindOf(err) != apperr.KindInvalid {
+			h.logger.Error().Ctx(c.Request.Context()).Err(err).Int("year", year).Msg("Failed to get year in review")
+		}
+		abortWithError(c, apperr.From(err, "stats_fetch_failed", "Failed to get year in review"))
+		return
+	}
+
+	c.JSON(http.StatusOK, wrapped)
+}
+
+// wrappedYear reads the year of a year in review from the path
+func wrappedYear(c *gin.Context) (int, error) {
+	year, err := strconv.Atoi(c.Param("year"))
+	if err != nil {
+		return 0, apperr.Invalid("invalid_year", "Year must be a number like 2024")
+	}
+	return year, nil
+}
+
+// getMoodStats breaks down the user's plays by mood and energy
+func (h *statsHandler) getMoodStats(c *gin.Context) {
+	req, from, err := bindStatsQuery(c)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	mood, err := h.profileService.GetMoodStats(c.Request.Context(), c.GetString("user_id"), from, time.Time{})
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to get mood stats")
+		abortWithError(c, apperr.From(err, "stats_fetch_failed", "Failed to get mood stats"))
+		return
+	}
+
+	c.JSON(http.StatusOK, moodStatsResponse{Range: req.Range, From: rangeStart(from), MoodStats: *mood})
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+// TenantMiddleware scopes each request to the tenant whose domain it was sent
+// to, so users, profiles, and the Spotify OAuth flow stay within that brand.
+// Hosts no tenant claims use the default Spotify app.
+func TenantMiddleware(tenants *services.TenantService, logger zerolog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenant, err := tenants.Resolve(c.Request.Context(), c.Request.Host)
+		if err != nil {
+			logger.Error().Ctx(c.Request.Context()).Err(err).Str("host", c.Request.Host).Msg("Failed to resolve tenant")
+			abortWithError(c, apperr.Unavailable("tenant_unavailable", "Service temporarily unavailable"))
+			return
+		}
+		if tenant == nil {
+			c.Next()
+			return
+		}
+		if !tenant.IsActive {
+			abortWithError(c, apperr.NotFound("tenant_not_found", "Site not found"))
+			return
+		}
+
+		c.Set("tenant_id", tenant.ID)
+		c.Request = c.Request.WithContext(services.WithTenant(c.Request.Context(), tenant))
+		c.Next()
+	}
+}
@@ -1,21 +1,36 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
-	"strconv"
 	"time"
 
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/database"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/idempotency"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/metrics"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/openapi"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/ratelimit"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/realtime"
 	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/rs/zerolog"
 )
 
 // RegisterTrackHandlers registers all track-related routes
-func RegisterTrackHandlers(r *gin.Engine, spotifyService *services.SpotifyService, userService *services.UserService, logger zerolog.Logger) {
+func RegisterTrackHandlers(r *gin.Engine, spotifyService *services.SpotifyService, providers *services.Providers, profileService *services.ProfileService, userService *services.UserService, lyrics *services.LyricsService, privacy config.PrivacyConfig, limiter *ratelimit.Limiter, idempotencyStore *idempotency.Store, logger zerolog.Logger) {
 	handler := &trackHandler{
 		spotifyService: spotifyService,
+		providers:      providers,
+		profileService: profileService,
 		userService:    userService,
+		lyrics:         lyrics,
+		privacy:        privacy,
 		logger:         logger.With().Str("handler", "track").Logger(),
 	}
 
@@ -23,21 +38,201 @@ func RegisterTrackHandlers(r *gin.Engine, spotifyService *services.SpotifyServic
 	r.GET("/ws/tracks/:profileURL", handler.trackUpdatesWebSocket)
 
 	// API endpoints
-	tracks := r.Group("/api/tracks")
-	tracks.Use(authMiddleware(userService))
-	{
-		tracks.GET("/current", handler.getCurrentTrack)
-		tracks.GET("/history", handler.getTrackHistory)
-		tracks.POST("/refresh", handler.refreshCurrentTrack)
-	}
+	registerAPIRoutes(r, "/tracks", []gin.HandlerFunc{authMiddleware(userService), rateLimit(limiter, "api")}, func(tracks *gin.RouterGroup) {
+		handle(tracks, http.MethodGet, "/current", openapi.Operation{
+			Summary:     "Get the currently playing track",
+			Description: "Returns a weak ETag; send it back in If-None-Match to get 304 Not Modified while playback is unchanged.",
+			Tag:         "tracks",
+			Auth:        true,
+			Responses: map[int]interface{}{
+				http.StatusOK:                  models.SpotifyCurrentlyPlaying{},
+				http.StatusNotModified:         nil,
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusForbidden:           errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.getCurrentTrack)
+		handle(tracks, http.MethodGet, "/history", openapi.Operation{
+			Summary:     "Get track history",
+			Description: "Returns tracks newest first. Pass next_cursor back as cursor to fetch the following page.",
+			Tag:         "tracks",
+			Auth:        true,
+			Params: []openapi.Param{
+				{Name: "from", In: "query", Description: "Only tracks played at or after this RFC 3339 time or YYYY-MM-DD date"},
+				{Name: "to", In: "query", Description: "Only tracks played before this RFC 3339 time, or on or before this YYYY-MM-DD date"},
+				{Name: "artist", In: "query", Description: "Case-insensitive artist substring"},
+				{Name: "album", In: "query", Description: "Case-insensitive album substring"},
+				{Name: "cursor", In: "query", Description: "Opaque cursor from a previous page"},
+				{Name: "limit", In: "query", Type: "integer", Description: "Page size, 1-100 (default 20)"},
+				{Name: "include_total", In: "query", Type: "boolean", Description: "Also count every matching track"},
+				fieldsParam,
+			},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  trackHistoryResponse{},
+				http.StatusBadRequest:          errorResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.getTrackHistory)
+		handle(tracks, http.MethodGet, "/search", openapi.Operation{
+			Summary:     "Search track history",
+			Description: "Finds tracks you've played whose title, artist, or album match q, best match first, each with its play count and first and last play times. Supports \"quoted phrases\" and -excluded words; when nothing matches exactly, close spellings are tried instead.",
+			Tag:         "tracks",
+			Auth:        true,
+			Params: []openapi.Param{
+				{Name: "q", In: "query", Description: "Search terms", Required: true},
+				{Name: "limit", In: "query", Type: "integer", Description: "Maximum results, 1-50 (default 20)"},
+			},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  trackSearchResponse{},
+				http.StatusBadRequest:          errorResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.searchTrackHistory)
+		handle(tracks, http.MethodGet, "/export", openapi.Operation{
+			Summary:     "Export your track history",
+			Description: "Downloads your whole history, oldest first, as a JSON array of tracks or as CSV. The response is streamed as it's read, so it starts at once however long your history is.",
+			Tag:         "tracks",
+			Auth:        true,
+			Params: []openapi.Param{
+				{Name: "format", In: "query", Description: "json (the default) or csv"},
+			},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  []models.Track{},
+				http.StatusBadRequest:          errorResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.exportTrackHistory)
+		handle(tracks, http.MethodGet, "/random", openapi.Operation{
+			Summary:     "Get a random track from your history",
+			Description: "Picks a track at random, weighted by how long ago it was last played, so older and forgotten tracks come up most. Returns the most recent play of it with its play count and first and last play times. Not cached; every call picks again.",
+			Tag:         "tracks",
+			Auth:        true,
+			Responses: map[int]interface{}{
+				http.StatusOK:                  randomTrackResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusNotFound:            errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.getRandomTrack)
+		handle(tracks, http.MethodGet, "/sessions", openapi.Operation{
+			Summary:     "Get listening sessions",
+			Description: "Returns sessions newest first. A session groups counted plays less than 15 minutes apart, with its start, end, track count, and most-played artist. Pass next_cursor back as cursor to fetch the following page.",
+			Tag:         "tracks",
+			Auth:        true,
+			Params: []openapi.Param{
+				{Name: "from", In: "query", Description: "Only sessions started at or after this RFC 3339 time or YYYY-MM-DD date"},
+				{Name: "to", In: "query", Description: "Only sessions started before this RFC 3339 time, or on or before this YYYY-MM-DD date"},
+				{Name: "cursor", In: "query", Description: "Opaque cursor from a previous page"},
+				{Name: "limit", In: "query", Type: "integer", Description: "Page size, 1-100 (default 20)"},
+			},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  listeningSessionsResponse{},
+				http.StatusBadRequest:          errorResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.getListeningSessions)
+		if lyrics != nil {
+			handle(tracks, http.MethodGet, "/:id/lyrics", openapi.Operation{
+				Summary:     "Get lyrics for a track in your history",
+				Description: "Looks up lyrics for a track you've played, by the track_id from your current track or spotify_track_id from your history. Includes lines timed in milliseconds when synced lyrics exist. Results, including tracks without lyrics, are cached.",
+				Tag:         "tracks",
+				Auth:        true,
+				Params: []openapi.Param{
+					{Name: "id", In: "path", Description: "Track ID"},
+				},
+				Responses: map[int]interface{}{
+					http.StatusOK:                  models.Lyrics{},
+					http.StatusUnauthorized:        errorResponse{},
+					http.StatusNotFound:            errorResponse{},
+					http.StatusServiceUnavailable:  errorResponse{},
+					http.StatusInternalServerError: errorResponse{},
+				},
+			}, handler.getTrackLyrics)
+		}
+		handle(tracks, http.MethodPost, "/refresh", openapi.Operation{
+			Summary:     "Refresh the currently playing track",
+			Description: "Fetches the current track from Spotify, bypassing the cache, and broadcasts it to profile viewers. Subject to a tighter rate limit.",
+			Tag:         "tracks",
+			Auth:        true,
+			Params:      []openapi.Param{idempotencyKeyParam},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  models.SpotifyCurrentlyPlaying{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusForbidden:           errorResponse{},
+				http.StatusConflict:            errorResponse{},
+				http.StatusTooManyRequests:     errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, rateLimit(limiter, "refresh"), idempotent(idempotencyStore), handler.refreshCurrentTrack)
+		handle(tracks, http.MethodPut, "/manual", openapi.Operation{
+			Summary:     "Set a manual now-playing entry",
+			Description: "Shows an entry typed in by hand, for vinyl, radio, or live shows, as playing for duration_seconds. It is cached, saved to history, and broadcast like a Spotify track, and takes precedence over Spotify until it ends.",
+			Tag:         "tracks",
+			Auth:        true,
+			Params:      []openapi.Param{idempotencyKeyParam},
+			Request:     manualNowPlayingRequest{},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  models.SpotifyCurrentlyPlaying{},
+				http.StatusBadRequest:          errorResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusForbidden:           errorResponse{},
+				http.StatusConflict:            errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, idempotent(idempotencyStore), handler.setManualNowPlaying)
+		handle(tracks, http.MethodPost, "/report", openapi.Operation{
+			Summary:     "Report what's playing",
+			Description: "For clients that can see playback no provider reports, such as a browser extension for YouTube or a desktop app for local files. Send the current state whenever it changes; a playing track holds until it would end, and a paused one for a few minutes. It is cached, saved to history, and broadcast like a Spotify track, and takes precedence over Spotify while it holds.",
+			Tag:         "tracks",
+			Auth:        true,
+			Params:      []openapi.Param{idempotencyKeyParam},
+			Request:     reportNowPlayingRequest{},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  models.SpotifyCurrentlyPlaying{},
+				http.StatusBadRequest:          errorResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusForbidden:           errorResponse{},
+				http.StatusConflict:            errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, idempotent(idempotencyStore), handler.reportNowPlaying)
+		handle(tracks, http.MethodDelete, "/manual", openapi.Operation{
+			Summary: "End a manual now-playing entry early",
+			Tag:     "tracks",
+			Auth:    true,
+			Responses: map[int]interface{}{
+				http.StatusOK:                  successResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.clearManualNowPlaying)
+	})
 }
 
 type trackHandler struct {
 	spotifyService *services.SpotifyService
+	providers      *services.Providers
+	profileService *services.ProfileService
 	userService    *services.UserService
+	lyrics         *services.LyricsService
+	privacy        config.PrivacyConfig
 	logger         zerolog.Logger
 }
 
+var websocketConnections = metrics.NewGaugeVec(
+	"websocket_connections",
+	"Open track update WebSocket connections.",
+)
+
+// OpenWebSocketConnections returns the number of open track update sockets
+func OpenWebSocketConnections() int {
+	return int(websocketConnections.Value())
+}
+
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
@@ -53,46 +248,90 @@ func (h *trackHandler) trackUpdatesWebSocket(c *gin.Context) {
 	// Get user by profile URL
 	user, err := h.userService.GetUserByProfileURL(c.Request.Context(), profileURL)
 	if err != nil {
-		h.logger.Error().Err(err).Str("profileURL", profileURL).Msg("Profile not found")
-		c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Str("profileURL", profileURL).Msg("Profile not found")
+		abortWithError(c, apperr.NotFound("profile_not_found", "Profile not found"))
+		return
+	}
+
+	if redirectToCanonicalProfile(c, user) {
 		return
 	}
 
 	// Verify that the user is active and sharing
 	if !user.IsActive || !user.IsSharingEnabled {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Profile not available"})
+		abortWithError(c, apperr.Forbidden("profile_unavailable", "Profile not available"))
 		return
 	}
 
-	// Validate the visitor
+	// Realtime updates need Redis pub/sub; clients fall back to polling
+	if !h.spotifyService.RealtimeAvailable() {
+		abortWithError(c, apperr.Unavailable("realtime_unavailable", "Realtime updates temporarily unavailable"))
+		return
+	}
+
+	// Validate the visitor. Untracked visitors have no visit, so they get a
+	// random ID that is forgotten when the connection closes.
+	anonymous := untracked(c, h.privacy)
 	visitID, err := c.Cookie("visit_id")
-	if err != nil {
-		h.logger.Error().Err(err).Msg("Missing visit_id cookie")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+	if anonymous {
+		visitID = uuid.New().String()
+	} else if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Missing visit_id cookie")
+		abortWithError(c, apperr.Unauthorized("visit_required", "Unauthorized"))
 		return
 	}
+	renewActivity := func(ctx context.Context) error {
+		if anonymous {
+			return h.userService.RenewAnonymousActivity(ctx, user.ID, visitID)
+		}
+		return h.userService.RenewVisitorActivity(ctx, user.ID, visitID)
+	}
 
 	// Upgrade to WebSocket connection
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("Failed to upgrade to WebSocket connection")
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to upgrade to WebSocket connection")
 		return
 	}
 	defer conn.Close()
 
+	websocketConnections.Add(1)
+	defer websocketConnections.Add(-1)
+
 	// Subscribe to Redis channel for track updates
-	ctx := c.Request.Context()
-	pubsub := h.spotifyService.SubscribeToTrackUpdates(ctx, user.ID)
-	defer pubsub.Close()
-	ch := pubsub.Channel()
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	events := h.spotifyService.SubscribeToTrackUpdates(ctx, user.ID).Events()
+
+	// Viewers can opt in to timed lyric lines when the owner shows lyrics.
+	// A nil channel never delivers, so lines stay off otherwise.
+	var lyrics *lyricsFollower
+	var lyricLines <-chan lyricLineMessage
+	if h.lyrics != nil && c.Query("lyrics") == "true" {
+		if profile, err := h.profileService.GetProfile(ctx, user.ID); err == nil && profile.ShowLyrics {
+			lyrics = newLyricsFollower(h.lyrics, h.logger)
+			lyricLines = lyrics.lines
+			go lyrics.run(ctx)
+		}
+	}
 
 	// Send initial track data
 	cachedTrack, err := h.spotifyService.GetCachedCurrentlyPlaying(ctx, user.ID)
 	if err == nil && cachedTrack != nil && cachedTrack.IsPlaying {
 		if err := conn.WriteJSON(cachedTrack); err != nil {
-			h.logger.Error().Err(err).Msg("Failed to send initial track data")
+			h.logger.Error().Ctx(ctx).Err(err).Msg("Failed to send initial track data")
 			return
 		}
+		if lyrics != nil {
+			lyrics.follow(cachedTrack)
+		}
+	}
+
+	// Tracked visitors were counted when the page loaded
+	if anonymous {
+		if err := renewActivity(ctx); err != nil {
+			h.logger.Warn().Ctx(ctx).Err(err).Msg("Failed to count anonymous visitor")
+		}
 	}
 
 	// Renewal routine for visitor activity
@@ -104,9 +343,9 @@ func (h *trackHandler) trackUpdatesWebSocket(c *gin.Context) {
 			select {
 			case <-ticker.C:
 				// Renew visitor activity
-				err := h.userService.RenewVisitorActivity(ctx, visitID)
+				err := renewActivity(ctx)
 				if err != nil {
-					h.logger.Error().Err(err).Msg("Failed to renew visitor activity")
+					h.logger.Error().Ctx(ctx).Err(err).Msg("Failed to renew visitor activity")
 					return
 				}
 			case <-ctx.Done():
@@ -118,10 +357,37 @@ func (h *trackHandler) trackUpdatesWebSocket(c *gin.Context) {
 	// Listen for messages from Redis channel
 	for {
 		select {
-		case msg := <-ch:
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			// Tell the client it may have missed updates so it can refetch
+			if event.Type == realtime.EventResync {
+				if err := conn.WriteJSON(gin.H{"type": realtime.EventResync}); err != nil {
+					h.logger.Error().Ctx(ctx).Err(err).Msg("Failed to write to WebSocket")
+					return
+				}
+				continue
+			}
+
+			var track models.SpotifyCurrentlyPlaying
+			decoded := json.Unmarshal([]byte(event.Payload), &track) == nil
+			if decoded && track.PublishedAt > 0 {
+				database.ObservePubSubDeliveryLag(event.Channel, time.UnixMilli(track.PublishedAt))
+			}
+
 			// Forward track update to the WebSocket client
-			if err := conn.WriteMessage(websocket.TextMessage, []byte(msg.Payload)); err != nil {
-				h.logger.Error().Err(err).Msg("Failed to write to WebSocket")
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(event.Payload)); err != nil {
+				h.logger.Error().Ctx(ctx).Err(err).Msg("Failed to write to WebSocket")
+				return
+			}
+			if lyrics != nil && decoded {
+				lyrics.follow(&track)
+			}
+		case line := <-lyricLines:
+			if err := conn.WriteJSON(line); err != nil {
+				h.logger.Error().Ctx(ctx).Err(err).Msg("Failed to write to WebSocket")
 				return
 			}
 		case <-ctx.Done():
@@ -137,29 +403,35 @@ func (h *trackHandler) getCurrentTrack(c *gin.Context) {
 	// Get user to check if sharing is enabled
 	user, err := h.userService.GetUserByID(c.Request.Context(), userID)
 	if err != nil {
-		h.logger.Error().Err(err).Str("userID", userID).Msg("Failed to get user")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Str("userID", userID).Msg("Failed to get user")
+		abortWithError(c, apperr.From(err, "user_fetch_failed", "Failed to get user"))
 		return
 	}
 
 	if !user.IsSharingEnabled {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Music sharing is disabled"})
+		abortWithError(c, apperr.Forbidden("sharing_disabled", "Music sharing is disabled"))
+		return
+	}
+
+	provider, err := h.providers.ForUser(user)
+	if err != nil {
+		abortWithError(c, err)
 		return
 	}
 
 	// Check if token is expired and refresh if needed
 	if h.userService.IsTokenExpired(user) {
-		tokenResp, err := h.spotifyService.RefreshAccessToken(c.Request.Context(), user.SpotifyRefreshToken)
+		tokenResp, err := provider.RefreshUserToken(c.Request.Context(), user)
 		if err != nil {
-			h.logger.Error().Err(err).Msg("Failed to refresh access token")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh Spotify access"})
+			h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to refresh access token")
+			abortWithError(c, apperr.From(err, "spotify_refresh_failed", "Failed to refresh Spotify access"))
 			return
 		}
 
 		// Update user's token
 		err = h.userService.UpdateUserToken(c.Request.Context(), user.ID, tokenResp.AccessToken, tokenResp.ExpiresIn)
 		if err != nil {
-			h.logger.Error().Err(err).Msg("Failed to update user token")
+			h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to update user token")
 		}
 
 		// Update in-memory token for immediate use
@@ -169,15 +441,18 @@ func (h *trackHandler) getCurrentTrack(c *gin.Context) {
 	// Try to get from cache first
 	cachedTrack, err := h.spotifyService.GetCachedCurrentlyPlaying(c.Request.Context(), user.ID)
 	if err == nil && cachedTrack != nil && cachedTrack.IsPlaying {
+		if notModified(c, nowPlayingETag(cachedTrack)) {
+			return
+		}
 		c.JSON(http.StatusOK, cachedTrack)
 		return
 	}
 
-	// Get from Spotify API
-	track, err := h.spotifyService.GetCurrentlyPlayingTrack(c.Request.Context(), user.SpotifyAccessToken)
+	// Get from the user's provider
+	track, err := provider.GetCurrentlyPlayingTrack(h.spotifyService.WithUserTenant(c.Request.Context(), user), user.SpotifyAccessToken)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("Failed to get currently playing track")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get track from Spotify"})
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to get currently playing track")
+		abortWithError(c, apperr.From(err, "spotify_track_failed", "Failed to get track from Spotify"))
 		return
 	}
 
@@ -185,78 +460,219 @@ func (h *trackHandler) getCurrentTrack(c *gin.Context) {
 	if track.IsPlaying {
 		err = h.spotifyService.CacheCurrentlyPlaying(c.Request.Context(), user.ID, track)
 		if err != nil {
-			h.logger.Warn().Err(err).Msg("Failed to cache currently playing track")
+			h.logger.Warn().Ctx(c.Request.Context()).Err(err).Msg("Failed to cache currently playing track")
 		}
 	}
 
+	if notModified(c, nowPlayingETag(track)) {
+		return
+	}
+
 	c.JSON(http.StatusOK, track)
 }
 
-// getTrackHistory gets the user's track history
+// getTrackHistory gets a filtered page of the user's track history
 func (h *trackHandler) getTrackHistory(c *gin.Context) {
 	userID := c.GetString("user_id")
 
-	// Get limit from query parameters, default to 10
-	limit := 10
-	if limitParam := c.Query("limit"); limitParam != "" {
-		// Convert string to int properly
-		if parsedLimit, err := strconv.Atoi(limitParam); err == nil {
-			limit = parsedLimit
+	var req trackHistoryQuery
+	if err := bindQuery(c, &req); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	query := services.TrackHistoryQuery{
+		Artist:       req.Artist,
+		Album:        req.Album,
+		Cursor:       req.Cursor,
+		Limit:        req.Limit,
+		IncludeTotal: req.IncludeTotal,
+	}
+	if req.From != "" {
+		query.From, _ = parseTimestamp(req.From)
+	}
+	if req.To != "" {
+		query.To, _ = parseTimestamp(req.To)
+		// A bare date includes the whole day
+		if len(req.To) == len(time.DateOnly) {
+			query.To = query.To.AddDate(0, 0, 1)
+		}
+	}
+
+	page, err := h.profileService.GetTrackHistory(c.Request.Context(), userID, query)
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to get track history")
+		abortWithError(c, apperr.From(err, "history_fetch_failed", "Failed to get track history"))
+		return
+	}
+
+	sparseJSON(c, http.StatusOK, trackHistoryResponse{
+		Tracks:     page.Tracks,
+		NextCursor: page.NextCursor,
+		Total:      page.Total,
+	})
+}
+
+// searchTrackHistory searches the user's track history
+func (h *trackHandler) searchTrackHistory(c *gin.Context) {
+	var req trackSearchQuery
+	if err := bindQuery(c, &req); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	results, err := h.profileService.SearchHistory(c.Request.Context(), c.GetString("user_id"), req.Q, req.Limit)
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to search track history")
+		abortWithError(c, apperr.From(err, "search_failed", "Failed to search track history"))
+		return
+	}
+
+	response := trackSearchResponse{Results: make([]trackSearchResult, len(results))}
+	for i, result := range results {
+		response.Results[i] = trackSearchResult{
+			Track:         result.Track,
+			PlayCount:     result.PlayCount,
+			FirstPlayedAt: result.FirstPlayedAt,
+			LastPlayedAt:  result.LastPlayedAt,
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// getRandomTrack picks a "blast from the past" track from the user's history
+func (h *trackHandler) getRandomTrack(c *gin.Context) {
+	random, err := h.profileService.GetRandomTrack(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		if apperr.KindOf(err) != apperr.KindNotFound {
+			h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to get random track")
+		}
+		abortWithError(c, apperr.From(err, "random_track_failed", "Failed to get random track"))
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, randomTrackResponse{
+		Track:         random.Track,
+		PlayCount:     random.PlayCount,
+		FirstPlayedAt: random.FirstPlayedAt,
+		LastPlayedAt:  random.LastPlayedAt,
+	})
+}
+
+// getTrackLyrics gets lyrics for a track the user has played. Owners can
+// always read lyrics for their own history; show_lyrics only governs the
+// public profile.
+func (h *trackHandler) getTrackLyrics(c *gin.Context) {
+	ctx := c.Request.Context()
+	track, err := h.profileService.GetHistoryTrack(ctx, c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		if apperr.KindOf(err) != apperr.KindNotFound {
+			h.logger.Error().Ctx(ctx).Err(err).Msg("Failed to get track for lyrics")
+		}
+		abortWithError(c, apperr.From(err, "track_fetch_failed", "Failed to get track"))
+		return
+	}
+
+	lyrics, err := h.lyrics.GetLyrics(ctx, &models.SpotifyCurrentlyPlaying{
+		TrackID:    track.SpotifyTrackID,
+		TrackName:  track.Name,
+		ArtistName: track.Artist,
+		AlbumName:  track.Album,
+		DurationMs: track.DurationMs,
+	})
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, lyrics)
+}
+
+// getListeningSessions gets a page of the user's listening sessions
+func (h *trackHandler) getListeningSessions(c *gin.Context) {
+	var req listeningSessionsQuery
+	if err := bindQuery(c, &req); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	var from, to time.Time
+	if req.From != "" {
+		from, _ = parseTimestamp(req.From)
+	}
+	if req.To != "" {
+		to, _ = parseTimestamp(req.To)
+		// A bare date includes the whole day
+		if len(req.To) == len(time.DateOnly) {
+			to = to.AddDate(0, 0, 1)
 		}
 	}
 
-	// Get tracks from database
-	tracks, err := h.spotifyService.GetTrackHistory(c.Request.Context(), userID, limit)
+	page, err := h.profileService.GetListeningSessions(c.Request.Context(), c.GetString("user_id"), from, to, req.Cursor, req.Limit)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("Failed to get track history")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get track history"})
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to get listening sessions")
+		abortWithError(c, apperr.From(err, "sessions_fetch_failed", "Failed to get listening sessions"))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"tracks": tracks})
+	c.JSON(http.StatusOK, listeningSessionsResponse{Sessions: page.Sessions, NextCursor: page.NextCursor})
 }
 
-// refreshCurrentTrack manually refreshes the user's currently playing track
+// refreshCurrentTrack manually refreshes the user's currently playing track.
+// A manual entry or other held state is returned as is rather than replaced
+// by the provider's.
 func (h *trackHandler) refreshCurrentTrack(c *gin.Context) {
 	userID := c.GetString("user_id")
 
 	// Get user to check if sharing is enabled
 	user, err := h.userService.GetUserByID(c.Request.Context(), userID)
 	if err != nil {
-		h.logger.Error().Err(err).Str("userID", userID).Msg("Failed to get user")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Str("userID", userID).Msg("Failed to get user")
+		abortWithError(c, apperr.From(err, "user_fetch_failed", "Failed to get user"))
 		return
 	}
 
 	if !user.IsSharingEnabled {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Music sharing is disabled"})
+		abortWithError(c, apperr.Forbidden("sharing_disabled", "Music sharing is disabled"))
+		return
+	}
+
+	if held := h.profileService.HeldNowPlaying(c.Request.Context(), user.ID); held != nil {
+		c.JSON(http.StatusOK, held)
+		return
+	}
+
+	provider, err := h.providers.ForUser(user)
+	if err != nil {
+		abortWithError(c, err)
 		return
 	}
 
 	// Check if token is expired and refresh if needed
 	if h.userService.IsTokenExpired(user) {
-		tokenResp, err := h.spotifyService.RefreshAccessToken(c.Request.Context(), user.SpotifyRefreshToken)
+		tokenResp, err := provider.RefreshUserToken(c.Request.Context(), user)
 		if err != nil {
-			h.logger.Error().Err(err).Msg("Failed to refresh access token")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh Spotify access"})
+			h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to refresh access token")
+			abortWithError(c, apperr.From(err, "spotify_refresh_failed", "Failed to refresh Spotify access"))
 			return
 		}
 
 		// Update user's token
 		err = h.userService.UpdateUserToken(c.Request.Context(), user.ID, tokenResp.AccessToken, tokenResp.ExpiresIn)
 		if err != nil {
-			h.logger.Error().Err(err).Msg("Failed to update user token")
+			h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to update user token")
 		}
 
 		// Update in-memory token for immediate use
 		user.SpotifyAccessToken = tokenResp.AccessToken
 	}
 
-	// Get from Spotify API
-	track, err := h.spotifyService.GetCurrentlyPlayingTrack(c.Request.Context(), user.SpotifyAccessToken)
+	// Get from the user's provider
+	track, err := provider.GetCurrentlyPlayingTrack(h.spotifyService.WithUserTenant(c.Request.Context(), user), user.SpotifyAccessToken)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("Failed to get currently playing track")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get track from Spotify"})
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to get currently playing track")
+		abortWithError(c, apperr.From(err, "spotify_track_failed", "Failed to get track from Spotify"))
 		return
 	}
 
@@ -264,14 +680,105 @@ func (h *trackHandler) refreshCurrentTrack(c *gin.Context) {
 	if track.IsPlaying {
 		err = h.spotifyService.CacheCurrentlyPlaying(c.Request.Context(), user.ID, track)
 		if err != nil {
-			h.logger.Warn().Err(err).Msg("Failed to cache currently playing track")
+			h.logger.Warn().Ctx(c.Request.Context()).Err(err).Msg("Failed to cache currently playing track")
 		}
 
 		err = h.spotifyService.NotifyTrackChange(c.Request.Context(), user.ID, track)
 		if err != nil {
-			h.logger.Warn().Err(err).Msg("Failed to notify track change")
+			h.logger.Warn().Ctx(c.Request.Context()).Err(err).Msg("Failed to notify track change")
 		}
 	}
 
 	c.JSON(http.StatusOK, track)
 }
+
+// setManualNowPlaying shows a hand-entered track as playing
+func (h *trackHandler) setManualNowPlaying(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req manualNowPlayingRequest
+	if err := bindJSON(c, &req); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	user, err := h.userService.GetUserByID(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Str("userID", userID).Msg("Failed to get user")
+		abortWithError(c, apperr.From(err, "user_fetch_failed", "Failed to get user"))
+		return
+	}
+
+	if !user.IsSharingEnabled {
+		abortWithError(c, apperr.Forbidden("sharing_disabled", "Music sharing is disabled"))
+		return
+	}
+
+	track, err := h.profileService.SetManualNowPlaying(c.Request.Context(), user.ID, services.ManualNowPlaying{
+		Title:      req.Title,
+		Artist:     req.Artist,
+		Album:      req.Album,
+		ArtworkURL: req.ArtworkURL,
+		SpotifyURL: req.SpotifyURL,
+		Duration:   time.Duration(req.DurationSeconds) * time.Second,
+	})
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to set manual now playing")
+		abortWithError(c, apperr.From(err, "now_playing_update_failed", "Failed to update now playing"))
+		return
+	}
+
+	c.JSON(http.StatusOK, track)
+}
+
+// reportNowPlaying publishes playback a client reported
+func (h *trackHandler) reportNowPlaying(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req reportNowPlayingRequest
+	if err := bindJSON(c, &req); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	user, err := h.userService.GetUserByID(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Str("userID", userID).Msg("Failed to get user")
+		abortWithError(c, apperr.From(err, "user_fetch_failed", "Failed to get user"))
+		return
+	}
+
+	if !user.IsSharingEnabled {
+		abortWithError(c, apperr.Forbidden("sharing_disabled", "Music sharing is disabled"))
+		return
+	}
+
+	track, err := h.profileService.ReportNowPlaying(c.Request.Context(), user.ID, services.ReportedPlayback{
+		Title:      req.Title,
+		Artist:     req.Artist,
+		Album:      req.Album,
+		ArtworkURL: req.ArtworkURL,
+		TrackURL:   req.TrackURL,
+		Duration:   time.Duration(req.DurationMs) * time.Millisecond,
+		Progress:   time.Duration(req.ProgressMs) * time.Millisecond,
+		IsPlaying:  req.IsPlaying == nil || *req.IsPlaying,
+	})
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to report now playing")
+		abortWithError(c, apperr.From(err, "now_playing_update_failed", "Failed to update now playing"))
+		return
+	}
+
+	c.JSON(http.StatusOK, track)
+}
+
+// clearManualNowPlaying ends a manual entry before its duration has passed
+func (h *trackHandler) clearManualNowPlaying(c *gin.Context) {
+	if err := h.profileService.ClearManualNowPlaying(c.Request.Context(), c.GetString("user_id")); err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to clear manual now playing")
+		abortWithError(c, apperr.From(err, "now_playing_update_failed", "Failed to update now playing"))
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse{Success: true})
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	// validThemes are the profile themes the frontend knows how to render
+	validThemes = []string{"default", "dark", "light", "minimal", "neon", "retro"}
+
+	// validAnimationStyles are the supported track-change animations
+	validAnimationStyles = []string{"fade", "slide", "none"}
+
+	registerValidatorsOnce sync.Once
+)
+
+// fieldError describes why a single request field was rejected
+type fieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// registerValidators configures gin's validator with JSON field names and the
+// app's custom rules
+func registerValidators() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	// Report fields by their JSON names rather than Go struct field names
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	_ = v.RegisterValidation("theme", oneOfValidator(validThemes))
+	_ = v.RegisterValidation("animation_style", oneOfValidator(validAnimationStyles))
+	_ = v.RegisterValidation("timestamp", func(fl validator.FieldLevel) bool {
+		_, err := parseTimestamp(fl.Field().String())
+		return err == nil
+	})
+	_ = v.RegisterValidation("timezone", func(fl validator.FieldLevel) bool {
+		return services.ValidTimezone(fl.Field().String())
+	})
+	_ = v.RegisterValidation("spotify_url", func(fl validator.FieldLevel) bool {
+		u, err := url.Parse(fl.Field().String())
+		return err == nil && u.Scheme == "https" && u.Host == "open.spotify.com"
+	})
+}
+
+// parseTimestamp accepts RFC 3339 timestamps or plain YYYY-MM-DD dates (UTC
+// midnight)
+func parseTimestamp(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+func oneOfValidator(allowed []string) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		value := fl.Field().String()
+		for _, candidate := range allowed {
+			if value == candidate {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// bindJSON decodes and validates the request body into req, returning an
+// invalid-request error with field-level details on failure
+func bindJSON(c *gin.Context, req interface{}) error {
+	registerValidatorsOnce.Do(registerValidators)
+
+	err := c.ShouldBindJSON(req)
+	if err == nil {
+		return nil
+	}
+	return bindingError(err, apperr.Invalid("invalid_body", "Invalid request body"))
+}
+
+// bindQuery decodes and validates query parameters into req. Fields are
+// reported by their JSON tag, so query structs should carry matching form and
+// json tags.
+func bindQuery(c *gin.Context, req interface{}) error {
+	registerValidatorsOnce.Do(registerValidators)
+
+	err := c.ShouldBindQuery(req)
+	if err == nil {
+		return nil
+	}
+	return bindingError(err, apperr.Invalid("invalid_query", "Invalid query parameters"))
+}
+
+// bindingError converts a binding failure into a validation error with
+// field-level details, or decodeErr when the input couldn't be decoded at all
+func bindingError(err error, decodeErr *apperr.Error) error {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return bodyReadError(err, decodeErr)
+	}
+
+	details := make([]fieldError, 0, len(validationErrs))
+	for _, fe := range validationErrs {
+		details = append(details, fieldError{
+			Field:   fe.Field(),
+			Rule:    fe.Tag(),
+			Message: validationMessage(fe),
+		})
+	}
+	return apperr.Invalid("validation_failed", "Request validation failed").WithDetails(details).Wrap(err)
+}
+
+// validationMessage renders a human-readable explanation for a failed rule
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", fe.Field())
+	case "required_without":
+		return fmt.Sprintf("%s is required when %s is not set", fe.Field(), strings.ToLower(fe.Param()))
+	case "required_without_all":
+		return fmt.Sprintf("%s is required when no other field is set", fe.Field())
+	case "hexcolor":
+		return fmt.Sprintf("%s must be a hex color like #1DB954", fe.Field())
+	case "max":
+		switch fe.Kind() {
+		case reflect.Int:
+			return fmt.Sprintf("%s must be at most %s", fe.Field(), fe.Param())
+		case reflect.Slice:
+			return fmt.Sprintf("%s must have at most %s items", fe.Field(), fe.Param())
+		}
+		return fmt.Sprintf("%s must be at most %s characters", fe.Field(), fe.Param())
+	case "min":
+		if fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("%s must have at least %s items", fe.Field(), fe.Param())
+		}
+		return fmt.Sprintf("%s must be at least %s", fe.Field(), fe.Param())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", fe.Field(), strings.ReplaceAll(fe.Param(), " ", ", "))
+	case "http_url":
+		return fmt.Sprintf("%s must be an http or https URL", fe.Field())
+	case "spotify_url":
+		return fmt.Sprintf("%s must be an https://open.spotify.com link", fe.Field())
+	case "timezone":
+		return fmt.Sprintf("%s must be an IANA time zone like Europe/Berlin", fe.Field())
+	case "timestamp":
+		return fmt.Sprintf("%s must be an RFC 3339 timestamp or a YYYY-MM-DD date", fe.Field())
+	case "theme":
+		return fmt.Sprintf("%s must be one of: %s", fe.Field(), strings.Join(validThemes, ", "))
+	case "animation_style":
+		return fmt.Sprintf("%s must be one of: %s", fe.Field(), strings.Join(validAnimationStyles, ", "))
+	default:
+		return fmt.Sprintf("%s failed the %s rule", fe.Field(), fe.Tag())
+	}
+}
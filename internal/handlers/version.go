@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/metrics"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/version"
+	"github.com/gin-gonic/gin"
+)
+
+// buildInfo is always 1; its labels let dashboards join metrics to a deploy
+var buildInfo = metrics.NewGaugeVec(
+	"build_info",
+	"Version and commit of the running build.",
+	"version", "commit",
+)
+
+// RegisterVersionHandlers registers /version, which identifies the running
+// build for bug reports and deploy dashboards
+func RegisterVersionHandlers(r *gin.Engine) {
+	info := version.Get()
+	buildInfo.Set(1, info.Version, info.Commit)
+
+	handler := func(c *gin.Context) {
+		c.JSON(http.StatusOK, info)
+	}
+	r.GET("/version", handler)
+	r.HEAD("/version", handler)
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// currentAPIVersion is the version served under /api/v1
+	currentAPIVersion = "1"
+
+	// legacySunset is when the unversioned /api routes will be removed
+	legacySunset = "Fri, 01 Oct 2027 00:00:00 GMT"
+)
+
+// vendorMediaType matches Accept values like application/vnd.whatamilisteningto.v1+json
+var vendorMediaType = regexp.MustCompile(`application/vnd\.whatamilisteningto\.v(\d+)\+json`)
+
+// registerAPIRoutes mounts a JSON route group under /api/v1 and under the
+// deprecated unversioned /api prefix so existing clients keep working
+func registerAPIRoutes(r *gin.Engine, path string, middleware []gin.HandlerFunc, register func(*gin.RouterGroup)) {
+	v1 := r.Group("/api/v1"+path, apiVersionMiddleware())
+	v1.Use(middleware...)
+	register(v1)
+
+	legacy := r.Group("/api"+path, deprecatedRouteMiddleware())
+	legacy.Use(middleware...)
+	register(legacy)
+}
+
+// apiVersionMiddleware negotiates the API version from the API-Version header
+// or a vendor media type in Accept, rejecting versions this server can't serve
+func apiVersionMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requested := c.GetHeader("API-Version")
+		if requested == "" {
+			if match := vendorMediaType.FindStringSubmatch(c.GetHeader("Accept")); match != nil {
+				requested = match[1]
+			}
+		}
+
+		if requested != "" && requested != currentAPIVersion {
+			abortWithError(c, apperr.New(apperr.KindNotAcceptable, "unsupported_api_version", "Unsupported API version").
+				WithDetails(gin.H{"supported": []string{currentAPIVersion}}))
+			return
+		}
+
+		c.Header("API-Version", currentAPIVersion)
+		c.Next()
+	}
+}
+
+// deprecatedRouteMiddleware marks legacy unversioned routes as deprecated and
+// points clients at their /api/v1 successor
+func deprecatedRouteMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		successor := "/api/v1" + strings.TrimPrefix(c.Request.URL.Path, "/api")
+		c.Header("Deprecation", "true")
+		c.Header("Sunset", legacySunset)
+		c.Header("Link", "<"+successor+`>; rel="successor-version"`)
+		c.Set(legacyErrorsKey, true)
+		c.Next()
+	}
+}
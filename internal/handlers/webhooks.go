@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/openapi"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/ratelimit"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+// RegisterMediaWebhookHandlers registers the inbound Plex/Jellyfin webhook
+// and the routes owners use to manage their webhook URL
+func RegisterMediaWebhookHandlers(r *gin.Engine, mediaWebhooks *services.MediaWebhookService, profileService *services.ProfileService, userService *services.UserService, limiter *ratelimit.Limiter, logger zerolog.Logger) {
+	handler := &mediaWebhookHandler{
+		mediaWebhooks:  mediaWebhooks,
+		profileService: profileService,
+		userService:    userService,
+		logger:         logger.With().Str("handler", "media_webhook").Logger(),
+	}
+
+	webhooks := r.Group("/webhooks", rateLimit(limiter, "public"))
+	handle(webhooks, http.MethodPost, "/media/:token", openapi.Operation{
+		Summary:     "Report playback from Plex or Jellyfin",
+		Description: "Accepts Plex webhooks (multipart with a payload field) and Jellyfin webhook plugin JSON. Music play, resume, pause, and stop events update now playing, history, and realtime viewers; other events are ignored.",
+		Tag:         "webhooks",
+		Params: []openapi.Param{
+			{Name: "token", In: "path", Description: "Secret token from POST /api/v1/profile/media-webhook"},
+		},
+		Responses: map[int]interface{}{
+			http.StatusNoContent:  nil,
+			http.StatusBadRequest: errorResponse{},
+			http.StatusNotFound:   errorResponse{},
+		},
+	}, handler.receive)
+
+	registerAPIRoutes(r, "/profile/media-webhook", []gin.HandlerFunc{authMiddleware(userService), sessionOnly("Managing the Plex/Jellyfin webhook"), rateLimit(limiter, "api")}, func(group *gin.RouterGroup) {
+		handle(group, http.MethodPost, "", openapi.Operation{
+			Summary:     "Create a Plex/Jellyfin webhook URL",
+			Description: "Returns a new secret webhook URL, replacing any previous one. The URL is only shown once.",
+			Tag:         "webhooks",
+			Auth:        true,
+			SessionOnly: true,
+			Responses: map[int]interface{}{
+				http.StatusOK:                  mediaWebhookResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusForbidden:           errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.create)
+		handle(group, http.MethodDelete, "", openapi.Operation{
+			Summary:     "Disable the Plex/Jellyfin webhook URL",
+			Tag:         "webhooks",
+			Auth:        true,
+			SessionOnly: true,
+			Responses: map[int]interface{}{
+				http.StatusOK:                  successResponse{},
+				http.StatusUnauthorized:        errorResponse{},
+				http.StatusForbidden:           errorResponse{},
+				http.StatusInternalServerError: errorResponse{},
+			},
+		}, handler.delete)
+	})
+}
+
+type mediaWebhookHandler struct {
+	mediaWebhooks  *services.MediaWebhookService
+	profileService *services.ProfileService
+	userService    *services.UserService
+	logger         zerolog.Logger
+}
+
+// receive applies a media server playback event to the webhook's owner
+func (h *mediaWebhookHandler) receive(c *gin.Context) {
+	userID, err := h.mediaWebhooks.UserIDForToken(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	// Plex posts multipart form data with the JSON in a payload field;
+	// the Jellyfin plugin posts JSON
+	var event *services.MediaEvent
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		event, err = services.ParsePlexPayload([]byte(c.PostForm("payload")))
+	} else {
+		var body []byte
+		body, err = io.ReadAll(c.Request.Body)
+		if err != nil {
+			abortWithError(c, apperr.Invalid("invalid_body", "Invalid request body"))
+			return
+		}
+		event, err = services.ParseJellyfinPayload(body)
+	}
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	if event == nil {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	user, err := h.userService.GetUserByID(c.Request.Context(), userID)
+	if err != nil {
+		abortWithError(c, apperr.From(err, "user_fetch_failed", "Failed to get user"))
+		return
+	}
+	if !user.IsSharingEnabled {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	if err := h.profileService.PublishNowPlaying(c.Request.Context(), user.ID, &event.Track, event.TTL); err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to apply media server event")
+		abortWithError(c, apperr.From(err, "now_playing_update_failed", "Failed to update now playing"))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// create issues a new webhook URL for the caller
+func (h *mediaWebhookHandler) create(c *gin.Context) {
+	token, err := h.mediaWebhooks.CreateWebhook(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to create media webhook")
+		abortWithError(c, apperr.From(err, "webhook_create_failed", "Failed to create webhook"))
+		return
+	}
+
+	c.JSON(http.StatusOK, mediaWebhookResponse{URL: requestBaseURL(c) + "/webhooks/media/" + token})
+}
+
+// delete disables the caller's webhook URL
+func (h *mediaWebhookHandler) delete(c *gin.Context) {
+	if err := h.mediaWebhooks.DeleteWebhook(c.Request.Context(), c.GetString("user_id")); err != nil {
+		h.logger.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to delete media webhook")
+		abortWithError(c, apperr.From(err, "webhook_delete_failed", "Failed to delete webhook"))
+		return
+	}
+
+	c.JSON(http.StatusOK, successResponse{Success: true})
+}
+
+// requestBaseURL returns the scheme and host the request was made to,
+// honoring X-Forwarded-Proto from a TLS-terminating proxy
+func requestBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
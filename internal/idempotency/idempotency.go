@@ -0,0 +1,107 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/database"
+	"github.com/go-redis/redis/v8"
+)
+
+// lockTTL bounds how long a request may hold a key before a retry can take over
+const lockTTL = time.Minute
+
+var (
+	// ErrInProgress means another request with the same key hasn't finished
+	ErrInProgress = errors.New("idempotency: request in progress")
+	// ErrMismatch means the key was already used for a different request
+	ErrMismatch = errors.New("idempotency: key reused with a different request")
+)
+
+// Response is a stored response replayed to retries
+type Response struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// record is the Redis value for a key; Response is nil while the original
+// request is still running
+type record struct {
+	RequestHash string    `json:"request_hash"`
+	Response    *Response `json:"response,omitempty"`
+}
+
+// Store tracks idempotency keys and their responses in Redis
+type Store struct {
+	redis *database.RedisClient
+	ttl   time.Duration
+}
+
+// New creates a store from configuration
+func New(cfg config.IdempotencyConfig, redis *database.RedisClient) *Store {
+	return &Store{redis: redis, ttl: time.Duration(cfg.TTLHours) * time.Hour}
+}
+
+// Available reports whether keys can be tracked; callers should process
+// requests normally when it is false
+func (s *Store) Available() bool {
+	return s != nil && s.redis.Available()
+}
+
+// Begin claims key for a request with the given hash. It returns the stored
+// response when the key has already completed for the same request,
+// ErrInProgress or ErrMismatch when it can't proceed, or nil to run the request.
+func (s *Store) Begin(ctx context.Context, key, requestHash string) (*Response, error) {
+	pending, err := json.Marshal(record{RequestHash: requestHash})
+	if err != nil {
+		return nil, err
+	}
+
+	claimed, err := s.redis.SetIfAbsent(ctx, redisKey(key), pending, lockTTL)
+	if err != nil || claimed {
+		return nil, err
+	}
+
+	raw, err := s.redis.Get(ctx, redisKey(key))
+	if errors.Is(err, redis.Nil) {
+		// Released between our claim and read; let the client retry
+		return nil, ErrInProgress
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var existing record
+	if err := json.Unmarshal([]byte(raw), &existing); err != nil {
+		return nil, err
+	}
+	if existing.RequestHash != requestHash {
+		return nil, ErrMismatch
+	}
+	if existing.Response == nil {
+		return nil, ErrInProgress
+	}
+	return existing.Response, nil
+}
+
+// Complete stores the response for key so retries replay it
+func (s *Store) Complete(ctx context.Context, key, requestHash string, resp Response) error {
+	done, err := json.Marshal(record{RequestHash: requestHash, Response: &resp})
+	if err != nil {
+		return err
+	}
+	return s.redis.Set(ctx, redisKey(key), done, s.ttl)
+}
+
+// Release drops a claim without storing a response, so the request can be retried
+func (s *Store) Release(ctx context.Context, key string) error {
+	return s.redis.Delete(ctx, redisKey(key))
+}
+
+func redisKey(key string) string {
+	return "idempotency:" + key
+}
@@ -0,0 +1,394 @@
+//go:build integration
+
+// Package integration runs the server against throwaway Postgres and Redis
+// containers with the fake Spotify. It needs Docker and only builds with
+// the integration tag:
+//
+//	go test -tags=integration ./internal/integration/
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/database"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/docker/go-connections/nat"
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog"
+	"github.com/testcontainers/testcontainers-go"
+	tcpostgres "github.com/testcontainers/testcontainers-go/modules/postgres"
+	tcredis "github.com/testcontainers/testcontainers-go/modules/redis"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// fakeTrackSeconds is how long each fake Spotify track plays, short enough
+// that the poller sees a few changes during the test
+const fakeTrackSeconds = 2
+
+// userAgent looks like a browser, since bot visits don't keep a profile
+// watched by the poller
+const userAgent = "Mozilla/5.0 (X11; Linux x86_64) integration-test"
+
+// TestSignInPollHistoryWebSocket signs in through the fake Spotify, waits for
+// the poller to record plays, reads them back from the history API, and
+// follows a track change over the profile's WebSocket
+func TestSignInPollHistoryWebSocket(t *testing.T) {
+	requireDocker(t)
+	ctx := context.Background()
+	dbCfg := startPostgres(ctx, t)
+	redisHost, redisPort := startRedis(ctx, t)
+	runMigrations(t, dbCfg)
+	base := startServer(t, dbCfg, redisHost, redisPort)
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Jar: jar, Timeout: 10 * time.Second, Transport: userAgentTransport{}}
+
+	// Sign in: the fake sends the browser straight back to the callback,
+	// which redirects to the new profile page
+	resp, err := client.Get(base + "/auth/spotify")
+	if err != nil {
+		t.Fatalf("signing in: %v", err)
+	}
+	resp.Body.Close()
+	profileURL, ok := strings.CutPrefix(resp.Request.URL.Path, "/profile/")
+	if resp.StatusCode != http.StatusOK || !ok || profileURL == "" {
+		t.Fatalf("sign-in ended at %s with status %d, want the profile page", resp.Request.URL, resp.StatusCode)
+	}
+
+	// Poll: the profile page visit makes the poller watch the profile, and
+	// every track change it sees lands in history
+	var current models.SpotifyCurrentlyPlaying
+	eventually(t, 30*time.Second, func() error {
+		if err := getJSON(client, base+"/api/v1/tracks/current", &current); err != nil {
+			return err
+		}
+		if !current.IsPlaying || current.TrackID == "" {
+			return fmt.Errorf("nothing playing yet")
+		}
+		return nil
+	})
+
+	var history struct {
+		Tracks []models.Track `json:"tracks"`
+	}
+	eventually(t, 30*time.Second, func() error {
+		if err := getJSON(client, base+"/api/v1/tracks/history", &history); err != nil {
+			return err
+		}
+		if len(history.Tracks) < 2 {
+			return fmt.Errorf("history has %d tracks, want at least 2", len(history.Tracks))
+		}
+		return nil
+	})
+	for _, track := range history.Tracks {
+		if !strings.HasPrefix(track.SpotifyTrackID, "fake") || track.Name == "" {
+			t.Errorf("history track %+v doesn't come from the fake catalog", track)
+		}
+	}
+
+	// WebSocket: the visit_id cookie from the profile page authenticates
+	// the viewer, and the next track change is pushed to it
+	wsURL := "ws" + strings.TrimPrefix(base, "http") + "/ws/tracks/" + url.PathEscape(profileURL)
+	dialer := websocket.Dialer{Jar: jar, HandshakeTimeout: 10 * time.Second}
+	conn, resp, err := dialer.Dial(wsURL, http.Header{"User-Agent": {userAgent}})
+	if err != nil {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		t.Fatalf("connecting to %s: %v (status %d)", wsURL, err, status)
+	}
+	defer conn.Close()
+
+	if err := conn.SetReadDeadline(time.Now().Add(30 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	first := ""
+	for {
+		var update models.SpotifyCurrentlyPlaying
+		if err := conn.ReadJSON(&update); err != nil {
+			t.Fatalf("waiting for a track change over the WebSocket: %v", err)
+		}
+		if update.TrackID == "" {
+			continue
+		}
+		if first == "" {
+			first = update.TrackID
+			continue
+		}
+		if update.TrackID != first {
+			break
+		}
+	}
+}
+
+// requireDocker skips the test when there's no Docker to run containers on,
+// which testcontainers reports by panicking
+func requireDocker(t *testing.T) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != nil {
+			t.Skipf("Docker is not available: %v", r)
+		}
+	}()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+}
+
+// startPostgres runs a Postgres container for the test and returns its
+// connection settings
+func startPostgres(ctx context.Context, t *testing.T) config.DatabaseConfig {
+	t.Helper()
+	container, err := tcpostgres.Run(ctx, "postgres:16-alpine",
+		tcpostgres.WithDatabase("music_sharing"),
+		tcpostgres.WithUsername("postgres"),
+		tcpostgres.WithPassword("postgres"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(time.Minute),
+		),
+	)
+	if err != nil {
+		t.Fatalf("starting Postgres: %v", err)
+	}
+	t.Cleanup(func() { _ = container.Terminate(context.Background()) })
+
+	host, port := endpoint(ctx, t, container, "5432/tcp")
+	return config.DatabaseConfig{
+		Host:                    host,
+		Port:                    port,
+		User:                    "postgres",
+		Password:                "postgres",
+		DBName:                  "music_sharing",
+		SSLMode:                 "disable",
+		QueryTimeoutSeconds:     30,
+		StatementTimeoutSeconds: 30,
+	}
+}
+
+// startRedis runs a Redis container for the test and returns its address
+func startRedis(ctx context.Context, t *testing.T) (string, int) {
+	t.Helper()
+	container, err := tcredis.Run(ctx, "redis:7-alpine")
+	if err != nil {
+		t.Fatalf("starting Redis: %v", err)
+	}
+	t.Cleanup(func() { _ = container.Terminate(context.Background()) })
+	return endpoint(ctx, t, container, "6379/tcp")
+}
+
+// endpoint returns the host and mapped port of a container's port
+func endpoint(ctx context.Context, t *testing.T, container testcontainers.Container, port nat.Port) (string, int) {
+	t.Helper()
+	host, err := container.Host(ctx)
+	if err != nil {
+		t.Fatalf("getting container host: %v", err)
+	}
+	mapped, err := container.MappedPort(ctx, port)
+	if err != nil {
+		t.Fatalf("getting mapped port %s: %v", port, err)
+	}
+	return host, mapped.Int()
+}
+
+// runMigrations applies the schema the same way the migrate command does
+func runMigrations(t *testing.T, cfg config.DatabaseConfig) {
+	t.Helper()
+	db, err := database.NewPostgresConnection(cfg, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("connecting to Postgres: %v", err)
+	}
+	defer db.Close()
+	if err := database.RunMigrations(db); err != nil {
+		t.Fatalf("running migrations: %v", err)
+	}
+}
+
+// startServer builds cmd/server and serves it with the fake Spotify until the
+// test ends, returning its base URL. The server's output is logged if the
+// test fails.
+func startServer(t *testing.T, dbCfg config.DatabaseConfig, redisHost string, redisPort int) string {
+	t.Helper()
+	root, err := filepath.Abs(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The server runs from a scratch directory so no .env is picked up, with
+	// stand-in templates for the HTML pages it renders
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "server")
+	build := exec.Command("go", "build", "-o", binary, "./cmd/server")
+	build.Dir = root
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("building server: %v\n%s", err, out)
+	}
+	templates := filepath.Join(dir, "web", "templates")
+	if err := os.MkdirAll(templates, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"profile.html", "profile_unavailable.html"} {
+		if err := os.WriteFile(filepath.Join(templates, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	port, adminPort := freePort(t), freePort(t)
+	base := fmt.Sprintf("http://127.0.0.1:%d", port)
+
+	cmd := exec.Command(binary, "serve", "--skip-migrations")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"APP_ENV=development",
+		"SERVER_PORT="+strconv.Itoa(port),
+		"ADMIN_PORT="+strconv.Itoa(adminPort),
+		"GRPC_ENABLED=false",
+		"DB_HOST="+dbCfg.Host,
+		"DB_PORT="+strconv.Itoa(dbCfg.Port),
+		"DB_USER="+dbCfg.User,
+		"DB_PASSWORD="+dbCfg.Password,
+		"DB_NAME="+dbCfg.DBName,
+		"DB_SSLMODE="+dbCfg.SSLMode,
+		"REDIS_HOST="+redisHost,
+		"REDIS_PORT="+strconv.Itoa(redisPort),
+		"DEV_FAKE_SPOTIFY=true",
+		"DEV_FAKE_SPOTIFY_TRACK_SECONDS="+strconv.Itoa(fakeTrackSeconds),
+		"SPOTIFY_REDIRECT_URI="+base+"/auth/spotify/callback",
+		"POLLER_INTERVAL_SECONDS=1",
+	)
+	output := &syncBuffer{}
+	cmd.Stdout = output
+	cmd.Stderr = output
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("starting server: %v", err)
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+	}()
+	t.Cleanup(func() {
+		_ = cmd.Process.Signal(os.Interrupt)
+		select {
+		case <-exited:
+		case <-time.After(15 * time.Second):
+			_ = cmd.Process.Kill()
+			<-exited
+		}
+		if t.Failed() {
+			t.Logf("server output:\n%s", output.String())
+		}
+	})
+
+	eventually(t, time.Minute, func() error {
+		select {
+		case <-exited:
+			t.Fatalf("server exited during startup:\n%s", output.String())
+		default:
+		}
+		resp, err := http.Get(base + "/healthz")
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("healthz returned %d", resp.StatusCode)
+		}
+		return nil
+	})
+	return base
+}
+
+// freePort returns a local TCP port that was free a moment ago
+func freePort(t *testing.T) int {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	return lis.Addr().(*net.TCPAddr).Port
+}
+
+// eventually retries check until it passes or timeout elapses
+func eventually(t *testing.T, timeout time.Duration, check func() error) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		err := check()
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("gave up after %s: %v", timeout, err)
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+// getJSON fetches an API route and decodes its 200 response into dest
+func getJSON(client *http.Client, route string, dest interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, route, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var body bytes.Buffer
+		_, _ = body.ReadFrom(resp.Body)
+		return fmt.Errorf("GET %s returned %d: %s", route, resp.StatusCode, body.String())
+	}
+	return json.NewDecoder(resp.Body).Decode(dest)
+}
+
+// userAgentTransport sends every request with a browser user agent
+type userAgentTransport struct{}
+
+func (userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", userAgent)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// syncBuffer collects the server's output while it's still being written
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
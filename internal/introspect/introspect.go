@@ -0,0 +1,109 @@
+package introspect
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Worker states
+const (
+	WorkerRunning  = "running"
+	WorkerStopped  = "stopped"
+	WorkerFailed   = "failed"
+	WorkerPanicked = "panicked"
+)
+
+// Section reports one component's current state for the stats snapshot
+type Section func() interface{}
+
+// WorkerStatus is the last known state of a background worker
+type WorkerStatus struct {
+	State string    `json:"state"`
+	Since time.Time `json:"since"`
+}
+
+// HeapStats is the subset of runtime.MemStats useful for triage
+type HeapStats struct {
+	AllocBytes    uint64  `json:"alloc_bytes"`
+	InuseBytes    uint64  `json:"inuse_bytes"`
+	SysBytes      uint64  `json:"sys_bytes"`
+	Objects       uint64  `json:"objects"`
+	NumGC         uint32  `json:"num_gc"`
+	LastGC        int64   `json:"last_gc_unix"`
+	PauseTotalNs  uint64  `json:"pause_total_ns"`
+	NextGC        uint64  `json:"next_gc_bytes"`
+	GCCPUFraction float64 `json:"gc_cpu_fraction"`
+}
+
+// Stats is a point-in-time snapshot of the process
+type Stats struct {
+	Goroutines int                     `json:"goroutines"`
+	Heap       HeapStats               `json:"heap"`
+	Workers    map[string]WorkerStatus `json:"workers"`
+	Components map[string]interface{}  `json:"components"`
+}
+
+var registry = struct {
+	sync.Mutex
+	sections map[string]Section
+	workers  map[string]WorkerStatus
+}{
+	sections: map[string]Section{},
+	workers:  map[string]WorkerStatus{},
+}
+
+// Register adds a named section to every snapshot, replacing any previous
+// section with the same name
+func Register(name string, section Section) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.sections[name] = section
+}
+
+// SetWorkerState records a background worker's state
+func SetWorkerState(name, state string) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.workers[name] = WorkerStatus{State: state, Since: time.Now()}
+}
+
+// Snapshot collects runtime, worker, and component stats. Sections run
+// without the registry lock held so they may be slow or call back in.
+func Snapshot() Stats {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	registry.Lock()
+	workers := make(map[string]WorkerStatus, len(registry.workers))
+	for name, status := range registry.workers {
+		workers[name] = status
+	}
+	sections := make(map[string]Section, len(registry.sections))
+	for name, section := range registry.sections {
+		sections[name] = section
+	}
+	registry.Unlock()
+
+	components := make(map[string]interface{}, len(sections))
+	for name, section := range sections {
+		components[name] = section()
+	}
+
+	return Stats{
+		Goroutines: runtime.NumGoroutine(),
+		Heap: HeapStats{
+			AllocBytes:    mem.HeapAlloc,
+			InuseBytes:    mem.HeapInuse,
+			SysBytes:      mem.HeapSys,
+			Objects:       mem.HeapObjects,
+			NumGC:         mem.NumGC,
+			LastGC:        int64(mem.LastGC / uint64(time.Second)),
+			PauseTotalNs:  mem.PauseTotalNs,
+			NextGC:        mem.NextGC,
+			GCCPUFraction: mem.GCCPUFraction,
+		},
+		Workers:    workers,
+		Components: components,
+	}
+}
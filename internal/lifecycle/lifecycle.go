@@ -0,0 +1,154 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/errreport"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/introspect"
+	"github.com/rs/zerolog"
+)
+
+// flushTimeout bounds how long a crashing component waits for its report to send
+const flushTimeout = 2 * time.Second
+
+// Component is a subsystem whose lifetime is managed by a Group
+type Component struct {
+	Name string
+	// Run does the component's work and blocks until ctx is cancelled. A
+	// non-nil error shuts the whole group down.
+	Run func(ctx context.Context) error
+	// Stop, if set, is called before Run's context is cancelled so the
+	// component can drain gracefully, e.g. http.Server.Shutdown
+	Stop func(ctx context.Context) error
+	// StopTimeout bounds Stop plus the wait for Run to return. Zero uses the
+	// group default.
+	StopTimeout time.Duration
+}
+
+// Group starts components together and stops them in reverse order
+type Group struct {
+	components  []Component
+	stopTimeout time.Duration
+	reporter    errreport.Reporter
+	logger      zerolog.Logger
+}
+
+// New creates a Group. stopTimeout applies to components without their own.
+func New(stopTimeout time.Duration, reporter errreport.Reporter, logger zerolog.Logger) *Group {
+	return &Group{
+		stopTimeout: stopTimeout,
+		reporter:    reporter,
+		logger:      logger.With().Str("component", "lifecycle").Logger(),
+	}
+}
+
+// Add registers a component. Components start in the order they are added
+// and stop in reverse, so add dependencies before the things that use them.
+func (g *Group) Add(c Component) {
+	g.components = append(g.components, c)
+}
+
+// running tracks one started component
+type running struct {
+	Component
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Run starts every component, then blocks until ctx is cancelled or a
+// component fails. It then stops the components and returns the first
+// failure, if any.
+func (g *Group) Run(ctx context.Context) error {
+	failed := make(chan error, len(g.components))
+
+	started := make([]*running, 0, len(g.components))
+	for _, c := range g.components {
+		runCtx, cancel := context.WithCancel(context.Background())
+		r := &running{Component: c, cancel: cancel, done: make(chan struct{})}
+		started = append(started, r)
+		go g.run(runCtx, r, failed)
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+		g.logger.Info().Msg("Shutting down")
+	case err = <-failed:
+		g.logger.Error().Err(err).Msg("Component failed, shutting down")
+	}
+
+	for i := len(started) - 1; i >= 0; i-- {
+		g.stop(started[i])
+	}
+	return err
+}
+
+// run executes one component, recording its state and reporting panics
+func (g *Group) run(ctx context.Context, r *running, failed chan<- error) {
+	introspect.SetWorkerState(r.Name, introspect.WorkerRunning)
+	defer close(r.done)
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			introspect.SetWorkerState(r.Name, introspect.WorkerPanicked)
+			g.reporter.CapturePanic(context.Background(), recovered, map[string]string{"worker": r.Name})
+			g.reporter.Flush(flushTimeout)
+			panic(recovered)
+		}
+	}()
+
+	if err := r.Run(ctx); err != nil && ctx.Err() == nil {
+		introspect.SetWorkerState(r.Name, introspect.WorkerFailed)
+		failed <- fmt.Errorf("%s: %w", r.Name, err)
+		return
+	}
+	introspect.SetWorkerState(r.Name, introspect.WorkerStopped)
+}
+
+// stop drains a component, cancels it, and waits for Run to return within
+// its timeout
+func (g *Group) stop(r *running) {
+	timeout := r.StopTimeout
+	if timeout <= 0 {
+		timeout = g.stopTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if r.Stop != nil {
+		if err := r.Stop(ctx); err != nil {
+			g.logger.Error().Err(err).Str("name", r.Name).Msg("Component did not stop cleanly")
+		}
+	}
+	r.cancel()
+
+	select {
+	case <-r.done:
+		g.logger.Info().Str("name", r.Name).Msg("Component stopped")
+	case <-ctx.Done():
+		g.logger.Error().Str("name", r.Name).Dur("timeout", timeout).Msg("Component did not stop in time, abandoning it")
+	}
+}
+
+// HTTPServer manages an http.Server. Shutdown drains in-flight requests, and
+// cancelling the server's base context then ends hijacked connections such as
+// WebSockets, which Shutdown does not track.
+func HTTPServer(name string, server *http.Server, stopTimeout time.Duration) Component {
+	return Component{
+		Name: name,
+		Run: func(ctx context.Context) error {
+			server.BaseContext = func(net.Listener) context.Context { return ctx }
+			if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			<-ctx.Done()
+			return nil
+		},
+		Stop:        server.Shutdown,
+		StopTimeout: stopTimeout,
+	}
+}
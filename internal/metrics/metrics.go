@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// DefaultBuckets are latency buckets in seconds suited to Redis and HTTP calls
+var DefaultBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5}
+
+// Handler serves every metric in the default Prometheus registry, including
+// the Go runtime and process collectors
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// CounterVec is a monotonically increasing value partitioned by labels
+type CounterVec struct {
+	vec *prometheus.CounterVec
+}
+
+// NewCounterVec creates and registers a counter
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, labels)
+	prometheus.MustRegister(c)
+	return &CounterVec{vec: c}
+}
+
+// Inc increments the series identified by labelValues by one
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.vec.WithLabelValues(labelValues...).Inc()
+}
+
+// Add increments the series identified by labelValues by delta
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	c.vec.WithLabelValues(labelValues...).Add(delta)
+}
+
+// Value returns the current value of a series
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	return value(c.vec.WithLabelValues(labelValues...))
+}
+
+// Total returns the sum of every series
+func (c *CounterVec) Total() float64 {
+	var total float64
+	for _, v := range values(c.vec) {
+		total += v
+	}
+	return total
+}
+
+// GaugeVec is a value that can go up and down, partitioned by labels
+type GaugeVec struct {
+	vec *prometheus.GaugeVec
+}
+
+// NewGaugeVec creates and registers a gauge
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: name, Help: help}, labels)
+	prometheus.MustRegister(g)
+	return &GaugeVec{vec: g}
+}
+
+// Set sets the series identified by labelValues
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	g.vec.WithLabelValues(labelValues...).Set(value)
+}
+
+// Add adds delta (which may be negative) to the series identified by labelValues
+func (g *GaugeVec) Add(delta float64, labelValues ...string) {
+	g.vec.WithLabelValues(labelValues...).Add(delta)
+}
+
+// Value returns the current value of a series
+func (g *GaugeVec) Value(labelValues ...string) float64 {
+	return value(g.vec.WithLabelValues(labelValues...))
+}
+
+// HistogramVec samples observations into cumulative buckets, partitioned by labels
+type HistogramVec struct {
+	vec *prometheus.HistogramVec
+}
+
+// NewHistogramVec creates and registers a histogram; nil buckets uses DefaultBuckets
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	h := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: name, Help: help, Buckets: buckets}, labels)
+	prometheus.MustRegister(h)
+	return &HistogramVec{vec: h}
+}
+
+// Observe records a single observation for the series identified by labelValues
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	h.vec.WithLabelValues(labelValues...).Observe(value)
+}
+
+// value reads a single counter or gauge series
+func value(m prometheus.Metric) float64 {
+	var out dto.Metric
+	if err := m.Write(&out); err != nil {
+		return 0
+	}
+	if out.Counter != nil {
+		return out.Counter.GetValue()
+	}
+	return out.Gauge.GetValue()
+}
+
+// values reads every series a counter or gauge vector has collected
+func values(c prometheus.Collector) []float64 {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		c.Collect(ch)
+		close(ch)
+	}()
+
+	var out []float64
+	for m := range ch {
+		out = append(out, value(m))
+	}
+	return out
+}
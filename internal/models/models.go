@@ -1,23 +1,50 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
-// User represents a registered user in the system
+// User represents a registered user in the system. SpotifyID and the token
+// fields hold the account and credentials of whichever Provider the user
+// signed in with.
 type User struct {
-	ID                  string    `json:"id" db:"id"`
-	SpotifyID           string    `json:"spotify_id" db:"spotify_id"`
-	Email               string    `json:"email" db:"email"`
-	DisplayName         string    `json:"display_name" db:"display_name"`
-	ProfileURL          string    `json:"profile_url" db:"profile_url"`
-	SpotifyAccessToken  string    `json:"-" db:"spotify_access_token"`
-	SpotifyRefreshToken string    `json:"-" db:"spotify_refresh_token"`
-	TokenExpiresAt      time.Time `json:"-" db:"token_expires_at"`
-	IsActive            bool      `json:"is_active" db:"is_active"`
-	IsSharingEnabled    bool      `json:"is_sharing_enabled" db:"is_sharing_enabled"`
-	CreatedAt           time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt           time.Time `json:"updated_at" db:"updated_at"`
+	ID                  string     `json:"id" db:"id"`
+	TenantID            *string    `json:"tenant_id,omitempty" db:"tenant_id"`
+	Provider            string     `json:"provider" db:"provider"`
+	SpotifyID           string     `json:"spotify_id" db:"spotify_id"`
+	Email               string     `json:"email" db:"email"`
+	DisplayName         string     `json:"display_name" db:"display_name"`
+	ProfileURL          string     `json:"profile_url" db:"profile_url"`
+	SpotifyAccessToken  string     `json:"-" db:"spotify_access_token"`
+	SpotifyRefreshToken string     `json:"-" db:"spotify_refresh_token"`
+	TokenExpiresAt      time.Time  `json:"-" db:"token_expires_at"`
+	IsActive            bool       `json:"is_active" db:"is_active"`
+	IsSharingEnabled    bool       `json:"is_sharing_enabled" db:"is_sharing_enabled"`
+	Timezone            string     `json:"timezone" db:"timezone"`
+	TrackRecentlyViewed bool       `json:"track_recently_viewed" db:"track_recently_viewed"`
+	DeletedAt           *time.Time `json:"-" db:"deleted_at"`
+	CreatedAt           time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Tenant is a white-label brand with its own domain and Spotify application.
+// Users without a tenant belong to the deployment's default Spotify app.
+type Tenant struct {
+	ID                     string    `json:"id" db:"id"`
+	Slug                   string    `json:"slug" db:"slug"`
+	Name                   string    `json:"name" db:"name"`
+	Domain                 string    `json:"domain" db:"domain"`
+	SpotifyClientID        string    `json:"spotify_client_id" db:"spotify_client_id"`
+	SpotifyClientSecret    string    `json:"-" db:"spotify_client_secret"`
+	SpotifyRedirectURI     string    `json:"spotify_redirect_uri" db:"spotify_redirect_uri"`
+	DefaultTheme           string    `json:"default_theme" db:"default_theme"`
+	DefaultBackgroundColor string    `json:"default_background_color" db:"default_background_color"`
+	DefaultTextColor       string    `json:"default_text_color" db:"default_text_color"`
+	DefaultAnimationStyle  string    `json:"default_animation_style" db:"default_animation_style"`
+	IsActive               bool      `json:"is_active" db:"is_active"`
+	CreatedAt              time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt              time.Time `json:"updated_at" db:"updated_at"`
 }
 
 // Profile represents user profile customization
@@ -30,12 +57,15 @@ type Profile struct {
 	CustomMessage   string    `json:"custom_message" db:"custom_message"`
 	ShowStats       bool      `json:"show_stats" db:"show_stats"`
 	ShowHistory     bool      `json:"show_history" db:"show_history"`
+	ShowLyrics      bool      `json:"show_lyrics" db:"show_lyrics"`
 	AnimationStyle  string    `json:"animation_style" db:"animation_style"`
 	CreatedAt       time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
 }
 
-// Track represents a song that a user has played or is playing
+// Track represents a song that a user has played or is playing. CreatedAt is
+// when the play started and PlayedAt when it ended, or while it's still
+// playing, when it was last seen.
 type Track struct {
 	ID                 string    `json:"id" db:"id"`
 	UserID             string    `json:"user_id" db:"user_id"`
@@ -49,6 +79,19 @@ type Track struct {
 	IsCurrentlyPlaying bool      `json:"is_currently_playing" db:"is_currently_playing"`
 	PlayedAt           time.Time `json:"played_at" db:"played_at"`
 	CreatedAt          time.Time `json:"created_at" db:"created_at"`
+
+	// Links is the track on other platforms, when known; only set on tracks
+	// shown to profile visitors
+	Links *TrackLinks `json:"links,omitempty" db:"-"`
+}
+
+// TrackLinks are a track's links on other platforms, resolved through
+// Odesli. Empty links are platforms that don't have the track.
+type TrackLinks struct {
+	PageURL    string `json:"page_url,omitempty" db:"page_url"`
+	AppleMusic string `json:"apple_music,omitempty" db:"apple_music_url"`
+	YouTube    string `json:"youtube,omitempty" db:"youtube_url"`
+	Deezer     string `json:"deezer,omitempty" db:"deezer_url"`
 }
 
 // ProfileVisit tracks profile visits by anonymous users
@@ -57,23 +100,396 @@ type ProfileVisit struct {
 	UserID        string     `json:"user_id" db:"user_id"`
 	VisitorIP     string     `json:"-" db:"visitor_ip"`
 	VisitorUserID *string    `json:"visitor_user_id,omitempty" db:"visitor_user_id"`
+	VisitorHash   string     `json:"-" db:"visitor_hash"`
+	Country       string     `json:"country,omitempty" db:"country"`
+	Region        string     `json:"region,omitempty" db:"region"`
+	IsBot         bool       `json:"is_bot" db:"is_bot"`
 	UserAgent     string     `json:"-" db:"user_agent"`
 	ReferrerURL   string     `json:"referrer_url" db:"referrer_url"`
 	StartedAt     time.Time  `json:"started_at" db:"started_at"`
 	EndedAt       *time.Time `json:"ended_at,omitempty" db:"ended_at"`
 }
 
+// VisitAnalytics summarizes the visits to a profile over a range. Visitors
+// are told apart by account when signed in, by their visitor cookie
+// otherwise, and by IP address for visits recorded before the cookie.
+// Average duration only counts visits that have ended.
+type VisitAnalytics struct {
+	Visits                 int64            `json:"visits" db:"visits"`
+	UniqueVisitors         int64            `json:"unique_visitors" db:"unique_visitors"`
+	AverageDurationSeconds int64            `json:"average_duration_seconds" db:"average_duration_seconds"`
+	Periods                []VisitPeriod    `json:"periods" db:"-"`
+	TopReferrers           []ReferrerVisits `json:"top_referrers" db:"-"`
+	Countries              []CountryVisits  `json:"countries" db:"-"`
+}
+
+// CountryVisits counts the visits and unique visitors from one country, by
+// ISO 3166-1 alpha-2 code or "unknown"
+type CountryVisits struct {
+	Country        string `json:"country" db:"country"`
+	Visits         int64  `json:"visits" db:"visits"`
+	UniqueVisitors int64  `json:"unique_visitors" db:"unique_visitors"`
+}
+
+// VisitPeriod is the visits in one day, week, or month. Start is its first
+// day, YYYY-MM-DD in the owner's time zone; weeks start on Monday.
+type VisitPeriod struct {
+	Start          string `json:"start" db:"start"`
+	Visits         int64  `json:"visits" db:"visits"`
+	UniqueVisitors int64  `json:"unique_visitors" db:"unique_visitors"`
+}
+
+// PlayEvent is one raw play of a track, kept whether or not it lasted long
+// enough to count in history. Counted is false for skips; counted plays
+// belong to a listening session.
+type PlayEvent struct {
+	ID             string    `json:"id" db:"id"`
+	UserID         string    `json:"user_id" db:"user_id"`
+	SpotifyTrackID string    `json:"spotify_track_id" db:"spotify_track_id"`
+	Name           string    `json:"name" db:"name"`
+	Artist         string    `json:"artist" db:"artist"`
+	DurationMs     int       `json:"duration_ms" db:"duration_ms"`
+	ListenedMs     int       `json:"listened_ms" db:"listened_ms"`
+	Counted        bool      `json:"counted" db:"counted"`
+	StartedAt      time.Time `json:"started_at" db:"started_at"`
+	EndedAt        time.Time `json:"ended_at" db:"ended_at"`
+	SessionID      *string   `json:"session_id,omitempty" db:"session_id"`
+}
+
+// ScrobbleAccount is a scrobbling service, such as Last.fm, a user has
+// connected. Their listens are submitted with SessionKey.
+type ScrobbleAccount struct {
+	UserID     string    `json:"-" db:"user_id"`
+	Provider   string    `json:"provider" db:"provider"`
+	Username   string    `json:"username" db:"username"`
+	SessionKey string    `json:"-" db:"session_key"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// ScrobbleAccountStatus is a scrobbling account with how its scrobbles are
+// doing. LastError is the most recent error of a scrobble not yet submitted.
+type ScrobbleAccountStatus struct {
+	ScrobbleAccount
+	Pending         int        `json:"pending" db:"pending"`
+	Submitted       int        `json:"submitted" db:"submitted"`
+	Failed          int        `json:"failed" db:"failed"`
+	LastSubmittedAt *time.Time `json:"last_submitted_at,omitempty" db:"last_submitted_at"`
+	LastError       string     `json:"last_error,omitempty" db:"last_error"`
+}
+
+// Scrobble is a listen queued for, or submitted to, one of a user's
+// scrobbling accounts
+type Scrobble struct {
+	ID            int64      `json:"id" db:"id"`
+	UserID        string     `json:"-" db:"user_id"`
+	Provider      string     `json:"provider" db:"provider"`
+	Artist        string     `json:"artist" db:"artist"`
+	Track         string     `json:"track" db:"track"`
+	Album         string     `json:"album" db:"album"`
+	DurationMs    int        `json:"duration_ms" db:"duration_ms"`
+	StartedAt     time.Time  `json:"started_at" db:"started_at"`
+	Status        string     `json:"status" db:"status"`
+	Attempts      int        `json:"attempts" db:"attempts"`
+	LastError     string     `json:"last_error,omitempty" db:"last_error"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	SubmittedAt   *time.Time `json:"submitted_at,omitempty" db:"submitted_at"`
+}
+
+// ListeningSession is a run of counted plays with no long gap between them.
+// DominantArtist is the artist played most in the session.
+type ListeningSession struct {
+	ID             string    `json:"id" db:"id"`
+	UserID         string    `json:"-" db:"user_id"`
+	StartedAt      time.Time `json:"started_at" db:"started_at"`
+	EndedAt        time.Time `json:"ended_at" db:"ended_at"`
+	TrackCount     int       `json:"track_count" db:"track_count"`
+	DominantArtist string    `json:"dominant_artist" db:"dominant_artist"`
+}
+
+// DailyListeningStats is one day of a user's listening, rolled up from play
+// events. Day is YYYY-MM-DD in the user's time zone; plays count only plays
+// kept in history, while minutes include skips.
+type DailyListeningStats struct {
+	Day             string `json:"day" db:"day"`
+	Plays           int    `json:"plays" db:"plays"`
+	ListenedMs      int64  `json:"-" db:"listened_ms"`
+	MinutesListened int    `json:"minutes_listened" db:"-"`
+	UniqueArtists   int    `json:"unique_artists" db:"unique_artists"`
+}
+
+// ListeningPeriod is the listening in one day, week, or month. Start is its
+// first day, YYYY-MM-DD in the user's time zone; weeks start on Monday.
+type ListeningPeriod struct {
+	Start           string `json:"start" db:"start"`
+	Plays           int    `json:"plays" db:"plays"`
+	ListenedMs      int64  `json:"-" db:"listened_ms"`
+	MinutesListened int    `json:"minutes_listened" db:"-"`
+}
+
+// Wrapped is a user's year in review. Days and the year's bounds are in the
+// user's time zone. Mood stands in for a genre mix, since providers don't
+// report genres per track; it is empty without Spotify audio features.
+type Wrapped struct {
+	Year            int                  `json:"year"`
+	Plays           int                  `json:"plays"`
+	MinutesListened int                  `json:"minutes_listened"`
+	ActiveDays      int                  `json:"active_days"`
+	BusiestDay      *DailyListeningStats `json:"busiest_day,omitempty"`
+	TopArtists      []TopArtist          `json:"top_artists"`
+	TopTracks       []TopTrack           `json:"top_tracks"`
+	Mood            MoodStats            `json:"mood"`
+}
+
+// ListeningHeatmap counts plays by weekday and hour in the user's time zone.
+// Plays[d][h] is plays on weekday d, Monday first, in the hour starting at h.
+type ListeningHeatmap struct {
+	Timezone string     `json:"timezone"`
+	Plays    [7][24]int `json:"plays"`
+}
+
+// TopArtist is an artist ranked by plays in a user's history. Artist is the
+// most recent spelling seen.
+type TopArtist struct {
+	Artist       string    `json:"artist" db:"artist"`
+	PlayCount    int       `json:"play_count" db:"play_count"`
+	LastPlayedAt time.Time `json:"last_played_at" db:"last_played_at"`
+}
+
+// TopTrack is a track ranked by plays in a user's history, described by its
+// most recent play
+type TopTrack struct {
+	SpotifyTrackID string    `json:"spotify_track_id" db:"spotify_track_id"`
+	Name           string    `json:"name" db:"name"`
+	Artist         string    `json:"artist" db:"artist"`
+	Album          string    `json:"album" db:"album"`
+	AlbumArtURL    string    `json:"album_art_url" db:"album_art_url"`
+	TrackURL       string    `json:"track_url" db:"track_url"`
+	PlayCount      int       `json:"play_count" db:"play_count"`
+	LastPlayedAt   time.Time `json:"last_played_at" db:"last_played_at"`
+}
+
+// TrackFeatures is how a track sounds, from Spotify's audio analysis. Tempo
+// is in beats per minute; the rest range from 0 to 1, with higher Valence
+// sounding more positive.
+type TrackFeatures struct {
+	SpotifyTrackID string  `json:"spotify_track_id" db:"spotify_track_id"`
+	Tempo          float64 `json:"tempo" db:"tempo"`
+	Energy         float64 `json:"energy" db:"energy"`
+	Danceability   float64 `json:"danceability" db:"danceability"`
+	Valence        float64 `json:"valence" db:"valence"`
+}
+
+// MoodStats breaks down how the tracks a user played sound. Only plays of
+// tracks with audio features are analyzed; the averages are nil when there
+// are none.
+type MoodStats struct {
+	Plays               int           `json:"plays"`
+	PlaysAnalyzed       int           `json:"plays_analyzed"`
+	AverageTempo        *float64      `json:"average_tempo"`
+	AverageEnergy       *float64      `json:"average_energy"`
+	AverageDanceability *float64      `json:"average_danceability"`
+	AverageValence      *float64      `json:"average_valence"`
+	Energy              EnergyLevels  `json:"energy"`
+	Moods               MoodQuadrants `json:"moods"`
+}
+
+// EnergyLevels counts plays by energy: low below 0.33, high from 0.66
+type EnergyLevels struct {
+	Low    int `json:"low"`
+	Medium int `json:"medium"`
+	High   int `json:"high"`
+}
+
+// MoodQuadrants counts plays by valence and energy, each split at 0.5: happy
+// is positive and energetic, calm positive and mellow, intense negative and
+// energetic, and melancholy negative and mellow
+type MoodQuadrants struct {
+	Happy      int `json:"happy"`
+	Calm       int `json:"calm"`
+	Intense    int `json:"intense"`
+	Melancholy int `json:"melancholy"`
+}
+
+// Session is a signed-in browser. Only a hash of the session token is
+// stored.
+type Session struct {
+	ID           string    `json:"id" db:"id"`
+	UserID       string    `json:"-" db:"user_id"`
+	TokenHash    string    `json:"-" db:"token_hash"`
+	IPAddress    string    `json:"ip_address" db:"ip_address"`
+	UserAgent    string    `json:"user_agent" db:"user_agent"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	LastActiveAt time.Time `json:"last_active_at" db:"last_active_at"`
+	ExpiresAt    time.Time `json:"expires_at" db:"expires_at"`
+	// Current marks the session making the request when sessions are listed
+	Current bool `json:"current" db:"-"`
+}
+
+// ShortLink is a short /s/:code link to a user's profile
+type ShortLink struct {
+	Code          string     `json:"code" db:"code"`
+	UserID        string     `json:"-" db:"user_id"`
+	Label         string     `json:"label" db:"label"`
+	ClickCount    int64      `json:"click_count" db:"click_count"`
+	LastClickedAt *time.Time `json:"last_clicked_at,omitempty" db:"last_clicked_at"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+}
+
+// ShortLinkStats is a short link with the sites its clicks came from
+type ShortLinkStats struct {
+	ShortLink
+	TopReferrers []ReferrerCount `json:"top_referrers"`
+}
+
+// ReferrerCount is the number of clicks from one referring site. Referrer is
+// "direct" for clicks without a Referer header.
+type ReferrerCount struct {
+	Referrer string `json:"referrer" db:"referrer"`
+	Clicks   int64  `json:"clicks" db:"clicks"`
+}
+
+// APIKey lets scripts call the API as a user. Only a hash of the key is
+// stored; Prefix identifies it in listings. A DailyQuota of 0 in the database
+// means the configured default.
+type APIKey struct {
+	ID         string     `json:"id" db:"id"`
+	UserID     string     `json:"-" db:"user_id"`
+	Name       string     `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"prefix"`
+	KeyHash    string     `json:"-" db:"key_hash"`
+	DailyQuota int        `json:"daily_quota" db:"daily_quota"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// APIKeyUsage reports an API key's requests per day and busiest endpoints
+type APIKeyUsage struct {
+	KeyID         string                `json:"key_id"`
+	DailyQuota    int                   `json:"daily_quota"`
+	RequestsToday int64                 `json:"requests_today"`
+	LastUsedAt    *time.Time            `json:"last_used_at,omitempty"`
+	Days          []APIKeyDayUsage      `json:"days"`
+	TopEndpoints  []APIKeyEndpointUsage `json:"top_endpoints"`
+}
+
+// APIKeyDayUsage is an API key's request count on one UTC day
+type APIKeyDayUsage struct {
+	Day      string `json:"date" db:"day"`
+	Requests int64  `json:"requests" db:"requests"`
+}
+
+// APIKeyEndpointUsage is an API key's request count for one endpoint, such as
+// "GET /api/v1/tracks/current"
+type APIKeyEndpointUsage struct {
+	Endpoint string `json:"endpoint" db:"endpoint"`
+	Requests int64  `json:"requests" db:"requests"`
+}
+
+// Webhook is a URL a user has asked to be sent their track changes
+type Webhook struct {
+	ID          string    `json:"id" db:"id"`
+	UserID      string    `json:"-" db:"user_id"`
+	URL         string    `json:"url" db:"url"`
+	Description string    `json:"description" db:"description"`
+	Secret      string    `json:"-" db:"secret"`
+	IsActive    bool      `json:"is_active" db:"is_active"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// WebhookDelivery is one event sent, or waiting to be sent, to a webhook.
+// Status is pending until it's delivered or runs out of attempts and fails.
+type WebhookDelivery struct {
+	ID             int64           `json:"id" db:"id"`
+	WebhookID      string          `json:"webhook_id" db:"webhook_id"`
+	Event          string          `json:"event" db:"event"`
+	PlaybackKey    string          `json:"-" db:"playback_key"`
+	Payload        json.RawMessage `json:"payload" db:"payload"`
+	Status         string          `json:"status" db:"status"`
+	Attempts       int             `json:"attempts" db:"attempts"`
+	ResponseStatus *int            `json:"response_status,omitempty" db:"response_status"`
+	LastError      string          `json:"last_error,omitempty" db:"last_error"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at" db:"next_attempt_at"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty" db:"delivered_at"`
+}
+
+// WebhookEvent is the body POSTed to webhooks. NowPlaying is the new
+// playback state for track.changed events.
+type WebhookEvent struct {
+	Event      string                   `json:"event"`
+	ProfileURL string                   `json:"profile_url"`
+	NowPlaying *SpotifyCurrentlyPlaying `json:"now_playing"`
+	OccurredAt time.Time                `json:"occurred_at"`
+}
+
+// RecentlyViewedProfile is a public profile a signed-in user has visited
+type RecentlyViewedProfile struct {
+	ProfileURL   string    `json:"profile_url" db:"profile_url"`
+	DisplayName  string    `json:"display_name" db:"display_name"`
+	ViewCount    int       `json:"view_count" db:"view_count"`
+	LastViewedAt time.Time `json:"last_viewed_at" db:"last_viewed_at"`
+}
+
+// UsageReport is one month of anonymized platform metrics for operators.
+// It holds only counts, never anything identifying a user or visitor.
+type UsageReport struct {
+	Month          string           `json:"month" db:"month"`
+	ActiveUsers    int64            `json:"active_users" db:"active_users"`
+	NewUsers       int64            `json:"new_users" db:"new_users"`
+	TotalUsers     int64            `json:"total_users" db:"total_users"`
+	TracksLogged   int64            `json:"tracks_logged" db:"tracks_logged"`
+	ProfileVisits  int64            `json:"profile_visits" db:"profile_visits"`
+	UniqueVisitors int64            `json:"unique_visitors" db:"unique_visitors"`
+	TopReferrers   []ReferrerVisits `json:"top_referrers" db:"-"`
+	GeneratedAt    time.Time        `json:"generated_at" db:"generated_at"`
+}
+
+// ReferrerVisits is the number of profile visits from one referring site
+type ReferrerVisits struct {
+	Referrer string `json:"referrer" db:"referrer"`
+	Visits   int64  `json:"visits" db:"visits"`
+}
+
+// Lyrics are the words to a track. Lines are set when the lyrics are synced
+// to playback; Plain is always set unless the track is instrumental.
+type Lyrics struct {
+	TrackID      string      `json:"track_id"`
+	Instrumental bool        `json:"instrumental"`
+	Plain        string      `json:"plain,omitempty"`
+	Lines        []LyricLine `json:"lines,omitempty"`
+}
+
+// LyricLine is one line of synced lyrics, starting TimeMs into the track
+type LyricLine struct {
+	TimeMs int    `json:"time_ms"`
+	Text   string `json:"text"`
+}
+
 // SpotifyCurrentlyPlaying represents the currently playing track from Spotify API
 type SpotifyCurrentlyPlaying struct {
-	IsPlaying   bool   `json:"is_playing"`
-	TrackID     string `json:"track_id"`
-	TrackName   string `json:"track_name"`
-	ArtistName  string `json:"artist_name"`
-	AlbumName   string `json:"album_name"`
-	AlbumArtURL string `json:"album_art_url"`
-	TrackURL    string `json:"track_url"`
-	DurationMs  int    `json:"duration_ms"`
-	ProgressMs  int    `json:"progress_ms"`
+	IsPlaying   bool   `json:"is_playing" xml:"is_playing"`
+	TrackID     string `json:"track_id" xml:"track_id"`
+	TrackName   string `json:"track_name" xml:"track_name"`
+	ArtistName  string `json:"artist_name" xml:"artist_name"`
+	AlbumName   string `json:"album_name" xml:"album_name"`
+	AlbumArtURL string `json:"album_art_url" xml:"album_art_url"`
+	TrackURL    string `json:"track_url" xml:"track_url"`
+	DurationMs  int    `json:"duration_ms" xml:"duration_ms"`
+	ProgressMs  int    `json:"progress_ms" xml:"progress_ms"`
+
+	// ChangedAt records when the track or play state last changed (Unix milliseconds)
+	ChangedAt int64 `json:"changed_at,omitempty" xml:"changed_at,omitempty"`
+
+	// PublishedAt is set when the track is broadcast over pub/sub (Unix milliseconds)
+	PublishedAt int64 `json:"published_at,omitempty" xml:"published_at,omitempty"`
+	// Source names where the state came from when it wasn't the user's provider
+	Source string `json:"source,omitempty" xml:"source,omitempty"`
+	// HeldUntil is when a state from Source stops taking precedence over the
+	// user's provider (Unix milliseconds)
+	HeldUntil int64 `json:"held_until,omitempty" xml:"held_until,omitempty"`
 }
 
 // ProfileResponse represents the data sent to profile visitors
@@ -90,4 +506,5 @@ type UserPublic struct {
 	ID          string `json:"id"`
 	DisplayName string `json:"display_name"`
 	ProfileURL  string `json:"profile_url"`
+	Timezone    string `json:"timezone"`
 }
@@ -0,0 +1,339 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Document is an OpenAPI 3 document
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// PathItem holds the operations available on a single path
+type PathItem struct {
+	Get    *OperationObject `json:"get,omitempty"`
+	Head   *OperationObject `json:"head,omitempty"`
+	Post   *OperationObject `json:"post,omitempty"`
+	Put    *OperationObject `json:"put,omitempty"`
+	Patch  *OperationObject `json:"patch,omitempty"`
+	Delete *OperationObject `json:"delete,omitempty"`
+}
+
+// OperationObject is the serialized form of an Operation
+type OperationObject struct {
+	Summary     string                     `json:"summary,omitempty"`
+	Description string                     `json:"description,omitempty"`
+	Tags        []string                   `json:"tags,omitempty"`
+	Parameters  []ParameterObject          `json:"parameters,omitempty"`
+	RequestBody *RequestBodyObject         `json:"requestBody,omitempty"`
+	Responses   map[string]*ResponseObject `json:"responses"`
+	Security    []map[string][]string      `json:"security,omitempty"`
+	Deprecated  bool                       `json:"deprecated,omitempty"`
+}
+
+// ParameterObject describes a path, query, or header parameter
+type ParameterObject struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBodyObject describes a JSON request body
+type RequestBodyObject struct {
+	Required bool                  `json:"required"`
+	Content  map[string]MediaTypes `json:"content"`
+}
+
+// ResponseObject describes a single response
+type ResponseObject struct {
+	Description string                `json:"description"`
+	Content     map[string]MediaTypes `json:"content,omitempty"`
+}
+
+// MediaTypes holds the schema for one content type
+type MediaTypes struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds reusable schemas and security schemes
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme describes how clients authenticate
+type SecurityScheme struct {
+	Type string `json:"type"`
+	In   string `json:"in,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// Schema is a JSON schema subset sufficient for the API's models
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+}
+
+// Param documents a path or query parameter
+type Param struct {
+	Name        string
+	In          string // "path", "query", or "header"
+	Description string
+	Required    bool
+	Type        string // defaults to "string"
+}
+
+// Operation annotates a route for the generated spec
+type Operation struct {
+	Summary     string
+	Description string
+	Tag         string
+	// Auth requires a session cookie or API key, or only a session cookie
+	// with SessionOnly
+	Auth        bool
+	SessionOnly bool
+	Deprecated  bool
+	Params      []Param
+	// Request is an example value of the JSON request body type
+	Request interface{}
+	// Responses maps status codes to example values of the response body type;
+	// a nil value documents a response without a body
+	Responses map[int]interface{}
+	// ContentType overrides the response media type (defaults to application/json)
+	ContentType string
+}
+
+type route struct {
+	method string
+	path   string
+	op     Operation
+}
+
+// Registry collects annotated routes and builds the spec from them
+type Registry struct {
+	mu     sync.Mutex
+	routes []route
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// ginParam matches gin path parameters like :profileURL
+var ginParam = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+// Add documents a route; path uses gin syntax and is converted to OpenAPI templating
+func (r *Registry) Add(method, path string, op Operation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes = append(r.routes, route{method: method, path: ginParam.ReplaceAllString(path, "{$1}"), op: op})
+}
+
+// Build generates the OpenAPI document for every registered route
+func (r *Registry) Build(info Info) *Document {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info:    info,
+		Paths:   make(map[string]*PathItem),
+		Components: Components{
+			Schemas: make(map[string]*Schema),
+			SecuritySchemes: map[string]SecurityScheme{
+				"cookieAuth": {Type: "apiKey", In: "cookie", Name: "session"},
+				"apiKeyAuth": {Type: "apiKey", In: "header", Name: "X-API-Key"},
+			},
+		},
+	}
+	gen := &schemaGenerator{components: doc.Components.Schemas}
+
+	routes := append([]route(nil), r.routes...)
+	sort.SliceStable(routes, func(i, j int) bool { return routes[i].path < routes[j].path })
+
+	for _, rt := range routes {
+		item, ok := doc.Paths[rt.path]
+		if !ok {
+			item = &PathItem{}
+			doc.Paths[rt.path] = item
+		}
+
+		obj := &OperationObject{
+			Summary:     rt.op.Summary,
+			Description: rt.op.Description,
+			Deprecated:  rt.op.Deprecated,
+			Responses:   make(map[string]*ResponseObject),
+		}
+		if rt.op.Tag != "" {
+			obj.Tags = []string{rt.op.Tag}
+		}
+		switch {
+		case rt.op.SessionOnly:
+			obj.Security = []map[string][]string{{"cookieAuth": {}}}
+		case rt.op.Auth:
+			obj.Security = []map[string][]string{{"cookieAuth": {}}, {"apiKeyAuth": {}}}
+		}
+
+		for _, p := range rt.op.Params {
+			typ := p.Type
+			if typ == "" {
+				typ = "string"
+			}
+			obj.Parameters = append(obj.Parameters, ParameterObject{
+				Name:        p.Name,
+				In:          p.In,
+				Description: p.Description,
+				Required:    p.Required || p.In == "path",
+				Schema:      &Schema{Type: typ},
+			})
+		}
+
+		if rt.op.Request != nil {
+			obj.RequestBody = &RequestBodyObject{
+				Required: true,
+				Content:  map[string]MediaTypes{"application/json": {Schema: gen.schema(reflect.TypeOf(rt.op.Request))}},
+			}
+		}
+
+		contentType := rt.op.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		for status, body := range rt.op.Responses {
+			resp := &ResponseObject{Description: http.StatusText(status)}
+			if body != nil {
+				resp.Content = map[string]MediaTypes{contentType: {Schema: gen.schema(reflect.TypeOf(body))}}
+			}
+			obj.Responses[strconv.Itoa(status)] = resp
+		}
+
+		switch rt.method {
+		case http.MethodGet:
+			item.Get = obj
+		case http.MethodHead:
+			item.Head = obj
+		case http.MethodPost:
+			item.Post = obj
+		case http.MethodPut:
+			item.Put = obj
+		case http.MethodPatch:
+			item.Patch = obj
+		case http.MethodDelete:
+			item.Delete = obj
+		}
+	}
+
+	return doc
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaGenerator derives schemas from Go types, registering named structs as components
+type schemaGenerator struct {
+	components map[string]*Schema
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) *Schema {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+
+	var s *Schema
+	switch {
+	case t == timeType:
+		s = &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.String:
+		s = &Schema{Type: "string"}
+	case t.Kind() == reflect.Bool:
+		s = &Schema{Type: "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		s = &Schema{Type: "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		s = &Schema{Type: "number"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		s = &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case t.Kind() == reflect.Map:
+		s = &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		if _, ok := g.components[t.Name()]; !ok {
+			// Reserve the name first so recursive types terminate
+			g.components[t.Name()] = &Schema{}
+			*g.components[t.Name()] = *g.object(t)
+		}
+		s = &Schema{Ref: "#/components/schemas/" + t.Name()}
+	case t.Kind() == reflect.Struct:
+		s = g.object(t)
+	default:
+		s = &Schema{}
+	}
+
+	if nullable && s.Ref == "" {
+		s.Nullable = true
+	}
+	return s
+}
+
+func (g *schemaGenerator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.addFields(s, t)
+	sort.Strings(s.Required)
+	return s
+}
+
+func (g *schemaGenerator) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		// Embedded structs without a JSON name are flattened into the parent
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			g.addFields(s, field.Type)
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		s.Properties[name] = g.schema(field.Type)
+		if !strings.Contains(opts, "omitempty") && field.Type.Kind() != reflect.Ptr {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package poller
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/metrics"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/services"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/utils"
+	"github.com/rs/zerolog"
+)
+
+var (
+	pollerPolls = metrics.NewCounterVec(
+		"now_playing_poller_polls_total",
+		"Now-playing polls by result (changed, unchanged, rate_limited, failure).",
+		"result",
+	)
+	pollerWatched = metrics.NewGaugeVec(
+		"now_playing_poller_watched_profiles",
+		"Profiles with active viewers in the last poll cycle.",
+	)
+	pollerCycleDuration = metrics.NewHistogramVec(
+		"now_playing_poller_cycle_duration_seconds",
+		"Duration of each now-playing poll cycle.",
+		[]float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	)
+)
+
+// Poller periodically refreshes what users with active viewers are playing,
+// so viewers are served from the cache and track changes are published over
+// Redis pub/sub without waiting for a viewer's request to hit the provider
+type Poller struct {
+	users       *services.UserService
+	profiles    *services.ProfileService
+	interval    time.Duration
+	timeout     time.Duration
+	concurrency int
+	logger      zerolog.Logger
+
+	// pending counts profiles still waiting to be polled in the current cycle
+	pending atomic.Int64
+}
+
+// New creates a poller, or returns nil when polling is disabled. A nil Poller
+// is safe to use and does nothing.
+func New(cfg config.PollerConfig, users *services.UserService, profiles *services.ProfileService, logger zerolog.Logger) *Poller {
+	if cfg.IntervalSeconds <= 0 {
+		return nil
+	}
+	return &Poller{
+		users:       users,
+		profiles:    profiles,
+		interval:    time.Duration(cfg.IntervalSeconds) * time.Second,
+		timeout:     time.Duration(cfg.TimeoutSeconds) * time.Second,
+		concurrency: cfg.Concurrency,
+		logger:      utils.ModuleLogger(logger.With().Str("service", "poller").Logger(), utils.LogModuleSpotify),
+	}
+}
+
+// Run polls every interval until ctx is cancelled
+func (p *Poller) Run(ctx context.Context) {
+	if p == nil {
+		return
+	}
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.Poll(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Backlog returns the number of profiles not yet polled in the current cycle
+func (p *Poller) Backlog() int {
+	if p == nil {
+		return 0
+	}
+	return int(p.pending.Load())
+}
+
+// Poll refreshes every profile with active viewers once. Profiles are polled
+// concurrently, up to the configured limit. Presence lives in Redis, so the
+// cycle is skipped while it is unavailable.
+func (p *Poller) Poll(ctx context.Context) {
+	if !p.users.PresenceAvailable() {
+		return
+	}
+
+	start := time.Now()
+	userIDs, err := p.users.GetWatchedProfiles(ctx)
+	if err != nil {
+		p.logger.Error().Err(err).Msg("Failed to list watched profiles")
+		return
+	}
+	pollerWatched.Set(float64(len(userIDs)))
+	p.pending.Store(int64(len(userIDs)))
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, p.concurrency)
+	for _, userID := range userIDs {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			p.pending.Store(0)
+			return
+		}
+		wg.Add(1)
+		go func(userID string) {
+			defer func() {
+				<-slots
+				p.pending.Add(-1)
+				wg.Done()
+			}()
+			p.poll(ctx, userID)
+		}(userID)
+	}
+	wg.Wait()
+
+	duration := time.Since(start)
+	pollerCycleDuration.Observe(duration.Seconds())
+	p.logger.Debug().Int("profiles", len(userIDs)).Dur("duration", duration).Msg("Poll cycle finished")
+}
+
+// poll refreshes one user's playback state
+func (p *Poller) poll(ctx context.Context, userID string) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	user, err := p.users.GetUserByID(ctx, userID)
+	if err != nil {
+		pollerPolls.Inc("failure")
+		p.logger.Warn().Err(err).Str("user_id", userID).Msg("Failed to load user for polling")
+		return
+	}
+
+	changed, err := p.profiles.RefreshNowPlaying(ctx, user, p.users)
+	var rateLimited *services.ProviderRateLimitError
+	switch {
+	case errors.As(err, &rateLimited):
+		// The provider's backoff fails these fast; the next cycle after
+		// RetryAfter picks them up again
+		pollerPolls.Inc("rate_limited")
+		p.logger.Debug().Str("user_id", userID).Str("provider", rateLimited.Provider).Dur("retry_after", rateLimited.RetryAfter).Msg("Provider rate limited, skipping poll")
+	case err != nil:
+		pollerPolls.Inc("failure")
+		p.logger.Warn().Err(err).Str("user_id", userID).Msg("Failed to poll currently playing")
+	case changed:
+		pollerPolls.Inc("changed")
+	default:
+		pollerPolls.Inc("unchanged")
+	}
+}
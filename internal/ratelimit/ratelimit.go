@@ -0,0 +1,144 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/database"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/metrics"
+	"github.com/go-redis/redis/v8"
+)
+
+var limiterDecisions = metrics.NewCounterVec(
+	"ratelimit_decisions_total",
+	"Rate limiter decisions by policy and result (allowed, limited, error).",
+	"policy", "result",
+)
+
+// tokenBucket refills a bucket at rate tokens/second up to burst and takes one
+// token per request. It returns {allowed, remaining, retry_after_ms}.
+var tokenBucket = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+
+tokens = math.min(burst, tokens + math.max(0, now - ts) / 1000 * rate)
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate * 1000)
+end
+
+redis.call("HSET", KEYS[1], "tokens", tokens, "ts", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+
+return {allowed, math.floor(tokens), retry}
+`)
+
+// Policy is a named token-bucket limit
+type Policy struct {
+	Name      string
+	PerMinute int
+	Burst     int
+}
+
+// Result is the outcome of a single rate-limit check
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// Limiter enforces token-bucket policies with state shared in Redis
+type Limiter struct {
+	redis    *database.RedisClient
+	policies atomic.Pointer[policySet]
+}
+
+// policySet is the configuration a limiter enforces, replaced as a whole on
+// Update
+type policySet struct {
+	enabled  bool
+	policies map[string]Policy
+}
+
+// New creates a limiter from configuration
+func New(cfg config.RateLimitConfig, redis *database.RedisClient) *Limiter {
+	l := &Limiter{redis: redis}
+	l.Update(cfg)
+	return l
+}
+
+// Update replaces the enforced policies. Buckets already in Redis are kept
+// and refill at the new rate.
+func (l *Limiter) Update(cfg config.RateLimitConfig) {
+	policies := make(map[string]Policy, len(cfg.Policies))
+	for name, p := range cfg.Policies {
+		policies[name] = Policy{Name: name, PerMinute: p.PerMinute, Burst: p.Burst}
+	}
+	l.policies.Store(&policySet{enabled: cfg.Enabled, policies: policies})
+}
+
+// Policy returns the named policy and whether it is configured and enforced
+func (l *Limiter) Policy(name string) (Policy, bool) {
+	if l == nil {
+		return Policy{}, false
+	}
+	set := l.policies.Load()
+	if !set.enabled {
+		return Policy{}, false
+	}
+	p, ok := set.policies[name]
+	return p, ok && p.PerMinute > 0 && p.Burst > 0
+}
+
+// Allow takes one token from identity's bucket under policy. While Redis is
+// unavailable requests are allowed so rate limiting never takes the API down.
+func (l *Limiter) Allow(ctx context.Context, policy Policy, identity string) (Result, error) {
+	result := Result{Allowed: true, Limit: policy.Burst, Remaining: policy.Burst}
+	if !l.redis.Available() {
+		limiterDecisions.Inc(policy.Name, "error")
+		return result, database.ErrRedisUnavailable
+	}
+
+	key := fmt.Sprintf("ratelimit:%s:%s", policy.Name, identity)
+	rate := float64(policy.PerMinute) / 60
+	reply, err := l.redis.RunScript(ctx, tokenBucket, []string{key}, rate, policy.Burst, time.Now().UnixMilli())
+	if err != nil {
+		limiterDecisions.Inc(policy.Name, "error")
+		return result, err
+	}
+
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 3 {
+		limiterDecisions.Inc(policy.Name, "error")
+		return result, errors.New("unexpected rate limit script reply")
+	}
+	allowed, _ := values[0].(int64)
+	remaining, _ := values[1].(int64)
+	retryMs, _ := values[2].(int64)
+
+	result.Allowed = allowed == 1
+	result.Remaining = int(remaining)
+	result.RetryAfter = time.Duration(retryMs) * time.Millisecond
+
+	if result.Allowed {
+		limiterDecisions.Inc(policy.Name, "allowed")
+	} else {
+		limiterDecisions.Inc(policy.Name, "limited")
+	}
+	return result, nil
+}
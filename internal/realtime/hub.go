@@ -0,0 +1,160 @@
+package realtime
+
+import (
+	"context"
+	"sync"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/database"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/metrics"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/utils"
+	"github.com/rs/zerolog"
+)
+
+// clientBuffer is how many events a client may fall behind before it is dropped
+const clientBuffer = 16
+
+var (
+	hubChannels = metrics.NewGaugeVec(
+		"realtime_hub_channels",
+		"Pub/sub channels the hub holds a Redis subscription for.",
+	)
+	hubClients = metrics.NewGaugeVec(
+		"realtime_hub_clients",
+		"Clients attached to the hub across all channels.",
+	)
+	hubDropped = metrics.NewCounterVec(
+		"realtime_hub_dropped_clients_total",
+		"Clients disconnected because they fell too far behind.",
+	)
+)
+
+// Hub shares one Redis subscription per channel between every local client
+// listening on it. The subscription is opened when the first client registers
+// and closed when the last one leaves, so a popular profile costs one Redis
+// connection per instance rather than one per viewer.
+type Hub struct {
+	redis  *database.RedisClient
+	logger zerolog.Logger
+
+	mu     sync.Mutex
+	topics map[string]*topic
+}
+
+// topic is one channel's subscription and the clients attached to it
+type topic struct {
+	channel string
+	clients map[*Client]struct{}
+	cancel  context.CancelFunc
+}
+
+// Client receives the events broadcast on one channel
+type Client struct {
+	topic  *topic
+	events chan Event
+}
+
+// NewHub creates an empty hub
+func NewHub(redis *database.RedisClient, logger zerolog.Logger) *Hub {
+	return &Hub{
+		redis:  redis,
+		logger: utils.ModuleLogger(logger.With().Str("component", "realtime_hub").Logger(), utils.LogModuleRealtime),
+		topics: make(map[string]*topic),
+	}
+}
+
+// Register attaches a client to channel, subscribing to it if no other client
+// is. The client is unregistered when ctx is cancelled.
+func (h *Hub) Register(ctx context.Context, channel string) *Client {
+	h.mu.Lock()
+	t, ok := h.topics[channel]
+	if !ok {
+		subCtx, cancel := context.WithCancel(context.Background())
+		t = &topic{channel: channel, clients: make(map[*Client]struct{}), cancel: cancel}
+		h.topics[channel] = t
+		hubChannels.Add(1)
+		go h.forward(t, Subscribe(subCtx, h.redis, h.logger, channel))
+	}
+	client := &Client{topic: t, events: make(chan Event, clientBuffer)}
+	t.clients[client] = struct{}{}
+	hubClients.Add(1)
+	h.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		h.Unregister(client)
+	}()
+	return client
+}
+
+// Unregister detaches a client and closes its events channel. The channel's
+// subscription is closed once its last client leaves. Unregistering a client
+// twice is a no-op.
+func (h *Hub) Unregister(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.detach(client)
+}
+
+// Broadcast delivers event to every local client registered on channel.
+// Clients that have fallen behind are dropped rather than blocking the rest.
+func (h *Hub) Broadcast(channel string, event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if t, ok := h.topics[channel]; ok {
+		h.deliver(t, event)
+	}
+}
+
+// Channels returns the number of channels with at least one client
+func (h *Hub) Channels() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.topics)
+}
+
+// forward fans a topic's subscription events out to its clients until the
+// subscription ends
+func (h *Hub) forward(t *topic, sub *Subscription) {
+	for event := range sub.Events() {
+		h.mu.Lock()
+		h.deliver(t, event)
+		h.mu.Unlock()
+	}
+}
+
+// deliver sends event to t's clients. h.mu must be held.
+func (h *Hub) deliver(t *topic, event Event) {
+	for client := range t.clients {
+		select {
+		case client.events <- event:
+		default:
+			hubDropped.Inc()
+			h.logger.Warn().Str("channel", t.channel).Msg("Dropping client that fell behind")
+			h.detach(client)
+		}
+	}
+}
+
+// detach removes client from its topic, closing the topic when it empties.
+// h.mu must be held.
+func (h *Hub) detach(client *Client) {
+	t := client.topic
+	if _, ok := t.clients[client]; !ok {
+		return
+	}
+	delete(t.clients, client)
+	close(client.events)
+	hubClients.Add(-1)
+
+	if len(t.clients) == 0 && h.topics[t.channel] == t {
+		delete(h.topics, t.channel)
+		t.cancel()
+		hubChannels.Add(-1)
+	}
+}
+
+// Events returns the channel on which the client's messages and resync events
+// are delivered. It is closed when the client is unregistered or dropped.
+func (c *Client) Events() <-chan Event {
+	return c.events
+}
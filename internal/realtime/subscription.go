@@ -0,0 +1,153 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/database"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/utils"
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// pingInterval is how long a subscription may sit idle before its connection is probed
+	pingInterval = 30 * time.Second
+
+	minBackoff = 250 * time.Millisecond
+	maxBackoff = 30 * time.Second
+)
+
+// EventType distinguishes pub/sub messages from subscription lifecycle events
+type EventType string
+
+const (
+	// EventMessage carries a message published on a subscribed channel
+	EventMessage EventType = "message"
+	// EventResync is emitted after a reconnect; messages may have been missed
+	// while disconnected, so consumers should refetch current state
+	EventResync EventType = "resync"
+)
+
+// Event is delivered to subscribers for every message and lifecycle change
+type Event struct {
+	Type    EventType
+	Channel string
+	Payload string
+}
+
+// Subscription delivers messages from Redis pub/sub channels, reconnecting with
+// exponential backoff and resubscribing whenever the underlying connection drops
+type Subscription struct {
+	redis    *database.RedisClient
+	channels []string
+	events   chan Event
+	logger   zerolog.Logger
+}
+
+// Subscribe starts a subscription to channels that runs until ctx is cancelled,
+// at which point the events channel is closed
+func Subscribe(ctx context.Context, redis *database.RedisClient, logger zerolog.Logger, channels ...string) *Subscription {
+	s := &Subscription{
+		redis:    redis,
+		channels: channels,
+		events:   make(chan Event, 16),
+		logger:   utils.ModuleLogger(logger.With().Strs("channels", channels).Logger(), utils.LogModuleRealtime),
+	}
+	go s.run(ctx)
+	return s
+}
+
+// Events returns the channel on which messages and resync events are delivered
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+func (s *Subscription) run(ctx context.Context) {
+	defer close(s.events)
+
+	backoff := minBackoff
+	connectedBefore := false
+
+	for ctx.Err() == nil {
+		pubsub := s.redis.Subscribe(ctx, s.channels...)
+
+		// The first reply confirms the subscription is active
+		if _, err := pubsub.Receive(ctx); err != nil {
+			pubsub.Close()
+			if ctx.Err() != nil {
+				return
+			}
+			s.logger.Warn().Err(err).Dur("backoff", backoff).Msg("Pub/sub subscribe failed, retrying")
+			if !sleep(ctx, backoff) {
+				return
+			}
+			backoff = min(backoff*2, maxBackoff)
+			continue
+		}
+
+		backoff = minBackoff
+		if connectedBefore {
+			s.logger.Info().Msg("Pub/sub resubscribed")
+			if !s.emit(ctx, Event{Type: EventResync}) {
+				pubsub.Close()
+				return
+			}
+		}
+		connectedBefore = true
+
+		err := s.receive(ctx, pubsub)
+		pubsub.Close()
+		if ctx.Err() != nil {
+			return
+		}
+		s.logger.Warn().Err(err).Msg("Pub/sub connection lost, reconnecting")
+	}
+}
+
+// receive forwards messages until the connection fails or ctx is cancelled
+func (s *Subscription) receive(ctx context.Context, pubsub *redis.PubSub) error {
+	for {
+		msg, err := pubsub.ReceiveTimeout(ctx, pingInterval)
+		if err != nil {
+			// An idle connection is fine as long as it still answers a ping
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() && ctx.Err() == nil {
+				if err := pubsub.Ping(ctx); err != nil {
+					return err
+				}
+				continue
+			}
+			return err
+		}
+
+		if m, ok := msg.(*redis.Message); ok {
+			s.logger.Debug().Str("channel", m.Channel).Msg("Pub/sub message received")
+			if !s.emit(ctx, Event{Type: EventMessage, Channel: m.Channel, Payload: m.Payload}) {
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+func (s *Subscription) emit(ctx context.Context, event Event) bool {
+	select {
+	case s.events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
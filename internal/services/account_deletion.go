@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/lib/pq"
+)
+
+// DeleteAccount deletes a user's account. The user is hidden and signed out
+// everywhere, their provider tokens are wiped, their listening history, API
+// keys, webhooks, and integrations are deleted, visits to their profile are
+// deleted, their visits to other profiles are anonymized, and their
+// profile's custom message is cleared. Only the emptied user row and the
+// profile's display settings are kept until PurgeDeletedAccounts removes
+// them, holding the profile URL, so signing in again before then gets the
+// account back.
+func (s *UserService) DeleteAccount(ctx context.Context, userID string) error {
+	// Data-modifying CTEs run whether or not they're referenced, all in the
+	// one statement and so one transaction: the account is never left half
+	// deleted or with sessions still signed in
+	var tokenHashes pq.StringArray
+	err := s.db.GetContext(ctx, &tokenHashes, `
+		WITH deleted_sessions AS (
+			DELETE FROM sessions WHERE user_id = $1 RETURNING token_hash
+		), deleted_tracks AS (
+			DELETE FROM tracks WHERE user_id = $1
+		), deleted_play_events AS (
+			DELETE FROM play_events WHERE user_id = $1
+		), deleted_listening_sessions AS (
+			DELETE FROM listening_sessions WHERE user_id = $1
+		), deleted_daily_stats AS (
+			DELETE FROM listening_stats_daily WHERE user_id = $1
+		), deleted_api_keys AS (
+			DELETE FROM api_keys WHERE user_id = $1
+		), deleted_webhooks AS (
+			DELETE FROM webhooks WHERE user_id = $1
+		), deleted_media_webhooks AS (
+			DELETE FROM media_webhooks WHERE user_id = $1
+		), deleted_scrobble_accounts AS (
+			DELETE FROM scrobble_accounts WHERE user_id = $1
+		), deleted_short_links AS (
+			DELETE FROM short_links WHERE user_id = $1
+		), deleted_visits AS (
+			DELETE FROM profile_visits WHERE user_id = $1
+		), anonymized_visits AS (
+			UPDATE profile_visits SET visitor_user_id = NULL, visitor_ip = NULL, user_agent = NULL
+			WHERE visitor_user_id = $1
+		), deleted_recently_viewed AS (
+			DELETE FROM recently_viewed_profiles WHERE viewer_id = $1 OR profile_user_id = $1
+		), cleared_profile AS (
+			UPDATE profiles SET custom_message = '', updated_at = NOW() WHERE user_id = $1
+		)
+		UPDATE users SET
+			is_active = false,
+			spotify_access_token = '',
+			spotify_refresh_token = '',
+			token_expires_at = NOW(),
+			deleted_at = NOW(),
+			updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING (SELECT COALESCE(ARRAY_AGG(token_hash), '{}') FROM deleted_sessions)
+	`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperr.NotFound("user_not_found", "User not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete account: %w", err)
+	}
+	s.uncacheSessions(ctx, tokenHashes...)
+
+	s.forgetAccount(ctx, userID)
+	return nil
+}
+
+// forgetAccount drops what Redis holds for a deleted user: their now playing
+// track and profile presence. Anyone still watching is told nothing is
+// playing, which also drops the hot cache copies on every instance. Failures
+// are logged; these keys expire on their own.
+func (s *UserService) forgetAccount(ctx context.Context, userID string) {
+	if !s.redis.Available() {
+		return
+	}
+	for _, key := range []string{fmt.Sprintf("track:current:%s", userID), presenceKey(userID)} {
+		if err := s.redis.Delete(ctx, key); err != nil {
+			s.logger.Warn().Ctx(ctx).Err(err).Str("key", key).Msg("Failed to drop deleted account's key")
+		}
+	}
+	if err := s.redis.RemoveFromSortedSet(ctx, watchedProfilesKey, userID); err != nil {
+		s.logger.Warn().Ctx(ctx).Err(err).Msg("Failed to unwatch deleted account's profile")
+	}
+
+	stopped, err := json.Marshal(models.SpotifyCurrentlyPlaying{ChangedAt: time.Now().UnixMilli(), PublishedAt: time.Now().UnixMilli()})
+	if err == nil {
+		err = s.redis.Publish(ctx, fmt.Sprintf("track:updates:%s", userID), stopped)
+	}
+	if err == nil {
+		err = s.redis.Publish(ctx, fmt.Sprintf("profile:updates:%s", userID), time.Now().Unix())
+	}
+	if err != nil {
+		s.logger.Warn().Ctx(ctx).Err(err).Msg("Failed to publish account deletion")
+	}
+}
+
+// PurgeDeletedAccounts permanently removes accounts deleted before cutoff,
+// along with everything that cascades from them, returning how many were
+// removed
+func (s *UserService) PurgeDeletedAccounts(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM users WHERE deleted_at < $1", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted accounts: %w", err)
+	}
+	return result.RowsAffected()
+}
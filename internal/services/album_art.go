@@ -0,0 +1,206 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // decoders for album art sources
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/database"
+	"github.com/brandonhuynh1/whatamilisteningto-api/pkg/imaging"
+	"github.com/rs/zerolog"
+)
+
+// AlbumArtSizes are the square sizes, in pixels, album art is served at.
+// A fixed set keeps the cache small.
+var AlbumArtSizes = []int{64, 160, 300, 640}
+
+// albumArtQuality is the JPEG quality of resized album art
+const albumArtQuality = 85
+
+// maxSourcePixels caps the dimensions of album art that gets decoded. A small
+// file can declare a huge image, and decoding allocates for every pixel.
+const maxSourcePixels = 4096 * 4096
+
+// AlbumArtService serves album art for tracks in listening history from its
+// own origin, resized and cached, so embeds don't depend on provider CDN
+// URLs that expire or block hotlinking
+type AlbumArtService struct {
+	db             *database.DB
+	redis          *database.RedisClient
+	httpClient     *http.Client
+	allowedHosts   []string
+	maxSourceBytes int64
+	cacheTTL       time.Duration
+	logger         zerolog.Logger
+}
+
+// NewAlbumArtService creates the album art service, or returns nil when the
+// proxy is disabled
+func NewAlbumArtService(cfg config.ArtConfig, db *database.DB, redis *database.RedisClient, logger zerolog.Logger) *AlbumArtService {
+	if !cfg.Enabled {
+		return nil
+	}
+	s := &AlbumArtService{
+		db:             db,
+		redis:          redis,
+		allowedHosts:   cfg.AllowedHosts,
+		maxSourceBytes: cfg.MaxSourceBytes,
+		cacheTTL:       time.Duration(cfg.CacheHours) * time.Hour,
+		logger:         logger.With().Str("service", "album_art").Logger(),
+	}
+	s.httpClient = &http.Client{
+		Timeout:       10 * time.Second,
+		CheckRedirect: s.checkRedirect,
+	}
+	return s
+}
+
+// checkRedirect applies the allowed host check to every redirect hop, so an
+// allowed URL can't bounce the fetch to some other host
+func (s *AlbumArtService) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if req.URL.Scheme != "https" || !s.allowedHost(req.URL.Hostname()) {
+		return fmt.Errorf("redirect to disallowed URL %s://%s", req.URL.Scheme, req.URL.Host)
+	}
+	return nil
+}
+
+// GetAlbumArt returns a track's album art as a size x size JPEG
+func (s *AlbumArtService) GetAlbumArt(ctx context.Context, trackID string, size int) ([]byte, error) {
+	key := fmt.Sprintf("art:%s:%d", trackID, size)
+	if s.redis.Available() {
+		if data, err := s.redis.Get(ctx, key); err == nil {
+			return []byte(data), nil
+		}
+	}
+
+	source, err := s.source(ctx, trackID)
+	if err != nil {
+		return nil, err
+	}
+
+	header, _, err := image.DecodeConfig(bytes.NewReader(source))
+	if err != nil {
+		return nil, apperr.Unavailable("art_unreadable", "Album art could not be read").Wrap(err)
+	}
+	if int64(header.Width)*int64(header.Height) > maxSourcePixels {
+		return nil, apperr.Unavailable("art_unreadable", "Album art could not be read").
+			Wrap(fmt.Errorf("album art is %dx%d, over %d pixels", header.Width, header.Height, maxSourcePixels))
+	}
+	img, _, err := image.Decode(bytes.NewReader(source))
+	if err != nil {
+		return nil, apperr.Unavailable("art_unreadable", "Album art could not be read").Wrap(err)
+	}
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, imaging.Square(img, size), &jpeg.Options{Quality: albumArtQuality}); err != nil {
+		return nil, fmt.Errorf("failed to encode album art: %w", err)
+	}
+
+	s.cache(ctx, key, out.Bytes())
+	return out.Bytes(), nil
+}
+
+// source returns the original album art for a track, downloading it only
+// when it isn't cached, so each size doesn't hit the CDN again
+func (s *AlbumArtService) source(ctx context.Context, trackID string) ([]byte, error) {
+	key := fmt.Sprintf("art:%s:source", trackID)
+	if s.redis.Available() {
+		if data, err := s.redis.Get(ctx, key); err == nil {
+			return []byte(data), nil
+		}
+	}
+
+	var artURL string
+	err := s.db.GetContext(ctx, &artURL, `
+		SELECT album_art_url FROM tracks
+		WHERE spotify_track_id = $1 AND album_art_url <> ''
+		ORDER BY played_at DESC
+		LIMIT 1
+	`, trackID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperr.NotFound("art_not_found", "No album art for this track")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up album art: %w", err)
+	}
+
+	// Manual entries can carry any artwork URL, so only known image hosts
+	// are fetched
+	parsed, err := url.Parse(artURL)
+	if err != nil || parsed.Scheme != "https" || !s.allowedHost(parsed.Hostname()) {
+		return nil, apperr.NotFound("art_not_found", "No album art for this track")
+	}
+
+	data, err := s.download(ctx, artURL)
+	if err != nil {
+		return nil, apperr.Unavailable("art_unavailable", "Album art is temporarily unavailable").Wrap(err)
+	}
+	s.cache(ctx, key, data)
+	return data, nil
+}
+
+func (s *AlbumArtService) download(ctx context.Context, artURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", artURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non-200 response: %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, s.maxSourceBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+	if int64(len(data)) > s.maxSourceBytes {
+		return nil, fmt.Errorf("album art larger than %d bytes", s.maxSourceBytes)
+	}
+	return data, nil
+}
+
+// allowedHost reports whether host matches an allowed host. A leading "*."
+// matches any subdomain.
+func (s *AlbumArtService) allowedHost(host string) bool {
+	host = strings.ToLower(host)
+	for _, allowed := range s.allowedHosts {
+		allowed = strings.ToLower(allowed)
+		if suffix, ok := strings.CutPrefix(allowed, "*"); ok {
+			if strings.HasSuffix(host, suffix) {
+				return true
+			}
+		} else if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *AlbumArtService) cache(ctx context.Context, key string, data []byte) {
+	if !s.redis.Available() {
+		return
+	}
+	if err := s.redis.Set(ctx, key, data, s.cacheTTL); err != nil {
+		s.logger.Warn().Ctx(ctx).Err(err).Str("key", key).Msg("Failed to cache album art")
+	}
+}
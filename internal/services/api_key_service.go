@@ -0,0 +1,361 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/database"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// apiKeyPrefix starts every API key so leaked keys are easy to recognize
+const apiKeyPrefix = "wail_"
+
+// maxAPIKeysPerUser bounds how many keys one account can hold
+const maxAPIKeysPerUser = 10
+
+// apiKeyUsageTTL keeps Redis usage counters long enough for a missed rollup
+// to catch up the next day
+const apiKeyUsageTTL = 72 * time.Hour
+
+// topEndpointLimit is how many endpoints usage reports
+const topEndpointLimit = 10
+
+// APIKeyService issues API keys, enforces their daily quotas, and tracks
+// their usage. Requests are counted in Redis and rolled into Postgres
+// periodically and whenever usage is read.
+type APIKeyService struct {
+	db         *database.DB
+	redis      *database.RedisClient
+	dailyQuota int
+	logger     zerolog.Logger
+}
+
+// NewAPIKeyService creates a new API key service
+func NewAPIKeyService(cfg config.APIKeyConfig, db *database.DB, redis *database.RedisClient, logger zerolog.Logger) *APIKeyService {
+	return &APIKeyService{
+		db:         db,
+		redis:      redis,
+		dailyQuota: cfg.DailyQuota,
+		logger:     logger.With().Str("service", "api_key").Logger(),
+	}
+}
+
+// hashAPIKey hashes an API key for storage and lookup
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// usageDay is the UTC date usage is counted under
+func usageDay(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
+
+func apiKeyCountKey(keyID, day string) string {
+	return fmt.Sprintf("apikey:count:%s:%s", keyID, day)
+}
+
+func apiKeyUsageKey(keyID, day string) string {
+	return fmt.Sprintf("apikey:usage:%s:%s", keyID, day)
+}
+
+func apiKeyActiveKey(day string) string {
+	return "apikey:active:" + day
+}
+
+func apiKeyLastUsedKey(keyID string) string {
+	return "apikey:last_used:" + keyID
+}
+
+// CreateKey issues a new API key for a user. The key itself is only returned
+// here; just its hash is stored.
+func (s *APIKeyService) CreateKey(ctx context.Context, userID, name string) (*models.APIKey, string, error) {
+	var count int
+	if err := s.db.GetContext(ctx, &count, "SELECT COUNT(*) FROM api_keys WHERE user_id = $1", userID); err != nil {
+		return nil, "", fmt.Errorf("failed to count API keys: %w", err)
+	}
+	if count >= maxAPIKeysPerUser {
+		return nil, "", apperr.Conflict("api_key_limit", fmt.Sprintf("You can have up to %d API keys; revoke one first", maxAPIKeysPerUser))
+	}
+
+	raw := make([]byte, 20)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	secret := apiKeyPrefix + hex.EncodeToString(raw)
+
+	key := &models.APIKey{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Name:      name,
+		Prefix:    secret[:len(apiKeyPrefix)+6],
+		KeyHash:   hashAPIKey(secret),
+		CreatedAt: time.Now(),
+	}
+	_, err := s.db.NamedExecContext(ctx, `
+		INSERT INTO api_keys (id, user_id, name, prefix, key_hash, created_at)
+		VALUES (:id, :user_id, :name, :prefix, :key_hash, :created_at)
+	`, key)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	key.DailyQuota = s.dailyQuota
+	return key, secret, nil
+}
+
+// ListKeys returns a user's API keys, newest first
+func (s *APIKeyService) ListKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	keys := []models.APIKey{}
+	err := s.db.SelectContext(ctx, &keys,
+		"SELECT * FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	for i := range keys {
+		s.applyDefaultQuota(&keys[i])
+	}
+	return keys, nil
+}
+
+// GetKey returns one of a user's API keys
+func (s *APIKeyService) GetKey(ctx context.Context, userID, keyID string) (*models.APIKey, error) {
+	if _, err := uuid.Parse(keyID); err != nil {
+		return nil, apperr.NotFound("api_key_not_found", "API key not found")
+	}
+
+	var key models.APIKey
+	err := s.db.GetContext(ctx, &key, "SELECT * FROM api_keys WHERE id = $1 AND user_id = $2", keyID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperr.NotFound("api_key_not_found", "API key not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	s.applyDefaultQuota(&key)
+	return &key, nil
+}
+
+// RevokeKey deletes one of a user's API keys along with its usage
+func (s *APIKeyService) RevokeKey(ctx context.Context, userID, keyID string) error {
+	if _, err := uuid.Parse(keyID); err != nil {
+		return apperr.NotFound("api_key_not_found", "API key not found")
+	}
+
+	result, err := s.db.ExecContext(ctx, "DELETE FROM api_keys WHERE id = $1 AND user_id = $2", keyID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return apperr.NotFound("api_key_not_found", "API key not found")
+	}
+	return nil
+}
+
+// Authenticate returns the API key matching secret
+func (s *APIKeyService) Authenticate(ctx context.Context, secret string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := s.db.GetContext(ctx, &key, "SELECT * FROM api_keys WHERE key_hash = $1", hashAPIKey(secret))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperr.Unauthorized("invalid_api_key", "Invalid API key")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up API key: %w", err)
+	}
+	s.applyDefaultQuota(&key)
+	return &key, nil
+}
+
+// applyDefaultQuota fills in the configured quota for keys without their own
+func (s *APIKeyService) applyDefaultQuota(key *models.APIKey) {
+	if key.DailyQuota == 0 {
+		key.DailyQuota = s.dailyQuota
+	}
+}
+
+// TakeQuota counts a request against the key's daily quota. Once the quota
+// is used up it returns a rate limited error with the wait until the quota
+// resets at UTC midnight. Quotas aren't enforced while Redis is unavailable.
+func (s *APIKeyService) TakeQuota(ctx context.Context, key *models.APIKey) error {
+	if !s.redis.Available() {
+		return nil
+	}
+
+	now := time.Now()
+	countKey := apiKeyCountKey(key.ID, usageDay(now))
+	count, err := s.redis.IncrementCounter(ctx, countKey)
+	if err != nil {
+		s.logger.Warn().Ctx(ctx).Err(err).Msg("Failed to count API key request")
+		return nil
+	}
+	if count == 1 {
+		if err := s.redis.SetExpiration(ctx, countKey, apiKeyUsageTTL); err != nil {
+			s.logger.Warn().Ctx(ctx).Err(err).Msg("Failed to expire API key counter")
+		}
+	}
+
+	if key.DailyQuota > 0 && count > int64(key.DailyQuota) {
+		midnight := now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+		return &QuotaExceededError{Quota: key.DailyQuota, RetryAfter: midnight.Sub(now)}
+	}
+	return nil
+}
+
+// QuotaExceededError reports that an API key used up its daily quota
+type QuotaExceededError struct {
+	Quota      int
+	RetryAfter time.Duration
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("API key daily quota of %d requests exceeded", e.Quota)
+}
+
+// RecordUsage counts a request to endpoint, such as "GET /api/v1/tracks/current",
+// for the key's usage report
+func (s *APIKeyService) RecordUsage(ctx context.Context, keyID, endpoint string) {
+	if !s.redis.Available() {
+		return
+	}
+
+	now := time.Now()
+	day := usageDay(now)
+	err := s.redis.Pipelined(ctx, func(pipe *database.Pipeline) {
+		pipe.HashIncrement(ctx, apiKeyUsageKey(keyID, day), endpoint, 1)
+		pipe.SetExpiration(ctx, apiKeyUsageKey(keyID, day), apiKeyUsageTTL)
+		pipe.AddToSet(ctx, apiKeyActiveKey(day), keyID)
+		pipe.SetExpiration(ctx, apiKeyActiveKey(day), apiKeyUsageTTL)
+		pipe.Set(ctx, apiKeyLastUsedKey(keyID), now.Unix(), apiKeyUsageTTL)
+	})
+	if err != nil {
+		s.logger.Warn().Ctx(ctx).Err(err).Msg("Failed to record API key usage")
+	}
+}
+
+// RollupUsage copies today's and yesterday's Redis usage counters for every
+// key used on those days into Postgres. Counters hold running daily totals,
+// so rolling up again is safe.
+func (s *APIKeyService) RollupUsage(ctx context.Context) error {
+	if !s.redis.Available() {
+		return nil
+	}
+
+	now := time.Now()
+	for _, day := range []string{usageDay(now.Add(-24 * time.Hour)), usageDay(now)} {
+		keyIDs, err := s.redis.GetSetMembers(ctx, apiKeyActiveKey(day))
+		if err != nil {
+			return fmt.Errorf("failed to list active API keys: %w", err)
+		}
+		for _, keyID := range keyIDs {
+			if err := s.rollupKey(ctx, keyID, day); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rollupKey copies one key's usage counters for day into Postgres
+func (s *APIKeyService) rollupKey(ctx context.Context, keyID, day string) error {
+	counts, err := s.redis.HashGetAll(ctx, apiKeyUsageKey(keyID, day))
+	if err != nil {
+		return fmt.Errorf("failed to read API key usage: %w", err)
+	}
+
+	for endpoint, value := range counts {
+		requests, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		// Keys revoked since are skipped by the join
+		_, err = s.db.ExecContext(ctx, `
+			INSERT INTO api_key_usage (key_id, day, endpoint, requests)
+			SELECT id, $2, $3, $4 FROM api_keys WHERE id = $1
+			ON CONFLICT (key_id, day, endpoint) DO UPDATE SET requests = GREATEST(api_key_usage.requests, EXCLUDED.requests)
+		`, keyID, day, endpoint, requests)
+		if err != nil {
+			return fmt.Errorf("failed to roll up API key usage: %w", err)
+		}
+	}
+
+	if lastUsed, err := s.redis.Get(ctx, apiKeyLastUsedKey(keyID)); err == nil {
+		if unix, err := strconv.ParseInt(lastUsed, 10, 64); err == nil {
+			_, err := s.db.ExecContext(ctx,
+				"UPDATE api_keys SET last_used_at = $1 WHERE id = $2 AND (last_used_at IS NULL OR last_used_at < $1)",
+				time.Unix(unix, 0), keyID)
+			if err != nil {
+				return fmt.Errorf("failed to update API key last use: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// GetUsage reports a key's requests per day and top endpoints over the last
+// days days, including today
+func (s *APIKeyService) GetUsage(ctx context.Context, key *models.APIKey, days int) (*models.APIKeyUsage, error) {
+	// Bring Postgres up to date with the live counters first
+	if s.redis.Available() {
+		now := time.Now()
+		for _, day := range []string{usageDay(now.Add(-24 * time.Hour)), usageDay(now)} {
+			if err := s.rollupKey(ctx, key.ID, day); err != nil {
+				s.logger.Warn().Ctx(ctx).Err(err).Msg("Failed to roll up API key usage")
+			}
+		}
+		if refreshed, err := s.GetKey(ctx, key.UserID, key.ID); err == nil {
+			key = refreshed
+		}
+	}
+
+	since := time.Now().UTC().AddDate(0, 0, -(days - 1)).Format(time.DateOnly)
+	usage := &models.APIKeyUsage{
+		KeyID:        key.ID,
+		DailyQuota:   key.DailyQuota,
+		LastUsedAt:   key.LastUsedAt,
+		Days:         []models.APIKeyDayUsage{},
+		TopEndpoints: []models.APIKeyEndpointUsage{},
+	}
+
+	err := s.db.SelectContext(ctx, &usage.Days, `
+		SELECT TO_CHAR(day, 'YYYY-MM-DD') AS day, SUM(requests) AS requests
+		FROM api_key_usage
+		WHERE key_id = $1 AND day >= $2
+		GROUP BY day
+		ORDER BY day
+	`, key.ID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key usage: %w", err)
+	}
+
+	err = s.db.SelectContext(ctx, &usage.TopEndpoints, `
+		SELECT endpoint, SUM(requests) AS requests
+		FROM api_key_usage
+		WHERE key_id = $1 AND day >= $2
+		GROUP BY endpoint
+		ORDER BY requests DESC, endpoint
+		LIMIT $3
+	`, key.ID, since, topEndpointLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key endpoints: %w", err)
+	}
+
+	today := usageDay(time.Now())
+	for _, day := range usage.Days {
+		if day.Day == today {
+			usage.RequestsToday = day.Requests
+		}
+	}
+	return usage, nil
+}
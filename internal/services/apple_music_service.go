@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/brandonhuynh1/whatamilisteningto-api/pkg/applemusic"
+	"github.com/rs/zerolog"
+)
+
+// AppleMusicTokenLifetime is how long a Music User Token is treated as valid.
+// Apple does not report an expiry or allow refreshing, so users reconnect
+// Apple Music once it passes or Apple starts rejecting the token.
+const AppleMusicTokenLifetime = 180 * 24 * time.Hour
+
+// appleMusicArtworkSize is the album art size requested, matching the
+// medium Spotify image
+const appleMusicArtworkSize = 300
+
+// appleMusicDisplayName is used for new Apple Music users, since Sign in with
+// Apple is used without the name scope
+const appleMusicDisplayName = "Apple Music Listener"
+
+// AppleMusicService signs users in with Sign in with Apple and reads their
+// listening from Apple Music. The Music User Token that grants access to a
+// user's library is obtained in the browser with MusicKit JS and stored as
+// the user's access token.
+type AppleMusicService struct {
+	client *applemusic.Client
+	logger zerolog.Logger
+}
+
+// NewAppleMusicService creates the Apple Music provider, or returns nil when
+// Apple Music is not configured
+func NewAppleMusicService(cfg config.AppleMusicConfig, logger zerolog.Logger) (*AppleMusicService, error) {
+	if cfg.TeamID == "" {
+		return nil, nil
+	}
+
+	key := []byte(cfg.PrivateKey)
+	if len(key) == 0 {
+		var err error
+		if key, err = os.ReadFile(cfg.PrivateKeyFile); err != nil {
+			return nil, fmt.Errorf("failed to read Apple private key: %w", err)
+		}
+	}
+	client, err := applemusic.NewClient(cfg.TeamID, cfg.KeyID, key, cfg.ServicesID, cfg.RedirectURI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Apple private key: %w", err)
+	}
+
+	return &AppleMusicService{
+		client: client,
+		logger: logger.With().Str("service", "apple_music").Logger(),
+	}, nil
+}
+
+// Name implements MusicProvider
+func (s *AppleMusicService) Name() string {
+	return ProviderAppleMusic
+}
+
+// GetAuthURL returns the Sign in with Apple URL
+func (s *AppleMusicService) GetAuthURL(_ context.Context, state string) string {
+	return s.client.GetAuthURL(state)
+}
+
+// ExchangeCodeForToken completes Sign in with Apple. The token identifies the
+// user but grants no Apple Music access, so AccessToken is empty until the
+// user connects Apple Music.
+func (s *AppleMusicService) ExchangeCodeForToken(ctx context.Context, code, _ string) (*ProviderToken, error) {
+	token, err := s.client.ExchangeCodeForToken(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	return &ProviderToken{
+		ExpiresIn: int(AppleMusicTokenLifetime.Seconds()),
+		IDToken:   token.IDToken,
+	}, nil
+}
+
+// GetAccount identifies the user from the Sign in with Apple ID token
+func (s *AppleMusicService) GetAccount(_ context.Context, token *ProviderToken) (*ProviderAccount, error) {
+	claims, err := s.client.ParseIDToken(token.IDToken)
+	if err != nil {
+		return nil, err
+	}
+	return &ProviderAccount{ID: claims.Subject, Email: claims.Email, DisplayName: appleMusicDisplayName}, nil
+}
+
+// RefreshUserToken fails because Music User Tokens cannot be refreshed; the
+// user has to connect Apple Music again
+func (s *AppleMusicService) RefreshUserToken(_ context.Context, _ *models.User) (*ProviderToken, error) {
+	return nil, apperr.Unauthorized("apple_music_reconnect_required", "Reconnect Apple Music to keep sharing")
+}
+
+// DeveloperToken returns the MusicKit developer token browsers need to
+// configure MusicKit JS
+func (s *AppleMusicService) DeveloperToken() (string, time.Time, error) {
+	return s.client.DeveloperToken()
+}
+
+// GetCurrentlyPlayingTrack returns the user's most recently played song as
+// playing. Apple Music does not expose playback state, so this is the best
+// available approximation. Users who have not connected Apple Music yet have
+// nothing playing.
+func (s *AppleMusicService) GetCurrentlyPlayingTrack(ctx context.Context, musicUserToken string) (*models.SpotifyCurrentlyPlaying, error) {
+	if musicUserToken == "" {
+		return &models.SpotifyCurrentlyPlaying{IsPlaying: false}, nil
+	}
+
+	tracks, err := s.client.GetRecentlyPlayed(ctx, musicUserToken, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(tracks) == 0 {
+		return &models.SpotifyCurrentlyPlaying{IsPlaying: false}, nil
+	}
+
+	track := appleMusicTrack(tracks[0])
+	track.IsPlaying = true
+	return &track, nil
+}
+
+// GetRecentlyPlayed returns the user's recently played songs, newest first.
+// Apple Music does not report when each was played, so PlayedAt is zero.
+func (s *AppleMusicService) GetRecentlyPlayed(ctx context.Context, musicUserToken string, limit int) ([]PlayedTrack, error) {
+	if musicUserToken == "" {
+		return nil, nil
+	}
+
+	tracks, err := s.client.GetRecentlyPlayed(ctx, musicUserToken, limit)
+	if err != nil {
+		return nil, err
+	}
+	played := make([]PlayedTrack, 0, len(tracks))
+	for _, track := range tracks {
+		played = append(played, PlayedTrack{Track: appleMusicTrack(track)})
+	}
+	return played, nil
+}
+
+// appleMusicTrack converts an Apple Music song to the shared track shape
+func appleMusicTrack(track applemusic.Track) models.SpotifyCurrentlyPlaying {
+	return models.SpotifyCurrentlyPlaying{
+		TrackID:     track.ID,
+		TrackName:   track.Attributes.Name,
+		ArtistName:  track.Attributes.ArtistName,
+		AlbumName:   track.Attributes.AlbumName,
+		AlbumArtURL: track.ArtworkURL(appleMusicArtworkSize),
+		TrackURL:    track.Attributes.URL,
+		DurationMs:  track.Attributes.DurationInMillis,
+	}
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/brandonhuynh1/whatamilisteningto-api/pkg/spotify"
+	"github.com/lib/pq"
+)
+
+// spotifyTrackIDPattern matches Spotify track IDs, leaving out tracks from
+// media servers and other providers
+const spotifyTrackIDPattern = `'^[0-9A-Za-z]{22}$'`
+
+// EnrichAudioFeatures fetches audio features for tracks in Spotify users'
+// history that don't have them yet, up to spotify.MaxAudioFeatureIDs tracks
+// per user, most recently played first. Users whose request fails are
+// skipped until the next run. It returns the number of tracks enriched.
+func (s *ProfileService) EnrichAudioFeatures(ctx context.Context, userService *UserService) (int, error) {
+	var users []models.User
+	err := s.db.SelectContext(ctx, &users, `
+		SELECT * FROM users u
+		WHERE u.is_active = true AND u.provider = $1 AND EXISTS (
+			SELECT 1 FROM tracks t
+			LEFT JOIN track_features f ON f.spotify_track_id = t.spotify_track_id
+			WHERE t.user_id = u.id AND f.spotify_track_id IS NULL
+				AND t.spotify_track_id ~ `+spotifyTrackIDPattern+`
+		)
+		ORDER BY u.id
+	`, ProviderSpotify)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list users to enrich: %w", err)
+	}
+
+	total := 0
+	for i := range users {
+		enriched, err := s.enrichUserAudioFeatures(ctx, &users[i], userService)
+		total += enriched
+		if ctx.Err() != nil {
+			return total, ctx.Err()
+		}
+		var rateLimited *ProviderRateLimitError
+		switch {
+		case errors.As(err, &rateLimited):
+			// Every user shares the app's limit, so the rest would fail too
+			s.logger.Debug().Dur("retry_after", rateLimited.RetryAfter).Msg("Spotify rate limited, pausing audio feature enrichment")
+			return total, nil
+		case err != nil:
+			s.logger.Warn().Err(err).Str("user_id", users[i].ID).Msg("Failed to enrich audio features")
+		}
+	}
+	return total, nil
+}
+
+// enrichUserAudioFeatures fetches and stores features for one batch of the
+// user's tracks that don't have them
+func (s *ProfileService) enrichUserAudioFeatures(ctx context.Context, user *models.User, userService *UserService) (int, error) {
+	var trackIDs []string
+	err := s.db.SelectContext(ctx, &trackIDs, `
+		SELECT t.spotify_track_id
+		FROM tracks t
+		LEFT JOIN track_features f ON f.spotify_track_id = t.spotify_track_id
+		WHERE t.user_id = $1 AND f.spotify_track_id IS NULL
+			AND t.spotify_track_id ~ `+spotifyTrackIDPattern+`
+		GROUP BY t.spotify_track_id
+		ORDER BY MAX(t.played_at) DESC
+		LIMIT $2
+	`, user.ID, spotify.MaxAudioFeatureIDs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list tracks without audio features: %w", err)
+	}
+	if len(trackIDs) == 0 {
+		return 0, nil
+	}
+
+	provider, err := s.providers.ForUser(user)
+	if err != nil {
+		return 0, err
+	}
+	s.refreshExpiredToken(ctx, provider, user, userService)
+
+	features, err := s.spotifyService.GetAudioFeatures(s.spotifyService.WithUserTenant(ctx, user), user.SpotifyAccessToken, trackIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.saveAudioFeatures(ctx, trackIDs, features); err != nil {
+		return 0, err
+	}
+	return len(features), nil
+}
+
+// saveAudioFeatures stores the features fetched for trackIDs, recording the
+// tracks Spotify had none for with NULL features
+func (s *ProfileService) saveAudioFeatures(ctx context.Context, trackIDs []string, features []models.TrackFeatures) error {
+	ids := make([]string, len(features))
+	tempo := make([]float64, len(features))
+	energy := make([]float64, len(features))
+	danceability := make([]float64, len(features))
+	valence := make([]float64, len(features))
+	for i, f := range features {
+		ids[i], tempo[i], energy[i], danceability[i], valence[i] = f.SpotifyTrackID, f.Tempo, f.Energy, f.Danceability, f.Valence
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO track_features (spotify_track_id, tempo, energy, danceability, valence, fetched_at)
+		SELECT requested.id, f.tempo, f.energy, f.danceability, f.valence, NOW()
+		FROM UNNEST($1::text[]) AS requested(id)
+		LEFT JOIN UNNEST($2::text[], $3::float8[], $4::float8[], $5::float8[], $6::float8[])
+			AS f(id, tempo, energy, danceability, valence) ON f.id = requested.id
+		ON CONFLICT (spotify_track_id) DO UPDATE SET
+			tempo = EXCLUDED.tempo,
+			energy = EXCLUDED.energy,
+			danceability = EXCLUDED.danceability,
+			valence = EXCLUDED.valence,
+			fetched_at = EXCLUDED.fetched_at
+	`, pq.Array(trackIDs), pq.Array(ids), pq.Array(tempo), pq.Array(energy), pq.Array(danceability), pq.Array(valence))
+	if err != nil {
+		return fmt.Errorf("failed to save audio features: %w", err)
+	}
+	return nil
+}
+
+// GetMoodStats breaks down how the tracks a user played from from until to
+// sound. A zero to leaves the range open.
+func (s *ProfileService) GetMoodStats(ctx context.Context, userID string, from, to time.Time) (*models.MoodStats, error) {
+	var row struct {
+		Plays               int      `db:"plays"`
+		PlaysAnalyzed       int      `db:"plays_analyzed"`
+		AverageTempo        *float64 `db:"average_tempo"`
+		AverageEnergy       *float64 `db:"average_energy"`
+		AverageDanceability *float64 `db:"average_danceability"`
+		AverageValence      *float64 `db:"average_valence"`
+		EnergyLow           int      `db:"energy_low"`
+		EnergyMedium        int      `db:"energy_medium"`
+		EnergyHigh          int      `db:"energy_high"`
+		Happy               int      `db:"happy"`
+		Calm                int      `db:"calm"`
+		Intense             int      `db:"intense"`
+		Melancholy          int      `db:"melancholy"`
+	}
+	err := s.db.GetContext(ctx, &row, `
+		SELECT COUNT(*) AS plays,
+			COUNT(f.energy) AS plays_analyzed,
+			AVG(f.tempo) AS average_tempo,
+			AVG(f.energy) AS average_energy,
+			AVG(f.danceability) AS average_danceability,
+			AVG(f.valence) AS average_valence,
+			COUNT(*) FILTER (WHERE f.energy < 0.33) AS energy_low,
+			COUNT(*) FILTER (WHERE f.energy >= 0.33 AND f.energy < 0.66) AS energy_medium,
+			COUNT(*) FILTER (WHERE f.energy >= 0.66) AS energy_high,
+			COUNT(*) FILTER (WHERE f.valence >= 0.5 AND f.energy >= 0.5) AS happy,
+			COUNT(*) FILTER (WHERE f.valence >= 0.5 AND f.energy < 0.5) AS calm,
+			COUNT(*) FILTER (WHERE f.valence < 0.5 AND f.energy >= 0.5) AS intense,
+			COUNT(*) FILTER (WHERE f.valence < 0.5 AND f.energy < 0.5) AS melancholy
+		FROM tracks t
+		LEFT JOIN track_features f ON f.spotify_track_id = t.spotify_track_id
+		WHERE t.user_id = $1 AND t.is_currently_playing = false AND t.played_at >= $2
+			AND ($3::timestamptz IS NULL OR t.played_at < $3)
+	`, userID, from, rangeEnd(to))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mood stats: %w", err)
+	}
+
+	return &models.MoodStats{
+		Plays:               row.Plays,
+		PlaysAnalyzed:       row.PlaysAnalyzed,
+		AverageTempo:        row.AverageTempo,
+		AverageEnergy:       row.AverageEnergy,
+		AverageDanceability: row.AverageDanceability,
+		AverageValence:      row.AverageValence,
+		Energy:              models.EnergyLevels{Low: row.EnergyLow, Medium: row.EnergyMedium, High: row.EnergyHigh},
+		Moods:               models.MoodQuadrants{Happy: row.Happy, Calm: row.Calm, Intense: row.Intense, Melancholy: row.Melancholy},
+	}, nil
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/database"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// badgeArtSize is the size, in pixels, of album art embedded in badges
+	badgeArtSize = 64
+
+	// badgeLineRunes is how many characters of a track or artist name fit on
+	// a badge before it's cut short
+	badgeLineRunes = 30
+
+	defaultBadgeBackground = "#121212"
+	defaultBadgeText       = "#ffffff"
+)
+
+// BadgeService renders SVG "now playing" badges for READMEs and blogs. Sites
+// like GitHub proxy embedded images and block anything an SVG loads itself,
+// so album art is inlined rather than linked.
+type BadgeService struct {
+	profileService *ProfileService
+	albumArt       *AlbumArtService
+	redis          *database.RedisClient
+	logger         zerolog.Logger
+}
+
+// NewBadgeService creates the badge service. albumArt may be nil, in which
+// case badges are rendered without art.
+func NewBadgeService(profileService *ProfileService, albumArt *AlbumArtService, redis *database.RedisClient, logger zerolog.Logger) *BadgeService {
+	return &BadgeService{
+		profileService: profileService,
+		albumArt:       albumArt,
+		redis:          redis,
+		logger:         logger.With().Str("service", "badge").Logger(),
+	}
+}
+
+// badgeCacheKey includes when playback last changed, so a cached badge is
+// never older than the Last-Modified it's served with
+func badgeCacheKey(userID string, nowPlaying *models.SpotifyCurrentlyPlaying) string {
+	return fmt.Sprintf("badge:%s:%d", userID, nowPlaying.ChangedAt)
+}
+
+// RenderBadge returns a user's now playing badge as SVG, in their profile's
+// colors. Rendered badges are cached in Redis for ttl.
+func (s *BadgeService) RenderBadge(ctx context.Context, user *models.User, nowPlaying *models.SpotifyCurrentlyPlaying, ttl time.Duration) ([]byte, error) {
+	key := badgeCacheKey(user.ID, nowPlaying)
+	if s.redis.Available() {
+		if data, err := s.redis.Get(ctx, key); err == nil {
+			return []byte(data), nil
+		}
+	}
+
+	profile, err := s.profileService.GetProfile(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var art string
+	if nowPlaying.IsPlaying && nowPlaying.TrackID != "" && s.albumArt != nil {
+		data, err := s.albumArt.GetAlbumArt(ctx, nowPlaying.TrackID, badgeArtSize)
+		if err != nil {
+			s.logger.Debug().Ctx(ctx).Err(err).Str("track_id", nowPlaying.TrackID).Msg("Rendering badge without album art")
+		} else {
+			art = "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(data)
+		}
+	}
+
+	svg := renderBadge(user, profile, nowPlaying, art)
+
+	if s.redis.Available() && ttl > 0 {
+		if err := s.redis.Set(ctx, key, svg, ttl); err != nil {
+			s.logger.Debug().Ctx(ctx).Err(err).Msg("Failed to cache badge")
+		}
+	}
+	return svg, nil
+}
+
+// renderBadge draws the badge. art is a data URI, or empty to draw a
+// placeholder in its place.
+func renderBadge(user *models.User, profile *models.Profile, nowPlaying *models.SpotifyCurrentlyPlaying, art string) []byte {
+	background := profile.BackgroundColor
+	if background == "" {
+		background = defaultBadgeBackground
+	}
+	text := profile.TextColor
+	if text == "" {
+		text = defaultBadgeText
+	}
+
+	heading := fmt.Sprintf("%s is listening to", user.DisplayName)
+	title, subtitle := nowPlaying.TrackName, nowPlaying.ArtistName
+	label := fmt.Sprintf("%s is listening to %s by %s", user.DisplayName, title, subtitle)
+	if !nowPlaying.IsPlaying {
+		heading = user.DisplayName
+		title, subtitle = "Not listening right now", ""
+		label = fmt.Sprintf("%s is not listening right now", user.DisplayName)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="400" height="96" viewBox="0 0 400 96" role="img" aria-label="%s">`, badgeText(label, 0))
+	fmt.Fprintf(&b, `<title>%s</title>`, badgeText(label, 0))
+	fmt.Fprintf(&b, `<rect width="400" height="96" rx="12" fill="%s"/>`, html.EscapeString(background))
+	if art != "" {
+		fmt.Fprintf(&b, `<image x="16" y="16" width="64" height="64" preserveAspectRatio="xMidYMid slice" xlink:href="%s"/>`, art)
+	} else {
+		fmt.Fprintf(&b, `<rect x="16" y="16" width="64" height="64" rx="6" fill="%s" fill-opacity="0.15"/>`, html.EscapeString(text))
+	}
+	fmt.Fprintf(&b, `<g fill="%s" font-family="-apple-system,BlinkMacSystemFont,'Segoe UI',Helvetica,Arial,sans-serif">`, html.EscapeString(text))
+	fmt.Fprintf(&b, `<text x="96" y="34" font-size="11" opacity="0.7">%s</text>`, badgeText(heading, badgeLineRunes))
+	fmt.Fprintf(&b, `<text x="96" y="56" font-size="16" font-weight="600">%s</text>`, badgeText(title, badgeLineRunes))
+	if subtitle != "" {
+		fmt.Fprintf(&b, `<text x="96" y="76" font-size="13" opacity="0.8">%s</text>`, badgeText(subtitle, badgeLineRunes))
+	}
+	b.WriteString(`</g></svg>`)
+	return b.Bytes()
+}
+
+// badgeText escapes s for SVG, first cutting it to limit characters when
+// limit is positive
+func badgeText(s string, limit int) string {
+	s = strings.TrimSpace(s)
+	if runes := []rune(s); limit > 0 && len(runes) > limit {
+		s = strings.TrimSpace(string(runes[:limit-1])) + "…"
+	}
+	return html.EscapeString(s)
+}
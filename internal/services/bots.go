@@ -0,0 +1,65 @@
+package services
+
+import "strings"
+
+// botUserAgents are lowercase fragments of the user agents search engine
+// crawlers, link preview fetchers, monitors, and HTTP libraries send.
+// Generic words like "bot" and "spider" catch most crawlers; the rest name
+// fetchers that don't use them.
+var botUserAgents = []string{
+	"bot",
+	"crawl",
+	"spider",
+	"slurp",
+	"facebookexternalhit",
+	"facebookcatalog",
+	"meta-externalagent",
+	"embedly",
+	"quora link preview",
+	"whatsapp",
+	"skypeuripreview",
+	"vkshare",
+	"pinterest",
+	"iframely",
+	"outbrain",
+	"nuzzel",
+	"google-inspectiontool",
+	"google-read-aloud",
+	"mediapartners-google",
+	"feedfetcher",
+	"headlesschrome",
+	"phantomjs",
+	"lighthouse",
+	"pingdom",
+	"uptime",
+	"monitor",
+	"curl/",
+	"wget/",
+	"python-requests",
+	"python-urllib",
+	"aiohttp",
+	"go-http-client",
+	"okhttp",
+	"java/",
+	"axios/",
+	"node-fetch",
+	"libwww-perl",
+	"httpclient",
+	"scrapy",
+}
+
+// IsBot reports whether a user agent belongs to a crawler, link preview
+// fetcher, or script rather than a person. A missing user agent counts as a
+// bot, since browsers always send one.
+func IsBot(userAgent string) bool {
+	userAgent = strings.ToLower(strings.TrimSpace(userAgent))
+	if userAgent == "" {
+		return true
+	}
+	for _, fragment := range botUserAgents {
+		if strings.Contains(userAgent, fragment) {
+			return true
+		}
+	}
+	return false
+}
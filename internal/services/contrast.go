@@ -0,0 +1,153 @@
+package services
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// MinContrastRatio is the WCAG AA contrast ratio required for normal text
+const MinContrastRatio = 4.5
+
+// Palette is a background and text color pair with its contrast ratio
+type Palette struct {
+	BackgroundColor string  `json:"background_color"`
+	TextColor       string  `json:"text_color"`
+	ContrastRatio   float64 `json:"contrast_ratio"`
+}
+
+// rgb is a color with channels from 0 to 1
+type rgb struct {
+	r, g, b float64
+}
+
+// parseHexColor parses #rgb, #rgba, #rrggbb, or #rrggbbaa. Profiles are
+// drawn on an opaque page, so alpha is ignored.
+func parseHexColor(hex string) (rgb, error) {
+	digits := strings.TrimPrefix(hex, "#")
+	switch len(digits) {
+	case 3, 4:
+		digits = string([]byte{digits[0], digits[0], digits[1], digits[1], digits[2], digits[2]})
+	case 6, 8:
+		digits = digits[:6]
+	default:
+		return rgb{}, fmt.Errorf("invalid hex color %q", hex)
+	}
+
+	value, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil {
+		return rgb{}, fmt.Errorf("invalid hex color %q", hex)
+	}
+	return rgb{
+		r: float64(value>>16&0xff) / 255,
+		g: float64(value>>8&0xff) / 255,
+		b: float64(value&0xff) / 255,
+	}, nil
+}
+
+// rgba converts the color for drawing images
+func (c rgb) rgba() color.RGBA {
+	channel := func(v float64) uint8 { return uint8(math.Round(v * 255)) }
+	return color.RGBA{R: channel(c.r), G: channel(c.g), B: channel(c.b), A: 0xff}
+}
+
+func (c rgb) hex() string {
+	channel := func(v float64) int { return int(math.Round(v * 255)) }
+	return fmt.Sprintf("#%02x%02x%02x", channel(c.r), channel(c.g), channel(c.b))
+}
+
+// luminance is the WCAG relative luminance of the color
+func (c rgb) luminance() float64 {
+	linear := func(v float64) float64 {
+		if v <= 0.03928 {
+			return v / 12.92
+		}
+		return math.Pow((v+0.055)/1.055, 2.4)
+	}
+	return 0.2126*linear(c.r) + 0.7152*linear(c.g) + 0.0722*linear(c.b)
+}
+
+// mix moves the color a fraction t of the way to target
+func (c rgb) mix(target rgb, t float64) rgb {
+	return rgb{
+		r: c.r + (target.r-c.r)*t,
+		g: c.g + (target.g-c.g)*t,
+		b: c.b + (target.b-c.b)*t,
+	}
+}
+
+func contrast(a, b rgb) float64 {
+	la, lb := a.luminance(), b.luminance()
+	return (max(la, lb) + 0.05) / (min(la, lb) + 0.05)
+}
+
+// RoundContrastRatio rounds a contrast ratio down to two decimals for
+// display, so a failing ratio never shows as 4.50
+func RoundContrastRatio(ratio float64) float64 {
+	return math.Floor(ratio*100) / 100
+}
+
+// ContrastRatio returns the WCAG contrast ratio between two hex colors,
+// from 1 (identical) to 21 (black on white)
+func ContrastRatio(background, text string) (float64, error) {
+	bg, err := parseHexColor(background)
+	if err != nil {
+		return 0, err
+	}
+	fg, err := parseHexColor(text)
+	if err != nil {
+		return 0, err
+	}
+	return contrast(bg, fg), nil
+}
+
+// SuggestPalettes returns accessible alternatives to a color pair: one that
+// keeps the background and adjusts the text, and one that keeps the text and
+// adjusts the background. Each changes its color as little as possible, by
+// darkening or lightening it, to reach MinContrastRatio. A pair that
+// already passes is returned unchanged.
+func SuggestPalettes(background, text string) ([]Palette, error) {
+	bg, err := parseHexColor(background)
+	if err != nil {
+		return nil, err
+	}
+	fg, err := parseHexColor(text)
+	if err != nil {
+		return nil, err
+	}
+
+	if ratio := contrast(bg, fg); ratio >= MinContrastRatio {
+		return []Palette{{BackgroundColor: bg.hex(), TextColor: fg.hex(), ContrastRatio: RoundContrastRatio(ratio)}}, nil
+	}
+
+	adjustedText := adjustForContrast(fg, bg)
+	adjustedBackground := adjustForContrast(bg, fg)
+	return []Palette{
+		{BackgroundColor: bg.hex(), TextColor: adjustedText.hex(), ContrastRatio: RoundContrastRatio(contrast(bg, adjustedText))},
+		{BackgroundColor: adjustedBackground.hex(), TextColor: fg.hex(), ContrastRatio: RoundContrastRatio(contrast(adjustedBackground, fg))},
+	}, nil
+}
+
+// adjustForContrast moves c toward black or white, whichever contrasts more
+// with fixed, just far enough to reach MinContrastRatio. Every color has at
+// least 4.58:1 against black or white, so the target is always reachable.
+func adjustForContrast(c, fixed rgb) rgb {
+	black, white := rgb{}, rgb{r: 1, g: 1, b: 1}
+	target := white
+	if contrast(fixed, black) > contrast(fixed, white) {
+		target = black
+	}
+
+	// Contrast can dip before it rises (a lighter color moving toward black
+	// passes the fixed color's luminance), so walk the whole way, checking
+	// each candidate after rounding to hex
+	for step := 1; step < 200; step++ {
+		adjusted, _ := parseHexColor(c.mix(target, float64(step)/200).hex())
+		if contrast(fixed, adjusted) >= MinContrastRatio {
+			return adjusted
+		}
+	}
+	return target
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/brandonhuynh1/whatamilisteningto-api/pkg/deezer"
+	"github.com/rs/zerolog"
+)
+
+// deezerOfflineTokenLifetime is recorded for tokens granted offline_access,
+// which Deezer never expires
+const deezerOfflineTokenLifetime = 10 * 365 * 24 * time.Hour
+
+// deezerPlayingGrace keeps the latest history entry playing for a moment
+// past its duration, covering the gap before the next track is recorded
+const deezerPlayingGrace = 30 * time.Second
+
+// DeezerService signs users in with Deezer and reads their listening
+// history. Deezer has no playback state API, so the latest history entry is
+// treated as playing until its duration has passed.
+type DeezerService struct {
+	client  *deezer.Client
+	perms   []string
+	backoff *rateLimitBackoff
+	logger  zerolog.Logger
+}
+
+// NewDeezerService creates the Deezer provider, or returns nil when Deezer is
+// not configured
+func NewDeezerService(cfg config.DeezerConfig, logger zerolog.Logger) *DeezerService {
+	if cfg.AppID == "" {
+		return nil
+	}
+	return &DeezerService{
+		client:  deezer.NewClient(cfg.AppID, cfg.Secret, cfg.RedirectURI),
+		perms:   cfg.Perms,
+		backoff: &rateLimitBackoff{provider: ProviderDeezer},
+		logger:  logger.With().Str("service", "deezer").Logger(),
+	}
+}
+
+// Name implements MusicProvider
+func (s *DeezerService) Name() string {
+	return ProviderDeezer
+}
+
+// GetAuthURL returns the Deezer authorization URL
+func (s *DeezerService) GetAuthURL(_ context.Context, state string) string {
+	return s.client.GetAuthURL(state, s.perms)
+}
+
+// ExchangeCodeForToken exchanges an authorization code for an access token
+func (s *DeezerService) ExchangeCodeForToken(ctx context.Context, code, _ string) (*ProviderToken, error) {
+	if err := s.backoff.check(); err != nil {
+		return nil, err
+	}
+	token, err := s.client.ExchangeCodeForToken(ctx, code)
+	if err != nil {
+		return nil, s.observe(err)
+	}
+
+	expiresIn := token.Expires
+	if expiresIn == 0 {
+		expiresIn = int(deezerOfflineTokenLifetime.Seconds())
+	}
+	return &ProviderToken{AccessToken: token.AccessToken, ExpiresIn: expiresIn}, nil
+}
+
+// GetAccount gets the Deezer user a token belongs to
+func (s *DeezerService) GetAccount(ctx context.Context, token *ProviderToken) (*ProviderAccount, error) {
+	if err := s.backoff.check(); err != nil {
+		return nil, err
+	}
+	user, err := s.client.GetUser(ctx, token.AccessToken)
+	if err != nil {
+		return nil, s.observe(err)
+	}
+	return &ProviderAccount{
+		ID:          strconv.FormatInt(user.ID, 10),
+		Email:       user.Email,
+		DisplayName: user.Name,
+	}, nil
+}
+
+// RefreshUserToken fails because Deezer has no refresh tokens; users signed
+// in without offline_access have to sign in again
+func (s *DeezerService) RefreshUserToken(_ context.Context, _ *models.User) (*ProviderToken, error) {
+	return nil, apperr.Unauthorized("deezer_reconnect_required", "Sign in with Deezer again to keep sharing")
+}
+
+// GetCurrentlyPlayingTrack returns the latest history entry, playing while
+// its duration has not yet passed
+func (s *DeezerService) GetCurrentlyPlayingTrack(ctx context.Context, accessToken string) (*models.SpotifyCurrentlyPlaying, error) {
+	played, err := s.GetRecentlyPlayed(ctx, accessToken, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(played) == 0 {
+		return &models.SpotifyCurrentlyPlaying{IsPlaying: false}, nil
+	}
+
+	track := played[0].Track
+	elapsed := time.Since(played[0].PlayedAt)
+	duration := time.Duration(track.DurationMs) * time.Millisecond
+	if elapsed < duration+deezerPlayingGrace {
+		track.IsPlaying = true
+		track.ProgressMs = int(min(elapsed, duration).Milliseconds())
+	}
+	return &track, nil
+}
+
+// GetRecentlyPlayed returns the user's listening history, newest first
+func (s *DeezerService) GetRecentlyPlayed(ctx context.Context, accessToken string, limit int) ([]PlayedTrack, error) {
+	if err := s.backoff.check(); err != nil {
+		return nil, err
+	}
+	tracks, err := s.client.GetHistory(ctx, accessToken, limit)
+	if err != nil {
+		return nil, s.observe(err)
+	}
+
+	played := make([]PlayedTrack, 0, len(tracks))
+	for _, track := range tracks {
+		played = append(played, PlayedTrack{
+			Track: models.SpotifyCurrentlyPlaying{
+				TrackID:     strconv.FormatInt(track.ID, 10),
+				TrackName:   track.Title,
+				ArtistName:  track.Artist.Name,
+				AlbumName:   track.Album.Title,
+				AlbumArtURL: track.Album.CoverMedium,
+				TrackURL:    track.Link,
+				DurationMs:  track.Duration * 1000,
+			},
+			PlayedAt: time.Unix(track.Timestamp, 0),
+		})
+	}
+	return played, nil
+}
+
+// observe starts a backoff when Deezer reports its quota exceeded, and
+// reports revoked tokens as needing a new sign-in
+func (s *DeezerService) observe(err error) error {
+	var rateLimited *deezer.RateLimitError
+	switch {
+	case errors.As(err, &rateLimited):
+		s.logger.Warn().Dur("retry_after", rateLimited.RetryAfter).Msg("Deezer request quota exceeded")
+		return s.backoff.limit(rateLimited.RetryAfter)
+	case errors.Is(err, deezer.ErrInvalidToken):
+		return apperr.Unauthorized("deezer_reconnect_required", "Sign in with Deezer again to keep sharing").Wrap(err)
+	}
+	return err
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/google/uuid"
+)
+
+const (
+	// backfillLimit is how many recent plays are requested per user, the
+	// most Spotify returns at once
+	backfillLimit = 50
+
+	// backfillSlack absorbs the difference between when polling saw a play
+	// and when the provider says it happened
+	backfillSlack = 2 * time.Minute
+)
+
+// BackfillHistory merges every sharing user's recently played tracks into
+// their history, so plays nobody was watching still show up. Users whose
+// provider fails are skipped until the next run. It returns the number of
+// plays added.
+func (s *ProfileService) BackfillHistory(ctx context.Context, userService *UserService) (int, error) {
+	var users []models.User
+	err := s.db.SelectContext(ctx, &users,
+		"SELECT * FROM users WHERE is_active = true AND is_sharing_enabled = true ORDER BY id")
+	if err != nil {
+		return 0, fmt.Errorf("failed to list users to backfill: %w", err)
+	}
+
+	total := 0
+	for i := range users {
+		added, err := s.BackfillRecentlyPlayed(ctx, &users[i], userService)
+		total += added
+		if ctx.Err() != nil {
+			return total, ctx.Err()
+		}
+		var rateLimited *ProviderRateLimitError
+		switch {
+		case errors.As(err, &rateLimited):
+			s.logger.Debug().Str("user_id", users[i].ID).Dur("retry_after", rateLimited.RetryAfter).Msg("Provider rate limited, skipping backfill")
+		case err != nil:
+			s.logger.Warn().Err(err).Str("user_id", users[i].ID).Msg("Failed to backfill listening history")
+		}
+	}
+	return total, nil
+}
+
+// BackfillRecentlyPlayed adds the user's recently played tracks that aren't
+// in their history yet, oldest first, as counted plays. Plays are matched to
+// existing history by track and time, so plays already seen while polling
+// aren't added twice. Providers that don't report when a track was played
+// are skipped. It returns the number of plays added.
+func (s *ProfileService) BackfillRecentlyPlayed(ctx context.Context, user *models.User, userService *UserService) (int, error) {
+	provider, err := s.providers.ForUser(user)
+	if err != nil {
+		return 0, err
+	}
+	s.refreshExpiredToken(ctx, provider, user, userService)
+
+	played, err := provider.GetRecentlyPlayed(s.spotifyService.WithUserTenant(ctx, user), user.SpotifyAccessToken, backfillLimit)
+	if err != nil {
+		return 0, err
+	}
+
+	added := 0
+	for i := len(played) - 1; i >= 0; i-- {
+		play := played[i]
+		if play.PlayedAt.IsZero() || play.Track.TrackID == "" {
+			continue
+		}
+
+		// Spotify reports when a play finished
+		endedAt := play.PlayedAt
+		startedAt := endedAt.Add(-time.Duration(play.Track.DurationMs) * time.Millisecond)
+		known, err := s.playRecorded(ctx, user.ID, play.Track.TrackID, startedAt, endedAt)
+		if err != nil {
+			return added, err
+		}
+		if known {
+			continue
+		}
+
+		if err := s.recordBackfilledPlay(ctx, user.ID, &play.Track, startedAt, endedAt); err != nil {
+			return added, err
+		}
+		added++
+	}
+
+	if added > 0 {
+		s.logger.Debug().Str("user_id", user.ID).Int("plays", added).Msg("Backfilled listening history")
+	}
+	return added, nil
+}
+
+// playRecorded reports whether history already has a play of trackID
+// overlapping startedAt to endedAt, either still in tracks or finished in
+// play_events
+func (s *ProfileService) playRecorded(ctx context.Context, userID, trackID string, startedAt, endedAt time.Time) (bool, error) {
+	var known bool
+	err := s.db.GetContext(ctx, &known, `
+		SELECT EXISTS (
+			SELECT 1 FROM tracks
+			WHERE user_id = $1 AND spotify_track_id = $2 AND created_at <= $4 AND played_at >= $3
+		) OR EXISTS (
+			SELECT 1 FROM play_events
+			WHERE user_id = $1 AND spotify_track_id = $2 AND started_at <= $4 AND ended_at >= $3
+		)
+	`, userID, trackID, startedAt.Add(-backfillSlack), endedAt.Add(backfillSlack))
+	if err != nil {
+		return false, fmt.Errorf("failed to check history for play: %w", err)
+	}
+	return known, nil
+}
+
+// recordBackfilledPlay saves a finished play to history with its play event
+func (s *ProfileService) recordBackfilledPlay(ctx context.Context, userID string, track *models.SpotifyCurrentlyPlaying, startedAt, endedAt time.Time) error {
+	_, err := s.db.NamedExecContext(ctx, `
+		INSERT INTO tracks (
+			id, user_id, spotify_track_id, name, artist, album, album_art_url,
+			track_url, duration_ms, is_currently_playing, played_at, created_at
+		) VALUES (
+			:id, :user_id, :spotify_track_id, :name, :artist, :album, :album_art_url,
+			:track_url, :duration_ms, :is_currently_playing, :played_at, :created_at
+		)
+	`, models.Track{
+		ID:             uuid.New().String(),
+		UserID:         userID,
+		SpotifyTrackID: track.TrackID,
+		Name:           track.TrackName,
+		Artist:         track.ArtistName,
+		Album:          track.AlbumName,
+		AlbumArtURL:    track.AlbumArtURL,
+		TrackURL:       track.TrackURL,
+		DurationMs:     track.DurationMs,
+		PlayedAt:       endedAt,
+		CreatedAt:      startedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to insert backfilled track: %w", err)
+	}
+
+	// Spotify only lists plays of 30 seconds or more, so each is taken as a
+	// full listen
+	return s.recordPlayEvent(ctx, &models.PlayEvent{
+		ID:             uuid.New().String(),
+		UserID:         userID,
+		SpotifyTrackID: track.TrackID,
+		Name:           track.TrackName,
+		Artist:         track.ArtistName,
+		DurationMs:     track.DurationMs,
+		ListenedMs:     track.DurationMs,
+		Counted:        true,
+		StartedAt:      startedAt,
+		EndedAt:        endedAt,
+	}, track.AlbumName)
+}
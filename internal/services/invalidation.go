@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/database"
+	"github.com/rs/zerolog"
+)
+
+// WatchCacheInvalidations listens for track and profile change events on Redis
+// pub/sub and drops the matching in-process hot cache entries. It blocks until
+// ctx is cancelled.
+func WatchCacheInvalidations(ctx context.Context, redis *database.RedisClient, spotifyService *SpotifyService, profileService *ProfileService, logger zerolog.Logger) {
+	logger = logger.With().Str("service", "cache-invalidation").Logger()
+
+	pubsub := redis.PatternSubscribe(ctx, "track:updates:*", "profile:updates:*")
+	defer pubsub.Close()
+	ch := pubsub.Channel()
+
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			// Channels look like [prefix]track:updates:<userID>
+			userID := msg.Channel[strings.LastIndex(msg.Channel, ":")+1:]
+			switch {
+			case strings.Contains(msg.Channel, "track:updates:"):
+				spotifyService.InvalidateHotTrack(userID)
+			case strings.Contains(msg.Channel, "profile:updates:"):
+				profileService.InvalidateHotProfile(userID)
+			}
+			logger.Debug().Str("channel", msg.Channel).Msg("Invalidated hot cache entry")
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/google/uuid"
+)
+
+// keysetQuery builds a query for one of a user's tables paged newest first
+// on (timeColumn, id), so inserts between requests don't shift results
+type keysetQuery struct {
+	table      string
+	timeColumn string
+	conditions []string
+	args       []interface{}
+}
+
+func newKeysetQuery(table, timeColumn, userID string) *keysetQuery {
+	return &keysetQuery{
+		table:      table,
+		timeColumn: timeColumn,
+		conditions: []string{"user_id = $1"},
+		args:       []interface{}{userID},
+	}
+}
+
+// where adds a condition, replacing each %s in format with a placeholder
+// for the matching value
+func (q *keysetQuery) where(format string, values ...interface{}) {
+	placeholders := make([]interface{}, len(values))
+	for i, value := range values {
+		q.args = append(q.args, value)
+		placeholders[i] = "$" + strconv.Itoa(len(q.args))
+	}
+	q.conditions = append(q.conditions, fmt.Sprintf(format, placeholders...))
+}
+
+// after limits the query to rows past a cursor from a previous page
+func (q *keysetQuery) after(cursor string) error {
+	at, id, err := decodeCursor(cursor)
+	if err != nil {
+		return apperr.Invalid("invalid_cursor", "Invalid pagination cursor").Wrap(err)
+	}
+	q.where("("+q.timeColumn+", id) < (%s, %s)", at, id)
+	return nil
+}
+
+// count returns the query counting every matching row, and its arguments
+func (q *keysetQuery) count() (string, []interface{}) {
+	return "SELECT COUNT(*) FROM " + q.table + " WHERE " + strings.Join(q.conditions, " AND "), q.args
+}
+
+// page returns the query selecting a page of limit rows, and its arguments.
+// It fetches one extra row to learn whether another page exists; nextPage
+// trims it.
+func (q *keysetQuery) page(limit int) (string, []interface{}) {
+	args := append(q.args[:len(q.args):len(q.args)], limit+1)
+	query := fmt.Sprintf(`
+		SELECT * FROM %s
+		WHERE %s
+		ORDER BY %s DESC, id DESC
+		LIMIT $%d
+	`, q.table, strings.Join(q.conditions, " AND "), q.timeColumn, len(args))
+	return query, args
+}
+
+// nextPage trims rows fetched by keysetQuery.page to limit, returning them
+// (never nil) with the cursor for the following page, or "" on the last
+func nextPage[T any](rows []T, limit int, key func(T) (time.Time, string)) ([]T, string) {
+	if rows == nil {
+		return []T{}, ""
+	}
+	if len(rows) <= limit {
+		return rows, ""
+	}
+	rows = rows[:limit]
+	return rows, encodeCursor(key(rows[len(rows)-1]))
+}
+
+// encodeCursor makes an opaque cursor pointing just past a row
+func encodeCursor(at time.Time, id string) string {
+	raw := strconv.FormatInt(at.UnixNano(), 10) + "|" + id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+
+	nanos, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return time.Time{}, "", fmt.Errorf("malformed cursor")
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return time.Time{}, "", err
+	}
+
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+
+	return time.Unix(0, n), id, nil
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/google/uuid"
+)
+
+// SessionGap is the longest pause between plays that still continues a
+// listening session
+const SessionGap = 15 * time.Minute
+
+const (
+	// DefaultSessionLimit is the page size when none is requested
+	DefaultSessionLimit = 20
+	// MaxSessionLimit caps a single page of listening sessions
+	MaxSessionLimit = 100
+)
+
+// ListeningSessionPage is one page of listening sessions, newest first
+type ListeningSessionPage struct {
+	Sessions   []models.ListeningSession
+	NextCursor string
+}
+
+// assignSession adds a counted play to the latest listening session within
+// SessionGap of it, or starts a new one when there is none. Backfilled plays
+// can arrive after later ones, so a session may grow at either end. It
+// returns the session's ID.
+func (s *ProfileService) assignSession(ctx context.Context, event models.PlayEvent) (string, error) {
+	var sessionID string
+	err := s.db.GetContext(ctx, &sessionID, `
+		UPDATE listening_sessions
+		SET started_at = LEAST(started_at, $5),
+			ended_at = GREATEST(ended_at, $3),
+			track_count = track_count + 1
+		WHERE id = (
+			SELECT id FROM listening_sessions
+			WHERE user_id = $1 AND ended_at >= $2 AND started_at <= $4
+			ORDER BY ended_at DESC
+			LIMIT 1
+		)
+		RETURNING id
+	`, event.UserID, event.StartedAt.Add(-SessionGap), event.EndedAt, event.EndedAt.Add(SessionGap), event.StartedAt)
+	if err == nil {
+		return sessionID, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("failed to extend listening session: %w", err)
+	}
+
+	sessionID = uuid.New().String()
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO listening_sessions (id, user_id, started_at, ended_at, track_count, dominant_artist)
+		VALUES ($1, $2, $3, $4, 1, $5)
+	`, sessionID, event.UserID, event.StartedAt, event.EndedAt, event.Artist)
+	if err != nil {
+		return "", fmt.Errorf("failed to start listening session: %w", err)
+	}
+	return sessionID, nil
+}
+
+// updateDominantArtist sets a session's dominant artist to the one with the
+// most plays in it, breaking ties by the most recent play
+func (s *ProfileService) updateDominantArtist(ctx context.Context, sessionID string) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE listening_sessions SET dominant_artist = COALESCE((
+			SELECT artist FROM play_events
+			WHERE session_id = $1
+			GROUP BY artist
+			ORDER BY COUNT(*) DESC, MAX(ended_at) DESC
+			LIMIT 1
+		), dominant_artist)
+		WHERE id = $1
+	`, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to update session artist: %w", err)
+	}
+	return nil
+}
+
+// GetListeningSessions returns a page of a user's listening sessions, newest
+// first. From (inclusive) and To (exclusive) bound when sessions started;
+// zero values leave that bound open.
+func (s *ProfileService) GetListeningSessions(ctx context.Context, userID string, from, to time.Time, cursor string, limit int) (*ListeningSessionPage, error) {
+	if limit <= 0 {
+		limit = DefaultSessionLimit
+	}
+	if limit > MaxSessionLimit {
+		limit = MaxSessionLimit
+	}
+
+	query := newKeysetQuery("listening_sessions", "started_at", userID)
+	if !from.IsZero() {
+		query.where("started_at >= %s", from)
+	}
+	if !to.IsZero() {
+		query.where("started_at < %s", to)
+	}
+	if cursor != "" {
+		if err := query.after(cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	var sessions []models.ListeningSession
+	pageQuery, args := query.page(limit)
+	if err := s.db.SelectContext(ctx, &sessions, pageQuery, args...); err != nil {
+		return nil, fmt.Errorf("failed to get listening sessions: %w", err)
+	}
+
+	page := &ListeningSessionPage{}
+	page.Sessions, page.NextCursor = nextPage(sessions, limit, func(session models.ListeningSession) (time.Time, string) {
+		return session.StartedAt, session.ID
+	})
+
+	return page, nil
+}
+
+// PurgeListeningSessions deletes sessions that ended before cutoff. It
+// returns the number of sessions deleted.
+func (s *ProfileService) PurgeListeningSessions(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM listening_sessions WHERE ended_at < $1", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge listening sessions: %w", err)
+	}
+	return result.RowsAffected()
+}
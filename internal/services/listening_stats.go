@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+)
+
+// userTimezone is a joined user's time zone for SQL date math, with UTC for
+// users who haven't set one
+const userTimezone = `COALESCE(NULLIF(u.timezone, ''), 'UTC')`
+
+// RollupListeningStats recomputes the daily stats of every day with a play
+// event started since lookback, and of every day for users with no rollups
+// yet, so existing history is rolled up on the first run. Whole days are
+// recomputed, so running it again is harmless. It returns the number of days
+// written.
+func (s *ProfileService) RollupListeningStats(ctx context.Context, lookback time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		WITH touched AS (
+			SELECT DISTINCT e.user_id, (e.started_at AT TIME ZONE `+userTimezone+`)::date AS day
+			FROM play_events e
+			JOIN users u ON u.id = e.user_id
+			WHERE e.started_at >= $1
+				OR NOT EXISTS (SELECT 1 FROM listening_stats_daily d WHERE d.user_id = e.user_id)
+		)
+		INSERT INTO listening_stats_daily (user_id, day, plays, listened_ms, unique_artists, updated_at)
+		SELECT t.user_id, t.day,
+			COUNT(*) FILTER (WHERE e.counted),
+			COALESCE(SUM(e.listened_ms), 0),
+			COUNT(DISTINCT LOWER(e.artist)) FILTER (WHERE e.counted),
+			NOW()
+		FROM touched t
+		JOIN users u ON u.id = t.user_id
+		JOIN play_events e ON e.user_id = t.user_id
+			AND e.started_at >= t.day::timestamp AT TIME ZONE `+userTimezone+`
+			AND e.started_at < (t.day + 1)::timestamp AT TIME ZONE `+userTimezone+`
+		GROUP BY t.user_id, t.day
+		ON CONFLICT (user_id, day) DO UPDATE SET
+			plays = EXCLUDED.plays,
+			listened_ms = EXCLUDED.listened_ms,
+			unique_artists = EXCLUDED.unique_artists,
+			updated_at = EXCLUDED.updated_at
+	`, lookback)
+	if err != nil {
+		return 0, fmt.Errorf("failed to roll up listening stats: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// Periods minutes listened can be broken down by
+const (
+	PeriodDay   = "day"
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+)
+
+// GetMinutesListened totals a user's rolled up listening since from by day,
+// week, or month, oldest first. Periods without any listening are left out,
+// and the first one may start before from.
+func (s *ProfileService) GetMinutesListened(ctx context.Context, userID string, from time.Time, period string) ([]models.ListeningPeriod, error) {
+	periods := []models.ListeningPeriod{}
+	err := s.db.SelectContext(ctx, &periods, `
+		SELECT TO_CHAR(DATE_TRUNC($3, d.day::timestamp), 'YYYY-MM-DD') AS start,
+			SUM(d.plays) AS plays, SUM(d.listened_ms) AS listened_ms
+		FROM listening_stats_daily d
+		JOIN users u ON u.id = d.user_id
+		WHERE d.user_id = $1 AND d.day >= ($2::timestamptz AT TIME ZONE `+userTimezone+`)::date
+		GROUP BY start
+		ORDER BY start
+	`, userID, from, period)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get minutes listened: %w", err)
+	}
+	for i := range periods {
+		periods[i].MinutesListened = msToMinutes(periods[i].ListenedMs)
+	}
+	return periods, nil
+}
+
+// msToMinutes converts a listening time to whole minutes
+func msToMinutes(ms int64) int {
+	return int(ms / int64(time.Minute/time.Millisecond))
+}
+
+// GetHeatmap counts the plays in a user's history since from by weekday and
+// hour in their time zone
+func (s *ProfileService) GetHeatmap(ctx context.Context, user *models.User, from time.Time) (*models.ListeningHeatmap, error) {
+	var cells []struct {
+		Weekday int `db:"weekday"`
+		Hour    int `db:"hour"`
+		Plays   int `db:"plays"`
+	}
+	loc := UserLocation(user)
+	err := s.db.SelectContext(ctx, &cells, `
+		SELECT EXTRACT(ISODOW FROM played_at AT TIME ZONE $3)::int - 1 AS weekday,
+			EXTRACT(HOUR FROM played_at AT TIME ZONE $3)::int AS hour,
+			COUNT(*) AS plays
+		FROM tracks
+		WHERE user_id = $1 AND is_currently_playing = false AND played_at >= $2
+		GROUP BY weekday, hour
+	`, user.ID, from, loc.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get listening heatmap: %w", err)
+	}
+
+	heatmap := &models.ListeningHeatmap{Timezone: loc.String()}
+	for _, cell := range cells {
+		heatmap.Plays[cell.Weekday][cell.Hour] = cell.Plays
+	}
+	return heatmap, nil
+}
+
+// GetDailyStats returns a user's rolled up stats for each day they listened
+// since from, oldest first. Today's numbers lag by up to the rollup interval.
+func (s *ProfileService) GetDailyStats(ctx context.Context, userID string, from time.Time) ([]models.DailyListeningStats, error) {
+	days := []models.DailyListeningStats{}
+	err := s.db.SelectContext(ctx, &days, `
+		SELECT TO_CHAR(d.day, 'YYYY-MM-DD') AS day, d.plays, d.listened_ms, d.unique_artists
+		FROM listening_stats_daily d
+		JOIN users u ON u.id = d.user_id
+		WHERE d.user_id = $1 AND d.day >= ($2::timestamptz AT TIME ZONE `+userTimezone+`)::date
+		ORDER BY d.day
+	`, userID, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily stats: %w", err)
+	}
+	for i := range days {
+		days[i].MinutesListened = msToMinutes(days[i].ListenedMs)
+	}
+	return days, nil
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/database"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/version"
+	"github.com/brandonhuynh1/whatamilisteningto-api/pkg/lrclib"
+	"github.com/rs/zerolog"
+)
+
+// lrcTimestamp matches the [mm:ss.xx] timestamps that start LRC lines
+var lrcTimestamp = regexp.MustCompile(`^\[(\d+):(\d{1,2})(?:[.:](\d{1,3}))?\]`)
+
+// LyricsService looks up lyrics for tracks, caching each result (including
+// tracks without lyrics) in Redis
+type LyricsService struct {
+	client   *lrclib.Client
+	redis    *database.RedisClient
+	cacheTTL time.Duration
+	logger   zerolog.Logger
+}
+
+// cachedLyrics is the cached lookup result; Lyrics is nil when the track has none
+type cachedLyrics struct {
+	Lyrics *models.Lyrics `json:"lyrics"`
+}
+
+// NewLyricsService creates the lyrics service, or returns nil when lyrics are
+// disabled
+func NewLyricsService(cfg config.LyricsConfig, redis *database.RedisClient, logger zerolog.Logger) *LyricsService {
+	if !cfg.Enabled {
+		return nil
+	}
+	userAgent := "whatamilisteningto-api/" + version.Get().Version
+	return &LyricsService{
+		client:   lrclib.NewClient(cfg.APIURL, userAgent),
+		redis:    redis,
+		cacheTTL: time.Duration(cfg.CacheHours) * time.Hour,
+		logger:   logger.With().Str("service", "lyrics").Logger(),
+	}
+}
+
+// GetLyrics returns the lyrics for a track, or a not found error when none
+// are known
+func (s *LyricsService) GetLyrics(ctx context.Context, track *models.SpotifyCurrentlyPlaying) (*models.Lyrics, error) {
+	if track.TrackID == "" {
+		return nil, apperr.NotFound("lyrics_not_found", "No lyrics for this track")
+	}
+
+	key := fmt.Sprintf("lyrics:%s", track.TrackID)
+	if s.redis.Available() {
+		if data, err := s.redis.Get(ctx, key); err == nil {
+			var cached cachedLyrics
+			if json.Unmarshal([]byte(data), &cached) == nil {
+				return lyricsOrNotFound(cached.Lyrics)
+			}
+		}
+	}
+
+	found, err := s.client.Get(ctx, track.TrackName, track.ArtistName, track.AlbumName,
+		time.Duration(track.DurationMs)*time.Millisecond)
+	if err != nil {
+		return nil, apperr.Unavailable("lyrics_unavailable", "Lyrics are temporarily unavailable").Wrap(err)
+	}
+
+	var lyrics *models.Lyrics
+	if found != nil {
+		lyrics = &models.Lyrics{
+			TrackID:      track.TrackID,
+			Instrumental: found.Instrumental,
+			Plain:        found.PlainLyrics,
+			Lines:        ParseLRC(found.SyncedLyrics),
+		}
+	}
+
+	if s.redis.Available() {
+		if data, err := json.Marshal(cachedLyrics{Lyrics: lyrics}); err == nil {
+			if err := s.redis.Set(ctx, key, data, s.cacheTTL); err != nil {
+				s.logger.Warn().Ctx(ctx).Err(err).Msg("Failed to cache lyrics")
+			}
+		}
+	}
+
+	return lyricsOrNotFound(lyrics)
+}
+
+func lyricsOrNotFound(lyrics *models.Lyrics) (*models.Lyrics, error) {
+	if lyrics == nil {
+		return nil, apperr.NotFound("lyrics_not_found", "No lyrics for this track")
+	}
+	return lyrics, nil
+}
+
+// ParseLRC parses LRC-format synced lyrics into lines ordered by time.
+// Lines without a timestamp, such as [ar:] tags, are skipped.
+func ParseLRC(lrc string) []models.LyricLine {
+	var lines []models.LyricLine
+	for _, raw := range strings.Split(lrc, "\n") {
+		raw = strings.TrimSpace(raw)
+		match := lrcTimestamp.FindStringSubmatch(raw)
+		if match == nil {
+			continue
+		}
+		minutes, _ := strconv.Atoi(match[1])
+		seconds, _ := strconv.Atoi(match[2])
+		// Fractions are hundredths ("12.34") or milliseconds ("12.345")
+		fraction := 0
+		if match[3] != "" {
+			fraction, _ = strconv.Atoi((match[3] + "00")[:3])
+		}
+		lines = append(lines, models.LyricLine{
+			TimeMs: (minutes*60+seconds)*1000 + fraction,
+			Text:   strings.TrimSpace(raw[len(match[0]):]),
+		})
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].TimeMs < lines[j].TimeMs })
+	return lines
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+)
+
+// ManualNowPlaying is an entry an owner sets by hand, for listening no
+// provider can see such as vinyl, radio, or a live show
+type ManualNowPlaying struct {
+	Title      string
+	Artist     string
+	Album      string
+	ArtworkURL string
+	// SpotifyURL optionally links the entry to a Spotify track
+	SpotifyURL string
+	Duration   time.Duration
+}
+
+// SetManualNowPlaying shows a manual entry as playing for its duration,
+// taking precedence over the user's provider until then
+func (s *ProfileService) SetManualNowPlaying(ctx context.Context, userID string, entry ManualNowPlaying) (*models.SpotifyCurrentlyPlaying, error) {
+	track := &models.SpotifyCurrentlyPlaying{
+		IsPlaying:   true,
+		TrackID:     manualTrackID(entry),
+		TrackName:   entry.Title,
+		ArtistName:  entry.Artist,
+		AlbumName:   entry.Album,
+		AlbumArtURL: entry.ArtworkURL,
+		TrackURL:    entry.SpotifyURL,
+		DurationMs:  int(entry.Duration.Milliseconds()),
+		Source:      NowPlayingSourceManual,
+	}
+	if err := s.PublishNowPlaying(ctx, userID, track, entry.Duration); err != nil {
+		return nil, err
+	}
+	return track, nil
+}
+
+// ReportedPlayback is playback pushed by a client that can see it, such as a
+// browser extension or desktop app, for sources with no server API like
+// local files or YouTube
+type ReportedPlayback struct {
+	Title      string
+	Artist     string
+	Album      string
+	ArtworkURL string
+	// TrackURL optionally links where the track is playing
+	TrackURL  string
+	Duration  time.Duration
+	Progress  time.Duration
+	IsPlaying bool
+}
+
+// ReportNowPlaying publishes playback a client reported. A playing track
+// holds until it would end, and anything else briefly, the same as media
+// server events.
+func (s *ProfileService) ReportNowPlaying(ctx context.Context, userID string, report ReportedPlayback) (*models.SpotifyCurrentlyPlaying, error) {
+	event := mediaEvent(models.SpotifyCurrentlyPlaying{
+		IsPlaying:   report.IsPlaying,
+		TrackID:     manualTrackID(ManualNowPlaying{Title: report.Title, Artist: report.Artist, SpotifyURL: report.TrackURL}),
+		TrackName:   report.Title,
+		ArtistName:  report.Artist,
+		AlbumName:   report.Album,
+		AlbumArtURL: report.ArtworkURL,
+		TrackURL:    report.TrackURL,
+		DurationMs:  int(report.Duration.Milliseconds()),
+		ProgressMs:  int(report.Progress.Milliseconds()),
+		Source:      NowPlayingSourceReported,
+	})
+	if err := s.PublishNowPlaying(ctx, userID, &event.Track, event.TTL); err != nil {
+		return nil, err
+	}
+	return &event.Track, nil
+}
+
+// ClearManualNowPlaying ends a manual entry early. Nothing shows as playing
+// until the cached state expires and the user's provider is polled again.
+func (s *ProfileService) ClearManualNowPlaying(ctx context.Context, userID string) error {
+	return s.PublishNowPlaying(ctx, userID, &models.SpotifyCurrentlyPlaying{IsPlaying: false, Source: NowPlayingSourceManual}, 0)
+}
+
+// manualTrackID uses the Spotify track ID when the entry links one, so it
+// matches plays of the same track from Spotify in history, and otherwise a
+// stable ID derived from the artist and title
+func manualTrackID(entry ManualNowPlaying) string {
+	if u, err := url.Parse(entry.SpotifyURL); err == nil && u.Host == "open.spotify.com" {
+		if id, ok := strings.CutPrefix(u.Path, "/track/"); ok && id != "" {
+			return id
+		}
+	}
+	sum := sha256.Sum256([]byte(strings.ToLower(entry.Artist) + "\x00" + strings.ToLower(entry.Title)))
+	return "manual:" + hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/database"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/rs/zerolog"
+)
+
+// mediaServerIdleTTL is how long a play event without a duration is kept,
+// and how long a stop is served before the user's provider is polled again
+const mediaServerIdleTTL = 5 * time.Minute
+
+// mediaServerGrace keeps a track playing a moment past its end, covering the
+// gap before the media server reports the next one
+const mediaServerGrace = 30 * time.Second
+
+// jellyfinTicksPerMs converts Jellyfin's 100ns ticks to milliseconds
+const jellyfinTicksPerMs = 10000
+
+// MediaEvent is a playback change reported by a media server
+type MediaEvent struct {
+	Track models.SpotifyCurrentlyPlaying
+	// TTL is how long the state holds without another event
+	TTL time.Duration
+}
+
+// MediaWebhookService manages the per-user secret webhook URLs that Plex and
+// Jellyfin report playback to, and parses their payloads
+type MediaWebhookService struct {
+	db     *database.DB
+	logger zerolog.Logger
+}
+
+// NewMediaWebhookService creates a new media webhook service
+func NewMediaWebhookService(db *database.DB, logger zerolog.Logger) *MediaWebhookService {
+	return &MediaWebhookService{
+		db:     db,
+		logger: logger.With().Str("service", "media_webhook").Logger(),
+	}
+}
+
+// hashWebhookToken hashes a webhook token for storage and lookup
+func hashWebhookToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateWebhook issues a new webhook token for a user, replacing any previous
+// one. Only a hash is stored, so the token can't be shown again.
+func (s *MediaWebhookService) CreateWebhook(ctx context.Context, userID string) (string, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate webhook token: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO media_webhooks (user_id, token_hash, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, created_at = EXCLUDED.created_at, last_event_at = NULL
+	`, userID, hashWebhookToken(token), time.Now())
+	if err != nil {
+		return "", fmt.Errorf("failed to create media webhook: %w", err)
+	}
+	return token, nil
+}
+
+// DeleteWebhook disables a user's webhook URL
+func (s *MediaWebhookService) DeleteWebhook(ctx context.Context, userID string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM media_webhooks WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("failed to delete media webhook: %w", err)
+	}
+	return nil
+}
+
+// UserIDForToken returns the user a webhook token belongs to and records
+// that an event arrived
+func (s *MediaWebhookService) UserIDForToken(ctx context.Context, token string) (string, error) {
+	var userID string
+	err := s.db.GetContext(ctx, &userID,
+		"UPDATE media_webhooks SET last_event_at = $1 WHERE token_hash = $2 RETURNING user_id",
+		time.Now(), hashWebhookToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", apperr.NotFound("webhook_not_found", "Webhook not found")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up media webhook: %w", err)
+	}
+	return userID, nil
+}
+
+// plexPayload is the part of a Plex webhook payload the app uses
+type plexPayload struct {
+	Event    string `json:"event"`
+	Metadata struct {
+		Type             string `json:"type"`
+		RatingKey        string `json:"ratingKey"`
+		Title            string `json:"title"`
+		GrandparentTitle string `json:"grandparentTitle"`
+		OriginalTitle    string `json:"originalTitle"`
+		ParentTitle      string `json:"parentTitle"`
+		Duration         int    `json:"duration"`
+		ViewOffset       int    `json:"viewOffset"`
+	} `json:"Metadata"`
+}
+
+// ParsePlexPayload parses the JSON payload field of a Plex webhook. It
+// returns nil for events that don't change what is playing, such as
+// scrobbles or video playback.
+func ParsePlexPayload(data []byte) (*MediaEvent, error) {
+	var payload plexPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, apperr.Invalid("invalid_payload", "Invalid Plex payload")
+	}
+	if payload.Metadata.Type != "track" {
+		return nil, nil
+	}
+
+	var playing bool
+	switch payload.Event {
+	case "media.play", "media.resume":
+		playing = true
+	case "media.pause", "media.stop":
+		playing = false
+	default:
+		return nil, nil
+	}
+
+	// Plex sets originalTitle when a track's artist differs from the album's
+	artist := payload.Metadata.OriginalTitle
+	if artist == "" {
+		artist = payload.Metadata.GrandparentTitle
+	}
+	return mediaEvent(models.SpotifyCurrentlyPlaying{
+		IsPlaying:  playing,
+		TrackID:    "plex:" + payload.Metadata.RatingKey,
+		Source:     NowPlayingSourceMediaServer,
+		TrackName:  payload.Metadata.Title,
+		ArtistName: artist,
+		AlbumName:  payload.Metadata.ParentTitle,
+		DurationMs: payload.Metadata.Duration,
+		ProgressMs: payload.Metadata.ViewOffset,
+	}), nil
+}
+
+// jellyfinPayload is the JSON the Jellyfin webhook plugin is configured to
+// send (see the README for the template)
+type jellyfinPayload struct {
+	NotificationType      string `json:"NotificationType"`
+	ItemType              string `json:"ItemType"`
+	ItemID                string `json:"ItemId"`
+	Name                  string `json:"Name"`
+	Artist                string `json:"Artist"`
+	Album                 string `json:"Album"`
+	RunTimeTicks          int64  `json:"RunTimeTicks"`
+	PlaybackPositionTicks int64  `json:"PlaybackPositionTicks"`
+	IsPaused              bool   `json:"IsPaused"`
+}
+
+// ParseJellyfinPayload parses a Jellyfin webhook plugin payload. It returns
+// nil for events that don't change what is playing.
+func ParseJellyfinPayload(data []byte) (*MediaEvent, error) {
+	var payload jellyfinPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, apperr.Invalid("invalid_payload", "Invalid Jellyfin payload")
+	}
+	if payload.ItemType != "Audio" {
+		return nil, nil
+	}
+
+	var playing bool
+	switch payload.NotificationType {
+	case "PlaybackStart":
+		playing = true
+	case "PlaybackProgress":
+		playing = !payload.IsPaused
+	case "PlaybackStop":
+		playing = false
+	default:
+		return nil, nil
+	}
+
+	return mediaEvent(models.SpotifyCurrentlyPlaying{
+		IsPlaying:  playing,
+		TrackID:    "jellyfin:" + payload.ItemID,
+		Source:     NowPlayingSourceMediaServer,
+		TrackName:  payload.Name,
+		ArtistName: payload.Artist,
+		AlbumName:  payload.Album,
+		DurationMs: int(payload.RunTimeTicks / jellyfinTicksPerMs),
+		ProgressMs: int(payload.PlaybackPositionTicks / jellyfinTicksPerMs),
+	}), nil
+}
+
+// mediaEvent wraps a playback state with how long it holds: a playing track
+// until it would end, anything else briefly
+func mediaEvent(track models.SpotifyCurrentlyPlaying) *MediaEvent {
+	ttl := mediaServerIdleTTL
+	if track.IsPlaying && track.DurationMs > 0 {
+		remaining := time.Duration(track.DurationMs-track.ProgressMs) * time.Millisecond
+		ttl = max(remaining, 0) + mediaServerGrace
+	}
+	return &MediaEvent{Track: track, TTL: ttl}
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/database"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/brandonhuynh1/whatamilisteningto-api/pkg/imaging"
+	"github.com/rs/zerolog"
+)
+
+// OpenGraph images are drawn at the 1.91:1 size social networks crop link
+// previews to
+const (
+	ogImageWidth  = 1200
+	ogImageHeight = 630
+	ogArtSize     = 400
+	ogMargin      = 80
+	ogTextX       = ogMargin + ogArtSize + 50
+	ogTextWidth   = ogImageWidth - ogTextX - ogMargin
+)
+
+// OGImageService renders the PNG link previews shared profile links show on
+// social media
+type OGImageService struct {
+	profileService *ProfileService
+	albumArt       *AlbumArtService
+	redis          *database.RedisClient
+	logger         zerolog.Logger
+}
+
+// NewOGImageService creates the OpenGraph image service. albumArt may be
+// nil, in which case images are rendered without art.
+func NewOGImageService(profileService *ProfileService, albumArt *AlbumArtService, redis *database.RedisClient, logger zerolog.Logger) *OGImageService {
+	return &OGImageService{
+		profileService: profileService,
+		albumArt:       albumArt,
+		redis:          redis,
+		logger:         logger.With().Str("service", "og_image").Logger(),
+	}
+}
+
+// ogImageCacheKey includes when playback last changed, so a cached image is
+// never older than the Last-Modified it's served with
+func ogImageCacheKey(userID string, nowPlaying *models.SpotifyCurrentlyPlaying) string {
+	return fmt.Sprintf("og:%s:%d", userID, nowPlaying.ChangedAt)
+}
+
+// RenderOGImage returns a user's link preview as PNG, showing their current
+// track or, when they show history, the last one they played. Rendered
+// images are cached in Redis for ttl.
+func (s *OGImageService) RenderOGImage(ctx context.Context, user *models.User, nowPlaying *models.SpotifyCurrentlyPlaying, ttl time.Duration) ([]byte, error) {
+	key := ogImageCacheKey(user.ID, nowPlaying)
+	if s.redis.Available() {
+		if data, err := s.redis.Get(ctx, key); err == nil {
+			return []byte(data), nil
+		}
+	}
+
+	profile, err := s.profileService.GetProfile(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	heading := fmt.Sprintf("%s is listening to", user.DisplayName)
+	track := trackFromNowPlaying(user.ID, nowPlaying)
+	if !nowPlaying.IsPlaying {
+		heading, track = user.DisplayName, nil
+		if profile.ShowHistory {
+			recent, err := s.profileService.GetRecentTracks(ctx, user.ID, 1)
+			if err != nil {
+				return nil, err
+			}
+			if len(recent) > 0 {
+				heading, track = fmt.Sprintf("%s last played", user.DisplayName), &recent[0]
+			}
+		}
+	}
+
+	var art image.Image
+	if track != nil && track.SpotifyTrackID != "" && s.albumArt != nil {
+		art, err = s.albumArtImage(ctx, track.SpotifyTrackID)
+		if err != nil {
+			s.logger.Debug().Ctx(ctx).Err(err).Str("track_id", track.SpotifyTrackID).Msg("Rendering OpenGraph image without album art")
+		}
+	}
+
+	var out bytes.Buffer
+	if err := png.Encode(&out, drawOGImage(user, profile, heading, track, art)); err != nil {
+		return nil, fmt.Errorf("failed to encode OpenGraph image: %w", err)
+	}
+
+	if s.redis.Available() && ttl > 0 {
+		if err := s.redis.Set(ctx, key, out.Bytes(), ttl); err != nil {
+			s.logger.Debug().Ctx(ctx).Err(err).Msg("Failed to cache OpenGraph image")
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// albumArtImage returns a track's album art scaled for the image
+func (s *OGImageService) albumArtImage(ctx context.Context, trackID string) (image.Image, error) {
+	data, err := s.albumArt.GetAlbumArt(ctx, trackID, AlbumArtSizes[len(AlbumArtSizes)-1])
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode album art: %w", err)
+	}
+	return imaging.Square(img, ogArtSize), nil
+}
+
+// drawOGImage lays out the image in the profile's colors. track is nil when
+// there's nothing to show, and art is nil to draw a placeholder in its place.
+func drawOGImage(user *models.User, profile *models.Profile, heading string, track *models.Track, art image.Image) *image.RGBA {
+	background, err := parseHexColor(profile.BackgroundColor)
+	if err != nil {
+		background, _ = parseHexColor(defaultBadgeBackground)
+	}
+	text, err := parseHexColor(profile.TextColor)
+	if err != nil {
+		text, _ = parseHexColor(defaultBadgeText)
+	}
+	muted := background.mix(text, 0.6).rgba()
+
+	img := image.NewRGBA(image.Rect(0, 0, ogImageWidth, ogImageHeight))
+	imaging.Fill(img, img.Bounds(), background.rgba())
+
+	artRect := image.Rect(0, 0, ogArtSize, ogArtSize).Add(image.Pt(ogMargin, (ogImageHeight-ogArtSize)/2))
+	if art != nil {
+		draw.Draw(img, artRect, art, art.Bounds().Min, draw.Src)
+	} else {
+		imaging.Fill(img, artRect, background.mix(text, 0.15).rgba())
+	}
+
+	y := artRect.Min.Y + 10
+	imaging.DrawText(img, ogTextX, y, imaging.Fit(heading, ogTextWidth, 4), 4, muted)
+	y += imaging.TextHeight(4) + 40
+
+	if track == nil {
+		imaging.DrawText(img, ogTextX, y, "Not listening right now", 5, text.rgba())
+	} else {
+		for _, line := range imaging.Wrap(track.Name, ogTextWidth, 6, 2) {
+			imaging.DrawText(img, ogTextX, y, line, 6, text.rgba())
+			y += imaging.TextHeight(6) + 20
+		}
+		y += 10
+		imaging.DrawText(img, ogTextX, y, imaging.Fit(track.Artist, ogTextWidth, 4), 4, text.rgba())
+	}
+
+	footer := imaging.Fit("/profile/"+user.ProfileURL, ogTextWidth, 3)
+	imaging.DrawText(img, ogTextX, artRect.Max.Y-imaging.TextHeight(3), footer, 3, muted)
+	return img
+}
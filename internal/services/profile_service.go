@@ -2,43 +2,80 @@ package services
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/cache"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
 	"github.com/brandonhuynh1/whatamilisteningto-api/internal/database"
 	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
-	"github.com/jmoiron/sqlx"
+	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 )
 
 // ProfileService handles profile-related operations
 type ProfileService struct {
-	db             *sqlx.DB
+	db             *database.DB
 	redis          *database.RedisClient
 	spotifyService *SpotifyService
+	providers      *Providers
+	hotProfiles    *cache.Cache[models.Profile]
+	history        config.HistoryConfig
+	onListen       []ListenHook
 	logger         zerolog.Logger
 }
 
-// NewProfileService creates a new profile service
-func NewProfileService(db *sqlx.DB, redis *database.RedisClient, spotifyService *SpotifyService, logger zerolog.Logger) *ProfileService {
+// NewProfileService creates a new profile service. spotifyService caches and
+// broadcasts now-playing state for every provider.
+func NewProfileService(db *database.DB, redis *database.RedisClient, spotifyService *SpotifyService, providers *Providers, cacheCfg config.CacheConfig, historyCfg config.HistoryConfig, logger zerolog.Logger) *ProfileService {
 	return &ProfileService{
 		db:             db,
 		redis:          redis,
 		spotifyService: spotifyService,
+		providers:      providers,
+		hotProfiles:    cache.New[models.Profile](time.Duration(cacheCfg.HotTTLMillis)*time.Millisecond, cacheCfg.HotMaxEntries),
+		history:        historyCfg,
 		logger:         logger.With().Str("service", "profile").Logger(),
 	}
 }
 
-// GetProfile gets a user's profile
+// GetProfile gets a user's profile, checking the in-process hot cache first
 func (s *ProfileService) GetProfile(ctx context.Context, userID string) (*models.Profile, error) {
+	if profile, ok := s.hotProfiles.Get(userID); ok {
+		return &profile, nil
+	}
+
 	var profile models.Profile
 	err := s.db.GetContext(ctx, &profile, "SELECT * FROM profiles WHERE user_id = $1", userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperr.NotFound("profile_not_found", "Profile not found")
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get profile: %w", err)
 	}
+
+	s.hotProfiles.Set(userID, profile)
 	return &profile, nil
 }
 
+// CacheStats reports the hot profile cache's effectiveness
+func (s *ProfileService) CacheStats() cache.Stats {
+	return s.hotProfiles.Stats()
+}
+
+// UpdateCacheConfig applies new hot cache limits at runtime
+func (s *ProfileService) UpdateCacheConfig(cacheCfg config.CacheConfig) {
+	s.hotProfiles.SetLimits(time.Duration(cacheCfg.HotTTLMillis)*time.Millisecond, cacheCfg.HotMaxEntries)
+}
+
+// InvalidateHotProfile drops a user's profile from the in-process cache
+func (s *ProfileService) InvalidateHotProfile(userID string) {
+	s.hotProfiles.Delete(userID)
+}
+
 // UpdateProfile updates a user's profile
 func (s *ProfileService) UpdateProfile(ctx context.Context, userID string, updates models.Profile) error {
 	// Get the current profile
@@ -54,6 +91,7 @@ func (s *ProfileService) UpdateProfile(ctx context.Context, userID string, updat
 	currentProfile.CustomMessage = updates.CustomMessage
 	currentProfile.ShowStats = updates.ShowStats
 	currentProfile.ShowHistory = updates.ShowHistory
+	currentProfile.ShowLyrics = updates.ShowLyrics
 	currentProfile.AnimationStyle = updates.AnimationStyle
 	currentProfile.UpdatedAt = time.Now()
 
@@ -66,6 +104,7 @@ func (s *ProfileService) UpdateProfile(ctx context.Context, userID string, updat
 			custom_message = :custom_message,
 			show_stats = :show_stats,
 			show_history = :show_history,
+			show_lyrics = :show_lyrics,
 			animation_style = :animation_style,
 			updated_at = :updated_at
 		WHERE id = :id
@@ -75,6 +114,13 @@ func (s *ProfileService) UpdateProfile(ctx context.Context, userID string, updat
 		return fmt.Errorf("failed to update profile: %w", err)
 	}
 
+	// Drop the stale copy here and tell other instances to do the same
+	s.hotProfiles.Delete(userID)
+	channel := fmt.Sprintf("profile:updates:%s", userID)
+	if err := s.redis.Publish(ctx, channel, currentProfile.UpdatedAt.Unix()); err != nil {
+		s.logger.Warn().Ctx(ctx).Err(err).Msg("Failed to publish profile update")
+	}
+
 	return nil
 }
 
@@ -88,96 +134,53 @@ func (s *ProfileService) GetProfileResponse(ctx context.Context, user *models.Us
 
 	// Get currently playing track (try cache first, then Spotify API)
 	var currentTrack *models.Track
-	cachedTrack, err := s.spotifyService.GetCachedCurrentlyPlaying(ctx, user.ID)
-
-	// If not in cache or cache error, try Spotify API if sharing is enabled
-	if err != nil || cachedTrack == nil {
-		if user.IsSharingEnabled {
-			// Check if token is expired and refresh if needed
-			if userService.IsTokenExpired(user) {
-				s.logger.Debug().Msg("Refreshing expired Spotify token")
-				tokenResp, err := s.spotifyService.RefreshAccessToken(ctx, user.SpotifyRefreshToken)
-				if err != nil {
-					s.logger.Error().Err(err).Msg("Failed to refresh access token")
-				} else {
-					// Update the user's token
-					err = userService.UpdateUserToken(ctx, user.ID, tokenResp.AccessToken, tokenResp.ExpiresIn)
-					if err != nil {
-						s.logger.Error().Err(err).Msg("Failed to update user token")
-					}
-
-					// Update in-memory token for immediate use
-					user.SpotifyAccessToken = tokenResp.AccessToken
-					user.TokenExpiresAt = time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
-				}
-			}
-
-			// Get currently playing from Spotify API
-			spotifyTrack, err := s.spotifyService.GetCurrentlyPlayingTrack(ctx, user.SpotifyAccessToken)
-			if err != nil {
-				s.logger.Error().Err(err).Msg("Failed to get currently playing track")
-			} else if spotifyTrack != nil && spotifyTrack.IsPlaying {
-				// Cache the result
-				err = s.spotifyService.CacheCurrentlyPlaying(ctx, user.ID, spotifyTrack)
-				if err != nil {
-					s.logger.Warn().Err(err).Msg("Failed to cache currently playing track")
-				}
-
-				// Convert to track model
-				currentTrack = &models.Track{
-					UserID:             user.ID,
-					SpotifyTrackID:     spotifyTrack.TrackID,
-					Name:               spotifyTrack.TrackName,
-					Artist:             spotifyTrack.ArtistName,
-					Album:              spotifyTrack.AlbumName,
-					AlbumArtURL:        spotifyTrack.AlbumArtURL,
-					TrackURL:           spotifyTrack.TrackURL,
-					DurationMs:         spotifyTrack.DurationMs,
-					IsCurrentlyPlaying: true,
-					PlayedAt:           time.Now(),
-				}
-
-				// Save to track history
-				s.SaveTrackToHistory(ctx, currentTrack)
-
-				// Notify listeners of track change
-				s.spotifyService.NotifyTrackChange(ctx, user.ID, spotifyTrack)
-			}
-		}
-	} else if cachedTrack.IsPlaying {
-		// Convert cached track to track model
-		currentTrack = &models.Track{
-			UserID:             user.ID,
-			SpotifyTrackID:     cachedTrack.TrackID,
-			Name:               cachedTrack.TrackName,
-			Artist:             cachedTrack.ArtistName,
-			Album:              cachedTrack.AlbumName,
-			AlbumArtURL:        cachedTrack.AlbumArtURL,
-			TrackURL:           cachedTrack.TrackURL,
-			DurationMs:         cachedTrack.DurationMs,
-			IsCurrentlyPlaying: true,
-			PlayedAt:           time.Now(), // Approximate time
-		}
+	nowPlaying, err := s.GetNowPlaying(ctx, user, userService)
+	if err != nil {
+		s.logger.Error().Ctx(ctx).Err(err).Msg("Failed to get currently playing track")
+	} else if nowPlaying.IsPlaying {
+		currentTrack = trackFromNowPlaying(user.ID, nowPlaying)
 	}
 
 	// Get recent tracks if history should be shown
+	loc := UserLocation(user)
 	var recentTracks []models.Track
 	if profile.ShowHistory {
 		recentTracks, err = s.GetRecentTracks(ctx, user.ID, 10)
 		if err != nil {
-			s.logger.Error().Err(err).Msg("Failed to get recent tracks")
+			s.logger.Error().Ctx(ctx).Err(err).Msg("Failed to get recent tracks")
 			recentTracks = []models.Track{} // Empty slice instead of nil
 		}
 	} else {
 		recentTracks = []models.Track{} // Empty slice instead of nil
 	}
 
-	// Get active viewer count if stats should be shown
+	// Show play times in the owner's time zone
+	for i := range recentTracks {
+		recentTracks[i].PlayedAt = recentTracks[i].PlayedAt.In(loc)
+	}
+	if currentTrack != nil {
+		currentTrack.PlayedAt = currentTrack.PlayedAt.In(loc)
+	}
+
+	// Offer visitors the tracks on their own platforms
+	shown := make([]*models.Track, 0, len(recentTracks)+1)
+	if currentTrack != nil {
+		shown = append(shown, currentTrack)
+	}
+	for i := range recentTracks {
+		shown = append(shown, &recentTracks[i])
+	}
+	if err := s.attachTrackLinks(ctx, shown...); err != nil {
+		s.logger.Warn().Ctx(ctx).Err(err).Msg("Failed to get track links")
+	}
+
+	// Get active viewer count if stats should be shown; presence is disabled
+	// while Redis is unavailable
 	viewerCount := 0
-	if profile.ShowStats {
+	if profile.ShowStats && userService.PresenceAvailable() {
 		count, err := userService.GetActiveUserCount(ctx, user.ID)
 		if err != nil {
-			s.logger.Error().Err(err).Msg("Failed to get active viewer count")
+			s.logger.Error().Ctx(ctx).Err(err).Msg("Failed to get active viewer count")
 		} else {
 			viewerCount = count
 		}
@@ -188,6 +191,7 @@ func (s *ProfileService) GetProfileResponse(ctx context.Context, user *models.Us
 		ID:          user.ID,
 		DisplayName: user.DisplayName,
 		ProfileURL:  user.ProfileURL,
+		Timezone:    loc.String(),
 	}
 
 	// Create profile response
@@ -202,6 +206,194 @@ func (s *ProfileService) GetProfileResponse(ctx context.Context, user *models.Us
 	return response, nil
 }
 
+// GetNowPlaying returns a user's playback state, preferring the cache and
+// falling back to their music provider when sharing is enabled. Fresh results are
+// cached, saved to history, and broadcast to listeners.
+func (s *ProfileService) GetNowPlaying(ctx context.Context, user *models.User, userService *UserService) (*models.SpotifyCurrentlyPlaying, error) {
+	if s.redis.Available() {
+		cachedTrack, err := s.spotifyService.GetCachedCurrentlyPlaying(ctx, user.ID)
+		if err == nil && cachedTrack != nil {
+			return cachedTrack, nil
+		}
+	}
+
+	if !user.IsSharingEnabled {
+		return &models.SpotifyCurrentlyPlaying{IsPlaying: false}, nil
+	}
+
+	spotifyTrack, err := s.fetchNowPlaying(ctx, user, userService)
+	if err != nil {
+		return nil, err
+	}
+
+	if spotifyTrack.IsPlaying {
+		// Cache the result
+		err = s.spotifyService.CacheCurrentlyPlaying(ctx, user.ID, spotifyTrack)
+		if err != nil {
+			s.logger.Warn().Ctx(ctx).Err(err).Msg("Failed to cache currently playing track")
+		}
+
+		// Save to track history
+		if err := s.SaveTrackToHistory(ctx, trackFromNowPlaying(user.ID, spotifyTrack)); err != nil {
+			s.logger.Error().Ctx(ctx).Err(err).Msg("Failed to save track to history")
+		}
+
+		// Notify listeners of track change
+		if err := s.spotifyService.NotifyTrackChange(ctx, user.ID, spotifyTrack); err != nil {
+			s.logger.Warn().Ctx(ctx).Err(err).Msg("Failed to notify track change")
+		}
+	}
+
+	return spotifyTrack, nil
+}
+
+// RefreshNowPlaying polls a user's provider regardless of the cache and
+// caches the result, unless the cached state came from another source that
+// still holds. When the track or playing state differs from the cached one,
+// it is also saved to history and broadcast to listeners. It reports whether
+// the playback state changed.
+func (s *ProfileService) RefreshNowPlaying(ctx context.Context, user *models.User, userService *UserService) (bool, error) {
+	if !user.IsSharingEnabled {
+		return false, nil
+	}
+
+	previous, _ := s.spotifyService.GetCachedCurrentlyPlaying(ctx, user.ID)
+	if nowPlayingHeld(previous) {
+		return false, nil
+	}
+	nowPlaying, err := s.fetchNowPlaying(ctx, user, userService)
+	if err != nil {
+		return false, err
+	}
+
+	if !playbackChanged(previous, nowPlaying) {
+		if err := s.spotifyService.CacheCurrentlyPlaying(ctx, user.ID, nowPlaying); err != nil {
+			s.logger.Warn().Ctx(ctx).Err(err).Msg("Failed to cache currently playing track")
+		}
+		return false, nil
+	}
+	return true, s.PublishNowPlaying(ctx, user.ID, nowPlaying, 0)
+}
+
+// playbackChanged reports whether current is a different track or playing
+// state than previous. Nothing cached counts as stopped.
+func playbackChanged(previous, current *models.SpotifyCurrentlyPlaying) bool {
+	if previous == nil {
+		return current.IsPlaying
+	}
+	return previous.IsPlaying != current.IsPlaying || previous.TrackID != current.TrackID
+}
+
+// Sources of now-playing state other than the user's provider
+const (
+	NowPlayingSourceManual      = "manual"
+	NowPlayingSourceMediaServer = "media_server"
+	NowPlayingSourceReported    = "reported"
+)
+
+// nowPlayingHeld reports whether a cached state from a source other than the
+// user's provider still takes precedence over polling the provider
+func nowPlayingHeld(nowPlaying *models.SpotifyCurrentlyPlaying) bool {
+	return nowPlaying != nil && nowPlaying.Source != "" && nowPlaying.HeldUntil > time.Now().UnixMilli()
+}
+
+// HeldNowPlaying returns the user's cached state when it came from another
+// source that still takes precedence over the provider, or nil
+func (s *ProfileService) HeldNowPlaying(ctx context.Context, userID string) *models.SpotifyCurrentlyPlaying {
+	cached, err := s.spotifyService.GetCachedCurrentlyPlaying(ctx, userID)
+	if err != nil || !nowPlayingHeld(cached) {
+		return nil
+	}
+	return cached
+}
+
+// fetchNowPlaying asks the user's provider what they're playing, refreshing
+// an expired access token first
+func (s *ProfileService) fetchNowPlaying(ctx context.Context, user *models.User, userService *UserService) (*models.SpotifyCurrentlyPlaying, error) {
+	provider, err := s.providers.ForUser(user)
+	if err != nil {
+		return nil, err
+	}
+	s.refreshExpiredToken(ctx, provider, user, userService)
+
+	// Get currently playing from the provider
+	return provider.GetCurrentlyPlayingTrack(s.spotifyService.WithUserTenant(ctx, user), user.SpotifyAccessToken)
+}
+
+// refreshExpiredToken refreshes user's access token if it has expired,
+// saving the new one. Failures are logged, leaving the old token to fail.
+func (s *ProfileService) refreshExpiredToken(ctx context.Context, provider MusicProvider, user *models.User, userService *UserService) {
+	if !userService.IsTokenExpired(user) {
+		return
+	}
+
+	s.logger.Debug().Ctx(ctx).Str("provider", provider.Name()).Msg("Refreshing expired access token")
+	tokenResp, err := provider.RefreshUserToken(ctx, user)
+	if err != nil {
+		s.logger.Error().Ctx(ctx).Err(err).Msg("Failed to refresh access token")
+		return
+	}
+
+	// Update the user's token
+	err = userService.UpdateUserToken(ctx, user.ID, tokenResp.AccessToken, tokenResp.ExpiresIn)
+	if err != nil {
+		s.logger.Error().Ctx(ctx).Err(err).Msg("Failed to update user token")
+	}
+
+	// Update in-memory token for immediate use
+	user.SpotifyAccessToken = tokenResp.AccessToken
+	user.TokenExpiresAt = time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+}
+
+// PublishNowPlaying records playback reported by a source other than the
+// user's provider. The state is cached for ttl, or the configured now-playing
+// TTL when ttl is zero. When nowPlaying names its Source, it holds until then:
+// it is served and the poller leaves the provider alone. It is saved to
+// history and broadcast like a polled track.
+func (s *ProfileService) PublishNowPlaying(ctx context.Context, userID string, nowPlaying *models.SpotifyCurrentlyPlaying, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = s.spotifyService.NowPlayingTTL()
+	}
+	if nowPlaying.Source != "" {
+		nowPlaying.HeldUntil = time.Now().Add(ttl).UnixMilli()
+	}
+	if err := s.spotifyService.CacheCurrentlyPlayingFor(ctx, userID, nowPlaying, ttl); err != nil {
+		s.logger.Warn().Ctx(ctx).Err(err).Msg("Failed to cache currently playing track")
+	}
+
+	if nowPlaying.IsPlaying {
+		if err := s.SaveTrackToHistory(ctx, trackFromNowPlaying(userID, nowPlaying)); err != nil {
+			return err
+		}
+	} else if err := s.finishPlays(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := s.spotifyService.NotifyTrackChange(ctx, userID, nowPlaying); err != nil {
+		s.logger.Warn().Ctx(ctx).Err(err).Msg("Failed to notify track change")
+	}
+	return nil
+}
+
+// trackFromNowPlaying converts a playback state into a history track played now
+func trackFromNowPlaying(userID string, nowPlaying *models.SpotifyCurrentlyPlaying) *models.Track {
+	now := time.Now()
+	return &models.Track{
+		ID:                 uuid.New().String(),
+		UserID:             userID,
+		SpotifyTrackID:     nowPlaying.TrackID,
+		Name:               nowPlaying.TrackName,
+		Artist:             nowPlaying.ArtistName,
+		Album:              nowPlaying.AlbumName,
+		AlbumArtURL:        nowPlaying.AlbumArtURL,
+		TrackURL:           nowPlaying.TrackURL,
+		DurationMs:         nowPlaying.DurationMs,
+		IsCurrentlyPlaying: true,
+		PlayedAt:           now,
+		CreatedAt:          now,
+	}
+}
+
 // SaveTrackToHistory saves a track to the user's history
 func (s *ProfileService) SaveTrackToHistory(ctx context.Context, track *models.Track) error {
 	// Check if this track is already in history and currently playing
@@ -211,7 +403,8 @@ func (s *ProfileService) SaveTrackToHistory(ctx context.Context, track *models.T
 		track.UserID, track.SpotifyTrackID)
 
 	if err == nil {
-		// Track exists and is currently playing, just update the played_at time
+		// Track exists and is currently playing, just move played_at up to
+		// now, the last time it was seen
 		_, err = s.db.ExecContext(ctx,
 			"UPDATE tracks SET played_at = $1 WHERE id = $2",
 			time.Now(), existingTrack.ID)
@@ -223,13 +416,9 @@ func (s *ProfileService) SaveTrackToHistory(ctx context.Context, track *models.T
 		return nil
 	}
 
-	// Set any currently playing tracks to not currently playing
-	_, err = s.db.ExecContext(ctx,
-		"UPDATE tracks SET is_currently_playing = false WHERE user_id = $1 AND is_currently_playing = true",
-		track.UserID)
-
-	if err != nil {
-		return fmt.Errorf("failed to update currently playing tracks: %w", err)
+	// End the previous play, if any
+	if err := s.finishPlays(ctx, track.UserID); err != nil {
+		return err
 	}
 
 	// Insert the new track
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+)
+
+// Providers users can sign in with, stored in users.provider
+const (
+	ProviderSpotify    = "spotify"
+	ProviderAppleMusic = "apple_music"
+	ProviderTidal      = "tidal"
+	ProviderDeezer     = "deezer"
+)
+
+// ProviderToken is the credential a provider issues when a user signs in or
+// their access is refreshed
+type ProviderToken struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int
+	// IDToken identifies the user for providers that sign in with OpenID
+	// Connect rather than a profile endpoint
+	IDToken string
+}
+
+// ProviderAccount identifies a user at their provider
+type ProviderAccount struct {
+	ID          string
+	Email       string
+	DisplayName string
+}
+
+// PlayedTrack is a track from a user's listening history at their provider
+type PlayedTrack struct {
+	Track    models.SpotifyCurrentlyPlaying
+	PlayedAt time.Time
+}
+
+// MusicProvider is a streaming service users sign in with and share their
+// listening from
+type MusicProvider interface {
+	// Name is the provider's users.provider value
+	Name() string
+	// GetAuthURL returns where to send the browser to sign in
+	GetAuthURL(ctx context.Context, state string) string
+	// ExchangeCodeForToken completes sign-in with the code and state from
+	// the callback. Providers using PKCE derive the code verifier from state.
+	ExchangeCodeForToken(ctx context.Context, code, state string) (*ProviderToken, error)
+	// GetAccount identifies the user a token from ExchangeCodeForToken
+	// belongs to
+	GetAccount(ctx context.Context, token *ProviderToken) (*ProviderAccount, error)
+	// RefreshUserToken renews a user's access token
+	RefreshUserToken(ctx context.Context, user *models.User) (*ProviderToken, error)
+	// GetCurrentlyPlayingTrack returns what the user is listening to
+	GetCurrentlyPlayingTrack(ctx context.Context, accessToken string) (*models.SpotifyCurrentlyPlaying, error)
+	// GetRecentlyPlayed returns up to limit recently played tracks, newest first
+	GetRecentlyPlayed(ctx context.Context, accessToken string, limit int) ([]PlayedTrack, error)
+}
+
+// Providers looks up the configured music providers by name
+type Providers struct {
+	byName map[string]MusicProvider
+}
+
+// NewProviders registers the configured providers
+func NewProviders(providers ...MusicProvider) *Providers {
+	p := &Providers{byName: make(map[string]MusicProvider)}
+	for _, provider := range providers {
+		p.byName[provider.Name()] = provider
+	}
+	return p
+}
+
+// Get returns a provider by name
+func (p *Providers) Get(name string) (MusicProvider, bool) {
+	provider, ok := p.byName[name]
+	return provider, ok
+}
+
+// ForUser returns the provider a user signed in with
+func (p *Providers) ForUser(user *models.User) (MusicProvider, error) {
+	name := user.Provider
+	if name == "" {
+		name = ProviderSpotify
+	}
+	provider, ok := p.byName[name]
+	if !ok {
+		return nil, apperr.Unavailable("provider_unavailable", "Music provider "+name+" is not configured")
+	}
+	return provider, nil
+}
+
+// rateLimitBackoff stops calls to a provider after it reports a rate limit,
+// so every poll in the meantime fails fast instead of extending the limit
+type rateLimitBackoff struct {
+	provider string
+	mu       sync.Mutex
+	until    time.Time
+}
+
+// ProviderRateLimitError is wrapped by the errors returned while a provider
+// is rate limiting requests, so callers can schedule a retry after RetryAfter
+// instead of failing outright
+type ProviderRateLimitError struct {
+	Provider   string
+	RetryAfter time.Duration
+}
+
+func (e *ProviderRateLimitError) Error() string {
+	return fmt.Sprintf("%s rate limited, retry after %s", e.Provider, e.RetryAfter)
+}
+
+// check returns a rate limit error while the backoff is in effect
+func (b *rateLimitBackoff) check() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if time.Now().Before(b.until) {
+		return b.err()
+	}
+	return nil
+}
+
+// limit starts or extends the backoff for wait and returns the error to
+// surface to callers
+func (b *rateLimitBackoff) limit(wait time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if until := time.Now().Add(wait); until.After(b.until) {
+		b.until = until
+	}
+	return b.err()
+}
+
+// err returns the error for the current backoff. b.mu must be held.
+func (b *rateLimitBackoff) err() error {
+	wait := time.Until(b.until)
+	return apperr.RateLimited(b.provider+"_rate_limited", "The music provider is rate limiting requests, try again shortly").
+		WithDetails(map[string]int{"retry_after_seconds": int(math.Ceil(wait.Seconds()))}).
+		Wrap(&ProviderRateLimitError{Provider: b.provider, RetryAfter: wait})
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/google/uuid"
+)
+
+// maxRecentlyViewed caps how many profiles are remembered per viewer; the
+// least recently viewed are dropped first
+const maxRecentlyViewed = 20
+
+// RecordRecentlyViewed notes that viewerID visited profileUserID's public
+// profile. Viewers who opted out, and unknown viewers, are ignored.
+func (s *UserService) RecordRecentlyViewed(ctx context.Context, viewerID, profileUserID string) error {
+	if _, err := uuid.Parse(viewerID); err != nil || viewerID == profileUserID {
+		return nil
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO recently_viewed_profiles (viewer_id, profile_user_id, view_count, last_viewed_at)
+		SELECT id, $2, 1, $3 FROM users WHERE id = $1 AND track_recently_viewed
+		ON CONFLICT (viewer_id, profile_user_id) DO UPDATE
+		SET view_count = recently_viewed_profiles.view_count + 1, last_viewed_at = EXCLUDED.last_viewed_at
+	`, viewerID, profileUserID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to record recently viewed profile: %w", err)
+	}
+	if recorded, _ := result.RowsAffected(); recorded == 0 {
+		return nil
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		DELETE FROM recently_viewed_profiles
+		WHERE viewer_id = $1 AND profile_user_id NOT IN (
+			SELECT profile_user_id FROM recently_viewed_profiles
+			WHERE viewer_id = $1
+			ORDER BY last_viewed_at DESC
+			LIMIT $2
+		)
+	`, viewerID, maxRecentlyViewed)
+	if err != nil {
+		return fmt.Errorf("failed to trim recently viewed profiles: %w", err)
+	}
+	return nil
+}
+
+// GetRecentlyViewed returns the profiles a user visited, most recent first.
+// Profiles that are no longer shared are left out.
+func (s *UserService) GetRecentlyViewed(ctx context.Context, viewerID string) ([]models.RecentlyViewedProfile, error) {
+	profiles := []models.RecentlyViewedProfile{}
+	err := s.db.SelectContext(ctx, &profiles, `
+		SELECT u.profile_url, u.display_name, r.view_count, r.last_viewed_at
+		FROM recently_viewed_profiles r
+		JOIN users u ON u.id = r.profile_user_id
+		WHERE r.viewer_id = $1 AND u.is_active AND u.is_sharing_enabled
+		ORDER BY r.last_viewed_at DESC
+	`, viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recently viewed profiles: %w", err)
+	}
+	return profiles, nil
+}
+
+// SetTrackRecentlyViewed turns recently viewed tracking on or off for a
+// user. Turning it off also forgets the profiles already recorded.
+func (s *UserService) SetTrackRecentlyViewed(ctx context.Context, userID string, enabled bool) error {
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE users SET track_recently_viewed = $1, updated_at = $2 WHERE id = $3",
+		enabled, time.Now(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to update recently viewed setting: %w", err)
+	}
+	if enabled {
+		return nil
+	}
+
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM recently_viewed_profiles WHERE viewer_id = $1", userID); err != nil {
+		return fmt.Errorf("failed to clear recently viewed profiles: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,465 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/database"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/brandonhuynh1/whatamilisteningto-api/pkg/lastfm"
+	"github.com/brandonhuynh1/whatamilisteningto-api/pkg/listenbrainz"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
+)
+
+// Scrobbling services users can connect, stored in
+// scrobble_accounts.provider
+const (
+	ScrobblerLastFM       = "lastfm"
+	ScrobblerListenBrainz = "listenbrainz"
+)
+
+// Scrobble states
+const (
+	ScrobblePending   = "pending"
+	ScrobbleSubmitted = "submitted"
+	ScrobbleFailed    = "failed"
+)
+
+const (
+	// scrobbleBatch is how many due scrobbles each run claims
+	scrobbleBatch = 200
+
+	// scrobbleLease is how long claimed scrobbles are held back from other
+	// workers while they're submitted
+	scrobbleLease = 5 * time.Minute
+
+	// scrobbleRetryBase is the wait before the first retry, doubling after
+	// each failed attempt
+	scrobbleRetryBase = time.Minute
+
+	// maxScrobbleRetryDoublings caps the retry wait at about four hours
+	maxScrobbleRetryDoublings = 8
+
+	// scrobbleListLimit is how many recent scrobbles are listed
+	scrobbleListLimit = 50
+
+	// Last.fm only counts plays of tracks longer than minScrobbleTrack that
+	// lasted half the track or scrobbleListenCap, whichever is shorter
+	minScrobbleTrack  = 30 * time.Second
+	scrobbleListenCap = 4 * time.Minute
+)
+
+// ScrobbleService connects users' scrobbling accounts and submits their
+// listens to them. Listens are queued in Postgres and submitted in the
+// background, so plays aren't lost while a service is down.
+type ScrobbleService struct {
+	db                *database.DB
+	lastfm            *lastfm.Client
+	lastfmRedirectURI string
+	listenbrainz      *listenbrainz.Client
+	maxAttempts       int
+	logger            zerolog.Logger
+}
+
+// NewScrobbleService creates a new scrobble service. Last.fm can only be
+// connected when lastfmCfg has an API key, and ListenBrainz when it's
+// enabled.
+func NewScrobbleService(lastfmCfg config.LastFMConfig, listenBrainzCfg config.ListenBrainzConfig, cfg config.ScrobbleConfig, db *database.DB, logger zerolog.Logger) *ScrobbleService {
+	s := &ScrobbleService{
+		db:                db,
+		lastfmRedirectURI: lastfmCfg.RedirectURI,
+		maxAttempts:       cfg.MaxAttempts,
+		logger:            logger.With().Str("service", "scrobble").Logger(),
+	}
+	if lastfmCfg.APIKey != "" {
+		s.lastfm = lastfm.NewClient(lastfmCfg.APIKey, lastfmCfg.Secret)
+	}
+	if listenBrainzCfg.Enabled {
+		s.listenbrainz = listenbrainz.NewClient(listenBrainzCfg.APIURL)
+	}
+	return s
+}
+
+// LastFMEnabled reports whether Last.fm is configured
+func (s *ScrobbleService) LastFMEnabled() bool {
+	return s.lastfm != nil
+}
+
+// ListenBrainzEnabled reports whether ListenBrainz is enabled
+func (s *ScrobbleService) ListenBrainzEnabled() bool {
+	return s.listenbrainz != nil
+}
+
+// LastFMAuthURL returns where to send the browser to authorize Last.fm.
+// Last.fm passes state back to the callback untouched.
+func (s *ScrobbleService) LastFMAuthURL(state string) string {
+	callback, err := url.Parse(s.lastfmRedirectURI)
+	if err != nil {
+		return s.lastfm.GetAuthURL(s.lastfmRedirectURI)
+	}
+	query := callback.Query()
+	query.Set("state", state)
+	callback.RawQuery = query.Encode()
+	return s.lastfm.GetAuthURL(callback.String())
+}
+
+// ConnectLastFM exchanges the token from Last.fm's callback for a session
+// and saves it, replacing any Last.fm account the user already connected
+func (s *ScrobbleService) ConnectLastFM(ctx context.Context, userID, token string) (*models.ScrobbleAccount, error) {
+	if token == "" {
+		return nil, apperr.Invalid("lastfm_token_missing", "Last.fm didn't return a token")
+	}
+
+	session, err := s.lastfm.GetSession(ctx, token)
+	var apiErr *lastfm.Error
+	if errors.As(err, &apiErr) && !apiErr.Temporary() {
+		return nil, apperr.Invalid("lastfm_auth_failed", "Last.fm didn't accept the authorization; try connecting again")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Last.fm session: %w", err)
+	}
+
+	return s.saveAccount(ctx, userID, ScrobblerLastFM, session.Name, session.Key)
+}
+
+// ConnectListenBrainz checks a user's ListenBrainz token and saves it,
+// replacing any ListenBrainz account the user already connected
+func (s *ScrobbleService) ConnectListenBrainz(ctx context.Context, userID, token string) (*models.ScrobbleAccount, error) {
+	username, err := s.listenbrainz.ValidateToken(ctx, token)
+	if errors.Is(err, listenbrainz.ErrInvalidToken) {
+		return nil, apperr.Invalid("listenbrainz_token_invalid", "ListenBrainz didn't accept the token")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate ListenBrainz token: %w", err)
+	}
+
+	return s.saveAccount(ctx, userID, ScrobblerListenBrainz, username, token)
+}
+
+// saveAccount stores a scrobbling account's credential, replacing the one
+// the user had for that provider
+func (s *ScrobbleService) saveAccount(ctx context.Context, userID, provider, username, sessionKey string) (*models.ScrobbleAccount, error) {
+	var account models.ScrobbleAccount
+	err := s.db.GetContext(ctx, &account, `
+		INSERT INTO scrobble_accounts (user_id, provider, username, session_key)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, provider) DO UPDATE
+		SET username = EXCLUDED.username, session_key = EXCLUDED.session_key
+		RETURNING *
+	`, userID, provider, username, sessionKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save scrobbling account: %w", err)
+	}
+	return &account, nil
+}
+
+// ListAccounts returns the scrobbling accounts a user has connected, with
+// counts of their scrobbles by status
+func (s *ScrobbleService) ListAccounts(ctx context.Context, userID string) ([]models.ScrobbleAccountStatus, error) {
+	accounts := []models.ScrobbleAccountStatus{}
+	err := s.db.SelectContext(ctx, &accounts, `
+		SELECT a.*,
+			COUNT(s.id) FILTER (WHERE s.status = $2) AS pending,
+			COUNT(s.id) FILTER (WHERE s.status = $3) AS submitted,
+			COUNT(s.id) FILTER (WHERE s.status = $4) AS failed,
+			MAX(s.submitted_at) AS last_submitted_at,
+			COALESCE((
+				SELECT e.last_error FROM scrobbles e
+				WHERE e.user_id = a.user_id AND e.provider = a.provider AND e.last_error <> ''
+				ORDER BY e.id DESC
+				LIMIT 1
+			), '') AS last_error
+		FROM scrobble_accounts a
+		LEFT JOIN scrobbles s ON s.user_id = a.user_id AND s.provider = a.provider
+		WHERE a.user_id = $1
+		GROUP BY a.user_id, a.provider
+		ORDER BY a.provider
+	`, userID, ScrobblePending, ScrobbleSubmitted, ScrobbleFailed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scrobbling accounts: %w", err)
+	}
+	return accounts, nil
+}
+
+// ListScrobbles returns the most recent scrobbles for one of a user's
+// scrobbling accounts, newest first
+func (s *ScrobbleService) ListScrobbles(ctx context.Context, userID, provider string) ([]models.Scrobble, error) {
+	var exists bool
+	err := s.db.GetContext(ctx, &exists,
+		"SELECT EXISTS (SELECT 1 FROM scrobble_accounts WHERE user_id = $1 AND provider = $2)", userID, provider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up scrobbling account: %w", err)
+	}
+	if !exists {
+		return nil, apperr.NotFound("scrobbling_account_not_found", "No such scrobbling account is connected")
+	}
+
+	scrobbles := []models.Scrobble{}
+	err = s.db.SelectContext(ctx, &scrobbles, `
+		SELECT * FROM scrobbles
+		WHERE user_id = $1 AND provider = $2
+		ORDER BY id DESC
+		LIMIT $3
+	`, userID, provider, scrobbleListLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scrobbles: %w", err)
+	}
+	return scrobbles, nil
+}
+
+// Disconnect removes one of a user's scrobbling accounts along with its
+// queue
+func (s *ScrobbleService) Disconnect(ctx context.Context, userID, provider string) error {
+	result, err := s.db.ExecContext(ctx,
+		"DELETE FROM scrobble_accounts WHERE user_id = $1 AND provider = $2", userID, provider)
+	if err != nil {
+		return fmt.Errorf("failed to disconnect scrobbling account: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return apperr.NotFound("scrobbling_account_not_found", "No such scrobbling account is connected")
+	}
+	return nil
+}
+
+// scrobbleable reports whether a listen lasted long enough to scrobble.
+// Listens of unknown length need the full four minutes.
+func scrobbleable(listen *models.PlayEvent) bool {
+	duration := time.Duration(listen.DurationMs) * time.Millisecond
+	if listen.DurationMs > 0 && duration <= minScrobbleTrack {
+		return false
+	}
+	needed := scrobbleListenCap
+	if listen.DurationMs > 0 {
+		needed = min(duration/2, scrobbleListenCap)
+	}
+	return time.Duration(listen.ListenedMs)*time.Millisecond >= needed
+}
+
+// EnqueueListen queues a listen for each of the user's scrobbling accounts
+func (s *ScrobbleService) EnqueueListen(ctx context.Context, listen *models.PlayEvent, album string) error {
+	if !scrobbleable(listen) {
+		return nil
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO scrobbles (user_id, provider, artist, track, album, duration_ms, started_at)
+		SELECT user_id, provider, $2, $3, $4, $5, $6
+		FROM scrobble_accounts
+		WHERE user_id = $1
+	`, listen.UserID, listen.Artist, listen.Name, album, listen.DurationMs, listen.StartedAt)
+	if err != nil {
+		return fmt.Errorf("failed to queue scrobbles: %w", err)
+	}
+	return nil
+}
+
+// dueScrobble is a claimed scrobble with the session it's submitted with
+type dueScrobble struct {
+	models.Scrobble
+	SessionKey string `db:"session_key"`
+}
+
+// scrobbleAccountKey identifies the account a batch is submitted to
+type scrobbleAccountKey struct {
+	userID   string
+	provider string
+}
+
+// SubmitDue submits scrobbles whose next attempt is due, returning how many
+// were accepted. Each account's scrobbles go in as few requests as the
+// service allows.
+func (s *ScrobbleService) SubmitDue(ctx context.Context) (int, error) {
+	var due []dueScrobble
+	err := s.db.SelectContext(ctx, &due, `
+		UPDATE scrobbles s
+		SET next_attempt_at = NOW() + $1 * INTERVAL '1 second'
+		FROM scrobble_accounts a
+		WHERE a.user_id = s.user_id AND a.provider = s.provider AND s.id IN (
+			SELECT id FROM scrobbles
+			WHERE status = $2 AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING s.*, a.session_key
+	`, int(scrobbleLease.Seconds()), ScrobblePending, scrobbleBatch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim scrobbles: %w", err)
+	}
+
+	byAccount := make(map[scrobbleAccountKey][]dueScrobble)
+	for _, scrobble := range due {
+		key := scrobbleAccountKey{userID: scrobble.UserID, provider: scrobble.Provider}
+		byAccount[key] = append(byAccount[key], scrobble)
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		submitted int
+	)
+	for key, scrobbles := range byAccount {
+		wg.Add(1)
+		go func(key scrobbleAccountKey, scrobbles []dueScrobble) {
+			defer wg.Done()
+			n := s.submitAccount(ctx, key, scrobbles)
+			mu.Lock()
+			submitted += n
+			mu.Unlock()
+		}(key, scrobbles)
+	}
+	wg.Wait()
+	return submitted, nil
+}
+
+// scrobbleBatchSize is the most scrobbles a provider takes in one request
+func scrobbleBatchSize(provider string) int {
+	if provider == ScrobblerListenBrainz {
+		return listenbrainz.MaxListensPerRequest
+	}
+	return lastfm.MaxScrobbleBatch
+}
+
+// credentialRevoked reports whether a provider rejected an account's session
+// or token, which happens when the user revokes access
+func credentialRevoked(err error) bool {
+	return errors.Is(err, lastfm.ErrInvalidSession) || errors.Is(err, listenbrainz.ErrInvalidToken)
+}
+
+// permanentScrobbleError reports whether a provider rejected scrobbles in a
+// way retrying won't fix
+func permanentScrobbleError(err error) bool {
+	var lastfmErr *lastfm.Error
+	if errors.As(err, &lastfmErr) {
+		return !lastfmErr.Temporary()
+	}
+	var listenBrainzErr *listenbrainz.Error
+	if errors.As(err, &listenBrainzErr) {
+		return !listenBrainzErr.Temporary()
+	}
+	return false
+}
+
+// submitAccount submits one account's due scrobbles in batches, returning
+// how many were accepted. A revoked credential disconnects the account.
+func (s *ScrobbleService) submitAccount(ctx context.Context, key scrobbleAccountKey, scrobbles []dueScrobble) int {
+	logger := s.logger.With().Str("user_id", key.userID).Str("provider", key.provider).Logger()
+
+	submitted := 0
+	for len(scrobbles) > 0 {
+		batch := scrobbles[:min(len(scrobbles), scrobbleBatchSize(key.provider))]
+		scrobbles = scrobbles[len(batch):]
+
+		err := s.submit(ctx, key.provider, batch)
+		if credentialRevoked(err) {
+			logger.Info().Msg("Scrobbling access was revoked, disconnecting account")
+			if err := s.Disconnect(ctx, key.userID, key.provider); err != nil {
+				logger.Error().Err(err).Msg("Failed to disconnect revoked scrobbling account")
+			}
+			return submitted
+		}
+		if err := s.recordAttempt(ctx, batch, err); err != nil {
+			logger.Error().Err(err).Msg("Failed to record scrobble attempt")
+		}
+		if err != nil {
+			// The service is likely down, so the rest are left to be claimed
+			// again once their lease runs out
+			logger.Warn().Err(err).Int("scrobbles", len(batch)).Msg("Failed to submit scrobbles")
+			return submitted
+		}
+		submitted += len(batch)
+	}
+	return submitted
+}
+
+// submit sends a batch of one account's scrobbles to its service
+func (s *ScrobbleService) submit(ctx context.Context, provider string, batch []dueScrobble) error {
+	switch provider {
+	case ScrobblerLastFM:
+		if s.lastfm == nil {
+			return errors.New("Last.fm isn't configured")
+		}
+		scrobbles := make([]lastfm.Scrobble, len(batch))
+		for i, scrobble := range batch {
+			scrobbles[i] = lastfm.Scrobble{
+				Artist:     scrobble.Artist,
+				Track:      scrobble.Track,
+				Album:      scrobble.Album,
+				DurationMs: scrobble.DurationMs,
+				StartedAt:  scrobble.StartedAt,
+			}
+		}
+		result, err := s.lastfm.Scrobble(ctx, batch[0].SessionKey, scrobbles)
+		if err != nil {
+			return err
+		}
+		if result.Ignored > 0 {
+			s.logger.Debug().Ctx(ctx).Int("ignored", result.Ignored).Msg("Last.fm ignored scrobbles")
+		}
+		return nil
+	case ScrobblerListenBrainz:
+		if s.listenbrainz == nil {
+			return errors.New("ListenBrainz isn't enabled")
+		}
+		listens := make([]listenbrainz.Listen, len(batch))
+		for i, scrobble := range batch {
+			listens[i] = listenbrainz.Listen{
+				Artist:     scrobble.Artist,
+				Track:      scrobble.Track,
+				Album:      scrobble.Album,
+				DurationMs: scrobble.DurationMs,
+				ListenedAt: scrobble.StartedAt,
+			}
+		}
+		return s.listenbrainz.SubmitListens(ctx, batch[0].SessionKey, listens)
+	}
+	return fmt.Errorf("unknown scrobbling provider %q", provider)
+}
+
+// recordAttempt logs the outcome of submitting scrobbles, scheduling a retry
+// after a failure until they run out of attempts. Errors the service won't
+// recover from fail the scrobbles straight away.
+func (s *ScrobbleService) recordAttempt(ctx context.Context, batch []dueScrobble, submitErr error) error {
+	ids := make([]int64, len(batch))
+	for i, scrobble := range batch {
+		ids[i] = scrobble.ID
+	}
+
+	if submitErr == nil {
+		_, err := s.db.ExecContext(ctx, `
+			UPDATE scrobbles
+			SET status = $2, attempts = attempts + 1, last_error = '', submitted_at = NOW()
+			WHERE id = ANY($1)
+		`, pq.Array(ids), ScrobbleSubmitted)
+		return err
+	}
+
+	permanent := permanentScrobbleError(submitErr)
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE scrobbles
+		SET attempts = attempts + 1, last_error = $2,
+			status = CASE WHEN $3 OR attempts + 1 >= $4 THEN $5 ELSE status END,
+			next_attempt_at = NOW() + $6 * POWER(2, LEAST(attempts, $7)) * INTERVAL '1 second'
+		WHERE id = ANY($1)
+	`, pq.Array(ids), submitErr.Error(), permanent, s.maxAttempts, ScrobbleFailed,
+		int(scrobbleRetryBase.Seconds()), maxScrobbleRetryDoublings)
+	return err
+}
+
+// PurgeScrobbles deletes finished scrobbles created before the cutoff,
+// returning how many were removed
+func (s *ScrobbleService) PurgeScrobbles(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx,
+		"DELETE FROM scrobbles WHERE created_at < $1 AND status <> $2", before, ScrobblePending)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge scrobbles: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,294 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/google/uuid"
+)
+
+const (
+	// sessionCacheTTL is how long a validated session is cached in Redis.
+	// Ending a session deletes its entry, so this only bounds how stale the
+	// cached activity gets.
+	sessionCacheTTL = 5 * time.Minute
+
+	// sessionTouchInterval is how often a session in use has its last
+	// activity and expiry moved forward
+	sessionTouchInterval = 5 * time.Minute
+)
+
+// cachedSession is a session as cached in Redis, where the user it belongs
+// to has to be kept
+type cachedSession struct {
+	models.Session
+	UserID string `json:"user_id"`
+}
+
+// hashSessionToken hashes a session token for storage and lookup
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func sessionCacheKey(tokenHash string) string {
+	return "session:" + tokenHash
+}
+
+// SessionLifetime is how long a session lasts without being used
+func (s *UserService) SessionLifetime() time.Duration {
+	return s.sessionLifetime
+}
+
+// CreateSession signs a browser in as userID. The returned token goes in the
+// session cookie; only its hash is stored.
+func (s *UserService) CreateSession(ctx context.Context, userID, ipAddress, userAgent string) (string, *models.Session, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", nil, fmt.Errorf("failed to generate session token: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+
+	now := time.Now()
+	session := &models.Session{
+		ID:           uuid.New().String(),
+		UserID:       userID,
+		TokenHash:    hashSessionToken(token),
+		IPAddress:    ipAddress,
+		UserAgent:    userAgent,
+		CreatedAt:    now,
+		LastActiveAt: now,
+		ExpiresAt:    now.Add(s.sessionLifetime),
+	}
+	_, err := s.db.NamedExecContext(ctx, `
+		INSERT INTO sessions (id, user_id, token_hash, ip_address, user_agent, created_at, last_active_at, expires_at)
+		VALUES (:id, :user_id, :token_hash, :ip_address, :user_agent, :created_at, :last_active_at, :expires_at)
+	`, session)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	return token, session, nil
+}
+
+// AuthenticateSession returns the session a token belongs to, if it hasn't
+// expired or been ended. Sessions are looked up in Redis before Postgres,
+// and every few minutes of use push their expiry back.
+func (s *UserService) AuthenticateSession(ctx context.Context, token string) (*models.Session, error) {
+	tokenHash := hashSessionToken(token)
+
+	session, cached := s.getCachedSession(ctx, tokenHash)
+	if !cached {
+		var stored models.Session
+		err := s.db.GetContext(ctx, &stored,
+			"SELECT * FROM sessions WHERE token_hash = $1 AND expires_at > NOW()", tokenHash)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperr.Unauthorized("invalid_session", "Session expired or signed out")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up session: %w", err)
+		}
+		session = &stored
+	}
+	if !session.ExpiresAt.After(time.Now()) {
+		return nil, apperr.Unauthorized("invalid_session", "Session expired or signed out")
+	}
+
+	if time.Since(session.LastActiveAt) >= sessionTouchInterval {
+		err := s.touchSession(ctx, session)
+		if errors.Is(err, errSessionEnded) {
+			// Ended in Postgres but still cached, such as when Redis was
+			// down at sign-out
+			s.uncacheSessions(ctx, tokenHash)
+			return nil, apperr.Unauthorized("invalid_session", "Session expired or signed out")
+		}
+		if err != nil {
+			s.logger.Warn().Ctx(ctx).Err(err).Msg("Failed to record session activity")
+		}
+	} else if cached {
+		return session, nil
+	}
+	s.cacheSession(ctx, session)
+	return session, nil
+}
+
+// errSessionEnded is returned by touchSession when the session no longer
+// exists in Postgres
+var errSessionEnded = errors.New("session ended")
+
+// touchSession records that a session was just used and extends it. It
+// returns errSessionEnded if the session was deleted or has expired.
+func (s *UserService) touchSession(ctx context.Context, session *models.Session) error {
+	now := time.Now()
+	expiresAt := now.Add(s.sessionLifetime)
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE sessions SET last_active_at = $2, expires_at = $3 WHERE id = $1 AND expires_at > NOW()",
+		session.ID, now, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to update session activity: %w", err)
+	}
+	if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+		return errSessionEnded
+	}
+	session.LastActiveAt = now
+	session.ExpiresAt = expiresAt
+	return nil
+}
+
+// EndSession signs out the browser holding token. Ending a session that
+// doesn't exist is not an error.
+func (s *UserService) EndSession(ctx context.Context, token string) error {
+	tokenHash := hashSessionToken(token)
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM sessions WHERE token_hash = $1", tokenHash); err != nil {
+		return fmt.Errorf("failed to end session: %w", err)
+	}
+	s.uncacheSessions(ctx, tokenHash)
+	return nil
+}
+
+// ListSessions returns a user's unexpired sessions, most recently active
+// first
+func (s *UserService) ListSessions(ctx context.Context, userID string) ([]models.Session, error) {
+	sessions := []models.Session{}
+	err := s.db.SelectContext(ctx, &sessions, `
+		SELECT * FROM sessions
+		WHERE user_id = $1 AND expires_at > NOW()
+		ORDER BY last_active_at DESC, created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// RevokeSession signs out one of a user's sessions
+func (s *UserService) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return apperr.NotFound("session_not_found", "Session not found")
+	}
+
+	var tokenHashes []string
+	err := s.db.SelectContext(ctx, &tokenHashes,
+		"DELETE FROM sessions WHERE id = $1 AND user_id = $2 RETURNING token_hash", sessionID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if len(tokenHashes) == 0 {
+		return apperr.NotFound("session_not_found", "Session not found")
+	}
+	s.uncacheSessions(ctx, tokenHashes...)
+	return nil
+}
+
+// RevokeOtherSessions signs out every one of a user's sessions except
+// keepID, returning how many were revoked
+func (s *UserService) RevokeOtherSessions(ctx context.Context, userID, keepID string) (int, error) {
+	var tokenHashes []string
+	err := s.db.SelectContext(ctx, &tokenHashes,
+		"DELETE FROM sessions WHERE user_id = $1 AND id <> $2 RETURNING token_hash", userID, keepID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	s.uncacheSessions(ctx, tokenHashes...)
+	return len(tokenHashes), nil
+}
+
+// PurgeExpiredSessions deletes sessions that have expired, returning how
+// many were removed
+func (s *UserService) PurgeExpiredSessions(ctx context.Context) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM sessions WHERE expires_at <= NOW()")
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge expired sessions: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// getCachedSession reads a session from Redis. Misses and errors both fall
+// back to Postgres.
+func (s *UserService) getCachedSession(ctx context.Context, tokenHash string) (*models.Session, bool) {
+	if !s.redis.Available() {
+		return nil, false
+	}
+	if s.flushPendingUncaches(ctx, tokenHash) {
+		return nil, false
+	}
+	value, err := s.redis.Get(ctx, sessionCacheKey(tokenHash))
+	if err != nil {
+		return nil, false
+	}
+	var entry cachedSession
+	if err := json.Unmarshal([]byte(value), &entry); err != nil {
+		return nil, false
+	}
+	entry.Session.UserID = entry.UserID
+	entry.Session.TokenHash = tokenHash
+	return &entry.Session, true
+}
+
+// cacheSession caches a validated session in Redis, never past its expiry
+func (s *UserService) cacheSession(ctx context.Context, session *models.Session) {
+	if !s.redis.Available() {
+		return
+	}
+	ttl := min(sessionCacheTTL, time.Until(session.ExpiresAt))
+	if ttl <= 0 {
+		return
+	}
+	value, err := json.Marshal(cachedSession{Session: *session, UserID: session.UserID})
+	if err != nil {
+		return
+	}
+	if err := s.redis.Set(ctx, sessionCacheKey(session.TokenHash), value, ttl); err != nil {
+		s.logger.Debug().Ctx(ctx).Err(err).Msg("Failed to cache session")
+	}
+}
+
+// uncacheSessions drops ended sessions from the Redis cache. Entries that
+// can't be deleted, such as while Redis is down, are retried once it is back
+// so the sessions aren't served from the cache again.
+func (s *UserService) uncacheSessions(ctx context.Context, tokenHashes ...string) {
+	for _, tokenHash := range tokenHashes {
+		if !s.redis.Available() {
+			s.deferUncache(tokenHash)
+			continue
+		}
+		if err := s.redis.Delete(ctx, sessionCacheKey(tokenHash)); err != nil {
+			s.logger.Warn().Ctx(ctx).Err(err).Msg("Failed to drop ended session from cache")
+			s.deferUncache(tokenHash)
+		}
+	}
+}
+
+func (s *UserService) deferUncache(tokenHash string) {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	if s.pendingUncaches == nil {
+		s.pendingUncaches = make(map[string]struct{})
+	}
+	s.pendingUncaches[tokenHash] = struct{}{}
+}
+
+// flushPendingUncaches deletes the cache entries of sessions ended while
+// Redis was down. It reports whether tokenHash was among them and is still
+// waiting, in which case its cache entry can't be trusted.
+func (s *UserService) flushPendingUncaches(ctx context.Context, tokenHash string) bool {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	for pending := range s.pendingUncaches {
+		if err := s.redis.Delete(ctx, sessionCacheKey(pending)); err != nil {
+			s.logger.Warn().Ctx(ctx).Err(err).Msg("Failed to drop ended session from cache")
+			continue
+		}
+		delete(s.pendingUncaches, pending)
+	}
+	_, waiting := s.pendingUncaches[tokenHash]
+	return waiting
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/database"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/rs/zerolog"
+)
+
+// shortLinkAlphabet leaves out characters that are easy to misread
+const shortLinkAlphabet = "23456789abcdefghjkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// shortLinkCodeLength gives about 10^12 codes, so collisions are rare
+const shortLinkCodeLength = 7
+
+// maxShortLinksPerUser keeps one account from filling the code space
+const maxShortLinksPerUser = 100
+
+// topReferrerLimit is how many referrers link stats report
+const topReferrerLimit = 10
+
+// ShortLinkService manages short /s/:code links to profiles and counts
+// their clicks
+type ShortLinkService struct {
+	db     *database.DB
+	logger zerolog.Logger
+}
+
+// NewShortLinkService creates a new short link service
+func NewShortLinkService(db *database.DB, logger zerolog.Logger) *ShortLinkService {
+	return &ShortLinkService{
+		db:     db,
+		logger: logger.With().Str("service", "short_link").Logger(),
+	}
+}
+
+func generateShortLinkCode() (string, error) {
+	code := make([]byte, shortLinkCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(shortLinkAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = shortLinkAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// CreateLink creates a short link to a user's profile. A nil expiresAt never
+// expires.
+func (s *ShortLinkService) CreateLink(ctx context.Context, userID, label string, expiresAt *time.Time) (*models.ShortLink, error) {
+	var count int
+	if err := s.db.GetContext(ctx, &count, "SELECT COUNT(*) FROM short_links WHERE user_id = $1", userID); err != nil {
+		return nil, fmt.Errorf("failed to count short links: %w", err)
+	}
+	if count >= maxShortLinksPerUser {
+		return nil, apperr.Conflict("short_link_limit", fmt.Sprintf("You can have up to %d short links; delete one first", maxShortLinksPerUser))
+	}
+
+	link := &models.ShortLink{
+		UserID:    userID,
+		Label:     label,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+
+	// Retry the rare code collision with a fresh code
+	for attempt := 0; attempt < 3; attempt++ {
+		code, err := generateShortLinkCode()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate short link code: %w", err)
+		}
+		link.Code = code
+
+		result, err := s.db.NamedExecContext(ctx, `
+			INSERT INTO short_links (code, user_id, label, expires_at, created_at)
+			VALUES (:code, :user_id, :label, :expires_at, :created_at)
+			ON CONFLICT (code) DO NOTHING
+		`, link)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create short link: %w", err)
+		}
+		if inserted, _ := result.RowsAffected(); inserted == 1 {
+			return link, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to create short link: no free code after retries")
+}
+
+// ListLinks returns a user's short links, newest first
+func (s *ShortLinkService) ListLinks(ctx context.Context, userID string) ([]models.ShortLink, error) {
+	links := []models.ShortLink{}
+	err := s.db.SelectContext(ctx, &links,
+		"SELECT * FROM short_links WHERE user_id = $1 ORDER BY created_at DESC", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list short links: %w", err)
+	}
+	return links, nil
+}
+
+// GetLinkStats returns one of a user's short links with its top referrers
+func (s *ShortLinkService) GetLinkStats(ctx context.Context, userID, code string) (*models.ShortLinkStats, error) {
+	var stats models.ShortLinkStats
+	err := s.db.GetContext(ctx, &stats.ShortLink,
+		"SELECT * FROM short_links WHERE code = $1 AND user_id = $2", code, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperr.NotFound("short_link_not_found", "Short link not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get short link: %w", err)
+	}
+
+	stats.TopReferrers = []models.ReferrerCount{}
+	err = s.db.SelectContext(ctx, &stats.TopReferrers, `
+		SELECT referrer, COUNT(*) AS clicks
+		FROM short_link_clicks
+		WHERE code = $1
+		GROUP BY referrer
+		ORDER BY clicks DESC, referrer
+		LIMIT $2
+	`, code, topReferrerLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get short link referrers: %w", err)
+	}
+	return &stats, nil
+}
+
+// DeleteLink deletes one of a user's short links and its click history
+func (s *ShortLinkService) DeleteLink(ctx context.Context, userID, code string) error {
+	result, err := s.db.ExecContext(ctx,
+		"DELETE FROM short_links WHERE code = $1 AND user_id = $2", code, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete short link: %w", err)
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return apperr.NotFound("short_link_not_found", "Short link not found")
+	}
+	return nil
+}
+
+// ResolveLink records a click on a short link and returns the user it points
+// to. Expired links are not found. Only the referring site is kept, not the
+// full referrer URL, since paths can carry personal data.
+func (s *ShortLinkService) ResolveLink(ctx context.Context, code, referrer string) (string, error) {
+	var userID string
+	err := s.db.GetContext(ctx, &userID, `
+		UPDATE short_links SET click_count = click_count + 1, last_clicked_at = NOW()
+		WHERE code = $1 AND (expires_at IS NULL OR expires_at > NOW())
+		RETURNING user_id
+	`, code)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", apperr.NotFound("short_link_not_found", "Short link not found")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve short link: %w", err)
+	}
+
+	// The redirect matters more than the analytics
+	_, err = s.db.ExecContext(ctx,
+		"INSERT INTO short_link_clicks (code, referrer, clicked_at) VALUES ($1, $2, NOW())",
+		code, referrerHost(referrer))
+	if err != nil {
+		s.logger.Warn().Ctx(ctx).Err(err).Str("code", code).Msg("Failed to record short link click")
+	}
+	return userID, nil
+}
+
+// PurgeClicks deletes click records older than cutoff. Link click counts are
+// kept. It returns the number of clicks deleted.
+func (s *ShortLinkService) PurgeClicks(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM short_link_clicks WHERE clicked_at < $1", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge short link clicks: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// referrerHost reduces a Referer header to its host, or "direct" without one
+func referrerHost(referrer string) string {
+	if referrer == "" {
+		return "direct"
+	}
+	parsed, err := url.Parse(referrer)
+	if err != nil || parsed.Host == "" {
+		return "unknown"
+	}
+	return parsed.Hostname()
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+)
+
+// DefaultSpeechLocale is used when the caller asks for no supported locale
+const DefaultSpeechLocale = "en"
+
+// speechPhrases holds one locale's sentences for voice assistants
+type speechPhrases struct {
+	// playing takes the listener, track, artist, and elapsed phrase
+	playing    string
+	notPlaying string
+	// elapsed describes how far into the track playback is, by whole minutes
+	elapsed func(minutes int) string
+}
+
+var speechLocales = map[string]speechPhrases{
+	"en": {
+		playing:    "%s is listening to %s by %s, %s.",
+		notPlaying: "%s isn't listening to anything right now.",
+		elapsed: func(minutes int) string {
+			switch minutes {
+			case 0:
+				return "just started"
+			case 1:
+				return "1 minute in"
+			}
+			return fmt.Sprintf("%d minutes in", minutes)
+		},
+	},
+	"es": {
+		playing:    "%s está escuchando %s de %s, %s.",
+		notPlaying: "%s no está escuchando nada ahora mismo.",
+		elapsed: func(minutes int) string {
+			switch minutes {
+			case 0:
+				return "acaba de empezar"
+			case 1:
+				return "va por el minuto 1"
+			}
+			return fmt.Sprintf("va por el minuto %d", minutes)
+		},
+	},
+	"fr": {
+		playing:    "%s écoute %s de %s, %s.",
+		notPlaying: "%s n'écoute rien en ce moment.",
+		elapsed: func(minutes int) string {
+			switch minutes {
+			case 0:
+				return "ça vient de commencer"
+			case 1:
+				return "depuis 1 minute"
+			}
+			return fmt.Sprintf("depuis %d minutes", minutes)
+		},
+	},
+	"de": {
+		playing:    "%s hört gerade %s von %s, %s.",
+		notPlaying: "%s hört gerade nichts.",
+		elapsed: func(minutes int) string {
+			switch minutes {
+			case 0:
+				return "gerade erst angefangen"
+			case 1:
+				return "seit einer Minute"
+			}
+			return fmt.Sprintf("seit %d Minuten", minutes)
+		},
+	},
+}
+
+// SpeechLocales lists the supported speech locales
+func SpeechLocales() []string {
+	return []string{"en", "es", "fr", "de"}
+}
+
+// MatchSpeechLocale picks the first supported locale from tags such as
+// "de-DE" or an Accept-Language header, falling back to DefaultSpeechLocale.
+// Only the language is matched; regional variants share phrasing.
+func MatchSpeechLocale(tags ...string) string {
+	for _, tag := range tags {
+		for _, part := range strings.Split(tag, ",") {
+			lang, _, _ := strings.Cut(strings.TrimSpace(part), ";")
+			lang, _, _ = strings.Cut(lang, "-")
+			lang, _, _ = strings.Cut(lang, "_")
+			lang = strings.ToLower(lang)
+			if _, ok := speechLocales[lang]; ok {
+				return lang
+			}
+		}
+	}
+	return DefaultSpeechLocale
+}
+
+// NowPlayingSpeech describes what a listener is playing in a sentence a voice
+// assistant can read out, such as "Sam is listening to Song by Artist,
+// 2 minutes in." A nil or paused track is reported as nothing playing.
+func NowPlayingSpeech(listener string, track *models.SpotifyCurrentlyPlaying, locale string) string {
+	phrases, ok := speechLocales[locale]
+	if !ok {
+		phrases = speechLocales[DefaultSpeechLocale]
+	}
+	if track == nil || !track.IsPlaying {
+		return fmt.Sprintf(phrases.notPlaying, listener)
+	}
+
+	// Progress was measured when the track was published; playback has moved on since
+	progress := time.Duration(track.ProgressMs) * time.Millisecond
+	if track.PublishedAt > 0 {
+		progress += time.Since(time.UnixMilli(track.PublishedAt))
+	}
+	if track.DurationMs > 0 {
+		progress = min(progress, time.Duration(track.DurationMs)*time.Millisecond)
+	}
+	return fmt.Sprintf(phrases.playing, listener, track.TrackName, track.ArtistName, phrases.elapsed(int(progress.Minutes())))
+}
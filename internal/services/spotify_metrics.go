@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/metrics"
+)
+
+// Spotify API operations, as recorded in spotify_requests_total
+const (
+	SpotifyOpTokenExchange    = "token_exchange"
+	SpotifyOpTokenRefresh     = "token_refresh"
+	SpotifyOpUserProfile      = "user_profile"
+	SpotifyOpCurrentlyPlaying = "currently_playing"
+	SpotifyOpRecentlyPlayed   = "recently_played"
+	SpotifyOpAudioFeatures    = "audio_features"
+)
+
+var spotifyOperations = []string{SpotifyOpTokenExchange, SpotifyOpTokenRefresh, SpotifyOpUserProfile, SpotifyOpCurrentlyPlaying, SpotifyOpRecentlyPlayed, SpotifyOpAudioFeatures}
+
+var spotifyRequests = metrics.NewCounterVec(
+	"spotify_requests_total",
+	"Spotify API calls by operation and result (success, error).",
+	"operation", "result",
+)
+
+// observeSpotify records the outcome of a Spotify API call. Calls abandoned
+// because the caller went away say nothing about Spotify and are skipped.
+func observeSpotify(operation string, err error) {
+	switch {
+	case err == nil:
+		spotifyRequests.Inc(operation, "success")
+	case errors.Is(err, context.Canceled):
+	default:
+		spotifyRequests.Inc(operation, "error")
+	}
+}
+
+// SpotifyRequestCounts returns the cumulative successful and failed Spotify
+// calls for an operation, or across all operations when operation is empty
+func SpotifyRequestCounts(operation string) (success, failed float64) {
+	operations := spotifyOperations
+	if operation != "" {
+		operations = []string{operation}
+	}
+	for _, op := range operations {
+		success += spotifyRequests.Value(op, "success")
+		failed += spotifyRequests.Value(op, "error")
+	}
+	return success, failed
+}
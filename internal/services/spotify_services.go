@@ -5,150 +5,379 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/cache"
 	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
 	"github.com/brandonhuynh1/whatamilisteningto-api/internal/database"
 	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/realtime"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/utils"
 	"github.com/brandonhuynh1/whatamilisteningto-api/pkg/spotify"
-	"github.com/go-redis/redis/v8"
-	"github.com/jmoiron/sqlx"
 	"github.com/rs/zerolog"
 )
 
 // SpotifyService handles interaction with the Spotify API
 type SpotifyService struct {
-	spotifyClient *spotify.Client
+	spotifyClient spotify.API
+	fake          bool
+	tenants       *TenantService
+	tenantHTTP    *http.Client
 	redis         *database.RedisClient
+	hotTracks     *cache.Cache[*models.SpotifyCurrentlyPlaying]
+	trackUpdates  *realtime.Hub
+	backoffMu     sync.Mutex
+	backoffs      map[string]*rateLimitBackoff
+	nowPlayingTTL atomic.Int64
+	onTrackChange []TrackChangeHook
 	logger        zerolog.Logger
 }
 
-// NewSpotifyService creates a new Spotify service
-func NewSpotifyService(cfg config.SpotifyConfig, redis *database.RedisClient, logger zerolog.Logger) *SpotifyService {
-	return &SpotifyService{
-		spotifyClient: spotify.NewClient(cfg.ClientID, cfg.ClientSecret, cfg.RedirectURI),
+// TrackChangeHook is called with every track change NotifyTrackChange
+// announces
+type TrackChangeHook func(ctx context.Context, userID string, track *models.SpotifyCurrentlyPlaying)
+
+// NewSpotifyService creates a new Spotify service. Tenants with their own
+// Spotify app are authorized with it; everyone else uses cfg's app.
+func NewSpotifyService(cfg config.SpotifyConfig, cacheCfg config.CacheConfig, redis *database.RedisClient, tenants *TenantService, logger zerolog.Logger) *SpotifyService {
+	var client spotify.API = spotify.NewClient(cfg.ClientID, cfg.ClientSecret, cfg.RedirectURI)
+	if cfg.Fake {
+		client = spotify.NewFakeClient(cfg.RedirectURI, time.Duration(cfg.FakeTrackSeconds)*time.Second)
+	}
+
+	s := &SpotifyService{
+		spotifyClient: client,
+		fake:          cfg.Fake,
+		tenants:       tenants,
+		tenantHTTP:    &http.Client{Timeout: 10 * time.Second},
+		backoffs:      make(map[string]*rateLimitBackoff),
 		redis:         redis,
-		logger:        logger.With().Str("service", "spotify").Logger(),
+		hotTracks:     cache.New[*models.SpotifyCurrentlyPlaying](time.Duration(cacheCfg.HotTTLMillis)*time.Millisecond, cacheCfg.HotMaxEntries),
+		logger:        utils.ModuleLogger(logger.With().Str("service", "spotify").Logger(), utils.LogModuleSpotify),
+	}
+	s.trackUpdates = realtime.NewHub(redis, s.logger)
+	s.nowPlayingTTL.Store(int64(time.Duration(cacheCfg.NowPlayingTTLSeconds) * time.Second))
+	if cfg.Fake {
+		s.logger.Warn().Msg("DEV_FAKE_SPOTIFY is enabled, Spotify is replaced by generated tracks")
 	}
+	return s
+}
+
+// CacheStats reports the hot now-playing cache's effectiveness
+func (s *SpotifyService) CacheStats() cache.Stats {
+	return s.hotTracks.Stats()
+}
+
+// UpdateCacheConfig applies new cache TTLs and limits at runtime
+func (s *SpotifyService) UpdateCacheConfig(cacheCfg config.CacheConfig) {
+	s.hotTracks.SetLimits(time.Duration(cacheCfg.HotTTLMillis)*time.Millisecond, cacheCfg.HotMaxEntries)
+	s.nowPlayingTTL.Store(int64(time.Duration(cacheCfg.NowPlayingTTLSeconds) * time.Second))
 }
 
-// GetAuthURL returns the Spotify authorization URL
-func (s *SpotifyService) GetAuthURL(state string) string {
-	return s.spotifyClient.GetAuthURL(state, []string{
+// clientFor returns the Spotify app client for a tenant, or the default app's
+// for nil. The fake replaces every tenant's app in development.
+func (s *SpotifyService) clientFor(tenant *models.Tenant) spotify.API {
+	if tenant == nil || s.fake {
+		return s.spotifyClient
+	}
+	client := spotify.NewClient(tenant.SpotifyClientID, tenant.SpotifyClientSecret, tenant.SpotifyRedirectURI)
+	client.HTTPClient = s.tenantHTTP
+	return client
+}
+
+// backoffFor returns the rate limit backoff of a tenant's Spotify app, or the
+// default app's for nil. Spotify limits each app separately, so one tenant
+// hitting its limit doesn't stop calls for the others.
+func (s *SpotifyService) backoffFor(tenant *models.Tenant) *rateLimitBackoff {
+	clientID := ""
+	if tenant != nil && !s.fake {
+		clientID = tenant.SpotifyClientID
+	}
+
+	s.backoffMu.Lock()
+	defer s.backoffMu.Unlock()
+	b, ok := s.backoffs[clientID]
+	if !ok {
+		b = &rateLimitBackoff{provider: ProviderSpotify}
+		s.backoffs[clientID] = b
+	}
+	return b
+}
+
+// WithUserTenant scopes ctx to the tenant user belongs to, so calls made
+// with their token count against the backoff of the Spotify app that issued
+// it. ctx is returned as is for default users or when the tenant can't be
+// loaded.
+func (s *SpotifyService) WithUserTenant(ctx context.Context, user *models.User) context.Context {
+	if user.TenantID == nil {
+		return ctx
+	}
+	tenant, err := s.tenants.GetByID(ctx, *user.TenantID)
+	if err != nil {
+		s.logger.Warn().Ctx(ctx).Err(err).Msg("Failed to load user's tenant")
+		return ctx
+	}
+	return WithTenant(ctx, tenant)
+}
+
+// Name implements MusicProvider
+func (s *SpotifyService) Name() string {
+	return ProviderSpotify
+}
+
+// GetAuthURL returns the Spotify authorization URL for the tenant ctx is
+// scoped to
+func (s *SpotifyService) GetAuthURL(ctx context.Context, state string) string {
+	return s.clientFor(TenantFromContext(ctx)).GetAuthURL(state, []string{
 		"user-read-private",
 		"user-read-email",
 		"user-read-currently-playing",
+		"user-read-recently-played",
 	})
 }
 
-// ExchangeCodeForToken exchanges an authorization code for tokens
-func (s *SpotifyService) ExchangeCodeForToken(ctx context.Context, code string) (*spotify.TokenResponse, error) {
-	return s.spotifyClient.ExchangeCodeForToken(ctx, code)
+// ExchangeCodeForToken exchanges an authorization code for tokens with the
+// Spotify app of the tenant ctx is scoped to
+func (s *SpotifyService) ExchangeCodeForToken(ctx context.Context, code, _ string) (*ProviderToken, error) {
+	tenant := TenantFromContext(ctx)
+	backoff := s.backoffFor(tenant)
+	if err := backoff.check(); err != nil {
+		return nil, err
+	}
+	token, err := s.clientFor(tenant).ExchangeCodeForToken(ctx, code)
+	observeSpotify(SpotifyOpTokenExchange, err)
+	if err != nil {
+		return nil, s.observe(backoff, err)
+	}
+	return providerToken(token), nil
 }
 
-// RefreshAccessToken refreshes an access token
+// RefreshAccessToken refreshes an access token issued to the default app
 func (s *SpotifyService) RefreshAccessToken(ctx context.Context, refreshToken string) (*spotify.TokenResponse, error) {
-	return s.spotifyClient.RefreshAccessToken(ctx, refreshToken)
+	backoff := s.backoffFor(nil)
+	if err := backoff.check(); err != nil {
+		return nil, err
+	}
+	token, err := s.spotifyClient.RefreshAccessToken(ctx, refreshToken)
+	observeSpotify(SpotifyOpTokenRefresh, err)
+	if err != nil {
+		return nil, s.observe(backoff, err)
+	}
+	return token, nil
 }
 
-// GetUserProfile gets a user's Spotify profile
-func (s *SpotifyService) GetUserProfile(ctx context.Context, accessToken string) (string, string, string, error) {
-	profile, err := s.spotifyClient.GetUserProfile(ctx, accessToken)
+// RefreshUserToken refreshes a user's access token with their tenant's
+// Spotify app, since refresh tokens only work with the app that issued them
+func (s *SpotifyService) RefreshUserToken(ctx context.Context, user *models.User) (*ProviderToken, error) {
+	var tenant *models.Tenant
+	if user.TenantID != nil {
+		var err error
+		if tenant, err = s.tenants.GetByID(ctx, *user.TenantID); err != nil {
+			return nil, err
+		}
+	}
+	backoff := s.backoffFor(tenant)
+	if err := backoff.check(); err != nil {
+		return nil, err
+	}
+	token, err := s.clientFor(tenant).RefreshAccessToken(ctx, user.SpotifyRefreshToken)
+	observeSpotify(SpotifyOpTokenRefresh, err)
 	if err != nil {
-		return "", "", "", err
+		return nil, s.observe(backoff, err)
 	}
+	return providerToken(token), nil
+}
+
+// providerToken converts a Spotify token response
+func providerToken(token *spotify.TokenResponse) *ProviderToken {
+	return &ProviderToken{
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		ExpiresIn:    token.ExpiresIn,
+	}
+}
 
-	spotifyID, _ := profile["id"].(string)
-	email, _ := profile["email"].(string)
-	displayName, _ := profile["display_name"].(string)
+// GetAccount gets the Spotify profile a token belongs to
+func (s *SpotifyService) GetAccount(ctx context.Context, token *ProviderToken) (*ProviderAccount, error) {
+	backoff := s.backoffFor(TenantFromContext(ctx))
+	if err := backoff.check(); err != nil {
+		return nil, err
+	}
+	profile, err := s.spotifyClient.GetUserProfile(ctx, token.AccessToken)
+	observeSpotify(SpotifyOpUserProfile, err)
+	if err != nil {
+		return nil, s.observe(backoff, err)
+	}
 
-	return spotifyID, email, displayName, nil
+	return &ProviderAccount{
+		ID:          profile.ID,
+		Email:       profile.Email,
+		DisplayName: profile.DisplayName,
+	}, nil
 }
 
 // GetCurrentlyPlayingTrack gets the user's currently playing track
 func (s *SpotifyService) GetCurrentlyPlayingTrack(ctx context.Context, accessToken string) (*models.SpotifyCurrentlyPlaying, error) {
+	backoff := s.backoffFor(TenantFromContext(ctx))
+	if err := backoff.check(); err != nil {
+		return nil, err
+	}
 	result, err := s.spotifyClient.GetCurrentlyPlaying(ctx, accessToken)
+	observeSpotify(SpotifyOpCurrentlyPlaying, err)
 	if err != nil {
-		return nil, err
+		return nil, s.observe(backoff, err)
 	}
 
-	// If nothing is playing
-	if result == nil {
+	// Nothing is playing, or something without a track to show (an ad or an
+	// episode) is
+	if result == nil || result.Item == nil {
 		return &models.SpotifyCurrentlyPlaying{
 			IsPlaying: false,
 		}, nil
 	}
 
-	// Extract track information
-	isPlaying, _ := result["is_playing"].(bool)
+	track := nowPlayingFromSpotify(result.Item)
+	track.IsPlaying = result.IsPlaying
+	track.ProgressMs = result.ProgressMs
+	return track, nil
+}
 
-	item, ok := result["item"].(map[string]interface{})
-	if !ok {
-		return nil, errors.New("invalid response format")
+// GetRecentlyPlayed gets the user's most recently played tracks, newest first
+func (s *SpotifyService) GetRecentlyPlayed(ctx context.Context, accessToken string, limit int) ([]PlayedTrack, error) {
+	backoff := s.backoffFor(TenantFromContext(ctx))
+	if err := backoff.check(); err != nil {
+		return nil, err
+	}
+	result, err := s.spotifyClient.GetRecentlyPlayed(ctx, accessToken, limit)
+	observeSpotify(SpotifyOpRecentlyPlayed, err)
+	if err != nil {
+		return nil, s.observe(backoff, err)
 	}
 
-	trackID, _ := item["id"].(string)
-	trackName, _ := item["name"].(string)
-	trackURL, _ := item["external_urls"].(map[string]interface{})["spotify"].(string)
-	durationMs, _ := item["duration_ms"].(float64)
-	progressMs, _ := result["progress_ms"].(float64)
-
-	// Extract album information
-	album, ok := item["album"].(map[string]interface{})
-	if !ok {
-		return nil, errors.New("invalid album format")
+	played := make([]PlayedTrack, 0, len(result.Items))
+	for _, entry := range result.Items {
+		if entry.Track.ID == "" || entry.PlayedAt.IsZero() {
+			continue
+		}
+		played = append(played, PlayedTrack{Track: *nowPlayingFromSpotify(&entry.Track), PlayedAt: entry.PlayedAt})
 	}
+	return played, nil
+}
 
-	albumName, _ := album["name"].(string)
+// GetAudioFeatures gets the audio features of up to spotify.MaxAudioFeatureIDs
+// tracks. Tracks Spotify has no features for are left out.
+func (s *SpotifyService) GetAudioFeatures(ctx context.Context, accessToken string, trackIDs []string) ([]models.TrackFeatures, error) {
+	backoff := s.backoffFor(TenantFromContext(ctx))
+	if err := backoff.check(); err != nil {
+		return nil, err
+	}
+	result, err := s.spotifyClient.GetAudioFeatures(ctx, accessToken, trackIDs)
+	observeSpotify(SpotifyOpAudioFeatures, err)
+	if err != nil {
+		return nil, s.observe(backoff, err)
+	}
 
-	// Get album art (use the second-to-last image for medium size)
-	var albumArtURL string
-	if images, ok := album["images"].([]interface{}); ok && len(images) > 0 {
-		imageIdx := 1 // medium size
-		if len(images) == 1 {
-			imageIdx = 0
-		}
-		if image, ok := images[imageIdx].(map[string]interface{}); ok {
-			albumArtURL, _ = image["url"].(string)
+	features := make([]models.TrackFeatures, 0, len(result.AudioFeatures))
+	for _, entry := range result.AudioFeatures {
+		if entry == nil || entry.ID == "" {
+			continue
 		}
+		features = append(features, models.TrackFeatures{
+			SpotifyTrackID: entry.ID,
+			Tempo:          entry.Tempo,
+			Energy:         entry.Energy,
+			Danceability:   entry.Danceability,
+			Valence:        entry.Valence,
+		})
+	}
+	return features, nil
+}
+
+// observe starts backoff when Spotify answers 429, so calls to that app fail
+// fast until its Retry-After passes instead of extending the limit
+func (s *SpotifyService) observe(backoff *rateLimitBackoff, err error) error {
+	var rateLimited *spotify.RateLimitError
+	if errors.As(err, &rateLimited) {
+		s.logger.Warn().Dur("retry_after", rateLimited.RetryAfter).Msg("Spotify rate limit reached")
+		return backoff.limit(rateLimited.RetryAfter)
 	}
+	return err
+}
 
-	// Extract artist information
-	var artistName string
-	if artists, ok := item["artists"].([]interface{}); ok && len(artists) > 0 {
-		if artist, ok := artists[0].(map[string]interface{}); ok {
-			artistName, _ = artist["name"].(string)
+// nowPlayingFromSpotify extracts the fields the app uses from a Spotify track.
+// Every credited artist is listed, in order, as Spotify shows them.
+func nowPlayingFromSpotify(item *spotify.TrackObject) *models.SpotifyCurrentlyPlaying {
+	artists := make([]string, 0, len(item.Artists))
+	for _, artist := range item.Artists {
+		if artist.Name != "" {
+			artists = append(artists, artist.Name)
 		}
 	}
 
+	// Use the second image for medium size, or the only one there is
+	var albumArtURL string
+	if images := item.Album.Images; len(images) > 0 {
+		albumArtURL = images[min(1, len(images)-1)].URL
+	}
+
 	return &models.SpotifyCurrentlyPlaying{
-		IsPlaying:   isPlaying,
-		TrackID:     trackID,
-		TrackName:   trackName,
-		ArtistName:  artistName,
-		AlbumName:   albumName,
+		TrackID:     item.ID,
+		TrackName:   item.Name,
+		ArtistName:  strings.Join(artists, ", "),
+		AlbumName:   item.Album.Name,
 		AlbumArtURL: albumArtURL,
-		TrackURL:    trackURL,
-		DurationMs:  int(durationMs),
-		ProgressMs:  int(progressMs),
-	}, nil
+		TrackURL:    item.ExternalURLs.Spotify,
+		DurationMs:  item.DurationMs,
+	}
 }
 
-// CacheCurrentlyPlaying caches the currently playing track in Redis
+// CacheCurrentlyPlaying caches the currently playing track in Redis, stamping
+// ChangedAt when the track or play state differs from the cached one
 func (s *SpotifyService) CacheCurrentlyPlaying(ctx context.Context, userID string, track *models.SpotifyCurrentlyPlaying) error {
+	return s.CacheCurrentlyPlayingFor(ctx, userID, track, s.NowPlayingTTL())
+}
+
+// NowPlayingTTL returns how long a polled track stays cached
+func (s *SpotifyService) NowPlayingTTL() time.Duration {
+	return time.Duration(s.nowPlayingTTL.Load())
+}
+
+// CacheCurrentlyPlayingFor caches a track for ttl rather than the configured
+// expiration, for sources that report how long playback lasts
+func (s *SpotifyService) CacheCurrentlyPlayingFor(ctx context.Context, userID string, track *models.SpotifyCurrentlyPlaying, ttl time.Duration) error {
+	track.ChangedAt = time.Now().UnixMilli()
+	if previous, err := s.GetCachedCurrentlyPlaying(ctx, userID); err == nil &&
+		previous.TrackID == track.TrackID && previous.IsPlaying == track.IsPlaying && previous.ChangedAt > 0 {
+		track.ChangedAt = previous.ChangedAt
+	}
+
 	// Convert track to JSON
 	trackJSON, err := json.Marshal(track)
 	if err != nil {
 		return err
 	}
 
-	// Store in Redis with 2-minute expiration
+	// Store in Redis until ttl passes
 	key := fmt.Sprintf("track:current:%s", userID)
-	return s.redis.Set(ctx, key, trackJSON, 2*time.Minute)
+	if err := s.redis.Set(ctx, key, trackJSON, ttl); err != nil {
+		return err
+	}
+
+	s.hotTracks.Set(userID, track)
+	return nil
 }
 
-// GetCachedCurrentlyPlaying gets a cached currently playing track from Redis
+// GetCachedCurrentlyPlaying gets a cached currently playing track, checking the
+// in-process hot cache before Redis
 func (s *SpotifyService) GetCachedCurrentlyPlaying(ctx context.Context, userID string) (*models.SpotifyCurrentlyPlaying, error) {
+	if track, ok := s.hotTracks.Get(userID); ok {
+		return track, nil
+	}
+
 	key := fmt.Sprintf("track:current:%s", userID)
 	trackJSON, err := s.redis.Get(ctx, key)
 	if err != nil {
@@ -160,47 +389,95 @@ func (s *SpotifyService) GetCachedCurrentlyPlaying(ctx context.Context, userID s
 		return nil, err
 	}
 
+	s.hotTracks.Set(userID, &track)
 	return &track, nil
 }
 
-// NotifyTrackChange publishes a track change to Redis pub/sub
+// GetCachedCurrentlyPlayingBatch looks up cached tracks for several users,
+// reading hot-cache misses from Redis in one round trip. Users with nothing
+// cached are left out of the result.
+func (s *SpotifyService) GetCachedCurrentlyPlayingBatch(ctx context.Context, userIDs []string) (map[string]*models.SpotifyCurrentlyPlaying, error) {
+	tracks := make(map[string]*models.SpotifyCurrentlyPlaying, len(userIDs))
+	keys := make([]string, 0, len(userIDs))
+	keyUsers := make(map[string]string, len(userIDs))
+	for _, userID := range userIDs {
+		if track, ok := s.hotTracks.Get(userID); ok {
+			tracks[userID] = track
+			continue
+		}
+		key := fmt.Sprintf("track:current:%s", userID)
+		keys = append(keys, key)
+		keyUsers[key] = userID
+	}
+
+	if len(keys) == 0 || !s.redis.Available() {
+		return tracks, nil
+	}
+
+	values, err := s.redis.GetMany(ctx, keys...)
+	if err != nil {
+		return tracks, err
+	}
+
+	for key, value := range values {
+		var track models.SpotifyCurrentlyPlaying
+		if err := json.Unmarshal([]byte(value), &track); err != nil {
+			continue
+		}
+		userID := keyUsers[key]
+		s.hotTracks.Set(userID, &track)
+		tracks[userID] = &track
+	}
+
+	return tracks, nil
+}
+
+// InvalidateHotTrack drops a user's now-playing entry from the in-process cache
+func (s *SpotifyService) InvalidateHotTrack(userID string) {
+	s.hotTracks.Delete(userID)
+}
+
+// NotifyTrackChange publishes a track change to Redis pub/sub and runs the
+// track change hooks
 func (s *SpotifyService) NotifyTrackChange(ctx context.Context, userID string, track *models.SpotifyCurrentlyPlaying) error {
+	// Stamp a copy so subscribers can measure delivery lag
+	published := *track
+	published.PublishedAt = time.Now().UnixMilli()
+
 	// Convert track to JSON
-	trackJSON, err := json.Marshal(track)
+	trackJSON, err := json.Marshal(published)
 	if err != nil {
 		return err
 	}
 
 	// Publish to channel for this user
 	channel := fmt.Sprintf("track:updates:%s", userID)
-	return s.redis.Publish(ctx, channel, trackJSON)
-}
+	err = s.redis.Publish(ctx, channel, trackJSON)
 
-// SubscribeToTrackUpdates subscribes to track updates for a user
-func (s *SpotifyService) SubscribeToTrackUpdates(ctx context.Context, userID string) *redis.PubSub {
-	channel := fmt.Sprintf("track:updates:%s", userID)
-	return s.redis.Subscribe(ctx, channel)
+	// Hooks don't need Redis, so they run even when publishing failed
+	for _, hook := range s.onTrackChange {
+		hook(ctx, userID, track)
+	}
+	return err
 }
 
-// GetTrackHistory gets a user's track history
-func (s *SpotifyService) GetTrackHistory(ctx context.Context, userID string, limit int) ([]models.Track, error) {
-	var tracks []models.Track
-	query := `
-		SELECT * FROM tracks 
-		WHERE user_id = $1 
-		ORDER BY played_at DESC 
-		LIMIT $2
-	`
-
-	db, ok := ctx.Value("db").(*sqlx.DB)
-	if !ok {
-		return nil, errors.New("database connection not found in context")
-	}
+// OnTrackChange registers a hook to run on every track change. Hooks must
+// be registered before the service is used.
+func (s *SpotifyService) OnTrackChange(hook TrackChangeHook) {
+	s.onTrackChange = append(s.onTrackChange, hook)
+}
 
-	err := db.SelectContext(ctx, &tracks, query, userID, limit)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get track history: %w", err)
-	}
+// RealtimeAvailable reports whether realtime updates can be delivered, which
+// requires Redis pub/sub
+func (s *SpotifyService) RealtimeAvailable() bool {
+	return s.redis.Available()
+}
 
-	return tracks, nil
+// SubscribeToTrackUpdates subscribes to track updates for a user. Everyone
+// watching the same user on this instance shares one Redis subscription,
+// which survives reconnects. The client is detached when ctx is cancelled, or
+// earlier if it falls behind, and its events channel is then closed.
+func (s *SpotifyService) SubscribeToTrackUpdates(ctx context.Context, userID string) *realtime.Client {
+	channel := fmt.Sprintf("track:updates:%s", userID)
+	return s.trackUpdates.Register(ctx, channel)
 }
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/cache"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/database"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// tenantCacheTTL bounds how long a tenant change takes to reach every instance
+const tenantCacheTTL = time.Minute
+
+// TenantService resolves white-label tenants by domain and manages them
+type TenantService struct {
+	db     *database.DB
+	byHost *cache.Cache[*models.Tenant]
+	byID   *cache.Cache[*models.Tenant]
+	logger zerolog.Logger
+}
+
+// NewTenantService creates a new tenant service
+func NewTenantService(db *database.DB, logger zerolog.Logger) *TenantService {
+	return &TenantService{
+		db:     db,
+		byHost: cache.New[*models.Tenant](tenantCacheTTL, 1000),
+		byID:   cache.New[*models.Tenant](tenantCacheTTL, 1000),
+		logger: logger.With().Str("service", "tenant").Logger(),
+	}
+}
+
+type tenantKey struct{}
+
+// WithTenant returns a copy of ctx scoped to a tenant. A nil tenant is the
+// default Spotify app.
+func WithTenant(ctx context.Context, tenant *models.Tenant) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant ctx is scoped to, or nil for the
+// default Spotify app
+func TenantFromContext(ctx context.Context) *models.Tenant {
+	tenant, _ := ctx.Value(tenantKey{}).(*models.Tenant)
+	return tenant
+}
+
+// tenantIDFromContext returns the ID of ctx's tenant, or nil for the default
+func tenantIDFromContext(ctx context.Context) *string {
+	if tenant := TenantFromContext(ctx); tenant != nil {
+		return &tenant.ID
+	}
+	return nil
+}
+
+// InTenant reports whether a user belongs to the tenant ctx is scoped to
+func InTenant(ctx context.Context, user *models.User) bool {
+	tenantID := tenantIDFromContext(ctx)
+	if tenantID == nil || user.TenantID == nil {
+		return tenantID == nil && user.TenantID == nil
+	}
+	return *tenantID == *user.TenantID
+}
+
+// NormalizeDomain lowercases a host and strips any port
+func NormalizeDomain(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(host, ".")
+}
+
+// Resolve returns the tenant serving a request host, or nil when no tenant
+// claims the domain and the default Spotify app applies
+func (s *TenantService) Resolve(ctx context.Context, host string) (*models.Tenant, error) {
+	domain := NormalizeDomain(host)
+	if tenant, ok := s.byHost.Get(domain); ok {
+		return tenant, nil
+	}
+
+	var tenant models.Tenant
+	err := s.db.GetContext(ctx, &tenant, "SELECT * FROM tenants WHERE domain = $1", domain)
+	if errors.Is(err, sql.ErrNoRows) {
+		s.byHost.Set(domain, nil)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve tenant: %w", err)
+	}
+	s.byHost.Set(domain, &tenant)
+	return &tenant, nil
+}
+
+// GetByID gets a tenant by ID
+func (s *TenantService) GetByID(ctx context.Context, id string) (*models.Tenant, error) {
+	if tenant, ok := s.byID.Get(id); ok {
+		return tenant, nil
+	}
+
+	var tenant models.Tenant
+	err := s.db.GetContext(ctx, &tenant, "SELECT * FROM tenants WHERE id = $1", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperr.NotFound("tenant_not_found", "Tenant not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant: %w", err)
+	}
+	s.byID.Set(id, &tenant)
+	return &tenant, nil
+}
+
+// GetBySlug gets a tenant by slug
+func (s *TenantService) GetBySlug(ctx context.Context, slug string) (*models.Tenant, error) {
+	var tenant models.Tenant
+	err := s.db.GetContext(ctx, &tenant, "SELECT * FROM tenants WHERE slug = $1", strings.ToLower(slug))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperr.NotFound("tenant_not_found", "Tenant not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant: %w", err)
+	}
+	return &tenant, nil
+}
+
+// ListTenants returns every tenant ordered by slug
+func (s *TenantService) ListTenants(ctx context.Context) ([]models.Tenant, error) {
+	tenants := []models.Tenant{}
+	if err := s.db.SelectContext(ctx, &tenants, "SELECT * FROM tenants ORDER BY slug"); err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %w", err)
+	}
+	return tenants, nil
+}
+
+// CreateTenant stores a new tenant. Theme defaults left empty get the same
+// defaults as the default Spotify app.
+func (s *TenantService) CreateTenant(ctx context.Context, tenant *models.Tenant) error {
+	tenant.ID = uuid.New().String()
+	tenant.Slug = strings.ToLower(strings.TrimSpace(tenant.Slug))
+	tenant.Domain = NormalizeDomain(tenant.Domain)
+	if tenant.DefaultTheme == "" {
+		tenant.DefaultTheme = "default"
+	}
+	if tenant.DefaultBackgroundColor == "" {
+		tenant.DefaultBackgroundColor = "#121212"
+	}
+	if tenant.DefaultTextColor == "" {
+		tenant.DefaultTextColor = "#FFFFFF"
+	}
+	if tenant.DefaultAnimationStyle == "" {
+		tenant.DefaultAnimationStyle = "fade"
+	}
+	tenant.IsActive = true
+	tenant.CreatedAt = time.Now()
+	tenant.UpdatedAt = tenant.CreatedAt
+
+	_, err := s.db.NamedExecContext(ctx, `
+		INSERT INTO tenants (
+			id, slug, name, domain,
+			spotify_client_id, spotify_client_secret, spotify_redirect_uri,
+			default_theme, default_background_color, default_text_color, default_animation_style,
+			is_active, created_at, updated_at
+		) VALUES (
+			:id, :slug, :name, :domain,
+			:spotify_client_id, :spotify_client_secret, :spotify_redirect_uri,
+			:default_theme, :default_background_color, :default_text_color, :default_animation_style,
+			:is_active, :created_at, :updated_at
+		)
+	`, tenant)
+	if err != nil {
+		return fmt.Errorf("failed to create tenant: %w", err)
+	}
+
+	// Drop a cached "no tenant" result for the domain
+	s.byHost.Delete(tenant.Domain)
+	return nil
+}
+
+// SetTenantActive enables or disables a tenant. Disabled tenants' domains
+// answer 404 until the change reaches each instance's cache.
+func (s *TenantService) SetTenantActive(ctx context.Context, slug string, active bool) error {
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE tenants SET is_active = $1, updated_at = $2 WHERE slug = $3",
+		active, time.Now(), strings.ToLower(slug))
+	if err != nil {
+		return fmt.Errorf("failed to update tenant: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return apperr.NotFound("tenant_not_found", "Tenant not found")
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/brandonhuynh1/whatamilisteningto-api/pkg/tidal"
+	"github.com/rs/zerolog"
+)
+
+// TidalService signs users in with TIDAL. TIDAL does not publish a playback
+// state or listening history API, so TIDAL users have nothing playing unless
+// they report it another way.
+type TidalService struct {
+	client  *tidal.Client
+	scopes  []string
+	backoff *rateLimitBackoff
+	logger  zerolog.Logger
+}
+
+// NewTidalService creates the TIDAL provider, or returns nil when TIDAL is
+// not configured
+func NewTidalService(cfg config.TidalConfig, logger zerolog.Logger) *TidalService {
+	if cfg.ClientID == "" {
+		return nil
+	}
+	return &TidalService{
+		client:  tidal.NewClient(cfg.ClientID, cfg.ClientSecret, cfg.RedirectURI),
+		scopes:  cfg.Scopes,
+		backoff: &rateLimitBackoff{provider: ProviderTidal},
+		logger:  logger.With().Str("service", "tidal").Logger(),
+	}
+}
+
+// Name implements MusicProvider
+func (s *TidalService) Name() string {
+	return ProviderTidal
+}
+
+// GetAuthURL returns the TIDAL authorization URL
+func (s *TidalService) GetAuthURL(_ context.Context, state string) string {
+	return s.client.GetAuthURL(state, s.codeVerifier(state), s.scopes)
+}
+
+// codeVerifier derives the PKCE code verifier from the OAuth state, so it
+// does not need storing between the redirect and the callback. It is keyed
+// with the client secret so the state alone does not reveal it.
+func (s *TidalService) codeVerifier(state string) string {
+	mac := hmac.New(sha256.New, []byte(s.client.ClientSecret))
+	mac.Write([]byte(state))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ExchangeCodeForToken exchanges an authorization code for tokens
+func (s *TidalService) ExchangeCodeForToken(ctx context.Context, code, state string) (*ProviderToken, error) {
+	if err := s.backoff.check(); err != nil {
+		return nil, err
+	}
+	token, err := s.client.ExchangeCodeForToken(ctx, code, s.codeVerifier(state))
+	if err != nil {
+		return nil, s.observe(err)
+	}
+	return tidalToken(token), nil
+}
+
+// GetAccount gets the TIDAL user a token belongs to
+func (s *TidalService) GetAccount(ctx context.Context, token *ProviderToken) (*ProviderAccount, error) {
+	if err := s.backoff.check(); err != nil {
+		return nil, err
+	}
+	user, err := s.client.GetUser(ctx, token.AccessToken)
+	if err != nil {
+		return nil, s.observe(err)
+	}
+	return &ProviderAccount{ID: user.ID, Email: user.Email, DisplayName: user.Username}, nil
+}
+
+// RefreshUserToken refreshes a user's access token
+func (s *TidalService) RefreshUserToken(ctx context.Context, user *models.User) (*ProviderToken, error) {
+	if err := s.backoff.check(); err != nil {
+		return nil, err
+	}
+	token, err := s.client.RefreshAccessToken(ctx, user.SpotifyRefreshToken)
+	if err != nil {
+		return nil, s.observe(err)
+	}
+	// TIDAL only rotates the refresh token sometimes
+	if token.RefreshToken == "" {
+		token.RefreshToken = user.SpotifyRefreshToken
+	}
+	return tidalToken(token), nil
+}
+
+// GetCurrentlyPlayingTrack reports nothing playing, since TIDAL does not
+// expose playback state
+func (s *TidalService) GetCurrentlyPlayingTrack(_ context.Context, _ string) (*models.SpotifyCurrentlyPlaying, error) {
+	return &models.SpotifyCurrentlyPlaying{IsPlaying: false}, nil
+}
+
+// GetRecentlyPlayed returns no tracks, since TIDAL does not expose listening
+// history
+func (s *TidalService) GetRecentlyPlayed(_ context.Context, _ string, _ int) ([]PlayedTrack, error) {
+	return nil, nil
+}
+
+// observe starts a backoff when TIDAL reports a rate limit
+func (s *TidalService) observe(err error) error {
+	var rateLimited *tidal.RateLimitError
+	if errors.As(err, &rateLimited) {
+		s.logger.Warn().Dur("retry_after", rateLimited.RetryAfter).Msg("TIDAL rate limit reached")
+		return s.backoff.limit(rateLimited.RetryAfter)
+	}
+	return err
+}
+
+// tidalToken converts a TIDAL token response
+func tidalToken(token *tidal.TokenResponse) *ProviderToken {
+	return &ProviderToken{
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		ExpiresIn:    token.ExpiresIn,
+	}
+}
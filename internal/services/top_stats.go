@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+)
+
+// Time ranges top artists and tracks can be ranked over, each counting back
+// from now
+const (
+	StatsRangeWeek  = "week"
+	StatsRangeMonth = "month"
+	StatsRangeYear  = "year"
+	StatsRangeAll   = "all"
+)
+
+const (
+	// DefaultTopLimit is how many artists or tracks are ranked when no limit
+	// is requested
+	DefaultTopLimit = 10
+	// MaxTopLimit caps how many artists or tracks are ranked
+	MaxTopLimit = 50
+)
+
+// StatsRangeStart returns when statsRange starts counting, or the zero time
+// for StatsRangeAll
+func StatsRangeStart(statsRange string, now time.Time) time.Time {
+	switch statsRange {
+	case StatsRangeWeek:
+		return now.AddDate(0, 0, -7)
+	case StatsRangeMonth:
+		return now.AddDate(0, 0, -30)
+	case StatsRangeYear:
+		return now.AddDate(-1, 0, 0)
+	}
+	return time.Time{}
+}
+
+// rangeEnd converts the end of a time range to a query argument, NULL for a
+// zero end so the range is left open
+func rangeEnd(to time.Time) interface{} {
+	if to.IsZero() {
+		return nil
+	}
+	return to
+}
+
+// clampTopLimit applies the default and maximum to a requested limit
+func clampTopLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultTopLimit
+	}
+	return min(limit, MaxTopLimit)
+}
+
+// GetTopArtists ranks the artists a user played most from from until to,
+// breaking ties by the most recently played. A zero to leaves the range open.
+// Artists are matched case-insensitively.
+func (s *ProfileService) GetTopArtists(ctx context.Context, userID string, from, to time.Time, limit int) ([]models.TopArtist, error) {
+	artists := []models.TopArtist{}
+	err := s.db.SelectContext(ctx, &artists, `
+		SELECT (ARRAY_AGG(artist ORDER BY played_at DESC))[1] AS artist,
+			COUNT(*) AS play_count, MAX(played_at) AS last_played_at
+		FROM tracks
+		WHERE user_id = $1 AND is_currently_playing = false AND artist <> '' AND played_at >= $2
+			AND ($4::timestamptz IS NULL OR played_at < $4)
+		GROUP BY LOWER(artist)
+		ORDER BY play_count DESC, last_played_at DESC
+		LIMIT $3
+	`, userID, from, clampTopLimit(limit), rangeEnd(to))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top artists: %w", err)
+	}
+	return artists, nil
+}
+
+// GetTopTracks ranks the tracks a user played most from from until to,
+// breaking ties by the most recently played. A zero to leaves the range open.
+func (s *ProfileService) GetTopTracks(ctx context.Context, userID string, from, to time.Time, limit int) ([]models.TopTrack, error) {
+	tracks := []models.TopTrack{}
+	err := s.db.SelectContext(ctx, &tracks, `
+		SELECT spotify_track_id, name, artist, album, album_art_url, track_url,
+			play_count, last_played_at
+		FROM (
+			SELECT *,
+				COUNT(*) OVER plays AS play_count,
+				MAX(played_at) OVER plays AS last_played_at,
+				ROW_NUMBER() OVER (PARTITION BY `+trackKey+` ORDER BY played_at DESC) AS latest
+			FROM tracks
+			WHERE user_id = $1 AND is_currently_playing = false AND played_at >= $2
+				AND ($4::timestamptz IS NULL OR played_at < $4)
+			WINDOW plays AS (PARTITION BY `+trackKey+`)
+		) ranked
+		WHERE latest = 1
+		ORDER BY play_count DESC, last_played_at DESC
+		LIMIT $3
+	`, userID, from, clampTopLimit(limit), rangeEnd(to))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top tracks: %w", err)
+	}
+	return tracks, nil
+}
@@ -0,0 +1,355 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultHistoryLimit is the page size when none is requested
+	DefaultHistoryLimit = 20
+	// MaxHistoryLimit caps a single page of track history
+	MaxHistoryLimit = 100
+)
+
+// TrackHistoryQuery filters and pages a user's track history. From is
+// inclusive and To exclusive; zero values leave that bound open.
+type TrackHistoryQuery struct {
+	From         time.Time
+	To           time.Time
+	Artist       string
+	Album        string
+	Cursor       string
+	Limit        int
+	IncludeTotal bool
+}
+
+// TrackHistoryPage is one page of track history, newest first
+type TrackHistoryPage struct {
+	Tracks     []models.Track
+	NextCursor string
+	// Total counts every track matching the filters, ignoring the cursor; it
+	// is only computed when requested
+	Total *int
+}
+
+// GetTrackHistory returns a page of a user's track history. Pages are keyed
+// on (played_at, id) so inserts between requests don't shift results.
+func (s *ProfileService) GetTrackHistory(ctx context.Context, userID string, q TrackHistoryQuery) (*TrackHistoryPage, error) {
+	if q.Limit <= 0 {
+		q.Limit = DefaultHistoryLimit
+	}
+	if q.Limit > MaxHistoryLimit {
+		q.Limit = MaxHistoryLimit
+	}
+
+	query := newKeysetQuery("tracks", "played_at", userID)
+	if !q.From.IsZero() {
+		query.where("played_at >= %s", q.From)
+	}
+	if !q.To.IsZero() {
+		query.where("played_at < %s", q.To)
+	}
+	if q.Artist != "" {
+		query.where(`artist ILIKE %s ESCAPE '\'`, likePattern(q.Artist))
+	}
+	if q.Album != "" {
+		query.where(`album ILIKE %s ESCAPE '\'`, likePattern(q.Album))
+	}
+
+	page := &TrackHistoryPage{}
+
+	if q.IncludeTotal {
+		var total int
+		countQuery, args := query.count()
+		if err := s.db.GetContext(ctx, &total, countQuery, args...); err != nil {
+			return nil, fmt.Errorf("failed to count track history: %w", err)
+		}
+		page.Total = &total
+	}
+
+	if q.Cursor != "" {
+		if err := query.after(q.Cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	var tracks []models.Track
+	pageQuery, args := query.page(q.Limit)
+	if err := s.db.SelectContext(ctx, &tracks, pageQuery, args...); err != nil {
+		return nil, fmt.Errorf("failed to get track history: %w", err)
+	}
+	page.Tracks, page.NextCursor = nextPage(tracks, q.Limit, func(t models.Track) (time.Time, string) {
+		return t.PlayedAt, t.ID
+	})
+
+	return page, nil
+}
+
+// RandomTrack is a track picked from a user's history with how often and
+// when it was played
+type RandomTrack struct {
+	Track         models.Track
+	PlayCount     int
+	FirstPlayedAt time.Time
+	LastPlayedAt  time.Time
+}
+
+// trackKey identifies the same track across plays; manual entries have no
+// Spotify ID, so they are matched by artist and title
+const trackKey = `COALESCE(NULLIF(spotify_track_id, ''), LOWER(artist || E'\n' || name))`
+
+// GetRandomTrack picks a track from a user's history at random, weighted by
+// how long ago it was last played, so long-forgotten tracks come up far more
+// often than this week's favorites. It returns a not found error when the
+// history is empty.
+func (s *ProfileService) GetRandomTrack(ctx context.Context, userID string) (*RandomTrack, error) {
+	// -ln(u)/weight is an exponential draw; taking the smallest picks each
+	// track with probability proportional to its weight
+	var picked struct {
+		Key           string    `db:"track_key"`
+		PlayCount     int       `db:"play_count"`
+		FirstPlayedAt time.Time `db:"first_played_at"`
+		LastPlayedAt  time.Time `db:"last_played_at"`
+	}
+	err := s.db.GetContext(ctx, &picked, `
+		SELECT `+trackKey+` AS track_key, COUNT(*) AS play_count,
+			MIN(played_at) AS first_played_at, MAX(played_at) AS last_played_at
+		FROM tracks
+		WHERE user_id = $1 AND is_currently_playing = false
+		GROUP BY track_key
+		ORDER BY -LN(1 - RANDOM()) / (EXTRACT(EPOCH FROM NOW() - MAX(played_at)) / 86400 + 1)
+		LIMIT 1
+	`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperr.NotFound("no_history", "No listening history yet")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to pick random track: %w", err)
+	}
+
+	random := &RandomTrack{
+		PlayCount:     picked.PlayCount,
+		FirstPlayedAt: picked.FirstPlayedAt,
+		LastPlayedAt:  picked.LastPlayedAt,
+	}
+	err = s.db.GetContext(ctx, &random.Track, `
+		SELECT * FROM tracks
+		WHERE user_id = $1 AND is_currently_playing = false AND `+trackKey+` = $2
+		ORDER BY played_at DESC
+		LIMIT 1
+	`, userID, picked.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get random track: %w", err)
+	}
+	return random, nil
+}
+
+// exportBatchSize is how many tracks a history export reads per query
+const exportBatchSize = 1000
+
+// ExportHistory calls emit with every track in a user's history, oldest
+// first. Tracks are read in batches keyed on (played_at, id), so memory stays
+// flat and no single query runs long however big the history is. It stops
+// at the first error emit returns.
+func (s *ProfileService) ExportHistory(ctx context.Context, userID string, emit func(*models.Track) error) error {
+	var lastPlayedAt time.Time
+	lastID := uuid.Nil.String()
+	for {
+		var batch []models.Track
+		err := s.db.SelectContext(ctx, &batch, `
+			SELECT * FROM tracks
+			WHERE user_id = $1 AND (played_at, id) > ($2, $3)
+			ORDER BY played_at, id
+			LIMIT $4
+		`, userID, lastPlayedAt, lastID, exportBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to export track history: %w", err)
+		}
+
+		for i := range batch {
+			if err := emit(&batch[i]); err != nil {
+				return err
+			}
+		}
+		if len(batch) < exportBatchSize {
+			return nil
+		}
+		last := batch[len(batch)-1]
+		lastPlayedAt, lastID = last.PlayedAt, last.ID
+	}
+}
+
+// GetHistoryTrack returns the most recent play of a track in a user's
+// history by its track ID, or a not found error if they've never played it
+func (s *ProfileService) GetHistoryTrack(ctx context.Context, userID, trackID string) (*models.Track, error) {
+	var track models.Track
+	err := s.db.GetContext(ctx, &track, `
+		SELECT * FROM tracks
+		WHERE user_id = $1 AND spotify_track_id = $2
+		ORDER BY played_at DESC
+		LIMIT 1
+	`, userID, trackID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperr.NotFound("track_not_found", "Track not found in your history")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get track: %w", err)
+	}
+	return &track, nil
+}
+
+// likePattern builds a case-insensitive substring pattern, escaping LIKE
+// wildcards in the user's input
+func likePattern(value string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+	return "%" + escaped + "%"
+}
+
+// PurgeTrackHistory deletes history played before cutoff, keeping each
+// user's currently playing track. It returns the number of tracks deleted.
+func (s *ProfileService) PurgeTrackHistory(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx,
+		"DELETE FROM tracks WHERE played_at < $1 AND is_currently_playing = false",
+		cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge track history: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// finishPlays ends the user's currently playing tracks. Each play is recorded
+// as a play event; plays too short to count as a listen are then dropped
+// from history, so quick skips don't fill it, and the rest are grouped into
+// listening sessions with played_at set to when they ended.
+func (s *ProfileService) finishPlays(ctx context.Context, userID string) error {
+	var playing []models.Track
+	err := s.db.SelectContext(ctx, &playing,
+		"SELECT * FROM tracks WHERE user_id = $1 AND is_currently_playing = true", userID)
+	if err != nil {
+		return fmt.Errorf("failed to get currently playing tracks: %w", err)
+	}
+
+	now := time.Now()
+	for _, track := range playing {
+		// Rows are created when a play starts. A play can't outlast its
+		// track, which also caps plays whose end was never reported.
+		listened := now.Sub(track.CreatedAt)
+		if track.DurationMs > 0 {
+			listened = min(listened, time.Duration(track.DurationMs)*time.Millisecond)
+		}
+		event := models.PlayEvent{
+			ID:             uuid.New().String(),
+			UserID:         userID,
+			SpotifyTrackID: track.SpotifyTrackID,
+			Name:           track.Name,
+			Artist:         track.Artist,
+			DurationMs:     track.DurationMs,
+			ListenedMs:     int(listened.Milliseconds()),
+			Counted:        s.countsAsListen(listened, track.DurationMs),
+			StartedAt:      track.CreatedAt,
+			EndedAt:        now,
+		}
+		if err := s.recordPlayEvent(ctx, &event, track.Album); err != nil {
+			return err
+		}
+
+		if !event.Counted {
+			if _, err := s.db.ExecContext(ctx, "DELETE FROM tracks WHERE id = $1", track.ID); err != nil {
+				return fmt.Errorf("failed to drop skipped track: %w", err)
+			}
+			continue
+		}
+
+		// played_at marks when a play ended, as it does for backfilled plays
+		_, err := s.db.ExecContext(ctx, "UPDATE tracks SET played_at = $2 WHERE id = $1", track.ID, track.CreatedAt.Add(listened))
+		if err != nil {
+			return fmt.Errorf("failed to record when track ended: %w", err)
+		}
+	}
+
+	_, err = s.db.ExecContext(ctx,
+		"UPDATE tracks SET is_currently_playing = false WHERE user_id = $1 AND is_currently_playing = true",
+		userID)
+	if err != nil {
+		return fmt.Errorf("failed to update currently playing tracks: %w", err)
+	}
+	return nil
+}
+
+// ListenHook is called with every play that counts as a listen once it's
+// saved, along with the album it's from, if known
+type ListenHook func(ctx context.Context, listen *models.PlayEvent, album string)
+
+// OnListen registers a hook to run on every listen. Hooks must be registered
+// before the service is used.
+func (s *ProfileService) OnListen(hook ListenHook) {
+	s.onListen = append(s.onListen, hook)
+}
+
+// recordPlayEvent saves a finished play, adding it to a listening session
+// first when it counts as a listen
+func (s *ProfileService) recordPlayEvent(ctx context.Context, event *models.PlayEvent, album string) error {
+	if event.Counted {
+		sessionID, err := s.assignSession(ctx, *event)
+		if err != nil {
+			return err
+		}
+		event.SessionID = &sessionID
+	}
+
+	_, err := s.db.NamedExecContext(ctx, `
+		INSERT INTO play_events (
+			id, user_id, spotify_track_id, name, artist, duration_ms,
+			listened_ms, counted, started_at, ended_at, session_id
+		) VALUES (
+			:id, :user_id, :spotify_track_id, :name, :artist, :duration_ms,
+			:listened_ms, :counted, :started_at, :ended_at, :session_id
+		)
+	`, event)
+	if err != nil {
+		return fmt.Errorf("failed to record play event: %w", err)
+	}
+	if !event.Counted {
+		return nil
+	}
+	if err := s.updateDominantArtist(ctx, *event.SessionID); err != nil {
+		return err
+	}
+
+	for _, hook := range s.onListen {
+		hook(ctx, event, album)
+	}
+	return nil
+}
+
+// countsAsListen reports whether a play lasted long enough to keep in history
+func (s *ProfileService) countsAsListen(listened time.Duration, durationMs int) bool {
+	bySeconds, byPercent := s.history.MinListenSeconds, s.history.MinListenPercent
+	if bySeconds == 0 && byPercent == 0 {
+		return true
+	}
+	if bySeconds > 0 && listened >= time.Duration(bySeconds)*time.Second {
+		return true
+	}
+	return byPercent > 0 && durationMs > 0 &&
+		listened >= time.Duration(durationMs)*time.Millisecond*time.Duration(byPercent)/100
+}
+
+// PurgePlayEvents deletes play events that ended before cutoff. It returns
+// the number of events deleted.
+func (s *ProfileService) PurgePlayEvents(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM play_events WHERE ended_at < $1", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge play events: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/database"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/brandonhuynh1/whatamilisteningto-api/pkg/odesli"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
+)
+
+// TrackLinkService resolves tracks in listening history to the same track on
+// other platforms through Odesli, so profile visitors who don't use the
+// owner's service can still open what they're playing
+type TrackLinkService struct {
+	client    *odesli.Client
+	db        *database.DB
+	batchSize int
+	logger    zerolog.Logger
+}
+
+// NewTrackLinkService creates the track link service, or returns nil when
+// Odesli lookups are disabled
+func NewTrackLinkService(cfg config.OdesliConfig, db *database.DB, logger zerolog.Logger) *TrackLinkService {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &TrackLinkService{
+		client:    odesli.NewClient(cfg.APIURL, cfg.APIKey),
+		db:        db,
+		batchSize: cfg.BatchSize,
+		logger:    logger.With().Str("service", "track_links").Logger(),
+	}
+}
+
+// ResolveNew looks up links for up to a batch of tracks in anyone's history
+// that haven't been resolved yet, most recently played first. Tracks that
+// fail are retried on the next run; resolving stops early when Odesli rate
+// limits. It returns the number of tracks resolved.
+func (s *TrackLinkService) ResolveNew(ctx context.Context) (int, error) {
+	var pending []struct {
+		TrackID  string `db:"spotify_track_id"`
+		TrackURL string `db:"track_url"`
+	}
+	err := s.db.SelectContext(ctx, &pending, `
+		SELECT t.spotify_track_id, (ARRAY_AGG(t.track_url ORDER BY t.played_at DESC))[1] AS track_url
+		FROM tracks t
+		LEFT JOIN track_links l ON l.spotify_track_id = t.spotify_track_id
+		WHERE l.spotify_track_id IS NULL AND t.track_url LIKE 'https://%'
+		GROUP BY t.spotify_track_id
+		ORDER BY MAX(t.played_at) DESC
+		LIMIT $1
+	`, s.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list tracks without links: %w", err)
+	}
+
+	resolved := 0
+	for _, track := range pending {
+		links, err := s.client.Links(ctx, track.TrackURL)
+		if errors.Is(err, odesli.ErrRateLimited) {
+			s.logger.Debug().Msg("Odesli rate limited, pausing link resolution")
+			break
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return resolved, ctx.Err()
+			}
+			s.logger.Warn().Err(err).Str("track_id", track.TrackID).Msg("Failed to resolve track links")
+			continue
+		}
+		if links == nil {
+			links = &odesli.Links{}
+		}
+
+		_, err = s.db.ExecContext(ctx, `
+			INSERT INTO track_links (spotify_track_id, page_url, apple_music_url, youtube_url, deezer_url, fetched_at)
+			VALUES ($1, $2, $3, $4, $5, NOW())
+			ON CONFLICT (spotify_track_id) DO UPDATE SET
+				page_url = EXCLUDED.page_url,
+				apple_music_url = EXCLUDED.apple_music_url,
+				youtube_url = EXCLUDED.youtube_url,
+				deezer_url = EXCLUDED.deezer_url,
+				fetched_at = EXCLUDED.fetched_at
+		`, track.TrackID, links.PageURL, links.AppleMusic, links.YouTube, links.Deezer)
+		if err != nil {
+			return resolved, fmt.Errorf("failed to save track links: %w", err)
+		}
+		resolved++
+	}
+	return resolved, nil
+}
+
+// attachTrackLinks sets Links on each track that has resolved links on at
+// least one other platform
+func (s *ProfileService) attachTrackLinks(ctx context.Context, tracks ...*models.Track) error {
+	ids := make([]string, 0, len(tracks))
+	for _, track := range tracks {
+		ids = append(ids, track.SpotifyTrackID)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var rows []struct {
+		TrackID string `db:"spotify_track_id"`
+		models.TrackLinks
+	}
+	err := s.db.SelectContext(ctx, &rows, `
+		SELECT spotify_track_id, page_url, apple_music_url, youtube_url, deezer_url
+		FROM track_links
+		WHERE spotify_track_id = ANY($1) AND page_url <> ''
+	`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to get track links: %w", err)
+	}
+
+	byTrack := make(map[string]models.TrackLinks, len(rows))
+	for _, row := range rows {
+		byTrack[row.TrackID] = row.TrackLinks
+	}
+	for _, track := range tracks {
+		if links, ok := byTrack[track.SpotifyTrackID]; ok {
+			track.Links = &links
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+)
+
+const (
+	// DefaultSearchLimit is the number of search results when none is requested
+	DefaultSearchLimit = 20
+	// MaxSearchLimit caps the results of a single history search
+	MaxSearchLimit = 50
+
+	// trackSearchDocument and trackSearchText match the expressions
+	// tracks_search_idx and tracks_search_trgm_idx are built on
+	trackSearchDocument = `to_tsvector('simple', name || ' ' || artist || ' ' || album)`
+	trackSearchText     = `LOWER(name || ' ' || artist || ' ' || album)`
+)
+
+// TrackSearchResult is a track matching a history search: its most recent
+// play, with how often and when it was played
+type TrackSearchResult struct {
+	models.Track
+	PlayCount     int       `db:"play_count"`
+	FirstPlayedAt time.Time `db:"first_played_at"`
+	LastPlayedAt  time.Time `db:"last_played_at"`
+}
+
+// SearchHistory finds tracks in a user's history whose title, artist, or
+// album match query, best matches first and then most recently played. Each
+// track appears once however often it was played. Words are matched whole
+// with web search syntax ("quoted phrases", -excluded); when that finds
+// nothing, trigram similarity is tried so misspellings still match.
+func (s *ProfileService) SearchHistory(ctx context.Context, userID, query string, limit int) ([]TrackSearchResult, error) {
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	if limit > MaxSearchLimit {
+		limit = MaxSearchLimit
+	}
+
+	results, err := s.searchHistory(ctx, userID, query, limit,
+		trackSearchDocument+` @@ websearch_to_tsquery('simple', $2)`,
+		`ts_rank(`+trackSearchDocument+`, websearch_to_tsquery('simple', $2))`)
+	if err != nil || len(results) > 0 {
+		return results, err
+	}
+	return s.searchHistory(ctx, userID, query, limit,
+		`LOWER($2) <% `+trackSearchText,
+		`word_similarity(LOWER($2), `+trackSearchText+`)`)
+}
+
+// searchHistory runs a history search with the given match condition and
+// relevance expression, both of which may refer to the query as $2
+func (s *ProfileService) searchHistory(ctx context.Context, userID, query string, limit int, match, rank string) ([]TrackSearchResult, error) {
+	results := []TrackSearchResult{}
+	err := s.db.SelectContext(ctx, &results, `
+		WITH matches AS (
+			SELECT *, `+trackKey+` AS track_key, `+rank+` AS rank
+			FROM tracks
+			WHERE user_id = $1 AND `+match+`
+		),
+		grouped AS (
+			SELECT DISTINCT ON (track_key) *,
+				COUNT(*) OVER (PARTITION BY track_key) AS play_count,
+				MIN(played_at) OVER (PARTITION BY track_key) AS first_played_at
+			FROM matches
+			ORDER BY track_key, played_at DESC
+		)
+		SELECT id, user_id, spotify_track_id, name, artist, album, album_art_url,
+			track_url, duration_ms, is_currently_playing, played_at, created_at,
+			play_count, first_played_at, played_at AS last_played_at
+		FROM grouped
+		ORDER BY rank DESC, played_at DESC
+		LIMIT $3
+	`, userID, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search history: %w", err)
+	}
+	return results, nil
+}
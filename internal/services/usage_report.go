@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/database"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/rs/zerolog"
+)
+
+// UsageReportMonth is the layout of report months, such as "2024-05"
+const UsageReportMonth = "2006-01"
+
+// reportReferrerLimit is how many referring sites a report lists
+const reportReferrerLimit = 10
+
+// reportMinReferrerVisits leaves out referring sites with fewer visits, since
+// a site sending one or two visits can point at a single person
+const reportMinReferrerVisits = 5
+
+// referrerHostPattern extracts the host from a referrer URL
+const referrerHostPattern = `^[a-zA-Z][a-zA-Z0-9+.-]*://([^/:?#]+)`
+
+// referrerOriginPattern extracts the scheme, host, and port from a referrer
+// URL, dropping the path and query. Browsers already strip credentials.
+const referrerOriginPattern = `^[a-zA-Z][a-zA-Z0-9+.-]*://[^/?#]+`
+
+// UsageReportService compiles monthly anonymized platform metrics for
+// operators of public instances
+type UsageReportService struct {
+	db     *database.DB
+	logger zerolog.Logger
+}
+
+// NewUsageReportService creates a new usage report service
+func NewUsageReportService(db *database.DB, logger zerolog.Logger) *UsageReportService {
+	return &UsageReportService{
+		db:     db,
+		logger: logger.With().Str("service", "usage_report").Logger(),
+	}
+}
+
+// monthBounds returns the UTC start of month and of the month after
+func monthBounds(month string) (time.Time, time.Time, error) {
+	start, err := time.Parse(UsageReportMonth, month)
+	if err != nil {
+		return time.Time{}, time.Time{}, apperr.Invalid("invalid_month", "Month must look like 2024-05").Wrap(err)
+	}
+	return start, start.AddDate(0, 1, 0), nil
+}
+
+// GenerateReport compiles and stores the report for month, replacing any
+// earlier one. Visits are subject to retention, so reports should be
+// generated soon after the month ends.
+func (s *UsageReportService) GenerateReport(ctx context.Context, month string) (*models.UsageReport, error) {
+	start, end, err := monthBounds(month)
+	if err != nil {
+		return nil, err
+	}
+	if end.After(time.Now()) {
+		return nil, apperr.Invalid("month_not_over", "Reports can only be generated for months that have ended")
+	}
+
+	report := &models.UsageReport{Month: month, GeneratedAt: time.Now()}
+	err = s.db.GetContext(ctx, report, `
+		SELECT
+			$1::text AS month,
+			(SELECT COUNT(DISTINCT user_id) FROM tracks WHERE played_at >= $2 AND played_at < $3) AS active_users,
+			(SELECT COUNT(*) FROM users WHERE created_at >= $2 AND created_at < $3) AS new_users,
+			(SELECT COUNT(*) FROM users WHERE created_at < $3) AS total_users,
+			(SELECT COUNT(*) FROM tracks WHERE played_at >= $2 AND played_at < $3) AS tracks_logged,
+			(SELECT COUNT(*) FROM profile_visits WHERE started_at >= $2 AND started_at < $3 AND NOT is_bot) AS profile_visits,
+			(SELECT COUNT(DISTINCT `+visitorIdentity+`) FROM profile_visits WHERE started_at >= $2 AND started_at < $3 AND NOT is_bot) AS unique_visitors,
+			$4::timestamptz AS generated_at
+	`, month, start, end, report.GeneratedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile usage report: %w", err)
+	}
+
+	report.TopReferrers = []models.ReferrerVisits{}
+	err = s.db.SelectContext(ctx, &report.TopReferrers, `
+		SELECT COALESCE(LOWER(SUBSTRING(referrer_url FROM $3)), 'direct') AS referrer, COUNT(*) AS visits
+		FROM profile_visits
+		WHERE started_at >= $1 AND started_at < $2 AND NOT is_bot
+		GROUP BY 1
+		HAVING COUNT(*) >= $4
+		ORDER BY visits DESC, referrer
+		LIMIT $5
+	`, start, end, referrerHostPattern, reportMinReferrerVisits, reportReferrerLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile usage report referrers: %w", err)
+	}
+
+	_, err = s.db.NamedExecContext(ctx, `
+		INSERT INTO usage_reports (
+			month, active_users, new_users, total_users, tracks_logged,
+			profile_visits, unique_visitors, generated_at
+		) VALUES (
+			:month, :active_users, :new_users, :total_users, :tracks_logged,
+			:profile_visits, :unique_visitors, :generated_at
+		)
+		ON CONFLICT (month) DO UPDATE SET
+			active_users = EXCLUDED.active_users,
+			new_users = EXCLUDED.new_users,
+			total_users = EXCLUDED.total_users,
+			tracks_logged = EXCLUDED.tracks_logged,
+			profile_visits = EXCLUDED.profile_visits,
+			unique_visitors = EXCLUDED.unique_visitors,
+			generated_at = EXCLUDED.generated_at
+	`, report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save usage report: %w", err)
+	}
+
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM usage_report_referrers WHERE month = $1", month); err != nil {
+		return nil, fmt.Errorf("failed to replace usage report referrers: %w", err)
+	}
+	for _, referrer := range report.TopReferrers {
+		_, err := s.db.ExecContext(ctx,
+			"INSERT INTO usage_report_referrers (month, referrer, visits) VALUES ($1, $2, $3)",
+			month, referrer.Referrer, referrer.Visits)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save usage report referrers: %w", err)
+		}
+	}
+
+	return report, nil
+}
+
+// GetReport returns the stored report for month
+func (s *UsageReportService) GetReport(ctx context.Context, month string) (*models.UsageReport, error) {
+	if _, _, err := monthBounds(month); err != nil {
+		return nil, err
+	}
+
+	var report models.UsageReport
+	err := s.db.GetContext(ctx, &report, "SELECT * FROM usage_reports WHERE month = $1", month)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperr.NotFound("report_not_found", "No usage report for that month")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage report: %w", err)
+	}
+
+	report.TopReferrers = []models.ReferrerVisits{}
+	err = s.db.SelectContext(ctx, &report.TopReferrers,
+		"SELECT referrer, visits FROM usage_report_referrers WHERE month = $1 ORDER BY visits DESC, referrer", month)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage report referrers: %w", err)
+	}
+	return &report, nil
+}
+
+// ListReports returns the months that have a report, newest first
+func (s *UsageReportService) ListReports(ctx context.Context) ([]string, error) {
+	months := []string{}
+	if err := s.db.SelectContext(ctx, &months, "SELECT month FROM usage_reports ORDER BY month DESC"); err != nil {
+		return nil, fmt.Errorf("failed to list usage reports: %w", err)
+	}
+	return months, nil
+}
+
+// Run generates last month's report, if it is missing, now and then hourly
+// until ctx is cancelled
+func (s *UsageReportService) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		s.ensureLastMonth(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *UsageReportService) ensureLastMonth(ctx context.Context) {
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0).Format(UsageReportMonth)
+
+	var exists bool
+	if err := s.db.GetContext(ctx, &exists, "SELECT EXISTS (SELECT 1 FROM usage_reports WHERE month = $1)", month); err != nil {
+		if ctx.Err() == nil {
+			s.logger.Error().Err(err).Msg("Failed to check for usage report")
+		}
+		return
+	}
+	if exists {
+		return
+	}
+
+	if _, err := s.GenerateReport(ctx, month); err != nil {
+		if ctx.Err() == nil {
+			s.logger.Error().Err(err).Str("month", month).Msg("Failed to generate usage report")
+		}
+		return
+	}
+	s.logger.Info().Str("month", month).Msg("Generated usage report")
+}
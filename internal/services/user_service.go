@@ -2,50 +2,85 @@ package services
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+	_ "time/tzdata" // time zone names must resolve on hosts without zoneinfo
 
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
 	"github.com/brandonhuynh1/whatamilisteningto-api/internal/database"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/geoip"
 	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
 	"github.com/google/uuid"
-	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/rs/zerolog"
 )
 
+// presenceWindow is how long a visitor counts as active after their last heartbeat
+const presenceWindow = 5 * time.Minute
+
+// watchedProfilesKey is the sorted set of profiles scored by the last time
+// any visitor was seen on them
+const watchedProfilesKey = "visitors:watched"
+
 // UserService handles user-related operations
 type UserService struct {
-	db     *sqlx.DB
-	redis  *database.RedisClient
-	logger zerolog.Logger
+	db              *database.DB
+	redis           *database.RedisClient
+	sessionLifetime time.Duration
+	visitDedupe     time.Duration
+	geo             *geoip.Locator
+	logger          zerolog.Logger
+
+	// pendingUncaches holds hashes of sessions ended while their cache
+	// entries couldn't be deleted, until Redis is back
+	pendingMu       sync.Mutex
+	pendingUncaches map[string]struct{}
 }
 
 // NewUserService creates a new user service
-func NewUserService(db *sqlx.DB, redis *database.RedisClient, logger zerolog.Logger) *UserService {
+func NewUserService(db *database.DB, redis *database.RedisClient, sessionCfg config.SessionConfig, visitCfg config.VisitConfig, geo *geoip.Locator, logger zerolog.Logger) *UserService {
 	return &UserService{
-		db:     db,
-		redis:  redis,
-		logger: logger.With().Str("service", "user").Logger(),
+		db:              db,
+		redis:           redis,
+		sessionLifetime: time.Duration(sessionCfg.LifetimeDays) * 24 * time.Hour,
+		visitDedupe:     time.Duration(visitCfg.DedupeMinutes) * time.Minute,
+		geo:             geo,
+		logger:          logger.With().Str("service", "user").Logger(),
 	}
 }
 
-// CreateOrUpdateUser creates a new user or updates an existing one
-func (s *UserService) CreateOrUpdateUser(ctx context.Context, spotifyID, email, displayName string, accessToken, refreshToken string, expiresIn int) (*models.User, error) {
+// CreateOrUpdateUser creates a new user or updates an existing one in the
+// tenant ctx is scoped to. A token without an access token, as Apple Music
+// issues at sign-in, leaves an existing user's credentials unchanged.
+func (s *UserService) CreateOrUpdateUser(ctx context.Context, provider string, account *ProviderAccount, token *ProviderToken) (*models.User, error) {
+	tenant := TenantFromContext(ctx)
+	tenantID := tenantIDFromContext(ctx)
+
 	// Check if user exists
 	var user models.User
-	err := s.db.GetContext(ctx, &user, "SELECT * FROM users WHERE spotify_id = $1", spotifyID)
+	err := s.db.GetContext(ctx, &user,
+		"SELECT * FROM users WHERE provider = $1 AND spotify_id = $2 AND tenant_id IS NOT DISTINCT FROM $3",
+		provider, account.ID, tenantID)
 
 	if err != nil {
 		// User doesn't exist, create new user
 		newUser := models.User{
 			ID:                  uuid.New().String(),
-			SpotifyID:           spotifyID,
-			Email:               email,
-			DisplayName:         displayName,
-			ProfileURL:          s.generateProfileURL(displayName),
-			SpotifyAccessToken:  accessToken,
-			SpotifyRefreshToken: refreshToken,
-			TokenExpiresAt:      time.Now().Add(time.Duration(expiresIn) * time.Second),
+			TenantID:            tenantID,
+			Provider:            provider,
+			SpotifyID:           account.ID,
+			Email:               account.Email,
+			DisplayName:         account.DisplayName,
+			ProfileURL:          s.generateProfileURL(ctx, account.DisplayName),
+			SpotifyAccessToken:  token.AccessToken,
+			SpotifyRefreshToken: token.RefreshToken,
+			TokenExpiresAt:      time.Now().Add(time.Duration(token.ExpiresIn) * time.Second),
 			IsActive:            true,
 			IsSharingEnabled:    true,
 			CreatedAt:           time.Now(),
@@ -54,11 +89,11 @@ func (s *UserService) CreateOrUpdateUser(ctx context.Context, spotifyID, email,
 
 		_, err := s.db.NamedExecContext(ctx, `
 			INSERT INTO users (
-				id, spotify_id, email, display_name, profile_url, 
+				id, tenant_id, provider, spotify_id, email, display_name, profile_url, 
 				spotify_access_token, spotify_refresh_token, token_expires_at,
 				is_active, is_sharing_enabled, created_at, updated_at
 			) VALUES (
-				:id, :spotify_id, :email, :display_name, :profile_url,
+				:id, :tenant_id, :provider, :spotify_id, :email, :display_name, :profile_url,
 				:spotify_access_token, :spotify_refresh_token, :token_expires_at,
 				:is_active, :is_sharing_enabled, :created_at, :updated_at
 			)
@@ -81,6 +116,12 @@ func (s *UserService) CreateOrUpdateUser(ctx context.Context, spotifyID, email,
 			CreatedAt:       time.Now(),
 			UpdatedAt:       time.Now(),
 		}
+		if tenant != nil {
+			profile.Theme = tenant.DefaultTheme
+			profile.BackgroundColor = tenant.DefaultBackgroundColor
+			profile.TextColor = tenant.DefaultTextColor
+			profile.AnimationStyle = tenant.DefaultAnimationStyle
+		}
 
 		_, err = s.db.NamedExecContext(ctx, `
 			INSERT INTO profiles (
@@ -101,10 +142,25 @@ func (s *UserService) CreateOrUpdateUser(ctx context.Context, spotifyID, email,
 		return &newUser, nil
 	}
 
+	// Signing in during the grace period restores a deleted account
+	if user.DeletedAt != nil {
+		_, err = s.db.ExecContext(ctx,
+			"UPDATE users SET is_active = true, deleted_at = NULL, updated_at = NOW() WHERE id = $1", user.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore user: %w", err)
+		}
+		user.IsActive = true
+		user.DeletedAt = nil
+	}
+
+	if token.AccessToken == "" {
+		return &user, nil
+	}
+
 	// User exists, update tokens
-	user.SpotifyAccessToken = accessToken
-	user.SpotifyRefreshToken = refreshToken
-	user.TokenExpiresAt = time.Now().Add(time.Duration(expiresIn) * time.Second)
+	user.SpotifyAccessToken = token.AccessToken
+	user.SpotifyRefreshToken = token.RefreshToken
+	user.TokenExpiresAt = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
 	user.UpdatedAt = time.Now()
 
 	_, err = s.db.NamedExecContext(ctx, `
@@ -127,22 +183,56 @@ func (s *UserService) CreateOrUpdateUser(ctx context.Context, spotifyID, email,
 func (s *UserService) GetUserByID(ctx context.Context, id string) (*models.User, error) {
 	var user models.User
 	err := s.db.GetContext(ctx, &user, "SELECT * FROM users WHERE id = $1", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperr.NotFound("user_not_found", "User not found")
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 	return &user, nil
 }
 
-// GetUserByProfileURL gets a user by profile URL
+// GetUserByProfileURL gets a user by profile URL within the tenant ctx is
+// scoped to
 func (s *UserService) GetUserByProfileURL(ctx context.Context, profileURL string) (*models.User, error) {
 	var user models.User
-	err := s.db.GetContext(ctx, &user, "SELECT * FROM users WHERE profile_url = $1", profileURL)
+	// Slugs match case-insensitively; an exact match wins over legacy
+	// mixed-case slugs that differ only by case
+	err := s.db.GetContext(ctx, &user, `
+		SELECT * FROM users
+		WHERE LOWER(profile_url) = LOWER($1) AND tenant_id IS NOT DISTINCT FROM $2
+		ORDER BY profile_url = $1 DESC
+		LIMIT 1
+	`, profileURL, tenantIDFromContext(ctx))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperr.NotFound("profile_not_found", "Profile not found")
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user by profile URL: %w", err)
 	}
 	return &user, nil
 }
 
+// GetUsersByProfileURLs gets the users owning any of the given profile URLs
+// within the tenant ctx is scoped to, matched case-insensitively. Unknown URLs
+// are skipped.
+func (s *UserService) GetUsersByProfileURLs(ctx context.Context, profileURLs []string) ([]models.User, error) {
+	var users []models.User
+	normalized := make([]string, len(profileURLs))
+	for i, profileURL := range profileURLs {
+		normalized[i] = NormalizeProfileURL(profileURL)
+	}
+
+	err := s.db.SelectContext(ctx, &users, `
+		SELECT * FROM users
+		WHERE LOWER(profile_url) = ANY($1) AND tenant_id IS NOT DISTINCT FROM $2
+	`, pq.Array(normalized), tenantIDFromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users by profile URLs: %w", err)
+	}
+	return users, nil
+}
+
 // UpdateUserSettings updates a user's settings
 func (s *UserService) UpdateUserSettings(ctx context.Context, userID string, isSharingEnabled bool) error {
 	_, err := s.db.ExecContext(ctx,
@@ -156,6 +246,51 @@ func (s *UserService) UpdateUserSettings(ctx context.Context, userID string, isS
 	return nil
 }
 
+// UpdateUserTimezone sets a user's IANA time zone
+func (s *UserService) UpdateUserTimezone(ctx context.Context, userID, timezone string) error {
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE users SET timezone = $1, updated_at = $2 WHERE id = $3",
+		timezone, time.Now(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to update user timezone: %w", err)
+	}
+	return nil
+}
+
+// DetectUserTimezone sets a user's time zone from their browser unless they
+// already have one, so a zone chosen in settings isn't overwritten at login
+func (s *UserService) DetectUserTimezone(ctx context.Context, userID, timezone string) error {
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE users SET timezone = $1, updated_at = $2 WHERE id = $3 AND timezone = ''",
+		timezone, time.Now(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to detect user timezone: %w", err)
+	}
+	return nil
+}
+
+// ValidTimezone reports whether name is an IANA time zone such as
+// "Europe/Berlin"
+func ValidTimezone(name string) bool {
+	if name == "" || name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// UserLocation returns the user's time zone, or UTC when none is set
+func UserLocation(user *models.User) *time.Location {
+	if user.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(user.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
 // IsTokenExpired checks if a user's token is expired or about to expire
 func (s *UserService) IsTokenExpired(user *models.User) bool {
 	// Consider token expired if it expires in less than 5 minutes
@@ -176,65 +311,157 @@ func (s *UserService) UpdateUserToken(ctx context.Context, userID, accessToken s
 	return nil
 }
 
-// GetActiveUserCount gets the count of currently active viewers for a profile
+// PresenceAvailable reports whether active viewer counts can be served
+func (s *UserService) PresenceAvailable() bool {
+	return s.redis.Available()
+}
+
+// GetActiveUserCount gets the count of currently active viewers for a
+// profile: tracked visitors plus an estimate of anonymous ones
 func (s *UserService) GetActiveUserCount(ctx context.Context, userID string) (int, error) {
-	key := fmt.Sprintf("visitors:%s", userID)
-	count, err := s.redis.GetSetSize(ctx, key)
+	key := presenceKey(userID)
+	count, err := s.redis.CountSortedSetByScore(ctx, key, presenceCutoff(), "+inf")
 	if err != nil {
 		return 0, fmt.Errorf("failed to get active viewer count: %w", err)
 	}
 
-	return int(count), nil
+	anonymous, err := s.redis.CountHyperLogLog(ctx, anonymousPresenceKeys(userID, time.Now())...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get anonymous viewer count: %w", err)
+	}
+
+	return int(count + anonymous), nil
 }
 
-// RecordProfileVisit records a new profile visit
-func (s *UserService) RecordProfileVisit(ctx context.Context, userID string, visitorIP, userAgent, referrerURL string, visitorUserID *string) (string, error) {
-	// Create a new profile visit record
-	visitID := uuid.New().String()
-	visit := models.ProfileVisit{
-		ID:            visitID,
-		UserID:        userID,
-		VisitorIP:     visitorIP,
-		VisitorUserID: visitorUserID,
-		UserAgent:     userAgent,
-		ReferrerURL:   referrerURL,
-		StartedAt:     time.Now(),
-	}
-
-	_, err := s.db.NamedExecContext(ctx, `
-		INSERT INTO profile_visits (
-			id, user_id, visitor_ip, visitor_user_id, user_agent, referrer_url, started_at
-		) VALUES (
-			:id, :user_id, :visitor_ip, :visitor_user_id, :user_agent, :referrer_url, :started_at
-		)
-	`, visit)
+// RenewAnonymousActivity counts an anonymous viewer as active on a profile.
+// Viewers who asked not to be tracked are identified only by a random ID
+// that lives as long as their connection, and are counted in a HyperLogLog
+// per minute, so Redis never holds a list of who was watching.
+func (s *UserService) RenewAnonymousActivity(ctx context.Context, userID, ephemeralID string) error {
+	key := anonymousPresenceKey(userID, time.Now())
+	return s.redis.Pipelined(ctx, func(pipe *database.Pipeline) {
+		pipe.AddToHyperLogLog(ctx, key, ephemeralID)
+		pipe.SetExpiration(ctx, key, 2*presenceWindow)
+		pipe.AddToSortedSet(ctx, watchedProfilesKey, float64(time.Now().Unix()), userID)
+	})
+}
 
+// GetWatchedProfiles returns the IDs of users whose profiles had a visitor
+// within the presence window, pruning profiles nobody is watching anymore
+func (s *UserService) GetWatchedProfiles(ctx context.Context) ([]string, error) {
+	if err := s.redis.RemoveSortedSetByScore(ctx, watchedProfilesKey, "-inf", "("+presenceCutoff()); err != nil {
+		return nil, fmt.Errorf("failed to prune watched profiles: %w", err)
+	}
+	userIDs, err := s.redis.GetSortedSetByScore(ctx, watchedProfilesKey, presenceCutoff(), "+inf")
 	if err != nil {
-		return "", fmt.Errorf("failed to record profile visit: %w", err)
+		return nil, fmt.Errorf("failed to get watched profiles: %w", err)
 	}
+	return userIDs, nil
+}
+
+// RecordProfileVisit records a visit to a profile and returns its ID. A
+// visitor whose last visit, recognized by visitorToken, ended within the
+// dedupe window resumes that visit instead of starting another. Visits by
+// bots are recorded but flagged, and never count as viewers.
+func (s *UserService) RecordProfileVisit(ctx context.Context, userID string, visitorIP, userAgent, referrerURL, visitorToken string, visitorUserID *string) (string, error) {
+	visitorHash := hashVisitorToken(visitorToken)
 
-	// Add to active visitors set with 5-minute expiration
-	visitorKey := fmt.Sprintf("visitor:%s", visitID)
-	err = s.redis.Set(ctx, visitorKey, "1", 5*time.Minute)
+	visitID, err := s.resumeProfileVisit(ctx, userID, visitorHash)
 	if err != nil {
-		s.logger.Warn().Err(err).Msg("Failed to set visitor key in Redis")
+		return "", err
+	}
+
+	bot := IsBot(userAgent)
+	if visitID == "" {
+		// Create a new profile visit record
+		location := s.geo.Lookup(visitorIP)
+		visitID = uuid.New().String()
+		visit := models.ProfileVisit{
+			ID:            visitID,
+			UserID:        userID,
+			VisitorIP:     visitorIP,
+			VisitorUserID: visitorUserID,
+			VisitorHash:   visitorHash,
+			Country:       location.Country,
+			Region:        location.Region,
+			IsBot:         bot,
+			UserAgent:     userAgent,
+			ReferrerURL:   referrerURL,
+			StartedAt:     time.Now(),
+		}
+
+		_, err = s.db.NamedExecContext(ctx, `
+			INSERT INTO profile_visits (
+				id, user_id, visitor_ip, visitor_user_id, visitor_hash, country, region,
+				is_bot, user_agent, referrer_url, started_at
+			) VALUES (
+				:id, :user_id, :visitor_ip, :visitor_user_id, :visitor_hash, :country, :region,
+				:is_bot, :user_agent, :referrer_url, :started_at
+			)
+		`, visit)
+
+		if err != nil {
+			return "", fmt.Errorf("failed to record profile visit: %w", err)
+		}
+	}
+
+	// Presence is best effort and skipped entirely in degraded mode. Bots
+	// aren't viewers, and counting them would also have the poller refresh
+	// profiles nobody is watching.
+	if bot || !s.redis.Available() {
+		return visitID, nil
 	}
 
-	// Add to active visitors set for this profile
-	activeVisitorsKey := fmt.Sprintf("visitors:%s", userID)
-	err = s.redis.AddToSet(ctx, activeVisitorsKey, visitID)
+	// Mark the visitor as seen now and prune visitors whose last heartbeat
+	// fell outside the presence window, in a single round trip
+	key := presenceKey(userID)
+	err = s.redis.TxPipelined(ctx, func(pipe *database.Pipeline) {
+		pipe.AddToSortedSet(ctx, key, float64(time.Now().Unix()), visitID)
+		pipe.RemoveSortedSetByScore(ctx, key, "-inf", "("+presenceCutoff())
+		pipe.SetExpiration(ctx, key, 2*presenceWindow)
+		pipe.AddToSortedSet(ctx, watchedProfilesKey, float64(time.Now().Unix()), userID)
+	})
 	if err != nil {
-		s.logger.Warn().Err(err).Msg("Failed to add to active visitors set")
+		s.logger.Warn().Ctx(ctx).Err(err).Msg("Failed to register active visitor in Redis")
 	}
 
 	return visitID, nil
 }
 
+// resumeProfileVisit reopens the visitor's latest visit to the profile when
+// it ended, or started if it's still open, within the dedupe window. It
+// returns "" when there's none to resume.
+func (s *UserService) resumeProfileVisit(ctx context.Context, userID, visitorHash string) (string, error) {
+	if s.visitDedupe <= 0 || visitorHash == "" {
+		return "", nil
+	}
+
+	var visitID string
+	err := s.db.GetContext(ctx, &visitID, `
+		UPDATE profile_visits SET ended_at = NULL
+		WHERE id = (
+			SELECT id FROM profile_visits
+			WHERE user_id = $1 AND visitor_hash = $2 AND COALESCE(ended_at, started_at) >= $3
+			ORDER BY started_at DESC
+			LIMIT 1
+		)
+		RETURNING id
+	`, userID, visitorHash, time.Now().Add(-s.visitDedupe))
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resume profile visit: %w", err)
+	}
+	return visitID, nil
+}
+
 // EndProfileVisit marks a profile visit as ended
 func (s *UserService) EndProfileVisit(ctx context.Context, visitID string) error {
-	// Get the visit to find the user ID
-	var visit models.ProfileVisit
-	err := s.db.GetContext(ctx, &visit, "SELECT * FROM profile_visits WHERE id = $1", visitID)
+	// Get the visit's user ID. Anonymized visits have NULL columns that
+	// models.ProfileVisit can't hold, so only the ID is read.
+	var userID string
+	err := s.db.GetContext(ctx, &userID, "SELECT user_id FROM profile_visits WHERE id = $1", visitID)
 	if err != nil {
 		return fmt.Errorf("failed to get profile visit: %w", err)
 	}
@@ -249,34 +476,93 @@ func (s *UserService) EndProfileVisit(ctx context.Context, visitID string) error
 		return fmt.Errorf("failed to update profile visit: %w", err)
 	}
 
-	// Remove from active visitors set
-	activeVisitorsKey := fmt.Sprintf("visitors:%s", visit.UserID)
-	err = s.redis.RemoveFromSet(ctx, activeVisitorsKey, visitID)
+	// Remove from the profile's presence set
+	if !s.redis.Available() {
+		return nil
+	}
+	err = s.redis.RemoveFromSortedSet(ctx, presenceKey(userID), visitID)
 	if err != nil {
-		s.logger.Warn().Err(err).Msg("Failed to remove from active visitors set")
+		s.logger.Warn().Ctx(ctx).Err(err).Msg("Failed to remove active visitor from Redis")
 	}
 
-	// Delete visitor key
-	visitorKey := fmt.Sprintf("visitor:%s", visitID)
-	err = s.redis.Delete(ctx, visitorKey)
+	return nil
+}
+
+// AnonymizeProfileVisits scrubs the IP address and user agent from visits
+// that started before cutoff and cuts their referrer down to its origin,
+// keeping what analytics need. It returns the number of visits scrubbed.
+func (s *UserService) AnonymizeProfileVisits(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE profile_visits SET
+			visitor_ip = NULL,
+			user_agent = NULL,
+			referrer_url = SUBSTRING(referrer_url FROM $2)
+		WHERE started_at < $1 AND (visitor_ip IS NOT NULL OR user_agent IS NOT NULL)
+	`, cutoff, referrerOriginPattern)
 	if err != nil {
-		s.logger.Warn().Err(err).Msg("Failed to delete visitor key")
+		return 0, fmt.Errorf("failed to anonymize profile visits: %w", err)
 	}
+	return result.RowsAffected()
+}
 
-	return nil
+// PurgeProfileVisits deletes visits that started before cutoff. It returns the
+// number of visits deleted.
+func (s *UserService) PurgeProfileVisits(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM profile_visits WHERE started_at < $1", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge profile visits: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// RenewVisitorActivity bumps a visitor's last-seen score on a profile
+func (s *UserService) RenewVisitorActivity(ctx context.Context, userID, visitID string) error {
+	key := presenceKey(userID)
+	return s.redis.TxPipelined(ctx, func(pipe *database.Pipeline) {
+		pipe.AddToSortedSet(ctx, key, float64(time.Now().Unix()), visitID)
+		pipe.SetExpiration(ctx, key, 2*presenceWindow)
+		pipe.AddToSortedSet(ctx, watchedProfilesKey, float64(time.Now().Unix()), userID)
+	})
+}
+
+// presenceKey returns the sorted set holding a profile's visitors scored by
+// last-seen time. It isn't visitors:<id>, which older releases used for a
+// plain set, so mixed-version deploys don't hit WRONGTYPE errors.
+func presenceKey(userID string) string {
+	return fmt.Sprintf("presence:%s", userID)
 }
 
-// RenewVisitorActivity renews a visitor's activity timeout
-func (s *UserService) RenewVisitorActivity(ctx context.Context, visitID string) error {
-	// Set visitor key with new 5-minute expiration
-	visitorKey := fmt.Sprintf("visitor:%s", visitID)
-	return s.redis.Set(ctx, visitorKey, "1", 5*time.Minute)
+// anonymousPresenceKey returns the HyperLogLog of anonymous viewers seen on a
+// profile in the minute containing t
+func anonymousPresenceKey(userID string, t time.Time) string {
+	return fmt.Sprintf("visitors:anon:%s:%d", userID, t.Unix()/60)
+}
+
+// anonymousPresenceKeys returns the HyperLogLogs of every minute in the
+// presence window up to now
+func anonymousPresenceKeys(userID string, now time.Time) []string {
+	minutes := int(presenceWindow / time.Minute)
+	keys := make([]string, 0, minutes+1)
+	for i := 0; i <= minutes; i++ {
+		keys = append(keys, anonymousPresenceKey(userID, now.Add(-time.Duration(i)*time.Minute)))
+	}
+	return keys
+}
+
+// presenceCutoff returns the oldest last-seen score that still counts as active
+func presenceCutoff() string {
+	return strconv.FormatInt(time.Now().Add(-presenceWindow).Unix(), 10)
+}
+
+// NormalizeProfileURL returns the canonical lowercase form of a profile slug
+func NormalizeProfileURL(profileURL string) string {
+	return strings.ToLower(strings.TrimSpace(profileURL))
 }
 
 // generateProfileURL creates a unique profile URL from a display name
-func (s *UserService) generateProfileURL(displayName string) string {
+func (s *UserService) generateProfileURL(ctx context.Context, displayName string) string {
 	// Convert to lowercase
-	urlBase := strings.ToLower(displayName)
+	urlBase := NormalizeProfileURL(displayName)
 
 	// Replace spaces with hyphens and remove special characters
 	urlBase = strings.ReplaceAll(urlBase, " ", "-")
@@ -286,10 +572,13 @@ func (s *UserService) generateProfileURL(displayName string) string {
 		}
 		return -1
 	}, urlBase)
+	if urlBase == "" {
+		urlBase = "listener"
+	}
 
-	// Check if URL already exists, if so, add a random suffix
+	// Check if URL already exists in any casing, if so, add a random suffix
 	var count int
-	err := s.db.Get(&count, "SELECT COUNT(*) FROM users WHERE profile_url = $1", urlBase)
+	err := s.db.GetContext(ctx, &count, "SELECT COUNT(*) FROM users WHERE LOWER(profile_url) = $1", urlBase)
 	if err != nil || count > 0 {
 		// Add a random suffix (last 6 chars of a UUID)
 		suffix := uuid.New().String()
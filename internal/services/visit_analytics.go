@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+)
+
+// visitReferrerLimit is how many referring sites visit analytics lists
+const visitReferrerLimit = 10
+
+// visitorIdentity tells profile visitors apart: by account when signed in,
+// by visitor cookie otherwise, and by IP address for visits recorded before
+// the cookie
+const visitorIdentity = `COALESCE(visitor_user_id::text, NULLIF(visitor_hash, ''), visitor_ip)`
+
+// visitsSince picks the visits to profile $1 since $2, skipping bots unless
+// $3 is true
+const visitsSince = `user_id = $1 AND started_at >= $2 AND (NOT is_bot OR $3)`
+
+// GetVisitAnalytics summarizes the visits to a user's profile since from,
+// broken down by day, week, or month in their time zone, oldest first.
+// Periods without visits are left out, and the first one may start before
+// from. Visits by bots are left out unless includeBots is set.
+func (s *UserService) GetVisitAnalytics(ctx context.Context, user *models.User, from time.Time, period string, includeBots bool) (*models.VisitAnalytics, error) {
+	analytics := &models.VisitAnalytics{}
+	err := s.db.GetContext(ctx, analytics, `
+		SELECT COUNT(*) AS visits,
+			COUNT(DISTINCT `+visitorIdentity+`) AS unique_visitors,
+			COALESCE(ROUND(AVG(EXTRACT(EPOCH FROM ended_at - started_at))), 0)::bigint AS average_duration_seconds
+		FROM profile_visits
+		WHERE `+visitsSince+`
+	`, user.ID, from, includeBots)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get visit totals: %w", err)
+	}
+
+	analytics.Periods = []models.VisitPeriod{}
+	err = s.db.SelectContext(ctx, &analytics.Periods, `
+		SELECT TO_CHAR(DATE_TRUNC($4, started_at AT TIME ZONE $5), 'YYYY-MM-DD') AS start,
+			COUNT(*) AS visits,
+			COUNT(DISTINCT `+visitorIdentity+`) AS unique_visitors
+		FROM profile_visits
+		WHERE `+visitsSince+`
+		GROUP BY start
+		ORDER BY start
+	`, user.ID, from, includeBots, period, UserLocation(user).String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get visits by period: %w", err)
+	}
+
+	analytics.TopReferrers = []models.ReferrerVisits{}
+	err = s.db.SelectContext(ctx, &analytics.TopReferrers, `
+		SELECT COALESCE(LOWER(SUBSTRING(referrer_url FROM $4)), 'direct') AS referrer, COUNT(*) AS visits
+		FROM profile_visits
+		WHERE `+visitsSince+`
+		GROUP BY 1
+		ORDER BY visits DESC, referrer
+		LIMIT $5
+	`, user.ID, from, includeBots, referrerHostPattern, visitReferrerLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get visit referrers: %w", err)
+	}
+
+	analytics.Countries = []models.CountryVisits{}
+	err = s.db.SelectContext(ctx, &analytics.Countries, `
+		SELECT COALESCE(NULLIF(country, ''), 'unknown') AS country,
+			COUNT(*) AS visits,
+			COUNT(DISTINCT `+visitorIdentity+`) AS unique_visitors
+		FROM profile_visits
+		WHERE `+visitsSince+`
+		GROUP BY 1
+		ORDER BY unique_visitors DESC, country
+	`, user.ID, from, includeBots)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get visits by country: %w", err)
+	}
+
+	return analytics, nil
+}
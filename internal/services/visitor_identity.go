@@ -0,0 +1,38 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// visitorTokenBytes is the size of a visitor cookie's random token
+const visitorTokenBytes = 32
+
+// NewVisitorToken generates the random token for a browser's visitor cookie,
+// which recognizes repeat visits without an account
+func NewVisitorToken() (string, error) {
+	raw := make([]byte, visitorTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate visitor token: %w", err)
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// ValidVisitorToken reports whether token looks like one NewVisitorToken
+// generated, so a tampered cookie is replaced rather than stored
+func ValidVisitorToken(token string) bool {
+	raw, err := hex.DecodeString(token)
+	return err == nil && len(raw) == visitorTokenBytes
+}
+
+// hashVisitorToken hashes a visitor token for storage, so the cookie can't be
+// read back out of the visits table. An empty token hashes to "".
+func hashVisitorToken(token string) string {
+	if token == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,439 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/database"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/version"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// WebhookEventTrackChanged is sent when a user starts, stops, or changes
+// what they're playing
+const WebhookEventTrackChanged = "track.changed"
+
+// Webhook delivery states
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+const (
+	// maxWebhooksPerUser keeps one account from fanning out without bound
+	maxWebhooksPerUser = 10
+
+	// webhookDeliveryBatch is how many due deliveries each run sends at once
+	webhookDeliveryBatch = 20
+
+	// webhookRetryBase is the wait before the first retry, doubling after
+	// each failed attempt
+	webhookRetryBase = 30 * time.Second
+
+	// maxWebhookRetryDoublings caps the retry wait at about eight and a half
+	// hours
+	maxWebhookRetryDoublings = 10
+
+	// webhookDeliveryListLimit is how many recent deliveries are listed
+	webhookDeliveryListLimit = 50
+
+	// maxWebhookErrorBytes caps how much of a failed response is logged
+	maxWebhookErrorBytes = 512
+
+	// webhookSecretPrefix marks webhook signing secrets, so they're easy to
+	// spot if leaked
+	webhookSecretPrefix = "whsec_"
+)
+
+// errPrivateAddress is returned when a webhook resolves to an address it may
+// not call
+var errPrivateAddress = errors.New("webhook resolves to a private address")
+
+// WebhookService manages the URLs users are sent track changes at, queues
+// an event for each when playback changes, and delivers them with retries
+type WebhookService struct {
+	db          *database.DB
+	httpClient  *http.Client
+	maxAttempts int
+	userAgent   string
+	logger      zerolog.Logger
+}
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService(cfg config.WebhookConfig, db *database.DB, logger zerolog.Logger) *WebhookService {
+	dialer := &net.Dialer{Timeout: time.Duration(cfg.TimeoutSeconds) * time.Second}
+	if !cfg.AllowPrivateNetworks {
+		dialer.Control = publicAddressesOnly
+	}
+
+	return &WebhookService{
+		db: db,
+		httpClient: &http.Client{
+			Timeout: time.Duration(cfg.TimeoutSeconds) * time.Second,
+			// No proxy, so the address check sees where requests really go
+			Transport: &http.Transport{DialContext: dialer.DialContext},
+			// Redirects could lead anywhere, so they count as failures
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		maxAttempts: cfg.MaxAttempts,
+		userAgent:   "whatamilisteningto-api/" + version.Get().Version,
+		logger:      logger.With().Str("service", "webhook").Logger(),
+	}
+}
+
+// publicAddressesOnly refuses connections to loopback, private, link-local,
+// and other non-public addresses. It runs after DNS resolution, so hostnames
+// pointing inside the network are caught too.
+func publicAddressesOnly(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() ||
+		sharedAddressSpace.Contains(ip) {
+		return errPrivateAddress
+	}
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, which net.IP doesn't
+// count as private
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// validateWebhookURL checks that a webhook URL is an absolute HTTP(S) URL
+func validateWebhookURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return apperr.Invalid("invalid_webhook_url", "Webhook URL must be an absolute http or https URL")
+	}
+	if parsed.User != nil {
+		return apperr.Invalid("invalid_webhook_url", "Webhook URL can't contain credentials")
+	}
+	return nil
+}
+
+// newWebhookSecret generates a secret to sign a webhook's deliveries with
+func newWebhookSecret() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return webhookSecretPrefix + hex.EncodeToString(raw), nil
+}
+
+// signWebhookPayload returns the X-Signature header for a payload: the
+// hex-encoded HMAC-SHA256 of the body, keyed with the webhook's secret
+func signWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// CreateWebhook registers a URL to be sent the user's track changes. The
+// returned webhook's Secret is what its deliveries are signed with.
+func (s *WebhookService) CreateWebhook(ctx context.Context, userID, webhookURL, description string) (*models.Webhook, error) {
+	if err := validateWebhookURL(webhookURL); err != nil {
+		return nil, err
+	}
+
+	var count int
+	if err := s.db.GetContext(ctx, &count, "SELECT COUNT(*) FROM webhooks WHERE user_id = $1", userID); err != nil {
+		return nil, fmt.Errorf("failed to count webhooks: %w", err)
+	}
+	if count >= maxWebhooksPerUser {
+		return nil, apperr.Conflict("webhook_limit", fmt.Sprintf("You can have up to %d webhooks; delete one first", maxWebhooksPerUser))
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	webhook := &models.Webhook{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		URL:         webhookURL,
+		Description: description,
+		Secret:      secret,
+		IsActive:    true,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	_, err = s.db.NamedExecContext(ctx, `
+		INSERT INTO webhooks (id, user_id, url, description, secret, is_active, created_at, updated_at)
+		VALUES (:id, :user_id, :url, :description, :secret, :is_active, :created_at, :updated_at)
+	`, webhook)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// ListWebhooks returns a user's webhooks, newest first
+func (s *WebhookService) ListWebhooks(ctx context.Context, userID string) ([]models.Webhook, error) {
+	webhooks := []models.Webhook{}
+	err := s.db.SelectContext(ctx, &webhooks,
+		"SELECT * FROM webhooks WHERE user_id = $1 ORDER BY created_at DESC", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+// RotateSecret replaces one of a user's webhooks' signing secret. Pending
+// deliveries, including retries, are signed with the new secret.
+func (s *WebhookService) RotateSecret(ctx context.Context, userID, webhookID string) (*models.Webhook, error) {
+	if _, err := uuid.Parse(webhookID); err != nil {
+		return nil, apperr.NotFound("webhook_not_found", "Webhook not found")
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	var webhook models.Webhook
+	err = s.db.GetContext(ctx, &webhook, `
+		UPDATE webhooks SET secret = $3, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		RETURNING *
+	`, webhookID, userID, secret)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperr.NotFound("webhook_not_found", "Webhook not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate webhook secret: %w", err)
+	}
+	return &webhook, nil
+}
+
+// DeleteWebhook removes one of a user's webhooks and its delivery log
+func (s *WebhookService) DeleteWebhook(ctx context.Context, userID, webhookID string) error {
+	if _, err := uuid.Parse(webhookID); err != nil {
+		return apperr.NotFound("webhook_not_found", "Webhook not found")
+	}
+
+	result, err := s.db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = $1 AND user_id = $2", webhookID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return apperr.NotFound("webhook_not_found", "Webhook not found")
+	}
+	return nil
+}
+
+// ListDeliveries returns the most recent deliveries to one of a user's
+// webhooks, newest first
+func (s *WebhookService) ListDeliveries(ctx context.Context, userID, webhookID string) ([]models.WebhookDelivery, error) {
+	if _, err := uuid.Parse(webhookID); err != nil {
+		return nil, apperr.NotFound("webhook_not_found", "Webhook not found")
+	}
+
+	var exists bool
+	err := s.db.GetContext(ctx, &exists,
+		"SELECT EXISTS (SELECT 1 FROM webhooks WHERE id = $1 AND user_id = $2)", webhookID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up webhook: %w", err)
+	}
+	if !exists {
+		return nil, apperr.NotFound("webhook_not_found", "Webhook not found")
+	}
+
+	deliveries := []models.WebhookDelivery{}
+	err = s.db.SelectContext(ctx, &deliveries,
+		"SELECT * FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY id DESC LIMIT $2",
+		webhookID, webhookDeliveryListLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// EnqueueTrackChange queues a track.changed event for each of the user's
+// active webhooks. Track changes are announced more than once, so a webhook
+// whose last delivery already reported this playback state is skipped.
+func (s *WebhookService) EnqueueTrackChange(ctx context.Context, userID string, nowPlaying *models.SpotifyCurrentlyPlaying) error {
+	payload, err := json.Marshal(models.WebhookEvent{
+		Event:      WebhookEventTrackChanged,
+		NowPlaying: nowPlaying,
+		OccurredAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook event: %w", err)
+	}
+	playbackKey := nowPlaying.TrackID + ":" + strconv.FormatBool(nowPlaying.IsPlaying)
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event, playback_key, payload)
+		SELECT w.id, $2, $3, jsonb_set($4::jsonb, '{profile_url}', to_jsonb(u.profile_url))
+		FROM webhooks w
+		JOIN users u ON u.id = w.user_id
+		WHERE w.user_id = $1 AND w.is_active
+			AND $3 IS DISTINCT FROM (
+				SELECT d.playback_key FROM webhook_deliveries d
+				WHERE d.webhook_id = w.id
+				ORDER BY d.id DESC
+				LIMIT 1
+			)
+	`, userID, WebhookEventTrackChanged, playbackKey, string(payload))
+	if err != nil {
+		return fmt.Errorf("failed to queue webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// dueDelivery is a claimed delivery with the URL it goes to
+type dueDelivery struct {
+	ID       int64           `db:"id"`
+	Event    string          `db:"event"`
+	Payload  json.RawMessage `db:"payload"`
+	Attempts int             `db:"attempts"`
+	URL      string          `db:"url"`
+	Secret   string          `db:"secret"`
+}
+
+// DeliverDue sends deliveries whose next attempt is due, returning how many
+// succeeded. Claimed deliveries are pushed back while they're sent, so
+// workers running at the same time don't send them twice.
+func (s *WebhookService) DeliverDue(ctx context.Context) (int, error) {
+	lease := s.httpClient.Timeout + time.Minute
+
+	var due []dueDelivery
+	err := s.db.SelectContext(ctx, &due, `
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = NOW() + $1 * INTERVAL '1 second'
+		FROM webhooks w
+		WHERE w.id = d.webhook_id AND d.id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = $2 AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING d.id, d.event, d.payload, d.attempts, w.url, w.secret
+	`, int(lease.Seconds()), WebhookDeliveryPending, webhookDeliveryBatch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		delivered int
+	)
+	for i := range due {
+		wg.Add(1)
+		go func(delivery *dueDelivery) {
+			defer wg.Done()
+			statusCode, sendErr := s.send(ctx, delivery)
+			if err := s.recordAttempt(ctx, delivery, statusCode, sendErr); err != nil {
+				s.logger.Error().Err(err).Int64("delivery_id", delivery.ID).Msg("Failed to record webhook delivery")
+			}
+			if sendErr == nil {
+				mu.Lock()
+				delivered++
+				mu.Unlock()
+			}
+		}(&due[i])
+	}
+	wg.Wait()
+	return delivered, nil
+}
+
+// send POSTs a delivery's payload, returning the response status, if there
+// was one, and an error unless it was a 2xx
+func (s *WebhookService) send(ctx context.Context, delivery *dueDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", s.userAgent)
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(delivery.ID, 10))
+	if delivery.Secret != "" {
+		req.Header.Set("X-Signature", signWebhookPayload(delivery.Secret, delivery.Payload))
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookErrorBytes))
+		return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return resp.StatusCode, nil
+}
+
+// recordAttempt logs the outcome of sending a delivery, scheduling a retry
+// after a failure until the delivery runs out of attempts
+func (s *WebhookService) recordAttempt(ctx context.Context, delivery *dueDelivery, statusCode int, sendErr error) error {
+	var responseStatus *int
+	if statusCode != 0 {
+		responseStatus = &statusCode
+	}
+	attempts := delivery.Attempts + 1
+
+	if sendErr == nil {
+		_, err := s.db.ExecContext(ctx, `
+			UPDATE webhook_deliveries
+			SET status = $2, attempts = $3, response_status = $4, last_error = '', delivered_at = NOW()
+			WHERE id = $1
+		`, delivery.ID, WebhookDeliveryDelivered, attempts, responseStatus)
+		return err
+	}
+
+	status := WebhookDeliveryPending
+	if attempts >= s.maxAttempts {
+		status = WebhookDeliveryFailed
+	}
+	retryAfter := webhookRetryBase << min(attempts-1, maxWebhookRetryDoublings)
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, response_status = $4, last_error = $5,
+			next_attempt_at = NOW() + $6 * INTERVAL '1 second'
+		WHERE id = $1
+	`, delivery.ID, status, attempts, responseStatus, sendErr.Error(), int(retryAfter.Seconds()))
+	return err
+}
+
+// PurgeDeliveries deletes finished deliveries created before the cutoff,
+// returning how many were removed
+func (s *WebhookService) PurgeDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx,
+		"DELETE FROM webhook_deliveries WHERE created_at < $1 AND status <> $2", before, WebhookDeliveryPending)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge webhook deliveries: %w", err)
+	}
+	return result.RowsAffected()
+}
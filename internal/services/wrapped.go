@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/apperr"
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/models"
+)
+
+const (
+	// wrappedTopLimit is how many artists and tracks a year in review ranks
+	wrappedTopLimit = 5
+
+	// A year still in progress is recomputed more often than a finished one,
+	// which only changes if late plays are backfilled
+	wrappedCurrentYearTTL  = time.Hour
+	wrappedFinishedYearTTL = 24 * time.Hour
+)
+
+// GetWrapped summarizes a user's listening in year, in their time zone.
+// Totals come from the daily rollups and rankings from history, so both are
+// limited by the retention settings. Results are cached in Redis.
+func (s *ProfileService) GetWrapped(ctx context.Context, user *models.User, year int) (*models.Wrapped, error) {
+	loc := UserLocation(user)
+	now := time.Now().In(loc)
+	if year < 2000 || year > now.Year() {
+		return nil, apperr.Invalid("invalid_year", fmt.Sprintf("Year must be between 2000 and %d", now.Year()))
+	}
+
+	key := fmt.Sprintf("wrapped:%s:%d", user.ID, year)
+	if s.redis.Available() {
+		if data, err := s.redis.Get(ctx, key); err == nil {
+			var cached models.Wrapped
+			if json.Unmarshal([]byte(data), &cached) == nil {
+				return &cached, nil
+			}
+		}
+	}
+
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	to := from.AddDate(1, 0, 0)
+	wrapped := &models.Wrapped{Year: year}
+
+	var totals struct {
+		Plays      int   `db:"plays"`
+		ListenedMs int64 `db:"listened_ms"`
+		ActiveDays int   `db:"active_days"`
+	}
+	err := s.db.GetContext(ctx, &totals, `
+		SELECT COALESCE(SUM(plays), 0) AS plays, COALESCE(SUM(listened_ms), 0) AS listened_ms,
+			COUNT(*) FILTER (WHERE plays > 0) AS active_days
+		FROM listening_stats_daily
+		WHERE user_id = $1 AND day >= $2 AND day < $3
+	`, user.ID, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		return nil, fmt.Errorf("failed to total year: %w", err)
+	}
+	wrapped.Plays = totals.Plays
+	wrapped.MinutesListened = msToMinutes(totals.ListenedMs)
+	wrapped.ActiveDays = totals.ActiveDays
+
+	var busiest models.DailyListeningStats
+	err = s.db.GetContext(ctx, &busiest, `
+		SELECT TO_CHAR(day, 'YYYY-MM-DD') AS day, plays, listened_ms, unique_artists
+		FROM listening_stats_daily
+		WHERE user_id = $1 AND day >= $2 AND day < $3 AND plays > 0
+		ORDER BY listened_ms DESC, plays DESC, day
+		LIMIT 1
+	`, user.ID, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	switch {
+	case err == nil:
+		busiest.MinutesListened = msToMinutes(busiest.ListenedMs)
+		wrapped.BusiestDay = &busiest
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, fmt.Errorf("failed to get busiest day: %w", err)
+	}
+
+	if wrapped.TopArtists, err = s.GetTopArtists(ctx, user.ID, from, to, wrappedTopLimit); err != nil {
+		return nil, err
+	}
+	if wrapped.TopTracks, err = s.GetTopTracks(ctx, user.ID, from, to, wrappedTopLimit); err != nil {
+		return nil, err
+	}
+	mood, err := s.GetMoodStats(ctx, user.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	wrapped.Mood = *mood
+
+	if s.redis.Available() {
+		ttl := wrappedFinishedYearTTL
+		if year == now.Year() {
+			ttl = wrappedCurrentYearTTL
+		}
+		if data, err := json.Marshal(wrapped); err == nil {
+			if err := s.redis.Set(ctx, key, data, ttl); err != nil {
+				s.logger.Warn().Ctx(ctx).Err(err).Msg("Failed to cache year in review")
+			}
+		}
+	}
+	return wrapped, nil
+}
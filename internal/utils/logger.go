@@ -13,10 +13,12 @@ import (
 func NewLogger() zerolog.Logger {
 	// Configure the logger
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
-	zerolog.SetGlobalLevel(zerolog.InfoLevel)
 
+	// Levels are enforced per module (see ModuleLogger) so they can change at
+	// runtime; the global level only has to let everything through
+	zerolog.SetGlobalLevel(zerolog.TraceLevel)
 	if os.Getenv("APP_ENV") == "development" {
-		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+		setFallbackLogLevel(zerolog.DebugLevel)
 	}
 
 	// Create a logger that prints a human-friendly format in development
@@ -27,7 +29,7 @@ func NewLogger() zerolog.Logger {
 		logger = log.Logger
 	}
 
-	return logger
+	return ModuleLogger(logger, LogModuleDefault).Hook(RequestIDHook{})
 }
 
 // LoggerMiddleware returns a Gin middleware for logging HTTP requests
@@ -59,6 +61,7 @@ func LoggerMiddleware(logger zerolog.Logger) gin.HandlerFunc {
 		errorMessage := c.Errors.ByType(gin.ErrorTypePrivate).String()
 
 		log := logger.With().
+			Str("request_id", c.GetString("request_id")).
 			Str("method", method).
 			Str("path", path).
 			Int("status", statusCode).
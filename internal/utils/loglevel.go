@@ -0,0 +1,196 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/brandonhuynh1/whatamilisteningto-api/internal/config"
+	"github.com/rs/zerolog"
+)
+
+// Modules whose log levels can be changed at runtime. Loggers that don't
+// belong to a module follow LogModuleDefault.
+const (
+	LogModuleDefault  = "default"
+	LogModuleSpotify  = "spotify"
+	LogModuleRealtime = "realtime"
+	LogModuleDB       = "db"
+)
+
+// moduleLevel is a zerolog sampler that filters events below a runtime
+// adjustable level and keeps only one in every N debug or trace events
+type moduleLevel struct {
+	level      atomic.Int32
+	debugEvery atomic.Uint32
+	debugCount atomic.Uint32
+}
+
+// Sample implements zerolog.Sampler
+func (m *moduleLevel) Sample(lvl zerolog.Level) bool {
+	if lvl < zerolog.Level(m.level.Load()) {
+		return false
+	}
+	if every := m.debugEvery.Load(); lvl <= zerolog.DebugLevel && every > 1 {
+		return m.debugCount.Add(1)%every == 1
+	}
+	return true
+}
+
+var logLevels = struct {
+	sync.Mutex
+	// fallback applies when no default level is configured
+	fallback   zerolog.Level
+	configured map[string]zerolog.Level
+	debugEvery uint32
+	modules    map[string]*moduleLevel
+}{
+	fallback:   zerolog.InfoLevel,
+	configured: map[string]zerolog.Level{},
+	debugEvery: 1,
+	modules:    map[string]*moduleLevel{},
+}
+
+func init() {
+	for _, name := range []string{LogModuleDefault, LogModuleSpotify, LogModuleRealtime, LogModuleDB} {
+		logModule(name)
+	}
+}
+
+// logModule returns the level state for a module, creating it on first use
+func logModule(name string) *moduleLevel {
+	logLevels.Lock()
+	defer logLevels.Unlock()
+
+	if m, ok := logLevels.modules[name]; ok {
+		return m
+	}
+
+	m := &moduleLevel{}
+	level, ok := logLevels.configured[name]
+	if !ok {
+		level = defaultLogLevel()
+	}
+	m.level.Store(int32(level))
+	m.debugEvery.Store(logLevels.debugEvery)
+	logLevels.modules[name] = m
+	return m
+}
+
+// defaultLogLevel is the level for modules without an override. Callers must
+// hold the logLevels lock.
+func defaultLogLevel() zerolog.Level {
+	if level, ok := logLevels.configured[LogModuleDefault]; ok {
+		return level
+	}
+	return logLevels.fallback
+}
+
+// ModuleLogger returns a logger whose level is controlled by module
+func ModuleLogger(logger zerolog.Logger, module string) zerolog.Logger {
+	return logger.Sample(logModule(module))
+}
+
+// ConfigureLogLevels applies the configured default level, per-module
+// overrides, and debug sampling rate. They are also what ResetLogLevels
+// returns to.
+func ConfigureLogLevels(cfg config.LoggingConfig) error {
+	configured := map[string]zerolog.Level{}
+	if cfg.Level != "" {
+		level, err := zerolog.ParseLevel(cfg.Level)
+		if err != nil {
+			return fmt.Errorf("invalid LOG_LEVEL: %w", err)
+		}
+		configured[LogModuleDefault] = level
+	}
+	for _, entry := range cfg.ModuleLevels {
+		module, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return fmt.Errorf("invalid LOG_MODULE_LEVELS entry %q, expected module=level", entry)
+		}
+		level, err := zerolog.ParseLevel(value)
+		if err != nil {
+			return fmt.Errorf("invalid LOG_MODULE_LEVELS entry %q: %w", entry, err)
+		}
+		configured[strings.TrimSpace(module)] = level
+	}
+
+	logLevels.Lock()
+	logLevels.configured = configured
+	logLevels.debugEvery = uint32(max(cfg.DebugSampleEvery, 1))
+	for _, m := range logLevels.modules {
+		m.debugEvery.Store(logLevels.debugEvery)
+	}
+	logLevels.Unlock()
+
+	ResetLogLevels()
+	return nil
+}
+
+// SetLogLevel changes a module's level until the next reset. Setting the
+// default module also moves every module without a configured override.
+func SetLogLevel(module string, level zerolog.Level) error {
+	logLevels.Lock()
+	defer logLevels.Unlock()
+
+	target, ok := logLevels.modules[module]
+	if !ok {
+		return fmt.Errorf("unknown log module %q", module)
+	}
+	target.level.Store(int32(level))
+
+	if module == LogModuleDefault {
+		for name, m := range logLevels.modules {
+			if _, overridden := logLevels.configured[name]; !overridden {
+				m.level.Store(int32(level))
+			}
+		}
+	}
+	return nil
+}
+
+// SetAllLogLevels moves every module to level until the next reset
+func SetAllLogLevels(level zerolog.Level) {
+	logLevels.Lock()
+	defer logLevels.Unlock()
+
+	for _, m := range logLevels.modules {
+		m.level.Store(int32(level))
+	}
+}
+
+// ResetLogLevels restores every module to its configured level
+func ResetLogLevels() {
+	logLevels.Lock()
+	defer logLevels.Unlock()
+
+	for name, m := range logLevels.modules {
+		level, ok := logLevels.configured[name]
+		if !ok {
+			level = defaultLogLevel()
+		}
+		m.level.Store(int32(level))
+	}
+}
+
+// LogLevels reports the current level of every module
+func LogLevels() map[string]string {
+	logLevels.Lock()
+	defer logLevels.Unlock()
+
+	levels := make(map[string]string, len(logLevels.modules))
+	for name, m := range logLevels.modules {
+		levels[name] = zerolog.Level(m.level.Load()).String()
+	}
+	return levels
+}
+
+// setFallbackLogLevel changes the level used when LOG_LEVEL is not set
+func setFallbackLogLevel(level zerolog.Level) {
+	logLevels.Lock()
+	logLevels.fallback = level
+	logLevels.Unlock()
+
+	ResetLogLevels()
+}
@@ -0,0 +1,62 @@
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Build details, set at link time:
+//
+//	go build -ldflags "-X github.com/brandonhuynh1/whatamilisteningto-api/internal/version.Version=v1.2.3 \
+//	  -X github.com/brandonhuynh1/whatamilisteningto-api/internal/version.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/brandonhuynh1/whatamilisteningto-api/internal/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/server
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Info identifies the running build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build details. Commit and build time fall back to the VCS
+// stamp Go embeds in binaries built from a checkout, when ldflags left them
+// unset.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	var revision, modified string
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value
+		case "vcs.time":
+			if info.BuildTime == "" {
+				info.BuildTime = setting.Value
+			}
+		}
+	}
+	if info.Commit == "" && revision != "" {
+		info.Commit = revision
+		if modified == "true" {
+			info.Commit += "-dirty"
+		}
+	}
+	return info
+}
@@ -0,0 +1,298 @@
+package applemusic
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	appleIDIssuer    = "https://appleid.apple.com"
+	appleAuthURL     = "https://appleid.apple.com/auth/authorize"
+	appleTokenURL    = "https://appleid.apple.com/auth/token"
+	appleMusicAPIURL = "https://api.music.apple.com/v1"
+
+	// developerTokenLifetime is how long a MusicKit developer token is valid;
+	// Apple allows up to six months
+	developerTokenLifetime = 12 * time.Hour
+	// clientSecretLifetime is how long a Sign in with Apple client secret is valid
+	clientSecretLifetime = 5 * time.Minute
+)
+
+// Client signs users in with Sign in with Apple and reads their Apple Music
+// listening through the Apple Music API. One MusicKit key with Sign in with
+// Apple enabled signs both the developer token and the client secret.
+type Client struct {
+	TeamID      string
+	KeyID       string
+	ServicesID  string
+	RedirectURI string
+	HTTPClient  *http.Client
+
+	key *ecdsa.PrivateKey
+
+	mu             sync.Mutex
+	developerToken string
+	developerExp   time.Time
+}
+
+// TokenResponse is Apple's response to an authorization code exchange
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	IDToken      string `json:"id_token"`
+}
+
+// IDTokenClaims are the identity claims from a Sign in with Apple ID token
+type IDTokenClaims struct {
+	Issuer   string `json:"iss"`
+	Audience string `json:"aud"`
+	Expiry   int64  `json:"exp"`
+	Subject  string `json:"sub"`
+	Email    string `json:"email"`
+}
+
+// Track is a song from the Apple Music API
+type Track struct {
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	Attributes struct {
+		Name             string `json:"name"`
+		ArtistName       string `json:"artistName"`
+		AlbumName        string `json:"albumName"`
+		DurationInMillis int    `json:"durationInMillis"`
+		URL              string `json:"url"`
+		Artwork          struct {
+			URL string `json:"url"`
+		} `json:"artwork"`
+	} `json:"attributes"`
+}
+
+// ArtworkURL returns the track's artwork at size x size pixels
+func (t Track) ArtworkURL(size int) string {
+	s := strconv.Itoa(size)
+	return strings.NewReplacer("{w}", s, "{h}", s).Replace(t.Attributes.Artwork.URL)
+}
+
+// NewClient creates a client from a MusicKit private key in PEM (PKCS #8)
+// form, as downloaded from the Apple Developer portal
+func NewClient(teamID, keyID string, privateKeyPEM []byte, servicesID, redirectURI string) (*Client, error) {
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing private key: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an ECDSA key")
+	}
+
+	return &Client{
+		TeamID:      teamID,
+		KeyID:       keyID,
+		ServicesID:  servicesID,
+		RedirectURI: redirectURI,
+		HTTPClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		key: key,
+	}, nil
+}
+
+// DeveloperToken returns a MusicKit developer token, reusing one until it is
+// close to expiring
+func (c *Client) DeveloperToken() (string, time.Time, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.developerToken != "" && time.Until(c.developerExp) > time.Hour {
+		return c.developerToken, c.developerExp, nil
+	}
+
+	now := time.Now()
+	exp := now.Add(developerTokenLifetime)
+	token, err := c.sign(map[string]interface{}{
+		"iss": c.TeamID,
+		"iat": now.Unix(),
+		"exp": exp.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	c.developerToken, c.developerExp = token, exp
+	return token, exp, nil
+}
+
+// GetAuthURL returns the Sign in with Apple URL to redirect the user to. No
+// scopes are requested, so Apple redirects back with a query string rather
+// than a cross-site form post.
+func (c *Client) GetAuthURL(state string) string {
+	params := url.Values{}
+	params.Add("client_id", c.ServicesID)
+	params.Add("response_type", "code")
+	params.Add("response_mode", "query")
+	params.Add("redirect_uri", c.RedirectURI)
+	params.Add("state", state)
+
+	return appleAuthURL + "?" + params.Encode()
+}
+
+// ExchangeCodeForToken exchanges a Sign in with Apple authorization code for
+// tokens, including the ID token identifying the user
+func (c *Client) ExchangeCodeForToken(ctx context.Context, code string) (*TokenResponse, error) {
+	secret, err := c.sign(map[string]interface{}{
+		"iss": c.TeamID,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(clientSecretLifetime).Unix(),
+		"aud": appleIDIssuer,
+		"sub": c.ServicesID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data := url.Values{}
+	data.Set("client_id", c.ServicesID)
+	data.Set("client_secret", secret)
+	data.Set("code", code)
+	data.Set("grant_type", "authorization_code")
+	data.Set("redirect_uri", c.RedirectURI)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", appleTokenURL, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("non-200 response: %d %s", resp.StatusCode, body)
+	}
+
+	var tokenResp TokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	return &tokenResp, nil
+}
+
+// ParseIDToken decodes an ID token received from ExchangeCodeForToken and
+// checks its issuer, audience, and expiry. The signature is not verified:
+// the token came straight from Apple's token endpoint over TLS, which OpenID
+// Connect accepts in place of signature validation.
+func (c *Client) ParseIDToken(idToken string) (*IDTokenClaims, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed ID token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("decoding ID token: %w", err)
+	}
+
+	var claims IDTokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("decoding ID token: %w", err)
+	}
+	switch {
+	case claims.Issuer != appleIDIssuer:
+		return nil, fmt.Errorf("unexpected ID token issuer %q", claims.Issuer)
+	case claims.Audience != c.ServicesID:
+		return nil, fmt.Errorf("unexpected ID token audience %q", claims.Audience)
+	case time.Now().Unix() >= claims.Expiry:
+		return nil, errors.New("ID token has expired")
+	case claims.Subject == "":
+		return nil, errors.New("ID token has no subject")
+	}
+	return &claims, nil
+}
+
+// GetRecentlyPlayed returns the user's most recently played songs, newest
+// first. Apple Music has no playback state API, so this is the closest thing
+// to what the user is listening to now.
+func (c *Client) GetRecentlyPlayed(ctx context.Context, musicUserToken string, limit int) ([]Track, error) {
+	developerToken, _, err := c.DeveloperToken()
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf("%s/me/recent/played/tracks?types=songs&limit=%d", appleMusicAPIURL, limit)
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+developerToken)
+	req.Header.Set("Music-User-Token", musicUserToken)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("non-200 response: %d %s", resp.StatusCode, body)
+	}
+
+	var result struct {
+		Data []Track `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	return result.Data, nil
+}
+
+// sign creates an ES256 JWT with the client's key
+func (c *Client) sign(claims map[string]interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "ES256", "kid": c.KeyID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, c.key, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("signing token: %w", err)
+	}
+
+	// JWS encodes the signature as fixed-width r || s
+	size := (c.key.Curve.Params().BitSize + 7) / 8
+	signature := make([]byte, 2*size)
+	r.FillBytes(signature[:size])
+	s.FillBytes(signature[size:])
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,231 @@
+package deezer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	deezerAuthURL    = "https://connect.deezer.com/oauth/auth.php"
+	deezerTokenURL   = "https://connect.deezer.com/oauth/access_token.php"
+	deezerAPIBaseURL = "https://api.deezer.com"
+
+	// quotaWindow is how long Deezer's request quota (50 requests per five
+	// seconds) takes to reset
+	quotaWindow = 5 * time.Second
+
+	// Deezer API error codes
+	errCodeQuota         = 4
+	errCodeInvalidToken  = 300
+	errCodeOAuthRequired = 200
+)
+
+// ErrInvalidToken is returned when Deezer rejects an access token, which
+// happens when the user revokes the app
+var ErrInvalidToken = errors.New("deezer: invalid access token")
+
+// RateLimitError is returned when Deezer reports the request quota exceeded.
+// Deezer answers 200 with an error body rather than 429.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited, retry after %s", e.RetryAfter)
+}
+
+// Client handles communication with the Deezer API
+type Client struct {
+	AppID       string
+	Secret      string
+	RedirectURI string
+	HTTPClient  *http.Client
+}
+
+// TokenResponse represents the response from the Deezer token endpoint.
+// Expires is zero for tokens granted the offline_access permission, which do
+// not expire.
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	Expires     int    `json:"expires"`
+}
+
+// User is a Deezer user
+type User struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// Track is an entry in a user's listening history
+type Track struct {
+	ID        int64  `json:"id"`
+	Title     string `json:"title"`
+	Link      string `json:"link"`
+	Duration  int    `json:"duration"`
+	Timestamp int64  `json:"timestamp"`
+	Artist    struct {
+		Name string `json:"name"`
+	} `json:"artist"`
+	Album struct {
+		Title       string `json:"title"`
+		CoverMedium string `json:"cover_medium"`
+	} `json:"album"`
+}
+
+// apiError is the error body Deezer returns in place of a result
+type apiError struct {
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+		Code    int    `json:"code"`
+	} `json:"error"`
+}
+
+// NewClient creates a new Deezer API client
+func NewClient(appID, secret, redirectURI string) *Client {
+	return &Client{
+		AppID:       appID,
+		Secret:      secret,
+		RedirectURI: redirectURI,
+		HTTPClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// GetAuthURL returns the URL to redirect the user to for Deezer
+// authorization. Deezer does not return an OAuth state parameter, so state
+// is carried in the redirect URI's query string instead.
+func (c *Client) GetAuthURL(state string, perms []string) string {
+	params := url.Values{}
+	params.Add("app_id", c.AppID)
+	params.Add("redirect_uri", c.redirectURIWithState(state))
+	params.Add("perms", strings.Join(perms, ","))
+
+	return deezerAuthURL + "?" + params.Encode()
+}
+
+// redirectURIWithState adds state to the configured redirect URI
+func (c *Client) redirectURIWithState(state string) string {
+	u, err := url.Parse(c.RedirectURI)
+	if err != nil {
+		return c.RedirectURI
+	}
+	q := u.Query()
+	q.Set("state", state)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// ExchangeCodeForToken exchanges an authorization code for an access token
+func (c *Client) ExchangeCodeForToken(ctx context.Context, code string) (*TokenResponse, error) {
+	params := url.Values{}
+	params.Set("app_id", c.AppID)
+	params.Set("secret", c.Secret)
+	params.Set("code", code)
+	params.Set("output", "json")
+
+	req, err := http.NewRequestWithContext(ctx, "GET", deezerTokenURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non-200 response: %d %s", resp.StatusCode, body)
+	}
+
+	// Rejected codes get a plain text body such as "wrong code"
+	var tokenResp TokenResponse
+	if err := json.Unmarshal(body, &tokenResp); err != nil || tokenResp.AccessToken == "" {
+		return nil, fmt.Errorf("token exchange failed: %s", body)
+	}
+
+	return &tokenResp, nil
+}
+
+// GetUser gets the Deezer user an access token belongs to
+func (c *Client) GetUser(ctx context.Context, accessToken string) (*User, error) {
+	var user User
+	if err := c.get(ctx, "/user/me", accessToken, nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetHistory gets the user's listening history, newest first
+func (c *Client) GetHistory(ctx context.Context, accessToken string, limit int) ([]Track, error) {
+	var result struct {
+		Data []Track `json:"data"`
+	}
+	params := url.Values{}
+	params.Set("limit", strconv.Itoa(limit))
+	if err := c.get(ctx, "/user/me/history", accessToken, params, &result); err != nil {
+		return nil, err
+	}
+	return result.Data, nil
+}
+
+// get makes an authenticated API request and decodes the result
+func (c *Client) get(ctx context.Context, path, accessToken string, params url.Values, out interface{}) error {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("access_token", accessToken)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", deezerAPIBaseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return &RateLimitError{RetryAfter: quotaWindow}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("non-200 response: %d %s", resp.StatusCode, body)
+	}
+
+	var apiErr apiError
+	if err := json.Unmarshal(body, &apiErr); err == nil && apiErr.Error != nil {
+		switch apiErr.Error.Code {
+		case errCodeQuota:
+			return &RateLimitError{RetryAfter: quotaWindow}
+		case errCodeInvalidToken, errCodeOAuthRequired:
+			return ErrInvalidToken
+		}
+		return fmt.Errorf("deezer error %d: %s", apiErr.Error.Code, apiErr.Error.Message)
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
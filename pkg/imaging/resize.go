@@ -0,0 +1,62 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Square crops src to a centered square and scales it to size x size pixels.
+// Downscaling averages every source pixel under each output pixel, so
+// detail isn't lost to aliasing; upscaling repeats pixels.
+func Square(src image.Image, size int) *image.RGBA {
+	bounds := src.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	crop := image.Rect(0, 0, side, side).Add(image.Pt(
+		bounds.Min.X+(bounds.Dx()-side)/2,
+		bounds.Min.Y+(bounds.Dy()-side)/2,
+	))
+
+	// Work on RGBA so pixel reads don't go through the color.Color interface
+	rgba := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(rgba, rgba.Bounds(), src, crop.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	if side == 0 {
+		return dst
+	}
+	for y := 0; y < size; y++ {
+		y0, y1 := span(y, size, side)
+		for x := 0; x < size; x++ {
+			x0, x1 := span(x, size, side)
+			dst.SetRGBA(x, y, average(rgba, x0, y0, x1, y1))
+		}
+	}
+	return dst
+}
+
+// span returns the source rows or columns [from, to) under output pixel i,
+// always at least one wide
+func span(i, size, side int) (int, int) {
+	from := i * side / size
+	to := (i + 1) * side / size
+	if to <= from {
+		to = from + 1
+	}
+	return from, to
+}
+
+func average(img *image.RGBA, x0, y0, x1, y1 int) color.RGBA {
+	var r, g, b, a, n int
+	for y := y0; y < y1; y++ {
+		row := img.Pix[img.PixOffset(x0, y):img.PixOffset(x1, y)]
+		for i := 0; i < len(row); i += 4 {
+			r += int(row[i])
+			g += int(row[i+1])
+			b += int(row[i+2])
+			a += int(row[i+3])
+			n++
+		}
+	}
+	return color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: uint8(a / n)}
+}
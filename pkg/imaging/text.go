@@ -0,0 +1,216 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"strings"
+)
+
+const (
+	glyphWidth  = 5
+	glyphHeight = 7
+
+	// glyphAdvance is a glyph's width plus the column between glyphs
+	glyphAdvance = glyphWidth + 1
+)
+
+// font is a 5x7 pixel font for printable ASCII, starting at ' '. Each glyph
+// is five columns, left to right, with the lowest bit the top row.
+var font = [...][glyphWidth]uint8{
+	{0x00, 0x00, 0x00, 0x00, 0x00}, // ' '
+	{0x00, 0x00, 0x5F, 0x00, 0x00}, // !
+	{0x00, 0x07, 0x00, 0x07, 0x00}, // "
+	{0x14, 0x7F, 0x14, 0x7F, 0x14}, // #
+	{0x24, 0x2A, 0x7F, 0x2A, 0x12}, // $
+	{0x23, 0x13, 0x08, 0x64, 0x62}, // %
+	{0x36, 0x49, 0x55, 0x22, 0x50}, // &
+	{0x00, 0x05, 0x03, 0x00, 0x00}, // '
+	{0x00, 0x1C, 0x22, 0x41, 0x00}, // (
+	{0x00, 0x41, 0x22, 0x1C, 0x00}, // )
+	{0x08, 0x2A, 0x1C, 0x2A, 0x08}, // *
+	{0x08, 0x08, 0x3E, 0x08, 0x08}, // +
+	{0x00, 0x50, 0x30, 0x00, 0x00}, // ,
+	{0x08, 0x08, 0x08, 0x08, 0x08}, // -
+	{0x00, 0x60, 0x60, 0x00, 0x00}, // .
+	{0x20, 0x10, 0x08, 0x04, 0x02}, // /
+	{0x3E, 0x51, 0x49, 0x45, 0x3E}, // 0
+	{0x00, 0x42, 0x7F, 0x40, 0x00}, // 1
+	{0x42, 0x61, 0x51, 0x49, 0x46}, // 2
+	{0x21, 0x41, 0x45, 0x4B, 0x31}, // 3
+	{0x18, 0x14, 0x12, 0x7F, 0x10}, // 4
+	{0x27, 0x45, 0x45, 0x45, 0x39}, // 5
+	{0x3C, 0x4A, 0x49, 0x49, 0x30}, // 6
+	{0x01, 0x71, 0x09, 0x05, 0x03}, // 7
+	{0x36, 0x49, 0x49, 0x49, 0x36}, // 8
+	{0x06, 0x49, 0x49, 0x29, 0x1E}, // 9
+	{0x00, 0x36, 0x36, 0x00, 0x00}, // :
+	{0x00, 0x56, 0x36, 0x00, 0x00}, // ;
+	{0x08, 0x14, 0x22, 0x41, 0x00}, // <
+	{0x14, 0x14, 0x14, 0x14, 0x14}, // =
+	{0x00, 0x41, 0x22, 0x14, 0x08}, // >
+	{0x02, 0x01, 0x51, 0x09, 0x06}, // ?
+	{0x32, 0x49, 0x79, 0x41, 0x3E}, // @
+	{0x7E, 0x11, 0x11, 0x11, 0x7E}, // A
+	{0x7F, 0x49, 0x49, 0x49, 0x36}, // B
+	{0x3E, 0x41, 0x41, 0x41, 0x22}, // C
+	{0x7F, 0x41, 0x41, 0x22, 0x1C}, // D
+	{0x7F, 0x49, 0x49, 0x49, 0x41}, // E
+	{0x7F, 0x09, 0x09, 0x01, 0x01}, // F
+	{0x3E, 0x41, 0x41, 0x51, 0x32}, // G
+	{0x7F, 0x08, 0x08, 0x08, 0x7F}, // H
+	{0x00, 0x41, 0x7F, 0x41, 0x00}, // I
+	{0x20, 0x40, 0x41, 0x3F, 0x01}, // J
+	{0x7F, 0x08, 0x14, 0x22, 0x41}, // K
+	{0x7F, 0x40, 0x40, 0x40, 0x40}, // L
+	{0x7F, 0x02, 0x04, 0x02, 0x7F}, // M
+	{0x7F, 0x04, 0x08, 0x10, 0x7F}, // N
+	{0x3E, 0x41, 0x41, 0x41, 0x3E}, // O
+	{0x7F, 0x09, 0x09, 0x09, 0x06}, // P
+	{0x3E, 0x41, 0x51, 0x21, 0x5E}, // Q
+	{0x7F, 0x09, 0x19, 0x29, 0x46}, // R
+	{0x46, 0x49, 0x49, 0x49, 0x31}, // S
+	{0x01, 0x01, 0x7F, 0x01, 0x01}, // T
+	{0x3F, 0x40, 0x40, 0x40, 0x3F}, // U
+	{0x1F, 0x20, 0x40, 0x20, 0x1F}, // V
+	{0x7F, 0x20, 0x18, 0x20, 0x7F}, // W
+	{0x63, 0x14, 0x08, 0x14, 0x63}, // X
+	{0x03, 0x04, 0x78, 0x04, 0x03}, // Y
+	{0x61, 0x51, 0x49, 0x45, 0x43}, // Z
+	{0x00, 0x7F, 0x41, 0x41, 0x00}, // [
+	{0x02, 0x04, 0x08, 0x10, 0x20}, // \
+	{0x00, 0x41, 0x41, 0x7F, 0x00}, // ]
+	{0x04, 0x02, 0x01, 0x02, 0x04}, // ^
+	{0x40, 0x40, 0x40, 0x40, 0x40}, // _
+	{0x00, 0x01, 0x02, 0x04, 0x00}, // `
+	{0x20, 0x54, 0x54, 0x54, 0x78}, // a
+	{0x7F, 0x48, 0x44, 0x44, 0x38}, // b
+	{0x38, 0x44, 0x44, 0x44, 0x20}, // c
+	{0x38, 0x44, 0x44, 0x48, 0x7F}, // d
+	{0x38, 0x54, 0x54, 0x54, 0x18}, // e
+	{0x08, 0x7E, 0x09, 0x01, 0x02}, // f
+	{0x08, 0x54, 0x54, 0x54, 0x3C}, // g
+	{0x7F, 0x08, 0x04, 0x04, 0x78}, // h
+	{0x00, 0x44, 0x7D, 0x40, 0x00}, // i
+	{0x20, 0x40, 0x44, 0x3D, 0x00}, // j
+	{0x7F, 0x10, 0x28, 0x44, 0x00}, // k
+	{0x00, 0x41, 0x7F, 0x40, 0x00}, // l
+	{0x7C, 0x04, 0x18, 0x04, 0x78}, // m
+	{0x7C, 0x08, 0x04, 0x04, 0x78}, // n
+	{0x38, 0x44, 0x44, 0x44, 0x38}, // o
+	{0x7C, 0x14, 0x14, 0x14, 0x08}, // p
+	{0x08, 0x14, 0x14, 0x18, 0x7C}, // q
+	{0x7C, 0x08, 0x04, 0x04, 0x08}, // r
+	{0x48, 0x54, 0x54, 0x54, 0x20}, // s
+	{0x04, 0x3F, 0x44, 0x40, 0x20}, // t
+	{0x3C, 0x40, 0x40, 0x20, 0x7C}, // u
+	{0x1C, 0x20, 0x40, 0x20, 0x1C}, // v
+	{0x3C, 0x40, 0x30, 0x40, 0x3C}, // w
+	{0x44, 0x28, 0x10, 0x28, 0x44}, // x
+	{0x0C, 0x50, 0x50, 0x50, 0x3C}, // y
+	{0x44, 0x64, 0x54, 0x4C, 0x44}, // z
+	{0x00, 0x08, 0x36, 0x41, 0x00}, // {
+	{0x00, 0x00, 0x7F, 0x00, 0x00}, // |
+	{0x00, 0x41, 0x36, 0x08, 0x00}, // }
+	{0x10, 0x08, 0x08, 0x10, 0x08}, // ~
+}
+
+// typographic replaces punctuation the font lacks with the closest ASCII
+var typographic = strings.NewReplacer(
+	"‘", "'", "’", "'", "“", `"`, "”", `"`,
+	"–", "-", "—", "-", "…", "...", "\u00a0", " ",
+)
+
+// ASCII returns s as the font draws it: typographic punctuation becomes its
+// ASCII equivalent, and any other character outside printable ASCII is
+// replaced with '?'
+func ASCII(s string) string {
+	s = typographic.Replace(s)
+	var b strings.Builder
+	for _, r := range s {
+		if r < ' ' || r > '~' {
+			r = '?'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// TextWidth is the width, in pixels, of s drawn at scale
+func TextWidth(s string, scale int) int {
+	n := len(ASCII(s))
+	if n == 0 {
+		return 0
+	}
+	return (n*glyphAdvance - 1) * scale
+}
+
+// TextHeight is the height, in pixels, of a line of text drawn at scale
+func TextHeight(scale int) int {
+	return glyphHeight * scale
+}
+
+// DrawText draws s with its top-left corner at (x, y), each font pixel
+// scaled to a scale x scale block, and returns the x where the next
+// character would go
+func DrawText(dst *image.RGBA, x, y int, s string, scale int, c color.RGBA) int {
+	for _, r := range ASCII(s) {
+		glyph := font[r-' ']
+		for col, bits := range glyph {
+			for row := 0; row < glyphHeight; row++ {
+				if bits&(1<<row) == 0 {
+					continue
+				}
+				block := image.Rect(0, 0, scale, scale).Add(image.Pt(x+col*scale, y+row*scale))
+				Fill(dst, block, c)
+			}
+		}
+		x += glyphAdvance * scale
+	}
+	return x
+}
+
+// Fit cuts s short with "..." so it's at most width pixels wide at scale
+func Fit(s string, width, scale int) string {
+	s = ASCII(s)
+	if TextWidth(s, scale) <= width {
+		return s
+	}
+	for len(s) > 0 && TextWidth(s+"...", scale) > width {
+		s = strings.TrimRight(s[:len(s)-1], " ")
+	}
+	return s + "..."
+}
+
+// Wrap splits s into at most lines lines that each fit width pixels at scale,
+// breaking between words where it can. Text that doesn't fit ends the last
+// line with "...".
+func Wrap(s string, width, scale, lines int) []string {
+	words := strings.Fields(ASCII(s))
+	var out []string
+	for len(words) > 0 && len(out) < lines {
+		line := words[0]
+		n := 1
+		for ; n < len(words); n++ {
+			if TextWidth(line+" "+words[n], scale) > width {
+				break
+			}
+			line += " " + words[n]
+		}
+		words = words[n:]
+		if len(out) == lines-1 && len(words) > 0 {
+			line += " " + strings.Join(words, " ")
+		}
+		out = append(out, Fit(line, width, scale))
+	}
+	return out
+}
+
+// Fill paints rect a solid color
+func Fill(dst *image.RGBA, rect image.Rectangle, c color.RGBA) {
+	rect = rect.Intersect(dst.Bounds())
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			dst.SetRGBA(x, y, c)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package listenbrainz
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultAPIURL is the public ListenBrainz server
+	DefaultAPIURL = "https://api.listenbrainz.org"
+
+	// MaxListensPerRequest is the most listens submit-listens accepts at once
+	MaxListensPerRequest = 1000
+
+	// maxErrorBytes caps how much of an error response is kept
+	maxErrorBytes = 512
+)
+
+// ErrInvalidToken is returned when ListenBrainz rejects a user token, which
+// happens when the user resets it
+var ErrInvalidToken = errors.New("listenbrainz: invalid user token")
+
+// Error is a non-2xx response from ListenBrainz
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("listenbrainz error %d: %s", e.StatusCode, e.Message)
+}
+
+// Temporary reports whether the request may succeed if retried later
+func (e *Error) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// Client handles communication with a ListenBrainz server. Users
+// authenticate with the token from their ListenBrainz settings page.
+type Client struct {
+	APIURL     string
+	HTTPClient *http.Client
+}
+
+// Listen is a play to submit to a user's ListenBrainz history
+type Listen struct {
+	Artist     string
+	Track      string
+	Album      string
+	DurationMs int
+	ListenedAt time.Time
+}
+
+// NewClient creates a new ListenBrainz client for the server at apiURL
+func NewClient(apiURL string) *Client {
+	return &Client{
+		APIURL: strings.TrimRight(apiURL, "/"),
+		HTTPClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// ValidateToken checks a user token, returning the username it belongs to
+func (c *Client) ValidateToken(ctx context.Context, token string) (string, error) {
+	var resp struct {
+		Valid    bool   `json:"valid"`
+		UserName string `json:"user_name"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/1/validate-token", token, nil, &resp); err != nil {
+		return "", err
+	}
+	if !resp.Valid {
+		return "", ErrInvalidToken
+	}
+	return resp.UserName, nil
+}
+
+// SubmitListens adds up to MaxListensPerRequest finished plays to the token
+// owner's history
+func (c *Client) SubmitListens(ctx context.Context, token string, listens []Listen) error {
+	if len(listens) > MaxListensPerRequest {
+		return fmt.Errorf("listenbrainz: at most %d listens per request", MaxListensPerRequest)
+	}
+
+	type trackMetadata struct {
+		ArtistName     string                 `json:"artist_name"`
+		TrackName      string                 `json:"track_name"`
+		ReleaseName    string                 `json:"release_name,omitempty"`
+		AdditionalInfo map[string]interface{} `json:"additional_info,omitempty"`
+	}
+	type payload struct {
+		ListenedAt    int64         `json:"listened_at"`
+		TrackMetadata trackMetadata `json:"track_metadata"`
+	}
+
+	body := struct {
+		ListenType string    `json:"listen_type"`
+		Payload    []payload `json:"payload"`
+	}{ListenType: "import"}
+	if len(listens) == 1 {
+		body.ListenType = "single"
+	}
+	for _, listen := range listens {
+		metadata := trackMetadata{
+			ArtistName:  listen.Artist,
+			TrackName:   listen.Track,
+			ReleaseName: listen.Album,
+		}
+		if listen.DurationMs > 0 {
+			metadata.AdditionalInfo = map[string]interface{}{"duration_ms": listen.DurationMs}
+		}
+		body.Payload = append(body.Payload, payload{ListenedAt: listen.ListenedAt.Unix(), TrackMetadata: metadata})
+	}
+
+	return c.do(ctx, http.MethodPost, "/1/submit-listens", token, body, nil)
+}
+
+// do makes an authenticated request, encoding in as the JSON body when it's
+// not nil and decoding the response into out when it's not nil
+func (c *Client) do(ctx context.Context, method, path, token string, in, out interface{}) error {
+	var reqBody io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.APIURL+path, reqBody)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "Token "+token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return ErrInvalidToken
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBytes))
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		message := string(bytes.TrimSpace(body))
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Error != "" {
+			message = apiErr.Error
+		}
+		return &Error{StatusCode: resp.StatusCode, Message: message}
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
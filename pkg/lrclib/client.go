@@ -0,0 +1,86 @@
+package lrclib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client looks up lyrics from an LRCLIB server
+type Client struct {
+	BaseURL    string
+	UserAgent  string
+	HTTPClient *http.Client
+}
+
+// Lyrics is an LRCLIB lyrics record. SyncedLyrics is in LRC format, with a
+// [mm:ss.xx] timestamp before each line.
+type Lyrics struct {
+	ID           int64   `json:"id"`
+	TrackName    string  `json:"trackName"`
+	ArtistName   string  `json:"artistName"`
+	AlbumName    string  `json:"albumName"`
+	Duration     float64 `json:"duration"`
+	Instrumental bool    `json:"instrumental"`
+	PlainLyrics  string  `json:"plainLyrics"`
+	SyncedLyrics string  `json:"syncedLyrics"`
+}
+
+// NewClient creates a new LRCLIB client. LRCLIB asks clients to identify
+// themselves with userAgent.
+func NewClient(baseURL, userAgent string) *Client {
+	return &Client{
+		BaseURL:   strings.TrimRight(baseURL, "/"),
+		UserAgent: userAgent,
+		HTTPClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// Get looks up the lyrics for a track, returning nil when LRCLIB has none.
+// The duration lets LRCLIB pick the right version of a track.
+func (c *Client) Get(ctx context.Context, trackName, artistName, albumName string, duration time.Duration) (*Lyrics, error) {
+	params := url.Values{}
+	params.Set("track_name", trackName)
+	params.Set("artist_name", artistName)
+	if albumName != "" {
+		params.Set("album_name", albumName)
+	}
+	if duration > 0 {
+		params.Set("duration", strconv.Itoa(int(duration.Round(time.Second).Seconds())))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.BaseURL+"/get?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", c.UserAgent)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("non-200 response: %d %s", resp.StatusCode, body)
+	}
+
+	var lyrics Lyrics
+	if err := json.NewDecoder(resp.Body).Decode(&lyrics); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	return &lyrics, nil
+}
@@ -0,0 +1,104 @@
+package odesli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultAPIURL is the public Odesli (song.link) API
+const DefaultAPIURL = "https://api.song.link/v1-alpha.1"
+
+// ErrRateLimited is returned when Odesli turns a request away for exceeding
+// the rate limit: 10 requests a minute without an API key
+var ErrRateLimited = errors.New("odesli: rate limited")
+
+// Client looks up a track's links on other platforms from the Odesli API
+type Client struct {
+	APIURL     string
+	APIKey     string
+	HTTPClient *http.Client
+}
+
+// Links are the same track on other platforms. PageURL is the song.link page
+// listing every platform; the others are empty when the platform doesn't
+// have the track.
+type Links struct {
+	PageURL    string
+	AppleMusic string
+	YouTube    string
+	Deezer     string
+}
+
+// NewClient creates a new Odesli client. apiKey may be empty for the
+// rate-limited free tier.
+func NewClient(apiURL, apiKey string) *Client {
+	return &Client{
+		APIURL: strings.TrimRight(apiURL, "/"),
+		APIKey: apiKey,
+		HTTPClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// Links resolves a track URL from any supported platform, returning nil when
+// Odesli can't match it
+func (c *Client) Links(ctx context.Context, trackURL string) (*Links, error) {
+	params := url.Values{}
+	params.Set("url", trackURL)
+	if c.APIKey != "" {
+		params.Set("key", c.APIKey)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.APIURL+"/links?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusBadRequest, http.StatusNotFound:
+		// Unsupported URLs and unknown tracks
+		return nil, nil
+	case http.StatusTooManyRequests:
+		return nil, ErrRateLimited
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("non-200 response: %d %s", resp.StatusCode, body)
+	}
+
+	type platformLink struct {
+		URL string `json:"url"`
+	}
+	var result struct {
+		PageURL         string `json:"pageUrl"`
+		LinksByPlatform struct {
+			AppleMusic platformLink `json:"appleMusic"`
+			YouTube    platformLink `json:"youtube"`
+			Deezer     platformLink `json:"deezer"`
+		} `json:"linksByPlatform"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	return &Links{
+		PageURL:    result.PageURL,
+		AppleMusic: result.LinksByPlatform.AppleMusic.URL,
+		YouTube:    result.LinksByPlatform.YouTube.URL,
+		Deezer:     result.LinksByPlatform.Deezer.URL,
+	}, nil
+}
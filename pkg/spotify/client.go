@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -16,8 +17,37 @@ const (
 	spotifyAuthURL    = "https://accounts.spotify.com/authorize"
 	spotifyTokenURL   = "https://accounts.spotify.com/api/token"
 	spotifyAPIBaseURL = "https://api.spotify.com/v1"
+
+	// MaxAudioFeatureIDs is the most tracks GetAudioFeatures accepts at once
+	MaxAudioFeatureIDs = 100
+
+	// defaultRetryAfter is used when a 429 response has no usable Retry-After
+	// header
+	defaultRetryAfter = 30 * time.Second
 )
 
+// RateLimitError is returned when Spotify answers 429 Too Many Requests.
+// RetryAfter is how long Spotify asked callers to wait before trying again.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited, retry after %s", e.RetryAfter)
+}
+
+// API is the subset of Spotify used by the app, implemented by Client and,
+// for development without Spotify, FakeClient
+type API interface {
+	GetAuthURL(state string, scopes []string) string
+	ExchangeCodeForToken(ctx context.Context, code string) (*TokenResponse, error)
+	RefreshAccessToken(ctx context.Context, refreshToken string) (*TokenResponse, error)
+	GetCurrentlyPlaying(ctx context.Context, accessToken string) (*CurrentlyPlayingResponse, error)
+	GetRecentlyPlayed(ctx context.Context, accessToken string, limit int) (*RecentlyPlayedResponse, error)
+	GetUserProfile(ctx context.Context, accessToken string) (*UserProfileResponse, error)
+	GetAudioFeatures(ctx context.Context, accessToken string, trackIDs []string) (*AudioFeaturesResponse, error)
+}
+
 // Client handles communication with the Spotify API
 type Client struct {
 	ClientID     string
@@ -59,6 +89,91 @@ type TokenResponse struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+// UserProfileResponse represents the response from the current user's
+// profile endpoint. Email is only set with the user-read-email scope.
+type UserProfileResponse struct {
+	ID          string `json:"id"`
+	Email       string `json:"email"`
+	DisplayName string `json:"display_name"`
+}
+
+// CurrentlyPlayingResponse represents the user's playback state. Item is nil
+// while something other than a track plays, such as an ad or a podcast
+// episode.
+type CurrentlyPlayingResponse struct {
+	IsPlaying            bool         `json:"is_playing"`
+	ProgressMs           int          `json:"progress_ms"`
+	CurrentlyPlayingType string       `json:"currently_playing_type"`
+	Item                 *TrackObject `json:"item"`
+}
+
+// RecentlyPlayedResponse represents a page of the user's play history,
+// newest first
+type RecentlyPlayedResponse struct {
+	Items []PlayHistoryObject `json:"items"`
+}
+
+// PlayHistoryObject is one play in the user's history. PlayedAt is when the
+// track finished.
+type PlayHistoryObject struct {
+	Track    TrackObject `json:"track"`
+	PlayedAt time.Time   `json:"played_at"`
+}
+
+// TrackObject is a Spotify track. Artists are in credit order.
+type TrackObject struct {
+	ID           string         `json:"id"`
+	Name         string         `json:"name"`
+	DurationMs   int            `json:"duration_ms"`
+	ExternalURLs ExternalURLs   `json:"external_urls"`
+	Album        AlbumObject    `json:"album"`
+	Artists      []ArtistObject `json:"artists"`
+}
+
+// AlbumObject is the album a track appears on. Images are ordered widest
+// first.
+type AlbumObject struct {
+	ID     string        `json:"id"`
+	Name   string        `json:"name"`
+	Images []ImageObject `json:"images"`
+}
+
+// ArtistObject is an artist credited on a track
+type ArtistObject struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// ImageObject is a cover image. Width and Height are zero when unknown.
+type ImageObject struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// AudioFeaturesResponse holds the audio features of several tracks, in the
+// order they were requested. Entries are nil for tracks Spotify has no
+// features for.
+type AudioFeaturesResponse struct {
+	AudioFeatures []*AudioFeaturesObject `json:"audio_features"`
+}
+
+// AudioFeaturesObject describes how a track sounds. Tempo is in beats per
+// minute; the rest range from 0 to 1, with higher Valence sounding more
+// positive.
+type AudioFeaturesObject struct {
+	ID           string  `json:"id"`
+	Tempo        float64 `json:"tempo"`
+	Energy       float64 `json:"energy"`
+	Danceability float64 `json:"danceability"`
+	Valence      float64 `json:"valence"`
+}
+
+// ExternalURLs links an object on the Spotify web player
+type ExternalURLs struct {
+	Spotify string `json:"spotify"`
+}
+
 // ExchangeCodeForToken exchanges an authorization code for an access token
 func (c *Client) ExchangeCodeForToken(ctx context.Context, code string) (*TokenResponse, error) {
 	data := url.Values{}
@@ -96,9 +211,8 @@ func (c *Client) doTokenRequest(ctx context.Context, data url.Values) (*TokenRes
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("non-200 response: %d %s", resp.StatusCode, body)
+	if err := checkResponse(resp); err != nil {
+		return nil, err
 	}
 
 	var tokenResp TokenResponse
@@ -110,7 +224,7 @@ func (c *Client) doTokenRequest(ctx context.Context, data url.Values) (*TokenRes
 }
 
 // GetCurrentlyPlaying gets the user's currently playing track
-func (c *Client) GetCurrentlyPlaying(ctx context.Context, accessToken string) (map[string]interface{}, error) {
+func (c *Client) GetCurrentlyPlaying(ctx context.Context, accessToken string) (*CurrentlyPlayingResponse, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", spotifyAPIBaseURL+"/me/player/currently-playing", nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
@@ -129,21 +243,48 @@ func (c *Client) GetCurrentlyPlaying(ctx context.Context, accessToken string) (m
 		return nil, nil
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("non-200 response: %d %s", resp.StatusCode, body)
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var result CurrentlyPlayingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	return &result, nil
+}
+
+// GetRecentlyPlayed gets the user's most recently played tracks, newest first
+func (c *Client) GetRecentlyPlayed(ctx context.Context, accessToken string, limit int) (*RecentlyPlayedResponse, error) {
+	endpoint := fmt.Sprintf("%s/me/player/recently-played?limit=%d", spotifyAPIBaseURL, limit)
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return nil, err
 	}
 
-	var result map[string]interface{}
+	var result RecentlyPlayedResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return nil, fmt.Errorf("decoding response: %w", err)
 	}
 
-	return result, nil
+	return &result, nil
 }
 
 // GetUserProfile gets the user's Spotify profile
-func (c *Client) GetUserProfile(ctx context.Context, accessToken string) (map[string]interface{}, error) {
+func (c *Client) GetUserProfile(ctx context.Context, accessToken string) (*UserProfileResponse, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", spotifyAPIBaseURL+"/me", nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
@@ -157,15 +298,74 @@ func (c *Client) GetUserProfile(ctx context.Context, accessToken string) (map[st
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("non-200 response: %d %s", resp.StatusCode, body)
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var result UserProfileResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	return &result, nil
+}
+
+// GetAudioFeatures gets the audio features of up to MaxAudioFeatureIDs tracks
+func (c *Client) GetAudioFeatures(ctx context.Context, accessToken string, trackIDs []string) (*AudioFeaturesResponse, error) {
+	if len(trackIDs) > MaxAudioFeatureIDs {
+		return nil, fmt.Errorf("too many tracks: %d, at most %d", len(trackIDs), MaxAudioFeatureIDs)
+	}
+
+	endpoint := spotifyAPIBaseURL + "/audio-features?ids=" + url.QueryEscape(strings.Join(trackIDs, ","))
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return nil, err
 	}
 
-	var result map[string]interface{}
+	var result AudioFeaturesResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return nil, fmt.Errorf("decoding response: %w", err)
 	}
 
-	return result, nil
+	return &result, nil
+}
+
+// checkResponse returns an error for any status other than 200, reporting
+// 429 as a RateLimitError. Spotify sends Retry-After in seconds, but an HTTP
+// date is accepted too.
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return &RateLimitError{RetryAfter: retryAfter(resp.Header.Get("Retry-After"))}
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return fmt.Errorf("non-200 response: %d %s", resp.StatusCode, body)
+}
+
+// retryAfter parses a Retry-After header value, falling back to
+// defaultRetryAfter when it is missing or already in the past
+func retryAfter(value string) time.Duration {
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait
+		}
+	}
+	return defaultRetryAfter
 }
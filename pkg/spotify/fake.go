@@ -0,0 +1,196 @@
+package spotify
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Fake account returned by FakeClient
+const (
+	FakeUserID      = "fake-listener"
+	FakeDisplayName = "Dev Listener"
+	fakeAuthCode    = "fake-auth-code"
+)
+
+var (
+	fakeAdjectives = []string{"Neon", "Velvet", "Paper", "Golden", "Electric", "Quiet", "Broken", "Midnight", "Silver", "Wild", "Hollow", "Crystal"}
+	fakeNouns      = []string{"Skyline", "Echoes", "Satellite", "Harbor", "Daydream", "Static", "Horizon", "Lanterns", "Tides", "Parade", "Orbit", "Gardens"}
+	fakeArtists    = []string{"The Placeholders", "Lorem & the Ipsums", "Null Pointer", "Mock Turtle", "Stub Hub", "Fixture Club"}
+	fakeColors     = []string{"#e63946", "#f4a261", "#2a9d8f", "#457b9d", "#9b5de5", "#00bbf9", "#f15bb5", "#80b918"}
+)
+
+// fakeTrack is one generated entry in the fake catalog
+type fakeTrack struct {
+	id, name, artist, album, color string
+	features                       AudioFeaturesObject
+}
+
+// FakeClient stands in for Spotify during development. Authorization
+// succeeds immediately for a single fake account, and currently-playing
+// cycles through a generated catalog, moving to the next track every
+// trackLength with a short pause now and then.
+type FakeClient struct {
+	redirectURI string
+	trackLength time.Duration
+	catalog     []fakeTrack
+	epoch       time.Time
+}
+
+// NewFakeClient creates a fake Spotify client whose tracks each play for
+// trackLength
+func NewFakeClient(redirectURI string, trackLength time.Duration) *FakeClient {
+	// A fixed seed keeps the catalog the same across restarts
+	rng := rand.New(rand.NewSource(1))
+	catalog := make([]fakeTrack, 24)
+	for i := range catalog {
+		name := fakeAdjectives[rng.Intn(len(fakeAdjectives))] + " " + fakeNouns[rng.Intn(len(fakeNouns))]
+		catalog[i] = fakeTrack{
+			id:     fmt.Sprintf("fake%018d", i),
+			name:   name,
+			artist: fakeArtists[rng.Intn(len(fakeArtists))],
+			album:  fakeNouns[rng.Intn(len(fakeNouns))] + " (Demo)",
+			color:  fakeColors[i%len(fakeColors)],
+		}
+	}
+
+	// Features come from their own source so adding them didn't reshuffle
+	// the catalog
+	featureRNG := rand.New(rand.NewSource(2))
+	for i := range catalog {
+		catalog[i].features = AudioFeaturesObject{
+			ID:           catalog[i].id,
+			Tempo:        float64(70 + featureRNG.Intn(110)),
+			Energy:       featureRNG.Float64(),
+			Danceability: featureRNG.Float64(),
+			Valence:      featureRNG.Float64(),
+		}
+	}
+
+	return &FakeClient{
+		redirectURI: redirectURI,
+		trackLength: trackLength,
+		catalog:     catalog,
+		epoch:       time.Now(),
+	}
+}
+
+// GetAuthURL skips Spotify and sends the browser straight back to the
+// callback with a code ExchangeCodeForToken accepts
+func (c *FakeClient) GetAuthURL(state string, _ []string) string {
+	params := url.Values{}
+	params.Add("code", fakeAuthCode)
+	params.Add("state", state)
+	return c.redirectURI + "?" + params.Encode()
+}
+
+// ExchangeCodeForToken issues fake tokens for the code from GetAuthURL
+func (c *FakeClient) ExchangeCodeForToken(_ context.Context, code string) (*TokenResponse, error) {
+	if code != fakeAuthCode {
+		return nil, fmt.Errorf("non-200 response: 400 invalid authorization code")
+	}
+	return c.token(), nil
+}
+
+// RefreshAccessToken issues a new fake access token
+func (c *FakeClient) RefreshAccessToken(_ context.Context, _ string) (*TokenResponse, error) {
+	return c.token(), nil
+}
+
+func (c *FakeClient) token() *TokenResponse {
+	return &TokenResponse{
+		AccessToken:  fmt.Sprintf("fake-access-%d", time.Now().UnixNano()),
+		TokenType:    "Bearer",
+		Scope:        "user-read-private user-read-email user-read-currently-playing user-read-recently-played",
+		ExpiresIn:    3600,
+		RefreshToken: "fake-refresh-token",
+	}
+}
+
+// GetUserProfile returns the fake account
+func (c *FakeClient) GetUserProfile(_ context.Context, _ string) (*UserProfileResponse, error) {
+	return &UserProfileResponse{
+		ID:          FakeUserID,
+		Email:       FakeUserID + "@example.com",
+		DisplayName: FakeDisplayName,
+	}, nil
+}
+
+// GetCurrentlyPlaying returns the catalog entry for the current time slot.
+// Every seventh slot is paused.
+func (c *FakeClient) GetCurrentlyPlaying(_ context.Context, _ string) (*CurrentlyPlayingResponse, error) {
+	elapsed := time.Since(c.epoch)
+	slot := int(elapsed / c.trackLength)
+	progress := elapsed % c.trackLength
+
+	item := c.item(slot)
+	return &CurrentlyPlayingResponse{
+		IsPlaying:            slot%7 != 6,
+		ProgressMs:           int(progress.Milliseconds()),
+		CurrentlyPlayingType: "track",
+		Item:                 &item,
+	}, nil
+}
+
+// GetRecentlyPlayed returns the tracks of the slots before the current one,
+// newest first
+func (c *FakeClient) GetRecentlyPlayed(_ context.Context, _ string, limit int) (*RecentlyPlayedResponse, error) {
+	slot := int(time.Since(c.epoch) / c.trackLength)
+	result := &RecentlyPlayedResponse{Items: []PlayHistoryObject{}}
+	for previous := slot - 1; previous >= 0 && len(result.Items) < limit; previous-- {
+		result.Items = append(result.Items, PlayHistoryObject{
+			Track:    c.item(previous),
+			PlayedAt: c.epoch.Add(time.Duration(previous+1) * c.trackLength).UTC(),
+		})
+	}
+	return result, nil
+}
+
+// GetAudioFeatures returns the generated features of catalog tracks, and nil
+// for any other ID
+func (c *FakeClient) GetAudioFeatures(_ context.Context, _ string, trackIDs []string) (*AudioFeaturesResponse, error) {
+	result := &AudioFeaturesResponse{AudioFeatures: make([]*AudioFeaturesObject, len(trackIDs))}
+	for i, id := range trackIDs {
+		for _, track := range c.catalog {
+			if track.id == id {
+				features := track.features
+				result.AudioFeatures[i] = &features
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+// item returns a slot's track
+func (c *FakeClient) item(slot int) TrackObject {
+	track := c.catalog[slot%len(c.catalog)]
+	return TrackObject{
+		ID:           track.id,
+		Name:         track.name,
+		DurationMs:   int(c.trackLength.Milliseconds()),
+		ExternalURLs: ExternalURLs{Spotify: "https://open.spotify.com/track/" + track.id},
+		Album: AlbumObject{
+			Name:   track.album,
+			Images: []ImageObject{{URL: albumArt(track), Width: 300, Height: 300}},
+		},
+		Artists: []ArtistObject{{Name: track.artist}},
+	}
+}
+
+// albumArt draws a solid cover with the track's initials, so no image host
+// is needed
+func albumArt(track fakeTrack) string {
+	var initials strings.Builder
+	for _, word := range strings.Fields(track.name) {
+		initials.WriteByte(word[0])
+	}
+	svg := fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="300" height="300">`+
+		`<rect width="300" height="300" fill="%s"/>`+
+		`<text x="150" y="170" font-family="sans-serif" font-size="96" fill="#fff" text-anchor="middle">%s</text></svg>`,
+		track.color, initials.String())
+	return "data:image/svg+xml," + url.PathEscape(svg)
+}
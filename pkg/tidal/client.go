@@ -0,0 +1,193 @@
+package tidal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	tidalAuthURL    = "https://login.tidal.com/authorize"
+	tidalTokenURL   = "https://auth.tidal.com/v1/oauth2/token"
+	tidalAPIBaseURL = "https://openapi.tidal.com/v2"
+
+	// defaultRetryAfter is used when a 429 response has no Retry-After header
+	defaultRetryAfter = 30 * time.Second
+)
+
+// RateLimitError is returned when TIDAL answers 429 Too Many Requests
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited, retry after %s", e.RetryAfter)
+}
+
+// Client handles communication with the TIDAL API. TIDAL requires PKCE for
+// the authorization code flow, so callers supply a code verifier when
+// starting and completing sign-in.
+type Client struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURI  string
+	HTTPClient   *http.Client
+}
+
+// TokenResponse represents the response from the TIDAL token endpoint
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	Scope        string `json:"scope"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// User is the signed-in TIDAL user
+type User struct {
+	ID       string
+	Username string
+	Email    string
+}
+
+// NewClient creates a new TIDAL API client
+func NewClient(clientID, clientSecret, redirectURI string) *Client {
+	return &Client{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURI:  redirectURI,
+		HTTPClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// GetAuthURL returns the URL to redirect the user to for TIDAL authorization
+func (c *Client) GetAuthURL(state, codeVerifier string, scopes []string) string {
+	challenge := sha256.Sum256([]byte(codeVerifier))
+
+	params := url.Values{}
+	params.Add("client_id", c.ClientID)
+	params.Add("response_type", "code")
+	params.Add("redirect_uri", c.RedirectURI)
+	params.Add("scope", strings.Join(scopes, " "))
+	params.Add("state", state)
+	params.Add("code_challenge_method", "S256")
+	params.Add("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+
+	return tidalAuthURL + "?" + params.Encode()
+}
+
+// ExchangeCodeForToken exchanges an authorization code for an access token,
+// proving possession of the verifier the sign-in was started with
+func (c *Client) ExchangeCodeForToken(ctx context.Context, code, codeVerifier string) (*TokenResponse, error) {
+	data := url.Values{}
+	data.Set("grant_type", "authorization_code")
+	data.Set("code", code)
+	data.Set("redirect_uri", c.RedirectURI)
+	data.Set("code_verifier", codeVerifier)
+
+	return c.doTokenRequest(ctx, data)
+}
+
+// RefreshAccessToken refreshes an access token using a refresh token
+func (c *Client) RefreshAccessToken(ctx context.Context, refreshToken string) (*TokenResponse, error) {
+	data := url.Values{}
+	data.Set("grant_type", "refresh_token")
+	data.Set("refresh_token", refreshToken)
+
+	return c.doTokenRequest(ctx, data)
+}
+
+// doTokenRequest handles requests to the TIDAL token endpoint
+func (c *Client) doTokenRequest(ctx context.Context, data url.Values) (*TokenResponse, error) {
+	data.Set("client_id", c.ClientID)
+	data.Set("client_secret", c.ClientSecret)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", tidalTokenURL, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var tokenResp TokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	return &tokenResp, nil
+}
+
+// GetUser gets the TIDAL user an access token belongs to
+func (c *Client) GetUser(ctx context.Context, accessToken string) (*User, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", tidalAPIBaseURL+"/users/me", nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/vnd.api+json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Data struct {
+			ID         string `json:"id"`
+			Attributes struct {
+				Username string `json:"username"`
+				Email    string `json:"email"`
+			} `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	return &User{
+		ID:       result.Data.ID,
+		Username: result.Data.Attributes.Username,
+		Email:    result.Data.Attributes.Email,
+	}, nil
+}
+
+// checkResponse turns non-200 responses into errors, reporting 429s as a
+// RateLimitError
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		retryAfter := defaultRetryAfter
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+		return &RateLimitError{RetryAfter: retryAfter}
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return fmt.Errorf("non-200 response: %d %s", resp.StatusCode, body)
+}
@@ -0,0 +1,6 @@
+// Package proto holds the protobuf definitions for the gRPC API. Regenerate
+// the Go code with `go generate ./proto` (requires protoc, protoc-gen-go, and
+// protoc-gen-go-grpc).
+package proto
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative nowplaying/v1/nowplaying.proto
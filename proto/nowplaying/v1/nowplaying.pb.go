@@ -0,0 +1,782 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.1
+// 	protoc        (unknown)
+// source: nowplaying/v1/nowplaying.proto
+
+package nowplayingv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetProfileRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ProfileUrl string `protobuf:"bytes,1,opt,name=profile_url,json=profileUrl,proto3" json:"profile_url,omitempty"`
+}
+
+func (x *GetProfileRequest) Reset() {
+	*x = GetProfileRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_nowplaying_v1_nowplaying_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetProfileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetProfileRequest) ProtoMessage() {}
+
+func (x *GetProfileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_nowplaying_v1_nowplaying_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetProfileRequest.ProtoReflect.Descriptor instead.
+func (*GetProfileRequest) Descriptor() ([]byte, []int) {
+	return file_nowplaying_v1_nowplaying_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GetProfileRequest) GetProfileUrl() string {
+	if x != nil {
+		return x.ProfileUrl
+	}
+	return ""
+}
+
+type GetNowPlayingRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ProfileUrl string `protobuf:"bytes,1,opt,name=profile_url,json=profileUrl,proto3" json:"profile_url,omitempty"`
+}
+
+func (x *GetNowPlayingRequest) Reset() {
+	*x = GetNowPlayingRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_nowplaying_v1_nowplaying_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetNowPlayingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetNowPlayingRequest) ProtoMessage() {}
+
+func (x *GetNowPlayingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_nowplaying_v1_nowplaying_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetNowPlayingRequest.ProtoReflect.Descriptor instead.
+func (*GetNowPlayingRequest) Descriptor() ([]byte, []int) {
+	return file_nowplaying_v1_nowplaying_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetNowPlayingRequest) GetProfileUrl() string {
+	if x != nil {
+		return x.ProfileUrl
+	}
+	return ""
+}
+
+type WatchNowPlayingRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ProfileUrl string `protobuf:"bytes,1,opt,name=profile_url,json=profileUrl,proto3" json:"profile_url,omitempty"`
+}
+
+func (x *WatchNowPlayingRequest) Reset() {
+	*x = WatchNowPlayingRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_nowplaying_v1_nowplaying_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WatchNowPlayingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchNowPlayingRequest) ProtoMessage() {}
+
+func (x *WatchNowPlayingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_nowplaying_v1_nowplaying_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchNowPlayingRequest.ProtoReflect.Descriptor instead.
+func (*WatchNowPlayingRequest) Descriptor() ([]byte, []int) {
+	return file_nowplaying_v1_nowplaying_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *WatchNowPlayingRequest) GetProfileUrl() string {
+	if x != nil {
+		return x.ProfileUrl
+	}
+	return ""
+}
+
+type Profile struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	UserId          string   `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	DisplayName     string   `protobuf:"bytes,2,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"`
+	ProfileUrl      string   `protobuf:"bytes,3,opt,name=profile_url,json=profileUrl,proto3" json:"profile_url,omitempty"`
+	Theme           string   `protobuf:"bytes,4,opt,name=theme,proto3" json:"theme,omitempty"`
+	BackgroundColor string   `protobuf:"bytes,5,opt,name=background_color,json=backgroundColor,proto3" json:"background_color,omitempty"`
+	TextColor       string   `protobuf:"bytes,6,opt,name=text_color,json=textColor,proto3" json:"text_color,omitempty"`
+	CustomMessage   string   `protobuf:"bytes,7,opt,name=custom_message,json=customMessage,proto3" json:"custom_message,omitempty"`
+	ShowStats       bool     `protobuf:"varint,8,opt,name=show_stats,json=showStats,proto3" json:"show_stats,omitempty"`
+	ShowHistory     bool     `protobuf:"varint,9,opt,name=show_history,json=showHistory,proto3" json:"show_history,omitempty"`
+	AnimationStyle  string   `protobuf:"bytes,10,opt,name=animation_style,json=animationStyle,proto3" json:"animation_style,omitempty"`
+	CurrentTrack    *Track   `protobuf:"bytes,11,opt,name=current_track,json=currentTrack,proto3" json:"current_track,omitempty"`
+	RecentTracks    []*Track `protobuf:"bytes,12,rep,name=recent_tracks,json=recentTracks,proto3" json:"recent_tracks,omitempty"`
+	ViewerCount     int32    `protobuf:"varint,13,opt,name=viewer_count,json=viewerCount,proto3" json:"viewer_count,omitempty"`
+}
+
+func (x *Profile) Reset() {
+	*x = Profile{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_nowplaying_v1_nowplaying_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Profile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Profile) ProtoMessage() {}
+
+func (x *Profile) ProtoReflect() protoreflect.Message {
+	mi := &file_nowplaying_v1_nowplaying_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Profile.ProtoReflect.Descriptor instead.
+func (*Profile) Descriptor() ([]byte, []int) {
+	return file_nowplaying_v1_nowplaying_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Profile) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *Profile) GetDisplayName() string {
+	if x != nil {
+		return x.DisplayName
+	}
+	return ""
+}
+
+func (x *Profile) GetProfileUrl() string {
+	if x != nil {
+		return x.ProfileUrl
+	}
+	return ""
+}
+
+func (x *Profile) GetTheme() string {
+	if x != nil {
+		return x.Theme
+	}
+	return ""
+}
+
+func (x *Profile) GetBackgroundColor() string {
+	if x != nil {
+		return x.BackgroundColor
+	}
+	return ""
+}
+
+func (x *Profile) GetTextColor() string {
+	if x != nil {
+		return x.TextColor
+	}
+	return ""
+}
+
+func (x *Profile) GetCustomMessage() string {
+	if x != nil {
+		return x.CustomMessage
+	}
+	return ""
+}
+
+func (x *Profile) GetShowStats() bool {
+	if x != nil {
+		return x.ShowStats
+	}
+	return false
+}
+
+func (x *Profile) GetShowHistory() bool {
+	if x != nil {
+		return x.ShowHistory
+	}
+	return false
+}
+
+func (x *Profile) GetAnimationStyle() string {
+	if x != nil {
+		return x.AnimationStyle
+	}
+	return ""
+}
+
+func (x *Profile) GetCurrentTrack() *Track {
+	if x != nil {
+		return x.CurrentTrack
+	}
+	return nil
+}
+
+func (x *Profile) GetRecentTracks() []*Track {
+	if x != nil {
+		return x.RecentTracks
+	}
+	return nil
+}
+
+func (x *Profile) GetViewerCount() int32 {
+	if x != nil {
+		return x.ViewerCount
+	}
+	return 0
+}
+
+type Track struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SpotifyTrackId string `protobuf:"bytes,1,opt,name=spotify_track_id,json=spotifyTrackId,proto3" json:"spotify_track_id,omitempty"`
+	Name           string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Artist         string `protobuf:"bytes,3,opt,name=artist,proto3" json:"artist,omitempty"`
+	Album          string `protobuf:"bytes,4,opt,name=album,proto3" json:"album,omitempty"`
+	AlbumArtUrl    string `protobuf:"bytes,5,opt,name=album_art_url,json=albumArtUrl,proto3" json:"album_art_url,omitempty"`
+	TrackUrl       string `protobuf:"bytes,6,opt,name=track_url,json=trackUrl,proto3" json:"track_url,omitempty"`
+	DurationMs     int32  `protobuf:"varint,7,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`
+	PlayedAt       int64  `protobuf:"varint,8,opt,name=played_at,json=playedAt,proto3" json:"played_at,omitempty"`
+}
+
+func (x *Track) Reset() {
+	*x = Track{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_nowplaying_v1_nowplaying_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Track) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Track) ProtoMessage() {}
+
+func (x *Track) ProtoReflect() protoreflect.Message {
+	mi := &file_nowplaying_v1_nowplaying_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Track.ProtoReflect.Descriptor instead.
+func (*Track) Descriptor() ([]byte, []int) {
+	return file_nowplaying_v1_nowplaying_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Track) GetSpotifyTrackId() string {
+	if x != nil {
+		return x.SpotifyTrackId
+	}
+	return ""
+}
+
+func (x *Track) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Track) GetArtist() string {
+	if x != nil {
+		return x.Artist
+	}
+	return ""
+}
+
+func (x *Track) GetAlbum() string {
+	if x != nil {
+		return x.Album
+	}
+	return ""
+}
+
+func (x *Track) GetAlbumArtUrl() string {
+	if x != nil {
+		return x.AlbumArtUrl
+	}
+	return ""
+}
+
+func (x *Track) GetTrackUrl() string {
+	if x != nil {
+		return x.TrackUrl
+	}
+	return ""
+}
+
+func (x *Track) GetDurationMs() int32 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+func (x *Track) GetPlayedAt() int64 {
+	if x != nil {
+		return x.PlayedAt
+	}
+	return 0
+}
+
+type NowPlaying struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	IsPlaying   bool   `protobuf:"varint,1,opt,name=is_playing,json=isPlaying,proto3" json:"is_playing,omitempty"`
+	TrackId     string `protobuf:"bytes,2,opt,name=track_id,json=trackId,proto3" json:"track_id,omitempty"`
+	TrackName   string `protobuf:"bytes,3,opt,name=track_name,json=trackName,proto3" json:"track_name,omitempty"`
+	ArtistName  string `protobuf:"bytes,4,opt,name=artist_name,json=artistName,proto3" json:"artist_name,omitempty"`
+	AlbumName   string `protobuf:"bytes,5,opt,name=album_name,json=albumName,proto3" json:"album_name,omitempty"`
+	AlbumArtUrl string `protobuf:"bytes,6,opt,name=album_art_url,json=albumArtUrl,proto3" json:"album_art_url,omitempty"`
+	TrackUrl    string `protobuf:"bytes,7,opt,name=track_url,json=trackUrl,proto3" json:"track_url,omitempty"`
+	DurationMs  int32  `protobuf:"varint,8,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`
+	ProgressMs  int32  `protobuf:"varint,9,opt,name=progress_ms,json=progressMs,proto3" json:"progress_ms,omitempty"`
+	ChangedAt   int64  `protobuf:"varint,10,opt,name=changed_at,json=changedAt,proto3" json:"changed_at,omitempty"`
+}
+
+func (x *NowPlaying) Reset() {
+	*x = NowPlaying{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_nowplaying_v1_nowplaying_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *NowPlaying) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NowPlaying) ProtoMessage() {}
+
+func (x *NowPlaying) ProtoReflect() protoreflect.Message {
+	mi := &file_nowplaying_v1_nowplaying_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NowPlaying.ProtoReflect.Descriptor instead.
+func (*NowPlaying) Descriptor() ([]byte, []int) {
+	return file_nowplaying_v1_nowplaying_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *NowPlaying) GetIsPlaying() bool {
+	if x != nil {
+		return x.IsPlaying
+	}
+	return false
+}
+
+func (x *NowPlaying) GetTrackId() string {
+	if x != nil {
+		return x.TrackId
+	}
+	return ""
+}
+
+func (x *NowPlaying) GetTrackName() string {
+	if x != nil {
+		return x.TrackName
+	}
+	return ""
+}
+
+func (x *NowPlaying) GetArtistName() string {
+	if x != nil {
+		return x.ArtistName
+	}
+	return ""
+}
+
+func (x *NowPlaying) GetAlbumName() string {
+	if x != nil {
+		return x.AlbumName
+	}
+	return ""
+}
+
+func (x *NowPlaying) GetAlbumArtUrl() string {
+	if x != nil {
+		return x.AlbumArtUrl
+	}
+	return ""
+}
+
+func (x *NowPlaying) GetTrackUrl() string {
+	if x != nil {
+		return x.TrackUrl
+	}
+	return ""
+}
+
+func (x *NowPlaying) GetDurationMs() int32 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+func (x *NowPlaying) GetProgressMs() int32 {
+	if x != nil {
+		return x.ProgressMs
+	}
+	return 0
+}
+
+func (x *NowPlaying) GetChangedAt() int64 {
+	if x != nil {
+		return x.ChangedAt
+	}
+	return 0
+}
+
+var File_nowplaying_v1_nowplaying_proto protoreflect.FileDescriptor
+
+var file_nowplaying_v1_nowplaying_proto_rawDesc = []byte{
+	0x0a, 0x1e, 0x6e, 0x6f, 0x77, 0x70, 0x6c, 0x61, 0x79, 0x69, 0x6e, 0x67, 0x2f, 0x76, 0x31, 0x2f,
+	0x6e, 0x6f, 0x77, 0x70, 0x6c, 0x61, 0x79, 0x69, 0x6e, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x12, 0x20, 0x77, 0x68, 0x61, 0x74, 0x61, 0x6d, 0x69, 0x6c, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x69,
+	0x6e, 0x67, 0x74, 0x6f, 0x2e, 0x6e, 0x6f, 0x77, 0x70, 0x6c, 0x61, 0x79, 0x69, 0x6e, 0x67, 0x2e,
+	0x76, 0x31, 0x22, 0x34, 0x0a, 0x11, 0x47, 0x65, 0x74, 0x50, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1f, 0x0a, 0x0b, 0x70, 0x72, 0x6f, 0x66, 0x69,
+	0x6c, 0x65, 0x5f, 0x75, 0x72, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x70, 0x72,
+	0x6f, 0x66, 0x69, 0x6c, 0x65, 0x55, 0x72, 0x6c, 0x22, 0x37, 0x0a, 0x14, 0x47, 0x65, 0x74, 0x4e,
+	0x6f, 0x77, 0x50, 0x6c, 0x61, 0x79, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x12, 0x1f, 0x0a, 0x0b, 0x70, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65, 0x5f, 0x75, 0x72, 0x6c, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x70, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65, 0x55, 0x72,
+	0x6c, 0x22, 0x39, 0x0a, 0x16, 0x57, 0x61, 0x74, 0x63, 0x68, 0x4e, 0x6f, 0x77, 0x50, 0x6c, 0x61,
+	0x79, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1f, 0x0a, 0x0b, 0x70,
+	0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65, 0x5f, 0x75, 0x72, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x0a, 0x70, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65, 0x55, 0x72, 0x6c, 0x22, 0x97, 0x04, 0x0a,
+	0x07, 0x50, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65, 0x12, 0x17, 0x0a, 0x07, 0x75, 0x73, 0x65, 0x72,
+	0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x75, 0x73, 0x65, 0x72, 0x49,
+	0x64, 0x12, 0x21, 0x0a, 0x0c, 0x64, 0x69, 0x73, 0x70, 0x6c, 0x61, 0x79, 0x5f, 0x6e, 0x61, 0x6d,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x64, 0x69, 0x73, 0x70, 0x6c, 0x61, 0x79,
+	0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x70, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65, 0x5f,
+	0x75, 0x72, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x70, 0x72, 0x6f, 0x66, 0x69,
+	0x6c, 0x65, 0x55, 0x72, 0x6c, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x68, 0x65, 0x6d, 0x65, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x74, 0x68, 0x65, 0x6d, 0x65, 0x12, 0x29, 0x0a, 0x10, 0x62,
+	0x61, 0x63, 0x6b, 0x67, 0x72, 0x6f, 0x75, 0x6e, 0x64, 0x5f, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0f, 0x62, 0x61, 0x63, 0x6b, 0x67, 0x72, 0x6f, 0x75, 0x6e,
+	0x64, 0x43, 0x6f, 0x6c, 0x6f, 0x72, 0x12, 0x1d, 0x0a, 0x0a, 0x74, 0x65, 0x78, 0x74, 0x5f, 0x63,
+	0x6f, 0x6c, 0x6f, 0x72, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x74, 0x65, 0x78, 0x74,
+	0x43, 0x6f, 0x6c, 0x6f, 0x72, 0x12, 0x25, 0x0a, 0x0e, 0x63, 0x75, 0x73, 0x74, 0x6f, 0x6d, 0x5f,
+	0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x63,
+	0x75, 0x73, 0x74, 0x6f, 0x6d, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x1d, 0x0a, 0x0a,
+	0x73, 0x68, 0x6f, 0x77, 0x5f, 0x73, 0x74, 0x61, 0x74, 0x73, 0x18, 0x08, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x09, 0x73, 0x68, 0x6f, 0x77, 0x53, 0x74, 0x61, 0x74, 0x73, 0x12, 0x21, 0x0a, 0x0c, 0x73,
+	0x68, 0x6f, 0x77, 0x5f, 0x68, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x79, 0x18, 0x09, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x0b, 0x73, 0x68, 0x6f, 0x77, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x79, 0x12, 0x27,
+	0x0a, 0x0f, 0x61, 0x6e, 0x69, 0x6d, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x73, 0x74, 0x79, 0x6c,
+	0x65, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x61, 0x6e, 0x69, 0x6d, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x53, 0x74, 0x79, 0x6c, 0x65, 0x12, 0x4c, 0x0a, 0x0d, 0x63, 0x75, 0x72, 0x72, 0x65,
+	0x6e, 0x74, 0x5f, 0x74, 0x72, 0x61, 0x63, 0x6b, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x27,
+	0x2e, 0x77, 0x68, 0x61, 0x74, 0x61, 0x6d, 0x69, 0x6c, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x69, 0x6e,
+	0x67, 0x74, 0x6f, 0x2e, 0x6e, 0x6f, 0x77, 0x70, 0x6c, 0x61, 0x79, 0x69, 0x6e, 0x67, 0x2e, 0x76,
+	0x31, 0x2e, 0x54, 0x72, 0x61, 0x63, 0x6b, 0x52, 0x0c, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74,
+	0x54, 0x72, 0x61, 0x63, 0x6b, 0x12, 0x4c, 0x0a, 0x0d, 0x72, 0x65, 0x63, 0x65, 0x6e, 0x74, 0x5f,
+	0x74, 0x72, 0x61, 0x63, 0x6b, 0x73, 0x18, 0x0c, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x27, 0x2e, 0x77,
+	0x68, 0x61, 0x74, 0x61, 0x6d, 0x69, 0x6c, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x69, 0x6e, 0x67, 0x74,
+	0x6f, 0x2e, 0x6e, 0x6f, 0x77, 0x70, 0x6c, 0x61, 0x79, 0x69, 0x6e, 0x67, 0x2e, 0x76, 0x31, 0x2e,
+	0x54, 0x72, 0x61, 0x63, 0x6b, 0x52, 0x0c, 0x72, 0x65, 0x63, 0x65, 0x6e, 0x74, 0x54, 0x72, 0x61,
+	0x63, 0x6b, 0x73, 0x12, 0x21, 0x0a, 0x0c, 0x76, 0x69, 0x65, 0x77, 0x65, 0x72, 0x5f, 0x63, 0x6f,
+	0x75, 0x6e, 0x74, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0b, 0x76, 0x69, 0x65, 0x77, 0x65,
+	0x72, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0xf2, 0x01, 0x0a, 0x05, 0x54, 0x72, 0x61, 0x63, 0x6b,
+	0x12, 0x28, 0x0a, 0x10, 0x73, 0x70, 0x6f, 0x74, 0x69, 0x66, 0x79, 0x5f, 0x74, 0x72, 0x61, 0x63,
+	0x6b, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x73, 0x70, 0x6f, 0x74,
+	0x69, 0x66, 0x79, 0x54, 0x72, 0x61, 0x63, 0x6b, 0x49, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61,
+	0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x16,
+	0x0a, 0x06, 0x61, 0x72, 0x74, 0x69, 0x73, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06,
+	0x61, 0x72, 0x74, 0x69, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x61, 0x6c, 0x62, 0x75, 0x6d, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x61, 0x6c, 0x62, 0x75, 0x6d, 0x12, 0x22, 0x0a, 0x0d,
+	0x61, 0x6c, 0x62, 0x75, 0x6d, 0x5f, 0x61, 0x72, 0x74, 0x5f, 0x75, 0x72, 0x6c, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0b, 0x61, 0x6c, 0x62, 0x75, 0x6d, 0x41, 0x72, 0x74, 0x55, 0x72, 0x6c,
+	0x12, 0x1b, 0x0a, 0x09, 0x74, 0x72, 0x61, 0x63, 0x6b, 0x5f, 0x75, 0x72, 0x6c, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x08, 0x74, 0x72, 0x61, 0x63, 0x6b, 0x55, 0x72, 0x6c, 0x12, 0x1f, 0x0a,
+	0x0b, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x6d, 0x73, 0x18, 0x07, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x0a, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x4d, 0x73, 0x12, 0x1b,
+	0x0a, 0x09, 0x70, 0x6c, 0x61, 0x79, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x08, 0x70, 0x6c, 0x61, 0x79, 0x65, 0x64, 0x41, 0x74, 0x22, 0xc7, 0x02, 0x0a, 0x0a,
+	0x4e, 0x6f, 0x77, 0x50, 0x6c, 0x61, 0x79, 0x69, 0x6e, 0x67, 0x12, 0x1d, 0x0a, 0x0a, 0x69, 0x73,
+	0x5f, 0x70, 0x6c, 0x61, 0x79, 0x69, 0x6e, 0x67, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09,
+	0x69, 0x73, 0x50, 0x6c, 0x61, 0x79, 0x69, 0x6e, 0x67, 0x12, 0x19, 0x0a, 0x08, 0x74, 0x72, 0x61,
+	0x63, 0x6b, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x74, 0x72, 0x61,
+	0x63, 0x6b, 0x49, 0x64, 0x12, 0x1d, 0x0a, 0x0a, 0x74, 0x72, 0x61, 0x63, 0x6b, 0x5f, 0x6e, 0x61,
+	0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x74, 0x72, 0x61, 0x63, 0x6b, 0x4e,
+	0x61, 0x6d, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x61, 0x72, 0x74, 0x69, 0x73, 0x74, 0x5f, 0x6e, 0x61,
+	0x6d, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x61, 0x72, 0x74, 0x69, 0x73, 0x74,
+	0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x61, 0x6c, 0x62, 0x75, 0x6d, 0x5f, 0x6e, 0x61,
+	0x6d, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x61, 0x6c, 0x62, 0x75, 0x6d, 0x4e,
+	0x61, 0x6d, 0x65, 0x12, 0x22, 0x0a, 0x0d, 0x61, 0x6c, 0x62, 0x75, 0x6d, 0x5f, 0x61, 0x72, 0x74,
+	0x5f, 0x75, 0x72, 0x6c, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x61, 0x6c, 0x62, 0x75,
+	0x6d, 0x41, 0x72, 0x74, 0x55, 0x72, 0x6c, 0x12, 0x1b, 0x0a, 0x09, 0x74, 0x72, 0x61, 0x63, 0x6b,
+	0x5f, 0x75, 0x72, 0x6c, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x74, 0x72, 0x61, 0x63,
+	0x6b, 0x55, 0x72, 0x6c, 0x12, 0x1f, 0x0a, 0x0b, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x5f, 0x6d, 0x73, 0x18, 0x08, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x64, 0x75, 0x72, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x4d, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x70, 0x72, 0x6f, 0x67, 0x72, 0x65, 0x73,
+	0x73, 0x5f, 0x6d, 0x73, 0x18, 0x09, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x70, 0x72, 0x6f, 0x67,
+	0x72, 0x65, 0x73, 0x73, 0x4d, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65,
+	0x64, 0x5f, 0x61, 0x74, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x63, 0x68, 0x61, 0x6e,
+	0x67, 0x65, 0x64, 0x41, 0x74, 0x32, 0xf5, 0x02, 0x0a, 0x11, 0x4e, 0x6f, 0x77, 0x50, 0x6c, 0x61,
+	0x79, 0x69, 0x6e, 0x67, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x6c, 0x0a, 0x0a, 0x47,
+	0x65, 0x74, 0x50, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65, 0x12, 0x33, 0x2e, 0x77, 0x68, 0x61, 0x74,
+	0x61, 0x6d, 0x69, 0x6c, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x69, 0x6e, 0x67, 0x74, 0x6f, 0x2e, 0x6e,
+	0x6f, 0x77, 0x70, 0x6c, 0x61, 0x79, 0x69, 0x6e, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74,
+	0x50, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x29,
+	0x2e, 0x77, 0x68, 0x61, 0x74, 0x61, 0x6d, 0x69, 0x6c, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x69, 0x6e,
+	0x67, 0x74, 0x6f, 0x2e, 0x6e, 0x6f, 0x77, 0x70, 0x6c, 0x61, 0x79, 0x69, 0x6e, 0x67, 0x2e, 0x76,
+	0x31, 0x2e, 0x50, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65, 0x12, 0x75, 0x0a, 0x0d, 0x47, 0x65, 0x74,
+	0x4e, 0x6f, 0x77, 0x50, 0x6c, 0x61, 0x79, 0x69, 0x6e, 0x67, 0x12, 0x36, 0x2e, 0x77, 0x68, 0x61,
+	0x74, 0x61, 0x6d, 0x69, 0x6c, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x69, 0x6e, 0x67, 0x74, 0x6f, 0x2e,
+	0x6e, 0x6f, 0x77, 0x70, 0x6c, 0x61, 0x79, 0x69, 0x6e, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65,
+	0x74, 0x4e, 0x6f, 0x77, 0x50, 0x6c, 0x61, 0x79, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x2c, 0x2e, 0x77, 0x68, 0x61, 0x74, 0x61, 0x6d, 0x69, 0x6c, 0x69, 0x73, 0x74,
+	0x65, 0x6e, 0x69, 0x6e, 0x67, 0x74, 0x6f, 0x2e, 0x6e, 0x6f, 0x77, 0x70, 0x6c, 0x61, 0x79, 0x69,
+	0x6e, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x4e, 0x6f, 0x77, 0x50, 0x6c, 0x61, 0x79, 0x69, 0x6e, 0x67,
+	0x12, 0x7b, 0x0a, 0x0f, 0x57, 0x61, 0x74, 0x63, 0x68, 0x4e, 0x6f, 0x77, 0x50, 0x6c, 0x61, 0x79,
+	0x69, 0x6e, 0x67, 0x12, 0x38, 0x2e, 0x77, 0x68, 0x61, 0x74, 0x61, 0x6d, 0x69, 0x6c, 0x69, 0x73,
+	0x74, 0x65, 0x6e, 0x69, 0x6e, 0x67, 0x74, 0x6f, 0x2e, 0x6e, 0x6f, 0x77, 0x70, 0x6c, 0x61, 0x79,
+	0x69, 0x6e, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x4e, 0x6f, 0x77, 0x50,
+	0x6c, 0x61, 0x79, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x2c, 0x2e,
+	0x77, 0x68, 0x61, 0x74, 0x61, 0x6d, 0x69, 0x6c, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x69, 0x6e, 0x67,
+	0x74, 0x6f, 0x2e, 0x6e, 0x6f, 0x77, 0x70, 0x6c, 0x61, 0x79, 0x69, 0x6e, 0x67, 0x2e, 0x76, 0x31,
+	0x2e, 0x4e, 0x6f, 0x77, 0x50, 0x6c, 0x61, 0x79, 0x69, 0x6e, 0x67, 0x30, 0x01, 0x42, 0x52, 0x5a,
+	0x50, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x62, 0x72, 0x61, 0x6e,
+	0x64, 0x6f, 0x6e, 0x68, 0x75, 0x79, 0x6e, 0x68, 0x31, 0x2f, 0x77, 0x68, 0x61, 0x74, 0x61, 0x6d,
+	0x69, 0x6c, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x69, 0x6e, 0x67, 0x74, 0x6f, 0x2d, 0x61, 0x70, 0x69,
+	0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x6e, 0x6f, 0x77, 0x70, 0x6c, 0x61, 0x79, 0x69, 0x6e,
+	0x67, 0x2f, 0x76, 0x31, 0x3b, 0x6e, 0x6f, 0x77, 0x70, 0x6c, 0x61, 0x79, 0x69, 0x6e, 0x67, 0x76,
+	0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_nowplaying_v1_nowplaying_proto_rawDescOnce sync.Once
+	file_nowplaying_v1_nowplaying_proto_rawDescData = file_nowplaying_v1_nowplaying_proto_rawDesc
+)
+
+func file_nowplaying_v1_nowplaying_proto_rawDescGZIP() []byte {
+	file_nowplaying_v1_nowplaying_proto_rawDescOnce.Do(func() {
+		file_nowplaying_v1_nowplaying_proto_rawDescData = protoimpl.X.CompressGZIP(file_nowplaying_v1_nowplaying_proto_rawDescData)
+	})
+	return file_nowplaying_v1_nowplaying_proto_rawDescData
+}
+
+var file_nowplaying_v1_nowplaying_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_nowplaying_v1_nowplaying_proto_goTypes = []interface{}{
+	(*GetProfileRequest)(nil),      // 0: whatamilisteningto.nowplaying.v1.GetProfileRequest
+	(*GetNowPlayingRequest)(nil),   // 1: whatamilisteningto.nowplaying.v1.GetNowPlayingRequest
+	(*WatchNowPlayingRequest)(nil), // 2: whatamilisteningto.nowplaying.v1.WatchNowPlayingRequest
+	(*Profile)(nil),                // 3: whatamilisteningto.nowplaying.v1.Profile
+	(*Track)(nil),                  // 4: whatamilisteningto.nowplaying.v1.Track
+	(*NowPlaying)(nil),             // 5: whatamilisteningto.nowplaying.v1.NowPlaying
+}
+var file_nowplaying_v1_nowplaying_proto_depIdxs = []int32{
+	4, // 0: whatamilisteningto.nowplaying.v1.Profile.current_track:type_name -> whatamilisteningto.nowplaying.v1.Track
+	4, // 1: whatamilisteningto.nowplaying.v1.Profile.recent_tracks:type_name -> whatamilisteningto.nowplaying.v1.Track
+	0, // 2: whatamilisteningto.nowplaying.v1.NowPlayingService.GetProfile:input_type -> whatamilisteningto.nowplaying.v1.GetProfileRequest
+	1, // 3: whatamilisteningto.nowplaying.v1.NowPlayingService.GetNowPlaying:input_type -> whatamilisteningto.nowplaying.v1.GetNowPlayingRequest
+	2, // 4: whatamilisteningto.nowplaying.v1.NowPlayingService.WatchNowPlaying:input_type -> whatamilisteningto.nowplaying.v1.WatchNowPlayingRequest
+	3, // 5: whatamilisteningto.nowplaying.v1.NowPlayingService.GetProfile:output_type -> whatamilisteningto.nowplaying.v1.Profile
+	5, // 6: whatamilisteningto.nowplaying.v1.NowPlayingService.GetNowPlaying:output_type -> whatamilisteningto.nowplaying.v1.NowPlaying
+	5, // 7: whatamilisteningto.nowplaying.v1.NowPlayingService.WatchNowPlaying:output_type -> whatamilisteningto.nowplaying.v1.NowPlaying
+	5, // [5:8] is the sub-list for method output_type
+	2, // [2:5] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_nowplaying_v1_nowplaying_proto_init() }
+func file_nowplaying_v1_nowplaying_proto_init() {
+	if File_nowplaying_v1_nowplaying_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_nowplaying_v1_nowplaying_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetProfileRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_nowplaying_v1_nowplaying_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetNowPlayingRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_nowplaying_v1_nowplaying_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WatchNowPlayingRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_nowplaying_v1_nowplaying_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Profile); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_nowplaying_v1_nowplaying_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Track); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_nowplaying_v1_nowplaying_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*NowPlaying); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_nowplaying_v1_nowplaying_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_nowplaying_v1_nowplaying_proto_goTypes,
+		DependencyIndexes: file_nowplaying_v1_nowplaying_proto_depIdxs,
+		MessageInfos:      file_nowplaying_v1_nowplaying_proto_msgTypes,
+	}.Build()
+	File_nowplaying_v1_nowplaying_proto = out.File
+	file_nowplaying_v1_nowplaying_proto_rawDesc = nil
+	file_nowplaying_v1_nowplaying_proto_goTypes = nil
+	file_nowplaying_v1_nowplaying_proto_depIdxs = nil
+}
@@ -0,0 +1,201 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: nowplaying/v1/nowplaying.proto
+
+package nowplayingv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	NowPlayingService_GetProfile_FullMethodName      = "/whatamilisteningto.nowplaying.v1.NowPlayingService/GetProfile"
+	NowPlayingService_GetNowPlaying_FullMethodName   = "/whatamilisteningto.nowplaying.v1.NowPlayingService/GetNowPlaying"
+	NowPlayingService_WatchNowPlaying_FullMethodName = "/whatamilisteningto.nowplaying.v1.NowPlayingService/WatchNowPlaying"
+)
+
+// NowPlayingServiceClient is the client API for NowPlayingService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type NowPlayingServiceClient interface {
+	GetProfile(ctx context.Context, in *GetProfileRequest, opts ...grpc.CallOption) (*Profile, error)
+	GetNowPlaying(ctx context.Context, in *GetNowPlayingRequest, opts ...grpc.CallOption) (*NowPlaying, error)
+	WatchNowPlaying(ctx context.Context, in *WatchNowPlayingRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[NowPlaying], error)
+}
+
+type nowPlayingServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewNowPlayingServiceClient(cc grpc.ClientConnInterface) NowPlayingServiceClient {
+	return &nowPlayingServiceClient{cc}
+}
+
+func (c *nowPlayingServiceClient) GetProfile(ctx context.Context, in *GetProfileRequest, opts ...grpc.CallOption) (*Profile, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Profile)
+	err := c.cc.Invoke(ctx, NowPlayingService_GetProfile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nowPlayingServiceClient) GetNowPlaying(ctx context.Context, in *GetNowPlayingRequest, opts ...grpc.CallOption) (*NowPlaying, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(NowPlaying)
+	err := c.cc.Invoke(ctx, NowPlayingService_GetNowPlaying_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nowPlayingServiceClient) WatchNowPlaying(ctx context.Context, in *WatchNowPlayingRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[NowPlaying], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &NowPlayingService_ServiceDesc.Streams[0], NowPlayingService_WatchNowPlaying_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchNowPlayingRequest, NowPlaying]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type NowPlayingService_WatchNowPlayingClient = grpc.ServerStreamingClient[NowPlaying]
+
+// NowPlayingServiceServer is the server API for NowPlayingService service.
+// All implementations must embed UnimplementedNowPlayingServiceServer
+// for forward compatibility.
+type NowPlayingServiceServer interface {
+	GetProfile(context.Context, *GetProfileRequest) (*Profile, error)
+	GetNowPlaying(context.Context, *GetNowPlayingRequest) (*NowPlaying, error)
+	WatchNowPlaying(*WatchNowPlayingRequest, grpc.ServerStreamingServer[NowPlaying]) error
+	mustEmbedUnimplementedNowPlayingServiceServer()
+}
+
+// UnimplementedNowPlayingServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedNowPlayingServiceServer struct{}
+
+func (UnimplementedNowPlayingServiceServer) GetProfile(context.Context, *GetProfileRequest) (*Profile, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetProfile not implemented")
+}
+func (UnimplementedNowPlayingServiceServer) GetNowPlaying(context.Context, *GetNowPlayingRequest) (*NowPlaying, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNowPlaying not implemented")
+}
+func (UnimplementedNowPlayingServiceServer) WatchNowPlaying(*WatchNowPlayingRequest, grpc.ServerStreamingServer[NowPlaying]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchNowPlaying not implemented")
+}
+func (UnimplementedNowPlayingServiceServer) mustEmbedUnimplementedNowPlayingServiceServer() {}
+func (UnimplementedNowPlayingServiceServer) testEmbeddedByValue()                           {}
+
+// UnsafeNowPlayingServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to NowPlayingServiceServer will
+// result in compilation errors.
+type UnsafeNowPlayingServiceServer interface {
+	mustEmbedUnimplementedNowPlayingServiceServer()
+}
+
+func RegisterNowPlayingServiceServer(s grpc.ServiceRegistrar, srv NowPlayingServiceServer) {
+	// If the following call pancis, it indicates UnimplementedNowPlayingServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&NowPlayingService_ServiceDesc, srv)
+}
+
+func _NowPlayingService_GetProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetProfileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NowPlayingServiceServer).GetProfile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NowPlayingService_GetProfile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NowPlayingServiceServer).GetProfile(ctx, req.(*GetProfileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NowPlayingService_GetNowPlaying_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNowPlayingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NowPlayingServiceServer).GetNowPlaying(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NowPlayingService_GetNowPlaying_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NowPlayingServiceServer).GetNowPlaying(ctx, req.(*GetNowPlayingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NowPlayingService_WatchNowPlaying_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchNowPlayingRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(NowPlayingServiceServer).WatchNowPlaying(m, &grpc.GenericServerStream[WatchNowPlayingRequest, NowPlaying]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type NowPlayingService_WatchNowPlayingServer = grpc.ServerStreamingServer[NowPlaying]
+
+// NowPlayingService_ServiceDesc is the grpc.ServiceDesc for NowPlayingService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var NowPlayingService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "whatamilisteningto.nowplaying.v1.NowPlayingService",
+	HandlerType: (*NowPlayingServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetProfile",
+			Handler:    _NowPlayingService_GetProfile_Handler,
+		},
+		{
+			MethodName: "GetNowPlaying",
+			Handler:    _NowPlayingService_GetNowPlaying_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchNowPlaying",
+			Handler:       _NowPlayingService_WatchNowPlaying_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "nowplaying/v1/nowplaying.proto",
+}